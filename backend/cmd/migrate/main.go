@@ -1,15 +1,21 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
 
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"ecommerce-saas/internal/search"
 	"ecommerce-saas/internal/shared/config"
 	"ecommerce-saas/internal/shared/database"
 )
 
 func main() {
-	var action = flag.String("action", "up", "Migration action: up, down, or reset")
+	var action = flag.String("action", "up", "Migration action: up, seed, reset or reindex")
+	var tenant = flag.String("tenant", "", "Tenant ID to reindex; all tenants when empty")
 	flag.Parse()
 
 	// Load configuration
@@ -44,8 +50,51 @@ func main() {
 		// TODO: Implement database reset
 		log.Println("Database reset - TODO: implement")
 
+	case "reindex":
+		log.Println("Rebuilding search index...")
+		if err := reindex(db, cfg, *tenant); err != nil {
+			log.Fatalf("Failed to rebuild search index: %v", err)
+		}
+		log.Println("Search index rebuilt successfully")
+
 	default:
 		log.Printf("Unknown action: %s", *action)
-		log.Println("Available actions: up, seed, reset")
+		log.Println("Available actions: up, seed, reset, reindex")
+	}
+}
+
+// reindex rebuilds the product search index of one tenant or of every tenant
+func reindex(db *gorm.DB, cfg *config.Config, tenant string) error {
+	index, err := search.NewIndex(db, search.IndexConfig{
+		Backend:     cfg.Search.Backend,
+		URL:         cfg.Search.URL,
+		APIKey:      cfg.Search.APIKey,
+		IndexPrefix: cfg.Search.IndexPrefix,
+	})
+	if err != nil {
+		return err
+	}
+	repo := search.NewRepository(db)
+	indexer := search.NewIndexer(index, repo)
+	ctx := context.Background()
+
+	var tenantIDs []uuid.UUID
+	if tenant != "" {
+		tenantID, err := uuid.Parse(tenant)
+		if err != nil {
+			return err
+		}
+		tenantIDs = append(tenantIDs, tenantID)
+	} else if tenantIDs, err = repo.ListTenantIDs(ctx); err != nil {
+		return err
+	}
+
+	for _, tenantID := range tenantIDs {
+		result, err := indexer.Reindex(ctx, tenantID)
+		if err != nil {
+			return err
+		}
+		log.Printf("Tenant %s: %d products indexed, %d removed (%s)", tenantID, result.Indexed, result.Deleted, index.Name())
 	}
+	return nil
 }
\ No newline at end of file
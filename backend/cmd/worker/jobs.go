@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"ecommerce-saas/internal/discount"
+	"ecommerce-saas/internal/loyalty"
+	"ecommerce-saas/internal/notification"
+	"ecommerce-saas/internal/product"
+	"ecommerce-saas/internal/search"
+	"ecommerce-saas/internal/shared/config"
+)
+
+// newJobs builds the periodic jobs of the worker
+func newJobs(cfg *config.Config, db *gorm.DB) []job {
+	notifications := notification.NewService(notification.NewRepository(db))
+	discounts := discount.NewService(discount.NewRepository(db), notifications, nil)
+	loyalties := loyalty.NewService(loyalty.NewGormRepository(db), notifications, discounts)
+	products := product.NewModule(db)
+	products.SetChangeListener(search.NewModule(db, newSearchIndex(cfg, db)).GetIndexer())
+
+	return []job{
+		{
+			// Gift cards scheduled for a later date, and emails that failed
+			name:     "gift_card_delivery",
+			interval: 5 * time.Minute,
+			run: func(ctx context.Context, tenantID uuid.UUID) error {
+				result, err := discounts.DeliverGiftCards(ctx, tenantID)
+				if err != nil {
+					return err
+				}
+				if result.Delivered > 0 || result.Failed > 0 {
+					log.Printf("Tenant %s: %d gift cards delivered, %d failed", tenantID, result.Delivered, result.Failed)
+				}
+				return nil
+			},
+		},
+		{
+			// Settles lapsed holds, then forfeits expired gift card and store
+			// credit balances
+			name:     "gift_card_expiry",
+			interval: 24 * time.Hour,
+			run: func(ctx context.Context, tenantID uuid.UUID) error {
+				result, err := discounts.ExpireBalances(ctx, tenantID)
+				if err != nil {
+					return err
+				}
+				if result.GiftCardsExpired > 0 || result.StoreCreditsExpired > 0 {
+					log.Printf("Tenant %s: %d gift cards and %d store credits expired", tenantID, result.GiftCardsExpired, result.StoreCreditsExpired)
+				}
+				return nil
+			},
+		},
+		{
+			// Makes points spendable once their holding period has passed, a
+			// batch at a time until one releases nothing
+			name:     "loyalty_pending_points",
+			interval: time.Hour,
+			run: func(ctx context.Context, tenantID uuid.UUID) error {
+				released, points := 0, 0
+				for ctx.Err() == nil {
+					result, err := loyalties.ReleasePendingPoints(ctx, tenantID, time.Now())
+					if err != nil {
+						return err
+					}
+					if result.Released == 0 {
+						break
+					}
+					released += result.Released
+					points += result.Points
+				}
+				if released > 0 {
+					log.Printf("Tenant %s: %d pending loyalty transactions released, %d points", tenantID, released, points)
+				}
+				return nil
+			},
+		},
+		{
+			// Bonuses are awarded once per customer and year, so running more
+			// than daily only makes sure no day is skipped
+			name:     "loyalty_birthday_bonuses",
+			interval: 6 * time.Hour,
+			run: func(ctx context.Context, tenantID uuid.UUID) error {
+				result, err := loyalties.AwardBirthdayBonuses(ctx, tenantID, time.Now())
+				if err != nil {
+					return err
+				}
+				if result.Awarded > 0 || result.Failed > 0 {
+					log.Printf("Tenant %s: %d birthday bonuses awarded, %d failed", tenantID, result.Awarded, result.Failed)
+				}
+				return nil
+			},
+		},
+		{
+			// Forfeits expired points, then reminds customers of points that
+			// expire soon. Each expiry call handles a batch, so batches run
+			// until one expires nothing.
+			name:     "loyalty_points_expiry",
+			interval: 24 * time.Hour,
+			run: func(ctx context.Context, tenantID uuid.UUID) error {
+				expired := 0
+				for ctx.Err() == nil {
+					result, err := loyalties.ExpirePoints(ctx, tenantID, time.Now())
+					if err != nil {
+						return err
+					}
+					if result.Expired == 0 {
+						break
+					}
+					expired += result.Expired
+				}
+				if expired > 0 {
+					log.Printf("Tenant %s: %d loyalty point lots expired", tenantID, expired)
+				}
+
+				reminders, err := loyalties.SendExpiryReminders(ctx, tenantID, time.Now())
+				if err != nil {
+					return err
+				}
+				if reminders.Sent > 0 || reminders.Failed > 0 {
+					log.Printf("Tenant %s: %d points expiry reminders sent, %d failed", tenantID, reminders.Sent, reminders.Failed)
+				}
+				return nil
+			},
+		},
+		{
+			name:     "loyalty_tier_evaluation",
+			interval: 24 * time.Hour,
+			run: func(ctx context.Context, tenantID uuid.UUID) error {
+				result, err := loyalties.EvaluateTiers(ctx, tenantID, time.Now())
+				if err != nil {
+					return err
+				}
+				if result.Upgraded > 0 || result.Downgraded > 0 || result.Failed > 0 {
+					log.Printf("Tenant %s: %d loyalty tiers upgraded, %d downgraded, %d failed", tenantID, result.Upgraded, result.Downgraded, result.Failed)
+				}
+				return nil
+			},
+		},
+		{
+			// Rewards referrals whose return window closed, a batch at a time
+			// until one settles nothing
+			name:     "referral_rewards",
+			interval: 24 * time.Hour,
+			run: func(ctx context.Context, tenantID uuid.UUID) error {
+				rewarded, failed := 0, 0
+				for ctx.Err() == nil {
+					result, err := loyalties.IssueReferralRewards(ctx, tenantID, time.Now())
+					if err != nil {
+						return err
+					}
+					rewarded += result.Rewarded
+					failed = result.Failed
+					if result.Rewarded == 0 && result.Cancelled == 0 {
+						break
+					}
+				}
+				if rewarded > 0 || failed > 0 {
+					log.Printf("Tenant %s: %d referrals rewarded, %d failed", tenantID, rewarded, failed)
+				}
+				return nil
+			},
+		},
+		{
+			// Catches what product writes don't, such as created within rules
+			name:     "smart_collections",
+			interval: 24 * time.Hour,
+			run: func(ctx context.Context, tenantID uuid.UUID) error {
+				changed, err := products.Service.RefreshSmartCollections(tenantID)
+				if err != nil {
+					return err
+				}
+				if changed > 0 {
+					log.Printf("Tenant %s: %d products joined or left smart collections", tenantID, changed)
+				}
+				return nil
+			},
+		},
+		{
+			// Imports uploaded through the API, after failing those a
+			// stopped worker left running
+			name:     "product_imports",
+			interval: time.Minute,
+			run: func(ctx context.Context, tenantID uuid.UUID) error {
+				failed, err := products.Service.FailStaleImports(tenantID)
+				if err != nil {
+					return err
+				}
+				if failed > 0 {
+					log.Printf("Tenant %s: %d stale product imports failed", tenantID, failed)
+				}
+				run, err := products.Service.RunPendingImports(tenantID)
+				if run > 0 {
+					log.Printf("Tenant %s: %d product imports run", tenantID, run)
+				}
+				return err
+			},
+		},
+	}
+}
+
+// newSearchIndex returns the configured search index, or nil to use Postgres
+func newSearchIndex(cfg *config.Config, db *gorm.DB) search.SearchIndex {
+	index, err := search.NewIndex(db, search.IndexConfig{
+		Backend:     cfg.Search.Backend,
+		URL:         cfg.Search.URL,
+		APIKey:      cfg.Search.APIKey,
+		IndexPrefix: cfg.Search.IndexPrefix,
+	})
+	if err != nil {
+		log.Printf("Failed to create search index, using Postgres: %v", err)
+		return nil
+	}
+	return index
+}
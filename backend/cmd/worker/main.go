@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os/signal"
+	"syscall"
 
 	"ecommerce-saas/internal/shared/config"
 	"ecommerce-saas/internal/shared/database"
@@ -21,16 +24,12 @@ func main() {
 	}
 
 	log.Println("Worker starting...")
-	log.Printf("Database connection established: %v", db != nil)
-
-	// TODO: Implement background worker
-	// This will handle:
-	// - Email sending queue
-	// - Image processing
-	// - Report generation
-	// - Data cleanup tasks
-	// - Webhook processing
-	// - Notification dispatch
-
-	log.Println("Worker implementation pending - TODO")
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	worker := &scheduler{db: db, jobs: newJobs(cfg, db)}
+	worker.Run(ctx)
+
+	log.Println("Worker stopped")
 }
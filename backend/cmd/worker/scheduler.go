@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"ecommerce-saas/internal/tenant"
+)
+
+// job is periodic work run for every active tenant
+type job struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context, tenantID uuid.UUID) error
+}
+
+// scheduler runs each job once at start and then every interval until the
+// context is cancelled. A failure for one tenant is logged and does not stop
+// the job for the others.
+type scheduler struct {
+	db   *gorm.DB
+	jobs []job
+}
+
+// Run blocks until ctx is cancelled and every running job has finished
+func (s *scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, j := range s.jobs {
+		wg.Add(1)
+		go func(j job) {
+			defer wg.Done()
+			s.loop(ctx, j)
+		}(j)
+	}
+	wg.Wait()
+}
+
+func (s *scheduler) loop(ctx context.Context, j job) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		s.runOnce(ctx, j)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *scheduler) runOnce(ctx context.Context, j job) {
+	tenantIDs, err := s.activeTenants(ctx)
+	if err != nil {
+		log.Printf("Job %s: failed to list tenants: %v", j.name, err)
+		return
+	}
+
+	for _, tenantID := range tenantIDs {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := j.run(ctx, tenantID); err != nil {
+			log.Printf("Job %s failed for tenant %s: %v", j.name, tenantID, err)
+		}
+	}
+}
+
+// activeTenants lists the tenants periodic work is done for
+func (s *scheduler) activeTenants(ctx context.Context) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := s.db.WithContext(ctx).Model(&tenant.Tenant{}).
+		Where("status = ?", tenant.StatusActive).
+		Pluck("id", &ids).Error
+	return ids, err
+}
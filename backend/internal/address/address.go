@@ -27,6 +27,11 @@ type Address struct {
 	State       string     `json:"state" gorm:"type:varchar(100);not null"`
 	PostalCode  string     `json:"postal_code" gorm:"type:varchar(20);not null"`
 	Country     string     `json:"country" gorm:"type:varchar(2);not null"` // ISO 3166-1 alpha-2
+	Division    string     `json:"division" gorm:"type:varchar(100)"`
+	District    string     `json:"district" gorm:"type:varchar(100);index"`
+	Thana       string     `json:"thana" gorm:"type:varchar(100)"`
+	Area        string     `json:"area" gorm:"type:varchar(100)"`
+	AreaCode    string     `json:"area_code" gorm:"type:varchar(255);index"` // Most specific geo code, see geo.go
 	Phone       string     `json:"phone" gorm:"type:varchar(20)"`
 	IsDefault   bool       `json:"is_default" gorm:"default:false;index"`
 	IsValidated bool       `json:"is_validated" gorm:"default:false"`
@@ -55,6 +60,22 @@ type AddressValidation struct {
 	Address Address `json:"address" gorm:"foreignKey:AddressID;constraint:OnDelete:CASCADE"`
 }
 
+// CarrierZoneMapping maps a geo code to a courier's own city/zone/area IDs.
+// Rows with a nil TenantID are platform defaults; tenant rows override them.
+type CarrierZoneMapping struct {
+	ID            uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TenantID      uuid.UUID `json:"tenant_id" gorm:"type:uuid;not null;uniqueIndex:idx_carrier_zone_mapping"`
+	Provider      string    `json:"provider" gorm:"type:varchar(50);not null;uniqueIndex:idx_carrier_zone_mapping"`
+	GeoCode       string    `json:"geo_code" gorm:"type:varchar(255);not null;uniqueIndex:idx_carrier_zone_mapping"`
+	CarrierCityID string    `json:"carrier_city_id" gorm:"type:varchar(50)"`
+	CarrierZoneID string    `json:"carrier_zone_id" gorm:"type:varchar(50)"`
+	CarrierAreaID string    `json:"carrier_area_id" gorm:"type:varchar(50)"`
+	CarrierLabel  string    `json:"carrier_label" gorm:"type:varchar(255)"` // Name as the courier spells it
+	IsActive      bool      `json:"is_active" gorm:"default:true"`
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
 // Constants for address types
 const (
 	AddressTypeShipping = "shipping"
@@ -97,6 +118,28 @@ func (a *Address) GetCountryName() string {
 	return strings.ToUpper(a.Country)
 }
 
+// ApplyGeoPath fills the structured and legacy city/state fields from a
+// resolved hierarchy path
+func (a *Address) ApplyGeoPath(path *GeoPath) {
+	a.Division = path.Division.Name
+	a.State = path.Division.Name
+	a.District, a.Thana, a.Area = "", "", ""
+	if path.District != nil {
+		a.District = path.District.Name
+		a.City = path.District.Name
+	}
+	if path.Thana != nil {
+		a.Thana = path.Thana.Name
+		if a.PostalCode == "" {
+			a.PostalCode = path.Thana.PostalCode
+		}
+	}
+	if path.Area != nil {
+		a.Area = path.Area.Name
+	}
+	a.AreaCode = path.Leaf().Code
+}
+
 // IsShippingAddress checks if address can be used for shipping
 func (a *Address) IsShippingAddress() bool {
 	return a.Type == AddressTypeShipping || a.Type == AddressTypeBoth
@@ -157,7 +200,8 @@ func (a *Address) Validate() error {
 		return ErrStateRequired
 	}
 	
-	if strings.TrimSpace(a.PostalCode) == "" {
+	// Structured BD addresses are located by area code; postal codes are optional there
+	if strings.TrimSpace(a.PostalCode) == "" && a.AreaCode == "" {
 		return ErrPostalCodeRequired
 	}
 	
@@ -261,6 +305,11 @@ func (a *Address) NormalizeData() {
 	a.State = strings.TrimSpace(a.State)
 	a.PostalCode = strings.TrimSpace(a.PostalCode)
 	a.Country = strings.ToUpper(strings.TrimSpace(a.Country))
+	a.Division = strings.TrimSpace(a.Division)
+	a.District = strings.TrimSpace(a.District)
+	a.Thana = strings.TrimSpace(a.Thana)
+	a.Area = strings.TrimSpace(a.Area)
+	a.AreaCode = strings.ToLower(strings.TrimSpace(a.AreaCode))
 	a.Phone = strings.TrimSpace(a.Phone)
 	a.Label = strings.TrimSpace(a.Label)
 	a.Instructions = strings.TrimSpace(a.Instructions)
@@ -318,6 +367,7 @@ type CreateAddressRequest struct {
 	State        string    `json:"state" validate:"required,max=100"`
 	PostalCode   string    `json:"postal_code" validate:"required,max=20"`
 	Country      string    `json:"country" validate:"required,len=2"`
+	AreaCode     string    `json:"area_code" validate:"max=255"` // Fills division/district/thana/area for BD; a thana or area code
 	Phone        string    `json:"phone" validate:"max=20"`
 	IsDefault    bool      `json:"is_default"`
 	Latitude     *float64  `json:"latitude"`
@@ -338,6 +388,7 @@ type UpdateAddressRequest struct {
 	State        *string   `json:"state,omitempty" validate:"omitempty,max=100"`
 	PostalCode   *string   `json:"postal_code,omitempty" validate:"omitempty,max=20"`
 	Country      *string   `json:"country,omitempty" validate:"omitempty,len=2"`
+	AreaCode     *string   `json:"area_code,omitempty" validate:"omitempty,max=255"`
 	Phone        *string   `json:"phone,omitempty" validate:"omitempty,max=20"`
 	IsDefault    *bool     `json:"is_default,omitempty"`
 	Latitude     *float64  `json:"latitude,omitempty"`
@@ -363,6 +414,11 @@ type AddressResponse struct {
 	PostalCode       string     `json:"postal_code"`
 	Country          string     `json:"country"`
 	CountryName      string     `json:"country_name"`
+	Division         string     `json:"division,omitempty"`
+	District         string     `json:"district,omitempty"`
+	Thana            string     `json:"thana,omitempty"`
+	Area             string     `json:"area,omitempty"`
+	AreaCode         string     `json:"area_code,omitempty"`
 	Phone            string     `json:"phone"`
 	IsDefault        bool       `json:"is_default"`
 	IsValidated      bool       `json:"is_validated"`
@@ -384,6 +440,7 @@ type AddressFilter struct {
 	Country     string     `json:"country,omitempty"`
 	State       string     `json:"state,omitempty"`
 	City        string     `json:"city,omitempty"`
+	AreaCode    string     `json:"area_code,omitempty"`
 	IsDefault   *bool      `json:"is_default,omitempty"`
 	IsValidated *bool      `json:"is_validated,omitempty"`
 	Search      string     `json:"search,omitempty"`
@@ -443,12 +500,17 @@ type NormalizeAddressResponse struct {
 	State      string `json:"state"`
 	PostalCode string `json:"postal_code"`
 	Country    string `json:"country"`
+	Division   string `json:"division,omitempty"`
+	District   string `json:"district,omitempty"`
+	Thana      string `json:"thana,omitempty"`
+	AreaCode   string `json:"area_code,omitempty"`
 }
 
 // AddressSuggestionRequest represents a request for address suggestions
 type AddressSuggestionRequest struct {
 	Query     string `json:"query" validate:"required"`
 	Country   string `json:"country,omitempty"`
+	Level     string `json:"level,omitempty" validate:"omitempty,oneof=division district thana area"`
 	MaxResults int   `json:"max_results,omitempty"`
 }
 
@@ -462,6 +524,13 @@ type AddressSuggestion struct {
 	State       string  `json:"state"`
 	PostalCode  string  `json:"postal_code"`
 	Country     string  `json:"country"`
+	Level       string  `json:"level,omitempty"`
+	Division    string  `json:"division,omitempty"`
+	District    string  `json:"district,omitempty"`
+	Thana       string  `json:"thana,omitempty"`
+	Area        string  `json:"area,omitempty"`
+	AreaCode    string  `json:"area_code,omitempty"`
+	BnName      string  `json:"bn_name,omitempty"`
 	Confidence  float64 `json:"confidence"`
 }
 
@@ -471,6 +540,25 @@ type AddressSuggestionResponse struct {
 	Suggestions []AddressSuggestion `json:"suggestions"`
 }
 
+// CarrierZoneMappingRequest creates or updates a carrier zone mapping
+type CarrierZoneMappingRequest struct {
+	Provider      string `json:"provider" validate:"required,max=50"`
+	GeoCode       string `json:"geo_code" validate:"required,max=255"`
+	CarrierCityID string `json:"carrier_city_id" validate:"max=50"`
+	CarrierZoneID string `json:"carrier_zone_id" validate:"max=50"`
+	CarrierAreaID string `json:"carrier_area_id" validate:"max=50"`
+	CarrierLabel  string `json:"carrier_label" validate:"max=255"`
+	IsActive      *bool  `json:"is_active,omitempty"`
+}
+
+// CarrierZoneMappingListResponse represents paginated carrier zone mappings
+type CarrierZoneMappingListResponse struct {
+	Mappings []*CarrierZoneMapping `json:"mappings"`
+	Total    int64                 `json:"total"`
+	Limit    int                   `json:"limit"`
+	Offset   int                   `json:"offset"`
+}
+
 // AddressListResponse represents paginated address list
 type AddressListResponse struct {
 	Addresses []*AddressResponse `json:"addresses"`
@@ -530,4 +618,8 @@ var (
 	ErrInvalidState            = errors.New("invalid state")
 	ErrInvalidPostalCode       = errors.New("invalid postal code")
 	ErrInvalidCountry          = errors.New("invalid country")
+	ErrUnknownAreaCode         = errors.New("unknown area code")
+	ErrAreaCodeTooBroad        = errors.New("area code must identify at least a thana")
+	ErrCarrierZoneNotFound     = errors.New("no carrier zone mapping for area")
+	ErrInvalidProvider         = errors.New("invalid provider")
 )
\ No newline at end of file
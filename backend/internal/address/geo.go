@@ -0,0 +1,308 @@
+package address
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Bangladesh geo hierarchy
+//
+// The bundled dataset covers all 8 divisions, 64 districts and their
+// upazilas, plus the metropolitan thanas of Dhaka, Chattogram, Gazipur,
+// Narayanganj, Khulna, Rajshahi and Sylhet. Areas below a thana are only
+// listed for the main metros, so an address needs at least a thana and an
+// area is optional. Codes are dot-separated slugs of the path, e.g.
+// "dhaka.dhaka.gulshan.gulshan-2", so a code also encodes its ancestors.
+
+//go:embed data/bd_geo.json
+var bdGeoData []byte
+
+// GeoLevel is the depth of a node in the hierarchy
+type GeoLevel string
+
+const (
+	GeoLevelDivision GeoLevel = "division"
+	GeoLevelDistrict GeoLevel = "district"
+	GeoLevelThana    GeoLevel = "thana"
+	GeoLevelArea     GeoLevel = "area"
+)
+
+var geoLevels = []GeoLevel{GeoLevelDivision, GeoLevelDistrict, GeoLevelThana, GeoLevelArea}
+
+// GeoLocation is a single division, district, thana or area
+type GeoLocation struct {
+	Code       string         `json:"code"`
+	Name       string         `json:"name"`
+	BnName     string         `json:"bn_name"`
+	Level      GeoLevel       `json:"level"`
+	ParentCode string         `json:"parent_code,omitempty"`
+	PostalCode string         `json:"postal_code,omitempty"`
+	Aliases    []string       `json:"aliases,omitempty"`
+	Children   []*GeoLocation `json:"children,omitempty"`
+}
+
+// GeoPath is the resolved division → area chain for a code
+type GeoPath struct {
+	Division *GeoLocation `json:"division"`
+	District *GeoLocation `json:"district,omitempty"`
+	Thana    *GeoLocation `json:"thana,omitempty"`
+	Area     *GeoLocation `json:"area,omitempty"`
+}
+
+// GeoHierarchy indexes the dataset for lookup and autocomplete
+type GeoHierarchy struct {
+	Country   string         `json:"country"`
+	Divisions []*GeoLocation `json:"divisions"`
+
+	byCode map[string]*GeoLocation
+}
+
+var (
+	bdGeoOnce      sync.Once
+	bdGeoHierarchy *GeoHierarchy
+	bdGeoErr       error
+)
+
+// BDGeo returns the bundled Bangladesh hierarchy, parsed once
+func BDGeo() (*GeoHierarchy, error) {
+	bdGeoOnce.Do(func() {
+		bdGeoHierarchy, bdGeoErr = parseGeoHierarchy(bdGeoData)
+	})
+	return bdGeoHierarchy, bdGeoErr
+}
+
+func parseGeoHierarchy(data []byte) (*GeoHierarchy, error) {
+	var h GeoHierarchy
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, err
+	}
+
+	h.byCode = make(map[string]*GeoLocation)
+	var index func(nodes []*GeoLocation, depth int, parent string) error
+	index = func(nodes []*GeoLocation, depth int, parent string) error {
+		for _, node := range nodes {
+			if parent != "" && !strings.HasPrefix(node.Code, parent+".") {
+				return fmt.Errorf("geo code %q is not under its parent %q", node.Code, parent)
+			}
+			if _, exists := h.byCode[node.Code]; exists {
+				return fmt.Errorf("duplicate geo code %q", node.Code)
+			}
+			node.Level = geoLevels[depth]
+			node.ParentCode = parent
+			h.byCode[node.Code] = node
+
+			// Every district must list its thanas, see the note above
+			if node.Level == GeoLevelDistrict && len(node.Children) == 0 {
+				return fmt.Errorf("district %q has no thanas", node.Code)
+			}
+			if depth+1 < len(geoLevels) {
+				if err := index(node.Children, depth+1, node.Code); err != nil {
+					return err
+				}
+			} else if len(node.Children) > 0 {
+				return fmt.Errorf("geo code %q is nested below area level", node.Code)
+			}
+		}
+		return nil
+	}
+	if err := index(h.Divisions, 0, ""); err != nil {
+		return nil, err
+	}
+
+	return &h, nil
+}
+
+// Get returns the node for a code
+func (h *GeoHierarchy) Get(code string) (*GeoLocation, bool) {
+	node, ok := h.byCode[strings.ToLower(strings.TrimSpace(code))]
+	return node, ok
+}
+
+// Children returns the direct children of a code; an empty code lists divisions
+func (h *GeoHierarchy) Children(code string) ([]*GeoLocation, bool) {
+	if code == "" {
+		return h.Divisions, true
+	}
+	node, ok := h.Get(code)
+	if !ok {
+		return nil, false
+	}
+	return node.Children, true
+}
+
+// Path resolves a code to its division, district, thana and area
+func (h *GeoHierarchy) Path(code string) (*GeoPath, bool) {
+	node, ok := h.Get(code)
+	if !ok {
+		return nil, false
+	}
+
+	path := &GeoPath{}
+	for current := node; current != nil; current = h.byCode[current.ParentCode] {
+		switch current.Level {
+		case GeoLevelDivision:
+			path.Division = current
+		case GeoLevelDistrict:
+			path.District = current
+		case GeoLevelThana:
+			path.Thana = current
+		case GeoLevelArea:
+			path.Area = current
+		}
+	}
+	return path, true
+}
+
+// Ancestry returns the code followed by each ancestor code, most specific first
+func (h *GeoHierarchy) Ancestry(code string) []string {
+	var codes []string
+	for node, ok := h.Get(code); ok; node, ok = h.byCode[node.ParentCode] {
+		codes = append(codes, node.Code)
+	}
+	return codes
+}
+
+// Label formats a path as "Area, Thana, District, Division"
+func (p *GeoPath) Label() string {
+	var parts []string
+	for _, node := range []*GeoLocation{p.Area, p.Thana, p.District, p.Division} {
+		if node != nil {
+			parts = append(parts, node.Name)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Leaf returns the most specific node of the path
+func (p *GeoPath) Leaf() *GeoLocation {
+	for _, node := range []*GeoLocation{p.Area, p.Thana, p.District, p.Division} {
+		if node != nil {
+			return node
+		}
+	}
+	return nil
+}
+
+// GeoMatch is an autocomplete hit with its relevance score
+type GeoMatch struct {
+	Location *GeoLocation
+	Score    float64
+}
+
+// Search matches English names, Bangla names and aliases. Prefix matches
+// rank above substring matches; more specific levels break ties so that
+// typing "gulshan" offers Gulshan 1/2 alongside the thana.
+func (h *GeoHierarchy) Search(query string, level GeoLevel, limit int) []GeoMatch {
+	query = normalizeGeoName(query)
+	if query == "" {
+		return nil
+	}
+
+	var matches []GeoMatch
+	for _, node := range h.byCode {
+		if level != "" && node.Level != level {
+			continue
+		}
+		score := matchGeoNode(node, query)
+		if score == 0 {
+			continue
+		}
+		matches = append(matches, GeoMatch{Location: node, Score: score})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Location.Code < matches[j].Location.Code
+	})
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// Match finds the node at a level whose name or alias equals the given name,
+// optionally restricted to descendants of parentCode
+func (h *GeoHierarchy) Match(level GeoLevel, name, parentCode string) (*GeoLocation, bool) {
+	name = normalizeGeoName(name)
+	if name == "" {
+		return nil, false
+	}
+
+	var candidates []*GeoLocation
+	if parentCode != "" {
+		if parent, ok := h.Get(parentCode); ok {
+			candidates = parent.Children
+		}
+	} else {
+		for _, node := range h.byCode {
+			if node.Level == level {
+				candidates = append(candidates, node)
+			}
+		}
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].Code < candidates[j].Code })
+	}
+
+	for _, node := range candidates {
+		if node.Level != level {
+			continue
+		}
+		for _, candidate := range geoNames(node) {
+			if candidate == name {
+				return node, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func matchGeoNode(node *GeoLocation, query string) float64 {
+	best := 0.0
+	for _, name := range geoNames(node) {
+		var score float64
+		switch {
+		case name == query:
+			score = 1.0
+		case strings.HasPrefix(name, query):
+			score = 0.8
+		case strings.Contains(name, query):
+			score = 0.5
+		default:
+			continue
+		}
+		if score > best {
+			best = score
+		}
+	}
+	if best == 0 {
+		return 0
+	}
+
+	// Prefer more specific levels on equal text score
+	for i, level := range geoLevels {
+		if node.Level == level {
+			best += float64(i) * 0.01
+		}
+	}
+	return best
+}
+
+func geoNames(node *GeoLocation) []string {
+	names := []string{normalizeGeoName(node.Name), normalizeGeoName(node.BnName)}
+	for _, alias := range node.Aliases {
+		names = append(names, normalizeGeoName(alias))
+	}
+	return names
+}
+
+func normalizeGeoName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.NewReplacer("'", "", ".", "", "-", " ", "_", " ").Replace(name)
+	return strings.Join(strings.Fields(name), " ")
+}
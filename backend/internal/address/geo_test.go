@@ -0,0 +1,142 @@
+package address
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBDGeoCoverage(t *testing.T) {
+	geo, err := BDGeo()
+	if err != nil {
+		t.Fatalf("BDGeo() error = %v", err)
+	}
+
+	if got := len(geo.Divisions); got != 8 {
+		t.Errorf("divisions = %d, want 8", got)
+	}
+	districts := 0
+	for _, division := range geo.Divisions {
+		for _, district := range division.Children {
+			districts++
+			if len(district.Children) == 0 {
+				t.Errorf("district %s has no thanas", district.Code)
+			}
+			for _, thana := range district.Children {
+				if thana.Level != GeoLevelThana {
+					t.Errorf("%s level = %s, want thana", thana.Code, thana.Level)
+				}
+				if thana.BnName == "" {
+					t.Errorf("%s has no Bangla name", thana.Code)
+				}
+			}
+		}
+	}
+	if districts != 64 {
+		t.Errorf("districts = %d, want 64", districts)
+	}
+}
+
+func TestParseGeoHierarchyRejectsInvalidData(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name:    "district without thanas",
+			data:    `{"divisions":[{"code":"dhaka","children":[{"code":"dhaka.dhaka"}]}]}`,
+			wantErr: "has no thanas",
+		},
+		{
+			name:    "code outside its parent",
+			data:    `{"divisions":[{"code":"dhaka","children":[{"code":"sylhet.sylhet"}]}]}`,
+			wantErr: "is not under its parent",
+		},
+		{
+			name:    "duplicate code",
+			data:    `{"divisions":[{"code":"dhaka","children":[{"code":"dhaka.dhaka","children":[{"code":"dhaka.dhaka.gulshan"},{"code":"dhaka.dhaka.gulshan"}]}]}]}`,
+			wantErr: "duplicate geo code",
+		},
+		{
+			name:    "nested below area",
+			data:    `{"divisions":[{"code":"d","children":[{"code":"d.d","children":[{"code":"d.d.t","children":[{"code":"d.d.t.a","children":[{"code":"d.d.t.a.x"}]}]}]}]}]}`,
+			wantErr: "nested below area level",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseGeoHierarchy([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseGeoHierarchy() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGeoMatchUpazila(t *testing.T) {
+	geo, err := BDGeo()
+	if err != nil {
+		t.Fatalf("BDGeo() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		parent   string
+		wantCode string
+	}{
+		{name: "by name", query: "Sreemangal", parent: "sylhet.moulvibazar", wantCode: "sylhet.moulvibazar.sreemangal"},
+		{name: "by alias", query: "Srimangal", parent: "sylhet.moulvibazar", wantCode: "sylhet.moulvibazar.sreemangal"},
+		{name: "by Bangla name", query: "শ্রীমঙ্গল", parent: "sylhet.moulvibazar", wantCode: "sylhet.moulvibazar.sreemangal"},
+		{name: "upazila outside a metro", query: "Teknaf", parent: "chattogram.cox-s-bazar", wantCode: "chattogram.cox-s-bazar.teknaf"},
+		{name: "rural upazila next to metro thanas", query: "Sitakunda", parent: "chattogram.chattogram", wantCode: "chattogram.chattogram.sitakunda"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, ok := geo.Match(GeoLevelThana, tt.query, tt.parent)
+			if !ok {
+				t.Fatalf("Match(%q) found nothing", tt.query)
+			}
+			if node.Code != tt.wantCode {
+				t.Errorf("Match(%q) = %s, want %s", tt.query, node.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestApplyAreaCode(t *testing.T) {
+	tests := []struct {
+		name         string
+		code         string
+		wantErr      error
+		wantDistrict string
+		wantThana    string
+	}{
+		{name: "thana", code: "khulna.bagerhat.mongla", wantDistrict: "Bagerhat", wantThana: "Mongla"},
+		{name: "area", code: "dhaka.dhaka.gulshan.gulshan-2", wantDistrict: "Dhaka", wantThana: "Gulshan"},
+		{name: "district is too broad", code: "khulna.bagerhat", wantErr: ErrAreaCodeTooBroad},
+		{name: "unknown code", code: "khulna.bagerhat.nowhere", wantErr: ErrUnknownAreaCode},
+	}
+
+	s := &ServiceImpl{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address := &Address{}
+			err := s.applyAreaCode(address, tt.code)
+			if err != tt.wantErr {
+				t.Fatalf("applyAreaCode() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if address.District != tt.wantDistrict || address.Thana != tt.wantThana {
+				t.Errorf("district, thana = %q, %q, want %q, %q", address.District, address.Thana, tt.wantDistrict, tt.wantThana)
+			}
+			if address.AreaCode != tt.code {
+				t.Errorf("AreaCode = %q, want %q", address.AreaCode, tt.code)
+			}
+		})
+	}
+}
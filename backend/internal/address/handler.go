@@ -2,6 +2,7 @@ package address
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -47,6 +48,43 @@ func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 		validations.GET("", h.ListAddressValidations)        // ListAddressValidations
 		validations.DELETE("/cleanup", h.CleanupOperations)  // CleanupOperations (orphaned validations)
 	}
+	
+	// 📍 GEO HIERARCHY (3)
+	geo := router.Group("/address-geo")
+	{
+		geo.GET("/divisions", h.GetGeoDivisions)             // GetGeoDivisions
+		geo.GET("/:code", h.GetGeoPath)                      // GetGeoPath
+		geo.GET("/:code/children", h.GetGeoChildren)         // GetGeoChildren
+	}
+	
+	// 📍 CARRIER ZONE MAPPINGS (4)
+	zones := router.Group("/carrier-zones")
+	{
+		zones.POST("", h.UpsertCarrierZoneMappings)          // UpsertCarrierZoneMappings (single or array body)
+		zones.GET("", h.ListCarrierZoneMappings)             // ListCarrierZoneMappings
+		zones.GET("/resolve", h.ResolveCarrierZone)          // ResolveCarrierZone
+		zones.DELETE("/:id", h.DeleteCarrierZoneMapping)     // DeleteCarrierZoneMapping
+	}
+}
+
+// platformScopeKey marks requests that manage the platform default carrier
+// zone mappings instead of the tenant's own
+const platformScopeKey = "address_platform_scope"
+
+// RegisterPlatformRoutes registers the routes managing the platform default
+// carrier zone mappings, which every tenant without its own mapping uses.
+// The router must only admit platform administrators.
+func (h *Handler) RegisterPlatformRoutes(router *gin.RouterGroup) {
+	zones := router.Group("/platform/carrier-zones")
+	zones.Use(func(c *gin.Context) {
+		c.Set(platformScopeKey, true)
+		c.Next()
+	})
+	{
+		zones.POST("", h.UpsertCarrierZoneMappings)          // UpsertCarrierZoneMappings (single or array body)
+		zones.GET("", h.ListCarrierZoneMappings)             // ListCarrierZoneMappings
+		zones.DELETE("/:id", h.DeleteCarrierZoneMapping)     // DeleteCarrierZoneMapping
+	}
 }
 
 // Address CRUD operations
@@ -597,6 +635,148 @@ func (h *Handler) SuggestAddresses(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// Geo hierarchy handler methods
+
+// GetGeoDivisions lists Bangladesh divisions
+func (h *Handler) GetGeoDivisions(c *gin.Context) {
+	ctx := c.Request.Context()
+	divisions, err := h.service.GetGeoChildren(ctx, "")
+	if err != nil {
+		h.handleServiceError(c, err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": divisions})
+}
+
+// GetGeoChildren lists the districts, thanas or areas under a geo code
+func (h *Handler) GetGeoChildren(c *gin.Context) {
+	ctx := c.Request.Context()
+	children, err := h.service.GetGeoChildren(ctx, c.Param("code"))
+	if err != nil {
+		h.handleServiceError(c, err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": children})
+}
+
+// GetGeoPath resolves a geo code to its full division → area path
+func (h *Handler) GetGeoPath(c *gin.Context) {
+	ctx := c.Request.Context()
+	path, err := h.service.GetGeoPath(ctx, c.Param("code"))
+	if err != nil {
+		h.handleServiceError(c, err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": path})
+}
+
+// Carrier zone mapping handler methods
+
+// UpsertCarrierZoneMappings creates or updates one or many carrier zone mappings
+func (h *Handler) UpsertCarrierZoneMappings(c *gin.Context) {
+	ctx := c.Request.Context()
+	tenantID, err := h.zoneTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tenant ID", "details": err.Error()})
+		return
+	}
+	
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	
+	var requests []CarrierZoneMappingRequest
+	if err := json.Unmarshal(body, &requests); err != nil {
+		var single CarrierZoneMappingRequest
+		if err := json.Unmarshal(body, &single); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+			return
+		}
+		requests = []CarrierZoneMappingRequest{single}
+	}
+	
+	mappings, err := h.service.UpsertCarrierZoneMappings(ctx, tenantID, requests)
+	if err != nil {
+		h.handleServiceError(c, err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": mappings})
+}
+
+// ListCarrierZoneMappings lists carrier zone mappings, filtered by ?provider=
+func (h *Handler) ListCarrierZoneMappings(c *gin.Context) {
+	ctx := c.Request.Context()
+	tenantID, err := h.zoneTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tenant ID", "details": err.Error()})
+		return
+	}
+	
+	limit, offset := h.parsePagination(c)
+	
+	response, err := h.service.ListCarrierZoneMappings(ctx, tenantID, c.Query("provider"), limit, offset)
+	if err != nil {
+		h.handleServiceError(c, err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, response)
+}
+
+// ResolveCarrierZone returns the courier IDs for ?provider=&area_code=
+func (h *Handler) ResolveCarrierZone(c *gin.Context) {
+	ctx := c.Request.Context()
+	tenantID, err := h.getTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tenant ID", "details": err.Error()})
+		return
+	}
+	
+	provider := c.Query("provider")
+	areaCode := c.Query("area_code")
+	if provider == "" || areaCode == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "provider and area_code are required"})
+		return
+	}
+	
+	mapping, err := h.service.ResolveCarrierZone(ctx, tenantID, provider, areaCode)
+	if err != nil {
+		h.handleServiceError(c, err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": mapping})
+}
+
+// DeleteCarrierZoneMapping deletes a carrier zone mapping
+func (h *Handler) DeleteCarrierZoneMapping(c *gin.Context) {
+	ctx := c.Request.Context()
+	tenantID, err := h.zoneTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tenant ID", "details": err.Error()})
+		return
+	}
+	
+	mappingID, err := h.getUUIDParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mapping ID", "details": err.Error()})
+		return
+	}
+	
+	if err := h.service.DeleteCarrierZoneMapping(ctx, tenantID, mappingID); err != nil {
+		h.handleServiceError(c, err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"message": "Carrier zone mapping deleted successfully"})
+}
+
 // Statistics handler methods
 
 // GetAddressStats retrieves address statistics
@@ -692,6 +872,16 @@ func (h *Handler) getTenantID(c *gin.Context) (uuid.UUID, error) {
 	return uuid.Parse(tenantIDStr)
 }
 
+// zoneTenantID returns the tenant whose carrier zone mappings a request
+// manages: the platform defaults, stored under the nil tenant, on platform
+// routes
+func (h *Handler) zoneTenantID(c *gin.Context) (uuid.UUID, error) {
+	if c.GetBool(platformScopeKey) {
+		return uuid.Nil, nil
+	}
+	return h.getTenantID(c)
+}
+
 // getUUIDParam extracts UUID parameter from URL
 func (h *Handler) getUUIDParam(c *gin.Context, param string) (uuid.UUID, error) {
 	idStr := c.Param(param)
@@ -732,6 +922,10 @@ func (h *Handler) parseAddressFilter(c *gin.Context) AddressFilter {
 		filter.City = city
 	}
 	
+	if areaCode := c.Query("area_code"); areaCode != "" {
+		filter.AreaCode = strings.ToLower(areaCode)
+	}
+	
 	if isDefaultStr := c.Query("is_default"); isDefaultStr != "" {
 		if isDefault, err := strconv.ParseBool(isDefaultStr); err == nil {
 			filter.IsDefault = &isDefault
@@ -804,6 +998,14 @@ func (h *Handler) handleServiceError(c *gin.Context, err error) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid postal code", "details": err.Error()})
 	case err == ErrInvalidCountry:
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid country", "details": err.Error()})
+	case err == ErrUnknownAreaCode:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown area code", "details": err.Error()})
+	case err == ErrAreaCodeTooBroad:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Area code too broad", "details": err.Error()})
+	case err == ErrInvalidProvider:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid provider", "details": err.Error()})
+	case err == ErrCarrierZoneNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Carrier zone mapping not found", "details": err.Error()})
 	case strings.Contains(err.Error(), "validation failed"):
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
 	default:
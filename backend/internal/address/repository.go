@@ -50,6 +50,12 @@ type Repository interface {
 	// Maintenance operations
 	CleanupUnvalidatedAddresses(ctx context.Context, tenantID uuid.UUID, days int) (int64, error)
 	CleanupOrphanedValidations(ctx context.Context, tenantID uuid.UUID) (int64, error)
+	
+	// Carrier zone mapping operations
+	UpsertCarrierZoneMapping(ctx context.Context, mapping *CarrierZoneMapping) error
+	DeleteCarrierZoneMapping(ctx context.Context, tenantID, mappingID uuid.UUID) error
+	ListCarrierZoneMappings(ctx context.Context, tenantID uuid.UUID, provider string, limit, offset int) ([]*CarrierZoneMapping, int64, error)
+	FindCarrierZoneMappings(ctx context.Context, tenantID uuid.UUID, provider string, geoCodes []string) ([]*CarrierZoneMapping, error)
 }
 
 // GormRepository implements Repository using GORM
@@ -529,6 +535,80 @@ func (r *GormRepository) CleanupOrphanedValidations(ctx context.Context, tenantI
 	return result.RowsAffected, result.Error
 }
 
+// Carrier zone mapping operations
+
+// UpsertCarrierZoneMapping creates or replaces the mapping for a tenant, provider and geo code
+func (r *GormRepository) UpsertCarrierZoneMapping(ctx context.Context, mapping *CarrierZoneMapping) error {
+	var existing CarrierZoneMapping
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND provider = ? AND geo_code = ?", mapping.TenantID, mapping.Provider, mapping.GeoCode).
+		First(&existing).Error
+	
+	if err == gorm.ErrRecordNotFound {
+		// Select every column so an inactive mapping isn't given the
+		// is_active default
+		if mapping.ID == uuid.Nil {
+			mapping.ID = uuid.New()
+		}
+		return r.db.WithContext(ctx).Select("*").Create(mapping).Error
+	} else if err != nil {
+		return err
+	}
+	
+	mapping.ID = existing.ID
+	mapping.CreatedAt = existing.CreatedAt
+	return r.db.WithContext(ctx).Save(mapping).Error
+}
+
+// DeleteCarrierZoneMapping deletes a tenant's carrier zone mapping
+func (r *GormRepository) DeleteCarrierZoneMapping(ctx context.Context, tenantID, mappingID uuid.UUID) error {
+	result := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND id = ?", tenantID, mappingID).
+		Delete(&CarrierZoneMapping{})
+	
+	if result.Error != nil {
+		return result.Error
+	}
+	
+	if result.RowsAffected == 0 {
+		return ErrCarrierZoneNotFound
+	}
+	
+	return nil
+}
+
+// ListCarrierZoneMappings lists a tenant's mappings, optionally for one provider
+func (r *GormRepository) ListCarrierZoneMappings(ctx context.Context, tenantID uuid.UUID, provider string, limit, offset int) ([]*CarrierZoneMapping, int64, error) {
+	var mappings []*CarrierZoneMapping
+	var total int64
+	
+	query := r.db.WithContext(ctx).Model(&CarrierZoneMapping{}).Where("tenant_id = ?", tenantID)
+	if provider != "" {
+		query = query.Where("provider = ?", provider)
+	}
+	
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	err := query.Order("provider ASC, geo_code ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&mappings).Error
+	
+	return mappings, total, err
+}
+
+// FindCarrierZoneMappings returns active tenant and platform-default mappings for the given geo codes
+func (r *GormRepository) FindCarrierZoneMappings(ctx context.Context, tenantID uuid.UUID, provider string, geoCodes []string) ([]*CarrierZoneMapping, error) {
+	var mappings []*CarrierZoneMapping
+	err := r.db.WithContext(ctx).
+		Where("tenant_id IN ? AND provider = ? AND geo_code IN ? AND is_active = ?", []uuid.UUID{tenantID, uuid.Nil}, provider, geoCodes, true).
+		Find(&mappings).Error
+	
+	return mappings, err
+}
+
 // Helper methods
 
 // applyAddressFilters applies filters to the query
@@ -557,6 +637,11 @@ func (r *GormRepository) applyAddressFilters(query *gorm.DB, filter AddressFilte
 		query = query.Where("city ILIKE ?", "%"+filter.City+"%")
 	}
 	
+	if filter.AreaCode != "" {
+		// Codes encode their ancestors, so a prefix match covers the whole subtree
+		query = query.Where("area_code = ? OR area_code LIKE ?", filter.AreaCode, filter.AreaCode+".%")
+	}
+	
 	if filter.IsDefault != nil {
 		query = query.Where("is_default = ?", *filter.IsDefault)
 	}
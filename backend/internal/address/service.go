@@ -55,6 +55,16 @@ type Service interface {
 	// Utility operations
 	NormalizeAddress(ctx context.Context, req NormalizeAddressRequest) (*NormalizeAddressResponse, error)
 	SuggestAddresses(ctx context.Context, req AddressSuggestionRequest) (*AddressSuggestionResponse, error)
+	
+	// Geo hierarchy operations
+	GetGeoChildren(ctx context.Context, code string) ([]*GeoLocation, error)
+	GetGeoPath(ctx context.Context, code string) (*GeoPath, error)
+	
+	// Carrier zone mapping operations
+	UpsertCarrierZoneMappings(ctx context.Context, tenantID uuid.UUID, requests []CarrierZoneMappingRequest) ([]*CarrierZoneMapping, error)
+	DeleteCarrierZoneMapping(ctx context.Context, tenantID, mappingID uuid.UUID) error
+	ListCarrierZoneMappings(ctx context.Context, tenantID uuid.UUID, provider string, limit, offset int) (*CarrierZoneMappingListResponse, error)
+	ResolveCarrierZone(ctx context.Context, tenantID uuid.UUID, provider, geoCode string) (*CarrierZoneMapping, error)
 }
 
 // ServiceImpl implements the Service interface
@@ -113,6 +123,13 @@ func (s *ServiceImpl) CreateAddress(ctx context.Context, tenantID uuid.UUID, req
 		UpdatedAt:  time.Now(),
 	}
 	
+	// Structured BD addresses take city/state from the geo hierarchy
+	if req.AreaCode != "" {
+		if err := s.applyAreaCode(address, req.AreaCode); err != nil {
+			return nil, err
+		}
+	}
+	
 	// If this is set as default, unset other defaults
 	if req.IsDefault {
 		if err := s.repo.UnsetDefaultAddresses(ctx, tenantID, req.CustomerID, req.Type); err != nil {
@@ -188,6 +205,13 @@ func (s *ServiceImpl) UpdateAddress(ctx context.Context, tenantID, addressID uui
 	if req.Phone != nil {
 		address.Phone = *req.Phone
 	}
+	if req.AreaCode != nil {
+		if *req.AreaCode == "" {
+			address.Division, address.District, address.Thana, address.Area, address.AreaCode = "", "", "", "", ""
+		} else if err := s.applyAreaCode(address, *req.AreaCode); err != nil {
+			return nil, err
+		}
+	}
 	if req.IsDefault != nil {
 		address.IsDefault = *req.IsDefault
 		
@@ -568,21 +592,306 @@ func (s *ServiceImpl) NormalizeAddress(ctx context.Context, req NormalizeAddress
 		Country:    strings.TrimSpace(strings.ToUpper(req.Country)),
 	}
 	
+	// Bangladesh addresses are matched against the geo hierarchy so that
+	// legacy spellings (Chittagong, Comilla, Bogra) map to current names
+	if normalized.Country == "BD" {
+		s.normalizeBDAddress(&normalized, req)
+	}
+	
 	return &normalized, nil
 }
 
-// SuggestAddresses provides address suggestions
+// SuggestAddresses autocompletes divisions, districts, thanas and areas
 func (s *ServiceImpl) SuggestAddresses(ctx context.Context, req AddressSuggestionRequest) (*AddressSuggestionResponse, error) {
-	// This would typically call an external address suggestion service
-	// For now, we'll return an empty response
-	
-	return &AddressSuggestionResponse{
+	response := &AddressSuggestionResponse{
+		Query:       req.Query,
 		Suggestions: []AddressSuggestion{},
+	}
+	
+	// Only the Bangladesh hierarchy is bundled for now
+	if req.Country != "" && strings.ToUpper(req.Country) != "BD" {
+		return response, nil
+	}
+	
+	geo, err := BDGeo()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load geo hierarchy: %w", err)
+	}
+	
+	limit := req.MaxResults
+	if limit <= 0 || limit > MaxPageSize {
+		limit = 10
+	}
+	
+	for _, match := range geo.Search(req.Query, GeoLevel(req.Level), limit) {
+		path, _ := geo.Path(match.Location.Code)
+		response.Suggestions = append(response.Suggestions, buildGeoSuggestion(path, match))
+	}
+	
+	return response, nil
+}
+
+// Geo hierarchy operations
+
+// GetGeoChildren lists the children of a geo code; an empty code lists divisions
+func (s *ServiceImpl) GetGeoChildren(ctx context.Context, code string) ([]*GeoLocation, error) {
+	geo, err := BDGeo()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load geo hierarchy: %w", err)
+	}
+	
+	children, ok := geo.Children(code)
+	if !ok {
+		return nil, ErrUnknownAreaCode
+	}
+	
+	// Return one level only; clients walk down the tree as the user selects
+	result := make([]*GeoLocation, len(children))
+	for i, child := range children {
+		flat := *child
+		flat.Children = nil
+		result[i] = &flat
+	}
+	return result, nil
+}
+
+// GetGeoPath resolves a geo code to its division, district, thana and area
+func (s *ServiceImpl) GetGeoPath(ctx context.Context, code string) (*GeoPath, error) {
+	geo, err := BDGeo()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load geo hierarchy: %w", err)
+	}
+	
+	path, ok := geo.Path(code)
+	if !ok {
+		return nil, ErrUnknownAreaCode
+	}
+	return path, nil
+}
+
+// Carrier zone mapping operations
+
+// UpsertCarrierZoneMappings creates or updates carrier zone mappings for a tenant
+func (s *ServiceImpl) UpsertCarrierZoneMappings(ctx context.Context, tenantID uuid.UUID, requests []CarrierZoneMappingRequest) ([]*CarrierZoneMapping, error) {
+	if len(requests) > MaxBulkSize {
+		return nil, ErrBulkSizeExceeded
+	}
+	
+	geo, err := BDGeo()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load geo hierarchy: %w", err)
+	}
+	
+	mappings := make([]*CarrierZoneMapping, 0, len(requests))
+	for _, req := range requests {
+		provider := strings.ToLower(strings.TrimSpace(req.Provider))
+		if provider == "" {
+			return nil, ErrInvalidProvider
+		}
+		
+		node, ok := geo.Get(req.GeoCode)
+		if !ok {
+			return nil, ErrUnknownAreaCode
+		}
+		
+		mapping := &CarrierZoneMapping{
+			TenantID:      tenantID,
+			Provider:      provider,
+			GeoCode:       node.Code,
+			CarrierCityID: strings.TrimSpace(req.CarrierCityID),
+			CarrierZoneID: strings.TrimSpace(req.CarrierZoneID),
+			CarrierAreaID: strings.TrimSpace(req.CarrierAreaID),
+			CarrierLabel:  strings.TrimSpace(req.CarrierLabel),
+			IsActive:      true,
+		}
+		if req.IsActive != nil {
+			mapping.IsActive = *req.IsActive
+		}
+		
+		if err := s.repo.UpsertCarrierZoneMapping(ctx, mapping); err != nil {
+			return nil, fmt.Errorf("failed to save carrier zone mapping: %w", err)
+		}
+		mappings = append(mappings, mapping)
+	}
+	
+	return mappings, nil
+}
+
+// DeleteCarrierZoneMapping deletes a tenant's carrier zone mapping
+func (s *ServiceImpl) DeleteCarrierZoneMapping(ctx context.Context, tenantID, mappingID uuid.UUID) error {
+	return s.repo.DeleteCarrierZoneMapping(ctx, tenantID, mappingID)
+}
+
+// ListCarrierZoneMappings lists a tenant's carrier zone mappings
+func (s *ServiceImpl) ListCarrierZoneMappings(ctx context.Context, tenantID uuid.UUID, provider string, limit, offset int) (*CarrierZoneMappingListResponse, error) {
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	
+	mappings, total, err := s.repo.ListCarrierZoneMappings(ctx, tenantID, strings.ToLower(provider), limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list carrier zone mappings: %w", err)
+	}
+	
+	return &CarrierZoneMappingListResponse{
+		Mappings: mappings,
+		Total:    total,
+		Limit:    limit,
+		Offset:   offset,
 	}, nil
 }
 
+// ResolveCarrierZone finds the courier IDs for a geo code. The most specific
+// mapped ancestor wins (an unmapped area falls back to its thana), and a
+// tenant mapping overrides the platform default at the same level.
+func (s *ServiceImpl) ResolveCarrierZone(ctx context.Context, tenantID uuid.UUID, provider, geoCode string) (*CarrierZoneMapping, error) {
+	geo, err := BDGeo()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load geo hierarchy: %w", err)
+	}
+	
+	ancestry := geo.Ancestry(geoCode)
+	if len(ancestry) == 0 {
+		return nil, ErrUnknownAreaCode
+	}
+	
+	mappings, err := s.repo.FindCarrierZoneMappings(ctx, tenantID, strings.ToLower(provider), ancestry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find carrier zone mappings: %w", err)
+	}
+	
+	for _, code := range ancestry {
+		var platformDefault *CarrierZoneMapping
+		for _, mapping := range mappings {
+			if mapping.GeoCode != code {
+				continue
+			}
+			if mapping.TenantID == tenantID {
+				return mapping, nil
+			}
+			platformDefault = mapping
+		}
+		if platformDefault != nil {
+			return platformDefault, nil
+		}
+	}
+	
+	return nil, ErrCarrierZoneNotFound
+}
+
 // Helper methods
 
+// applyAreaCode resolves a geo code and copies the hierarchy onto the address
+func (s *ServiceImpl) applyAreaCode(address *Address, code string) error {
+	geo, err := BDGeo()
+	if err != nil {
+		return fmt.Errorf("failed to load geo hierarchy: %w", err)
+	}
+	
+	path, ok := geo.Path(code)
+	if !ok {
+		return ErrUnknownAreaCode
+	}
+	if path.Thana == nil {
+		return ErrAreaCodeTooBroad
+	}
+	
+	address.Country = "BD"
+	address.ApplyGeoPath(path)
+	return nil
+}
+
+// normalizeBDAddress matches free-text state/city against divisions, districts and thanas
+func (s *ServiceImpl) normalizeBDAddress(normalized *NormalizeAddressResponse, req NormalizeAddressRequest) {
+	geo, err := BDGeo()
+	if err != nil {
+		return
+	}
+	
+	var division, district, thana *GeoLocation
+	if node, ok := geo.Match(GeoLevelDivision, req.State, ""); ok {
+		division = node
+	}
+	
+	parent := ""
+	if division != nil {
+		parent = division.Code
+	}
+	if node, ok := geo.Match(GeoLevelDistrict, req.City, parent); ok {
+		district = node
+	} else if node, ok := geo.Match(GeoLevelDistrict, req.City, ""); ok {
+		district = node
+	}
+	
+	// Customers often type the thana as the city
+	if district == nil {
+		if node, ok := geo.Match(GeoLevelThana, req.City, ""); ok {
+			thana = node
+			district, _ = geo.Get(node.ParentCode)
+		}
+	} else if node, ok := geo.Match(GeoLevelThana, req.Address2, district.Code); ok {
+		thana = node
+	}
+	
+	if district != nil && (division == nil || division.Code != district.ParentCode) {
+		division, _ = geo.Get(district.ParentCode)
+	}
+	
+	if division != nil {
+		normalized.Division = division.Name
+		normalized.State = division.Name
+		normalized.AreaCode = division.Code
+	}
+	if district != nil {
+		normalized.District = district.Name
+		normalized.City = district.Name
+		normalized.AreaCode = district.Code
+	}
+	if thana != nil {
+		normalized.Thana = thana.Name
+		normalized.AreaCode = thana.Code
+		if normalized.PostalCode == "" {
+			normalized.PostalCode = thana.PostalCode
+		}
+	}
+}
+
+// buildGeoSuggestion converts a geo match into an address suggestion
+func buildGeoSuggestion(path *GeoPath, match GeoMatch) AddressSuggestion {
+	suggestion := AddressSuggestion{
+		ID:          match.Location.Code,
+		Description: path.Label(),
+		Country:     "BD",
+		Level:       string(match.Location.Level),
+		AreaCode:    match.Location.Code,
+		BnName:      match.Location.BnName,
+		Confidence:  match.Score,
+	}
+	if suggestion.Confidence > 1 {
+		suggestion.Confidence = 1
+	}
+	
+	suggestion.Division = path.Division.Name
+	suggestion.State = path.Division.Name
+	if path.District != nil {
+		suggestion.District = path.District.Name
+		suggestion.City = path.District.Name
+	}
+	if path.Thana != nil {
+		suggestion.Thana = path.Thana.Name
+		suggestion.PostalCode = path.Thana.PostalCode
+	}
+	if path.Area != nil {
+		suggestion.Area = path.Area.Name
+		suggestion.Address2 = path.Area.Name
+	}
+	
+	return suggestion
+}
+
 // validateCreateAddressRequest validates create address request
 func (s *ServiceImpl) validateCreateAddressRequest(req CreateAddressRequest) error {
 	if req.CustomerID == uuid.Nil {
@@ -609,6 +918,11 @@ func (s *ServiceImpl) validateCreateAddressRequest(req CreateAddressRequest) err
 		return ErrInvalidAddress
 	}
 	
+	// City, state and country are derived from the area code when present
+	if req.AreaCode != "" {
+		return nil
+	}
+	
 	if strings.TrimSpace(req.City) == "" {
 		return ErrInvalidCity
 	}
@@ -670,7 +984,7 @@ func (s *ServiceImpl) validateUpdateAddressRequest(req UpdateAddressRequest) err
 // addressDetailsChanged checks if address details changed
 func (s *ServiceImpl) addressDetailsChanged(req UpdateAddressRequest) bool {
 	return req.Address1 != nil || req.Address2 != nil || req.City != nil ||
-		req.State != nil || req.PostalCode != nil || req.Country != nil
+		req.State != nil || req.PostalCode != nil || req.Country != nil || req.AreaCode != nil
 }
 
 // buildAddressResponse builds address response
@@ -689,6 +1003,11 @@ func (s *ServiceImpl) buildAddressResponse(address *Address) *AddressResponse {
 		State:           address.State,
 		PostalCode:      address.PostalCode,
 		Country:         address.Country,
+		Division:        address.Division,
+		District:        address.District,
+		Thana:           address.Thana,
+		Area:            address.Area,
+		AreaCode:        address.AreaCode,
 		Phone:           address.Phone,
 		IsDefault:       address.IsDefault,
 		IsValidated:     address.IsValidated,
@@ -7,6 +7,9 @@ import (
 	"github.com/google/uuid"
 )
 
+// PaymentMethodCOD is cash on delivery, collected by the carrier
+const PaymentMethodCOD = "cod"
+
 // CartStatus represents the status of a cart
 type CartStatus string
 
@@ -41,12 +44,20 @@ type Cart struct {
 	ShippingAddress  *Address   `json:"shipping_address,omitempty" gorm:"embedded;embeddedPrefix:shipping_"`
 	BillingAddress   *Address   `json:"billing_address,omitempty" gorm:"embedded;embeddedPrefix:billing_"`
 	
+	// Payment method chosen at checkout or for an estimate, not stored. Cash
+	// on delivery has carriers quote their collection fee.
+	PaymentMethod string `json:"-" gorm:"-"`
+	
 	// Cart metadata
 	Currency     string `json:"currency" gorm:"default:USD"`
 	Notes        string `json:"notes,omitempty"`
 	AbandonedAt  *time.Time `json:"abandoned_at,omitempty"`
 	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
 	
+	// Catalog changes found since items were added, awaiting acknowledgement
+	PendingChanges []CartItemChange `json:"pending_changes,omitempty" gorm:"serializer:json"`
+	ValidatedAt    *time.Time       `json:"validated_at,omitempty"`
+	
 	// Timestamps
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
@@ -78,12 +89,51 @@ type CartItem struct {
 	// Item metadata
 	Customizations map[string]interface{} `json:"customizations,omitempty" gorm:"serializer:json"`
 	Notes          string                 `json:"notes,omitempty"`
+	GiftCard       *GiftCardDetails       `json:"gift_card,omitempty" gorm:"serializer:json"` // Set for gift card products
 	
 	// Timestamps
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// GiftCardDetails is what the buyer chose for a gift card product: the amount
+// and who receives the card, when and with what message
+type GiftCardDetails struct {
+	Amount         float64    `json:"amount" validate:"required,gt=0"`
+	RecipientName  string     `json:"recipient_name" validate:"required,max=100"`
+	RecipientEmail string     `json:"recipient_email" validate:"required,email"`
+	SenderName     string     `json:"sender_name,omitempty" validate:"max=100"`
+	Message        string     `json:"message,omitempty" validate:"max=500"`
+	SendAt         *time.Time `json:"send_at,omitempty"` // Delivered straight away when empty
+}
+
+// ChangeType describes how a cart item differs from the current catalog
+type ChangeType string
+
+const (
+	ChangePriceIncreased  ChangeType = "price_increased"
+	ChangePriceDecreased  ChangeType = "price_decreased"
+	ChangeUnavailable     ChangeType = "unavailable"
+	ChangeOutOfStock      ChangeType = "out_of_stock"
+	ChangeQuantityReduced ChangeType = "quantity_reduced"
+)
+
+// CartItemChange is a warning raised when a cart item is re-validated
+type CartItemChange struct {
+	Type        ChangeType `json:"type"`
+	ItemID      uuid.UUID  `json:"item_id"`
+	ProductID   uuid.UUID  `json:"product_id"`
+	VariantID   *uuid.UUID `json:"variant_id,omitempty"`
+	ProductName string     `json:"product_name"`
+	OldPrice    float64    `json:"old_price,omitempty"`
+	NewPrice    float64    `json:"new_price,omitempty"`
+	OldQuantity int        `json:"old_quantity,omitempty"`
+	NewQuantity int        `json:"new_quantity"`
+	Adjusted    bool       `json:"adjusted"` // Whether the cart was already updated
+	Message     string     `json:"message"`
+	DetectedAt  time.Time  `json:"detected_at"`
+}
+
 // Address represents shipping/billing address
 type Address struct {
 	FirstName   string `json:"first_name,omitempty"`
@@ -96,6 +146,7 @@ type Address struct {
 	PostalCode  string `json:"postal_code,omitempty"`
 	Country     string `json:"country,omitempty"`
 	Phone       string `json:"phone,omitempty"`
+	AreaCode    string `json:"area_code,omitempty"` // Geo code from the address hierarchy
 }
 
 // Business Logic Errors
@@ -109,6 +160,9 @@ var (
 	ErrProductNotFound  = errors.New("product not found")
 	ErrInsufficientStock = errors.New("insufficient stock")
 	ErrInvalidCoupon    = errors.New("invalid or expired coupon")
+	ErrChangesNotAcknowledged = errors.New("cart items have changed; review and acknowledge the changes before checkout")
+	ErrGiftCardDetailsRequired = errors.New("gift card recipient details are required")
+	ErrInvalidGiftCardAmount   = errors.New("gift card amount is not one of the offered denominations")
 )
 
 // Business Logic Methods for Cart
@@ -200,6 +254,17 @@ func (c *Cart) MarkAsConverted() {
 	c.Status = StatusConverted
 }
 
+// HasPendingChanges reports whether checkout must wait for the customer to
+// acknowledge item changes
+func (c *Cart) HasPendingChanges() bool {
+	for _, change := range c.PendingChanges {
+		if change.RequiresAcknowledgement() {
+			return true
+		}
+	}
+	return false
+}
+
 // SetExpiration sets cart expiration time
 func (c *Cart) SetExpiration(duration time.Duration) {
 	expiresAt := time.Now().Add(duration)
@@ -239,6 +304,12 @@ func (ci *CartItem) GetDiscountPercentage() float64 {
 	return ((ci.ComparePrice - ci.Price) / ci.ComparePrice) * 100
 }
 
+// RequiresAcknowledgement reports whether the change is unfavourable to the
+// customer; price drops are applied without asking
+func (cc CartItemChange) RequiresAcknowledgement() bool {
+	return cc.Type != ChangePriceDecreased
+}
+
 // Helper functions
 
 // GetUUIDValue safely gets UUID value from pointer
@@ -0,0 +1,76 @@
+package cart
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"ecommerce-saas/internal/discount"
+)
+
+// DiscountAdapter exposes discount code validation through the cart
+// DiscountService interface
+type DiscountAdapter struct {
+	service discount.Service
+}
+
+// NewDiscountAdapter creates a cart DiscountService backed by discount.Service
+func NewDiscountAdapter(service discount.Service) *DiscountAdapter {
+	return &DiscountAdapter{service: service}
+}
+
+// ValidateCoupon checks a code can be used on an order of the given total
+func (a *DiscountAdapter) ValidateCoupon(tenantID uuid.UUID, couponCode string, cartTotal float64) (*CouponInfo, error) {
+	validation, err := a.service.ValidateDiscountCode(context.Background(), discount.ValidateDiscountRequest{
+		TenantID:     tenantID,
+		Code:         couponCode,
+		OrderAmount:  cartTotal,
+		ItemQuantity: 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !validation.Valid {
+		return nil, errors.New(validation.Message)
+	}
+
+	d := validation.Discount
+	info := &CouponInfo{
+		Code:         couponCode,
+		DiscountType: string(d.Type),
+		Value:        d.Value,
+	}
+	if d.MinOrderAmount != nil {
+		info.MinAmount = *d.MinOrderAmount
+	}
+	if d.ExpiresAt != nil {
+		info.ExpiresAt = *d.ExpiresAt
+	}
+	return info, nil
+}
+
+// CalculateDiscount returns what the code takes off the cart's items
+func (a *DiscountAdapter) CalculateDiscount(tenantID uuid.UUID, cart *Cart, couponCode string) (float64, error) {
+	productIDs := make([]string, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		productIDs = append(productIDs, item.ProductID.String())
+	}
+
+	validation, err := a.service.ValidateDiscountCode(context.Background(), discount.ValidateDiscountRequest{
+		TenantID:     tenantID,
+		Code:         couponCode,
+		CustomerID:   cart.CustomerID,
+		OrderAmount:  cart.CalculateSubtotal(),
+		ItemQuantity: cart.GetItemCount(),
+		ProductIDs:   productIDs,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if !validation.Valid {
+		return 0, errors.New(validation.Message)
+	}
+
+	return validation.DiscountAmount, nil
+}
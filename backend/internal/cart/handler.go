@@ -29,10 +29,12 @@ func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 	router.PATCH("/cart/items/:id", h.UpdateCartItem) // PATCH /cart/items/:id
 	router.PATCH("/cart", h.UpdateCart)               // PATCH /cart
 	router.POST("/cart/estimates", h.GetEstimates)    // POST /cart/estimates
+	router.POST("/cart/acknowledge", h.AcknowledgeChanges) // POST /cart/acknowledge
 	
 	// Guest Cart & Checkout (3 endpoints)
 	router.GET("/cart/guest", h.GetGuestCart)         // GET /cart/guest
 	router.PATCH("/cart/guest", h.UpdateGuestCart)   // PATCH /cart/guest
+	router.POST("/cart/guest/acknowledge", h.AcknowledgeGuestChanges) // POST /cart/guest/acknowledge
 	router.POST("/checkout/guest", h.ProcessGuestCheckout) // POST /checkout/guest
 }
 
@@ -154,6 +156,15 @@ func (h *Handler) GetCart(c *gin.Context) {
 		}
 	}
 
+	// ?adjust=true applies catalog changes instead of only reporting them
+	if c.Query("adjust") == "true" {
+		cart, err = h.service.RevalidateCart(tenantID.(uuid.UUID), cart.ID, true)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revalidate cart"})
+			return
+		}
+	}
+
 	// Handle include options
 	response := gin.H{"data": cart}
 	if strings.Contains(include, "summary") {
@@ -320,6 +331,15 @@ func (h *Handler) GetGuestCart(c *gin.Context) {
 		}
 	}
 
+	// ?adjust=true applies catalog changes instead of only reporting them
+	if c.Query("adjust") == "true" {
+		cart, err = h.service.RevalidateCart(tenantID.(uuid.UUID), cart.ID, true)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revalidate cart"})
+			return
+		}
+	}
+
 	// Handle include options
 	response := gin.H{"data": cart}
 	if strings.Contains(include, "summary") {
@@ -381,6 +401,10 @@ func (h *Handler) ProcessGuestCheckout(c *gin.Context) {
 
 	result, err := h.service.ProcessGuestCheckout(tenantID.(uuid.UUID), req)
 	if err != nil {
+		if err == ErrChangesNotAcknowledged {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process checkout"})
 		return
 	}
@@ -388,6 +412,64 @@ func (h *Handler) ProcessGuestCheckout(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": result})
 }
 
+// AcknowledgeChanges accepts price and stock changes on the current user's cart
+func (h *Handler) AcknowledgeChanges(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tenant_id is required"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user authentication required"})
+		return
+	}
+
+	cart, err := h.service.GetCartByCustomer(tenantID.(uuid.UUID), userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cart not found"})
+		return
+	}
+
+	updatedCart, err := h.service.AcknowledgeChanges(tenantID.(uuid.UUID), cart.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to acknowledge cart changes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": updatedCart})
+}
+
+// AcknowledgeGuestChanges accepts price and stock changes on a guest cart
+func (h *Handler) AcknowledgeGuestChanges(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tenant_id is required"})
+		return
+	}
+
+	sessionID := c.Query("session_id")
+	if sessionID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "session_id is required"})
+		return
+	}
+
+	cart, err := h.service.GetCartBySession(tenantID.(uuid.UUID), sessionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cart not found"})
+		return
+	}
+
+	updatedCart, err := h.service.AcknowledgeChanges(tenantID.(uuid.UUID), cart.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to acknowledge cart changes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": updatedCart})
+}
+
 // GetCartByCustomer retrieves cart for a customer
 func (h *Handler) GetCartByCustomer(c *gin.Context) {
 	tenantID, exists := c.Get("tenant_id")
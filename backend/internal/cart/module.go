@@ -3,9 +3,6 @@ package cart
 import (
 	"gorm.io/gorm"
 	"github.com/gin-gonic/gin"
-	"ecommerce-saas/internal/product"
-	"ecommerce-saas/internal/discount"
-	"ecommerce-saas/internal/tax"
 	"ecommerce-saas/internal/shipping"
 )
 
@@ -16,10 +13,11 @@ type Module struct {
 	handler    *Handler
 }
 
-// NewModule creates a new cart module instance
-func NewModule(db *gorm.DB, productSvc *product.Service, discountSvc discount.Service, taxSvc tax.Service, shippingSvc *shipping.Service) *Module {
+// NewModule creates a new cart module instance. Product, discount and tax
+// services are passed through cart adapters since their module APIs differ.
+func NewModule(db *gorm.DB, productSvc ProductService, discountSvc DiscountService, taxSvc TaxService, shippingSvc *shipping.Service) *Module {
 	repo := NewRepository(db)
-	svc := NewCartService(repo, productSvc, discountSvc, taxSvc, shippingSvc)
+	svc := NewCartService(repo, productSvc, discountSvc, taxSvc, NewShippingAdapter(shippingSvc))
 	handler := NewHandler(svc)
 
 	return &Module{
@@ -0,0 +1,105 @@
+package cart
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+
+	"ecommerce-saas/internal/product"
+)
+
+// ProductAdapter exposes the product catalog through the cart ProductService
+// interface
+type ProductAdapter struct {
+	service *product.Service
+}
+
+// NewProductAdapter creates a cart ProductService backed by product.Service
+func NewProductAdapter(service *product.Service) *ProductAdapter {
+	return &ProductAdapter{service: service}
+}
+
+// GetProduct returns the cart's view of a product
+func (a *ProductAdapter) GetProduct(tenantID uuid.UUID, productID string) (*ProductInfo, error) {
+	p, err := a.service.GetProduct(tenantID, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProductInfo{
+		ID:                    p.ID,
+		Name:                  p.Name,
+		Slug:                  p.Slug,
+		Price:                 p.Price,
+		ComparePrice:          p.ComparePrice,
+		Image:                 p.GetMainImage(),
+		SKU:                   p.SKU,
+		IsAvailable:           p.IsAvailable(),
+		TrackQuantity:         p.TrackQuantity && !p.AllowBackorder,
+		AvailableQuantity:     p.InventoryQuantity,
+		IsGiftCard:            p.IsGiftCard(),
+		GiftCardDenominations: p.GiftCardDenominations,
+	}, nil
+}
+
+// GetProductVariant returns the cart's view of a variant. A zero price means
+// the variant sells at the product price.
+func (a *ProductAdapter) GetProductVariant(tenantID, productID, variantID uuid.UUID) (*VariantInfo, error) {
+	variant, err := a.variant(tenantID, productID, variantID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &VariantInfo{
+		ID:                variant.ID,
+		Name:              variant.Name,
+		Price:             variant.Price,
+		SKU:               variant.SKU,
+		Image:             variant.Image,
+		IsAvailable:       variant.IsAvailable(),
+		TrackQuantity:     variant.TrackQuantity && !variant.AllowBackorder,
+		AvailableQuantity: variant.InventoryQuantity,
+	}, nil
+}
+
+// CheckInventory reports whether the quantity can be sold now
+func (a *ProductAdapter) CheckInventory(tenantID, productID uuid.UUID, variantID *uuid.UUID, quantity int) (bool, error) {
+	if variantID != nil {
+		variant, err := a.variant(tenantID, productID, *variantID)
+		if err != nil {
+			return false, err
+		}
+		return !variant.TrackQuantity || variant.AllowBackorder || variant.InventoryQuantity >= quantity, nil
+	}
+
+	p, err := a.service.GetProduct(tenantID, productID.String())
+	if err != nil {
+		return false, err
+	}
+	return p.CanPurchase(quantity), nil
+}
+
+// ReserveInventory does nothing; carts don't hold stock, it is taken when the
+// order is placed
+func (a *ProductAdapter) ReserveInventory(tenantID, productID uuid.UUID, variantID *uuid.UUID, quantity int) error {
+	return nil
+}
+
+// ReleaseInventory does nothing, see ReserveInventory
+func (a *ProductAdapter) ReleaseInventory(tenantID, productID uuid.UUID, variantID *uuid.UUID, quantity int) error {
+	return nil
+}
+
+// variant finds one of the product's variants
+func (a *ProductAdapter) variant(tenantID, productID, variantID uuid.UUID) (*product.ProductVariant, error) {
+	variants, err := a.service.GetProductVariants(tenantID, productID.String())
+	if err != nil {
+		return nil, err
+	}
+	for _, variant := range variants {
+		if variant.ID == variantID {
+			return variant, nil
+		}
+	}
+	return nil, errors.New("product variant not found")
+}
@@ -0,0 +1,220 @@
+package cart
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RevalidateCart checks every item against the current catalog. With
+// autoAdjust the cart takes the new prices, quantities are reduced to what is
+// in stock and unavailable items are removed; otherwise changes are only
+// reported. Either way the changes stay pending until acknowledged.
+func (s *CartService) RevalidateCart(tenantID, cartID uuid.UUID, autoAdjust bool) (*CartResponse, error) {
+	cart, err := s.repo.FindCartByID(tenantID, cartID)
+	if err != nil {
+		return nil, ErrCartNotFound
+	}
+
+	if err := cart.CanModify(); err != nil {
+		return nil, err
+	}
+
+	return s.revalidate(cart, autoAdjust)
+}
+
+// AcknowledgeChanges accepts all pending changes, applying any that were only
+// reported, so the cart can proceed to checkout
+func (s *CartService) AcknowledgeChanges(tenantID, cartID uuid.UUID) (*CartResponse, error) {
+	cart, err := s.repo.FindCartByID(tenantID, cartID)
+	if err != nil {
+		return nil, ErrCartNotFound
+	}
+
+	if err := cart.CanModify(); err != nil {
+		return nil, err
+	}
+
+	response, err := s.revalidate(cart, true)
+	if err != nil {
+		return nil, err
+	}
+
+	cart.PendingChanges = nil
+	updatedCart, err := s.repo.UpdateCart(cart)
+	if err != nil {
+		return nil, err
+	}
+
+	acknowledged := s.buildCartResponse(updatedCart)
+	acknowledged.Warnings = response.Warnings
+	return acknowledged, nil
+}
+
+// revalidate compares cart items with the catalog and records what changed.
+// Changes that were already applied stay pending until acknowledged; changes
+// that were only reported are replaced by the latest detection.
+func (s *CartService) revalidate(cart *Cart, autoAdjust bool) (*CartResponse, error) {
+	if s.productService == nil || cart.Status != StatusActive || len(cart.Items) == 0 {
+		return s.buildCartResponse(cart), nil
+	}
+
+	now := time.Now()
+	var changes []CartItemChange
+	adjusted := false
+	items := make([]CartItem, 0, len(cart.Items))
+
+	for i := range cart.Items {
+		item := &cart.Items[i]
+		itemChanges, keep, changed := s.checkItem(cart.TenantID, item, autoAdjust, now)
+		changes = append(changes, itemChanges...)
+
+		if !keep {
+			if err := s.repo.RemoveCartItem(cart.TenantID, cart.ID, item.ID); err != nil {
+				return nil, err
+			}
+			adjusted = true
+			continue
+		}
+		if changed {
+			if _, err := s.repo.UpdateCartItem(item); err != nil {
+				return nil, err
+			}
+			adjusted = true
+		}
+		items = append(items, *item)
+	}
+	cart.Items = items
+
+	pending := make([]CartItemChange, 0, len(cart.PendingChanges)+len(changes))
+	for _, change := range cart.PendingChanges {
+		if change.Adjusted {
+			pending = append(pending, change)
+		}
+	}
+	cart.PendingChanges = append(pending, changes...)
+	cart.ValidatedAt = &now
+
+	if adjusted {
+		if err := s.recalculateCart(cart); err != nil {
+			return nil, err
+		}
+	}
+
+	updatedCart, err := s.repo.UpdateCart(cart)
+	if err != nil {
+		return nil, err
+	}
+
+	response := s.buildCartResponse(updatedCart)
+	response.Warnings = changes
+	return response, nil
+}
+
+// checkItem returns the changes for one item, whether it stays in the cart and
+// whether the item itself was modified
+func (s *CartService) checkItem(tenantID uuid.UUID, item *CartItem, autoAdjust bool, now time.Time) ([]CartItemChange, bool, bool) {
+	change := func(changeType ChangeType, message string) CartItemChange {
+		return CartItemChange{
+			Type:        changeType,
+			ItemID:      item.ID,
+			ProductID:   item.ProductID,
+			VariantID:   item.VariantID,
+			ProductName: item.ProductName,
+			OldQuantity: item.Quantity,
+			NewQuantity: item.Quantity,
+			Message:     message,
+			DetectedAt:  now,
+		}
+	}
+
+	product, err := s.productService.GetProduct(tenantID, item.ProductID.String())
+	if err != nil || !product.IsAvailable {
+		unavailable := change(ChangeUnavailable, fmt.Sprintf("%s is no longer available", item.ProductName))
+		unavailable.NewQuantity = 0
+		unavailable.Adjusted = autoAdjust
+		return []CartItemChange{unavailable}, !autoAdjust, false
+	}
+
+	// A gift card costs the amount chosen for it while that amount is offered
+	if item.GiftCard != nil {
+		if !product.IsGiftCard || !product.AcceptsGiftCardAmount(item.GiftCard.Amount) {
+			unavailable := change(ChangeUnavailable, fmt.Sprintf("%s is no longer offered for %.2f", item.ProductName, item.GiftCard.Amount))
+			unavailable.NewQuantity = 0
+			unavailable.Adjusted = autoAdjust
+			return []CartItemChange{unavailable}, !autoAdjust, false
+		}
+		return nil, true, false
+	}
+
+	price := product.Price
+	trackQuantity, available := product.TrackQuantity, product.AvailableQuantity
+	if item.VariantID != nil {
+		variant, err := s.productService.GetProductVariant(tenantID, item.ProductID, *item.VariantID)
+		if err != nil {
+			unavailable := change(ChangeUnavailable, fmt.Sprintf("The selected option of %s is no longer available", item.ProductName))
+			unavailable.NewQuantity = 0
+			unavailable.Adjusted = autoAdjust
+			return []CartItemChange{unavailable}, !autoAdjust, false
+		}
+		// Variants without their own price sell at the product price
+		if variant.Price > 0 {
+			price = variant.Price
+		}
+		trackQuantity, available = variant.TrackQuantity, variant.AvailableQuantity
+	}
+
+	var changes []CartItemChange
+	modified := false
+
+	if trackQuantity && available < item.Quantity {
+		if available <= 0 {
+			outOfStock := change(ChangeOutOfStock, fmt.Sprintf("%s is out of stock", item.ProductName))
+			outOfStock.NewQuantity = 0
+			outOfStock.Adjusted = autoAdjust
+			return []CartItemChange{outOfStock}, !autoAdjust, false
+		}
+
+		reduced := change(ChangeQuantityReduced, fmt.Sprintf("Only %d of %s left in stock", available, item.ProductName))
+		reduced.NewQuantity = available
+		reduced.Adjusted = autoAdjust
+		changes = append(changes, reduced)
+		if autoAdjust {
+			item.Quantity = available
+			modified = true
+		}
+	}
+
+	if math.Abs(price-item.Price) > 0.005 {
+		changeType := ChangePriceIncreased
+		message := fmt.Sprintf("The price of %s went up from %.2f to %.2f", item.ProductName, item.Price, price)
+		if price < item.Price {
+			changeType = ChangePriceDecreased
+			message = fmt.Sprintf("The price of %s dropped from %.2f to %.2f", item.ProductName, item.Price, price)
+		}
+
+		priceChange := change(changeType, message)
+		priceChange.OldPrice = item.Price
+		priceChange.NewPrice = price
+		priceChange.NewQuantity = item.Quantity
+
+		// Price drops are always passed on to the customer
+		if autoAdjust || changeType == ChangePriceDecreased {
+			priceChange.Adjusted = true
+			item.Price = price
+			if product.ComparePrice > 0 {
+				item.ComparePrice = product.ComparePrice
+			}
+			modified = true
+		}
+		changes = append(changes, priceChange)
+	}
+
+	if modified {
+		item.CalculateLineTotal()
+	}
+
+	return changes, true, modified
+}
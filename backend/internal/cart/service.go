@@ -2,6 +2,7 @@ package cart
 
 import (
 	"errors"
+	"math"
 	"strings"
 	"time"
 
@@ -23,12 +24,14 @@ type AddItemRequest struct {
 	Quantity       int                    `json:"quantity" validate:"required,min=1,max=100"`
 	Customizations map[string]interface{} `json:"customizations,omitempty"`
 	Notes          string                 `json:"notes,omitempty" validate:"max=200"`
+	GiftCard       *GiftCardDetails       `json:"gift_card,omitempty"` // Required for gift card products
 }
 
 type UpdateItemRequest struct {
 	Quantity       *int                   `json:"quantity,omitempty" validate:"omitempty,min=1,max=100"`
 	Customizations map[string]interface{} `json:"customizations,omitempty"`
 	Notes          string                 `json:"notes,omitempty" validate:"max=200"`
+	GiftCard       *GiftCardDetails       `json:"gift_card,omitempty"` // New recipient details; the amount cannot change
 }
 
 type ApplyCouponRequest struct {
@@ -55,6 +58,7 @@ type UpdateCartRequest struct {
 type EstimateRequest struct {
 	ShippingAddress *Address   `json:"shipping_address" validate:"required"`
 	ShippingMethodID *uuid.UUID `json:"shipping_method_id,omitempty"`
+	PaymentMethod    string     `json:"payment_method,omitempty"` // cod adds carriers' collection fees
 }
 
 type EstimateResponse struct {
@@ -91,6 +95,8 @@ type CartResponse struct {
 	ItemCount       int     `json:"item_count"`
 	UniqueItemCount int     `json:"unique_item_count"`
 	SavingsAmount   float64 `json:"savings_amount"`
+	Warnings        []CartItemChange `json:"warnings,omitempty"` // Changes found on this read
+	RequiresAcknowledgement bool     `json:"requires_acknowledgement"`
 }
 
 type CartSummary struct {
@@ -137,6 +143,20 @@ type ProductInfo struct {
 	Image       string    `json:"image"`
 	SKU         string    `json:"sku"`
 	IsAvailable bool      `json:"is_available"`
+	TrackQuantity     bool `json:"track_quantity"`
+	AvailableQuantity int  `json:"available_quantity"` // Only meaningful when TrackQuantity is set
+	IsGiftCard            bool      `json:"is_gift_card"`
+	GiftCardDenominations []float64 `json:"gift_card_denominations,omitempty"`
+}
+
+// AcceptsGiftCardAmount checks the amount is one of the gift card denominations
+func (p *ProductInfo) AcceptsGiftCardAmount(amount float64) bool {
+	for _, denomination := range p.GiftCardDenominations {
+		if math.Abs(denomination-amount) < 0.005 {
+			return true
+		}
+	}
+	return false
 }
 
 type VariantInfo struct {
@@ -145,6 +165,9 @@ type VariantInfo struct {
 	Price float64   `json:"price"`
 	SKU   string    `json:"sku"`
 	Image string    `json:"image"`
+	IsAvailable       bool `json:"is_available"`
+	TrackQuantity     bool `json:"track_quantity"`
+	AvailableQuantity int  `json:"available_quantity"`
 }
 
 type CouponInfo struct {
@@ -160,8 +183,13 @@ type ShippingMethod struct {
 	ID          uuid.UUID `json:"id"`
 	Name        string    `json:"name"`
 	Description string    `json:"description"`
+	Carrier     string    `json:"carrier"`
 	Cost        float64   `json:"cost"`
+	Currency    string    `json:"currency"`
 	EstimatedDays int     `json:"estimated_days"`
+	EstimatedDelivery time.Time `json:"estimated_delivery"`
+	Source      string    `json:"source"` // live, table
+	Rank        int       `json:"rank"`
 }
 
 // ServiceInterface defines the cart service interface
@@ -181,6 +209,8 @@ type ServiceInterface interface {
 	ClearCart(tenantID, cartID uuid.UUID) error
 	DeleteCart(tenantID, cartID uuid.UUID) error
 	ProcessGuestCheckout(tenantID uuid.UUID, req GuestCheckoutRequest) (*GuestCheckoutResponse, error)
+	RevalidateCart(tenantID, cartID uuid.UUID, autoAdjust bool) (*CartResponse, error)
+	AcknowledgeChanges(tenantID, cartID uuid.UUID) (*CartResponse, error)
 	ListCarts(tenantID uuid.UUID, filter CartListFilter, offset, limit int) ([]*CartResponse, int64, error)
 	GetCartStats(tenantID uuid.UUID) (*CartStats, error)
 }
@@ -197,6 +227,7 @@ type CartService struct {
 	taxService      TaxService
 	shippingService ShippingService
 	cartExpiration  time.Duration
+	autoAdjust      bool
 }
 
 // NewCartService creates a new cart service implementation
@@ -217,6 +248,12 @@ func NewService(repo Repository, productService ProductService, discountService
 	return NewCartService(repo, productService, discountService, taxService, shippingService)
 }
 
+// SetAutoAdjust makes cart reads apply catalog changes (new prices, reduced
+// quantities, removed items) instead of only reporting them
+func (s *CartService) SetAutoAdjust(autoAdjust bool) {
+	s.autoAdjust = autoAdjust
+}
+
 // CreateCart creates a new cart
 func (s *CartService) CreateCart(tenantID uuid.UUID, req CreateCartRequest) (*CartResponse, error) {
 	// Validate request
@@ -277,7 +314,7 @@ func (s *CartService) GetCart(tenantID, cartID uuid.UUID) (*CartResponse, error)
 		return nil, ErrCartExpired
 	}
 
-	return s.buildCartResponse(cart), nil
+	return s.revalidate(cart, s.autoAdjust)
 }
 
 // GetCartByID retrieves a cart by ID (alias for GetCart)
@@ -299,7 +336,7 @@ func (s *CartService) GetCartByCustomer(tenantID, customerID uuid.UUID) (*CartRe
 		return nil, ErrCartExpired
 	}
 
-	return s.buildCartResponse(cart), nil
+	return s.revalidate(cart, s.autoAdjust)
 }
 
 // GetCartBySession retrieves cart for a guest session
@@ -316,7 +353,7 @@ func (s *CartService) GetCartBySession(tenantID uuid.UUID, sessionID string) (*C
 		return nil, ErrCartExpired
 	}
 
-	return s.buildCartResponse(cart), nil
+	return s.revalidate(cart, s.autoAdjust)
 }
 
 // AddItem adds an item to the cart
@@ -347,6 +384,18 @@ func (s *CartService) AddItem(tenantID, cartID uuid.UUID, req AddItemRequest) (*
 		return nil, errors.New("product is not available")
 	}
 
+	// Gift cards are bought for a chosen amount and recipient
+	if product.IsGiftCard {
+		if req.GiftCard == nil {
+			return nil, ErrGiftCardDetailsRequired
+		}
+		if !product.AcceptsGiftCardAmount(req.GiftCard.Amount) {
+			return nil, ErrInvalidGiftCardAmount
+		}
+	} else {
+		req.GiftCard = nil
+	}
+
 	// Get variant information if specified
 	var variant *VariantInfo
 	if req.VariantID != nil {
@@ -365,8 +414,11 @@ func (s *CartService) AddItem(tenantID, cartID uuid.UUID, req AddItemRequest) (*
 		return nil, ErrInsufficientStock
 	}
 
-	// Check if item already exists in cart
-	existingItem := cart.FindItem(req.ProductID, req.VariantID)
+	// Check if item already exists in cart; every gift card keeps its own line
+	var existingItem *CartItem
+	if req.GiftCard == nil {
+		existingItem = cart.FindItem(req.ProductID, req.VariantID)
+	}
 	if existingItem != nil {
 		// Update existing item quantity
 		newQuantity := existingItem.Quantity + req.Quantity
@@ -397,13 +449,19 @@ func (s *CartService) AddItem(tenantID, cartID uuid.UUID, req AddItemRequest) (*
 		variantName := ""
 		
 		if variant != nil {
-			price = variant.Price
+			if variant.Price > 0 {
+				price = variant.Price
+			}
 			sku = variant.SKU
 			variantName = variant.Name
 			if variant.Image != "" {
 				image = variant.Image
 			}
 		}
+		if req.GiftCard != nil {
+			price = req.GiftCard.Amount
+			comparePrice = 0
+		}
 		
 		item := &CartItem{
 			ID:             uuid.New(),
@@ -420,6 +478,7 @@ func (s *CartService) AddItem(tenantID, cartID uuid.UUID, req AddItemRequest) (*
 			Quantity:       req.Quantity,
 			Customizations: req.Customizations,
 			Notes:          strings.TrimSpace(req.Notes),
+			GiftCard:       req.GiftCard,
 		}
 		
 		item.CalculateLineTotal()
@@ -490,6 +549,14 @@ func (s *CartService) UpdateItem(tenantID, cartID, itemID uuid.UUID, req UpdateI
 	}
 	item.Notes = strings.TrimSpace(req.Notes)
 
+	// Recipient details can be corrected; a different amount is a new item
+	if req.GiftCard != nil && item.GiftCard != nil {
+		if math.Abs(req.GiftCard.Amount-item.GiftCard.Amount) > 0.005 {
+			return nil, ErrInvalidGiftCardAmount
+		}
+		item.GiftCard = req.GiftCard
+	}
+
 	// Update item
 	updatedItem, err := s.repo.UpdateCartItem(item)
 	if err != nil {
@@ -967,6 +1034,7 @@ func (s *CartService) GetEstimates(tenantID, cartID uuid.UUID, req EstimateReque
 	// Create temporary cart with shipping address for calculations
 	tempCart := *cart
 	tempCart.ShippingAddress = req.ShippingAddress
+	tempCart.PaymentMethod = req.PaymentMethod
 
 	// Get available shipping methods
 	var shippingMethods []*ShippingMethod
@@ -1038,10 +1106,19 @@ func (s *CartService) ProcessGuestCheckout(tenantID uuid.UUID, req GuestCheckout
 		return nil, errors.New("cart is empty")
 	}
 
+	// Catalog changes must be acknowledged before the order is placed
+	if _, err := s.revalidate(cart, false); err != nil {
+		return nil, err
+	}
+	if cart.HasPendingChanges() {
+		return nil, ErrChangesNotAcknowledged
+	}
+
 	// Update cart with checkout information
 	cart.ShippingAddress = &req.ShippingAddress
 	cart.BillingAddress = &req.BillingAddress
 	cart.ShippingMethodID = &req.ShippingMethodID
+	cart.PaymentMethod = req.PaymentMethodID
 
 	// Recalculate totals
 	if err := s.recalculateCart(cart); err != nil {
@@ -1081,5 +1158,6 @@ func (s *CartService) buildCartResponse(cart *Cart) *CartResponse {
 		ItemCount:       cart.GetItemCount(),
 		UniqueItemCount: cart.GetUniqueItemCount(),
 		SavingsAmount:   savingsAmount,
+		RequiresAcknowledgement: cart.HasPendingChanges(),
 	}
 }
\ No newline at end of file
@@ -0,0 +1,131 @@
+package cart
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"github.com/google/uuid"
+
+	"ecommerce-saas/internal/shipping"
+)
+
+// defaultItemWeightKg is used for items whose weight can't be looked up
+const defaultItemWeightKg = 0.5
+
+// ItemWeigher looks up the shipping weight in grams of one unit of a product
+// or variant
+type ItemWeigher interface {
+	ShippingWeight(tenantID, productID uuid.UUID, variantID *uuid.UUID) (float64, error)
+}
+
+// ShippingAdapter exposes the shipping module's live rate shopping through
+// the cart ShippingService interface
+type ShippingAdapter struct {
+	service *shipping.Service
+	weigher ItemWeigher
+}
+
+// NewShippingAdapter creates a cart ShippingService backed by shipping.Service
+func NewShippingAdapter(service *shipping.Service) *ShippingAdapter {
+	return &ShippingAdapter{service: service}
+}
+
+// SetItemWeigher sets where item weights come from. Without one every unit
+// weighs defaultItemWeightKg.
+func (a *ShippingAdapter) SetItemWeigher(weigher ItemWeigher) {
+	a.weigher = weigher
+}
+
+// GetAvailableShippingMethods returns live carrier quotes merged with table
+// rates, ranked by price and then delivery time
+func (a *ShippingAdapter) GetAvailableShippingMethods(tenantID uuid.UUID, cart *Cart) ([]*ShippingMethod, error) {
+	req, err := a.shippingRateRequest(tenantID, cart)
+	if err != nil {
+		return nil, err
+	}
+
+	rates, err := a.service.CalculateLiveShippingRates(context.Background(), tenantID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	methods := make([]*ShippingMethod, len(rates))
+	for i, rate := range rates {
+		methods[i] = &ShippingMethod{
+			ID:                rate.RateID,
+			Name:              rate.Name,
+			Description:       rate.Description,
+			Carrier:           string(rate.Provider),
+			Cost:              rate.Cost,
+			Currency:          rate.Currency,
+			EstimatedDays:     rate.EstimatedDays,
+			EstimatedDelivery: rate.EstimatedDelivery,
+			Source:            rate.Source,
+			Rank:              rate.Rank,
+		}
+	}
+
+	return methods, nil
+}
+
+// CalculateShipping prices the selected method for the cart's current contents
+func (a *ShippingAdapter) CalculateShipping(tenantID uuid.UUID, cart *Cart, methodID uuid.UUID) (float64, error) {
+	req, err := a.shippingRateRequest(tenantID, cart)
+	if err != nil {
+		return 0, err
+	}
+
+	rate, err := a.service.ResolveShippingRate(context.Background(), tenantID, req, methodID)
+	if err != nil {
+		return 0, err
+	}
+
+	return rate.Cost, nil
+}
+
+// shippingRateRequest builds a rate request from the cart's items and address
+func (a *ShippingAdapter) shippingRateRequest(tenantID uuid.UUID, cart *Cart) (shipping.ShippingRateRequest, error) {
+	if cart.ShippingAddress == nil || cart.ShippingAddress.Country == "" {
+		return shipping.ShippingRateRequest{}, errors.New("shipping address is required")
+	}
+
+	items := make([]shipping.ShippingRateItem, 0, len(cart.Items))
+	var weightKg float64
+	for _, item := range cart.Items {
+		items = append(items, shipping.ShippingRateItem{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+		})
+		weightKg += a.itemWeightKg(tenantID, item) * float64(item.Quantity)
+	}
+
+	req := shipping.ShippingRateRequest{
+		DestinationCountry:  cart.ShippingAddress.Country,
+		DestinationState:    cart.ShippingAddress.State,
+		DestinationCity:     cart.ShippingAddress.City,
+		DestinationAreaCode: cart.ShippingAddress.AreaCode,
+		PostalCode:          cart.ShippingAddress.PostalCode,
+		Weight:              weightKg,
+		OrderValue:          cart.CalculateSubtotal(),
+		Items:               items,
+	}
+	// The carrier collects the cart total, less the shipping being quoted
+	if cart.PaymentMethod == PaymentMethodCOD {
+		req.CODAmount = math.Max(req.OrderValue+cart.TaxAmount-cart.DiscountAmount, 0)
+	}
+	return req, nil
+}
+
+// itemWeightKg returns the weight in kilograms of one unit of the item
+func (a *ShippingAdapter) itemWeightKg(tenantID uuid.UUID, item CartItem) float64 {
+	if a.weigher == nil {
+		return defaultItemWeightKg
+	}
+	grams, err := a.weigher.ShippingWeight(tenantID, item.ProductID, item.VariantID)
+	if err != nil {
+		return defaultItemWeightKg
+	}
+	return grams / 1000
+}
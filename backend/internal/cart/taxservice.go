@@ -0,0 +1,53 @@
+package cart
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"ecommerce-saas/internal/tax"
+)
+
+// TaxAdapter exposes the tax module's rules through the cart TaxService
+// interface
+type TaxAdapter struct {
+	service tax.Service
+}
+
+// NewTaxAdapter creates a cart TaxService backed by tax.Service
+func NewTaxAdapter(service tax.Service) *TaxAdapter {
+	return &TaxAdapter{service: service}
+}
+
+// CalculateTax previews the tax on the cart's discounted subtotal at its
+// shipping address. Nothing is recorded until the order is placed; carts no
+// tax rule applies to are untaxed.
+func (a *TaxAdapter) CalculateTax(tenantID uuid.UUID, cart *Cart) (float64, error) {
+	if cart.ShippingAddress == nil || cart.ShippingAddress.Country == "" {
+		return 0, errors.New("shipping address is required")
+	}
+
+	taxable := cart.Subtotal - cart.DiscountAmount
+	if taxable <= 0 {
+		return 0, nil
+	}
+
+	result, err := a.service.PreviewTaxCalculation(context.Background(), tenantID, tax.TaxCalculationRequest{
+		Amount:     taxable,
+		CustomerID: cart.CustomerID,
+		Country:    cart.ShippingAddress.Country,
+		State:      cart.ShippingAddress.State,
+		City:       cart.ShippingAddress.City,
+		PostalCode: cart.ShippingAddress.PostalCode,
+		Method:     tax.MethodExclusive,
+	})
+	if errors.Is(err, tax.ErrNoApplicableRules) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return result.TaxAmount, nil
+}
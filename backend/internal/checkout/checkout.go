@@ -0,0 +1,230 @@
+package checkout
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"ecommerce-saas/internal/cart"
+	"ecommerce-saas/internal/discount"
+)
+
+// SessionStatus represents the status of a checkout session
+type SessionStatus string
+
+const (
+	StatusOpen      SessionStatus = "open"
+	StatusCompleted SessionStatus = "completed"
+	StatusExpired   SessionStatus = "expired"
+)
+
+// Payment methods accepted at checkout; everything except cash on delivery is
+// paid through the payment module after the order is placed
+const (
+	PaymentCOD        = "cod"
+	PaymentBKash      = "bkash"
+	PaymentNagad      = "nagad"
+	PaymentSSLCommerz = "sslcommerz"
+	PaymentStripe     = "stripe"
+	PaymentPayPal     = "paypal"
+)
+
+// Session collects everything needed to turn a cart into an order. Prices are
+// always computed on the server from current product data; the client only
+// supplies choices.
+type Session struct {
+	ID         uuid.UUID     `json:"id" gorm:"primarykey"`
+	TenantID   uuid.UUID     `json:"tenant_id" gorm:"not null;index"`
+	CartID     uuid.UUID     `json:"cart_id" gorm:"not null;index"`
+	CustomerID *uuid.UUID    `json:"customer_id,omitempty" gorm:"index"`
+	Status     SessionStatus `json:"status" gorm:"default:open;index"`
+
+	// Contact
+	Email string `json:"email,omitempty"`
+	Phone string `json:"phone,omitempty"`
+
+	// Addresses and choices
+	ShippingAddress  *cart.Address `json:"shipping_address,omitempty" gorm:"serializer:json"`
+	BillingAddress   *cart.Address `json:"billing_address,omitempty" gorm:"serializer:json"`
+	ShippingMethodID *uuid.UUID    `json:"shipping_method_id,omitempty"`
+	PaymentMethod    string        `json:"payment_method,omitempty"`
+	DiscountCodes    []string      `json:"discount_codes,omitempty" gorm:"serializer:json"`
+	GiftCardCodes    []string      `json:"gift_card_codes,omitempty" gorm:"serializer:json"`
+	LoyaltyPoints    int           `json:"loyalty_points,omitempty"` // Points the customer wants to pay with
+	Notes            string        `json:"notes,omitempty"`
+
+	// Server-side pricing, refreshed on every update and again at completion
+	Lines     []Line            `json:"lines" gorm:"serializer:json"`
+	Discounts []AppliedDiscount `json:"discounts,omitempty" gorm:"serializer:json"`
+	GiftCards []AppliedGiftCard `json:"gift_cards,omitempty" gorm:"serializer:json"`
+	// Automatic promotions that did not apply, with the reason
+	RejectedPromotions []discount.RejectedPromotion `json:"rejected_promotions,omitempty" gorm:"serializer:json"`
+	Subtotal           float64                      `json:"subtotal" gorm:"default:0"`
+	DiscountAmount     float64                      `json:"discount_amount" gorm:"default:0"`
+	ShippingAmount     float64                      `json:"shipping_amount" gorm:"default:0"`
+	TaxAmount          float64                      `json:"tax_amount" gorm:"default:0"`
+	PointsRedeemed     int                          `json:"points_redeemed" gorm:"default:0"` // Capped by balance and program rules
+	PointsAmount       float64                      `json:"points_amount" gorm:"default:0"`
+	GiftCardAmount     float64                      `json:"gift_card_amount" gorm:"default:0"`
+	Total              float64                      `json:"total" gorm:"default:0"`
+	Currency           string                       `json:"currency" gorm:"default:BDT"`
+	PricedAt           *time.Time                   `json:"priced_at,omitempty"`
+
+	// Outcome
+	OrderID             *uuid.UUID `json:"order_id,omitempty" gorm:"index"`
+	OrderNumber         string     `json:"order_number,omitempty"`
+	PointsTransactionID *uuid.UUID `json:"points_transaction_id,omitempty"` // Loyalty redemption
+	ErrorCode           string     `json:"error_code,omitempty"`
+	ErrorMessage        string     `json:"error_message,omitempty"`
+
+	ExpiresAt   time.Time  `json:"expires_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName keeps checkout sessions out of the generic "sessions" table
+func (Session) TableName() string {
+	return "checkout_sessions"
+}
+
+// Line is a cart item priced at the current product price
+type Line struct {
+	CartItemID  uuid.UUID  `json:"cart_item_id"`
+	ProductID   uuid.UUID  `json:"product_id"`
+	VariantID   *uuid.UUID `json:"variant_id,omitempty"`
+	CategoryID  *uuid.UUID `json:"category_id,omitempty"`
+	Name        string     `json:"name"`
+	VariantName string     `json:"variant_name,omitempty"`
+	SKU         string     `json:"sku,omitempty"`
+	Quantity    int        `json:"quantity"`
+	UnitPrice   float64    `json:"unit_price"`
+	CartPrice   float64    `json:"cart_price"` // Price shown when the item was added
+	LineTotal   float64    `json:"line_total"`
+	Discount    float64    `json:"discount"` // Promotions allocated to this line
+
+	// Collections of the product, matched by collection discounts
+	CollectionIDs []uuid.UUID `json:"collection_ids,omitempty"`
+
+	// Gift card products only: who receives the card and how long it is valid
+	GiftCard             *cart.GiftCardDetails `json:"gift_card,omitempty"`
+	GiftCardValidityDays int                   `json:"gift_card_validity_days,omitempty"`
+}
+
+// AppliedDiscount is a discount code or automatic promotion accepted for the
+// session, with the lines it reduced
+type AppliedDiscount struct {
+	DiscountID uuid.UUID                 `json:"discount_id"`
+	Code       string                    `json:"code,omitempty"`
+	CodeID     *uuid.UUID                `json:"code_id,omitempty"` // Pool code, when one was entered
+	Title      string                    `json:"title,omitempty"`
+	Type       string                    `json:"type"`
+	Class      string                    `json:"class,omitempty"`
+	Automatic  bool                      `json:"automatic"`
+	Amount     float64                   `json:"amount"`
+	Lines      []discount.LineAllocation `json:"lines,omitempty"`
+	Benefit    string                    `json:"benefit,omitempty"` // Loyalty tier benefit; no discount ID
+}
+
+// AppliedGiftCard is the part of a gift card balance used for the session
+type AppliedGiftCard struct {
+	GiftCardID uuid.UUID  `json:"gift_card_id"`
+	Code       string     `json:"code"`
+	Amount     float64    `json:"amount"`
+	HoldID     *uuid.UUID `json:"hold_id,omitempty"` // Set once the order is placed
+}
+
+// Error codes, one for each checkout step that can fail
+const (
+	CodeSessionNotFound          = "session_not_found"
+	CodeSessionExpired           = "session_expired"
+	CodeSessionCompleted         = "session_completed"
+	CodeCartNotFound             = "cart_not_found"
+	CodeCartEmpty                = "cart_empty"
+	CodeCartConverted            = "cart_converted"
+	CodeCartChanged              = "cart_changes_pending"
+	CodeContactRequired          = "contact_required"
+	CodeAddressRequired          = "address_required"
+	CodeShippingMethodRequired   = "shipping_method_required"
+	CodeShippingUnavailable      = "shipping_unavailable"
+	CodeTaxUnavailable           = "tax_unavailable"
+	CodePaymentMethodRequired    = "payment_method_required"
+	CodeProductUnavailable       = "product_unavailable"
+	CodeInsufficientStock        = "insufficient_stock"
+	CodeInvalidDiscount          = "invalid_discount"
+	CodeDiscountNotStackable     = "discount_not_stackable"
+	CodeDiscountExhausted        = "discount_usage_exhausted"
+	CodeInvalidGiftCard          = "invalid_gift_card"
+	CodeGiftCardBalance          = "gift_card_insufficient_balance"
+	CodeGiftCardDetails          = "gift_card_details_required"
+	CodeGiftCardPrepayment       = "gift_card_requires_prepayment"
+	CodeLoyaltyPointsUnavailable = "loyalty_points_unavailable"
+	CodeLoyaltyPointsBalance     = "loyalty_points_insufficient"
+	CodePriceChanged             = "price_changed"
+	CodeOrderFailed              = "order_creation_failed"
+)
+
+// Error is a checkout failure with a stable code clients can act on
+type Error struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func newError(code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// withDetails attaches data such as the failing product to the error
+func (e *Error) withDetails(details map[string]interface{}) *Error {
+	e.Details = details
+	return e
+}
+
+// IsExpired checks if the session can no longer be completed
+func (s *Session) IsExpired() bool {
+	return s.Status == StatusExpired || (s.Status == StatusOpen && time.Now().After(s.ExpiresAt))
+}
+
+// ItemCount returns the total quantity across lines
+func (s *Session) ItemCount() int {
+	count := 0
+	for _, line := range s.Lines {
+		count += line.Quantity
+	}
+	return count
+}
+
+// RequiresShipping reports whether any line has to be shipped; gift cards
+// are delivered by email
+func (s *Session) RequiresShipping() bool {
+	if len(s.Lines) == 0 {
+		return true
+	}
+	for _, line := range s.Lines {
+		if line.GiftCard == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// HasGiftCardLines reports whether the session buys gift cards
+func (s *Session) HasGiftCardLines() bool {
+	for _, line := range s.Lines {
+		if line.GiftCard != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// RequiresPayment reports whether the customer still has to pay online
+func (s *Session) RequiresPayment() bool {
+	return s.PaymentMethod != PaymentCOD && s.Total > 0
+}
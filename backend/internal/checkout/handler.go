@@ -0,0 +1,204 @@
+package checkout
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Handler handles HTTP requests for checkout sessions
+type Handler struct {
+	service Service
+}
+
+// NewHandler creates a new checkout handler
+func NewHandler(service Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers storefront checkout routes
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	sessions := router.Group("/checkout/sessions")
+	{
+		sessions.POST("", h.CreateSession)
+		sessions.GET("/:id", h.GetSession)
+		sessions.PATCH("/:id", h.UpdateSession)
+		sessions.POST("/:id/acknowledge", h.AcknowledgeCartChanges)
+		sessions.POST("/:id/complete", h.CompleteSession)
+	}
+}
+
+// CreateSession starts checkout for a cart
+func (h *Handler) CreateSession(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	var req CreateSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := h.service.CreateSession(c.Request.Context(), tenantID.(uuid.UUID), caller(c), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": session})
+}
+
+// GetSession returns a checkout session with its current pricing
+func (h *Handler) GetSession(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	session, err := h.service.GetSession(c.Request.Context(), tenantID.(uuid.UUID), id, caller(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": session})
+}
+
+// UpdateSession sets contact details, addresses, shipping, payment and codes
+func (h *Handler) UpdateSession(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	var req UpdateSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := h.service.UpdateSession(c.Request.Context(), tenantID.(uuid.UUID), id, caller(c), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": session})
+}
+
+// AcknowledgeCartChanges accepts the price and stock changes found on the
+// session's cart so checkout can continue
+func (h *Handler) AcknowledgeCartChanges(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	session, err := h.service.AcknowledgeCartChanges(c.Request.Context(), tenantID.(uuid.UUID), id, caller(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": session})
+}
+
+// CompleteSession places the order for a checkout session
+func (h *Handler) CompleteSession(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	var req CompleteSessionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	req.IPAddress = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+
+	result, err := h.service.CompleteSession(c.Request.Context(), tenantID.(uuid.UUID), id, caller(c), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": result, "message": "Order placed successfully"})
+}
+
+// caller returns the signed-in customer, if any, and the guest cart session
+// passed like the cart API does
+func caller(c *gin.Context) Caller {
+	var caller Caller
+	if userID, exists := c.Get("user_id"); exists {
+		if id, ok := userID.(uuid.UUID); ok {
+			caller.CustomerID = &id
+		}
+	}
+	caller.CartSessionID = c.Query("session_id")
+	return caller
+}
+
+// respondError maps checkout error codes to HTTP statuses
+func respondError(c *gin.Context, err error) {
+	var checkoutErr *Error
+	if !errors.As(err, &checkoutErr) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := http.StatusBadRequest
+	switch checkoutErr.Code {
+	case CodeSessionNotFound, CodeCartNotFound:
+		status = http.StatusNotFound
+	case CodeSessionExpired:
+		status = http.StatusGone
+	case CodeSessionCompleted, CodeCartConverted, CodeCartChanged, CodeInsufficientStock, CodeProductUnavailable,
+		CodeDiscountExhausted, CodeGiftCardBalance, CodeLoyaltyPointsBalance, CodePriceChanged:
+		status = http.StatusConflict
+	case CodeTaxUnavailable:
+		status = http.StatusServiceUnavailable
+	case CodeOrderFailed:
+		status = http.StatusInternalServerError
+	}
+
+	c.JSON(status, gin.H{"error": checkoutErr.Message, "code": checkoutErr.Code, "details": checkoutErr.Details})
+}
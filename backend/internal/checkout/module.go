@@ -0,0 +1,49 @@
+package checkout
+
+import (
+	"ecommerce-saas/internal/discount"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Module represents the checkout module
+type Module struct {
+	repository Repository
+	service    Service
+	handler    *Handler
+}
+
+// NewModule creates a new checkout module. shippingCalculator,
+// taxCalculator, tiers and carts may be nil.
+func NewModule(db *gorm.DB, shippingCalculator ShippingCalculator, taxCalculator TaxCalculator, tiers discount.TierResolver, carts CartValidator) *Module {
+	repo := NewRepository(db)
+	svc := NewService(repo, shippingCalculator, taxCalculator, tiers, carts)
+	handler := NewHandler(svc)
+
+	return &Module{
+		repository: repo,
+		service:    svc,
+		handler:    handler,
+	}
+}
+
+// RegisterRoutes registers all checkout routes
+func (m *Module) RegisterRoutes(router *gin.RouterGroup) {
+	m.handler.RegisterRoutes(router)
+}
+
+// GetHandler returns the checkout handler
+func (m *Module) GetHandler() *Handler {
+	return m.handler
+}
+
+// GetService returns the checkout service
+func (m *Module) GetService() Service {
+	return m.service
+}
+
+// GetRepository returns the checkout repository
+func (m *Module) GetRepository() Repository {
+	return m.repository
+}
@@ -0,0 +1,406 @@
+package checkout
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"ecommerce-saas/internal/cart"
+	"ecommerce-saas/internal/discount"
+	"ecommerce-saas/internal/loyalty"
+	"ecommerce-saas/internal/order"
+	"ecommerce-saas/internal/product"
+)
+
+// Repository defines checkout data operations
+type Repository interface {
+	// Session operations
+	CreateSession(ctx context.Context, session *Session) error
+	UpdateSession(ctx context.Context, session *Session) error
+	GetSession(ctx context.Context, tenantID, sessionID uuid.UUID) (*Session, error)
+	GetOpenSessionByCart(ctx context.Context, tenantID, cartID uuid.UUID) (*Session, error)
+
+	// Reads used for pricing
+	GetCart(ctx context.Context, tenantID, cartID uuid.UUID) (*cart.Cart, error)
+	GetProducts(ctx context.Context, tenantID uuid.UUID, productIDs []uuid.UUID) ([]product.Product, error)
+	GetProductCollectionIDs(ctx context.Context, tenantID uuid.UUID, productIDs []uuid.UUID) (map[uuid.UUID][]uuid.UUID, error)
+	GetDiscountByCode(ctx context.Context, tenantID uuid.UUID, code string) (*discount.Discount, error)
+	GetAutomaticDiscounts(ctx context.Context, tenantID uuid.UUID) ([]discount.Discount, error)
+	GetDiscountByID(ctx context.Context, tenantID, discountID uuid.UUID) (*discount.Discount, error)
+	GetPoolCode(ctx context.Context, tenantID uuid.UUID, code string) (*discount.DiscountCode, error)
+	CountCustomerDiscountUsage(ctx context.Context, tenantID, discountID uuid.UUID, email string) (int64, error)
+	GetGiftCardByCode(ctx context.Context, tenantID uuid.UUID, code string) (*discount.GiftCard, error)
+	QuoteLoyaltyPoints(ctx context.Context, tenantID, customerID uuid.UUID, points int, orderAmount float64) (*loyalty.PointsQuote, error)
+
+	// Commit converts the cart, reserves stock, consumes discounts, loyalty
+	// points and gift cards, creates the order and completes the session in
+	// one transaction
+	Commit(ctx context.Context, session *Session, newOrder *order.Order, usage CommitContext) error
+}
+
+// CommitContext carries request details recorded with discount usage
+type CommitContext struct {
+	IPAddress string
+	UserAgent string
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new checkout repository
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) CreateSession(ctx context.Context, session *Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *repository) UpdateSession(ctx context.Context, session *Session) error {
+	return r.db.WithContext(ctx).Save(session).Error
+}
+
+func (r *repository) GetSession(ctx context.Context, tenantID, sessionID uuid.UUID) (*Session, error) {
+	var session Session
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND id = ?", tenantID, sessionID).
+		First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *repository) GetOpenSessionByCart(ctx context.Context, tenantID, cartID uuid.UUID) (*Session, error) {
+	var session Session
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND cart_id = ? AND status = ? AND expires_at > ?", tenantID, cartID, StatusOpen, time.Now()).
+		Order("created_at DESC").
+		First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *repository) GetCart(ctx context.Context, tenantID, cartID uuid.UUID) (*cart.Cart, error) {
+	var c cart.Cart
+	err := r.db.WithContext(ctx).Preload("Items").
+		First(&c, "id = ? AND tenant_id = ?", cartID, tenantID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *repository) GetProducts(ctx context.Context, tenantID uuid.UUID, productIDs []uuid.UUID) ([]product.Product, error) {
+	var products []product.Product
+	err := r.db.WithContext(ctx).Preload("Variants").
+		Where("tenant_id = ? AND id IN ?", tenantID, productIDs).
+		Find(&products).Error
+	return products, err
+}
+
+// GetProductCollectionIDs returns the collections each product belongs to
+func (r *repository) GetProductCollectionIDs(ctx context.Context, tenantID uuid.UUID, productIDs []uuid.UUID) (map[uuid.UUID][]uuid.UUID, error) {
+	var rows []struct {
+		ProductID    uuid.UUID
+		CollectionID uuid.UUID
+	}
+	err := r.db.WithContext(ctx).Table("collection_products cp").
+		Select("cp.product_id, cp.collection_id").
+		Joins("JOIN collections c ON c.id = cp.collection_id").
+		Where("c.tenant_id = ? AND cp.product_id IN ?", tenantID, productIDs).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	collectionIDs := make(map[uuid.UUID][]uuid.UUID, len(productIDs))
+	for _, row := range rows {
+		collectionIDs[row.ProductID] = append(collectionIDs[row.ProductID], row.CollectionID)
+	}
+	return collectionIDs, nil
+}
+
+func (r *repository) GetDiscountByCode(ctx context.Context, tenantID uuid.UUID, code string) (*discount.Discount, error) {
+	var d discount.Discount
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND UPPER(code) = UPPER(?)", tenantID, code).
+		First(&d).Error
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *repository) GetDiscountByID(ctx context.Context, tenantID, discountID uuid.UUID) (*discount.Discount, error) {
+	var d discount.Discount
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND id = ?", tenantID, discountID).
+		First(&d).Error
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *repository) GetPoolCode(ctx context.Context, tenantID uuid.UUID, code string) (*discount.DiscountCode, error) {
+	var poolCode discount.DiscountCode
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND UPPER(code) = UPPER(?)", tenantID, code).
+		First(&poolCode).Error
+	if err != nil {
+		return nil, err
+	}
+	return &poolCode, nil
+}
+
+// GetAutomaticDiscounts returns the live automatic promotions in the order the
+// promotion engine evaluates them
+func (r *repository) GetAutomaticDiscounts(ctx context.Context, tenantID uuid.UUID) ([]discount.Discount, error) {
+	return discount.NewRepository(r.db).GetAutomaticDiscounts(ctx, tenantID)
+}
+
+func (r *repository) CountCustomerDiscountUsage(ctx context.Context, tenantID, discountID uuid.UUID, email string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&discount.DiscountUsage{}).
+		Where("tenant_id = ? AND discount_id = ? AND LOWER(customer_email) = LOWER(?)", tenantID, discountID, email).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *repository) GetGiftCardByCode(ctx context.Context, tenantID uuid.UUID, code string) (*discount.GiftCard, error) {
+	var giftCard discount.GiftCard
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND UPPER(code) = UPPER(?)", tenantID, code).
+		First(&giftCard).Error
+	if err != nil {
+		return nil, err
+	}
+	return &giftCard, nil
+}
+
+func (r *repository) QuoteLoyaltyPoints(ctx context.Context, tenantID, customerID uuid.UUID, points int, orderAmount float64) (*loyalty.PointsQuote, error) {
+	return loyalty.NewPointsLedger(r.db.WithContext(ctx)).Quote(ctx, tenantID, customerID, points, orderAmount)
+}
+
+// Commit runs every write of a checkout in one transaction. Each step uses a
+// conditional update or row lock so concurrent checkouts cannot oversell
+// stock, exceed a discount's usage limit or overdraw loyalty points or a gift
+// card; the first failure rolls back everything, including the cart
+// conversion.
+func (r *repository) Commit(ctx context.Context, session *Session, newOrder *order.Order, usage CommitContext) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		// 1. Convert the cart; a second submit finds it already converted
+		result := tx.Model(&cart.Cart{}).
+			Where("id = ? AND tenant_id = ? AND status = ?", session.CartID, session.TenantID, cart.StatusActive).
+			Updates(map[string]interface{}{"status": cart.StatusConverted, "updated_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return newError(CodeCartConverted, "cart has already been checked out")
+		}
+
+		// 2. Reserve stock
+		for _, line := range session.Lines {
+			if err := reserveStock(tx, session.TenantID, line); err != nil {
+				return err
+			}
+		}
+
+		// 3. Create the order
+		if err := tx.Create(newOrder).Error; err != nil {
+			return newError(CodeOrderFailed, "failed to create order: %v", err)
+		}
+		history := &order.OrderHistory{
+			ID:                  uuid.New(),
+			OrderID:             newOrder.ID,
+			TenantID:            newOrder.TenantID,
+			ToStatus:            newOrder.Status,
+			ToPaymentStatus:     newOrder.PaymentStatus,
+			ToFulfillmentStatus: newOrder.FulfillmentStatus,
+			Action:              "created",
+			Description:         "Order placed through checkout",
+			ChangedBy:           session.CustomerID,
+			ChangedByType:       "customer",
+			Metadata:            map[string]interface{}{"checkout_session_id": session.ID.String()},
+			CreatedAt:           now,
+		}
+		if err := tx.Create(history).Error; err != nil {
+			return newError(CodeOrderFailed, "failed to create order history: %v", err)
+		}
+
+		// 4. Consume discount usages
+		for _, applied := range session.Discounts {
+			// Tier benefits are not stored discounts and have no usage limits
+			if applied.Benefit != "" {
+				continue
+			}
+
+			result := tx.Model(&discount.Discount{}).
+				Where("id = ? AND tenant_id = ? AND (usage_limit IS NULL OR usage_count < usage_limit)", applied.DiscountID, session.TenantID).
+				UpdateColumn("usage_count", gorm.Expr("usage_count + 1"))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return newError(CodeDiscountExhausted, "discount code %s has reached its usage limit", applied.Code).
+					withDetails(map[string]interface{}{"code": applied.Code})
+			}
+
+			// Single-use pool codes are claimed under the same rules
+			if applied.CodeID != nil {
+				result := tx.Model(&discount.DiscountCode{}).
+					Where("id = ? AND tenant_id = ? AND status = ? AND usage_count < usage_limit", *applied.CodeID, session.TenantID, discount.CodeStatusAvailable).
+					Updates(map[string]interface{}{
+						"usage_count":    gorm.Expr("usage_count + 1"),
+						"status":         gorm.Expr("CASE WHEN usage_count + 1 >= usage_limit THEN ? ELSE status END", discount.CodeStatusRedeemed),
+						"redeemed_at":    now,
+						"order_id":       newOrder.ID,
+						"order_number":   newOrder.OrderNumber,
+						"customer_id":    session.CustomerID,
+						"customer_email": session.Email,
+						"updated_at":     now,
+					})
+				if result.Error != nil {
+					return result.Error
+				}
+				if result.RowsAffected == 0 {
+					return newError(CodeDiscountExhausted, "discount code %s has already been used", applied.Code).
+						withDetails(map[string]interface{}{"code": applied.Code})
+				}
+			}
+
+			if err := tx.Create(&discount.DiscountUsage{
+				ID:             uuid.New(),
+				DiscountID:     applied.DiscountID,
+				TenantID:       session.TenantID,
+				OrderID:        newOrder.ID,
+				OrderNumber:    newOrder.OrderNumber,
+				CustomerID:     session.CustomerID,
+				CustomerEmail:  session.Email,
+				DiscountAmount: applied.Amount,
+				OrderAmount:    newOrder.TotalAmount,
+				IPAddress:      usage.IPAddress,
+				UserAgent:      usage.UserAgent,
+				UsedAt:         now,
+				CreatedAt:      now,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		// 5. Spend loyalty points. They are restored if the order is cancelled.
+		if session.PointsRedeemed > 0 && session.CustomerID != nil {
+			redemption, err := loyalty.NewPointsLedger(tx).Redeem(ctx, &loyalty.PointsRedemption{
+				TenantID:    session.TenantID,
+				UserID:      *session.CustomerID,
+				Points:      session.PointsRedeemed,
+				Amount:      session.PointsAmount,
+				OrderID:     newOrder.ID,
+				OrderNumber: newOrder.OrderNumber,
+				Reference:   "checkout:" + session.ID.String(),
+			})
+			if err != nil {
+				if errors.Is(err, loyalty.ErrInsufficientPoints) || errors.Is(err, loyalty.ErrPointsNotRedeemable) {
+					return newError(CodeLoyaltyPointsBalance, "you no longer have %d loyalty points to spend", session.PointsRedeemed).
+						withDetails(map[string]interface{}{"points": session.PointsRedeemed})
+				}
+				return err
+			}
+			if redemption != nil {
+				session.PointsTransactionID = &redemption.ID
+			}
+		}
+
+		// 6. Hold gift card balances. The ledger locks each card so parallel
+		// checkouts cannot spend the same balance; nothing is left to collect
+		// for cash on delivery or fully covered orders, so those are captured
+		// straight away, otherwise the holds wait for the payment outcome.
+		ledger := discount.NewLedger(tx)
+		capture := session.PaymentMethod == PaymentCOD || session.Total <= 0
+		holdExpiry := now.Add(discount.DefaultHoldDuration)
+		for i, applied := range session.GiftCards {
+			orderID := newOrder.ID
+			hold := &discount.BalanceHold{
+				TenantID:      session.TenantID,
+				AccountType:   discount.AccountGiftCard,
+				AccountID:     applied.GiftCardID,
+				Amount:        applied.Amount,
+				Reference:     "checkout:" + session.ID.String(),
+				OrderID:       &orderID,
+				OrderNumber:   newOrder.OrderNumber,
+				CustomerID:    session.CustomerID,
+				CustomerEmail: session.Email,
+				ExpiresAt:     &holdExpiry,
+			}
+			if err := ledger.Hold(ctx, hold); err != nil {
+				if errors.Is(err, discount.ErrInsufficientBalance) || errors.Is(err, discount.ErrAccountNotUsable) {
+					return newError(CodeGiftCardBalance, "gift card %s no longer has enough balance", maskCode(applied.Code)).
+						withDetails(map[string]interface{}{"code": maskCode(applied.Code)})
+				}
+				return err
+			}
+			if capture {
+				if _, err := ledger.Capture(ctx, session.TenantID, hold.ID); err != nil {
+					return err
+				}
+			}
+			session.GiftCards[i].HoldID = &hold.ID
+		}
+
+		// 7. Record the gift cards bought with the order. They are issued
+		// once payment succeeds, or right away when nothing is left to pay.
+		if purchases := buildGiftCardPurchases(session, newOrder); len(purchases) > 0 {
+			if err := tx.Create(&purchases).Error; err != nil {
+				return newError(CodeOrderFailed, "failed to record gift card purchases: %v", err)
+			}
+			if newOrder.PaymentStatus == order.PaymentPaid {
+				if _, err := ledger.IssuePurchases(ctx, session.TenantID, newOrder.ID); err != nil {
+					return newError(CodeOrderFailed, "failed to issue gift cards: %v", err)
+				}
+			}
+		}
+
+		// 8. Complete the session
+		session.Status = StatusCompleted
+		session.OrderID = &newOrder.ID
+		session.OrderNumber = newOrder.OrderNumber
+		session.CompletedAt = &now
+		session.ErrorCode = ""
+		session.ErrorMessage = ""
+		return tx.Save(session).Error
+	})
+}
+
+// reserveStock decrements tracked inventory only while enough is left
+func reserveStock(tx *gorm.DB, tenantID uuid.UUID, line Line) error {
+	var result *gorm.DB
+	if line.VariantID != nil {
+		result = tx.Model(&product.ProductVariant{}).
+			Where("id = ? AND product_id = ? AND (track_quantity = false OR allow_backorder = true OR inventory_quantity >= ?)",
+				*line.VariantID, line.ProductID, line.Quantity).
+			UpdateColumn("inventory_quantity", gorm.Expr("CASE WHEN track_quantity THEN inventory_quantity - ? ELSE inventory_quantity END", line.Quantity))
+	} else {
+		result = tx.Model(&product.Product{}).
+			Where("id = ? AND tenant_id = ? AND (track_quantity = false OR allow_backorder = true OR inventory_quantity >= ?)",
+				line.ProductID, tenantID, line.Quantity).
+			UpdateColumn("inventory_quantity", gorm.Expr("CASE WHEN track_quantity THEN inventory_quantity - ? ELSE inventory_quantity END", line.Quantity))
+	}
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return newError(CodeInsufficientStock, "%s is out of stock for the requested quantity", line.Name).
+			withDetails(map[string]interface{}{"product_id": line.ProductID, "variant_id": line.VariantID, "quantity": line.Quantity})
+	}
+	return nil
+}
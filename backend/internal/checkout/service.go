@@ -0,0 +1,1010 @@
+package checkout
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"ecommerce-saas/internal/cart"
+	"ecommerce-saas/internal/discount"
+	"ecommerce-saas/internal/loyalty"
+	"ecommerce-saas/internal/order"
+	"ecommerce-saas/internal/product"
+)
+
+// sessionTTL is how long a checkout session stays open
+const sessionTTL = time.Hour
+
+// ShippingCalculator prices the selected shipping method (cart.ShippingAdapter)
+type ShippingCalculator interface {
+	CalculateShipping(tenantID uuid.UUID, c *cart.Cart, methodID uuid.UUID) (float64, error)
+}
+
+// TaxCalculator computes tax for the priced cart (cart.TaxService)
+type TaxCalculator interface {
+	CalculateTax(tenantID uuid.UUID, c *cart.Cart) (float64, error)
+}
+
+// CartValidator checks cart items against the current catalog and records
+// price and stock changes the customer must acknowledge (cart.CartService)
+type CartValidator interface {
+	RevalidateCart(tenantID, cartID uuid.UUID, autoAdjust bool) (*cart.CartResponse, error)
+	AcknowledgeChanges(tenantID, cartID uuid.UUID) (*cart.CartResponse, error)
+}
+
+// Service defines the checkout service interface
+type Service interface {
+	CreateSession(ctx context.Context, tenantID uuid.UUID, caller Caller, req CreateSessionRequest) (*Session, error)
+	GetSession(ctx context.Context, tenantID, sessionID uuid.UUID, caller Caller) (*Session, error)
+	UpdateSession(ctx context.Context, tenantID, sessionID uuid.UUID, caller Caller, req UpdateSessionRequest) (*Session, error)
+	AcknowledgeCartChanges(ctx context.Context, tenantID, sessionID uuid.UUID, caller Caller) (*Session, error)
+	CompleteSession(ctx context.Context, tenantID, sessionID uuid.UUID, caller Caller, req CompleteSessionRequest) (*CompleteSessionResponse, error)
+}
+
+// Caller identifies who is checking out: a signed-in customer, or a guest by
+// the session ID their cart was created with
+type Caller struct {
+	CustomerID    *uuid.UUID
+	CartSessionID string
+}
+
+// owns reports whether the caller may check out the cart. Customer carts
+// belong to the signed-in customer, guest carts to their cart session.
+func (caller Caller) owns(c *cart.Cart) bool {
+	if c.CustomerID != nil {
+		return caller.CustomerID != nil && *caller.CustomerID == *c.CustomerID
+	}
+	return c.SessionID != "" && caller.CartSessionID == c.SessionID
+}
+
+type CreateSessionRequest struct {
+	CartID uuid.UUID `json:"cart_id" binding:"required"`
+}
+
+// UpdateSessionRequest changes any part of the session; omitted fields are kept
+type UpdateSessionRequest struct {
+	Email            *string       `json:"email,omitempty" binding:"omitempty,email"`
+	Phone            *string       `json:"phone,omitempty"`
+	ShippingAddress  *cart.Address `json:"shipping_address,omitempty"`
+	BillingAddress   *cart.Address `json:"billing_address,omitempty"`
+	ShippingMethodID *uuid.UUID    `json:"shipping_method_id,omitempty"`
+	PaymentMethod    *string       `json:"payment_method,omitempty" binding:"omitempty,oneof=cod bkash nagad sslcommerz stripe paypal"`
+	DiscountCodes    *[]string     `json:"discount_codes,omitempty"`
+	GiftCardCodes    *[]string     `json:"gift_card_codes,omitempty"`
+	LoyaltyPoints    *int          `json:"loyalty_points,omitempty" binding:"omitempty,min=0"`
+	Notes            *string       `json:"notes,omitempty" binding:"omitempty,max=500"`
+}
+
+// CompleteSessionRequest places the order. ExpectedTotal is the total the
+// customer confirmed; if prices moved since, completion stops with
+// price_changed and the refreshed session.
+type CompleteSessionRequest struct {
+	ExpectedTotal *float64 `json:"expected_total,omitempty"`
+	IPAddress     string   `json:"-"`
+	UserAgent     string   `json:"-"`
+}
+
+type CompleteSessionResponse struct {
+	Session         *Session  `json:"session"`
+	OrderID         uuid.UUID `json:"order_id"`
+	OrderNumber     string    `json:"order_number"`
+	Total           float64   `json:"total"`
+	PaymentMethod   string    `json:"payment_method"`
+	PaymentRequired bool      `json:"payment_required"`
+}
+
+type service struct {
+	repo     Repository
+	shipping ShippingCalculator
+	tax      TaxCalculator
+	tiers    discount.TierResolver
+	carts    CartValidator
+}
+
+// NewService creates a new checkout service. Shipping and tax calculators are
+// optional; without them those amounts are zero. Without a tier resolver no
+// loyalty tier promotions or benefits apply. Without a cart validator carts
+// are not re-checked against the catalog before pricing.
+func NewService(repo Repository, shippingCalculator ShippingCalculator, taxCalculator TaxCalculator, tiers discount.TierResolver, carts CartValidator) Service {
+	return &service{repo: repo, shipping: shippingCalculator, tax: taxCalculator, tiers: tiers, carts: carts}
+}
+
+// CreateSession starts checkout for a cart, reusing an open session for the
+// same cart so a page refresh does not create duplicates
+func (s *service) CreateSession(ctx context.Context, tenantID uuid.UUID, caller Caller, req CreateSessionRequest) (*Session, error) {
+	c, err := s.loadCart(ctx, tenantID, req.CartID, caller)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing, err := s.repo.GetOpenSessionByCart(ctx, tenantID, c.ID); err == nil {
+		if err := s.price(ctx, existing, c); err != nil {
+			return nil, err
+		}
+		if err := s.repo.UpdateSession(ctx, existing); err != nil {
+			return nil, err
+		}
+		return existing, nil
+	}
+
+	session := &Session{
+		ID:               uuid.New(),
+		TenantID:         tenantID,
+		CartID:           c.ID,
+		CustomerID:       c.CustomerID,
+		Status:           StatusOpen,
+		ShippingAddress:  c.ShippingAddress,
+		BillingAddress:   c.BillingAddress,
+		ShippingMethodID: c.ShippingMethodID,
+		Notes:            c.Notes,
+		Currency:         c.Currency,
+		ExpiresAt:        time.Now().Add(sessionTTL),
+	}
+	if caller.CustomerID != nil {
+		session.CustomerID = caller.CustomerID
+	}
+	if session.ShippingAddress != nil {
+		session.Phone = session.ShippingAddress.Phone
+	}
+	if c.CouponCode != "" {
+		session.DiscountCodes = []string{c.CouponCode}
+	}
+
+	// A stale coupon on the cart should not block checkout from starting
+	if err := s.price(ctx, session, c); err != nil {
+		var checkoutErr *Error
+		if !errors.As(err, &checkoutErr) || (checkoutErr.Code != CodeInvalidDiscount &&
+			checkoutErr.Code != CodeDiscountNotStackable && checkoutErr.Code != CodeDiscountExhausted) {
+			return nil, err
+		}
+		session.DiscountCodes = nil
+		if err := s.price(ctx, session, c); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.CreateSession(ctx, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *service) GetSession(ctx context.Context, tenantID, sessionID uuid.UUID, caller Caller) (*Session, error) {
+	return s.authorizedSession(ctx, tenantID, sessionID, caller)
+}
+
+// UpdateSession applies the customer's choices and re-prices the session.
+// Nothing is saved if a discount, gift card, points or shipping choice is
+// rejected.
+func (s *service) UpdateSession(ctx context.Context, tenantID, sessionID uuid.UUID, caller Caller, req UpdateSessionRequest) (*Session, error) {
+	session, err := s.openSession(ctx, tenantID, sessionID, caller)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := s.loadCart(ctx, tenantID, session.CartID, caller)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Email != nil {
+		session.Email = strings.ToLower(strings.TrimSpace(*req.Email))
+	}
+	if req.Phone != nil {
+		session.Phone = strings.TrimSpace(*req.Phone)
+	}
+	if req.ShippingAddress != nil {
+		session.ShippingAddress = req.ShippingAddress
+		if session.Phone == "" {
+			session.Phone = req.ShippingAddress.Phone
+		}
+	}
+	if req.BillingAddress != nil {
+		session.BillingAddress = req.BillingAddress
+	}
+	if req.ShippingMethodID != nil {
+		session.ShippingMethodID = req.ShippingMethodID
+	}
+	if req.PaymentMethod != nil {
+		session.PaymentMethod = *req.PaymentMethod
+	}
+	if req.DiscountCodes != nil {
+		session.DiscountCodes = normalizeCodes(*req.DiscountCodes)
+	}
+	if req.GiftCardCodes != nil {
+		session.GiftCardCodes = normalizeCodes(*req.GiftCardCodes)
+	}
+	if req.LoyaltyPoints != nil {
+		session.LoyaltyPoints = *req.LoyaltyPoints
+	}
+	if req.Notes != nil {
+		session.Notes = strings.TrimSpace(*req.Notes)
+	}
+
+	if err := s.price(ctx, session, c); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateSession(ctx, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// AcknowledgeCartChanges accepts the price and stock changes found on the
+// session's cart, applying any that were only reported, and re-prices the
+// session
+func (s *service) AcknowledgeCartChanges(ctx context.Context, tenantID, sessionID uuid.UUID, caller Caller) (*Session, error) {
+	session, err := s.openSession(ctx, tenantID, sessionID, caller)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.carts != nil {
+		if _, err := s.carts.AcknowledgeChanges(tenantID, session.CartID); err != nil {
+			if errors.Is(err, cart.ErrCartNotFound) {
+				return nil, newError(CodeCartNotFound, "cart not found")
+			}
+			return nil, err
+		}
+	}
+
+	c, err := s.loadCart(ctx, tenantID, session.CartID, caller)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.price(ctx, session, c); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateSession(ctx, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// CompleteSession re-validates prices and places the order atomically
+func (s *service) CompleteSession(ctx context.Context, tenantID, sessionID uuid.UUID, caller Caller, req CompleteSessionRequest) (*CompleteSessionResponse, error) {
+	session, err := s.authorizedSession(ctx, tenantID, sessionID, caller)
+	if err != nil {
+		return nil, err
+	}
+
+	// Completing twice returns the order placed the first time
+	if session.Status == StatusCompleted && session.OrderID != nil {
+		return completeResponse(session), nil
+	}
+
+	if _, err := s.openSession(ctx, tenantID, sessionID, caller); err != nil {
+		return nil, err
+	}
+
+	c, err := s.loadCart(ctx, tenantID, session.CartID, caller)
+	if err != nil {
+		return nil, s.fail(ctx, session, err)
+	}
+
+	if err := validateForCompletion(session, s.shipping != nil); err != nil {
+		return nil, s.fail(ctx, session, err)
+	}
+
+	previousTotal := session.Total
+	if err := s.price(ctx, session, c); err != nil {
+		return nil, s.fail(ctx, session, err)
+	}
+
+	expected := previousTotal
+	if req.ExpectedTotal != nil {
+		expected = *req.ExpectedTotal
+	}
+	if math.Abs(session.Total-expected) > 0.005 {
+		changed := newError(CodePriceChanged, "prices have changed since checkout was last reviewed").
+			withDetails(map[string]interface{}{"previous_total": expected, "total": session.Total})
+		return nil, s.fail(ctx, session, changed)
+	}
+
+	newOrder := buildOrder(session)
+	if err := s.repo.Commit(ctx, session, newOrder, CommitContext{IPAddress: req.IPAddress, UserAgent: req.UserAgent}); err != nil {
+		var checkoutErr *Error
+		if !errors.As(err, &checkoutErr) {
+			err = newError(CodeOrderFailed, "failed to place order: %v", err)
+		}
+		// The transaction rolled back the session too; reload the persisted copy
+		if stored, loadErr := s.repo.GetSession(ctx, tenantID, sessionID); loadErr == nil {
+			stored.Lines, stored.Discounts, stored.GiftCards = session.Lines, session.Discounts, session.GiftCards
+			session = stored
+		}
+		return nil, s.fail(ctx, session, err)
+	}
+
+	return completeResponse(session), nil
+}
+
+// Helper methods
+
+// authorizedSession loads a session of the caller. Sessions of signed-in
+// customers belong to them; guest sessions to whoever owns their cart.
+// Sessions of others are reported as not found.
+func (s *service) authorizedSession(ctx context.Context, tenantID, sessionID uuid.UUID, caller Caller) (*Session, error) {
+	session, err := s.repo.GetSession(ctx, tenantID, sessionID)
+	if err != nil {
+		return nil, newError(CodeSessionNotFound, "checkout session not found")
+	}
+
+	if session.CustomerID != nil {
+		if caller.CustomerID == nil || *caller.CustomerID != *session.CustomerID {
+			return nil, newError(CodeSessionNotFound, "checkout session not found")
+		}
+		return session, nil
+	}
+
+	c, err := s.repo.GetCart(ctx, tenantID, session.CartID)
+	if err != nil || !caller.owns(c) {
+		return nil, newError(CodeSessionNotFound, "checkout session not found")
+	}
+	return session, nil
+}
+
+// openSession loads a session of the caller that can still be changed
+func (s *service) openSession(ctx context.Context, tenantID, sessionID uuid.UUID, caller Caller) (*Session, error) {
+	session, err := s.authorizedSession(ctx, tenantID, sessionID, caller)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case session.Status == StatusCompleted:
+		return nil, newError(CodeSessionCompleted, "checkout session has already been completed")
+	case session.IsExpired():
+		if session.Status != StatusExpired {
+			session.Status = StatusExpired
+			_ = s.repo.UpdateSession(ctx, session)
+		}
+		return nil, newError(CodeSessionExpired, "checkout session has expired, please start again")
+	}
+	return session, nil
+}
+
+// loadCart loads the cart and checks it belongs to the caller
+func (s *service) loadCart(ctx context.Context, tenantID, cartID uuid.UUID, caller Caller) (*cart.Cart, error) {
+	c, err := s.repo.GetCart(ctx, tenantID, cartID)
+	if err != nil {
+		return nil, newError(CodeCartNotFound, "cart not found")
+	}
+	if !caller.owns(c) {
+		return nil, newError(CodeCartNotFound, "cart not found")
+	}
+	if c.Status == cart.StatusConverted {
+		return nil, newError(CodeCartConverted, "cart has already been checked out")
+	}
+	if c.IsExpired() || c.Status == cart.StatusExpired {
+		return nil, newError(CodeCartNotFound, "cart has expired")
+	}
+	if len(c.Items) == 0 {
+		return nil, newError(CodeCartEmpty, "cart is empty")
+	}
+
+	// Catalog changes since the items were added must be acknowledged first
+	if s.carts != nil {
+		if _, err := s.carts.RevalidateCart(tenantID, c.ID, false); err != nil {
+			return nil, err
+		}
+		if c, err = s.repo.GetCart(ctx, tenantID, cartID); err != nil {
+			return nil, newError(CodeCartNotFound, "cart not found")
+		}
+		if len(c.Items) == 0 {
+			return nil, newError(CodeCartEmpty, "cart is empty")
+		}
+	}
+	if c.HasPendingChanges() {
+		return nil, newError(CodeCartChanged, "cart items have changed, please review and acknowledge the changes").
+			withDetails(map[string]interface{}{"changes": c.PendingChanges})
+	}
+	return c, nil
+}
+
+// price rebuilds lines, shipping, discounts, tax, loyalty points and gift
+// cards from current data
+func (s *service) price(ctx context.Context, session *Session, c *cart.Cart) error {
+	lines, err := s.priceLines(ctx, session.TenantID, c)
+	if err != nil {
+		return err
+	}
+
+	subtotal := 0.0
+	for _, line := range lines {
+		subtotal += line.LineTotal
+	}
+	subtotal = roundMoney(subtotal)
+
+	// Work on a copy of the cart carrying current prices and the session address
+	priced := *c
+	priced.Items = make([]cart.CartItem, len(c.Items))
+	copy(priced.Items, c.Items)
+	for i := range priced.Items {
+		for _, line := range lines {
+			if line.CartItemID == priced.Items[i].ID {
+				priced.Items[i].Price = line.UnitPrice
+				priced.Items[i].LineTotal = line.LineTotal
+			}
+		}
+	}
+	priced.ShippingAddress = session.ShippingAddress
+	priced.BillingAddress = session.BillingAddress
+	priced.ShippingMethodID = session.ShippingMethodID
+	priced.PaymentMethod = session.PaymentMethod
+	priced.Subtotal = subtotal
+	// Amounts of the last pricing, for the cash a COD carrier collects
+	priced.DiscountAmount = session.DiscountAmount
+	priced.TaxAmount = session.TaxAmount
+
+	shippingAmount := 0.0
+	if s.shipping != nil && session.ShippingMethodID != nil && session.ShippingAddress != nil {
+		cost, err := s.shipping.CalculateShipping(session.TenantID, &priced, *session.ShippingMethodID)
+		if err != nil {
+			return newError(CodeShippingUnavailable, "selected shipping method is not available: %v", err)
+		}
+		shippingAmount = roundMoney(cost)
+	}
+
+	discounts, discountAmount, err := s.priceDiscounts(ctx, session, lines, shippingAmount)
+	if err != nil {
+		return err
+	}
+
+	taxAmount := 0.0
+	if s.tax != nil && session.ShippingAddress != nil && session.ShippingAddress.Country != "" {
+		priced.DiscountAmount = discountAmount
+		amount, err := s.tax.CalculateTax(session.TenantID, &priced)
+		if err != nil {
+			return newError(CodeTaxUnavailable, "tax could not be calculated: %v", err)
+		}
+		taxAmount = roundMoney(amount)
+	}
+
+	due := roundMoney(subtotal + shippingAmount + taxAmount - discountAmount)
+	if due < 0 {
+		due = 0
+	}
+
+	// Points pay first, so their cap applies to the whole order value
+	points, pointsAmount, err := s.pricePoints(ctx, session, due)
+	if err != nil {
+		return err
+	}
+
+	giftCards, giftCardAmount, err := s.priceGiftCards(ctx, session, roundMoney(due-pointsAmount))
+	if err != nil {
+		return err
+	}
+
+	// Attribute promotions to lines for per-item tax and refunds
+	for i := range lines {
+		lines[i].Discount = 0
+		for _, applied := range discounts {
+			for _, allocation := range applied.Lines {
+				if allocation.LineID == lines[i].CartItemID.String() {
+					lines[i].Discount = roundMoney(lines[i].Discount + allocation.Amount)
+				}
+			}
+		}
+	}
+
+	now := time.Now()
+	session.Lines = lines
+	session.Discounts = discounts
+	session.GiftCards = giftCards
+	session.Subtotal = subtotal
+	session.ShippingAmount = shippingAmount
+	session.DiscountAmount = discountAmount
+	session.TaxAmount = taxAmount
+	session.PointsRedeemed = points
+	session.PointsAmount = pointsAmount
+	session.GiftCardAmount = giftCardAmount
+	session.Total = roundMoney(due - pointsAmount - giftCardAmount)
+	session.PricedAt = &now
+	return nil
+}
+
+// priceLines prices every cart item from the product catalog, ignoring the
+// price captured in the cart
+func (s *service) priceLines(ctx context.Context, tenantID uuid.UUID, c *cart.Cart) ([]Line, error) {
+	ids := make([]uuid.UUID, 0, len(c.Items))
+	for _, item := range c.Items {
+		ids = append(ids, item.ProductID)
+	}
+
+	products, err := s.repo.GetProducts(ctx, tenantID, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*product.Product, len(products))
+	for i := range products {
+		byID[products[i].ID] = &products[i]
+	}
+	collectionIDs, err := s.repo.GetProductCollectionIDs(ctx, tenantID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]Line, 0, len(c.Items))
+	for _, item := range c.Items {
+		p, ok := byID[item.ProductID]
+		if !ok || p.Status != product.StatusActive {
+			return nil, newError(CodeProductUnavailable, "%s is no longer available", item.ProductName).
+				withDetails(map[string]interface{}{"product_id": item.ProductID})
+		}
+
+		line := Line{
+			CartItemID: item.ID,
+			ProductID:  p.ID,
+			Name:       p.Name,
+			SKU:        p.SKU,
+			Quantity:   item.Quantity,
+			UnitPrice:  p.Price,
+			CartPrice:  item.Price,
+		}
+		if p.CategoryID != uuid.Nil {
+			categoryID := p.CategoryID
+			line.CategoryID = &categoryID
+		}
+		line.CollectionIDs = collectionIDs[p.ID]
+
+		// Gift cards cost the amount chosen for them and are never out of stock
+		if p.IsGiftCard() {
+			if item.GiftCard == nil {
+				return nil, newError(CodeGiftCardDetails, "please enter who should receive the %s", p.Name).
+					withDetails(map[string]interface{}{"product_id": p.ID, "cart_item_id": item.ID})
+			}
+			if !p.AcceptsGiftCardAmount(item.GiftCard.Amount) {
+				return nil, newError(CodeProductUnavailable, "%s is no longer offered for %.2f", p.Name, item.GiftCard.Amount).
+					withDetails(map[string]interface{}{"product_id": p.ID, "amount": item.GiftCard.Amount})
+			}
+			details := *item.GiftCard
+			line.GiftCard = &details
+			line.GiftCardValidityDays = p.GiftCardValidityDays
+			line.UnitPrice = details.Amount
+			line.LineTotal = roundMoney(line.UnitPrice * float64(line.Quantity))
+			lines = append(lines, line)
+			continue
+		}
+
+		available, tracked, backorder := p.InventoryQuantity, p.TrackQuantity, p.AllowBackorder
+
+		if item.VariantID != nil {
+			var variant *product.ProductVariant
+			for i := range p.Variants {
+				if p.Variants[i].ID == *item.VariantID {
+					variant = &p.Variants[i]
+					break
+				}
+			}
+			if variant == nil {
+				return nil, newError(CodeProductUnavailable, "the selected option of %s is no longer available", p.Name).
+					withDetails(map[string]interface{}{"product_id": p.ID, "variant_id": item.VariantID})
+			}
+			line.VariantID = &variant.ID
+			line.VariantName = variant.Name
+			if variant.SKU != "" {
+				line.SKU = variant.SKU
+			}
+			if variant.Price > 0 {
+				line.UnitPrice = variant.Price
+			}
+			available, tracked, backorder = variant.InventoryQuantity, variant.TrackQuantity, variant.AllowBackorder
+		}
+
+		if tracked && !backorder && available < item.Quantity {
+			return nil, newError(CodeInsufficientStock, "only %d of %s left in stock", max(available, 0), p.Name).
+				withDetails(map[string]interface{}{"product_id": p.ID, "variant_id": item.VariantID, "available": max(available, 0)})
+		}
+
+		line.LineTotal = roundMoney(line.UnitPrice * float64(line.Quantity))
+		lines = append(lines, line)
+	}
+
+	return lines, nil
+}
+
+// priceDiscounts runs live automatic promotions and the entered codes through
+// the promotion engine. A rejected code is an error the customer must fix; a
+// rejected automatic promotion is only recorded on the session.
+func (s *service) priceDiscounts(ctx context.Context, session *Session, lines []Line, shippingAmount float64) ([]AppliedDiscount, float64, error) {
+	candidates, err := s.repo.GetAutomaticDiscounts(ctx, session.TenantID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	poolCodes := make(map[uuid.UUID]uuid.UUID)
+	for _, code := range session.DiscountCodes {
+		d, poolCode, err := s.resolveDiscountCode(ctx, session.TenantID, code)
+		if err != nil || d.IsAutomatic() {
+			return nil, 0, newError(CodeInvalidDiscount, "discount code %s is not valid", code).
+				withDetails(map[string]interface{}{"code": code})
+		}
+		if poolCode != nil {
+			if !poolCode.IsRedeemable() {
+				return nil, 0, newError(CodeDiscountExhausted, "discount code %s has already been used", code).
+					withDetails(map[string]interface{}{"code": code})
+			}
+			poolCodes[d.ID] = poolCode.ID
+		}
+		candidates = append(candidates, *d)
+	}
+
+	usage := make(map[uuid.UUID]int)
+	if session.Email != "" {
+		for _, d := range candidates {
+			if d.CustomerUsageLimit == nil {
+				continue
+			}
+			used, err := s.repo.CountCustomerDiscountUsage(ctx, session.TenantID, d.ID, session.Email)
+			if err != nil {
+				return nil, 0, err
+			}
+			usage[d.ID] = int(used)
+		}
+	}
+
+	input := discount.PromotionInput{
+		CustomerID:     session.CustomerID,
+		CustomerEmail:  session.Email,
+		ShippingAmount: shippingAmount,
+		CustomerUsage:  usage,
+	}
+	if s.tiers != nil && session.CustomerID != nil {
+		// A failed lookup only means no tier benefits
+		if tier, err := s.tiers.CustomerTier(ctx, session.TenantID, *session.CustomerID); err == nil {
+			input.LoyaltyTier = tier
+		}
+	}
+	for _, line := range lines {
+		// Gift cards are sold at face value and never count towards or
+		// receive a promotion
+		if line.GiftCard != nil {
+			continue
+		}
+		promotionLine := discount.PromotionLine{
+			ID:        line.CartItemID.String(),
+			ProductID: line.ProductID.String(),
+			Quantity:  line.Quantity,
+			UnitPrice: line.UnitPrice,
+		}
+		if line.VariantID != nil {
+			promotionLine.VariantID = line.VariantID.String()
+		}
+		if line.CategoryID != nil {
+			promotionLine.CategoryIDs = []string{line.CategoryID.String()}
+		}
+		for _, collectionID := range line.CollectionIDs {
+			promotionLine.CollectionIDs = append(promotionLine.CollectionIDs, collectionID.String())
+		}
+		input.Lines = append(input.Lines, promotionLine)
+	}
+
+	result := discount.EvaluatePromotions(candidates, input)
+
+	session.RejectedPromotions = nil
+	for _, rejected := range result.Rejected {
+		if rejected.Automatic {
+			session.RejectedPromotions = append(session.RejectedPromotions, rejected)
+			continue
+		}
+		code := CodeInvalidDiscount
+		switch rejected.Reason {
+		case discount.RejectNotCombinable:
+			code = CodeDiscountNotStackable
+		case discount.RejectUsageLimit:
+			code = CodeDiscountExhausted
+		}
+		return nil, 0, newError(code, "%s", rejected.Message).
+			withDetails(map[string]interface{}{"code": rejected.Code, "reason": rejected.Reason, "conflicts_with": rejected.ConflictsWith})
+	}
+
+	applied := make([]AppliedDiscount, 0, len(result.Applied))
+	for _, promotion := range result.Applied {
+		var codeID *uuid.UUID
+		if id, ok := poolCodes[promotion.DiscountID]; ok {
+			codeID = &id
+		}
+		applied = append(applied, AppliedDiscount{
+			DiscountID: promotion.DiscountID,
+			Code:       promotion.Code,
+			CodeID:     codeID,
+			Title:      promotion.Title,
+			Type:       string(promotion.Type),
+			Class:      string(promotion.Class),
+			Automatic:  promotion.Automatic,
+			Amount:     promotion.Amount,
+			Lines:      promotion.Lines,
+			Benefit:    promotion.Benefit,
+		})
+	}
+
+	return applied, result.TotalDiscount, nil
+}
+
+// resolveDiscountCode finds the discount for an entered code, falling back to
+// pool codes; the discount then carries the pool code
+func (s *service) resolveDiscountCode(ctx context.Context, tenantID uuid.UUID, code string) (*discount.Discount, *discount.DiscountCode, error) {
+	d, err := s.repo.GetDiscountByCode(ctx, tenantID, code)
+	if err == nil {
+		return d, nil, nil
+	}
+
+	poolCode, poolErr := s.repo.GetPoolCode(ctx, tenantID, code)
+	if poolErr != nil {
+		return nil, nil, err
+	}
+	d, err = s.repo.GetDiscountByID(ctx, tenantID, poolCode.DiscountID)
+	if err != nil {
+		return nil, nil, err
+	}
+	d.Code = poolCode.Code
+	return d, poolCode, nil
+}
+
+// pricePoints values the loyalty points the customer chose to pay with. The
+// points used are capped by the balance and the program's share of the order.
+func (s *service) pricePoints(ctx context.Context, session *Session, due float64) (int, float64, error) {
+	if session.LoyaltyPoints <= 0 {
+		return 0, 0, nil
+	}
+	if session.CustomerID == nil {
+		return 0, 0, newError(CodeLoyaltyPointsUnavailable, "please sign in to pay with loyalty points")
+	}
+
+	quote, err := s.repo.QuoteLoyaltyPoints(ctx, session.TenantID, *session.CustomerID, session.LoyaltyPoints, due)
+	if errors.Is(err, loyalty.ErrPointsNotRedeemable) {
+		checkoutErr := newError(CodeLoyaltyPointsUnavailable, "%s", err.Error())
+		if quote != nil {
+			checkoutErr.withDetails(map[string]interface{}{"balance": quote.Balance, "max_points": quote.MaxPoints})
+		}
+		return 0, 0, checkoutErr
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return quote.Points, quote.Amount, nil
+}
+
+// priceGiftCards draws down each gift card in turn until the order is covered
+func (s *service) priceGiftCards(ctx context.Context, session *Session, due float64) ([]AppliedGiftCard, float64, error) {
+	var applied []AppliedGiftCard
+	total := 0.0
+
+	for _, code := range session.GiftCardCodes {
+		giftCard, err := s.repo.GetGiftCardByCode(ctx, session.TenantID, code)
+		if err != nil || !giftCard.IsValid() {
+			return nil, 0, newError(CodeInvalidGiftCard, "gift card %s is not valid", maskCode(code)).
+				withDetails(map[string]interface{}{"code": maskCode(code)})
+		}
+		if giftCard.Currency != "" && session.Currency != "" && !strings.EqualFold(giftCard.Currency, session.Currency) {
+			return nil, 0, newError(CodeInvalidGiftCard, "gift card %s is in %s", maskCode(code), giftCard.Currency).
+				withDetails(map[string]interface{}{"code": maskCode(code)})
+		}
+
+		remaining := roundMoney(due - total)
+		if remaining <= 0 {
+			break
+		}
+		// Balance held by other checkouts cannot be spent
+		available := roundMoney(giftCard.AvailableBalance())
+		if available <= 0 {
+			return nil, 0, newError(CodeGiftCardBalance, "gift card %s has no available balance", maskCode(code)).
+				withDetails(map[string]interface{}{"code": maskCode(code)})
+		}
+		amount := roundMoney(math.Min(available, remaining))
+		total += amount
+		applied = append(applied, AppliedGiftCard{GiftCardID: giftCard.ID, Code: giftCard.Code, Amount: amount})
+	}
+
+	return applied, roundMoney(total), nil
+}
+
+// fail records the error on the session so the storefront and support can
+// see why a checkout did not complete
+func (s *service) fail(ctx context.Context, session *Session, err error) error {
+	var checkoutErr *Error
+	if errors.As(err, &checkoutErr) {
+		session.ErrorCode = checkoutErr.Code
+	} else {
+		session.ErrorCode = CodeOrderFailed
+	}
+	session.ErrorMessage = err.Error()
+	if updateErr := s.repo.UpdateSession(ctx, session); updateErr != nil && !errors.Is(updateErr, gorm.ErrRecordNotFound) {
+		return errors.Join(err, updateErr)
+	}
+	return err
+}
+
+// validateForCompletion checks the session has everything an order needs
+func validateForCompletion(session *Session, shippingEnabled bool) error {
+	if session.Email == "" && session.Phone == "" {
+		return newError(CodeContactRequired, "an email address or phone number is required")
+	}
+	if session.RequiresShipping() {
+		address := session.ShippingAddress
+		if address == nil || strings.TrimSpace(address.Address1) == "" || strings.TrimSpace(address.City) == "" ||
+			strings.TrimSpace(address.Country) == "" {
+			return newError(CodeAddressRequired, "a shipping address with street, city and country is required")
+		}
+		if shippingEnabled && session.ShippingMethodID == nil {
+			return newError(CodeShippingMethodRequired, "please choose a shipping method")
+		}
+	}
+	if session.PaymentMethod == "" {
+		return newError(CodePaymentMethodRequired, "please choose a payment method")
+	}
+	// Gift cards are issued on payment, which cash on delivery never confirms
+	if session.PaymentMethod == PaymentCOD && session.HasGiftCardLines() {
+		return newError(CodeGiftCardPrepayment, "gift cards must be paid for online")
+	}
+	return nil
+}
+
+// buildOrder maps the priced session onto a new pending order
+func buildOrder(session *Session) *order.Order {
+	now := time.Now()
+	orderID := uuid.New()
+
+	newOrder := &order.Order{
+		ID:                orderID,
+		TenantID:          session.TenantID,
+		OrderNumber:       order.GenerateOrderNumber(),
+		Status:            order.StatusPending,
+		CustomerEmail:     session.Email,
+		CustomerPhone:     session.Phone,
+		ShippingAddress:   toOrderAddress(session.ShippingAddress),
+		BillingAddress:    toOrderAddress(session.BillingAddress),
+		SubtotalAmount:    session.Subtotal,
+		TaxAmount:         session.TaxAmount,
+		ShippingAmount:    session.ShippingAmount,
+		DiscountAmount:    session.DiscountAmount,
+		PointsAmount:      session.PointsAmount,
+		GiftCardAmount:    session.GiftCardAmount,
+		TotalAmount:       session.Total,
+		Currency:          session.Currency,
+		PaymentStatus:     order.PaymentPending,
+		PaymentMethod:     session.PaymentMethod,
+		FulfillmentStatus: order.FulfillmentPending,
+		Notes:             session.Notes,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	if session.CustomerID != nil {
+		newOrder.UserID = *session.CustomerID
+	}
+	if session.PaymentMethod != PaymentCOD {
+		newOrder.PaymentGateway = session.PaymentMethod
+	}
+	if newOrder.BillingAddress.Address1 == "" {
+		newOrder.BillingAddress = newOrder.ShippingAddress
+	}
+	if newOrder.Currency == "" {
+		newOrder.Currency = "BDT"
+	}
+	// Fully covered by points and gift cards
+	if session.Total == 0 {
+		newOrder.PaymentStatus = order.PaymentPaid
+		newOrder.Status = order.StatusConfirmed
+	}
+
+	for _, line := range session.Lines {
+		newOrder.Items = append(newOrder.Items, order.OrderItem{
+			ID:             uuid.New(),
+			OrderID:        orderID,
+			ProductID:      line.ProductID,
+			VariantID:      line.VariantID,
+			ProductName:    line.Name,
+			ProductSKU:     line.SKU,
+			VariantName:    line.VariantName,
+			UnitPrice:      line.UnitPrice,
+			Quantity:       line.Quantity,
+			TotalPrice:     line.LineTotal,
+			DiscountAmount: line.Discount,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		})
+	}
+
+	return newOrder
+}
+
+// buildGiftCardPurchases records one pending gift card per unit of each gift
+// card line; they are issued once the order is paid
+func buildGiftCardPurchases(session *Session, newOrder *order.Order) []discount.GiftCardPurchase {
+	var purchases []discount.GiftCardPurchase
+	for _, line := range session.Lines {
+		if line.GiftCard == nil {
+			continue
+		}
+		for i := 0; i < line.Quantity; i++ {
+			purchases = append(purchases, discount.GiftCardPurchase{
+				ID:             uuid.New(),
+				TenantID:       session.TenantID,
+				OrderID:        newOrder.ID,
+				OrderNumber:    newOrder.OrderNumber,
+				ProductID:      line.ProductID,
+				VariantID:      line.VariantID,
+				Amount:         line.GiftCard.Amount,
+				Currency:       newOrder.Currency,
+				ValidityDays:   line.GiftCardValidityDays,
+				PurchaserID:    session.CustomerID,
+				PurchaserEmail: session.Email,
+				SenderName:     strings.TrimSpace(line.GiftCard.SenderName),
+				RecipientName:  strings.TrimSpace(line.GiftCard.RecipientName),
+				RecipientEmail: strings.TrimSpace(line.GiftCard.RecipientEmail),
+				Message:        strings.TrimSpace(line.GiftCard.Message),
+				SendAt:         line.GiftCard.SendAt,
+				Status:         discount.PurchaseStatusPending,
+				CreatedAt:      newOrder.CreatedAt,
+				UpdatedAt:      newOrder.CreatedAt,
+			})
+		}
+	}
+	return purchases
+}
+
+func toOrderAddress(address *cart.Address) order.Address {
+	if address == nil {
+		return order.Address{}
+	}
+	return order.Address{
+		FirstName:  address.FirstName,
+		LastName:   address.LastName,
+		Company:    address.Company,
+		Address1:   address.Address1,
+		Address2:   address.Address2,
+		City:       address.City,
+		State:      address.State,
+		PostalCode: address.PostalCode,
+		Country:    address.Country,
+		Phone:      address.Phone,
+	}
+}
+
+func completeResponse(session *Session) *CompleteSessionResponse {
+	return &CompleteSessionResponse{
+		Session:         session,
+		OrderID:         *session.OrderID,
+		OrderNumber:     session.OrderNumber,
+		Total:           session.Total,
+		PaymentMethod:   session.PaymentMethod,
+		PaymentRequired: session.RequiresPayment(),
+	}
+}
+
+// normalizeCodes upper-cases, trims and de-duplicates codes
+func normalizeCodes(codes []string) []string {
+	seen := make(map[string]bool, len(codes))
+	result := make([]string, 0, len(codes))
+	for _, code := range codes {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+		result = append(result, code)
+	}
+	return result
+}
+
+// maskCode hides all but the last four characters of a gift card code
+func maskCode(code string) string {
+	if len(code) <= 4 {
+		return code
+	}
+	return strings.Repeat("*", len(code)-4) + code[len(code)-4:]
+}
+
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
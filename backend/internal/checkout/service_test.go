@@ -0,0 +1,454 @@
+package checkout
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"ecommerce-saas/internal/cart"
+	"ecommerce-saas/internal/discount"
+	"ecommerce-saas/internal/loyalty"
+	"ecommerce-saas/internal/order"
+	"ecommerce-saas/internal/product"
+)
+
+// fakeRepository keeps sessions, carts and products in memory. Reads return
+// copies, as the database would.
+type fakeRepository struct {
+	sessions  map[uuid.UUID]Session
+	carts     map[uuid.UUID]cart.Cart
+	products  map[uuid.UUID]product.Product
+	orders    []*order.Order
+	commitErr error
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		sessions: make(map[uuid.UUID]Session),
+		carts:    make(map[uuid.UUID]cart.Cart),
+		products: make(map[uuid.UUID]product.Product),
+	}
+}
+
+func (r *fakeRepository) CreateSession(ctx context.Context, session *Session) error {
+	r.sessions[session.ID] = *session
+	return nil
+}
+
+func (r *fakeRepository) UpdateSession(ctx context.Context, session *Session) error {
+	r.sessions[session.ID] = *session
+	return nil
+}
+
+func (r *fakeRepository) GetSession(ctx context.Context, tenantID, sessionID uuid.UUID) (*Session, error) {
+	session, ok := r.sessions[sessionID]
+	if !ok || session.TenantID != tenantID {
+		return nil, errors.New("record not found")
+	}
+	return &session, nil
+}
+
+func (r *fakeRepository) GetOpenSessionByCart(ctx context.Context, tenantID, cartID uuid.UUID) (*Session, error) {
+	for _, session := range r.sessions {
+		if session.TenantID == tenantID && session.CartID == cartID && session.Status == StatusOpen {
+			return &session, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *fakeRepository) GetCart(ctx context.Context, tenantID, cartID uuid.UUID) (*cart.Cart, error) {
+	c, ok := r.carts[cartID]
+	if !ok || c.TenantID != tenantID {
+		return nil, errors.New("record not found")
+	}
+	c.Items = append([]cart.CartItem(nil), c.Items...)
+	return &c, nil
+}
+
+func (r *fakeRepository) GetProducts(ctx context.Context, tenantID uuid.UUID, productIDs []uuid.UUID) ([]product.Product, error) {
+	var products []product.Product
+	for _, id := range productIDs {
+		if p, ok := r.products[id]; ok && p.TenantID == tenantID {
+			products = append(products, p)
+		}
+	}
+	return products, nil
+}
+
+func (r *fakeRepository) GetProductCollectionIDs(ctx context.Context, tenantID uuid.UUID, productIDs []uuid.UUID) (map[uuid.UUID][]uuid.UUID, error) {
+	return map[uuid.UUID][]uuid.UUID{}, nil
+}
+
+func (r *fakeRepository) GetDiscountByCode(ctx context.Context, tenantID uuid.UUID, code string) (*discount.Discount, error) {
+	return nil, errors.New("record not found")
+}
+
+func (r *fakeRepository) GetAutomaticDiscounts(ctx context.Context, tenantID uuid.UUID) ([]discount.Discount, error) {
+	return nil, nil
+}
+
+func (r *fakeRepository) GetDiscountByID(ctx context.Context, tenantID, discountID uuid.UUID) (*discount.Discount, error) {
+	return nil, errors.New("record not found")
+}
+
+func (r *fakeRepository) GetPoolCode(ctx context.Context, tenantID uuid.UUID, code string) (*discount.DiscountCode, error) {
+	return nil, errors.New("record not found")
+}
+
+func (r *fakeRepository) CountCustomerDiscountUsage(ctx context.Context, tenantID, discountID uuid.UUID, email string) (int64, error) {
+	return 0, nil
+}
+
+func (r *fakeRepository) GetGiftCardByCode(ctx context.Context, tenantID uuid.UUID, code string) (*discount.GiftCard, error) {
+	return nil, errors.New("record not found")
+}
+
+func (r *fakeRepository) QuoteLoyaltyPoints(ctx context.Context, tenantID, customerID uuid.UUID, points int, orderAmount float64) (*loyalty.PointsQuote, error) {
+	return nil, loyalty.ErrPointsNotRedeemable
+}
+
+// Commit mirrors the outcome of the real transaction: on failure nothing is
+// saved, on success the cart is converted and the session completed
+func (r *fakeRepository) Commit(ctx context.Context, session *Session, newOrder *order.Order, usage CommitContext) error {
+	if r.commitErr != nil {
+		return r.commitErr
+	}
+	c := r.carts[session.CartID]
+	c.Status = cart.StatusConverted
+	r.carts[session.CartID] = c
+
+	session.Status = StatusCompleted
+	session.OrderID = &newOrder.ID
+	session.OrderNumber = newOrder.OrderNumber
+	session.ErrorCode, session.ErrorMessage = "", ""
+	r.sessions[session.ID] = *session
+	r.orders = append(r.orders, newOrder)
+	return nil
+}
+
+type fakeShipping struct {
+	cost float64
+	err  error
+}
+
+func (f *fakeShipping) CalculateShipping(tenantID uuid.UUID, c *cart.Cart, methodID uuid.UUID) (float64, error) {
+	return f.cost, f.err
+}
+
+type fakeTax struct {
+	rate float64
+	err  error
+}
+
+func (f *fakeTax) CalculateTax(tenantID uuid.UUID, c *cart.Cart) (float64, error) {
+	return (c.Subtotal - c.DiscountAmount) * f.rate, f.err
+}
+
+// fakeCarts reports the changes queued in pending when the cart is
+// re-validated, like cart.CartService comparing it with the catalog
+type fakeCarts struct {
+	repo    *fakeRepository
+	pending []cart.CartItemChange
+}
+
+func (f *fakeCarts) RevalidateCart(tenantID, cartID uuid.UUID, autoAdjust bool) (*cart.CartResponse, error) {
+	if len(f.pending) > 0 {
+		c := f.repo.carts[cartID]
+		c.PendingChanges = f.pending
+		f.repo.carts[cartID] = c
+	}
+	return nil, nil
+}
+
+func (f *fakeCarts) AcknowledgeChanges(tenantID, cartID uuid.UUID) (*cart.CartResponse, error) {
+	c := f.repo.carts[cartID]
+	for _, change := range c.PendingChanges {
+		for i := range c.Items {
+			if c.Items[i].ID == change.ItemID && change.NewPrice > 0 {
+				c.Items[i].Price = change.NewPrice
+			}
+		}
+	}
+	c.PendingChanges = nil
+	f.repo.carts[cartID] = c
+	f.pending = nil
+	return nil, nil
+}
+
+type checkoutFixture struct {
+	svc      Service
+	repo     *fakeRepository
+	shipping *fakeShipping
+	tax      *fakeTax
+	carts    *fakeCarts
+	tenantID uuid.UUID
+	caller   Caller
+	product  uuid.UUID
+	item     uuid.UUID
+	session  *Session
+}
+
+// newCheckoutFixture opens a session for two units of a 100 BDT product with
+// 60 BDT shipping and 5% tax, ready to complete
+func newCheckoutFixture(t *testing.T) *checkoutFixture {
+	t.Helper()
+
+	f := &checkoutFixture{
+		repo:     newFakeRepository(),
+		shipping: &fakeShipping{cost: 60},
+		tax:      &fakeTax{rate: 0.05},
+		tenantID: uuid.New(),
+		product:  uuid.New(),
+		item:     uuid.New(),
+	}
+	f.carts = &fakeCarts{repo: f.repo}
+	f.svc = NewService(f.repo, f.shipping, f.tax, nil, f.carts)
+
+	customerID := uuid.New()
+	f.caller = Caller{CustomerID: &customerID}
+
+	f.repo.products[f.product] = product.Product{
+		ID: f.product, TenantID: f.tenantID, Name: "Panjabi", SKU: "PJ-1", Price: 100,
+		Status: product.StatusActive, TrackQuantity: true, InventoryQuantity: 5,
+	}
+	cartID := uuid.New()
+	f.repo.carts[cartID] = cart.Cart{
+		ID: cartID, TenantID: f.tenantID, CustomerID: &customerID, Status: cart.StatusActive, Currency: "BDT",
+		Items: []cart.CartItem{{ID: f.item, CartID: cartID, ProductID: f.product, ProductName: "Panjabi", Quantity: 2, Price: 100}},
+	}
+
+	ctx := context.Background()
+	session, err := f.svc.CreateSession(ctx, f.tenantID, f.caller, CreateSessionRequest{CartID: cartID})
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+
+	email, payment, methodID := "rahim@example.com", PaymentCOD, uuid.New()
+	session, err = f.svc.UpdateSession(ctx, f.tenantID, session.ID, f.caller, UpdateSessionRequest{
+		Email:            &email,
+		PaymentMethod:    &payment,
+		ShippingMethodID: &methodID,
+		ShippingAddress:  &cart.Address{Address1: "House 12, Road 5", City: "Dhaka", Country: "BD", Phone: "01711000000"},
+	})
+	if err != nil {
+		t.Fatalf("UpdateSession() error = %v", err)
+	}
+	f.session = session
+	return f
+}
+
+func (f *checkoutFixture) complete(expected *float64) (*CompleteSessionResponse, error) {
+	return f.svc.CompleteSession(context.Background(), f.tenantID, f.session.ID, f.caller, CompleteSessionRequest{ExpectedTotal: expected})
+}
+
+func (f *checkoutFixture) setPrice(price float64) {
+	p := f.repo.products[f.product]
+	p.Price = price
+	f.repo.products[f.product] = p
+}
+
+func checkoutErrorCode(err error) string {
+	var checkoutErr *Error
+	if errors.As(err, &checkoutErr) {
+		return checkoutErr.Code
+	}
+	return ""
+}
+
+func TestSessionPricing(t *testing.T) {
+	f := newCheckoutFixture(t)
+
+	// 200 subtotal + 60 shipping + 10 tax
+	if f.session.Subtotal != 200 || f.session.ShippingAmount != 60 || f.session.TaxAmount != 10 || f.session.Total != 270 {
+		t.Errorf("session priced at subtotal %.2f shipping %.2f tax %.2f total %.2f, want 200/60/10/270",
+			f.session.Subtotal, f.session.ShippingAmount, f.session.TaxAmount, f.session.Total)
+	}
+}
+
+func TestCompleteSession(t *testing.T) {
+	f := newCheckoutFixture(t)
+
+	resp, err := f.complete(nil)
+	if err != nil {
+		t.Fatalf("CompleteSession() error = %v", err)
+	}
+	if len(f.repo.orders) != 1 {
+		t.Fatalf("committed %d orders, want 1", len(f.repo.orders))
+	}
+	placed := f.repo.orders[0]
+	if resp.OrderID != placed.ID || resp.OrderNumber != placed.OrderNumber || resp.Total != 270 || resp.PaymentRequired {
+		t.Errorf("response = %+v, want order %s for 270 with nothing to pay online", resp, placed.OrderNumber)
+	}
+	if placed.TotalAmount != 270 || placed.SubtotalAmount != 200 || placed.ShippingAmount != 60 || placed.TaxAmount != 10 {
+		t.Errorf("order amounts = %+v", placed)
+	}
+	if len(placed.Items) != 1 || placed.Items[0].Quantity != 2 || placed.Items[0].UnitPrice != 100 {
+		t.Errorf("order items = %+v", placed.Items)
+	}
+	if placed.CustomerEmail != "rahim@example.com" || placed.ShippingAddress.City != "Dhaka" {
+		t.Errorf("order contact = %s, %+v", placed.CustomerEmail, placed.ShippingAddress)
+	}
+
+	// Completing again, e.g. after a timeout on the client, returns the same order
+	again, err := f.complete(nil)
+	if err != nil {
+		t.Fatalf("second CompleteSession() error = %v", err)
+	}
+	if again.OrderID != resp.OrderID || len(f.repo.orders) != 1 {
+		t.Errorf("second completion placed order %s (%d orders), want %s", again.OrderID, len(f.repo.orders), resp.OrderID)
+	}
+}
+
+func TestCompleteSessionRevalidates(t *testing.T) {
+	total := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name      string
+		change    func(f *checkoutFixture)
+		expected  *float64
+		wantCode  string
+		wantTotal float64 // Of the stored session
+	}{
+		{
+			name:      "price increase since review",
+			change:    func(f *checkoutFixture) { f.setPrice(120) },
+			wantCode:  CodePriceChanged,
+			wantTotal: 312, // 240 + 60 + 12
+		},
+		{
+			name:      "price increase the customer confirmed",
+			change:    func(f *checkoutFixture) { f.setPrice(120) },
+			expected:  total(312),
+			wantTotal: 312,
+		},
+		{
+			name:     "client total does not match",
+			expected: total(200),
+			wantCode: CodePriceChanged,
+		},
+		{
+			name: "sold out",
+			change: func(f *checkoutFixture) {
+				p := f.repo.products[f.product]
+				p.InventoryQuantity = 1
+				f.repo.products[f.product] = p
+			},
+			wantCode: CodeInsufficientStock,
+		},
+		{
+			name: "product withdrawn",
+			change: func(f *checkoutFixture) {
+				p := f.repo.products[f.product]
+				p.Status = product.StatusInactive
+				f.repo.products[f.product] = p
+			},
+			wantCode: CodeProductUnavailable,
+		},
+		{
+			name:     "shipping method no longer offered",
+			change:   func(f *checkoutFixture) { f.shipping.err = errors.New("no rate for this destination") },
+			wantCode: CodeShippingUnavailable,
+		},
+		{
+			name:     "tax service failing",
+			change:   func(f *checkoutFixture) { f.tax.err = errors.New("tax service unavailable") },
+			wantCode: CodeTaxUnavailable,
+		},
+		{
+			name: "catalog change waiting for acknowledgement",
+			change: func(f *checkoutFixture) {
+				f.carts.pending = []cart.CartItemChange{{Type: cart.ChangePriceIncreased, ItemID: f.item, ProductID: f.product, OldPrice: 100, NewPrice: 120}}
+			},
+			wantCode: CodeCartChanged,
+		},
+		{
+			name:     "order transaction fails",
+			change:   func(f *checkoutFixture) { f.repo.commitErr = errors.New("connection reset") },
+			wantCode: CodeOrderFailed,
+		},
+		{
+			name: "discount used up while checking out",
+			change: func(f *checkoutFixture) {
+				f.repo.commitErr = newError(CodeDiscountExhausted, "discount code EID has already been used")
+			},
+			wantCode: CodeDiscountExhausted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newCheckoutFixture(t)
+			if tt.change != nil {
+				tt.change(f)
+			}
+
+			_, err := f.complete(tt.expected)
+			stored := f.repo.sessions[f.session.ID]
+
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatalf("CompleteSession() error = %v", err)
+				}
+				if stored.Status != StatusCompleted || stored.Total != tt.wantTotal {
+					t.Errorf("session %s total %.2f, want completed at %.2f", stored.Status, stored.Total, tt.wantTotal)
+				}
+				return
+			}
+
+			if code := checkoutErrorCode(err); code != tt.wantCode {
+				t.Fatalf("CompleteSession() error = %v (%s), want %s", err, code, tt.wantCode)
+			}
+			if len(f.repo.orders) != 0 {
+				t.Error("an order was placed")
+			}
+			if stored.Status != StatusOpen || stored.ErrorCode != tt.wantCode {
+				t.Errorf("stored session %s with error %q, want open with %q", stored.Status, stored.ErrorCode, tt.wantCode)
+			}
+			if tt.wantTotal != 0 && stored.Total != tt.wantTotal {
+				t.Errorf("stored total %.2f, want the refreshed %.2f", stored.Total, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestAcknowledgeCartChangesThenComplete(t *testing.T) {
+	f := newCheckoutFixture(t)
+	ctx := context.Background()
+
+	f.setPrice(120)
+	f.carts.pending = []cart.CartItemChange{{Type: cart.ChangePriceIncreased, ItemID: f.item, ProductID: f.product, OldPrice: 100, NewPrice: 120}}
+	if _, err := f.complete(nil); checkoutErrorCode(err) != CodeCartChanged {
+		t.Fatalf("CompleteSession() error = %v, want %s", err, CodeCartChanged)
+	}
+
+	session, err := f.svc.AcknowledgeCartChanges(ctx, f.tenantID, f.session.ID, f.caller)
+	if err != nil {
+		t.Fatalf("AcknowledgeCartChanges() error = %v", err)
+	}
+	if session.Total != 312 {
+		t.Fatalf("acknowledged session total %.2f, want 312", session.Total)
+	}
+
+	if _, err := f.complete(&session.Total); err != nil {
+		t.Fatalf("CompleteSession() after acknowledging error = %v", err)
+	}
+	if len(f.repo.orders) != 1 || f.repo.orders[0].Items[0].UnitPrice != 120 {
+		t.Errorf("orders = %+v, want one at the new price", f.repo.orders)
+	}
+}
+
+func TestCompleteSessionOfAnotherCustomer(t *testing.T) {
+	f := newCheckoutFixture(t)
+
+	stranger := uuid.New()
+	_, err := f.svc.CompleteSession(context.Background(), f.tenantID, f.session.ID, Caller{CustomerID: &stranger}, CompleteSessionRequest{})
+	if code := checkoutErrorCode(err); code != CodeSessionNotFound {
+		t.Errorf("CompleteSession() error = %v, want %s", err, CodeSessionNotFound)
+	}
+	if len(f.repo.orders) != 0 {
+		t.Error("an order was placed")
+	}
+}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+
+	"ecommerce-saas/internal/product"
 )
 
 // TODO: Implement content management entities
@@ -139,6 +141,7 @@ type MenuItem struct {
 	Title       string     `json:"title" gorm:"size:255;not null"`
 	URL         string     `json:"url" gorm:"size:500"`
 	PageID      *uuid.UUID `json:"page_id" gorm:"type:uuid"` // Link to internal page
+	CollectionID *uuid.UUID `json:"collection_id" gorm:"type:uuid"` // Link to product collection
 	Target      string     `json:"target" gorm:"size:20;default:'_self'"`
 	CSSClass    string     `json:"css_class" gorm:"size:100"`
 	IconClass   string     `json:"icon_class" gorm:"size:100"`
@@ -151,6 +154,7 @@ type MenuItem struct {
 	Parent   *MenuItem  `json:"parent" gorm:"foreignKey:ParentID"`
 	Children []MenuItem `json:"children" gorm:"foreignKey:ParentID"`
 	Page     *Page      `json:"page" gorm:"foreignKey:PageID"`
+	Collection *product.Collection `json:"collection,omitempty" gorm:"foreignKey:CollectionID"`
 }
 
 type Tag struct {
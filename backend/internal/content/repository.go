@@ -6,6 +6,8 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+
+	"ecommerce-saas/internal/product"
 )
 
 type Repository struct {
@@ -277,9 +279,9 @@ func (r *Repository) GetMenu(tenantID, menuID uuid.UUID) (*Menu, error) {
 	var menu Menu
 	err := r.db.Preload("Items", func(db *gorm.DB) *gorm.DB {
 		return db.Where("is_active = ?", true).Order("sort_order ASC")
-	}).Preload("Items.Page").Preload("Items.Children", func(db *gorm.DB) *gorm.DB {
+	}).Preload("Items.Page").Preload("Items.Collection").Preload("Items.Children", func(db *gorm.DB) *gorm.DB {
 		return db.Where("is_active = ?", true).Order("sort_order ASC")
-	}).
+	}).Preload("Items.Children.Collection").
 		Where("tenant_id = ? AND id = ?", tenantID, menuID).
 		First(&menu).Error
 	if err != nil {
@@ -307,9 +309,9 @@ func (r *Repository) GetMenuByLocation(tenantID uuid.UUID, location string) (*Me
 	var menu Menu
 	err := r.db.Preload("Items", func(db *gorm.DB) *gorm.DB {
 		return db.Where("is_active = ? AND parent_id IS NULL", true).Order("sort_order ASC")
-	}).Preload("Items.Page").Preload("Items.Children", func(db *gorm.DB) *gorm.DB {
+	}).Preload("Items.Page").Preload("Items.Collection").Preload("Items.Children", func(db *gorm.DB) *gorm.DB {
 		return db.Where("is_active = ?", true).Order("sort_order ASC")
-	}).
+	}).Preload("Items.Children.Collection").
 		Where("tenant_id = ? AND location = ? AND is_active = ?", tenantID, location, true).
 		First(&menu).Error
 	if err != nil {
@@ -344,6 +346,15 @@ func (r *Repository) DeleteMenuItem(tenantID, itemID uuid.UUID) error {
 	return r.db.Where("id = ?", itemID).Delete(&MenuItem{}).Error
 }
 
+// CollectionExists checks that a product collection belongs to the tenant
+func (r *Repository) CollectionExists(tenantID, collectionID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.Model(&product.Collection{}).
+		Where("tenant_id = ? AND id = ?", tenantID, collectionID).
+		Count(&count).Error
+	return count > 0, err
+}
+
 func (r *Repository) GetMenuItem(tenantID, itemID uuid.UUID) (*MenuItem, error) {
 	var menuItem MenuItem
 	// Join with menu to check tenant
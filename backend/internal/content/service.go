@@ -125,6 +125,7 @@ type CreateMenuItemRequest struct {
 	Title     string     `json:"title" binding:"required"`
 	URL       string     `json:"url"`
 	PageID    *uuid.UUID `json:"page_id"`
+	CollectionID *uuid.UUID `json:"collection_id"`
 	Target    string     `json:"target"`
 	CSSClass  string     `json:"css_class"`
 	IconClass string     `json:"icon_class"`
@@ -137,6 +138,7 @@ type UpdateMenuItemRequest struct {
 	Title     *string    `json:"title"`
 	URL       *string    `json:"url"`
 	PageID    *uuid.UUID `json:"page_id"`
+	CollectionID *uuid.UUID `json:"collection_id"` // uuid.Nil unlinks the collection
 	Target    *string    `json:"target"`
 	CSSClass  *string    `json:"css_class"`
 	IconClass *string    `json:"icon_class"`
@@ -692,6 +694,7 @@ func (s *Service) CreateMenuItem(tenantID, menuID uuid.UUID, req CreateMenuItemR
 		Title:     req.Title,
 		URL:       req.URL,
 		PageID:    req.PageID,
+		CollectionID: req.CollectionID,
 		Target:    req.Target,
 		CSSClass:  req.CSSClass,
 		IconClass: req.IconClass,
@@ -699,6 +702,10 @@ func (s *Service) CreateMenuItem(tenantID, menuID uuid.UUID, req CreateMenuItemR
 		SortOrder: req.SortOrder,
 	}
 
+	if err := s.validateMenuCollection(tenantID, req.CollectionID); err != nil {
+		return nil, err
+	}
+
 	// Set default target
 	if menuItem.Target == "" {
 		menuItem.Target = "_self"
@@ -725,6 +732,17 @@ func (s *Service) UpdateMenuItem(tenantID, itemID uuid.UUID, req UpdateMenuItemR
 	if req.PageID != nil {
 		menuItem.PageID = req.PageID
 	}
+	if req.CollectionID != nil {
+		if *req.CollectionID == uuid.Nil {
+			menuItem.CollectionID = nil
+			menuItem.Collection = nil
+		} else {
+			if err := s.validateMenuCollection(tenantID, req.CollectionID); err != nil {
+				return nil, err
+			}
+			menuItem.CollectionID = req.CollectionID
+		}
+	}
 	if req.Target != nil {
 		menuItem.Target = *req.Target
 	}
@@ -744,6 +762,22 @@ func (s *Service) UpdateMenuItem(tenantID, itemID uuid.UUID, req UpdateMenuItemR
 	return s.repository.UpdateMenuItem(menuItem)
 }
 
+// validateMenuCollection checks that a menu item links to a collection of
+// the tenant
+func (s *Service) validateMenuCollection(tenantID uuid.UUID, collectionID *uuid.UUID) error {
+	if collectionID == nil {
+		return nil
+	}
+	exists, err := s.repository.CollectionExists(tenantID, *collectionID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("collection not found")
+	}
+	return nil
+}
+
 func (s *Service) DeleteMenuItem(tenantID, itemID uuid.UUID) error {
 	return s.repository.DeleteMenuItem(tenantID, itemID)
 }
@@ -0,0 +1,408 @@
+package discount
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DiscountCodeStatus represents the state of a pool code
+type DiscountCodeStatus string
+
+const (
+	CodeStatusAvailable DiscountCodeStatus = "available"
+	CodeStatusRedeemed  DiscountCodeStatus = "redeemed"
+	CodeStatusDisabled  DiscountCodeStatus = "disabled"
+)
+
+// Code generation limits
+const (
+	MaxCodesPerBatch  = 10000
+	defaultCodeLength = 8
+	maxCodeLength     = 32
+	collisionRounds   = 10
+)
+
+// Pattern placeholders; every other character is copied as is. Letters and
+// digits that are easily confused (I, O, 0, 1) are never generated.
+const (
+	patternDigit        = '#'
+	patternLetter       = '?'
+	patternAlphanumeric = '*'
+
+	codeDigits  = "23456789"
+	codeLetters = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+)
+
+// DiscountCode is one unique code in a discount's code pool. Pool codes
+// apply their parent discount and are usually single-use.
+type DiscountCode struct {
+	ID         uuid.UUID          `json:"id" gorm:"primarykey"`
+	TenantID   uuid.UUID          `json:"tenant_id" gorm:"not null;index"`
+	DiscountID uuid.UUID          `json:"discount_id" gorm:"not null;index"`
+	BatchID    uuid.UUID          `json:"batch_id" gorm:"not null;index"`
+	Code       string             `json:"code" gorm:"unique;not null"`
+	Status     DiscountCodeStatus `json:"status" gorm:"default:available;index"`
+
+	// Usage
+	UsageLimit int `json:"usage_limit" gorm:"default:1"`
+	UsageCount int `json:"usage_count" gorm:"default:0"`
+
+	// Last redemption
+	RedeemedAt    *time.Time `json:"redeemed_at,omitempty"`
+	OrderID       *uuid.UUID `json:"order_id,omitempty" gorm:"index"`
+	OrderNumber   string     `json:"order_number,omitempty"`
+	CustomerID    *uuid.UUID `json:"customer_id,omitempty"`
+	CustomerEmail string     `json:"customer_email,omitempty"`
+
+	// Timestamps
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DiscountCodeBatch records one bulk generation run
+type DiscountCodeBatch struct {
+	ID         uuid.UUID  `json:"id" gorm:"primarykey"`
+	TenantID   uuid.UUID  `json:"tenant_id" gorm:"not null;index"`
+	DiscountID uuid.UUID  `json:"discount_id" gorm:"not null;index"`
+	Prefix     string     `json:"prefix,omitempty"`
+	Pattern    string     `json:"pattern"`
+	Quantity   int        `json:"quantity"`
+	UsageLimit int        `json:"usage_limit"`
+	Collisions int        `json:"collisions"` // Candidates discarded because the code already existed
+	Reference  string     `json:"reference,omitempty" gorm:"index"` // Idempotency key, e.g. referral:<id>:referee
+	CreatedBy  *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// DiscountCodeRedemption identifies the order a pool code was redeemed on
+type DiscountCodeRedemption struct {
+	OrderID       uuid.UUID
+	OrderNumber   string
+	CustomerID    *uuid.UUID
+	CustomerEmail string
+}
+
+// GenerateCodesRequest asks for a batch of unique codes for a discount. The
+// pattern uses # for a digit, ? for a letter and * for either; without a
+// pattern, Length alphanumeric characters are generated.
+type GenerateCodesRequest struct {
+	TenantID   uuid.UUID  `json:"-"`
+	DiscountID uuid.UUID  `json:"-"`
+	Quantity   int        `json:"quantity" binding:"required,min=1"`
+	Prefix     string     `json:"prefix"`
+	Pattern    string     `json:"pattern"`
+	Length     int        `json:"length"`
+	UsageLimit int        `json:"usage_limit"` // Uses per code, defaults to 1
+	CreatedBy  *uuid.UUID `json:"-"`
+	Reference  string     `json:"-"` // A batch with the same reference is only generated once
+}
+
+// DiscountCodeFilter narrows a code pool listing
+type DiscountCodeFilter struct {
+	Status  []DiscountCodeStatus `json:"status"`
+	BatchID *uuid.UUID           `json:"batch_id"`
+	Search  string               `json:"search"`
+	Page    int                  `json:"page"`
+	Limit   int                  `json:"limit"`
+}
+
+// IsRedeemable reports whether the code can still be used
+func (c *DiscountCode) IsRedeemable() bool {
+	return c.Status == CodeStatusAvailable && c.UsageCount < c.UsageLimit
+}
+
+// GenerateCodes creates a batch of unique pool codes for a discount. Codes
+// are checked against existing pool codes and discount codes, and colliding
+// candidates are replaced before anything is stored.
+func (s *service) GenerateCodes(ctx context.Context, req GenerateCodesRequest) (*DiscountCodeBatch, error) {
+	if req.Quantity <= 0 || req.Quantity > MaxCodesPerBatch {
+		return nil, fmt.Errorf("quantity must be between 1 and %d", MaxCodesPerBatch)
+	}
+
+	discount, err := s.repo.GetDiscountByID(ctx, req.TenantID, req.DiscountID)
+	if err != nil {
+		return nil, fmt.Errorf("discount not found: %w", err)
+	}
+	if discount.IsAutomatic() {
+		return nil, errors.New("automatic promotions cannot have codes")
+	}
+	if req.Reference != "" {
+		if batch, err := s.repo.GetCodeBatchByReference(ctx, req.TenantID, req.Reference); err == nil {
+			return batch, nil
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	prefix := strings.ToUpper(strings.TrimSpace(req.Prefix))
+	pattern := strings.ToUpper(strings.TrimSpace(req.Pattern))
+	if pattern == "" {
+		length := req.Length
+		if length == 0 {
+			length = defaultCodeLength
+		}
+		if length < 4 || length > maxCodeLength {
+			return nil, fmt.Errorf("code length must be between 4 and %d", maxCodeLength)
+		}
+		pattern = strings.Repeat(string(patternAlphanumeric), length)
+	}
+	if err := validateCodePattern(prefix, pattern, req.Quantity); err != nil {
+		return nil, err
+	}
+
+	usageLimit := req.UsageLimit
+	if usageLimit <= 0 {
+		usageLimit = 1
+	}
+
+	batch := &DiscountCodeBatch{
+		ID:         uuid.New(),
+		TenantID:   req.TenantID,
+		DiscountID: discount.ID,
+		Prefix:     prefix,
+		Pattern:    pattern,
+		Quantity:   req.Quantity,
+		UsageLimit: usageLimit,
+		CreatedBy:  req.CreatedBy,
+		Reference:  req.Reference,
+		CreatedAt:  time.Now(),
+	}
+
+	codes := make(map[string]bool, req.Quantity)
+	for round := 0; len(codes) < req.Quantity; round++ {
+		if round == collisionRounds {
+			return nil, errors.New("could not generate enough unique codes, use a longer pattern")
+		}
+
+		var candidates []string
+		pending := make(map[string]bool)
+		for len(codes)+len(candidates) < req.Quantity {
+			code, err := generatePatternCode(prefix, pattern)
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate code: %w", err)
+			}
+			if codes[code] || pending[code] {
+				batch.Collisions++
+				continue
+			}
+			pending[code] = true
+			candidates = append(candidates, code)
+		}
+
+		existing, err := s.repo.FindExistingCodes(ctx, candidates)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check code collisions: %w", err)
+		}
+		taken := make(map[string]bool, len(existing))
+		for _, code := range existing {
+			taken[code] = true
+		}
+		for _, code := range candidates {
+			if taken[code] {
+				batch.Collisions++
+				continue
+			}
+			codes[code] = true
+		}
+	}
+
+	now := time.Now()
+	poolCodes := make([]DiscountCode, 0, len(codes))
+	for code := range codes {
+		poolCodes = append(poolCodes, DiscountCode{
+			ID:         uuid.New(),
+			TenantID:   req.TenantID,
+			DiscountID: discount.ID,
+			BatchID:    batch.ID,
+			Code:       code,
+			Status:     CodeStatusAvailable,
+			UsageLimit: usageLimit,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		})
+	}
+
+	if err := s.repo.CreateDiscountCodes(ctx, batch, poolCodes); err != nil {
+		return nil, fmt.Errorf("failed to save codes: %w", err)
+	}
+	return batch, nil
+}
+
+func (s *service) GetDiscountCodes(ctx context.Context, tenantID, discountID uuid.UUID, filter DiscountCodeFilter) ([]DiscountCode, error) {
+	return s.repo.GetDiscountCodes(ctx, tenantID, discountID, filter)
+}
+
+// SetDiscountCodeStatus disables an unused code or re-enables a disabled one.
+// Redeemed codes keep their status.
+func (s *service) SetDiscountCodeStatus(ctx context.Context, tenantID, discountID, codeID uuid.UUID, status DiscountCodeStatus) error {
+	from := CodeStatusAvailable
+	switch status {
+	case CodeStatusDisabled:
+	case CodeStatusAvailable:
+		from = CodeStatusDisabled
+	default:
+		return fmt.Errorf("codes can only be set to %s or %s", CodeStatusAvailable, CodeStatusDisabled)
+	}
+
+	updated, err := s.repo.UpdateDiscountCodeStatus(ctx, tenantID, discountID, codeID, from, status)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return fmt.Errorf("code is not %s", from)
+	}
+	return nil
+}
+
+// ExportDiscountCodes writes a discount's code pool, with redemption
+// details, as CSV
+func (s *service) ExportDiscountCodes(ctx context.Context, tenantID, discountID uuid.UUID, filter DiscountCodeFilter) ([]byte, string, error) {
+	discount, err := s.repo.GetDiscountByID(ctx, tenantID, discountID)
+	if err != nil {
+		return nil, "", fmt.Errorf("discount not found: %w", err)
+	}
+
+	filter.Page, filter.Limit = 0, 0
+	codes, err := s.repo.GetDiscountCodes(ctx, tenantID, discountID, filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	header := []string{
+		"Code", "Status", "Usage Count", "Usage Limit", "Batch ID",
+		"Order Number", "Customer Email", "Redeemed At", "Created At",
+	}
+	if err := writer.Write(header); err != nil {
+		return nil, "", fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	for _, code := range codes {
+		redeemedAt := ""
+		if code.RedeemedAt != nil {
+			redeemedAt = code.RedeemedAt.Format(time.RFC3339)
+		}
+		record := []string{
+			code.Code,
+			string(code.Status),
+			strconv.Itoa(code.UsageCount),
+			strconv.Itoa(code.UsageLimit),
+			code.BatchID.String(),
+			code.OrderNumber,
+			code.CustomerEmail,
+			redeemedAt,
+			code.CreatedAt.Format(time.RFC3339),
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, "", fmt.Errorf("failed to write CSV record: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, "", fmt.Errorf("CSV writer error: %w", err)
+	}
+
+	filename := fmt.Sprintf("discount_codes_%s_%s.csv", strings.ToLower(discount.Code), time.Now().Format("20060102_150405"))
+	return buf.Bytes(), filename, nil
+}
+
+// resolveCode finds the discount for an entered code, falling back to pool
+// codes. A discount found through a pool code carries the pool code.
+func (s *service) resolveCode(ctx context.Context, tenantID uuid.UUID, code string) (*Discount, *DiscountCode, error) {
+	discount, err := s.repo.GetDiscountByCode(ctx, tenantID, code)
+	if err == nil {
+		return discount, nil, nil
+	}
+
+	poolCode, poolErr := s.repo.GetDiscountCodeByCode(ctx, tenantID, code)
+	if poolErr != nil {
+		return nil, nil, err
+	}
+	discount, err = s.repo.GetDiscountByID(ctx, tenantID, poolCode.DiscountID)
+	if err != nil {
+		return nil, nil, err
+	}
+	discount.Code = poolCode.Code
+	return discount, poolCode, nil
+}
+
+// validateCodePattern checks the pattern only uses code-safe characters and
+// leaves enough combinations for the requested quantity to stay unguessable
+func validateCodePattern(prefix, pattern string, quantity int) error {
+	if len(prefix)+len(pattern) > maxCodeLength {
+		return fmt.Errorf("codes cannot be longer than %d characters", maxCodeLength)
+	}
+	for _, r := range prefix + pattern {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+		case r == patternDigit || r == patternLetter || r == patternAlphanumeric:
+		default:
+			return fmt.Errorf("codes cannot contain %q", r)
+		}
+	}
+	if strings.ContainsAny(prefix, "#?*") {
+		return errors.New("prefix cannot contain pattern placeholders")
+	}
+
+	// Require at least 100 combinations per code so collisions stay rare and
+	// codes cannot be guessed from each other
+	capacity := big.NewInt(1)
+	for _, r := range pattern {
+		if alphabet := patternAlphabet(r); alphabet != "" {
+			capacity.Mul(capacity, big.NewInt(int64(len(alphabet))))
+		}
+	}
+	needed := big.NewInt(int64(quantity) * 100)
+	if capacity.Cmp(needed) < 0 {
+		return fmt.Errorf("pattern %s allows only %s codes, use a longer pattern for %d codes", pattern, capacity.String(), quantity)
+	}
+	return nil
+}
+
+// generatePatternCode fills the pattern's placeholders with random characters
+func generatePatternCode(prefix, pattern string) (string, error) {
+	var code strings.Builder
+	code.WriteString(prefix)
+	for _, r := range pattern {
+		alphabet := patternAlphabet(r)
+		if alphabet == "" {
+			code.WriteRune(r)
+			continue
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		if err != nil {
+			return "", err
+		}
+		code.WriteByte(alphabet[n.Int64()])
+	}
+	return code.String(), nil
+}
+
+// patternAlphabet returns the characters a placeholder expands to, or an
+// empty string for literal characters
+func patternAlphabet(r rune) string {
+	switch r {
+	case patternDigit:
+		return codeDigits
+	case patternLetter:
+		return codeLetters
+	case patternAlphanumeric:
+		return codeLetters + codeDigits
+	default:
+		return ""
+	}
+}
@@ -25,6 +25,8 @@ const (
 	TypeFixed      DiscountType = "fixed"
 	TypeFreeShipping DiscountType = "free_shipping"
 	TypeBuyXGetY   DiscountType = "buy_x_get_y"
+	TypeTiered     DiscountType = "tiered" // Volume breaks on eligible quantity
+	TypeBundle     DiscountType = "bundle" // Fixed price for a set of products
 )
 
 const (
@@ -89,21 +91,38 @@ type Discount struct {
 	CustomerEligibility string   `json:"customer_eligibility" gorm:"default:all"` // all, specific, groups
 	EligibleCustomerIDs []string `json:"eligible_customer_ids,omitempty" gorm:"serializer:json"`
 	EligibleCustomerGroups []string `json:"eligible_customer_groups,omitempty" gorm:"serializer:json"`
+	EligibleLoyaltyTiers   []string `json:"eligible_loyalty_tiers,omitempty" gorm:"serializer:json"` // Tier codes; members only when set
 	
 	// Buy X Get Y settings (for BOGO offers)
 	BuyQuantity *int     `json:"buy_quantity,omitempty"`  // Number of items to buy
 	GetQuantity *int     `json:"get_quantity,omitempty"`  // Number of items to get free/discounted
 	GetValue    *float64 `json:"get_value,omitempty"`     // Discount on "get" items (0-100 for percentage)
 	
+	// Reward items for Buy X Get Y; the qualifying items are used when empty
+	RewardProductIDs    []string `json:"reward_product_ids,omitempty" gorm:"serializer:json"`
+	RewardCategoryIDs   []string `json:"reward_category_ids,omitempty" gorm:"serializer:json"`
+	RewardCollectionIDs []string `json:"reward_collection_ids,omitempty" gorm:"serializer:json"`
+	
+	// Tiered and bundle pricing
+	Tiers       []DiscountTier `json:"tiers,omitempty" gorm:"serializer:json"`
+	BundleItems []BundleItem   `json:"bundle_items,omitempty" gorm:"serializer:json"` // Value is the bundle price
+	
 	// Stackability
 	Stackable       bool     `json:"stackable" gorm:"default:false"`           // Can combine with other discounts
 	StackableWith   []string `json:"stackable_with,omitempty" gorm:"serializer:json"` // Specific discount IDs it can stack with
 	ExclusiveGroup  string   `json:"exclusive_group,omitempty"`                // Mutually exclusive group
 	
+	// Combinability by discount class, used when Stackable is false
+	CombinesWithOrder    bool `json:"combines_with_order" gorm:"default:false"`
+	CombinesWithProduct  bool `json:"combines_with_product" gorm:"default:false"`
+	CombinesWithShipping bool `json:"combines_with_shipping" gorm:"default:false"`
+	Exclusive            bool `json:"exclusive" gorm:"default:false"` // Never combines with anything
+	Priority             int  `json:"priority" gorm:"default:0"`      // Higher priorities are evaluated first
+	
 	// Settings
 	ApplyOnce        bool `json:"apply_once" gorm:"default:false"`              // Apply only once per order (for percentage)
 	ShowInStorefront bool `json:"show_in_storefront" gorm:"default:false"`      // Display publicly
-	RequiresCode     bool `json:"requires_code" gorm:"default:true"`            // Automatic discount if false
+	RequiresCode     bool `json:"requires_code"`                                // Automatic discount if false
 	
 	// Tracking
 	CreatedBy uuid.UUID `json:"created_by" gorm:"not null"`
@@ -116,6 +135,20 @@ type Discount struct {
 	Usages []DiscountUsage `json:"usages,omitempty" gorm:"foreignKey:DiscountID"`
 }
 
+// DiscountTier is a volume break: from MinQuantity eligible units, either a
+// percentage off or a fixed unit price applies to every eligible unit
+type DiscountTier struct {
+	MinQuantity int     `json:"min_quantity"`
+	Percentage  float64 `json:"percentage,omitempty"`
+	UnitPrice   float64 `json:"unit_price,omitempty"`
+}
+
+// BundleItem is one product and quantity making up a bundle
+type BundleItem struct {
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+}
+
 // DiscountUsage tracks individual discount uses
 type DiscountUsage struct {
 	ID         uuid.UUID  `json:"id" gorm:"primarykey"`
@@ -153,6 +186,7 @@ type GiftCard struct {
 	Status       DiscountStatus `json:"status" gorm:"default:active"`
 	InitialValue float64        `json:"initial_value" gorm:"not null"`
 	CurrentValue float64        `json:"current_value" gorm:"not null"`
+	HeldAmount   float64        `json:"held_amount" gorm:"default:0"` // Reserved by open holds
 	Currency     string         `json:"currency" gorm:"not null"`
 	
 	// Recipient information
@@ -186,10 +220,11 @@ type GiftCardTransaction struct {
 	TenantID   uuid.UUID `json:"tenant_id" gorm:"not null;index"`
 	
 	// Transaction details
-	Type        string    `json:"type" gorm:"not null"` // usage, refill, initial
+	Type        string    `json:"type" gorm:"not null"` // See the Ledger* entry types
 	Amount      float64   `json:"amount" gorm:"not null"`
 	Balance     float64   `json:"balance" gorm:"not null"` // Balance after transaction
 	Description string    `json:"description,omitempty"`
+	HoldID      *uuid.UUID `json:"hold_id,omitempty" gorm:"index"`
 	
 	// Order information (for usage transactions)
 	OrderID     *uuid.UUID `json:"order_id,omitempty" gorm:"index"`
@@ -214,6 +249,7 @@ type StoreCredit struct {
 	
 	// Credit information
 	CurrentBalance float64 `json:"current_balance" gorm:"not null"`
+	HeldAmount     float64 `json:"held_amount" gorm:"default:0"` // Reserved by open holds
 	Currency       string  `json:"currency" gorm:"not null"`
 	
 	// Settings
@@ -236,10 +272,11 @@ type StoreCreditTransaction struct {
 	CustomerID    uuid.UUID `json:"customer_id" gorm:"not null;index"`
 	
 	// Transaction details
-	Type        string  `json:"type" gorm:"not null"` // usage, addition, refund, admin_adjustment
+	Type        string  `json:"type" gorm:"not null"` // See the Ledger* entry types
 	Amount      float64 `json:"amount" gorm:"not null"`
 	Balance     float64 `json:"balance" gorm:"not null"` // Balance after transaction
 	Description string  `json:"description,omitempty"`
+	HoldID      *uuid.UUID `json:"hold_id,omitempty" gorm:"index"`
 	
 	// Order information
 	OrderID     *uuid.UUID `json:"order_id,omitempty" gorm:"index"`
@@ -248,6 +285,7 @@ type StoreCreditTransaction struct {
 	// Reference information
 	RefundID   *uuid.UUID `json:"refund_id,omitempty" gorm:"index"`
 	ReturnID   *uuid.UUID `json:"return_id,omitempty" gorm:"index"`
+	Reference  string     `json:"reference,omitempty" gorm:"index"` // Idempotency key of the grant, e.g. referral:<id>:referrer
 	
 	// Admin information
 	ProcessedBy *uuid.UUID `json:"processed_by,omitempty" gorm:"index"`
@@ -344,9 +382,9 @@ func (d *Discount) CalculateDiscount(orderAmount float64, itemQuantity int) (flo
 	case TypeFreeShipping:
 		// This would need shipping amount to calculate
 		discountAmount = 0 // Handled separately in shipping calculation
-	case TypeBuyXGetY:
-		// Complex BOGO calculation - would need item details
-		discountAmount = 0 // TODO: Implement BOGO logic
+	case TypeBuyXGetY, TypeTiered, TypeBundle:
+		// Line-aware types need the cart lines; see EvaluatePromotions
+		discountAmount = 0
 	}
 	
 	return discountAmount, nil
@@ -392,57 +430,30 @@ func (gc *GiftCard) IsValid() bool {
 	return true
 }
 
-// CanUseAmount checks if gift card has sufficient balance
-func (gc *GiftCard) CanUseAmount(amount float64) bool {
-	return gc.IsValid() && gc.CurrentValue >= amount
+// AvailableBalance is the balance not reserved by open holds
+func (gc *GiftCard) AvailableBalance() float64 {
+	return gc.CurrentValue - gc.HeldAmount
 }
 
-// UseAmount deducts amount from gift card (returns actual amount used)
-func (gc *GiftCard) UseAmount(amount float64) float64 {
-	if !gc.IsValid() {
-		return 0
-	}
-	
-	usedAmount := amount
-	if usedAmount > gc.CurrentValue {
-		usedAmount = gc.CurrentValue
-	}
-	
-	gc.CurrentValue -= usedAmount
-	return usedAmount
+// CanUseAmount checks if gift card has sufficient balance
+func (gc *GiftCard) CanUseAmount(amount float64) bool {
+	return gc.IsValid() && gc.AvailableBalance() >= amount
 }
 
 // Store credit methods
 
+// AvailableBalance is the balance not reserved by open holds
+func (sc *StoreCredit) AvailableBalance() float64 {
+	return sc.CurrentBalance - sc.HeldAmount
+}
+
 // CanUseAmount checks if store credit has sufficient balance
 func (sc *StoreCredit) CanUseAmount(amount float64) bool {
 	if sc.ExpiresAt != nil && time.Now().After(*sc.ExpiresAt) {
 		return false
 	}
 	
-	return sc.CurrentBalance >= amount
-}
-
-// UseAmount deducts amount from store credit (returns actual amount used)
-func (sc *StoreCredit) UseAmount(amount float64) float64 {
-	if !sc.CanUseAmount(amount) {
-		return 0
-	}
-	
-	usedAmount := amount
-	if usedAmount > sc.CurrentBalance {
-		usedAmount = sc.CurrentBalance
-	}
-	
-	sc.CurrentBalance -= usedAmount
-	return usedAmount
-}
-
-// AddAmount adds amount to store credit
-func (sc *StoreCredit) AddAmount(amount float64) {
-	if amount > 0 {
-		sc.CurrentBalance += amount
-	}
+	return sc.AvailableBalance() >= amount
 }
 
 // Validation methods
@@ -460,7 +471,7 @@ func (d *Discount) Validate() error {
 		return errors.New("discount title is required")
 	}
 	
-	if d.Value <= 0 {
+	if d.Value <= 0 && d.Type != TypeTiered {
 		return errors.New("discount value must be positive")
 	}
 	
@@ -479,6 +490,34 @@ func (d *Discount) Validate() error {
 		}
 	}
 	
+	// Validate tiers and bundles
+	if d.Type == TypeTiered {
+		if len(d.Tiers) == 0 {
+			return errors.New("tiered discounts need at least one tier")
+		}
+		for _, tier := range d.Tiers {
+			if tier.MinQuantity <= 0 {
+				return errors.New("tier minimum quantity must be positive")
+			}
+			if (tier.Percentage <= 0) == (tier.UnitPrice <= 0) {
+				return errors.New("each tier needs either a percentage or a unit price")
+			}
+			if tier.Percentage > 100 {
+				return errors.New("tier percentage cannot exceed 100%")
+			}
+		}
+	}
+	if d.Type == TypeBundle {
+		if len(d.BundleItems) < 2 {
+			return errors.New("bundles need at least two items")
+		}
+		for _, item := range d.BundleItems {
+			if item.ProductID == "" || item.Quantity <= 0 {
+				return errors.New("bundle items need a product and a positive quantity")
+			}
+		}
+	}
+	
 	// Validate date range
 	if d.StartsAt != nil && d.ExpiresAt != nil && d.StartsAt.After(*d.ExpiresAt) {
 		return errors.New("start date cannot be after expiry date")
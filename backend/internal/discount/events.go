@@ -0,0 +1,64 @@
+package discount
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"ecommerce-saas/internal/order"
+)
+
+// EventHandler settles the gift card and store credit holds placed at
+// checkout, and issues or drops the gift cards bought with the order, once
+// the order's payment succeeds or fails, or the order is cancelled. It
+// implements order.PaymentListener and order.EventListener.
+type EventHandler struct {
+	service Service
+}
+
+// NewEventHandler creates an event handler backed by the discount service
+func NewEventHandler(service Service) *EventHandler {
+	return &EventHandler{service: service}
+}
+
+// OrderPaid spends the balance held for the order and issues the gift cards
+// bought with it
+func (h *EventHandler) OrderPaid(ctx context.Context, o *order.Order) error {
+	_, captureErr := h.service.CaptureOrderHolds(ctx, o.TenantID, o.ID)
+	_, issueErr := h.service.IssueOrderGiftCards(ctx, o.TenantID, o.ID)
+	return errors.Join(captureErr, issueErr)
+}
+
+// OrderPaymentFailed gives the balance held for the order back and drops the
+// gift cards bought with it
+func (h *EventHandler) OrderPaymentFailed(ctx context.Context, o *order.Order) error {
+	_, releaseErr := h.service.ReleaseOrderHolds(ctx, o.TenantID, o.ID, fmt.Sprintf("Payment for order %s failed", o.OrderNumber))
+	_, cancelErr := h.service.CancelOrderGiftCards(ctx, o.TenantID, o.ID)
+	return errors.Join(releaseErr, cancelErr)
+}
+
+// OrderCancelled gives back the balance still held for the order and drops
+// the gift cards not yet issued. Holds and cards of paid orders were already
+// captured and issued and are refunded with the order instead.
+func (h *EventHandler) OrderCancelled(ctx context.Context, o *order.Order) error {
+	_, releaseErr := h.service.ReleaseOrderHolds(ctx, o.TenantID, o.ID, fmt.Sprintf("Order %s cancelled", o.OrderNumber))
+	_, cancelErr := h.service.CancelOrderGiftCards(ctx, o.TenantID, o.ID)
+	return errors.Join(releaseErr, cancelErr)
+}
+
+// OrderDelivered needs nothing settled
+func (h *EventHandler) OrderDelivered(ctx context.Context, o *order.Order) error {
+	return nil
+}
+
+// OrderReturned needs nothing settled
+func (h *EventHandler) OrderReturned(ctx context.Context, o *order.Order) error {
+	return nil
+}
+
+// OrderRefunded needs nothing settled
+func (h *EventHandler) OrderRefunded(ctx context.Context, o *order.Order, refundID uuid.UUID, amount float64) error {
+	return nil
+}
@@ -0,0 +1,417 @@
+package discount
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"ecommerce-saas/internal/notification"
+)
+
+// GiftCardPurchaseStatus tracks a gift card bought in the store from the
+// order to the recipient's inbox
+type GiftCardPurchaseStatus string
+
+const (
+	PurchaseStatusPending   GiftCardPurchaseStatus = "pending"   // Waiting for the order to be paid
+	PurchaseStatusIssued    GiftCardPurchaseStatus = "issued"    // Card created, email not sent yet
+	PurchaseStatusDelivered GiftCardPurchaseStatus = "delivered" // Email handed to the notification service
+	PurchaseStatusCancelled GiftCardPurchaseStatus = "cancelled" // Order was never paid
+)
+
+// Gift card delivery limits
+const (
+	MaxGiftCardDeliveryAttempts = 5
+	giftCardDeliveryBatch       = 200
+)
+
+// ErrPurchaseNotIssued is returned when a card email is requested before the
+// card exists
+var ErrPurchaseNotIssued = errors.New("gift card has not been issued yet")
+
+// GiftCardPurchase is one gift card bought through checkout. It is recorded
+// with the order, issued as a GiftCard once the order is paid and emailed to
+// the recipient on the chosen date.
+type GiftCardPurchase struct {
+	ID       uuid.UUID `json:"id" gorm:"primarykey"`
+	TenantID uuid.UUID `json:"tenant_id" gorm:"not null;index"`
+
+	// Order and product the card was bought with
+	OrderID      uuid.UUID  `json:"order_id" gorm:"not null;index"`
+	OrderNumber  string     `json:"order_number,omitempty"`
+	ProductID    uuid.UUID  `json:"product_id" gorm:"not null;index"`
+	VariantID    *uuid.UUID `json:"variant_id,omitempty"`
+	Amount       float64    `json:"amount" gorm:"not null"`
+	Currency     string     `json:"currency" gorm:"not null"`
+	ValidityDays int        `json:"validity_days,omitempty"` // 0 means the card never expires
+
+	// Purchaser and recipient
+	PurchaserID    *uuid.UUID `json:"purchaser_id,omitempty" gorm:"index"`
+	PurchaserEmail string     `json:"purchaser_email,omitempty"`
+	SenderName     string     `json:"sender_name,omitempty"`
+	RecipientName  string     `json:"recipient_name" gorm:"not null"`
+	RecipientEmail string     `json:"recipient_email" gorm:"not null;index"`
+	Message        string     `json:"message,omitempty"`
+	SendAt         *time.Time `json:"send_at,omitempty" gorm:"index"` // Empty means as soon as the card is issued
+
+	// Issuance and delivery
+	Status           GiftCardPurchaseStatus `json:"status" gorm:"default:pending;index"`
+	GiftCardID       *uuid.UUID             `json:"gift_card_id,omitempty" gorm:"index"`
+	IssuedAt         *time.Time             `json:"issued_at,omitempty"`
+	DeliveredAt      *time.Time             `json:"delivered_at,omitempty"`
+	NotificationID   string                 `json:"notification_id,omitempty"`
+	DeliveryAttempts int                    `json:"delivery_attempts" gorm:"default:0"`
+	LastError        string                 `json:"last_error,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GiftCardPurchaseFilter narrows a purchase listing
+type GiftCardPurchaseFilter struct {
+	OrderID        *uuid.UUID               `json:"order_id"`
+	Status         []GiftCardPurchaseStatus `json:"status"`
+	RecipientEmail string                   `json:"recipient_email"`
+	Page           int                      `json:"page"`
+	Limit          int                      `json:"limit"`
+}
+
+// GiftCardDeliveryResult summarises one delivery run
+type GiftCardDeliveryResult struct {
+	Delivered int                       `json:"delivered"`
+	Failed    int                       `json:"failed"`
+	Failures  []GiftCardDeliveryFailure `json:"failures,omitempty"`
+}
+
+// GiftCardDeliveryFailure is a card email that could not be sent
+type GiftCardDeliveryFailure struct {
+	PurchaseID uuid.UUID `json:"purchase_id"`
+	Error      string    `json:"error"`
+}
+
+// ResendGiftCardRequest sends a card email again, optionally to a corrected
+// address
+type ResendGiftCardRequest struct {
+	RecipientEmail string `json:"recipient_email,omitempty" validate:"omitempty,email"`
+}
+
+// IsDue reports whether the card email should go out at the given time
+func (p *GiftCardPurchase) IsDue(now time.Time) bool {
+	return p.SendAt == nil || !p.SendAt.After(now)
+}
+
+// IssuePurchases creates the gift cards bought with a paid order. Pending
+// purchases are locked and issued once, so repeated payment confirmations
+// never issue a card twice.
+func (l *Ledger) IssuePurchases(ctx context.Context, tenantID, orderID uuid.UUID) ([]GiftCardPurchase, error) {
+	var purchases []GiftCardPurchase
+	err := l.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("tenant_id = ? AND order_id = ? AND status = ?", tenantID, orderID, PurchaseStatusPending).
+			Order("created_at ASC").
+			Find(&purchases).Error; err != nil {
+			return err
+		}
+
+		ledger := NewLedger(tx)
+		now := time.Now()
+		for i := range purchases {
+			purchase := &purchases[i]
+			giftCard := &GiftCard{
+				ID:              uuid.New(),
+				TenantID:        tenantID,
+				Code:            generateGiftCardCode(),
+				Status:          StatusActive,
+				InitialValue:    purchase.Amount,
+				Currency:        purchase.Currency,
+				RecipientName:   purchase.RecipientName,
+				RecipientEmail:  purchase.RecipientEmail,
+				Message:         purchase.Message,
+				PurchasedBy:     purchase.PurchaserID,
+				PurchaseOrderID: &purchase.OrderID,
+				CreatedAt:       now,
+				UpdatedAt:       now,
+			}
+			if purchase.ValidityDays > 0 {
+				expiresAt := now.AddDate(0, 0, purchase.ValidityDays)
+				giftCard.ExpiresAt = &expiresAt
+			}
+
+			entry := LedgerEntry{
+				Description:   "Gift card purchased with order " + purchase.OrderNumber,
+				OrderID:       &purchase.OrderID,
+				OrderNumber:   purchase.OrderNumber,
+				CustomerID:    purchase.PurchaserID,
+				CustomerEmail: purchase.PurchaserEmail,
+			}
+			if err := ledger.IssueGiftCard(ctx, giftCard, entry); err != nil {
+				return fmt.Errorf("failed to issue gift card for purchase %s: %w", purchase.ID, err)
+			}
+
+			purchase.Status = PurchaseStatusIssued
+			purchase.GiftCardID = &giftCard.ID
+			purchase.IssuedAt = &now
+			if err := tx.Model(purchase).Updates(map[string]interface{}{
+				"status":       purchase.Status,
+				"gift_card_id": giftCard.ID,
+				"issued_at":    now,
+				"updated_at":   now,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return purchases, nil
+}
+
+func (s *service) GetGiftCardPurchases(ctx context.Context, tenantID uuid.UUID, filter GiftCardPurchaseFilter) ([]GiftCardPurchase, error) {
+	return s.repo.GetGiftCardPurchases(ctx, tenantID, filter)
+}
+
+// IssueOrderGiftCards issues the gift cards bought with an order once it is
+// paid, and emails those that are not scheduled for later. A failed email is
+// recorded on the purchase and retried by DeliverGiftCards.
+func (s *service) IssueOrderGiftCards(ctx context.Context, tenantID, orderID uuid.UUID) ([]GiftCardPurchase, error) {
+	purchases, err := s.repo.Ledger().IssuePurchases(ctx, tenantID, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for i := range purchases {
+		if purchases[i].IsDue(now) {
+			_ = s.deliverPurchase(ctx, &purchases[i])
+		}
+	}
+	return purchases, nil
+}
+
+// CancelOrderGiftCards drops the gift cards of an order that was never paid.
+// Cards already issued are left alone; refund them through the ledger.
+func (s *service) CancelOrderGiftCards(ctx context.Context, tenantID, orderID uuid.UUID) (int64, error) {
+	return s.repo.CancelGiftCardPurchases(ctx, tenantID, orderID)
+}
+
+// DeliverGiftCards emails every issued card whose send date has arrived. It
+// is meant to be called periodically; each card is claimed before sending so
+// overlapping runs do not email a recipient twice.
+func (s *service) DeliverGiftCards(ctx context.Context, tenantID uuid.UUID) (*GiftCardDeliveryResult, error) {
+	purchases, err := s.repo.GetDueGiftCardPurchases(ctx, tenantID, time.Now(), giftCardDeliveryBatch)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &GiftCardDeliveryResult{}
+	for i := range purchases {
+		if err := s.deliverPurchase(ctx, &purchases[i]); err != nil {
+			if errors.Is(err, errDeliveryClaimed) {
+				continue
+			}
+			result.Failed++
+			result.Failures = append(result.Failures, GiftCardDeliveryFailure{PurchaseID: purchases[i].ID, Error: err.Error()})
+			continue
+		}
+		result.Delivered++
+	}
+	return result, nil
+}
+
+// ResendGiftCard emails an issued card again, for example after the
+// recipient lost the first email or the address was mistyped
+func (s *service) ResendGiftCard(ctx context.Context, tenantID, purchaseID uuid.UUID, req ResendGiftCardRequest) (*GiftCardPurchase, error) {
+	purchase, err := s.repo.GetGiftCardPurchase(ctx, tenantID, purchaseID)
+	if err != nil {
+		return nil, err
+	}
+	if purchase.GiftCardID == nil {
+		return nil, ErrPurchaseNotIssued
+	}
+
+	if email := strings.TrimSpace(req.RecipientEmail); email != "" && email != purchase.RecipientEmail {
+		if err := s.repo.UpdateGiftCardPurchase(ctx, tenantID, purchase.ID, map[string]interface{}{
+			"recipient_email": email,
+			"updated_at":      time.Now(),
+		}); err != nil {
+			return nil, err
+		}
+		if err := s.repo.UpdateGiftCard(ctx, tenantID, *purchase.GiftCardID, map[string]interface{}{
+			"recipient_email": email,
+			"updated_at":      time.Now(),
+		}); err != nil {
+			return nil, err
+		}
+		purchase.RecipientEmail = email
+	}
+
+	giftCard, err := s.repo.GetGiftCardByID(ctx, tenantID, *purchase.GiftCardID)
+	if err != nil {
+		return nil, err
+	}
+	notificationID, err := s.sendGiftCardEmail(purchase, giftCard)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	purchase.Status = PurchaseStatusDelivered
+	purchase.DeliveredAt = &now
+	purchase.NotificationID = notificationID
+	purchase.LastError = ""
+	if err := s.repo.UpdateGiftCardPurchase(ctx, tenantID, purchase.ID, map[string]interface{}{
+		"status":          purchase.Status,
+		"delivered_at":    now,
+		"notification_id": notificationID,
+		"last_error":      "",
+		"updated_at":      now,
+	}); err != nil {
+		return nil, err
+	}
+	return purchase, nil
+}
+
+// errDeliveryClaimed means another run is already sending the card
+var errDeliveryClaimed = errors.New("gift card delivery already claimed")
+
+// deliverPurchase claims an issued purchase and emails the card. On failure
+// the purchase goes back to issued with the error, until it runs out of
+// attempts.
+func (s *service) deliverPurchase(ctx context.Context, purchase *GiftCardPurchase) error {
+	if purchase.GiftCardID == nil {
+		return ErrPurchaseNotIssued
+	}
+
+	now := time.Now()
+	claimed, err := s.repo.ClaimGiftCardDelivery(ctx, purchase.TenantID, purchase.ID, now)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return errDeliveryClaimed
+	}
+
+	giftCard, err := s.repo.GetGiftCardByID(ctx, purchase.TenantID, *purchase.GiftCardID)
+	var notificationID string
+	if err == nil {
+		notificationID, err = s.sendGiftCardEmail(purchase, giftCard)
+	}
+	if err != nil {
+		purchase.DeliveryAttempts++
+		purchase.LastError = err.Error()
+		_ = s.repo.UpdateGiftCardPurchase(ctx, purchase.TenantID, purchase.ID, map[string]interface{}{
+			"status":            PurchaseStatusIssued,
+			"delivered_at":      nil,
+			"delivery_attempts": gorm.Expr("delivery_attempts + 1"),
+			"last_error":        purchase.LastError,
+			"updated_at":        time.Now(),
+		})
+		return err
+	}
+
+	purchase.Status = PurchaseStatusDelivered
+	purchase.DeliveredAt = &now
+	purchase.NotificationID = notificationID
+	return s.repo.UpdateGiftCardPurchase(ctx, purchase.TenantID, purchase.ID, map[string]interface{}{
+		"notification_id": notificationID,
+		"last_error":      "",
+		"updated_at":      time.Now(),
+	})
+}
+
+// sendGiftCardEmail renders the tenant's gift card template, or a plain
+// default when there is none, and queues the email
+func (s *service) sendGiftCardEmail(purchase *GiftCardPurchase, giftCard *GiftCard) (string, error) {
+	if s.notifications == nil {
+		return "", errors.New("notifications are not configured")
+	}
+
+	variables := giftCardEmailVariables(purchase, giftCard)
+	req := &notification.SendNotificationRequest{
+		Type:       notification.TypeEmail,
+		Channel:    notification.ChannelGiftCard,
+		Recipients: []string{purchase.RecipientEmail},
+		Variables:  variables,
+		Priority:   "normal",
+	}
+	if purchase.PurchaserID != nil {
+		req.UserID = purchase.PurchaserID.String()
+	}
+
+	if template := s.giftCardTemplate(purchase.TenantID); template != nil {
+		req.TemplateID = template.ID.String()
+	} else {
+		req.Subject, req.Content = defaultGiftCardEmail(variables)
+	}
+
+	response, err := s.notifications.SendNotification(purchase.TenantID, req)
+	if err != nil {
+		return "", err
+	}
+	if len(response.NotificationIDs) == 0 {
+		return "", errors.New("gift card email could not be queued")
+	}
+	return response.NotificationIDs[0], nil
+}
+
+// giftCardTemplate picks the tenant's own active gift card email template
+// over a system default
+func (s *service) giftCardTemplate(tenantID uuid.UUID) *notification.NotificationTemplate {
+	templates, err := s.notifications.ListTemplates(tenantID, notification.TypeEmail, notification.ChannelGiftCard)
+	if err != nil || len(templates) == 0 {
+		return nil
+	}
+	for _, template := range templates {
+		if !template.IsDefault {
+			return template
+		}
+	}
+	return templates[0]
+}
+
+// giftCardEmailVariables are the placeholders available to gift card templates
+func giftCardEmailVariables(purchase *GiftCardPurchase, giftCard *GiftCard) map[string]interface{} {
+	expiresAt := ""
+	if giftCard.ExpiresAt != nil {
+		expiresAt = giftCard.ExpiresAt.Format("2006-01-02")
+	}
+	senderName := purchase.SenderName
+	if senderName == "" {
+		senderName = "Someone"
+	}
+	return map[string]interface{}{
+		"recipient_name": purchase.RecipientName,
+		"sender_name":    senderName,
+		"message":        purchase.Message,
+		"amount":         fmt.Sprintf("%.2f", giftCard.InitialValue),
+		"currency":       giftCard.Currency,
+		"code":           giftCard.Code,
+		"expires_at":     expiresAt,
+		"order_number":   purchase.OrderNumber,
+	}
+}
+
+// defaultGiftCardEmail is used when the tenant has no gift card template
+func defaultGiftCardEmail(variables map[string]interface{}) (string, string) {
+	subject := fmt.Sprintf("%s sent you a %s %s gift card", variables["sender_name"], variables["amount"], variables["currency"])
+
+	var content strings.Builder
+	fmt.Fprintf(&content, "Hi %s,\n\n", variables["recipient_name"])
+	fmt.Fprintf(&content, "%s sent you a gift card worth %s %s.\n\n", variables["sender_name"], variables["amount"], variables["currency"])
+	if message, _ := variables["message"].(string); message != "" {
+		fmt.Fprintf(&content, "\"%s\"\n\n", message)
+	}
+	fmt.Fprintf(&content, "Your gift card code: %s\n", variables["code"])
+	if expiresAt, _ := variables["expires_at"].(string); expiresAt != "" {
+		fmt.Fprintf(&content, "Valid until: %s\n", expiresAt)
+	}
+	return subject, content.String()
+}
@@ -1,12 +1,15 @@
 package discount
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // Handler defines the discount HTTP handlers
@@ -30,12 +33,23 @@ func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 		discounts.PUT("/:id", h.updateDiscount)
 		discounts.DELETE("/:id", h.deleteDiscount)
 		discounts.GET("/:id/usage", h.getDiscountUsage)
+		discounts.POST("/:id/codes/generate", h.generateDiscountCodes)
+		discounts.GET("/:id/codes", h.getDiscountCodes)
+		discounts.GET("/:id/codes/export", h.exportDiscountCodes)
+		discounts.PATCH("/:id/codes/:codeId", h.updateDiscountCode)
 		// Stats endpoints consolidated into main GET /discounts with query parameters:
 		// ?type=stats - return discount statistics
 		// ?type=performance - return top performing discounts  
 		// ?type=revenue-impact - return revenue impact data
 	}
 	
+	// Automatic promotions and stacking evaluation
+	promotions := router.Group("/promotions")
+	{
+		promotions.GET("/automatic", h.getAutomaticPromotions)
+		promotions.POST("/evaluate", h.evaluatePromotions)
+	}
+	
 	// Public discount validation (for cart/checkout)
 	router.POST("/validate-discount", h.validateDiscountCode)
 	router.POST("/apply-discount", h.applyDiscount)
@@ -53,10 +67,35 @@ func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 		giftCards.POST("/:id/refill", h.refillGiftCard)
 	}
 	
+	// Gift cards bought through checkout
+	purchases := router.Group("/gift-card-purchases")
+	{
+		purchases.GET("", h.getGiftCardPurchases)
+		purchases.POST("/deliver", h.deliverGiftCards)
+		purchases.POST("/:id/resend", h.resendGiftCard)
+		purchases.POST("/orders/:orderId/issue", h.issueOrderGiftCards)
+		purchases.POST("/orders/:orderId/cancel", h.cancelOrderGiftCards)
+	}
+	
 	// Public gift card validation
 	router.POST("/validate-gift-card", h.validateGiftCard)
 	router.POST("/use-gift-card", h.useGiftCard)
 	
+	// Gift card and store credit ledgers
+	ledger := router.Group("/ledger")
+	{
+		ledger.GET("/holds", h.getHolds)
+		ledger.POST("/holds", h.holdBalance)
+		ledger.POST("/holds/:id/capture", h.captureHold)
+		ledger.POST("/holds/:id/release", h.releaseHold)
+		ledger.POST("/orders/:orderId/capture", h.captureOrderHolds)
+		ledger.POST("/orders/:orderId/release", h.releaseOrderHolds)
+		ledger.POST("/orders/:orderId/refund", h.refundToOriginalSources)
+		ledger.POST("/expire", h.expireBalances)
+		ledger.GET("/breakage", h.getBreakageReport)
+		ledger.GET("/reconciliation", h.reconcileLedgers)
+	}
+	
 	// Store credit routes
 	storeCredit := router.Group("/store-credit")
 	{
@@ -198,12 +237,135 @@ func (h *Handler) getDiscountUsage(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": usage})
 }
 
+// Code pool handlers
+func (h *Handler) generateDiscountCodes(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+	
+	discountID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid discount ID"})
+		return
+	}
+	
+	var req GenerateCodesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.TenantID = tenantID.(uuid.UUID)
+	req.DiscountID = discountID
+	if userID, exists := c.Get("user_id"); exists {
+		if id, ok := userID.(uuid.UUID); ok {
+			req.CreatedBy = &id
+		}
+	}
+	
+	batch, err := h.service.GenerateCodes(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusCreated, gin.H{"data": batch, "message": "Discount codes generated successfully"})
+}
+
+func (h *Handler) getDiscountCodes(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+	
+	discountID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid discount ID"})
+		return
+	}
+	
+	filter := h.parseDiscountCodeFilter(c)
+	
+	codes, err := h.service.GetDiscountCodes(c.Request.Context(), tenantID.(uuid.UUID), discountID, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": codes})
+}
+
+func (h *Handler) exportDiscountCodes(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+	
+	discountID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid discount ID"})
+		return
+	}
+	
+	filter := h.parseDiscountCodeFilter(c)
+	
+	data, filename, err := h.service.ExportDiscountCodes(c.Request.Context(), tenantID.(uuid.UUID), discountID, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Data(http.StatusOK, "text/csv", data)
+}
+
+func (h *Handler) updateDiscountCode(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+	
+	discountID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid discount ID"})
+		return
+	}
+	
+	codeID, err := uuid.Parse(c.Param("codeId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code ID"})
+		return
+	}
+	
+	var req struct {
+		Status DiscountCodeStatus `json:"status" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	if err := h.service.SetDiscountCodeStatus(c.Request.Context(), tenantID.(uuid.UUID), discountID, codeID, req.Status); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"message": "Discount code updated successfully"})
+}
+
 func (h *Handler) validateDiscountCode(c *gin.Context) {
 	var req ValidateDiscountRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if tenantID, exists := c.Get("tenant_id"); exists {
+		req.TenantID = tenantID.(uuid.UUID)
+	}
 	
 	validation, err := h.service.ValidateDiscountCode(c.Request.Context(), req)
 	if err != nil {
@@ -254,6 +416,46 @@ func (h *Handler) removeDiscount(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Discount removed successfully"})
 }
 
+// Promotion handlers
+func (h *Handler) getAutomaticPromotions(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+	
+	promotions, err := h.service.GetAutomaticPromotions(c.Request.Context(), tenantID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": promotions})
+}
+
+func (h *Handler) evaluatePromotions(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+	
+	var req EvaluatePromotionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.TenantID = tenantID.(uuid.UUID)
+	
+	result, err := h.service.EvaluatePromotions(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
 // Analytics handlers
 func (h *Handler) getDiscountStats(c *gin.Context) {
 	// TODO: Get tenant ID from context
@@ -307,6 +509,9 @@ func (h *Handler) createGiftCard(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if tenantID, exists := c.Get("tenant_id"); exists {
+		req.TenantID = tenantID.(uuid.UUID)
+	}
 	
 	giftCard, err := h.service.CreateGiftCard(c.Request.Context(), req)
 	if err != nil {
@@ -478,6 +683,386 @@ func (h *Handler) useGiftCard(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": transaction})
 }
 
+// Ledger handlers
+func (h *Handler) getHolds(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+	
+	filter := HoldFilter{AccountType: LedgerAccount(c.Query("account_type"))}
+	if accountID, err := uuid.Parse(c.Query("account_id")); err == nil {
+		filter.AccountID = &accountID
+	}
+	if orderID, err := uuid.Parse(c.Query("order_id")); err == nil {
+		filter.OrderID = &orderID
+	}
+	for _, status := range c.QueryArray("status") {
+		filter.Status = append(filter.Status, HoldStatus(status))
+	}
+	if page, err := strconv.Atoi(c.DefaultQuery("page", "1")); err == nil {
+		filter.Page = page
+	}
+	if limit, err := strconv.Atoi(c.DefaultQuery("limit", "20")); err == nil {
+		filter.Limit = limit
+	}
+	
+	holds, err := h.service.GetHolds(c.Request.Context(), tenantID.(uuid.UUID), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": holds})
+}
+
+func (h *Handler) holdBalance(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+	
+	var req HoldBalanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.TenantID = tenantID.(uuid.UUID)
+	
+	hold, err := h.service.HoldBalance(c.Request.Context(), req)
+	if err != nil {
+		respondLedgerError(c, err)
+		return
+	}
+	
+	c.JSON(http.StatusCreated, gin.H{"data": hold})
+}
+
+func (h *Handler) captureHold(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+	
+	holdID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hold ID"})
+		return
+	}
+	
+	hold, err := h.service.CaptureHold(c.Request.Context(), tenantID.(uuid.UUID), holdID)
+	if err != nil {
+		respondLedgerError(c, err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": hold})
+}
+
+func (h *Handler) releaseHold(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+	
+	holdID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hold ID"})
+		return
+	}
+	
+	hold, err := h.service.ReleaseHold(c.Request.Context(), tenantID.(uuid.UUID), holdID, c.Query("reason"))
+	if err != nil {
+		respondLedgerError(c, err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": hold})
+}
+
+func (h *Handler) captureOrderHolds(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+	
+	orderID, err := uuid.Parse(c.Param("orderId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+	
+	holds, err := h.service.CaptureOrderHolds(c.Request.Context(), tenantID.(uuid.UUID), orderID)
+	if err != nil {
+		respondLedgerError(c, err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": holds})
+}
+
+func (h *Handler) releaseOrderHolds(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+	
+	orderID, err := uuid.Parse(c.Param("orderId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+	
+	holds, err := h.service.ReleaseOrderHolds(c.Request.Context(), tenantID.(uuid.UUID), orderID, c.Query("reason"))
+	if err != nil {
+		respondLedgerError(c, err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": holds})
+}
+
+func (h *Handler) refundToOriginalSources(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+	
+	orderID, err := uuid.Parse(c.Param("orderId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+	
+	var req RefundBalanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.TenantID = tenantID.(uuid.UUID)
+	req.OrderID = orderID
+	if userID, exists := c.Get("user_id"); exists {
+		if id, ok := userID.(uuid.UUID); ok {
+			req.ProcessedBy = &id
+		}
+	}
+	
+	allocations, err := h.service.RefundToOriginalSources(c.Request.Context(), req)
+	if err != nil {
+		respondLedgerError(c, err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": allocations, "message": "Refund returned to original payment sources"})
+}
+
+func (h *Handler) expireBalances(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+	
+	result, err := h.service.ExpireBalances(c.Request.Context(), tenantID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+func (h *Handler) getBreakageReport(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+	
+	endDate := time.Now()
+	startDate := endDate.AddDate(0, -1, 0)
+	if t, err := time.Parse("2006-01-02", c.Query("start_date")); err == nil {
+		startDate = t
+	}
+	if t, err := time.Parse("2006-01-02", c.Query("end_date")); err == nil {
+		endDate = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	
+	report, err := h.service.GetBreakageReport(c.Request.Context(), tenantID.(uuid.UUID), startDate, endDate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+func (h *Handler) reconcileLedgers(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+	
+	report, err := h.service.ReconcileLedgers(c.Request.Context(), tenantID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+// respondLedgerError maps ledger errors to HTTP statuses
+func respondLedgerError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Hold or balance not found"})
+	case errors.Is(err, ErrInsufficientBalance), errors.Is(err, ErrAccountNotUsable), errors.Is(err, ErrHoldNotOpen):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// Gift card purchase handlers
+func (h *Handler) getGiftCardPurchases(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+	
+	filter := GiftCardPurchaseFilter{RecipientEmail: c.Query("recipient_email")}
+	if orderID, err := uuid.Parse(c.Query("order_id")); err == nil {
+		filter.OrderID = &orderID
+	}
+	for _, status := range c.QueryArray("status") {
+		filter.Status = append(filter.Status, GiftCardPurchaseStatus(status))
+	}
+	if page, err := strconv.Atoi(c.DefaultQuery("page", "1")); err == nil {
+		filter.Page = page
+	}
+	if limit, err := strconv.Atoi(c.DefaultQuery("limit", "20")); err == nil {
+		filter.Limit = limit
+	}
+	
+	purchases, err := h.service.GetGiftCardPurchases(c.Request.Context(), tenantID.(uuid.UUID), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": purchases})
+}
+
+// deliverGiftCards sends the gift card emails that are due; call it on a schedule
+func (h *Handler) deliverGiftCards(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+	
+	result, err := h.service.DeliverGiftCards(c.Request.Context(), tenantID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+func (h *Handler) resendGiftCard(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+	
+	purchaseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid purchase ID"})
+		return
+	}
+	
+	var req ResendGiftCardRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	
+	purchase, err := h.service.ResendGiftCard(c.Request.Context(), tenantID.(uuid.UUID), purchaseID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Gift card purchase not found"})
+		case errors.Is(err, ErrPurchaseNotIssued):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": purchase})
+}
+
+// issueOrderGiftCards is called once an order's payment succeeds
+func (h *Handler) issueOrderGiftCards(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+	
+	orderID, err := uuid.Parse(c.Param("orderId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+	
+	purchases, err := h.service.IssueOrderGiftCards(c.Request.Context(), tenantID.(uuid.UUID), orderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": purchases})
+}
+
+// cancelOrderGiftCards is called when an order is cancelled before payment
+func (h *Handler) cancelOrderGiftCards(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+	
+	orderID, err := uuid.Parse(c.Param("orderId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+	
+	cancelled, err := h.service.CancelOrderGiftCards(c.Request.Context(), tenantID.(uuid.UUID), orderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"cancelled": cancelled}})
+}
+
 // Store credit handlers
 func (h *Handler) getStoreCredit(c *gin.Context) {
 	customerID, err := uuid.Parse(c.Param("customerId"))
@@ -677,6 +1262,34 @@ func (h *Handler) parseUsageFilter(c *gin.Context) UsageFilter {
 	return filter
 }
 
+func (h *Handler) parseDiscountCodeFilter(c *gin.Context) DiscountCodeFilter {
+	filter := DiscountCodeFilter{}
+	
+	// Parse status array
+	if statuses := c.QueryArray("status"); len(statuses) > 0 {
+		for _, s := range statuses {
+			filter.Status = append(filter.Status, DiscountCodeStatus(s))
+		}
+	}
+	
+	if batchID, err := uuid.Parse(c.Query("batch_id")); err == nil {
+		filter.BatchID = &batchID
+	}
+	
+	filter.Search = c.Query("search")
+	
+	// Parse pagination
+	if page, err := strconv.Atoi(c.DefaultQuery("page", "1")); err == nil {
+		filter.Page = page
+	}
+	
+	if limit, err := strconv.Atoi(c.DefaultQuery("limit", "50")); err == nil {
+		filter.Limit = limit
+	}
+	
+	return filter
+}
+
 func (h *Handler) parseGiftCardFilter(c *gin.Context) GiftCardFilter {
 	filter := GiftCardFilter{}
 	
@@ -0,0 +1,847 @@
+package discount
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"ecommerce-saas/internal/order"
+)
+
+// LedgerAccount identifies the kind of balance a ledger entry belongs to
+type LedgerAccount string
+
+const (
+	AccountGiftCard    LedgerAccount = "gift_card"
+	AccountStoreCredit LedgerAccount = "store_credit"
+)
+
+// Ledger entry types. Gift card and store credit transactions are append-only;
+// an account's balance is always the sum of its entries.
+const (
+	LedgerInitial = "initial" // Balance a gift card was issued with
+	LedgerCredit  = "credit"  // Refill or store credit grant
+	LedgerRefund  = "refund"  // Money returned from a refunded order
+	LedgerDebit   = "debit"   // Direct redemption without a hold
+	LedgerHold    = "hold"    // Reserves balance; the balance itself is unchanged
+	LedgerCapture = "capture" // Spends a held amount
+	LedgerRelease = "release" // Returns a held amount to the available balance
+	LedgerExpiry  = "expiry"  // Balance forfeited when the account expired (breakage)
+)
+
+// HoldStatus represents the state of a balance hold
+type HoldStatus string
+
+const (
+	HoldStatusHeld     HoldStatus = "held"
+	HoldStatusCaptured HoldStatus = "captured"
+	HoldStatusReleased HoldStatus = "released"
+)
+
+// Ledger errors
+var (
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrAccountNotUsable    = errors.New("balance is not active or has expired")
+	ErrHoldNotOpen         = errors.New("hold is not open")
+)
+
+// BalanceHold reserves part of a gift card or store credit balance while an
+// order is being paid for. It is captured once payment succeeds or released
+// when payment fails or the hold expires.
+type BalanceHold struct {
+	ID          uuid.UUID     `json:"id" gorm:"primarykey"`
+	TenantID    uuid.UUID     `json:"tenant_id" gorm:"not null;index"`
+	AccountType LedgerAccount `json:"account_type" gorm:"not null;index"`
+	AccountID   uuid.UUID     `json:"account_id" gorm:"not null;index"`
+	Amount      float64       `json:"amount" gorm:"not null"`
+	Status      HoldStatus    `json:"status" gorm:"default:held;index"`
+	Reference   string        `json:"reference,omitempty" gorm:"index"` // e.g. the checkout session
+
+	// Order information
+	OrderID       *uuid.UUID `json:"order_id,omitempty" gorm:"index"`
+	OrderNumber   string     `json:"order_number,omitempty"`
+	CustomerID    *uuid.UUID `json:"customer_id,omitempty"`
+	CustomerEmail string     `json:"customer_email,omitempty"`
+
+	ExpiresAt     *time.Time `json:"expires_at,omitempty" gorm:"index"`
+	CapturedAt    *time.Time `json:"captured_at,omitempty"`
+	ReleasedAt    *time.Time `json:"released_at,omitempty"`
+	ReleaseReason string     `json:"release_reason,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// LedgerEntry describes one movement on an account. Amount is always
+// positive; the entry type decides its direction.
+type LedgerEntry struct {
+	Type          string
+	Amount        float64
+	Description   string
+	HoldID        *uuid.UUID
+	OrderID       *uuid.UUID
+	OrderNumber   string
+	CustomerID    *uuid.UUID
+	CustomerEmail string
+	ProcessedBy   *uuid.UUID
+	RefundID      *uuid.UUID
+	ReturnID      *uuid.UUID
+	Reference     string // Idempotency key; store credit entries only
+}
+
+// HoldFilter narrows a hold listing
+type HoldFilter struct {
+	AccountType LedgerAccount `json:"account_type"`
+	AccountID   *uuid.UUID    `json:"account_id"`
+	OrderID     *uuid.UUID    `json:"order_id"`
+	Status      []HoldStatus  `json:"status"`
+	Page        int           `json:"page"`
+	Limit       int           `json:"limit"`
+}
+
+// RefundAllocation is the part of a refund returned to one account
+type RefundAllocation struct {
+	AccountType LedgerAccount `json:"account_type"`
+	AccountID   uuid.UUID     `json:"account_id"`
+	Amount      float64       `json:"amount"`
+	Balance     float64       `json:"balance"`
+}
+
+// ExpiryResult summarises one expiry run
+type ExpiryResult struct {
+	GiftCardsExpired    int     `json:"gift_cards_expired"`
+	GiftCardBreakage    float64 `json:"gift_card_breakage"`
+	StoreCreditsExpired int     `json:"store_credits_expired"`
+	StoreCreditBreakage float64 `json:"store_credit_breakage"`
+	SkippedWithHolds    int     `json:"skipped_with_holds"` // Retried once their holds settle
+	HoldsReleased       int     `json:"holds_released"`
+	HoldsCaptured       int     `json:"holds_captured"` // Lapsed holds of orders paid in the meantime
+}
+
+// BreakageReport shows balance forfeited through expiry and what is still owed
+type BreakageReport struct {
+	StartDate              time.Time `json:"start_date"`
+	EndDate                time.Time `json:"end_date"`
+	GiftCardBreakage       float64   `json:"gift_card_breakage"`
+	GiftCardsExpired       int       `json:"gift_cards_expired"`
+	StoreCreditBreakage    float64   `json:"store_credit_breakage"`
+	StoreCreditsExpired    int       `json:"store_credits_expired"`
+	TotalBreakage          float64   `json:"total_breakage"`
+	OutstandingGiftCards   float64   `json:"outstanding_gift_cards"`   // Unspent gift card liability
+	OutstandingStoreCredit float64   `json:"outstanding_store_credit"` // Unspent store credit liability
+}
+
+// LedgerMismatch is an account whose stored balance disagrees with its ledger
+type LedgerMismatch struct {
+	AccountType     LedgerAccount `json:"account_type"`
+	AccountID       uuid.UUID     `json:"account_id"`
+	Reference       string        `json:"reference"` // Gift card code or customer ID
+	RecordedBalance float64       `json:"recorded_balance"`
+	LedgerBalance   float64       `json:"ledger_balance"`
+	RecordedHeld    float64       `json:"recorded_held"`
+	LedgerHeld      float64       `json:"ledger_held"`
+	OpenHolds       float64       `json:"open_holds"`
+}
+
+// ReconciliationReport compares every account balance with its ledger sum
+type ReconciliationReport struct {
+	AccountsChecked int              `json:"accounts_checked"`
+	RecordedTotal   float64          `json:"recorded_total"`
+	LedgerTotal     float64          `json:"ledger_total"`
+	Balanced        bool             `json:"balanced"`
+	Mismatches      []LedgerMismatch `json:"mismatches"`
+	CheckedAt       time.Time        `json:"checked_at"`
+}
+
+// balanceEffect is the sign an entry type applies to the balance
+func balanceEffect(entryType string) float64 {
+	switch entryType {
+	case LedgerInitial, LedgerCredit, LedgerRefund:
+		return 1
+	case LedgerDebit, LedgerCapture, LedgerExpiry:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// holdEffect is the sign an entry type applies to the held amount
+func holdEffect(entryType string) float64 {
+	switch entryType {
+	case LedgerHold:
+		return 1
+	case LedgerCapture, LedgerRelease:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// Ledger posts gift card and store credit movements. Every posting locks the
+// account row, so concurrent checkouts are serialised per account and can
+// never spend more than the available balance. Use NewLedger with a
+// transaction to post as part of a larger unit of work.
+type Ledger struct {
+	db *gorm.DB
+}
+
+// NewLedger creates a ledger on a database handle or transaction
+func NewLedger(db *gorm.DB) *Ledger {
+	return &Ledger{db: db}
+}
+
+// IssueGiftCard creates a gift card and records its initial balance as the
+// first ledger entry
+func (l *Ledger) IssueGiftCard(ctx context.Context, giftCard *GiftCard, entry LedgerEntry) error {
+	amount := giftCard.InitialValue
+	return l.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		giftCard.CurrentValue, giftCard.HeldAmount = 0, 0
+		if err := tx.Create(giftCard).Error; err != nil {
+			return err
+		}
+
+		entry.Type, entry.Amount = LedgerInitial, amount
+		if entry.Description == "" {
+			entry.Description = "Gift card issued"
+		}
+		if _, err := postGiftCard(tx, giftCard.TenantID, giftCard.ID, entry); err != nil {
+			return err
+		}
+		giftCard.CurrentValue = amount
+		return nil
+	})
+}
+
+// PostGiftCard applies an entry to a gift card and appends it to the card's
+// transactions
+func (l *Ledger) PostGiftCard(ctx context.Context, tenantID, giftCardID uuid.UUID, entry LedgerEntry) (*GiftCardTransaction, error) {
+	var transaction *GiftCardTransaction
+	err := l.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		transaction, err = postGiftCard(tx, tenantID, giftCardID, entry)
+		return err
+	})
+	return transaction, err
+}
+
+// PostStoreCredit applies an entry to a store credit account and appends it
+// to the account's transactions
+func (l *Ledger) PostStoreCredit(ctx context.Context, tenantID, storeCreditID uuid.UUID, entry LedgerEntry) (*StoreCreditTransaction, error) {
+	var transaction *StoreCreditTransaction
+	err := l.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		transaction, err = postStoreCredit(tx, tenantID, storeCreditID, entry)
+		return err
+	})
+	return transaction, err
+}
+
+// Hold reserves an amount on an account
+func (l *Ledger) Hold(ctx context.Context, hold *BalanceHold) error {
+	if hold.Amount <= 0 {
+		return errors.New("hold amount must be positive")
+	}
+	if hold.ID == uuid.Nil {
+		hold.ID = uuid.New()
+	}
+	now := time.Now()
+	hold.Status = HoldStatusHeld
+	hold.CreatedAt, hold.UpdatedAt = now, now
+
+	return l.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		entry := LedgerEntry{
+			Type:          LedgerHold,
+			Amount:        hold.Amount,
+			Description:   "Held for order",
+			HoldID:        &hold.ID,
+			OrderID:       hold.OrderID,
+			OrderNumber:   hold.OrderNumber,
+			CustomerID:    hold.CustomerID,
+			CustomerEmail: hold.CustomerEmail,
+		}
+		if err := post(tx, hold.TenantID, hold.AccountType, hold.AccountID, entry); err != nil {
+			return err
+		}
+		return tx.Create(hold).Error
+	})
+}
+
+// Capture spends a held amount
+func (l *Ledger) Capture(ctx context.Context, tenantID, holdID uuid.UUID) (*BalanceHold, error) {
+	return l.settle(ctx, tenantID, holdID, LedgerCapture, "Captured on payment")
+}
+
+// Release returns a held amount to the available balance
+func (l *Ledger) Release(ctx context.Context, tenantID, holdID uuid.UUID, reason string) (*BalanceHold, error) {
+	if reason == "" {
+		reason = "Released"
+	}
+	return l.settle(ctx, tenantID, holdID, LedgerRelease, reason)
+}
+
+// CaptureOrder captures every open hold for an order
+func (l *Ledger) CaptureOrder(ctx context.Context, tenantID, orderID uuid.UUID) ([]BalanceHold, error) {
+	return l.settleOrder(ctx, tenantID, orderID, func(holdID uuid.UUID) (*BalanceHold, error) {
+		return l.Capture(ctx, tenantID, holdID)
+	})
+}
+
+// ReleaseOrder releases every open hold for an order
+func (l *Ledger) ReleaseOrder(ctx context.Context, tenantID, orderID uuid.UUID, reason string) ([]BalanceHold, error) {
+	return l.settleOrder(ctx, tenantID, orderID, func(holdID uuid.UUID) (*BalanceHold, error) {
+		return l.Release(ctx, tenantID, holdID, reason)
+	})
+}
+
+// ReleaseExpiredHolds settles holds that were neither captured nor released
+// before they expired. Holds of orders that were paid in the meantime are
+// captured, since the balance was spent; holds of orders whose payment is
+// only authorized stay open until it settles. The rest are released.
+func (l *Ledger) ReleaseExpiredHolds(ctx context.Context, tenantID uuid.UUID, now time.Time) (released, captured int, err error) {
+	var holds []struct {
+		ID            uuid.UUID
+		PaymentStatus string
+	}
+	if err := l.db.WithContext(ctx).Model(&BalanceHold{}).
+		Select("balance_holds.id, COALESCE(orders.payment_status, '') AS payment_status").
+		Joins("LEFT JOIN orders ON orders.id = balance_holds.order_id AND orders.tenant_id = balance_holds.tenant_id").
+		Where("balance_holds.tenant_id = ? AND balance_holds.status = ? AND balance_holds.expires_at IS NOT NULL AND balance_holds.expires_at < ?", tenantID, HoldStatusHeld, now).
+		Scan(&holds).Error; err != nil {
+		return 0, 0, err
+	}
+
+	for _, hold := range holds {
+		switch order.PaymentStatus(hold.PaymentStatus) {
+		case order.PaymentPaid, order.PaymentRefunded:
+			if _, err := l.Capture(ctx, tenantID, hold.ID); err != nil {
+				if errors.Is(err, ErrHoldNotOpen) {
+					continue
+				}
+				return released, captured, err
+			}
+			captured++
+		case order.PaymentAuthorized:
+			continue
+		default:
+			if _, err := l.Release(ctx, tenantID, hold.ID, "Hold expired"); err != nil {
+				if errors.Is(err, ErrHoldNotOpen) {
+					continue
+				}
+				return released, captured, err
+			}
+			released++
+		}
+	}
+	return released, captured, nil
+}
+
+// RefundOrder returns up to amount to the gift cards and store credit that
+// paid for an order, in the order they were used, never more than each one
+// contributed
+func (l *Ledger) RefundOrder(ctx context.Context, tenantID, orderID uuid.UUID, amount float64, entry LedgerEntry) ([]RefundAllocation, error) {
+	if amount <= 0 {
+		return nil, errors.New("refund amount must be positive")
+	}
+
+	var allocations []RefundAllocation
+	err := l.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		type source struct {
+			AccountID uuid.UUID
+			Spent     float64
+			FirstUsed time.Time
+		}
+		spentSQL := "SUM(CASE WHEN type IN ? THEN amount WHEN type = ? THEN -amount ELSE 0 END)"
+		spentTypes := []string{LedgerDebit, LedgerCapture}
+
+		var giftCards, storeCredits []source
+		if err := tx.Model(&GiftCardTransaction{}).
+			Select("gift_card_id AS account_id, "+spentSQL+" AS spent, MIN(created_at) AS first_used", spentTypes, LedgerRefund).
+			Where("tenant_id = ? AND order_id = ?", tenantID, orderID).
+			Group("gift_card_id").Order("first_used ASC").
+			Scan(&giftCards).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&StoreCreditTransaction{}).
+			Select("store_credit_id AS account_id, "+spentSQL+" AS spent, MIN(created_at) AS first_used", spentTypes, LedgerRefund).
+			Where("tenant_id = ? AND order_id = ?", tenantID, orderID).
+			Group("store_credit_id").Order("first_used ASC").
+			Scan(&storeCredits).Error; err != nil {
+			return err
+		}
+
+		remaining := roundCents(amount)
+		refund := entry
+		refund.Type = LedgerRefund
+		refund.OrderID = &orderID
+
+		for _, gc := range giftCards {
+			if remaining <= 0 {
+				break
+			}
+			refund.Amount = roundCents(math.Min(gc.Spent, remaining))
+			if refund.Amount <= 0 {
+				continue
+			}
+			transaction, err := postGiftCard(tx, tenantID, gc.AccountID, refund)
+			if err != nil {
+				return err
+			}
+			allocations = append(allocations, RefundAllocation{AccountType: AccountGiftCard, AccountID: gc.AccountID, Amount: refund.Amount, Balance: transaction.Balance})
+			remaining = roundCents(remaining - refund.Amount)
+		}
+		for _, sc := range storeCredits {
+			if remaining <= 0 {
+				break
+			}
+			refund.Amount = roundCents(math.Min(sc.Spent, remaining))
+			if refund.Amount <= 0 {
+				continue
+			}
+			transaction, err := postStoreCredit(tx, tenantID, sc.AccountID, refund)
+			if err != nil {
+				return err
+			}
+			allocations = append(allocations, RefundAllocation{AccountType: AccountStoreCredit, AccountID: sc.AccountID, Amount: refund.Amount, Balance: transaction.Balance})
+			remaining = roundCents(remaining - refund.Amount)
+		}
+
+		if remaining > 0 {
+			return fmt.Errorf("only %.2f of the refund was paid with gift cards or store credit", roundCents(amount-remaining))
+		}
+		return nil
+	})
+	return allocations, err
+}
+
+// ExpireBalances forfeits the remaining balance of expired gift cards and
+// store credit. Accounts with open holds are skipped until the holds settle.
+func (l *Ledger) ExpireBalances(ctx context.Context, tenantID uuid.UUID, now time.Time) (*ExpiryResult, error) {
+	result := &ExpiryResult{}
+	db := l.db.WithContext(ctx)
+
+	var giftCardIDs []uuid.UUID
+	if err := db.Model(&GiftCard{}).
+		Where("tenant_id = ? AND status = ? AND expires_at IS NOT NULL AND expires_at < ?", tenantID, StatusActive, now).
+		Pluck("id", &giftCardIDs).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range giftCardIDs {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var gc GiftCard
+			if err := lockAccount(tx, tenantID, id, &gc); err != nil {
+				return err
+			}
+			if gc.HeldAmount > 0 {
+				result.SkippedWithHolds++
+				return nil
+			}
+			if gc.CurrentValue > 0 {
+				if _, err := postGiftCard(tx, tenantID, id, LedgerEntry{Type: LedgerExpiry, Amount: gc.CurrentValue, Description: "Gift card expired"}); err != nil {
+					return err
+				}
+				result.GiftCardBreakage += gc.CurrentValue
+			}
+			result.GiftCardsExpired++
+			return tx.Model(&GiftCard{}).Where("id = ?", id).
+				Updates(map[string]interface{}{"status": StatusExpired, "updated_at": now}).Error
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var storeCreditIDs []uuid.UUID
+	if err := db.Model(&StoreCredit{}).
+		Where("tenant_id = ? AND current_balance > 0 AND expires_at IS NOT NULL AND expires_at < ?", tenantID, now).
+		Pluck("id", &storeCreditIDs).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range storeCreditIDs {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var sc StoreCredit
+			if err := lockAccount(tx, tenantID, id, &sc); err != nil {
+				return err
+			}
+			if sc.HeldAmount > 0 {
+				result.SkippedWithHolds++
+				return nil
+			}
+			if sc.CurrentBalance <= 0 {
+				return nil
+			}
+			if _, err := postStoreCredit(tx, tenantID, id, LedgerEntry{Type: LedgerExpiry, Amount: sc.CurrentBalance, Description: "Store credit expired"}); err != nil {
+				return err
+			}
+			result.StoreCreditsExpired++
+			result.StoreCreditBreakage += sc.CurrentBalance
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result.GiftCardBreakage = roundCents(result.GiftCardBreakage)
+	result.StoreCreditBreakage = roundCents(result.StoreCreditBreakage)
+	return result, nil
+}
+
+// BreakageReport sums expired balances between two dates along with the
+// outstanding liability
+func (l *Ledger) BreakageReport(ctx context.Context, tenantID uuid.UUID, startDate, endDate time.Time) (*BreakageReport, error) {
+	db := l.db.WithContext(ctx)
+	report := &BreakageReport{StartDate: startDate, EndDate: endDate}
+
+	type breakage struct {
+		Accounts int
+		Amount   float64
+	}
+	var giftCards, storeCredits breakage
+	if err := db.Model(&GiftCardTransaction{}).
+		Select("COUNT(DISTINCT gift_card_id) AS accounts, COALESCE(SUM(amount), 0) AS amount").
+		Where("tenant_id = ? AND type = ? AND created_at BETWEEN ? AND ?", tenantID, LedgerExpiry, startDate, endDate).
+		Scan(&giftCards).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&StoreCreditTransaction{}).
+		Select("COUNT(DISTINCT store_credit_id) AS accounts, COALESCE(SUM(amount), 0) AS amount").
+		Where("tenant_id = ? AND type = ? AND created_at BETWEEN ? AND ?", tenantID, LedgerExpiry, startDate, endDate).
+		Scan(&storeCredits).Error; err != nil {
+		return nil, err
+	}
+
+	if err := db.Model(&GiftCard{}).
+		Select("COALESCE(SUM(current_value), 0)").
+		Where("tenant_id = ? AND status = ?", tenantID, StatusActive).
+		Scan(&report.OutstandingGiftCards).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&StoreCredit{}).
+		Select("COALESCE(SUM(current_balance), 0)").
+		Where("tenant_id = ?", tenantID).
+		Scan(&report.OutstandingStoreCredit).Error; err != nil {
+		return nil, err
+	}
+
+	report.GiftCardsExpired, report.GiftCardBreakage = giftCards.Accounts, roundCents(giftCards.Amount)
+	report.StoreCreditsExpired, report.StoreCreditBreakage = storeCredits.Accounts, roundCents(storeCredits.Amount)
+	report.TotalBreakage = roundCents(report.GiftCardBreakage + report.StoreCreditBreakage)
+	return report, nil
+}
+
+// Reconcile recomputes every balance and held amount from the ledger and
+// reports the accounts that disagree with their stored values
+func (l *Ledger) Reconcile(ctx context.Context, tenantID uuid.UUID) (*ReconciliationReport, error) {
+	db := l.db.WithContext(ctx)
+	report := &ReconciliationReport{Mismatches: []LedgerMismatch{}, CheckedAt: time.Now()}
+
+	balanceSQL := "COALESCE(SUM(CASE WHEN t.type IN ? THEN t.amount WHEN t.type IN ? THEN -t.amount ELSE 0 END), 0)"
+	heldSQL := "COALESCE(SUM(CASE WHEN t.type = ? THEN t.amount WHEN t.type IN ? THEN -t.amount ELSE 0 END), 0)"
+	credits := []string{LedgerInitial, LedgerCredit, LedgerRefund}
+	debits := []string{LedgerDebit, LedgerCapture, LedgerExpiry}
+	settled := []string{LedgerCapture, LedgerRelease}
+
+	type row struct {
+		AccountID       uuid.UUID
+		Reference       string
+		RecordedBalance float64
+		RecordedHeld    float64
+		LedgerBalance   float64
+		LedgerHeld      float64
+	}
+
+	var giftCards []row
+	if err := db.Table("gift_cards AS a").
+		Select("a.id AS account_id, a.code AS reference, a.current_value AS recorded_balance, a.held_amount AS recorded_held, "+
+			balanceSQL+" AS ledger_balance, "+heldSQL+" AS ledger_held", credits, debits, LedgerHold, settled).
+		Joins("LEFT JOIN gift_card_transactions t ON t.gift_card_id = a.id").
+		Where("a.tenant_id = ?", tenantID).
+		Group("a.id, a.code, a.current_value, a.held_amount").
+		Scan(&giftCards).Error; err != nil {
+		return nil, err
+	}
+
+	var storeCredits []row
+	if err := db.Table("store_credits AS a").
+		Select("a.id AS account_id, CAST(a.customer_id AS TEXT) AS reference, a.current_balance AS recorded_balance, a.held_amount AS recorded_held, "+
+			balanceSQL+" AS ledger_balance, "+heldSQL+" AS ledger_held", credits, debits, LedgerHold, settled).
+		Joins("LEFT JOIN store_credit_transactions t ON t.store_credit_id = a.id").
+		Where("a.tenant_id = ?", tenantID).
+		Group("a.id, a.customer_id, a.current_balance, a.held_amount").
+		Scan(&storeCredits).Error; err != nil {
+		return nil, err
+	}
+
+	openHolds := make(map[uuid.UUID]float64)
+	var holds []BalanceHold
+	if err := db.Where("tenant_id = ? AND status = ?", tenantID, HoldStatusHeld).Find(&holds).Error; err != nil {
+		return nil, err
+	}
+	for _, hold := range holds {
+		openHolds[hold.AccountID] += hold.Amount
+	}
+
+	check := func(accountType LedgerAccount, rows []row) {
+		for _, r := range rows {
+			report.AccountsChecked++
+			report.RecordedTotal += r.RecordedBalance
+			report.LedgerTotal += r.LedgerBalance
+
+			open := roundCents(openHolds[r.AccountID])
+			if math.Abs(r.RecordedBalance-r.LedgerBalance) < 0.005 &&
+				math.Abs(r.RecordedHeld-r.LedgerHeld) < 0.005 &&
+				math.Abs(r.RecordedHeld-open) < 0.005 {
+				continue
+			}
+			report.Mismatches = append(report.Mismatches, LedgerMismatch{
+				AccountType:     accountType,
+				AccountID:       r.AccountID,
+				Reference:       r.Reference,
+				RecordedBalance: roundCents(r.RecordedBalance),
+				LedgerBalance:   roundCents(r.LedgerBalance),
+				RecordedHeld:    roundCents(r.RecordedHeld),
+				LedgerHeld:      roundCents(r.LedgerHeld),
+				OpenHolds:       open,
+			})
+		}
+	}
+	check(AccountGiftCard, giftCards)
+	check(AccountStoreCredit, storeCredits)
+
+	report.RecordedTotal = roundCents(report.RecordedTotal)
+	report.LedgerTotal = roundCents(report.LedgerTotal)
+	report.Balanced = len(report.Mismatches) == 0
+	return report, nil
+}
+
+// settle captures or releases one open hold
+func (l *Ledger) settle(ctx context.Context, tenantID, holdID uuid.UUID, entryType, description string) (*BalanceHold, error) {
+	var hold BalanceHold
+	err := l.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND tenant_id = ?", holdID, tenantID).
+			First(&hold).Error; err != nil {
+			return err
+		}
+		if hold.Status != HoldStatusHeld {
+			return ErrHoldNotOpen
+		}
+
+		entry := LedgerEntry{
+			Type:          entryType,
+			Amount:        hold.Amount,
+			Description:   description,
+			HoldID:        &hold.ID,
+			OrderID:       hold.OrderID,
+			OrderNumber:   hold.OrderNumber,
+			CustomerID:    hold.CustomerID,
+			CustomerEmail: hold.CustomerEmail,
+		}
+		if err := post(tx, tenantID, hold.AccountType, hold.AccountID, entry); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		updates := map[string]interface{}{"updated_at": now}
+		if entryType == LedgerCapture {
+			hold.Status, hold.CapturedAt = HoldStatusCaptured, &now
+			updates["status"], updates["captured_at"] = hold.Status, now
+		} else {
+			hold.Status, hold.ReleasedAt, hold.ReleaseReason = HoldStatusReleased, &now, description
+			updates["status"], updates["released_at"], updates["release_reason"] = hold.Status, now, description
+		}
+		hold.UpdatedAt = now
+		return tx.Model(&BalanceHold{}).Where("id = ?", hold.ID).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// settleOrder settles every open hold for an order
+func (l *Ledger) settleOrder(ctx context.Context, tenantID, orderID uuid.UUID, settle func(uuid.UUID) (*BalanceHold, error)) ([]BalanceHold, error) {
+	var holdIDs []uuid.UUID
+	if err := l.db.WithContext(ctx).Model(&BalanceHold{}).
+		Where("tenant_id = ? AND order_id = ? AND status = ?", tenantID, orderID, HoldStatusHeld).
+		Order("created_at ASC").
+		Pluck("id", &holdIDs).Error; err != nil {
+		return nil, err
+	}
+
+	settled := make([]BalanceHold, 0, len(holdIDs))
+	for _, holdID := range holdIDs {
+		hold, err := settle(holdID)
+		if err != nil {
+			if errors.Is(err, ErrHoldNotOpen) {
+				continue
+			}
+			return settled, err
+		}
+		settled = append(settled, *hold)
+	}
+	return settled, nil
+}
+
+// post applies an entry to either kind of account
+func post(tx *gorm.DB, tenantID uuid.UUID, account LedgerAccount, accountID uuid.UUID, entry LedgerEntry) error {
+	switch account {
+	case AccountGiftCard:
+		_, err := postGiftCard(tx, tenantID, accountID, entry)
+		return err
+	case AccountStoreCredit:
+		_, err := postStoreCredit(tx, tenantID, accountID, entry)
+		return err
+	default:
+		return fmt.Errorf("unknown account type %q", account)
+	}
+}
+
+// postGiftCard locks the card, applies the entry and appends the transaction
+func postGiftCard(tx *gorm.DB, tenantID, giftCardID uuid.UUID, entry LedgerEntry) (*GiftCardTransaction, error) {
+	var gc GiftCard
+	if err := lockAccount(tx, tenantID, giftCardID, &gc); err != nil {
+		return nil, err
+	}
+
+	// Spending needs a live card; refunds and releases always go through
+	if spends(entry.Type) && (gc.Status != StatusActive || (gc.ExpiresAt != nil && time.Now().After(*gc.ExpiresAt))) {
+		return nil, ErrAccountNotUsable
+	}
+
+	balance, held, err := applyEntry(gc.CurrentValue, gc.HeldAmount, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{"current_value": balance, "held_amount": held, "updated_at": now}
+	switch {
+	case entry.Type == LedgerExpiry:
+		updates["status"] = StatusExpired
+	case balance <= 0 && held <= 0 && gc.Status == StatusActive:
+		updates["status"] = "used"
+	case balance > 0 && gc.Status == "used":
+		updates["status"] = StatusActive
+	}
+	if err := tx.Model(&GiftCard{}).Where("id = ?", gc.ID).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+
+	transaction := &GiftCardTransaction{
+		ID:            uuid.New(),
+		GiftCardID:    gc.ID,
+		TenantID:      tenantID,
+		Type:          entry.Type,
+		Amount:        entry.Amount,
+		Balance:       balance,
+		Description:   entry.Description,
+		HoldID:        entry.HoldID,
+		OrderID:       entry.OrderID,
+		OrderNumber:   entry.OrderNumber,
+		CustomerID:    entry.CustomerID,
+		CustomerEmail: entry.CustomerEmail,
+		ProcessedBy:   entry.ProcessedBy,
+		CreatedAt:     now,
+	}
+	if err := tx.Create(transaction).Error; err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// postStoreCredit locks the account, applies the entry and appends the
+// transaction
+func postStoreCredit(tx *gorm.DB, tenantID, storeCreditID uuid.UUID, entry LedgerEntry) (*StoreCreditTransaction, error) {
+	var sc StoreCredit
+	if err := lockAccount(tx, tenantID, storeCreditID, &sc); err != nil {
+		return nil, err
+	}
+
+	if spends(entry.Type) && sc.ExpiresAt != nil && time.Now().After(*sc.ExpiresAt) {
+		return nil, ErrAccountNotUsable
+	}
+
+	// A referenced entry is posted once; the account lock serialises retries
+	if entry.Reference != "" {
+		var existing StoreCreditTransaction
+		err := tx.Where("tenant_id = ? AND store_credit_id = ? AND reference = ?", tenantID, sc.ID, entry.Reference).
+			First(&existing).Error
+		if err == nil {
+			return &existing, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	balance, held, err := applyEntry(sc.CurrentBalance, sc.HeldAmount, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := tx.Model(&StoreCredit{}).Where("id = ?", sc.ID).
+		Updates(map[string]interface{}{"current_balance": balance, "held_amount": held, "updated_at": now}).Error; err != nil {
+		return nil, err
+	}
+
+	transaction := &StoreCreditTransaction{
+		ID:            uuid.New(),
+		StoreCreditID: sc.ID,
+		TenantID:      tenantID,
+		CustomerID:    sc.CustomerID,
+		Type:          entry.Type,
+		Amount:        entry.Amount,
+		Balance:       balance,
+		Description:   entry.Description,
+		HoldID:        entry.HoldID,
+		OrderID:       entry.OrderID,
+		OrderNumber:   entry.OrderNumber,
+		RefundID:      entry.RefundID,
+		ReturnID:      entry.ReturnID,
+		Reference:     entry.Reference,
+		ProcessedBy:   entry.ProcessedBy,
+		CreatedAt:     now,
+	}
+	if err := tx.Create(transaction).Error; err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// lockAccount loads an account row with SELECT ... FOR UPDATE
+func lockAccount(tx *gorm.DB, tenantID, accountID uuid.UUID, account interface{}) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND tenant_id = ?", accountID, tenantID).
+		First(account).Error
+}
+
+// applyEntry returns the balance and held amount after an entry, refusing
+// anything that would spend more than is available
+func applyEntry(balance, held float64, entry LedgerEntry) (float64, float64, error) {
+	if entry.Amount <= 0 {
+		return 0, 0, errors.New("ledger amount must be positive")
+	}
+
+	newBalance := roundCents(balance + balanceEffect(entry.Type)*entry.Amount)
+	newHeld := roundCents(held + holdEffect(entry.Type)*entry.Amount)
+
+	if newHeld < 0 {
+		return 0, 0, ErrHoldNotOpen
+	}
+	if spends(entry.Type) && newBalance-newHeld < -0.005 {
+		return 0, 0, fmt.Errorf("%w: requested %.2f, available %.2f", ErrInsufficientBalance, entry.Amount, roundCents(balance-held))
+	}
+	return newBalance, newHeld, nil
+}
+
+// spends reports whether an entry reduces the available balance
+func spends(entryType string) bool {
+	return entryType == LedgerDebit || entryType == LedgerHold
+}
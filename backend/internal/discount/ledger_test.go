@@ -0,0 +1,169 @@
+package discount
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestApplyEntry(t *testing.T) {
+	tests := []struct {
+		name        string
+		entries     []LedgerEntry
+		wantBalance float64
+		wantHeld    float64
+		wantErr     error // of the last entry; the others must succeed
+	}{
+		{
+			name:        "redemption spends the balance",
+			entries:     []LedgerEntry{{Type: LedgerInitial, Amount: 50}, {Type: LedgerDebit, Amount: 20}},
+			wantBalance: 30,
+		},
+		{
+			name: "a hold keeps the balance until captured",
+			entries: []LedgerEntry{
+				{Type: LedgerInitial, Amount: 50},
+				{Type: LedgerHold, Amount: 30},
+			},
+			wantBalance: 50,
+			wantHeld:    30,
+		},
+		{
+			name: "capturing a hold spends it",
+			entries: []LedgerEntry{
+				{Type: LedgerInitial, Amount: 50},
+				{Type: LedgerHold, Amount: 30},
+				{Type: LedgerCapture, Amount: 30},
+			},
+			wantBalance: 20,
+		},
+		{
+			name: "releasing a hold frees it",
+			entries: []LedgerEntry{
+				{Type: LedgerInitial, Amount: 50},
+				{Type: LedgerHold, Amount: 30},
+				{Type: LedgerRelease, Amount: 30},
+			},
+			wantBalance: 50,
+		},
+		{
+			name: "held balance cannot be spent again",
+			entries: []LedgerEntry{
+				{Type: LedgerInitial, Amount: 50},
+				{Type: LedgerHold, Amount: 30},
+				{Type: LedgerDebit, Amount: 25},
+			},
+			wantErr: ErrInsufficientBalance,
+		},
+		{
+			name: "held balance cannot be held again",
+			entries: []LedgerEntry{
+				{Type: LedgerInitial, Amount: 50},
+				{Type: LedgerHold, Amount: 30},
+				{Type: LedgerHold, Amount: 25},
+			},
+			wantErr: ErrInsufficientBalance,
+		},
+		{
+			name:    "spending more than the balance fails",
+			entries: []LedgerEntry{{Type: LedgerInitial, Amount: 50}, {Type: LedgerDebit, Amount: 50.01}},
+			wantErr: ErrInsufficientBalance,
+		},
+		{
+			name:    "capturing without a hold fails",
+			entries: []LedgerEntry{{Type: LedgerInitial, Amount: 50}, {Type: LedgerCapture, Amount: 10}},
+			wantErr: ErrHoldNotOpen,
+		},
+		{
+			name: "refunds credit a spent balance",
+			entries: []LedgerEntry{
+				{Type: LedgerInitial, Amount: 50},
+				{Type: LedgerDebit, Amount: 50},
+				{Type: LedgerRefund, Amount: 20},
+			},
+			wantBalance: 20,
+		},
+		{
+			name: "expiry forfeits what is left",
+			entries: []LedgerEntry{
+				{Type: LedgerInitial, Amount: 50},
+				{Type: LedgerCredit, Amount: 10},
+				{Type: LedgerExpiry, Amount: 60},
+			},
+		},
+		{
+			name: "amounts are kept in cents",
+			entries: []LedgerEntry{
+				{Type: LedgerInitial, Amount: 0.3},
+				{Type: LedgerDebit, Amount: 0.1},
+				{Type: LedgerDebit, Amount: 0.2},
+			},
+		},
+		{
+			name:    "amounts must be positive",
+			entries: []LedgerEntry{{Type: LedgerInitial, Amount: 50}, {Type: LedgerDebit, Amount: 0}},
+			wantErr: errors.New("ledger amount must be positive"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			balance, held := 0.0, 0.0
+			for i, entry := range tt.entries {
+				newBalance, newHeld, err := applyEntry(balance, held, entry)
+				if i < len(tt.entries)-1 || tt.wantErr == nil {
+					if err != nil {
+						t.Fatalf("entry %d (%s %.2f): %v", i, entry.Type, entry.Amount, err)
+					}
+					balance, held = newBalance, newHeld
+					continue
+				}
+
+				if err == nil {
+					t.Fatalf("entry %d (%s %.2f) succeeded, want %v", i, entry.Type, entry.Amount, tt.wantErr)
+				}
+				if !errors.Is(err, tt.wantErr) && err.Error() != tt.wantErr.Error() {
+					t.Fatalf("entry %d failed with %v, want %v", i, err, tt.wantErr)
+				}
+				return
+			}
+
+			if balance != tt.wantBalance || held != tt.wantHeld {
+				t.Errorf("balance %.2f held %.2f, want %.2f held %.2f", balance, held, tt.wantBalance, tt.wantHeld)
+			}
+		})
+	}
+}
+
+// The balance of an account is the sum of its entries, so every entry type
+// must move the balance and the held amount in a known direction
+func TestEntryEffects(t *testing.T) {
+	tests := []struct {
+		entryType string
+		balance   float64
+		held      float64
+		spends    bool
+	}{
+		{LedgerInitial, 1, 0, false},
+		{LedgerCredit, 1, 0, false},
+		{LedgerRefund, 1, 0, false},
+		{LedgerDebit, -1, 0, true},
+		{LedgerHold, 0, 1, true},
+		{LedgerCapture, -1, -1, false},
+		{LedgerRelease, 0, -1, false},
+		{LedgerExpiry, -1, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.entryType, func(t *testing.T) {
+			if got := balanceEffect(tt.entryType); got != tt.balance {
+				t.Errorf("balance effect %v, want %v", got, tt.balance)
+			}
+			if got := holdEffect(tt.entryType); got != tt.held {
+				t.Errorf("hold effect %v, want %v", got, tt.held)
+			}
+			if got := spends(tt.entryType); got != tt.spends {
+				t.Errorf("spends %v, want %v", got, tt.spends)
+			}
+		})
+	}
+}
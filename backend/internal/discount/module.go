@@ -3,6 +3,7 @@ package discount
 import (
 	"gorm.io/gorm"
 	"github.com/gin-gonic/gin"
+	"ecommerce-saas/internal/notification"
 )
 
 // Module represents the discount module
@@ -12,10 +13,10 @@ type Module struct {
 	handler    *Handler
 }
 
-// NewModule creates a new discount module instance
-func NewModule(db *gorm.DB) *Module {
+// NewModule creates a new discount module instance. tiers may be nil.
+func NewModule(db *gorm.DB, tiers TierResolver) *Module {
 	repo := NewRepository(db)
-	svc := NewService(repo)
+	svc := NewService(repo, notification.NewService(notification.NewRepository(db)), tiers)
 	handler := NewHandler(svc)
 
 	return &Module{
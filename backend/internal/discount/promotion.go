@@ -0,0 +1,693 @@
+package discount
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// DiscountClass groups discounts by what they reduce, for combinability rules
+type DiscountClass string
+
+const (
+	ClassProduct  DiscountClass = "product"
+	ClassOrder    DiscountClass = "order"
+	ClassShipping DiscountClass = "shipping"
+)
+
+// Reasons a promotion was not applied
+const (
+	RejectCodeNotFound    = "code_not_found"
+	RejectInactive        = "inactive"
+	RejectNotEligible     = "customer_not_eligible"
+	RejectUsageLimit      = "usage_limit_reached"
+	RejectMinimumNotMet   = "minimum_not_met"
+	RejectNoEligibleItems = "no_eligible_items"
+	RejectNotCombinable   = "not_combinable"
+	RejectNoDiscountValue = "no_discount_value"
+	RejectDuplicate       = "duplicate"
+	RejectCodeRedeemed    = "code_redeemed"
+)
+
+// PromotionLine is a cart or order line offered to the promotion engine
+type PromotionLine struct {
+	ID            string   `json:"id"`
+	ProductID     string   `json:"product_id"`
+	VariantID     string   `json:"variant_id,omitempty"`
+	CategoryIDs   []string `json:"category_ids,omitempty"`
+	CollectionIDs []string `json:"collection_ids,omitempty"`
+	Quantity      int      `json:"quantity"`
+	UnitPrice     float64  `json:"unit_price"`
+}
+
+// BenefitLoyaltyTier marks a reduction that comes from the customer's loyalty
+// tier rather than a stored discount
+const BenefitLoyaltyTier = "loyalty_tier"
+
+// LoyaltyTier is the customer's loyalty membership level and the benefits
+// the promotion engine applies for it
+type LoyaltyTier struct {
+	Code         string `json:"code"`
+	Name         string `json:"name"`
+	FreeShipping bool   `json:"free_shipping"`
+}
+
+// TierResolver looks up a customer's loyalty tier; nil means no tier
+type TierResolver interface {
+	CustomerTier(ctx context.Context, tenantID, customerID uuid.UUID) (*LoyaltyTier, error)
+}
+
+// PromotionInput is everything the engine needs besides the candidate discounts
+type PromotionInput struct {
+	CustomerID     *uuid.UUID
+	CustomerEmail  string
+	Lines          []PromotionLine
+	ShippingAmount float64
+	CustomerUsage  map[uuid.UUID]int // Previous uses of each discount by this customer
+	LoyaltyTier    *LoyaltyTier      // Enables tier-only promotions and tier benefits
+}
+
+// LineAllocation is the part of a promotion attributed to one line
+type LineAllocation struct {
+	LineID string  `json:"line_id"`
+	Amount float64 `json:"amount"`
+}
+
+// AppliedPromotion is a promotion the engine accepted
+type AppliedPromotion struct {
+	DiscountID uuid.UUID        `json:"discount_id"`
+	Code       string           `json:"code,omitempty"`
+	Title      string           `json:"title"`
+	Type       DiscountType     `json:"type"`
+	Class      DiscountClass    `json:"class"`
+	Automatic  bool             `json:"automatic"`
+	Priority   int              `json:"priority"`
+	Amount     float64          `json:"amount"`
+	Lines      []LineAllocation `json:"lines,omitempty"`
+	Message    string           `json:"message"`
+	Benefit    string           `json:"benefit,omitempty"` // Set for tier benefits, which have no discount ID
+}
+
+// RejectedPromotion is a promotion the engine skipped, and why
+type RejectedPromotion struct {
+	DiscountID    *uuid.UUID `json:"discount_id,omitempty"`
+	Code          string     `json:"code,omitempty"`
+	Title         string     `json:"title,omitempty"`
+	Automatic     bool       `json:"automatic"`
+	Reason        string     `json:"reason"`
+	Message       string     `json:"message"`
+	ConflictsWith *uuid.UUID `json:"conflicts_with,omitempty"`
+}
+
+// PromotionResult is the outcome of evaluating promotions against a cart
+type PromotionResult struct {
+	Applied          []AppliedPromotion  `json:"applied"`
+	Rejected         []RejectedPromotion `json:"rejected"`
+	Subtotal         float64             `json:"subtotal"`
+	ItemDiscount     float64             `json:"item_discount"` // Product and order discounts
+	ShippingDiscount float64             `json:"shipping_discount"`
+	TotalDiscount    float64             `json:"total_discount"`
+}
+
+// Class reports whether the discount reduces items, the order or shipping
+func (d *Discount) Class() DiscountClass {
+	switch {
+	case d.Type == TypeFreeShipping || d.Target == TargetShipping:
+		return ClassShipping
+	case d.Type == TypeBuyXGetY || d.Type == TypeTiered || d.Type == TypeBundle:
+		return ClassProduct
+	case d.Target == TargetProduct || d.Target == TargetCategory || d.Target == TargetCollection:
+		return ClassProduct
+	default:
+		return ClassOrder
+	}
+}
+
+// IsAutomatic reports whether the discount applies without a code
+func (d *Discount) IsAutomatic() bool {
+	return !d.RequiresCode
+}
+
+// CombinesWith reports whether both discounts may apply to the same order.
+// Exclusive discounts and discounts in the same exclusive group never
+// combine; an explicit StackableWith entry on either side always does;
+// otherwise each side must accept the other's class.
+func (d *Discount) CombinesWith(other *Discount) bool {
+	if d.Exclusive || other.Exclusive {
+		return false
+	}
+	if d.ExclusiveGroup != "" && d.ExclusiveGroup == other.ExclusiveGroup {
+		return false
+	}
+	if containsString(d.StackableWith, other.ID.String()) || containsString(other.StackableWith, d.ID.String()) {
+		return true
+	}
+	return d.combinesWithClass(other.Class()) && other.combinesWithClass(d.Class())
+}
+
+func (d *Discount) combinesWithClass(class DiscountClass) bool {
+	if d.Stackable {
+		return true
+	}
+	switch class {
+	case ClassProduct:
+		return d.CombinesWithProduct
+	case ClassShipping:
+		return d.CombinesWithShipping
+	default:
+		return d.CombinesWithOrder
+	}
+}
+
+// appliesToLine checks the discount's product, category and collection targets
+func (d *Discount) appliesToLine(line PromotionLine) bool {
+	if containsString(d.ExcludeProductIDs, line.ProductID) {
+		return false
+	}
+	if d.Type == TypeBundle {
+		for _, item := range d.BundleItems {
+			if item.ProductID == line.ProductID {
+				return true
+			}
+		}
+		return false
+	}
+	switch d.Target {
+	case TargetProduct:
+		return containsString(d.TargetProductIDs, line.ProductID)
+	case TargetCategory:
+		return intersects(d.TargetCategoryIDs, line.CategoryIDs)
+	case TargetCollection:
+		return intersects(d.TargetCollectionIDs, line.CollectionIDs)
+	default:
+		return true
+	}
+}
+
+// isRewardLine checks whether a line can be a Buy X Get Y reward. Without
+// reward targets the qualifying items are the rewards.
+func (d *Discount) isRewardLine(line PromotionLine) bool {
+	if len(d.RewardProductIDs) == 0 && len(d.RewardCategoryIDs) == 0 && len(d.RewardCollectionIDs) == 0 {
+		return d.appliesToLine(line)
+	}
+	if containsString(d.ExcludeProductIDs, line.ProductID) {
+		return false
+	}
+	return containsString(d.RewardProductIDs, line.ProductID) ||
+		intersects(d.RewardCategoryIDs, line.CategoryIDs) ||
+		intersects(d.RewardCollectionIDs, line.CollectionIDs)
+}
+
+// EvaluatePromotions applies candidate discounts to the lines in a fixed
+// order: priority (highest first), then product, order and shipping
+// discounts, then oldest first. Each discount works on the amount left by
+// the ones before it, so the same input always gives the same result.
+func EvaluatePromotions(candidates []Discount, input PromotionInput) *PromotionResult {
+	ordered := make([]*Discount, 0, len(candidates))
+	for i := range candidates {
+		ordered = append(ordered, &candidates[i])
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if classRank(a.Class()) != classRank(b.Class()) {
+			return classRank(a.Class()) < classRank(b.Class())
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID.String() < b.ID.String()
+	})
+
+	result := &PromotionResult{Applied: []AppliedPromotion{}, Rejected: []RejectedPromotion{}}
+	remaining := make(map[string]float64, len(input.Lines))
+	for _, line := range input.Lines {
+		amount := roundCents(line.UnitPrice * float64(line.Quantity))
+		remaining[line.ID] = amount
+		result.Subtotal += amount
+	}
+	result.Subtotal = roundCents(result.Subtotal)
+	shippingRemaining := input.ShippingAmount
+
+	var accepted []*Discount
+	seen := make(map[uuid.UUID]bool, len(ordered))
+
+	for _, d := range ordered {
+		reject := func(reason, format string, args ...interface{}) {
+			id := d.ID
+			result.Rejected = append(result.Rejected, RejectedPromotion{
+				DiscountID: &id,
+				Code:       d.Code,
+				Title:      d.Title,
+				Automatic:  d.IsAutomatic(),
+				Reason:     reason,
+				Message:    fmt.Sprintf(format, args...),
+			})
+		}
+
+		if seen[d.ID] {
+			reject(RejectDuplicate, "%s is already applied", d.Title)
+			continue
+		}
+		seen[d.ID] = true
+
+		if reason, message := checkEligibility(d, input); reason != "" {
+			reject(reason, "%s", message)
+			continue
+		}
+
+		// Eligible lines and minimum requirements
+		var eligible []PromotionLine
+		eligibleAmount, eligibleQuantity := 0.0, 0
+		for _, line := range input.Lines {
+			if d.appliesToLine(line) {
+				eligible = append(eligible, line)
+				eligibleAmount += line.UnitPrice * float64(line.Quantity)
+				eligibleQuantity += line.Quantity
+			}
+		}
+		if d.Class() != ClassShipping && len(eligible) == 0 {
+			reject(RejectNoEligibleItems, "No items in the cart qualify for %s", d.Title)
+			continue
+		}
+		if d.MinOrderAmount != nil && eligibleAmount < *d.MinOrderAmount {
+			reject(RejectMinimumNotMet, "Spend %.2f more on qualifying items to get %s", *d.MinOrderAmount-eligibleAmount, d.Title)
+			continue
+		}
+		if d.MinItemQuantity != nil && eligibleQuantity < *d.MinItemQuantity {
+			reject(RejectMinimumNotMet, "Add %d more qualifying items to get %s", *d.MinItemQuantity-eligibleQuantity, d.Title)
+			continue
+		}
+
+		// Combinability with everything accepted so far
+		var conflict *Discount
+		for _, other := range accepted {
+			if !d.CombinesWith(other) {
+				conflict = other
+				break
+			}
+		}
+		if conflict != nil {
+			conflictID := conflict.ID
+			id := d.ID
+			result.Rejected = append(result.Rejected, RejectedPromotion{
+				DiscountID:    &id,
+				Code:          d.Code,
+				Title:         d.Title,
+				Automatic:     d.IsAutomatic(),
+				Reason:        RejectNotCombinable,
+				Message:       fmt.Sprintf("%s cannot be combined with %s", d.Title, conflict.Title),
+				ConflictsWith: &conflictID,
+			})
+			continue
+		}
+
+		applied := AppliedPromotion{
+			DiscountID: d.ID,
+			Code:       d.Code,
+			Title:      d.Title,
+			Type:       d.Type,
+			Class:      d.Class(),
+			Automatic:  d.IsAutomatic(),
+			Priority:   d.Priority,
+		}
+
+		if d.Class() == ClassShipping {
+			applied.Amount = shippingDiscount(d, shippingRemaining)
+			applied.Message = fmt.Sprintf("%s applied to shipping", d.Title)
+		} else {
+			applied.Lines = allocateLineDiscount(d, eligible, input.Lines, remaining)
+			for _, allocation := range applied.Lines {
+				applied.Amount += allocation.Amount
+			}
+			applied.Amount = roundCents(applied.Amount)
+			applied.Message = fmt.Sprintf("%s applied to %d item(s)", d.Title, len(applied.Lines))
+		}
+
+		if applied.Amount <= 0 {
+			reject(RejectNoDiscountValue, "%s does not reduce the price of this order", d.Title)
+			continue
+		}
+
+		if applied.Class == ClassShipping {
+			shippingRemaining = roundCents(shippingRemaining - applied.Amount)
+			result.ShippingDiscount += applied.Amount
+		} else {
+			for _, allocation := range applied.Lines {
+				remaining[allocation.LineID] = roundCents(remaining[allocation.LineID] - allocation.Amount)
+			}
+			result.ItemDiscount += applied.Amount
+		}
+
+		accepted = append(accepted, d)
+		result.Applied = append(result.Applied, applied)
+	}
+
+	// Tier benefits are membership perks; they apply on top of any promotion
+	if tier := input.LoyaltyTier; tier != nil && tier.FreeShipping && shippingRemaining > 0 {
+		amount := roundCents(shippingRemaining)
+		result.Applied = append(result.Applied, AppliedPromotion{
+			Title:     fmt.Sprintf("%s free shipping", tier.Name),
+			Type:      TypeFreeShipping,
+			Class:     ClassShipping,
+			Automatic: true,
+			Amount:    amount,
+			Message:   fmt.Sprintf("Free shipping for %s members", tier.Name),
+			Benefit:   BenefitLoyaltyTier,
+		})
+		result.ShippingDiscount += amount
+	}
+
+	result.ItemDiscount = roundCents(result.ItemDiscount)
+	result.ShippingDiscount = roundCents(result.ShippingDiscount)
+	result.TotalDiscount = roundCents(result.ItemDiscount + result.ShippingDiscount)
+	return result
+}
+
+// checkEligibility returns a rejection reason and message, or empty strings
+func checkEligibility(d *Discount, input PromotionInput) (string, string) {
+	if d.UsageLimit != nil && d.UsageCount >= *d.UsageLimit {
+		return RejectUsageLimit, fmt.Sprintf("%s has reached its usage limit", d.Title)
+	}
+	if !d.IsActive() {
+		return RejectInactive, fmt.Sprintf("%s is not active", d.Title)
+	}
+	used := input.CustomerUsage[d.ID]
+	if d.CustomerUsageLimit != nil && used >= *d.CustomerUsageLimit {
+		return RejectUsageLimit, fmt.Sprintf("You have already used %s the maximum number of times", d.Title)
+	}
+	if !d.CanUseDiscount(input.CustomerID, input.CustomerEmail, used) {
+		return RejectNotEligible, fmt.Sprintf("You are not eligible for %s", d.Title)
+	}
+	if !d.AllowsTier(input.LoyaltyTier) {
+		return RejectNotEligible, fmt.Sprintf("%s is only available to loyalty members of certain tiers", d.Title)
+	}
+	return "", ""
+}
+
+// AllowsTier reports whether a customer with the given tier (nil for none)
+// may use the discount
+func (d *Discount) AllowsTier(tier *LoyaltyTier) bool {
+	if len(d.EligibleLoyaltyTiers) == 0 {
+		return true
+	}
+	return tier != nil && containsString(d.EligibleLoyaltyTiers, tier.Code)
+}
+
+// allocateLineDiscount computes the discount on each eligible line from the
+// amount still left on it. Fixed amounts on product discounts apply per unit
+// unless ApplyOnce is set; otherwise they are spread across lines by value.
+func allocateLineDiscount(d *Discount, lines, all []PromotionLine, remaining map[string]float64) []LineAllocation {
+	var allocations []LineAllocation
+
+	switch d.Type {
+	case TypeBuyXGetY:
+		return allocateBuyXGetY(d, lines, all, remaining)
+
+	case TypeTiered:
+		return allocateTiered(d, lines, remaining)
+
+	case TypeBundle:
+		return allocateBundle(d, lines, remaining)
+
+	case TypePercentage:
+		for _, line := range lines {
+			amount := roundCents(remaining[line.ID] * d.Value / 100)
+			if amount > 0 {
+				allocations = append(allocations, LineAllocation{LineID: line.ID, Amount: amount})
+			}
+		}
+
+	case TypeFixed:
+		if d.Class() == ClassProduct && !d.ApplyOnce {
+			for _, line := range lines {
+				amount := roundCents(math.Min(d.Value*float64(line.Quantity), remaining[line.ID]))
+				if amount > 0 {
+					allocations = append(allocations, LineAllocation{LineID: line.ID, Amount: amount})
+				}
+			}
+			break
+		}
+
+		base := 0.0
+		for _, line := range lines {
+			base += remaining[line.ID]
+		}
+		total := roundCents(math.Min(d.Value, base))
+		if total <= 0 {
+			break
+		}
+		last := 0
+		for i, line := range lines {
+			if remaining[line.ID] > 0 {
+				last = i
+			}
+		}
+		allocated := 0.0
+		for i, line := range lines {
+			if remaining[line.ID] <= 0 {
+				continue
+			}
+			amount := roundCents(total * remaining[line.ID] / base)
+			if i == last {
+				amount = roundCents(total - allocated)
+			}
+			allocated += amount
+			allocations = append(allocations, LineAllocation{LineID: line.ID, Amount: amount})
+		}
+	}
+
+	return allocations
+}
+
+// unit is one item of a line, priced at what is left on the line
+type unit struct {
+	lineID string
+	price  float64
+}
+
+// expandUnits splits lines into single units
+func expandUnits(lines []PromotionLine, remaining map[string]float64) []unit {
+	var units []unit
+	for _, line := range lines {
+		if line.Quantity <= 0 {
+			continue
+		}
+		price := remaining[line.ID] / float64(line.Quantity)
+		for i := 0; i < line.Quantity; i++ {
+			units = append(units, unit{lineID: line.ID, price: price})
+		}
+	}
+	return units
+}
+
+// allocateBuyXGetY rewards the cheapest reward units while enough other
+// qualifying units remain to pay for them: every BuyQuantity qualifying units
+// earn GetQuantity rewards at GetValue percent off (free when unset). A unit
+// can qualify or be rewarded, never both, so "buy 2 get 1" on one product
+// makes every third unit free.
+func allocateBuyXGetY(d *Discount, qualifying, all []PromotionLine, remaining map[string]float64) []LineAllocation {
+	if d.BuyQuantity == nil || d.GetQuantity == nil || *d.BuyQuantity <= 0 || *d.GetQuantity <= 0 {
+		return nil
+	}
+	percent := 100.0
+	if d.GetValue != nil {
+		percent = *d.GetValue
+	}
+
+	qualifyingUnits := 0
+	qualifies := make(map[string]bool, len(qualifying))
+	for _, line := range qualifying {
+		qualifies[line.ID] = true
+		qualifyingUnits += line.Quantity
+	}
+
+	var rewardLines []PromotionLine
+	for _, line := range all {
+		if d.isRewardLine(line) {
+			rewardLines = append(rewardLines, line)
+		}
+	}
+	candidates := expandUnits(rewardLines, remaining)
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].price < candidates[j].price })
+
+	maxRewards := -1
+	if d.ApplyOnce {
+		maxRewards = *d.GetQuantity
+	}
+
+	amounts := make(map[string]float64)
+	rewarded, rewardedQualifying := 0, 0
+	for _, candidate := range candidates {
+		if maxRewards >= 0 && rewarded >= maxRewards {
+			break
+		}
+		// Qualifying units left to pay if this candidate becomes a reward
+		payers := qualifyingUnits - rewardedQualifying
+		if qualifies[candidate.lineID] {
+			payers--
+		}
+		if rewarded+1 > (payers / *d.BuyQuantity)**d.GetQuantity {
+			continue
+		}
+		rewarded++
+		if qualifies[candidate.lineID] {
+			rewardedQualifying++
+		}
+		amounts[candidate.lineID] += candidate.price * percent / 100
+	}
+
+	return allocationsFor(all, amounts)
+}
+
+// allocateTiered applies the highest tier reached by the eligible quantity
+func allocateTiered(d *Discount, lines []PromotionLine, remaining map[string]float64) []LineAllocation {
+	quantity := 0
+	for _, line := range lines {
+		quantity += line.Quantity
+	}
+
+	var tier *DiscountTier
+	for i := range d.Tiers {
+		if d.Tiers[i].MinQuantity <= quantity && (tier == nil || d.Tiers[i].MinQuantity > tier.MinQuantity) {
+			tier = &d.Tiers[i]
+		}
+	}
+	if tier == nil {
+		return nil
+	}
+
+	amounts := make(map[string]float64)
+	for _, line := range lines {
+		if tier.Percentage > 0 {
+			amounts[line.ID] = remaining[line.ID] * tier.Percentage / 100
+			continue
+		}
+		tierTotal := tier.UnitPrice * float64(line.Quantity)
+		if remaining[line.ID] > tierTotal {
+			amounts[line.ID] = remaining[line.ID] - tierTotal
+		}
+	}
+
+	return allocationsFor(lines, amounts)
+}
+
+// allocateBundle prices each complete set of bundle items at the bundle
+// price (Value), spreading the saving over the units in the set by price
+func allocateBundle(d *Discount, lines []PromotionLine, remaining map[string]float64) []LineAllocation {
+	byProduct := make(map[string][]unit)
+	for _, u := range expandUnits(lines, remaining) {
+		for _, line := range lines {
+			if line.ID == u.lineID {
+				byProduct[line.ProductID] = append(byProduct[line.ProductID], u)
+				break
+			}
+		}
+	}
+
+	// Most expensive units go into bundles first so the saving is largest
+	sets := -1
+	for _, item := range d.BundleItems {
+		if item.Quantity <= 0 {
+			return nil
+		}
+		units := byProduct[item.ProductID]
+		sort.SliceStable(units, func(i, j int) bool { return units[i].price > units[j].price })
+		if count := len(units) / item.Quantity; sets < 0 || count < sets {
+			sets = count
+		}
+	}
+	if d.ApplyOnce && sets > 1 {
+		sets = 1
+	}
+	if sets <= 0 {
+		return nil
+	}
+
+	amounts := make(map[string]float64)
+	for set := 0; set < sets; set++ {
+		var members []unit
+		regular := 0.0
+		for _, item := range d.BundleItems {
+			units := byProduct[item.ProductID][set*item.Quantity : (set+1)*item.Quantity]
+			for _, u := range units {
+				members = append(members, u)
+				regular += u.price
+			}
+		}
+		saving := regular - d.Value
+		if saving <= 0 || regular <= 0 {
+			continue
+		}
+		for _, u := range members {
+			amounts[u.lineID] += saving * u.price / regular
+		}
+	}
+
+	return allocationsFor(lines, amounts)
+}
+
+// allocationsFor rounds per-line amounts into allocations in line order
+func allocationsFor(lines []PromotionLine, amounts map[string]float64) []LineAllocation {
+	var allocations []LineAllocation
+	for _, line := range lines {
+		if amount := roundCents(amounts[line.ID]); amount > 0 {
+			allocations = append(allocations, LineAllocation{LineID: line.ID, Amount: amount})
+		}
+	}
+	return allocations
+}
+
+// shippingDiscount computes the reduction on the shipping still charged
+func shippingDiscount(d *Discount, shipping float64) float64 {
+	if shipping <= 0 {
+		return 0
+	}
+	switch d.Type {
+	case TypeFreeShipping:
+		return roundCents(shipping)
+	case TypePercentage:
+		return roundCents(shipping * d.Value / 100)
+	case TypeFixed:
+		return roundCents(math.Min(d.Value, shipping))
+	}
+	return 0
+}
+
+func classRank(class DiscountClass) int {
+	switch class {
+	case ClassProduct:
+		return 0
+	case ClassOrder:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func intersects(a, b []string) bool {
+	for _, value := range b {
+		if containsString(a, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
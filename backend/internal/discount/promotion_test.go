@@ -0,0 +1,411 @@
+package discount
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var promotionEpoch = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// promotion returns an active discount identified by n, created n hours
+// after the epoch so tests control the oldest-first tie-break
+func promotion(n byte, title string, discountType DiscountType, value float64) Discount {
+	return Discount{
+		ID:        uuid.UUID{15: n},
+		Title:     title,
+		Type:      discountType,
+		Status:    StatusActive,
+		Value:     value,
+		Target:    TargetOrder,
+		CreatedAt: promotionEpoch.Add(time.Duration(n) * time.Hour),
+	}
+}
+
+func intPtr(v int) *int           { return &v }
+func floatPtr(v float64) *float64 { return &v }
+func line(id, productID string, quantity int, unitPrice float64) PromotionLine {
+	return PromotionLine{ID: id, ProductID: productID, Quantity: quantity, UnitPrice: unitPrice}
+}
+
+func TestEvaluatePromotions(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates func() []Discount
+		input      PromotionInput
+		applied    []string           // titles in application order
+		amounts    []float64          // amount of each applied promotion
+		rejected   map[string]string  // reason by title
+		lines      map[string]float64 // total allocated to each line, when checked
+		total      float64
+	}{
+		{
+			name: "higher priority applies first whatever its class",
+			candidates: func() []Discount {
+				order := promotion(1, "ten off", TypeFixed, 10)
+				order.Priority = 5
+				order.Stackable = true
+				product := promotion(2, "ten percent", TypePercentage, 10)
+				product.Target = TargetProduct
+				product.TargetProductIDs = []string{"p1"}
+				product.Stackable = true
+				return []Discount{product, order}
+			},
+			input:   PromotionInput{Lines: []PromotionLine{line("l1", "p1", 1, 100)}},
+			applied: []string{"ten off", "ten percent"},
+			amounts: []float64{10, 9},
+			lines:   map[string]float64{"l1": 19},
+			total:   19,
+		},
+		{
+			name: "product before order discounts, then oldest first",
+			candidates: func() []Discount {
+				late := promotion(3, "order one off", TypeFixed, 1)
+				late.Stackable = true
+				early := promotion(1, "order ten percent", TypePercentage, 10)
+				early.Stackable = true
+				product := promotion(2, "product five off", TypeFixed, 5)
+				product.Target = TargetProduct
+				product.TargetProductIDs = []string{"p1"}
+				product.Stackable = true
+				return []Discount{late, early, product}
+			},
+			input:   PromotionInput{Lines: []PromotionLine{line("l1", "p1", 2, 50)}},
+			applied: []string{"product five off", "order ten percent", "order one off"},
+			amounts: []float64{10, 9, 1},
+			total:   20,
+		},
+		{
+			name: "discounts that don't combine keep the first",
+			candidates: func() []Discount {
+				return []Discount{
+					promotion(1, "first", TypePercentage, 10),
+					promotion(2, "second", TypeFixed, 5),
+				}
+			},
+			input:    PromotionInput{Lines: []PromotionLine{line("l1", "p1", 1, 100)}},
+			applied:  []string{"first"},
+			amounts:  []float64{10},
+			rejected: map[string]string{"second": RejectNotCombinable},
+			total:    10,
+		},
+		{
+			name: "class combinability must be accepted by both sides",
+			candidates: func() []Discount {
+				product := promotion(1, "product", TypePercentage, 10)
+				product.Target = TargetProduct
+				product.TargetProductIDs = []string{"p1"}
+				product.CombinesWithOrder = true
+				return []Discount{product, promotion(2, "order", TypeFixed, 5)}
+			},
+			input:    PromotionInput{Lines: []PromotionLine{line("l1", "p1", 1, 100)}},
+			applied:  []string{"product"},
+			amounts:  []float64{10},
+			rejected: map[string]string{"order": RejectNotCombinable},
+			total:    10,
+		},
+		{
+			name: "classes combine when both sides accept each other",
+			candidates: func() []Discount {
+				product := promotion(1, "product", TypePercentage, 10)
+				product.Target = TargetProduct
+				product.TargetProductIDs = []string{"p1"}
+				product.CombinesWithOrder = true
+				order := promotion(2, "order", TypeFixed, 5)
+				order.CombinesWithProduct = true
+				return []Discount{product, order}
+			},
+			input:   PromotionInput{Lines: []PromotionLine{line("l1", "p1", 1, 100)}},
+			applied: []string{"product", "order"},
+			amounts: []float64{10, 5},
+			total:   15,
+		},
+		{
+			name: "an exclusive group overrides stackable",
+			candidates: func() []Discount {
+				a := promotion(1, "summer a", TypePercentage, 10)
+				b := promotion(2, "summer b", TypeFixed, 5)
+				a.Stackable, b.Stackable = true, true
+				a.ExclusiveGroup, b.ExclusiveGroup = "summer", "summer"
+				return []Discount{a, b}
+			},
+			input:    PromotionInput{Lines: []PromotionLine{line("l1", "p1", 1, 100)}},
+			applied:  []string{"summer a"},
+			amounts:  []float64{10},
+			rejected: map[string]string{"summer b": RejectNotCombinable},
+			total:    10,
+		},
+		{
+			name: "an explicit stackable entry combines otherwise incompatible discounts",
+			candidates: func() []Discount {
+				a := promotion(1, "a", TypePercentage, 10)
+				b := promotion(2, "b", TypeFixed, 5)
+				b.StackableWith = []string{a.ID.String()}
+				return []Discount{a, b}
+			},
+			input:   PromotionInput{Lines: []PromotionLine{line("l1", "p1", 1, 100)}},
+			applied: []string{"a", "b"},
+			amounts: []float64{10, 5},
+			total:   15,
+		},
+		{
+			name: "exclusive discounts apply alone",
+			candidates: func() []Discount {
+				exclusive := promotion(1, "exclusive", TypePercentage, 20)
+				exclusive.Exclusive = true
+				stackable := promotion(2, "stackable", TypeFixed, 5)
+				stackable.Stackable = true
+				return []Discount{exclusive, stackable}
+			},
+			input:    PromotionInput{Lines: []PromotionLine{line("l1", "p1", 1, 100)}},
+			applied:  []string{"exclusive"},
+			amounts:  []float64{20},
+			rejected: map[string]string{"stackable": RejectNotCombinable},
+			total:    20,
+		},
+		{
+			name: "minimums are checked on qualifying items",
+			candidates: func() []Discount {
+				d := promotion(1, "big spender", TypePercentage, 10)
+				d.MinOrderAmount = floatPtr(200)
+				return []Discount{d}
+			},
+			input:    PromotionInput{Lines: []PromotionLine{line("l1", "p1", 1, 100)}},
+			rejected: map[string]string{"big spender": RejectMinimumNotMet},
+		},
+		{
+			name: "buy 2 get 1 on one product rewards every third unit",
+			candidates: func() []Discount {
+				d := promotion(1, "b2g1", TypeBuyXGetY, 0)
+				d.Target = TargetProduct
+				d.TargetProductIDs = []string{"p1"}
+				d.BuyQuantity, d.GetQuantity = intPtr(2), intPtr(1)
+				return []Discount{d}
+			},
+			input:   PromotionInput{Lines: []PromotionLine{line("l1", "p1", 7, 10)}},
+			applied: []string{"b2g1"},
+			amounts: []float64{20},
+			total:   20,
+		},
+		{
+			name: "buy x get y rewards the cheapest reward item at the reward percentage",
+			candidates: func() []Discount {
+				d := promotion(1, "half off accessories", TypeBuyXGetY, 0)
+				d.Target = TargetProduct
+				d.TargetProductIDs = []string{"shoes"}
+				d.RewardProductIDs = []string{"socks", "laces"}
+				d.BuyQuantity, d.GetQuantity = intPtr(2), intPtr(1)
+				d.GetValue = floatPtr(50)
+				return []Discount{d}
+			},
+			input: PromotionInput{Lines: []PromotionLine{
+				line("l1", "shoes", 2, 20),
+				line("l2", "socks", 2, 8),
+				line("l3", "laces", 1, 2),
+			}},
+			applied: []string{"half off accessories"},
+			amounts: []float64{1},
+			lines:   map[string]float64{"l3": 1},
+			total:   1,
+		},
+		{
+			name: "buy x get y applied once rewards one set",
+			candidates: func() []Discount {
+				d := promotion(1, "b2g1 once", TypeBuyXGetY, 0)
+				d.BuyQuantity, d.GetQuantity = intPtr(2), intPtr(1)
+				d.ApplyOnce = true
+				return []Discount{d}
+			},
+			input:   PromotionInput{Lines: []PromotionLine{line("l1", "p1", 6, 10)}},
+			applied: []string{"b2g1 once"},
+			amounts: []float64{10},
+			total:   10,
+		},
+		{
+			name: "tiered pricing uses the highest tier reached across lines",
+			candidates: func() []Discount {
+				d := promotion(1, "volume", TypeTiered, 0)
+				d.Tiers = []DiscountTier{{MinQuantity: 2, Percentage: 10}, {MinQuantity: 5, Percentage: 20}}
+				return []Discount{d}
+			},
+			input: PromotionInput{Lines: []PromotionLine{
+				line("l1", "p1", 3, 10),
+				line("l2", "p2", 2, 5),
+			}},
+			applied: []string{"volume"},
+			amounts: []float64{8},
+			lines:   map[string]float64{"l1": 6, "l2": 2},
+			total:   8,
+		},
+		{
+			name: "tiered unit prices replace the line price",
+			candidates: func() []Discount {
+				d := promotion(1, "four for 32", TypeTiered, 0)
+				d.Tiers = []DiscountTier{{MinQuantity: 3, UnitPrice: 8}}
+				return []Discount{d}
+			},
+			input:   PromotionInput{Lines: []PromotionLine{line("l1", "p1", 4, 10)}},
+			applied: []string{"four for 32"},
+			amounts: []float64{8},
+			total:   8,
+		},
+		{
+			name: "tiered pricing below the first tier gives nothing",
+			candidates: func() []Discount {
+				d := promotion(1, "volume", TypeTiered, 0)
+				d.Tiers = []DiscountTier{{MinQuantity: 2, Percentage: 10}}
+				return []Discount{d}
+			},
+			input:    PromotionInput{Lines: []PromotionLine{line("l1", "p1", 1, 10)}},
+			rejected: map[string]string{"volume": RejectNoDiscountValue},
+		},
+		{
+			name: "bundle saving is spread over its items by price",
+			candidates: func() []Discount {
+				d := promotion(1, "bundle", TypeBundle, 25)
+				d.BundleItems = []BundleItem{{ProductID: "p1", Quantity: 1}, {ProductID: "p2", Quantity: 1}}
+				return []Discount{d}
+			},
+			input: PromotionInput{Lines: []PromotionLine{
+				line("l1", "p1", 1, 20),
+				line("l2", "p2", 1, 10),
+				line("l3", "p3", 1, 50),
+			}},
+			applied: []string{"bundle"},
+			amounts: []float64{5},
+			lines:   map[string]float64{"l1": 3.33, "l2": 1.67},
+			total:   5,
+		},
+		{
+			name: "bundle counts complete sets only",
+			candidates: func() []Discount {
+				d := promotion(1, "bundle", TypeBundle, 25)
+				d.BundleItems = []BundleItem{{ProductID: "p1", Quantity: 1}, {ProductID: "p2", Quantity: 1}}
+				return []Discount{d}
+			},
+			input: PromotionInput{Lines: []PromotionLine{
+				line("l1", "p1", 3, 20),
+				line("l2", "p2", 2, 10),
+			}},
+			applied: []string{"bundle"},
+			amounts: []float64{10},
+			lines:   map[string]float64{"l1": 6.67, "l2": 3.33},
+			total:   10,
+		},
+		{
+			name: "bundle applied once prices one set",
+			candidates: func() []Discount {
+				d := promotion(1, "bundle once", TypeBundle, 25)
+				d.BundleItems = []BundleItem{{ProductID: "p1", Quantity: 1}, {ProductID: "p2", Quantity: 1}}
+				d.ApplyOnce = true
+				return []Discount{d}
+			},
+			input: PromotionInput{Lines: []PromotionLine{
+				line("l1", "p1", 2, 20),
+				line("l2", "p2", 2, 10),
+			}},
+			applied: []string{"bundle once"},
+			amounts: []float64{5},
+			total:   5,
+		},
+		{
+			name: "incomplete bundles give nothing",
+			candidates: func() []Discount {
+				d := promotion(1, "bundle", TypeBundle, 25)
+				d.BundleItems = []BundleItem{{ProductID: "p1", Quantity: 1}, {ProductID: "p2", Quantity: 1}}
+				return []Discount{d}
+			},
+			input:    PromotionInput{Lines: []PromotionLine{line("l1", "p1", 2, 20)}},
+			rejected: map[string]string{"bundle": RejectNoDiscountValue},
+		},
+		{
+			name: "tier free shipping covers the shipping left by promotions",
+			candidates: func() []Discount {
+				d := promotion(1, "half shipping", TypePercentage, 50)
+				d.Target = TargetShipping
+				return []Discount{d}
+			},
+			input: PromotionInput{
+				Lines:          []PromotionLine{line("l1", "p1", 1, 100)},
+				ShippingAmount: 60,
+				LoyaltyTier:    &LoyaltyTier{Code: "gold", Name: "Gold", FreeShipping: true},
+			},
+			applied: []string{"half shipping", "Gold free shipping"},
+			amounts: []float64{30, 30},
+			total:   60,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := EvaluatePromotions(tt.candidates(), tt.input)
+
+			if len(result.Applied) != len(tt.applied) {
+				t.Fatalf("applied %d promotions, want %d: %+v", len(result.Applied), len(tt.applied), result.Applied)
+			}
+			lines := make(map[string]float64)
+			for i, applied := range result.Applied {
+				if applied.Title != tt.applied[i] {
+					t.Errorf("applied[%d] = %q, want %q", i, applied.Title, tt.applied[i])
+				}
+				if applied.Amount != tt.amounts[i] {
+					t.Errorf("%s amount = %.2f, want %.2f", applied.Title, applied.Amount, tt.amounts[i])
+				}
+				for _, allocation := range applied.Lines {
+					lines[allocation.LineID] = roundCents(lines[allocation.LineID] + allocation.Amount)
+				}
+			}
+
+			if len(result.Rejected) != len(tt.rejected) {
+				t.Errorf("rejected %d promotions, want %d: %+v", len(result.Rejected), len(tt.rejected), result.Rejected)
+			}
+			for _, rejected := range result.Rejected {
+				if want, ok := tt.rejected[rejected.Title]; !ok || rejected.Reason != want {
+					t.Errorf("%s rejected with %q, want %q", rejected.Title, rejected.Reason, want)
+				}
+			}
+
+			if tt.lines != nil {
+				for lineID, want := range tt.lines {
+					if lines[lineID] != want {
+						t.Errorf("line %s allocated %.2f, want %.2f", lineID, lines[lineID], want)
+					}
+				}
+				if len(lines) != len(tt.lines) {
+					t.Errorf("allocated to lines %v, want %v", lines, tt.lines)
+				}
+			}
+
+			if result.TotalDiscount != tt.total {
+				t.Errorf("total discount = %.2f, want %.2f", result.TotalDiscount, tt.total)
+			}
+		})
+	}
+}
+
+func TestEvaluatePromotionsRejectsDuplicates(t *testing.T) {
+	d := promotion(1, "twice", TypePercentage, 10)
+	d.Stackable = true
+	result := EvaluatePromotions([]Discount{d, d}, PromotionInput{Lines: []PromotionLine{line("l1", "p1", 1, 100)}})
+
+	if len(result.Applied) != 1 || result.TotalDiscount != 10 {
+		t.Fatalf("applied %+v, want the discount once", result.Applied)
+	}
+	if len(result.Rejected) != 1 || result.Rejected[0].Reason != RejectDuplicate {
+		t.Fatalf("rejected %+v, want one duplicate", result.Rejected)
+	}
+}
+
+func TestEvaluatePromotionsReportsConflict(t *testing.T) {
+	first := promotion(1, "first", TypePercentage, 10)
+	second := promotion(2, "second", TypeFixed, 5)
+	result := EvaluatePromotions([]Discount{second, first}, PromotionInput{Lines: []PromotionLine{line("l1", "p1", 1, 100)}})
+
+	if len(result.Rejected) != 1 {
+		t.Fatalf("rejected %+v, want one", result.Rejected)
+	}
+	if conflict := result.Rejected[0].ConflictsWith; conflict == nil || *conflict != first.ID {
+		t.Errorf("conflicts with %v, want %s", conflict, first.ID)
+	}
+}
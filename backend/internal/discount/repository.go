@@ -18,6 +18,7 @@ type Repository interface {
 	GetDiscounts(ctx context.Context, tenantID uuid.UUID, filter DiscountFilter) ([]Discount, error)
 	UpdateDiscount(ctx context.Context, tenantID, discountID uuid.UUID, updates map[string]interface{}) error
 	DeleteDiscount(ctx context.Context, tenantID, discountID uuid.UUID) error
+	GetAutomaticDiscounts(ctx context.Context, tenantID uuid.UUID) ([]Discount, error)
 	IncrementUsageCount(ctx context.Context, tenantID, discountID uuid.UUID) error
 	DecrementUsageCount(ctx context.Context, tenantID, discountID uuid.UUID) error
 	
@@ -29,6 +30,15 @@ type Repository interface {
 	GetCustomerDiscountUsageCount(ctx context.Context, tenantID uuid.UUID, customerEmail string, discountID uuid.UUID) (int, error)
 	DeleteDiscountUsage(ctx context.Context, tenantID, usageID uuid.UUID) error
 	
+	// Code pool operations
+	CreateDiscountCodes(ctx context.Context, batch *DiscountCodeBatch, codes []DiscountCode) error
+	GetCodeBatchByReference(ctx context.Context, tenantID uuid.UUID, reference string) (*DiscountCodeBatch, error)
+	FindExistingCodes(ctx context.Context, codes []string) ([]string, error)
+	GetDiscountCodes(ctx context.Context, tenantID, discountID uuid.UUID, filter DiscountCodeFilter) ([]DiscountCode, error)
+	GetDiscountCodeByCode(ctx context.Context, tenantID uuid.UUID, code string) (*DiscountCode, error)
+	UpdateDiscountCodeStatus(ctx context.Context, tenantID, discountID, codeID uuid.UUID, from, to DiscountCodeStatus) (bool, error)
+	RedeemDiscountCode(ctx context.Context, tenantID, codeID uuid.UUID, redemption DiscountCodeRedemption) (bool, error)
+	
 	// Gift card operations
 	CreateGiftCard(ctx context.Context, giftCard *GiftCard) error
 	GetGiftCardByID(ctx context.Context, tenantID, giftCardID uuid.UUID) (*GiftCard, error)
@@ -41,6 +51,18 @@ type Repository interface {
 	CreateGiftCardTransaction(ctx context.Context, transaction *GiftCardTransaction) error
 	GetGiftCardTransactions(ctx context.Context, tenantID, giftCardID uuid.UUID) ([]GiftCardTransaction, error)
 	
+	// Ledger operations
+	Ledger() *Ledger
+	GetHolds(ctx context.Context, tenantID uuid.UUID, filter HoldFilter) ([]BalanceHold, error)
+	
+	// Gift card purchase operations
+	GetGiftCardPurchases(ctx context.Context, tenantID uuid.UUID, filter GiftCardPurchaseFilter) ([]GiftCardPurchase, error)
+	GetGiftCardPurchase(ctx context.Context, tenantID, purchaseID uuid.UUID) (*GiftCardPurchase, error)
+	GetDueGiftCardPurchases(ctx context.Context, tenantID uuid.UUID, now time.Time, limit int) ([]GiftCardPurchase, error)
+	ClaimGiftCardDelivery(ctx context.Context, tenantID, purchaseID uuid.UUID, now time.Time) (bool, error)
+	UpdateGiftCardPurchase(ctx context.Context, tenantID, purchaseID uuid.UUID, updates map[string]interface{}) error
+	CancelGiftCardPurchases(ctx context.Context, tenantID, orderID uuid.UUID) (int64, error)
+	
 	// Store credit operations
 	CreateStoreCredit(ctx context.Context, storeCredit *StoreCredit) error
 	GetStoreCredit(ctx context.Context, tenantID, customerID uuid.UUID) (*StoreCredit, error)
@@ -89,6 +111,18 @@ func (r *repository) GetDiscountByCode(ctx context.Context, tenantID uuid.UUID,
 	return &discount, err
 }
 
+// GetAutomaticDiscounts returns codeless promotions that are live right now
+func (r *repository) GetAutomaticDiscounts(ctx context.Context, tenantID uuid.UUID) ([]Discount, error) {
+	var discounts []Discount
+	now := time.Now()
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND requires_code = ? AND status = ?", tenantID, false, StatusActive).
+		Where("(starts_at IS NULL OR starts_at <= ?) AND (expires_at IS NULL OR expires_at >= ?)", now, now).
+		Order("priority DESC, created_at ASC").
+		Find(&discounts).Error
+	return discounts, err
+}
+
 func (r *repository) GetDiscounts(ctx context.Context, tenantID uuid.UUID, filter DiscountFilter) ([]Discount, error) {
 	var discounts []Discount
 	query := r.db.WithContext(ctx).Where("tenant_id = ?", tenantID)
@@ -185,6 +219,16 @@ func (r *repository) DeleteDiscount(ctx context.Context, tenantID, discountID uu
 			return err
 		}
 		
+		// Delete the code pool
+		if err := tx.Where("discount_id = ? AND tenant_id = ?", discountID, tenantID).
+			Delete(&DiscountCode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("discount_id = ? AND tenant_id = ?", discountID, tenantID).
+			Delete(&DiscountCodeBatch{}).Error; err != nil {
+			return err
+		}
+		
 		// Delete the discount
 		return tx.Where("id = ? AND tenant_id = ?", discountID, tenantID).
 			Delete(&Discount{}).Error
@@ -268,6 +312,129 @@ func (r *repository) DeleteDiscountUsage(ctx context.Context, tenantID, usageID
 		Delete(&DiscountUsage{}).Error
 }
 
+// Code pool operations
+func (r *repository) CreateDiscountCodes(ctx context.Context, batch *DiscountCodeBatch, codes []DiscountCode) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(batch).Error; err != nil {
+			return err
+		}
+		return tx.CreateInBatches(codes, 500).Error
+	})
+}
+
+// GetCodeBatchByReference finds the batch generated for an idempotency
+// reference
+func (r *repository) GetCodeBatchByReference(ctx context.Context, tenantID uuid.UUID, reference string) (*DiscountCodeBatch, error) {
+	var batch DiscountCodeBatch
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND reference = ?", tenantID, reference).
+		First(&batch).Error
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// FindExistingCodes returns which of the codes are already taken by a pool
+// code or a discount, in any tenant, since both columns are globally unique
+func (r *repository) FindExistingCodes(ctx context.Context, codes []string) ([]string, error) {
+	var existing []string
+	for start := 0; start < len(codes); start += 1000 {
+		end := start + 1000
+		if end > len(codes) {
+			end = len(codes)
+		}
+		chunk := codes[start:end]
+		
+		var taken []string
+		if err := r.db.WithContext(ctx).Model(&DiscountCode{}).
+			Where("code IN ?", chunk).
+			Pluck("code", &taken).Error; err != nil {
+			return nil, err
+		}
+		existing = append(existing, taken...)
+		
+		taken = nil
+		if err := r.db.WithContext(ctx).Model(&Discount{}).
+			Where("code IN ?", chunk).
+			Pluck("code", &taken).Error; err != nil {
+			return nil, err
+		}
+		existing = append(existing, taken...)
+	}
+	return existing, nil
+}
+
+func (r *repository) GetDiscountCodes(ctx context.Context, tenantID, discountID uuid.UUID, filter DiscountCodeFilter) ([]DiscountCode, error) {
+	var codes []DiscountCode
+	query := r.db.WithContext(ctx).
+		Where("discount_id = ? AND tenant_id = ?", discountID, tenantID)
+	
+	// Apply filters
+	if len(filter.Status) > 0 {
+		query = query.Where("status IN ?", filter.Status)
+	}
+	
+	if filter.BatchID != nil {
+		query = query.Where("batch_id = ?", *filter.BatchID)
+	}
+	
+	if filter.Search != "" {
+		query = query.Where("code ILIKE ? OR customer_email ILIKE ? OR order_number ILIKE ?", 
+			"%"+filter.Search+"%", "%"+filter.Search+"%", "%"+filter.Search+"%")
+	}
+	
+	// Pagination
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+		if filter.Page > 0 {
+			query = query.Offset((filter.Page - 1) * filter.Limit)
+		}
+	}
+	
+	err := query.Order("created_at ASC, code ASC").Find(&codes).Error
+	return codes, err
+}
+
+func (r *repository) GetDiscountCodeByCode(ctx context.Context, tenantID uuid.UUID, code string) (*DiscountCode, error) {
+	var discountCode DiscountCode
+	err := r.db.WithContext(ctx).
+		Where("code = ? AND tenant_id = ?", code, tenantID).
+		First(&discountCode).Error
+	return &discountCode, err
+}
+
+// UpdateDiscountCodeStatus moves a code between statuses, reporting false
+// when the code was not in the expected status
+func (r *repository) UpdateDiscountCodeStatus(ctx context.Context, tenantID, discountID, codeID uuid.UUID, from, to DiscountCodeStatus) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&DiscountCode{}).
+		Where("id = ? AND discount_id = ? AND tenant_id = ? AND status = ?", codeID, discountID, tenantID, from).
+		Updates(map[string]interface{}{"status": to, "updated_at": time.Now()})
+	return result.RowsAffected > 0, result.Error
+}
+
+// RedeemDiscountCode uses a pool code once. The update only matches while the
+// code is available and under its limit, so concurrent checkouts cannot
+// redeem the same code twice; false means the code was already used up.
+func (r *repository) RedeemDiscountCode(ctx context.Context, tenantID, codeID uuid.UUID, redemption DiscountCodeRedemption) (bool, error) {
+	now := time.Now()
+	result := r.db.WithContext(ctx).
+		Model(&DiscountCode{}).
+		Where("id = ? AND tenant_id = ? AND status = ? AND usage_count < usage_limit", codeID, tenantID, CodeStatusAvailable).
+		Updates(map[string]interface{}{
+			"usage_count":    gorm.Expr("usage_count + 1"),
+			"status":         gorm.Expr("CASE WHEN usage_count + 1 >= usage_limit THEN ? ELSE status END", CodeStatusRedeemed),
+			"redeemed_at":    now,
+			"order_id":       redemption.OrderID,
+			"order_number":   redemption.OrderNumber,
+			"customer_id":    redemption.CustomerID,
+			"customer_email": redemption.CustomerEmail,
+			"updated_at":     now,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
 // Gift card operations
 func (r *repository) CreateGiftCard(ctx context.Context, giftCard *GiftCard) error {
 	return r.db.WithContext(ctx).Create(giftCard).Error
@@ -369,6 +536,131 @@ func (r *repository) GetGiftCardTransactions(ctx context.Context, tenantID, gift
 	return transactions, err
 }
 
+// Ledger operations
+func (r *repository) Ledger() *Ledger {
+	return NewLedger(r.db)
+}
+
+func (r *repository) GetHolds(ctx context.Context, tenantID uuid.UUID, filter HoldFilter) ([]BalanceHold, error) {
+	var holds []BalanceHold
+	query := r.db.WithContext(ctx).Where("tenant_id = ?", tenantID)
+	
+	// Apply filters
+	if filter.AccountType != "" {
+		query = query.Where("account_type = ?", filter.AccountType)
+	}
+	
+	if filter.AccountID != nil {
+		query = query.Where("account_id = ?", *filter.AccountID)
+	}
+	
+	if filter.OrderID != nil {
+		query = query.Where("order_id = ?", *filter.OrderID)
+	}
+	
+	if len(filter.Status) > 0 {
+		query = query.Where("status IN ?", filter.Status)
+	}
+	
+	// Pagination
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+		if filter.Page > 0 {
+			query = query.Offset((filter.Page - 1) * filter.Limit)
+		}
+	}
+	
+	err := query.Order("created_at DESC").Find(&holds).Error
+	return holds, err
+}
+
+// Gift card purchase operations
+func (r *repository) GetGiftCardPurchases(ctx context.Context, tenantID uuid.UUID, filter GiftCardPurchaseFilter) ([]GiftCardPurchase, error) {
+	var purchases []GiftCardPurchase
+	query := r.db.WithContext(ctx).Where("tenant_id = ?", tenantID)
+	
+	// Apply filters
+	if filter.OrderID != nil {
+		query = query.Where("order_id = ?", *filter.OrderID)
+	}
+	
+	if len(filter.Status) > 0 {
+		query = query.Where("status IN ?", filter.Status)
+	}
+	
+	if filter.RecipientEmail != "" {
+		query = query.Where("LOWER(recipient_email) = LOWER(?)", filter.RecipientEmail)
+	}
+	
+	// Pagination
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+		if filter.Page > 0 {
+			query = query.Offset((filter.Page - 1) * filter.Limit)
+		}
+	}
+	
+	err := query.Order("created_at DESC").Find(&purchases).Error
+	return purchases, err
+}
+
+func (r *repository) GetGiftCardPurchase(ctx context.Context, tenantID, purchaseID uuid.UUID) (*GiftCardPurchase, error) {
+	var purchase GiftCardPurchase
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND tenant_id = ?", purchaseID, tenantID).
+		First(&purchase).Error
+	if err != nil {
+		return nil, err
+	}
+	return &purchase, nil
+}
+
+// GetDueGiftCardPurchases returns issued cards whose send date has passed and
+// that have attempts left, oldest first
+func (r *repository) GetDueGiftCardPurchases(ctx context.Context, tenantID uuid.UUID, now time.Time, limit int) ([]GiftCardPurchase, error) {
+	var purchases []GiftCardPurchase
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND status = ? AND (send_at IS NULL OR send_at <= ?) AND delivery_attempts < ?",
+			tenantID, PurchaseStatusIssued, now, MaxGiftCardDeliveryAttempts).
+		Order("send_at ASC, created_at ASC").
+		Limit(limit).
+		Find(&purchases).Error
+	return purchases, err
+}
+
+// ClaimGiftCardDelivery marks an issued card as delivered only if no other
+// run got to it first
+func (r *repository) ClaimGiftCardDelivery(ctx context.Context, tenantID, purchaseID uuid.UUID, now time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&GiftCardPurchase{}).
+		Where("id = ? AND tenant_id = ? AND status = ?", purchaseID, tenantID, PurchaseStatusIssued).
+		Updates(map[string]interface{}{
+			"status":       PurchaseStatusDelivered,
+			"delivered_at": now,
+			"updated_at":   now,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *repository) UpdateGiftCardPurchase(ctx context.Context, tenantID, purchaseID uuid.UUID, updates map[string]interface{}) error {
+	return r.db.WithContext(ctx).
+		Model(&GiftCardPurchase{}).
+		Where("id = ? AND tenant_id = ?", purchaseID, tenantID).
+		Updates(updates).Error
+}
+
+// CancelGiftCardPurchases cancels an order's purchases that were never issued
+func (r *repository) CancelGiftCardPurchases(ctx context.Context, tenantID, orderID uuid.UUID) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Model(&GiftCardPurchase{}).
+		Where("tenant_id = ? AND order_id = ? AND status = ?", tenantID, orderID, PurchaseStatusPending).
+		Updates(map[string]interface{}{
+			"status":     PurchaseStatusCancelled,
+			"updated_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
+
 // Store credit operations
 func (r *repository) CreateStoreCredit(ctx context.Context, storeCredit *StoreCredit) error {
 	return r.db.WithContext(ctx).Create(storeCredit).Error
@@ -393,6 +685,7 @@ func (r *repository) GetStoreCredit(ctx context.Context, tenantID, customerID uu
 		if createErr := r.CreateStoreCredit(ctx, &storeCredit); createErr != nil {
 			return nil, createErr
 		}
+		err = nil
 	}
 	
 	return &storeCredit, err
@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+
+	"ecommerce-saas/internal/notification"
 )
 
 // Service defines the discount service interface
@@ -24,6 +26,16 @@ type Service interface {
 	ApplyDiscount(ctx context.Context, req ApplyDiscountRequest) (*DiscountApplication, error)
 	RemoveDiscount(ctx context.Context, tenantID uuid.UUID, orderID uuid.UUID) error
 	
+	// Automatic promotions and stacking
+	GetAutomaticPromotions(ctx context.Context, tenantID uuid.UUID) ([]Discount, error)
+	EvaluatePromotions(ctx context.Context, req EvaluatePromotionsRequest) (*PromotionResult, error)
+	
+	// Code pools
+	GenerateCodes(ctx context.Context, req GenerateCodesRequest) (*DiscountCodeBatch, error)
+	GetDiscountCodes(ctx context.Context, tenantID, discountID uuid.UUID, filter DiscountCodeFilter) ([]DiscountCode, error)
+	SetDiscountCodeStatus(ctx context.Context, tenantID, discountID, codeID uuid.UUID, status DiscountCodeStatus) error
+	ExportDiscountCodes(ctx context.Context, tenantID, discountID uuid.UUID, filter DiscountCodeFilter) ([]byte, string, error)
+	
 	// Discount usage tracking
 	RecordDiscountUsage(ctx context.Context, usage *DiscountUsage) error
 	GetDiscountUsage(ctx context.Context, tenantID, discountID uuid.UUID, filter UsageFilter) ([]DiscountUsage, error)
@@ -42,6 +54,25 @@ type Service interface {
 	RefillGiftCard(ctx context.Context, req RefillGiftCardRequest) (*GiftCardTransaction, error)
 	GetGiftCardTransactions(ctx context.Context, tenantID, giftCardID uuid.UUID) ([]GiftCardTransaction, error)
 	
+	// Balance holds, refunds, expiry and reconciliation
+	HoldBalance(ctx context.Context, req HoldBalanceRequest) (*BalanceHold, error)
+	CaptureHold(ctx context.Context, tenantID, holdID uuid.UUID) (*BalanceHold, error)
+	ReleaseHold(ctx context.Context, tenantID, holdID uuid.UUID, reason string) (*BalanceHold, error)
+	CaptureOrderHolds(ctx context.Context, tenantID, orderID uuid.UUID) ([]BalanceHold, error)
+	ReleaseOrderHolds(ctx context.Context, tenantID, orderID uuid.UUID, reason string) ([]BalanceHold, error)
+	GetHolds(ctx context.Context, tenantID uuid.UUID, filter HoldFilter) ([]BalanceHold, error)
+	RefundToOriginalSources(ctx context.Context, req RefundBalanceRequest) ([]RefundAllocation, error)
+	ExpireBalances(ctx context.Context, tenantID uuid.UUID) (*ExpiryResult, error)
+	GetBreakageReport(ctx context.Context, tenantID uuid.UUID, startDate, endDate time.Time) (*BreakageReport, error)
+	ReconcileLedgers(ctx context.Context, tenantID uuid.UUID) (*ReconciliationReport, error)
+	
+	// Gift card purchases
+	GetGiftCardPurchases(ctx context.Context, tenantID uuid.UUID, filter GiftCardPurchaseFilter) ([]GiftCardPurchase, error)
+	IssueOrderGiftCards(ctx context.Context, tenantID, orderID uuid.UUID) ([]GiftCardPurchase, error)
+	CancelOrderGiftCards(ctx context.Context, tenantID, orderID uuid.UUID) (int64, error)
+	DeliverGiftCards(ctx context.Context, tenantID uuid.UUID) (*GiftCardDeliveryResult, error)
+	ResendGiftCard(ctx context.Context, tenantID, purchaseID uuid.UUID, req ResendGiftCardRequest) (*GiftCardPurchase, error)
+	
 	// Store credit operations
 	GetStoreCredit(ctx context.Context, tenantID, customerID uuid.UUID) (*StoreCredit, error)
 	AddStoreCredit(ctx context.Context, req AddStoreCreditRequest) (*StoreCreditTransaction, error)
@@ -56,12 +87,14 @@ type Service interface {
 
 // service implements the Service interface
 type service struct {
-	repo Repository
+	repo          Repository
+	notifications notification.Service // Sends purchased gift cards
+	tiers         TierResolver         // Optional; enables loyalty tier promotions and benefits
 }
 
-// NewService creates a new discount service
-func NewService(repo Repository) Service {
-	return &service{repo: repo}
+// NewService creates a new discount service. tiers may be nil.
+func NewService(repo Repository, notifications notification.Service, tiers TierResolver) Service {
+	return &service{repo: repo, notifications: notifications, tiers: tiers}
 }
 
 // Request/Response DTOs
@@ -88,15 +121,26 @@ type CreateDiscountRequest struct {
 	CustomerEligibility    string             `json:"customer_eligibility"`
 	EligibleCustomerIDs    []string           `json:"eligible_customer_ids"`
 	EligibleCustomerGroups []string           `json:"eligible_customer_groups"`
+	EligibleLoyaltyTiers   []string           `json:"eligible_loyalty_tiers"`
 	BuyQuantity            *int               `json:"buy_quantity"`
 	GetQuantity            *int               `json:"get_quantity"`
 	GetValue               *float64           `json:"get_value"`
+	RewardProductIDs       []string           `json:"reward_product_ids"`
+	RewardCategoryIDs      []string           `json:"reward_category_ids"`
+	RewardCollectionIDs    []string           `json:"reward_collection_ids"`
+	Tiers                  []DiscountTier     `json:"tiers"`
+	BundleItems            []BundleItem       `json:"bundle_items"`
 	Stackable              bool               `json:"stackable"`
 	StackableWith          []string           `json:"stackable_with"`
 	ExclusiveGroup         string             `json:"exclusive_group"`
 	ApplyOnce              bool               `json:"apply_once"`
 	ShowInStorefront       bool               `json:"show_in_storefront"`
-	RequiresCode           bool               `json:"requires_code"`
+	RequiresCode           *bool              `json:"requires_code"` // Defaults to true; false creates an automatic promotion
+	CombinesWithOrder      bool               `json:"combines_with_order"`
+	CombinesWithProduct    bool               `json:"combines_with_product"`
+	CombinesWithShipping   bool               `json:"combines_with_shipping"`
+	Exclusive              bool               `json:"exclusive"`
+	Priority               int                `json:"priority"`
 }
 
 type UpdateDiscountRequest struct {
@@ -118,8 +162,25 @@ type UpdateDiscountRequest struct {
 	CustomerEligibility    *string            `json:"customer_eligibility"`
 	EligibleCustomerIDs    []string           `json:"eligible_customer_ids"`
 	EligibleCustomerGroups []string           `json:"eligible_customer_groups"`
+	EligibleLoyaltyTiers   []string           `json:"eligible_loyalty_tiers"`
+	BuyQuantity            *int               `json:"buy_quantity"`
+	GetQuantity            *int               `json:"get_quantity"`
+	GetValue               *float64           `json:"get_value"`
+	RewardProductIDs       []string           `json:"reward_product_ids"`
+	RewardCategoryIDs      []string           `json:"reward_category_ids"`
+	RewardCollectionIDs    []string           `json:"reward_collection_ids"`
+	Tiers                  []DiscountTier     `json:"tiers"`
+	BundleItems            []BundleItem       `json:"bundle_items"`
 	Stackable              *bool              `json:"stackable"`
+	StackableWith          []string           `json:"stackable_with"`
+	ExclusiveGroup         *string            `json:"exclusive_group"`
+	CombinesWithOrder      *bool              `json:"combines_with_order"`
+	CombinesWithProduct    *bool              `json:"combines_with_product"`
+	CombinesWithShipping   *bool              `json:"combines_with_shipping"`
+	Exclusive              *bool              `json:"exclusive"`
+	Priority               *int               `json:"priority"`
 	ShowInStorefront       *bool              `json:"show_in_storefront"`
+	RequiresCode           *bool              `json:"requires_code"` // false turns the discount into an automatic promotion
 }
 
 type DiscountFilter struct {
@@ -139,6 +200,7 @@ type DiscountFilter struct {
 }
 
 type ValidateDiscountRequest struct {
+	TenantID       uuid.UUID  `json:"-"`
 	Code           string     `json:"code" validate:"required"`
 	CustomerID     *uuid.UUID `json:"customer_id"`
 	CustomerEmail  string     `json:"customer_email"`
@@ -180,6 +242,17 @@ type DiscountApplication struct {
 	Message        string    `json:"message"`
 }
 
+// EvaluatePromotionsRequest describes a cart to evaluate automatic promotions
+// and entered codes against
+type EvaluatePromotionsRequest struct {
+	TenantID       uuid.UUID       `json:"-"`
+	CustomerID     *uuid.UUID      `json:"customer_id"`
+	CustomerEmail  string          `json:"customer_email"`
+	Codes          []string        `json:"codes"`
+	Lines          []PromotionLine `json:"lines" binding:"required,min=1,dive"`
+	ShippingAmount float64         `json:"shipping_amount"`
+}
+
 type UsageFilter struct {
 	StartDate *time.Time `json:"start_date"`
 	EndDate   *time.Time `json:"end_date"`
@@ -189,6 +262,7 @@ type UsageFilter struct {
 
 // Gift card DTOs
 type CreateGiftCardRequest struct {
+	TenantID       uuid.UUID  `json:"-"`
 	Code           string     `json:"code"`
 	InitialValue   float64    `json:"initial_value" validate:"required,gt=0"`
 	Currency       string     `json:"currency" validate:"required"`
@@ -246,6 +320,34 @@ type RefillGiftCardRequest struct {
 	ProcessedBy uuid.UUID  `json:"processed_by" validate:"required"`
 }
 
+// Ledger DTOs
+
+// DefaultHoldDuration is how long a hold stays open before it is released
+const DefaultHoldDuration = 72 * time.Hour
+
+type HoldBalanceRequest struct {
+	TenantID         uuid.UUID     `json:"-"`
+	AccountType      LedgerAccount `json:"account_type" binding:"required,oneof=gift_card store_credit"`
+	Code             string        `json:"code"`        // Gift card to hold
+	CustomerID       *uuid.UUID    `json:"customer_id"` // Store credit owner
+	Amount           float64       `json:"amount" binding:"required,gt=0"`
+	Reference        string        `json:"reference"`
+	OrderID          *uuid.UUID    `json:"order_id"`
+	OrderNumber      string        `json:"order_number"`
+	CustomerEmail    string        `json:"customer_email"`
+	ExpiresInMinutes int           `json:"expires_in_minutes"`
+}
+
+type RefundBalanceRequest struct {
+	TenantID    uuid.UUID  `json:"-"`
+	OrderID     uuid.UUID  `json:"-"`
+	Amount      float64    `json:"amount" binding:"required,gt=0"`
+	Reason      string     `json:"reason"`
+	RefundID    *uuid.UUID `json:"refund_id"`
+	ReturnID    *uuid.UUID `json:"return_id"`
+	ProcessedBy *uuid.UUID `json:"-"`
+}
+
 // Store credit DTOs
 type AddStoreCreditRequest struct {
 	TenantID      uuid.UUID  `json:"tenant_id" validate:"required"`
@@ -257,6 +359,7 @@ type AddStoreCreditRequest struct {
 	ReturnID      *uuid.UUID `json:"return_id"`
 	ProcessedBy   *uuid.UUID `json:"processed_by"`
 	ExpiresAt     *time.Time `json:"expires_at"`
+	Reference     string     `json:"reference"` // Credit with the same reference is only granted once
 }
 
 type UseStoreCreditRequest struct {
@@ -310,8 +413,12 @@ type RevenueImpact struct {
 
 // Implementation methods (TODO: implement business logic)
 func (s *service) CreateDiscount(ctx context.Context, req CreateDiscountRequest) (*Discount, error) {
-	// Normalize code
+	// Normalize code; automatic promotions get a generated internal code
 	code := strings.ToUpper(strings.TrimSpace(req.Code))
+	requiresCode := req.RequiresCode == nil || *req.RequiresCode
+	if code == "" && !requiresCode {
+		code = "AUTO-" + strings.ToUpper(uuid.New().String()[:8])
+	}
 
 	// Create discount entity
 	discount := &Discount{
@@ -338,12 +445,34 @@ func (s *service) CreateDiscount(ctx context.Context, req CreateDiscountRequest)
 		CustomerEligibility:    req.CustomerEligibility,
 		EligibleCustomerIDs:    req.EligibleCustomerIDs,
 		EligibleCustomerGroups: req.EligibleCustomerGroups,
+		EligibleLoyaltyTiers:   req.EligibleLoyaltyTiers,
+		BuyQuantity:            req.BuyQuantity,
+		GetQuantity:            req.GetQuantity,
+		GetValue:               req.GetValue,
+		RewardProductIDs:       req.RewardProductIDs,
+		RewardCategoryIDs:      req.RewardCategoryIDs,
+		RewardCollectionIDs:    req.RewardCollectionIDs,
+		Tiers:                  req.Tiers,
+		BundleItems:            req.BundleItems,
 		Stackable:              req.Stackable,
+		StackableWith:          req.StackableWith,
+		ExclusiveGroup:         req.ExclusiveGroup,
+		CombinesWithOrder:      req.CombinesWithOrder,
+		CombinesWithProduct:    req.CombinesWithProduct,
+		CombinesWithShipping:   req.CombinesWithShipping,
+		Exclusive:              req.Exclusive,
+		Priority:               req.Priority,
+		ApplyOnce:              req.ApplyOnce,
 		ShowInStorefront:       req.ShowInStorefront,
+		RequiresCode:           requiresCode,
 		CreatedAt:              time.Now(),
 		UpdatedAt:              time.Now(),
 	}
 
+	if err := discount.Validate(); err != nil {
+		return nil, err
+	}
+
 	err := s.repo.CreateDiscount(ctx, discount)
 	if err != nil {
 		return nil, err
@@ -356,9 +485,10 @@ func (s *service) GetDiscount(ctx context.Context, tenantID, discountID uuid.UUI
 }
 
 func (s *service) GetDiscountByCode(ctx context.Context, tenantID uuid.UUID, code string) (*Discount, error) {
-	// Normalize code
+	// Normalize code; pool codes resolve to their discount
 	code = strings.ToUpper(strings.TrimSpace(code))
-	return s.repo.GetDiscountByCode(ctx, tenantID, code)
+	discount, _, err := s.resolveCode(ctx, tenantID, code)
+	return discount, err
 }
 
 func (s *service) GetDiscounts(ctx context.Context, tenantID uuid.UUID, filter DiscountFilter) ([]Discount, error) {
@@ -366,71 +496,171 @@ func (s *service) GetDiscounts(ctx context.Context, tenantID uuid.UUID, filter D
 }
 
 func (s *service) UpdateDiscount(ctx context.Context, tenantID, discountID uuid.UUID, req UpdateDiscountRequest) (*Discount, error) {
+	existing, err := s.repo.GetDiscountByID(ctx, tenantID, discountID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Changes are applied to a copy first so the discount as a whole is
+	// validated before anything is saved
+	merged := *existing
 	updates := make(map[string]interface{})
 	updates["updated_at"] = time.Now()
 
 	if req.Title != nil {
+		merged.Title = *req.Title
 		updates["title"] = *req.Title
 	}
 	if req.Description != nil {
+		merged.Description = *req.Description
 		updates["description"] = *req.Description
 	}
 	if req.Value != nil {
+		merged.Value = *req.Value
 		updates["value"] = *req.Value
 	}
 	if req.MinOrderAmount != nil {
+		merged.MinOrderAmount = req.MinOrderAmount
 		updates["min_order_amount"] = *req.MinOrderAmount
 	}
 	if req.MinItemQuantity != nil {
+		merged.MinItemQuantity = req.MinItemQuantity
 		updates["min_item_quantity"] = *req.MinItemQuantity
 	}
 	if req.Target != nil {
+		merged.Target = *req.Target
 		updates["target"] = *req.Target
 	}
 	if req.TargetProductIDs != nil {
+		merged.TargetProductIDs = req.TargetProductIDs
 		updates["target_product_ids"] = req.TargetProductIDs
 	}
 	if req.TargetCategoryIDs != nil {
+		merged.TargetCategoryIDs = req.TargetCategoryIDs
 		updates["target_category_ids"] = req.TargetCategoryIDs
 	}
 	if req.TargetCollectionIDs != nil {
+		merged.TargetCollectionIDs = req.TargetCollectionIDs
 		updates["target_collection_ids"] = req.TargetCollectionIDs
 	}
 	if req.ExcludeProductIDs != nil {
+		merged.ExcludeProductIDs = req.ExcludeProductIDs
 		updates["exclude_product_ids"] = req.ExcludeProductIDs
 	}
 	if req.UsageLimit != nil {
+		merged.UsageLimit = req.UsageLimit
 		updates["usage_limit"] = *req.UsageLimit
 	}
 	if req.CustomerUsageLimit != nil {
+		merged.CustomerUsageLimit = req.CustomerUsageLimit
 		updates["customer_usage_limit"] = *req.CustomerUsageLimit
 	}
 	if req.StartsAt != nil {
+		merged.StartsAt = req.StartsAt
 		updates["starts_at"] = *req.StartsAt
 	}
 	if req.ExpiresAt != nil {
+		merged.ExpiresAt = req.ExpiresAt
 		updates["expires_at"] = *req.ExpiresAt
 	}
 	if req.Status != nil {
+		merged.Status = *req.Status
 		updates["status"] = *req.Status
 	}
 	if req.CustomerEligibility != nil {
+		merged.CustomerEligibility = *req.CustomerEligibility
 		updates["customer_eligibility"] = *req.CustomerEligibility
 	}
 	if req.EligibleCustomerIDs != nil {
+		merged.EligibleCustomerIDs = req.EligibleCustomerIDs
 		updates["eligible_customer_ids"] = req.EligibleCustomerIDs
 	}
 	if req.EligibleCustomerGroups != nil {
+		merged.EligibleCustomerGroups = req.EligibleCustomerGroups
 		updates["eligible_customer_groups"] = req.EligibleCustomerGroups
 	}
+	if req.EligibleLoyaltyTiers != nil {
+		merged.EligibleLoyaltyTiers = req.EligibleLoyaltyTiers
+		updates["eligible_loyalty_tiers"] = req.EligibleLoyaltyTiers
+	}
 	if req.Stackable != nil {
+		merged.Stackable = *req.Stackable
 		updates["stackable"] = *req.Stackable
 	}
+	if req.BuyQuantity != nil {
+		merged.BuyQuantity = req.BuyQuantity
+		updates["buy_quantity"] = *req.BuyQuantity
+	}
+	if req.GetQuantity != nil {
+		merged.GetQuantity = req.GetQuantity
+		updates["get_quantity"] = *req.GetQuantity
+	}
+	if req.GetValue != nil {
+		merged.GetValue = req.GetValue
+		updates["get_value"] = *req.GetValue
+	}
+	if req.RewardProductIDs != nil {
+		merged.RewardProductIDs = req.RewardProductIDs
+		updates["reward_product_ids"] = req.RewardProductIDs
+	}
+	if req.RewardCategoryIDs != nil {
+		merged.RewardCategoryIDs = req.RewardCategoryIDs
+		updates["reward_category_ids"] = req.RewardCategoryIDs
+	}
+	if req.RewardCollectionIDs != nil {
+		merged.RewardCollectionIDs = req.RewardCollectionIDs
+		updates["reward_collection_ids"] = req.RewardCollectionIDs
+	}
+	if req.Tiers != nil {
+		merged.Tiers = req.Tiers
+		updates["tiers"] = req.Tiers
+	}
+	if req.BundleItems != nil {
+		merged.BundleItems = req.BundleItems
+		updates["bundle_items"] = req.BundleItems
+	}
+	if req.StackableWith != nil {
+		merged.StackableWith = req.StackableWith
+		updates["stackable_with"] = req.StackableWith
+	}
+	if req.ExclusiveGroup != nil {
+		merged.ExclusiveGroup = *req.ExclusiveGroup
+		updates["exclusive_group"] = *req.ExclusiveGroup
+	}
+	if req.CombinesWithOrder != nil {
+		merged.CombinesWithOrder = *req.CombinesWithOrder
+		updates["combines_with_order"] = *req.CombinesWithOrder
+	}
+	if req.CombinesWithProduct != nil {
+		merged.CombinesWithProduct = *req.CombinesWithProduct
+		updates["combines_with_product"] = *req.CombinesWithProduct
+	}
+	if req.CombinesWithShipping != nil {
+		merged.CombinesWithShipping = *req.CombinesWithShipping
+		updates["combines_with_shipping"] = *req.CombinesWithShipping
+	}
+	if req.Exclusive != nil {
+		merged.Exclusive = *req.Exclusive
+		updates["exclusive"] = *req.Exclusive
+	}
+	if req.Priority != nil {
+		merged.Priority = *req.Priority
+		updates["priority"] = *req.Priority
+	}
 	if req.ShowInStorefront != nil {
+		merged.ShowInStorefront = *req.ShowInStorefront
 		updates["show_in_storefront"] = *req.ShowInStorefront
 	}
+	if req.RequiresCode != nil {
+		merged.RequiresCode = *req.RequiresCode
+		updates["requires_code"] = *req.RequiresCode
+	}
+
+	if err := merged.Validate(); err != nil {
+		return nil, err
+	}
 
-	err := s.repo.UpdateDiscount(ctx, tenantID, discountID, updates)
+	err = s.repo.UpdateDiscount(ctx, tenantID, discountID, updates)
 	if err != nil {
 		return nil, err
 	}
@@ -454,13 +684,21 @@ func (s *service) DeleteDiscount(ctx context.Context, tenantID, discountID uuid.
 
 func (s *service) ValidateDiscountCode(ctx context.Context, req ValidateDiscountRequest) (*DiscountValidation, error) {
 	// Get discount by code
-	discount, err := s.repo.GetDiscountByCode(ctx, uuid.Nil, req.Code) // TODO: Add tenantID to request
+	discount, poolCode, err := s.resolveCode(ctx, req.TenantID, strings.ToUpper(strings.TrimSpace(req.Code)))
 	if err != nil {
 		return &DiscountValidation{
 			Valid:   false,
 			Message: "Invalid discount code",
 		}, nil
 	}
+	
+	// Single-use pool codes can only be redeemed once
+	if poolCode != nil && !poolCode.IsRedeemable() {
+		return &DiscountValidation{
+			Valid:   false,
+			Message: "Discount code has already been used",
+		}, nil
+	}
 
 	// Check if discount is active
 	if !discount.IsActive() {
@@ -477,6 +715,12 @@ func (s *service) ValidateDiscountCode(ctx context.Context, req ValidateDiscount
 			Message: "You are not eligible for this discount",
 		}, nil
 	}
+	if !discount.AllowsTier(s.customerTier(ctx, req.TenantID, req.CustomerID)) {
+		return &DiscountValidation{
+			Valid:   false,
+			Message: "This discount is only available to loyalty members of certain tiers",
+		}, nil
+	}
 
 	// Calculate discount amount
 	discountAmount, err := discount.CalculateDiscount(req.OrderAmount, req.ItemQuantity)
@@ -513,6 +757,7 @@ func (s *service) ValidateDiscountCode(ctx context.Context, req ValidateDiscount
 func (s *service) ApplyDiscount(ctx context.Context, req ApplyDiscountRequest) (*DiscountApplication, error) {
 	// First validate the discount code
 	validationReq := ValidateDiscountRequest{
+		TenantID:      req.TenantID,
 		Code:          req.Code,
 		CustomerID:    req.CustomerID,
 		CustomerEmail: req.CustomerEmail,
@@ -534,6 +779,26 @@ func (s *service) ApplyDiscount(ctx context.Context, req ApplyDiscountRequest) (
 		}, nil
 	}
 
+	// Pool codes are redeemed first; a concurrent checkout that already used
+	// the code makes this one fail without recording usage
+	code := strings.ToUpper(strings.TrimSpace(req.Code))
+	if poolCode, err := s.repo.GetDiscountCodeByCode(ctx, req.TenantID, code); err == nil {
+		redeemed, err := s.repo.RedeemDiscountCode(ctx, req.TenantID, poolCode.ID, DiscountCodeRedemption{
+			OrderID:       req.OrderID,
+			CustomerID:    req.CustomerID,
+			CustomerEmail: req.CustomerEmail,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to redeem discount code: %w", err)
+		}
+		if !redeemed {
+			return &DiscountApplication{
+				Applied: false,
+				Message: "Discount code has already been used",
+			}, nil
+		}
+	}
+
 	// Create discount usage record
 	usage := &DiscountUsage{
 		ID:            uuid.New(),
@@ -588,6 +853,89 @@ func (s *service) RemoveDiscount(ctx context.Context, tenantID uuid.UUID, orderI
 	return nil
 }
 
+// GetAutomaticPromotions returns codeless promotions that are currently live
+func (s *service) GetAutomaticPromotions(ctx context.Context, tenantID uuid.UUID) ([]Discount, error) {
+	return s.repo.GetAutomaticDiscounts(ctx, tenantID)
+}
+
+// EvaluatePromotions runs live automatic promotions together with the entered
+// codes through the promotion engine
+func (s *service) EvaluatePromotions(ctx context.Context, req EvaluatePromotionsRequest) (*PromotionResult, error) {
+	candidates, err := s.repo.GetAutomaticDiscounts(ctx, req.TenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load automatic promotions: %w", err)
+	}
+
+	var notFound []RejectedPromotion
+	seen := make(map[string]bool, len(req.Codes))
+	for _, code := range req.Codes {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+
+		discount, poolCode, err := s.resolveCode(ctx, req.TenantID, code)
+		if err != nil || discount.IsAutomatic() {
+			notFound = append(notFound, RejectedPromotion{
+				Code:    code,
+				Reason:  RejectCodeNotFound,
+				Message: fmt.Sprintf("Discount code %s is not valid", code),
+			})
+			continue
+		}
+		if poolCode != nil && !poolCode.IsRedeemable() {
+			notFound = append(notFound, RejectedPromotion{
+				DiscountID: &discount.ID,
+				Code:       code,
+				Title:      discount.Title,
+				Reason:     RejectCodeRedeemed,
+				Message:    fmt.Sprintf("Discount code %s has already been used", code),
+			})
+			continue
+		}
+		candidates = append(candidates, *discount)
+	}
+
+	usage := make(map[uuid.UUID]int)
+	if req.CustomerEmail != "" {
+		for _, candidate := range candidates {
+			if candidate.CustomerUsageLimit == nil {
+				continue
+			}
+			count, err := s.repo.GetCustomerDiscountUsageCount(ctx, req.TenantID, req.CustomerEmail, candidate.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check customer usage: %w", err)
+			}
+			usage[candidate.ID] = count
+		}
+	}
+
+	result := EvaluatePromotions(candidates, PromotionInput{
+		CustomerID:     req.CustomerID,
+		CustomerEmail:  req.CustomerEmail,
+		Lines:          req.Lines,
+		ShippingAmount: req.ShippingAmount,
+		CustomerUsage:  usage,
+		LoyaltyTier:    s.customerTier(ctx, req.TenantID, req.CustomerID),
+	})
+	result.Rejected = append(notFound, result.Rejected...)
+	return result, nil
+}
+
+// customerTier looks up the customer's loyalty tier. Lookup failures are
+// treated as no tier so promotions still evaluate.
+func (s *service) customerTier(ctx context.Context, tenantID uuid.UUID, customerID *uuid.UUID) *LoyaltyTier {
+	if s.tiers == nil || customerID == nil {
+		return nil
+	}
+	tier, err := s.tiers.CustomerTier(ctx, tenantID, *customerID)
+	if err != nil {
+		return nil
+	}
+	return tier
+}
+
 func (s *service) RecordDiscountUsage(ctx context.Context, usage *DiscountUsage) error {
 	return s.repo.CreateDiscountUsage(ctx, usage)
 }
@@ -605,13 +953,14 @@ func (s *service) CreateGiftCard(ctx context.Context, req CreateGiftCardRequest)
 	// Generate code if not provided
 	code := req.Code
 	if code == "" {
-		code = s.generateGiftCardCode()
+		code = generateGiftCardCode()
 	}
 	code = strings.ToUpper(strings.TrimSpace(code))
 
 	// Create gift card entity
 	giftCard := &GiftCard{
 		ID:             uuid.New(),
+		TenantID:       req.TenantID,
 		Code:           code,
 		InitialValue:   req.InitialValue,
 		CurrentValue:   req.InitialValue,
@@ -628,7 +977,12 @@ func (s *service) CreateGiftCard(ctx context.Context, req CreateGiftCardRequest)
 		UpdatedAt:      time.Now(),
 	}
 
-	err := s.repo.CreateGiftCard(ctx, giftCard)
+	if err := giftCard.Validate(); err != nil {
+		return nil, err
+	}
+
+	// The initial balance is the card's first ledger entry
+	err := s.repo.Ledger().IssueGiftCard(ctx, giftCard, LedgerEntry{ProcessedBy: req.PurchasedBy})
 	if err != nil {
 		return nil, err
 	}
@@ -637,7 +991,7 @@ func (s *service) CreateGiftCard(ctx context.Context, req CreateGiftCardRequest)
 }
 
 // generateGiftCardCode generates a random gift card code
-func (s *service) generateGiftCardCode() string {
+func generateGiftCardCode() string {
 	// Generate a 16-character alphanumeric code
 	const charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 	code := make([]byte, 16)
@@ -721,8 +1075,8 @@ func (s *service) ValidateGiftCard(ctx context.Context, tenantID uuid.UUID, code
 	}, nil
 	}
 
-	// Check if gift card has balance
-	if giftCard.CurrentValue <= 0 {
+	// Check if gift card has balance that is not held
+	if giftCard.AvailableBalance() <= 0 {
 		return &GiftCardValidation{
 		Valid: false,
 		Message: "Gift card has no remaining balance",
@@ -733,7 +1087,7 @@ func (s *service) ValidateGiftCard(ctx context.Context, tenantID uuid.UUID, code
 		Valid:           true,
 		Message:         "Gift card is valid",
 		GiftCard:        giftCard,
-		AvailableAmount: giftCard.CurrentValue,
+		AvailableAmount: giftCard.AvailableBalance(),
 	}, nil
 }
 
@@ -748,49 +1102,19 @@ func (s *service) UseGiftCard(ctx context.Context, req UseGiftCardRequest) (*Gif
 		return nil, fmt.Errorf("gift card validation failed: %s", validation.Message)
 	}
 
-	giftCard := validation.GiftCard
-
-	// Check if amount is available
-	if req.Amount > giftCard.CurrentValue {
-		return nil, fmt.Errorf("insufficient gift card balance: requested %.2f, available %.2f", req.Amount, giftCard.CurrentValue)
-	}
-
-	// Calculate new balance
-	newBalance := giftCard.CurrentValue - req.Amount
-
-	// Update gift card balance
-	updates := map[string]interface{}{
-		"current_value": newBalance,
-		"updated_at":    time.Now(),
-	}
-
-	// If balance is zero, mark as used
-	if newBalance == 0 {
-		updates["status"] = "used"
-	}
-
-	err = s.repo.UpdateGiftCard(ctx, req.TenantID, giftCard.ID, updates)
-	if err != nil {
-		return nil, fmt.Errorf("failed to update gift card: %w", err)
-	}
-
-	// Record transaction
-	transaction := &GiftCardTransaction{
-		ID:            uuid.New(),
-		TenantID:      req.TenantID,
-		GiftCardID:    giftCard.ID,
-		Type:          "debit",
+	// The ledger locks the card and re-checks the balance, so parallel
+	// redemptions cannot overdraw it
+	transaction, err := s.repo.Ledger().PostGiftCard(ctx, req.TenantID, validation.GiftCard.ID, LedgerEntry{
+		Type:          LedgerDebit,
 		Amount:        req.Amount,
+		Description:   "Redeemed",
 		OrderID:       &req.OrderID,
 		OrderNumber:   req.OrderNumber,
 		CustomerID:    req.CustomerID,
 		CustomerEmail: req.CustomerEmail,
-		CreatedAt:     time.Now(),
-	}
-
-	err = s.repo.CreateGiftCardTransaction(ctx, transaction)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to record gift card transaction: %w", err)
+		return nil, fmt.Errorf("failed to use gift card: %w", err)
 	}
 
 	return transaction, nil
@@ -808,45 +1132,19 @@ func (s *service) RefillGiftCard(ctx context.Context, req RefillGiftCardRequest)
 		return nil, fmt.Errorf("gift card is not refillable")
 	}
 
-	// Check if gift card is active
-	if giftCard.Status != StatusActive {
+	// Check if gift card is active; used cards are reactivated by the refill
+	if giftCard.Status != StatusActive && giftCard.Status != "used" {
 		return nil, fmt.Errorf("gift card is not active")
 	}
 
-	// Calculate new balance
-	newBalance := giftCard.CurrentValue + req.Amount
-
-	// Update gift card balance
-	updates := map[string]interface{}{
-		"current_value": newBalance,
-		"updated_at":    time.Now(),
-	}
-
-	// If it was used, reactivate it
-	if giftCard.Status == "used" {
-		updates["status"] = StatusActive
-	}
-
-	err = s.repo.UpdateGiftCard(ctx, req.TenantID, req.GiftCardID, updates)
-	if err != nil {
-		return nil, fmt.Errorf("failed to update gift card: %w", err)
-	}
-
-	// Record transaction
-	transaction := &GiftCardTransaction{
-		ID:          uuid.New(),
-		TenantID:    req.TenantID,
-		GiftCardID:  req.GiftCardID,
-		Type:        "credit",
+	transaction, err := s.repo.Ledger().PostGiftCard(ctx, req.TenantID, req.GiftCardID, LedgerEntry{
+		Type:        LedgerCredit,
 		Amount:      req.Amount,
 		Description: req.Description,
 		ProcessedBy: &req.ProcessedBy,
-		CreatedAt:   time.Now(),
-	}
-
-	err = s.repo.CreateGiftCardTransaction(ctx, transaction)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to record gift card transaction: %w", err)
+		return nil, fmt.Errorf("failed to refill gift card: %w", err)
 	}
 
 	return transaction, nil
@@ -881,38 +1179,29 @@ func (s *service) AddStoreCredit(ctx context.Context, req AddStoreCreditRequest)
 		}
 	}
 
-	// Add to balance
-	newBalance := storeCredit.CurrentBalance + req.Amount
-
-	// Update store credit balance
-	updates := map[string]interface{}{
-		"current_balance": newBalance,
-		"updated_at":      time.Now(),
-	}
-
-	err = s.repo.UpdateStoreCredit(ctx, req.TenantID, storeCredit.ID, updates)
-	if err != nil {
-		return nil, fmt.Errorf("failed to update store credit: %w", err)
+	// Credit granted with an expiry extends the account's expiry
+	if req.ExpiresAt != nil && (storeCredit.ExpiresAt == nil || req.ExpiresAt.After(*storeCredit.ExpiresAt)) {
+		if err := s.repo.UpdateStoreCredit(ctx, req.TenantID, req.CustomerID, map[string]interface{}{"expires_at": req.ExpiresAt}); err != nil {
+			return nil, fmt.Errorf("failed to update store credit: %w", err)
+		}
 	}
 
-	// Record transaction
-	transaction := &StoreCreditTransaction{
-		ID:            uuid.New(),
-		TenantID:      req.TenantID,
-		StoreCreditID: storeCredit.ID,
-		CustomerID:    req.CustomerID,
-		Type:          "credit",
-		Amount:        req.Amount,
-		Description:   req.Description,
-		RefundID:      req.RefundID,
-		ReturnID:      req.ReturnID,
-		ProcessedBy:   req.ProcessedBy,
-		CreatedAt:     time.Now(),
+	entryType := LedgerCredit
+	if req.RefundID != nil || req.ReturnID != nil {
+		entryType = LedgerRefund
 	}
 
-	err = s.repo.CreateStoreCreditTransaction(ctx, transaction)
+	transaction, err := s.repo.Ledger().PostStoreCredit(ctx, req.TenantID, storeCredit.ID, LedgerEntry{
+		Type:        entryType,
+		Amount:      req.Amount,
+		Description: req.Description,
+		RefundID:    req.RefundID,
+		ReturnID:    req.ReturnID,
+		Reference:   req.Reference,
+		ProcessedBy: req.ProcessedBy,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to record store credit transaction: %w", err)
+		return nil, fmt.Errorf("failed to add store credit: %w", err)
 	}
 
 	return transaction, nil
@@ -925,44 +1214,135 @@ func (s *service) UseStoreCredit(ctx context.Context, req UseStoreCreditRequest)
 		return nil, fmt.Errorf("store credit not found: %w", err)
 	}
 
-	// Check if sufficient balance
-	if req.Amount > storeCredit.CurrentBalance {
-		return nil, fmt.Errorf("insufficient store credit balance: requested %.2f, available %.2f", req.Amount, storeCredit.CurrentBalance)
+	// The ledger locks the account and re-checks the available balance
+	transaction, err := s.repo.Ledger().PostStoreCredit(ctx, req.TenantID, storeCredit.ID, LedgerEntry{
+		Type:        LedgerDebit,
+		Amount:      req.Amount,
+		Description: req.Description,
+		OrderID:     &req.OrderID,
+		OrderNumber: req.OrderNumber,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to use store credit: %w", err)
 	}
 
-	// Calculate new balance
-	newBalance := storeCredit.CurrentBalance - req.Amount
+	return transaction, nil
+}
 
-	// Update store credit balance
-	updates := map[string]interface{}{
-		"current_balance": newBalance,
-		"updated_at":      time.Now(),
-	}
+// Ledger methods
 
-	err = s.repo.UpdateStoreCredit(ctx, req.TenantID, storeCredit.ID, updates)
-	if err != nil {
-		return nil, fmt.Errorf("failed to update store credit: %w", err)
-	}
-
-	// Record transaction
-	transaction := &StoreCreditTransaction{
+// HoldBalance reserves part of a gift card or store credit balance until the
+// hold is captured, released or expires
+func (s *service) HoldBalance(ctx context.Context, req HoldBalanceRequest) (*BalanceHold, error) {
+	hold := &BalanceHold{
 		ID:            uuid.New(),
 		TenantID:      req.TenantID,
-		StoreCreditID: storeCredit.ID,
-		CustomerID:    req.CustomerID,
-		Type:          "debit",
+		AccountType:   req.AccountType,
 		Amount:        req.Amount,
-		Description:   req.Description,
-		OrderID:       &req.OrderID,
-		CreatedAt:     time.Now(),
+		Reference:     req.Reference,
+		OrderID:       req.OrderID,
+		OrderNumber:   req.OrderNumber,
+		CustomerID:    req.CustomerID,
+		CustomerEmail: req.CustomerEmail,
+	}
+
+	switch req.AccountType {
+	case AccountGiftCard:
+		giftCard, err := s.repo.GetGiftCardByCode(ctx, req.TenantID, strings.ToUpper(strings.TrimSpace(req.Code)))
+		if err != nil {
+			return nil, fmt.Errorf("gift card not found: %w", err)
+		}
+		hold.AccountID = giftCard.ID
+	case AccountStoreCredit:
+		if req.CustomerID == nil {
+			return nil, fmt.Errorf("customer_id is required to hold store credit")
+		}
+		storeCredit, err := s.repo.GetStoreCredit(ctx, req.TenantID, *req.CustomerID)
+		if err != nil {
+			return nil, fmt.Errorf("store credit not found: %w", err)
+		}
+		hold.AccountID = storeCredit.ID
+	default:
+		return nil, fmt.Errorf("unknown account type %q", req.AccountType)
+	}
+
+	duration := DefaultHoldDuration
+	if req.ExpiresInMinutes > 0 {
+		duration = time.Duration(req.ExpiresInMinutes) * time.Minute
+	}
+	expiresAt := time.Now().Add(duration)
+	hold.ExpiresAt = &expiresAt
+
+	if err := s.repo.Ledger().Hold(ctx, hold); err != nil {
+		return nil, err
 	}
+	return hold, nil
+}
+
+func (s *service) CaptureHold(ctx context.Context, tenantID, holdID uuid.UUID) (*BalanceHold, error) {
+	return s.repo.Ledger().Capture(ctx, tenantID, holdID)
+}
 
-	err = s.repo.CreateStoreCreditTransaction(ctx, transaction)
+func (s *service) ReleaseHold(ctx context.Context, tenantID, holdID uuid.UUID, reason string) (*BalanceHold, error) {
+	return s.repo.Ledger().Release(ctx, tenantID, holdID, reason)
+}
+
+// CaptureOrderHolds spends every open hold for an order once it is paid
+func (s *service) CaptureOrderHolds(ctx context.Context, tenantID, orderID uuid.UUID) ([]BalanceHold, error) {
+	return s.repo.Ledger().CaptureOrder(ctx, tenantID, orderID)
+}
+
+// ReleaseOrderHolds gives back every open hold for an order whose payment
+// failed or that was cancelled before payment
+func (s *service) ReleaseOrderHolds(ctx context.Context, tenantID, orderID uuid.UUID, reason string) ([]BalanceHold, error) {
+	if reason == "" {
+		reason = "Payment failed"
+	}
+	return s.repo.Ledger().ReleaseOrder(ctx, tenantID, orderID, reason)
+}
+
+func (s *service) GetHolds(ctx context.Context, tenantID uuid.UUID, filter HoldFilter) ([]BalanceHold, error) {
+	return s.repo.GetHolds(ctx, tenantID, filter)
+}
+
+// RefundToOriginalSources returns a refund to the gift cards and store credit
+// the order was paid with
+func (s *service) RefundToOriginalSources(ctx context.Context, req RefundBalanceRequest) ([]RefundAllocation, error) {
+	description := req.Reason
+	if description == "" {
+		description = "Order refund"
+	}
+	return s.repo.Ledger().RefundOrder(ctx, req.TenantID, req.OrderID, req.Amount, LedgerEntry{
+		Description: description,
+		RefundID:    req.RefundID,
+		ReturnID:    req.ReturnID,
+		ProcessedBy: req.ProcessedBy,
+	})
+}
+
+// ExpireBalances settles lapsed holds, then forfeits the balance of expired
+// gift cards and store credit
+func (s *service) ExpireBalances(ctx context.Context, tenantID uuid.UUID) (*ExpiryResult, error) {
+	now := time.Now()
+	ledger := s.repo.Ledger()
+	released, captured, err := ledger.ReleaseExpiredHolds(ctx, tenantID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to release expired holds: %w", err)
+	}
+	result, err := ledger.ExpireBalances(ctx, tenantID, now)
 	if err != nil {
-		return nil, fmt.Errorf("failed to record store credit transaction: %w", err)
+		return nil, err
 	}
+	result.HoldsReleased, result.HoldsCaptured = released, captured
+	return result, nil
+}
 
-	return transaction, nil
+func (s *service) GetBreakageReport(ctx context.Context, tenantID uuid.UUID, startDate, endDate time.Time) (*BreakageReport, error) {
+	return s.repo.Ledger().BreakageReport(ctx, tenantID, startDate, endDate)
+}
+
+func (s *service) ReconcileLedgers(ctx context.Context, tenantID uuid.UUID) (*ReconciliationReport, error) {
+	return s.repo.Ledger().Reconcile(ctx, tenantID)
 }
 
 func (s *service) GetStoreCreditTransactions(ctx context.Context, tenantID, customerID uuid.UUID, filter StoreCreditFilter) ([]StoreCreditTransaction, error) {
@@ -0,0 +1,86 @@
+package finance
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Default chart-of-accounts codes used for courier cash-on-delivery bookings
+const (
+	AccountCodeCODReceivable   = "1150"
+	AccountCodeCarrierPayable  = "2150"
+	AccountCodeSalesRevenue    = "4000"
+	AccountCodeShippingExpense = "5200"
+)
+
+// CODLedger books the financial side of courier deliveries: booking COD
+// receivables when a parcel ships, reversing them when it returns to origin
+// and recording carrier return fees
+type CODLedger struct {
+	repo Repository
+}
+
+// NewCODLedger creates a new COD ledger
+func NewCODLedger(repo Repository) *CODLedger {
+	return &CODLedger{repo: repo}
+}
+
+// BookCODReceivable books the sale against the cash the courier will collect
+// for an order shipped cash on delivery
+func (l *CODLedger) BookCODReceivable(ctx context.Context, tenantID, orderID uuid.UUID, reference string, amount float64) error {
+	description := fmt.Sprintf("COD receivable for shipment %s", reference)
+	return l.post(ctx, tenantID, orderID, reference, description, amount, AccountCodeCODReceivable, AccountCodeSalesRevenue, "cod_receivable")
+}
+
+// ReverseCODReceivable reverses the sale booked against the courier's COD
+// collection for an order that was never delivered
+func (l *CODLedger) ReverseCODReceivable(ctx context.Context, tenantID, orderID uuid.UUID, reference string, amount float64) error {
+	description := fmt.Sprintf("COD receivable reversed for returned shipment %s", reference)
+	return l.post(ctx, tenantID, orderID, reference, description, amount, AccountCodeSalesRevenue, AccountCodeCODReceivable, "cod_reversal")
+}
+
+// RecordReturnFee books the carrier's return-to-origin charge as a shipping expense
+func (l *CODLedger) RecordReturnFee(ctx context.Context, tenantID, orderID uuid.UUID, reference string, amount float64) error {
+	description := fmt.Sprintf("Return-to-origin fee for shipment %s", reference)
+	return l.post(ctx, tenantID, orderID, reference, description, amount, AccountCodeShippingExpense, AccountCodeCarrierPayable, "rto_fee")
+}
+
+func (l *CODLedger) post(ctx context.Context, tenantID, orderID uuid.UUID, reference, description string, amount float64, debitCode, creditCode, kind string) error {
+	if amount <= 0 {
+		return nil
+	}
+
+	debit, err := l.repo.GetAccountByCode(ctx, tenantID, debitCode)
+	if err != nil {
+		return fmt.Errorf("account %s is not configured: %w", debitCode, err)
+	}
+	credit, err := l.repo.GetAccountByCode(ctx, tenantID, creditCode)
+	if err != nil {
+		return fmt.Errorf("account %s is not configured: %w", creditCode, err)
+	}
+
+	transactionID := uuid.New()
+	transaction := &Transaction{
+		ID:              transactionID,
+		TenantID:        tenantID,
+		Description:     description,
+		Reference:       reference,
+		Amount:          amount,
+		Type:            TransactionTypeDebit,
+		TransactionDate: time.Now(),
+		OrderID:         &orderID,
+		Metadata:        map[string]interface{}{"source": "shipping", "kind": kind},
+		Entries: []*TransactionEntry{
+			{ID: uuid.New(), TransactionID: transactionID, AccountID: debit.ID, Type: TransactionTypeDebit, Amount: amount, Description: description},
+			{ID: uuid.New(), TransactionID: transactionID, AccountID: credit.ID, Type: TransactionTypeCredit, Amount: amount, Description: description},
+		},
+	}
+
+	if err := l.repo.CreateTransaction(ctx, transaction); err != nil {
+		return fmt.Errorf("failed to create transaction: %w", err)
+	}
+	return nil
+}
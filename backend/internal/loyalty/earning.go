@@ -0,0 +1,734 @@
+package loyalty
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Transaction types used by automatic earning, redemption and expiry
+const (
+	TypeEarned   = "earned"
+	TypeRedeemed = "redeemed"
+	TypeReversed = "reversed"
+	TypeExpired  = "expired"
+	TypeRestored = "restored"
+	TypeAdjusted = "adjusted"
+)
+
+// Transaction statuses. Pending points are earned but cannot be spent until
+// the program's holding period has passed.
+const (
+	TransactionPending  = "pending"
+	TransactionPosted   = "posted"
+	TransactionReversed = "reversed"
+)
+
+// Earning sources
+const (
+	SourceOrder      = "order"
+	SourceFirstOrder = "first_order"
+	SourceBirthday   = "birthday"
+	SourceReview     = "review"
+	SourceReferral   = "referral"
+	SourceCheckout   = "checkout"
+)
+
+const releaseBatchSize = 500
+
+var ErrInvalidSettings = errors.New("invalid loyalty settings")
+
+// EarningRules configures how a program awards points automatically. Zero
+// values switch the corresponding rule off.
+type EarningRules struct {
+	PointsPerUnit       float64            `json:"points_per_unit"`                // Points per currency unit spent
+	CategoryMultipliers map[string]float64 `json:"category_multipliers,omitempty"` // Category ID to multiplier
+	MinOrderAmount      float64            `json:"min_order_amount"`
+	FirstOrderBonus     int                `json:"first_order_bonus"`
+	BirthdayBonus       int                `json:"birthday_bonus"`
+	ReviewBonus         int                `json:"review_bonus"`
+	ReferralBonus       int                `json:"referral_bonus"`
+	PendingDays         int                `json:"pending_days"` // Holding period for order points
+}
+
+// ProgramSettings is the typed view of LoyaltyProgram.Settings
+type ProgramSettings struct {
+	Earning   EarningRules  `json:"earning"`
+	Tiers     TierRules     `json:"tiers"`
+	Points    PointsRules   `json:"points"`
+	Referrals ReferralRules `json:"referrals"`
+}
+
+// Validate checks the rules for values that cannot be applied
+func (r *EarningRules) Validate() error {
+	if r.PointsPerUnit < 0 || r.MinOrderAmount < 0 || r.PendingDays < 0 {
+		return fmt.Errorf("%w: rates, amounts and days cannot be negative", ErrInvalidSettings)
+	}
+	if r.FirstOrderBonus < 0 || r.BirthdayBonus < 0 || r.ReviewBonus < 0 || r.ReferralBonus < 0 {
+		return fmt.Errorf("%w: bonuses cannot be negative", ErrInvalidSettings)
+	}
+	for categoryID, multiplier := range r.CategoryMultipliers {
+		if _, err := uuid.Parse(categoryID); err != nil {
+			return fmt.Errorf("%w: invalid category ID %q", ErrInvalidSettings, categoryID)
+		}
+		if multiplier < 0 {
+			return fmt.Errorf("%w: multiplier for category %s cannot be negative", ErrInvalidSettings, categoryID)
+		}
+	}
+	return nil
+}
+
+// Multiplier returns the multiplier for a category, 1 when none is set
+func (r *EarningRules) Multiplier(categoryID uuid.UUID) float64 {
+	if multiplier, ok := r.CategoryMultipliers[categoryID.String()]; ok {
+		return multiplier
+	}
+	return 1
+}
+
+// Bonus returns the fixed points awarded for a bonus source
+func (r *EarningRules) Bonus(source string) int {
+	switch source {
+	case SourceFirstOrder:
+		return r.FirstOrderBonus
+	case SourceBirthday:
+		return r.BirthdayBonus
+	case SourceReview:
+		return r.ReviewBonus
+	case SourceReferral:
+		return r.ReferralBonus
+	}
+	return 0
+}
+
+// holdsPoints reports whether points from this source wait out the holding
+// period. Birthday, review and referral bonuses are already gated and post
+// straight away.
+func (r *EarningRules) holdsPoints(source string) bool {
+	if r.PendingDays <= 0 {
+		return false
+	}
+	return source == SourceOrder || source == SourceFirstOrder
+}
+
+// ParseSettings decodes the program settings; empty settings mean no rules
+func (p *LoyaltyProgram) ParseSettings() (*ProgramSettings, error) {
+	return parseSettings(p.Settings)
+}
+
+func parseSettings(raw string) (*ProgramSettings, error) {
+	settings := &ProgramSettings{}
+	if raw == "" {
+		return settings, nil
+	}
+	if err := json.Unmarshal([]byte(raw), settings); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSettings, err)
+	}
+	if err := settings.Earning.Validate(); err != nil {
+		return nil, err
+	}
+	if err := settings.Tiers.Validate(); err != nil {
+		return nil, err
+	}
+	if err := settings.Points.Validate(); err != nil {
+		return nil, err
+	}
+	if err := settings.Referrals.Validate(); err != nil {
+		return nil, err
+	}
+	// Points must become spendable before they expire
+	if settings.Points.ExpiryDays > 0 && settings.Points.ExpiryDays <= settings.Earning.PendingDays {
+		return nil, fmt.Errorf("%w: points must expire after the %d day pending period", ErrInvalidSettings, settings.Earning.PendingDays)
+	}
+	return settings, nil
+}
+
+// withSetting stores one section of the settings JSON, keeping any other keys
+func withSetting(raw, key string, value interface{}) (string, error) {
+	settings := map[string]json.RawMessage{}
+	if raw != "" {
+		if err := json.Unmarshal([]byte(raw), &settings); err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidSettings, err)
+		}
+	}
+
+	section, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	settings[key] = section
+
+	encoded, err := json.Marshal(settings)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// OrderActivity is what the earning engine needs to know about a delivered order
+type OrderActivity struct {
+	TenantID    uuid.UUID
+	OrderID     uuid.UUID
+	UserID      uuid.UUID
+	OrderNumber string
+	Items       []OrderActivityItem
+}
+
+// OrderActivityItem is one order line and what the customer paid for it
+// after discounts
+type OrderActivityItem struct {
+	ProductID uuid.UUID
+	Amount    float64
+}
+
+// ReverseOrderRequest takes back points earned on an order. Amount is the
+// refunded value; zero reverses everything. Reference makes the reversal
+// idempotent, e.g. "return:<id>".
+type ReverseOrderRequest struct {
+	TenantID  uuid.UUID
+	OrderID   uuid.UUID
+	Amount    float64
+	Reference string
+	Reason    string
+}
+
+// AwardBonusRequest awards a fixed bonus from the program's rules, or Points
+// when set. Reference keeps the same bonus from being awarded twice, e.g.
+// "review:<id>".
+type AwardBonusRequest struct {
+	TenantID    uuid.UUID
+	UserID      uuid.UUID
+	Source      string
+	Reference   string
+	Points      int
+	Description string
+}
+
+// ReleaseResult summarises a run of the pending points release job
+type ReleaseResult struct {
+	Released int `json:"released"`
+	Points   int `json:"points"`
+	Failed   int `json:"failed"`
+}
+
+// BirthdayBonusResult summarises a run of the birthday bonus job
+type BirthdayBonusResult struct {
+	Awarded int `json:"awarded"`
+	Skipped int `json:"skipped"`
+	Failed  int `json:"failed"`
+}
+
+// GetEarningRules returns the earning rules configured on a program
+func (s *ServiceImpl) GetEarningRules(ctx context.Context, tenantID, programID uuid.UUID) (*EarningRules, error) {
+	program, err := s.repo.GetProgram(tenantID, programID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := program.ParseSettings()
+	if err != nil {
+		return nil, err
+	}
+	return &settings.Earning, nil
+}
+
+// UpdateEarningRules replaces a program's earning rules
+func (s *ServiceImpl) UpdateEarningRules(ctx context.Context, tenantID, programID uuid.UUID, rules *EarningRules) (*EarningRules, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	program, err := s.repo.GetProgram(tenantID, programID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := withSetting(program.Settings, "earning", rules)
+	if err != nil {
+		return nil, err
+	}
+	// The pending period must still end before points expire
+	if _, err := parseSettings(settings); err != nil {
+		return nil, err
+	}
+	program.Settings = settings
+
+	if _, err := s.repo.UpdateProgram(program); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// EarnForOrder awards points for a delivered order, enrolling the customer in
+// the tenant's active program if needed. Gift cards earn nothing since the
+// points are earned when the card is spent. Returns nil when nothing is earned.
+func (s *ServiceImpl) EarnForOrder(ctx context.Context, activity *OrderActivity) ([]*LoyaltyTransaction, error) {
+	if activity.UserID == uuid.Nil {
+		return nil, nil
+	}
+
+	account, program, err := s.enroll(activity.TenantID, activity.UserID)
+	if err != nil || account == nil {
+		return nil, err
+	}
+
+	settings, err := program.ParseSettings()
+	if err != nil {
+		return nil, err
+	}
+	rules := &settings.Earning
+
+	productIDs := make([]uuid.UUID, 0, len(activity.Items))
+	for _, item := range activity.Items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	products, err := s.repo.GetProducts(activity.TenantID, productIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load order products: %w", err)
+	}
+	multipliers := make(map[uuid.UUID]float64, len(products))
+	for i := range products {
+		if products[i].IsGiftCard() {
+			multipliers[products[i].ID] = 0
+			continue
+		}
+		multipliers[products[i].ID] = rules.Multiplier(products[i].CategoryID)
+	}
+
+	base, earned := 0.0, 0.0
+	for _, item := range activity.Items {
+		multiplier, ok := multipliers[item.ProductID]
+		if !ok {
+			multiplier = 1
+		}
+		if multiplier == 0 || item.Amount <= 0 {
+			continue
+		}
+		base += item.Amount
+		earned += item.Amount * rules.PointsPerUnit * multiplier
+	}
+
+	if base <= 0 || base < rules.MinOrderAmount {
+		return nil, nil
+	}
+
+	// Higher tiers earn faster
+	if tier, err := s.repo.GetTierByCode(activity.TenantID, account.ProgramID, account.Tier); err == nil && tier.EarningMultiplier > 0 {
+		earned *= tier.EarningMultiplier
+	}
+
+	firstOrder := false
+	if rules.FirstOrderBonus > 0 {
+		prior, err := s.repo.CountPriorOrders(activity.TenantID, activity.UserID, activity.OrderID)
+		if err != nil {
+			return nil, err
+		}
+		firstOrder = prior == 0
+	}
+
+	orderID := activity.OrderID
+	var transactions []*LoyaltyTransaction
+	err = s.repo.Transaction(func(repo Repository) error {
+		locked, err := repo.GetAccountForUpdate(activity.TenantID, account.ID)
+		if err != nil {
+			return err
+		}
+
+		if points := int(math.Floor(earned + 1e-9)); points > 0 {
+			transaction, err := s.credit(repo, locked, settings, &LoyaltyTransaction{
+				Source:      SourceOrder,
+				Reference:   "order:" + orderID.String(),
+				Points:      points,
+				BaseAmount:  base,
+				OrderID:     &orderID,
+				Description: fmt.Sprintf("Points for order %s", activity.OrderNumber),
+			})
+			if err != nil {
+				return err
+			}
+			if transaction != nil {
+				transactions = append(transactions, transaction)
+			}
+		}
+
+		if firstOrder {
+			transaction, err := s.credit(repo, locked, settings, &LoyaltyTransaction{
+				Source:      SourceFirstOrder,
+				Reference:   SourceFirstOrder,
+				Points:      rules.FirstOrderBonus,
+				OrderID:     &orderID,
+				Description: fmt.Sprintf("First order bonus for order %s", activity.OrderNumber),
+			})
+			if err != nil {
+				return err
+			}
+			if transaction != nil {
+				transactions = append(transactions, transaction)
+			}
+		}
+
+		if len(transactions) == 0 {
+			return nil
+		}
+		_, err = repo.UpdateAccount(locked)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+// ReverseForOrder takes back points earned on a returned or refunded order.
+// A partial refund reverses points in proportion to the refunded value; the
+// first order bonus is only taken back once the whole order is reversed.
+// Balances never go below zero, even if the points were already spent.
+func (s *ServiceImpl) ReverseForOrder(ctx context.Context, req *ReverseOrderRequest) ([]*LoyaltyTransaction, error) {
+	earnings, err := s.repo.GetOrderEarnings(req.TenantID, req.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	if len(earnings) == 0 {
+		return nil, nil
+	}
+
+	// The order earning goes first so the bonus knows whether it was fully reversed
+	sort.SliceStable(earnings, func(i, j int) bool {
+		return earnings[i].Source == SourceOrder && earnings[j].Source != SourceOrder
+	})
+
+	accountID := earnings[0].AccountID
+	orderID := req.OrderID
+	var reversals []*LoyaltyTransaction
+
+	err = s.repo.Transaction(func(repo Repository) error {
+		account, err := repo.GetAccountForUpdate(req.TenantID, accountID)
+		if err != nil {
+			return err
+		}
+
+		if req.Reference != "" {
+			done, err := repo.HasTransactionReference(req.TenantID, accountID, req.Reference)
+			if err != nil || done {
+				return err
+			}
+		}
+
+		orderFullyReversed := true
+		for _, earning := range earnings {
+			earning, err := repo.GetTransactionForUpdate(req.TenantID, earning.ID)
+			if err != nil {
+				return err
+			}
+			remaining := earning.Points - earning.ReversedPoints
+			if earning.Status == TransactionReversed || remaining <= 0 {
+				continue
+			}
+
+			points := remaining
+			switch earning.Source {
+			case SourceOrder:
+				if req.Amount > 0 && earning.BaseAmount > 0 && req.Amount < earning.BaseAmount-0.005 {
+					points = int(math.Round(float64(earning.Points) * req.Amount / earning.BaseAmount))
+					if points > remaining {
+						points = remaining
+					}
+				}
+				orderFullyReversed = points == remaining
+			case SourceFirstOrder:
+				if !orderFullyReversed {
+					continue
+				}
+			}
+			if points <= 0 {
+				continue
+			}
+
+			if earning.Status == TransactionPending {
+				account.PendingPoints = max(account.PendingPoints-points, 0)
+				earning.RemainingPoints = max(earning.RemainingPoints-points, 0)
+			} else {
+				// Points already spent from this lot come out of the next oldest lots
+				taken := min(points, account.Points)
+				fromLot := min(taken, earning.RemainingPoints)
+				earning.RemainingPoints -= fromLot
+				if taken > fromLot {
+					if _, err := spendLots(repo, account, taken-fromLot, earning.ID); err != nil {
+						return err
+					}
+				}
+				account.Points -= taken
+			}
+			earning.ReversedPoints += points
+			if earning.ReversedPoints >= earning.Points {
+				earning.Status = TransactionReversed
+			}
+			if _, err := repo.UpdateTransaction(earning); err != nil {
+				return err
+			}
+
+			reversal, err := repo.CreateTransaction(&LoyaltyTransaction{
+				TenantID:    req.TenantID,
+				AccountID:   account.ID,
+				Type:        TypeReversed,
+				Status:      TransactionPosted,
+				Source:      earning.Source,
+				Reference:   req.Reference,
+				Points:      -points,
+				OrderID:     &orderID,
+				Description: req.Reason,
+			})
+			if err != nil {
+				return err
+			}
+			reversals = append(reversals, reversal)
+		}
+
+		if len(reversals) == 0 {
+			return nil
+		}
+		_, err = repo.UpdateAccount(account)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reversals, nil
+}
+
+// AwardBonus awards the program's fixed bonus for a source such as a review
+// or referral. Returns nil when the program has no bonus for the source or it
+// was already awarded for the reference.
+func (s *ServiceImpl) AwardBonus(ctx context.Context, req *AwardBonusRequest) (*LoyaltyTransaction, error) {
+	if req.UserID == uuid.Nil {
+		return nil, nil
+	}
+
+	account, program, err := s.enroll(req.TenantID, req.UserID)
+	if err != nil || account == nil {
+		return nil, err
+	}
+
+	settings, err := program.ParseSettings()
+	if err != nil {
+		return nil, err
+	}
+	return s.awardBonus(account.ID, settings, req)
+}
+
+// ReleasePendingPoints makes pending points spendable once their holding
+// period has passed. Meant to run periodically; each run handles one batch.
+func (s *ServiceImpl) ReleasePendingPoints(ctx context.Context, tenantID uuid.UUID, now time.Time) (*ReleaseResult, error) {
+	due, err := s.repo.GetReleasableTransactions(tenantID, now, releaseBatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ReleaseResult{}
+	for _, pending := range due {
+		released := 0
+		err := s.repo.Transaction(func(repo Repository) error {
+			account, err := repo.GetAccountForUpdate(tenantID, pending.AccountID)
+			if err != nil {
+				return err
+			}
+			transaction, err := repo.GetTransactionForUpdate(tenantID, pending.ID)
+			if err != nil {
+				return err
+			}
+			if transaction.Status != TransactionPending {
+				return nil
+			}
+
+			released = transaction.Points - transaction.ReversedPoints
+			transaction.Status = TransactionPosted
+			account.PendingPoints = max(account.PendingPoints-released, 0)
+			account.Points += released
+
+			if _, err := repo.UpdateTransaction(transaction); err != nil {
+				return err
+			}
+			_, err = repo.UpdateAccount(account)
+			return err
+		})
+		if err != nil {
+			result.Failed++
+			continue
+		}
+		result.Released++
+		result.Points += released
+	}
+
+	return result, nil
+}
+
+// AwardBirthdayBonuses awards the birthday bonus to customers whose birthday
+// is today. Customers born on 29 February get theirs on the 28th in other
+// years. Meant to run daily; a second run the same day awards nothing.
+func (s *ServiceImpl) AwardBirthdayBonuses(ctx context.Context, tenantID uuid.UUID, now time.Time) (*BirthdayBonusResult, error) {
+	days := []int{now.Day()}
+	if now.Month() == time.February && now.Day() == 28 && !isLeapYear(now.Year()) {
+		days = append(days, 29)
+	}
+
+	accounts, err := s.repo.GetBirthdayAccounts(tenantID, now.Month(), days)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BirthdayBonusResult{}
+	settingsByProgram := make(map[uuid.UUID]*ProgramSettings)
+	for _, account := range accounts {
+		settings, ok := settingsByProgram[account.ProgramID]
+		if !ok {
+			program, err := s.repo.GetProgram(tenantID, account.ProgramID)
+			if err == nil && program.Status == "active" {
+				settings, _ = program.ParseSettings()
+			}
+			settingsByProgram[account.ProgramID] = settings
+		}
+		if settings == nil || settings.Earning.BirthdayBonus <= 0 {
+			result.Skipped++
+			continue
+		}
+
+		transaction, err := s.awardBonus(account.ID, settings, &AwardBonusRequest{
+			TenantID:    tenantID,
+			UserID:      account.UserID,
+			Source:      SourceBirthday,
+			Reference:   fmt.Sprintf("birthday:%d", now.Year()),
+			Description: "Happy birthday!",
+		})
+		switch {
+		case err != nil:
+			result.Failed++
+		case transaction == nil:
+			result.Skipped++
+		default:
+			result.Awarded++
+		}
+	}
+
+	return result, nil
+}
+
+// enroll returns the customer's account, creating one in the tenant's active
+// program on first activity. Returns a nil account when there is no active
+// program or the account is not active.
+func (s *ServiceImpl) enroll(tenantID, userID uuid.UUID) (*LoyaltyAccount, *LoyaltyProgram, error) {
+	account, err := s.repo.GetAccountByUser(tenantID, userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, err
+	}
+
+	if account == nil {
+		program, err := s.repo.GetActiveProgram(tenantID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		tier, err := s.entryTier(tenantID, program.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		account, err = s.repo.CreateAccount(&LoyaltyAccount{
+			TenantID:  tenantID,
+			UserID:    userID,
+			ProgramID: program.ID,
+			Tier:      tier,
+			Status:    "active",
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		account.Program = program
+	}
+
+	if account.Status != "active" || account.Program == nil || account.Program.Status != "active" {
+		return nil, nil, nil
+	}
+	return account, account.Program, nil
+}
+
+func (s *ServiceImpl) awardBonus(accountID uuid.UUID, settings *ProgramSettings, req *AwardBonusRequest) (*LoyaltyTransaction, error) {
+	points := req.Points
+	if points <= 0 {
+		points = settings.Earning.Bonus(req.Source)
+	}
+	if points <= 0 {
+		return nil, nil
+	}
+
+	var transaction *LoyaltyTransaction
+	err := s.repo.Transaction(func(repo Repository) error {
+		account, err := repo.GetAccountForUpdate(req.TenantID, accountID)
+		if err != nil {
+			return err
+		}
+
+		transaction, err = s.credit(repo, account, settings, &LoyaltyTransaction{
+			Source:      req.Source,
+			Reference:   req.Reference,
+			Points:      points,
+			Description: req.Description,
+		})
+		if err != nil || transaction == nil {
+			return err
+		}
+		_, err = repo.UpdateAccount(account)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// credit records an earning on a locked account as a new lot, holding it back
+// when the rules ask for it. The caller saves the account. Returns nil if the
+// reference was already credited.
+func (s *ServiceImpl) credit(repo Repository, account *LoyaltyAccount, settings *ProgramSettings, transaction *LoyaltyTransaction) (*LoyaltyTransaction, error) {
+	if transaction.Reference != "" {
+		done, err := repo.HasTransactionReference(account.TenantID, account.ID, transaction.Reference)
+		if err != nil || done {
+			return nil, err
+		}
+	}
+
+	transaction.TenantID = account.TenantID
+	transaction.AccountID = account.ID
+	transaction.Type = TypeEarned
+	transaction.Status = TransactionPosted
+	transaction.RemainingPoints = transaction.Points
+	transaction.ExpiresAt = settings.Points.lotExpiry(time.Now())
+
+	rules := &settings.Earning
+	if rules.holdsPoints(transaction.Source) {
+		availableAt := time.Now().AddDate(0, 0, rules.PendingDays)
+		transaction.Status = TransactionPending
+		transaction.AvailableAt = &availableAt
+		account.PendingPoints += transaction.Points
+	} else {
+		account.Points += transaction.Points
+	}
+
+	return repo.CreateTransaction(transaction)
+}
+
+func isLeapYear(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
@@ -0,0 +1,145 @@
+package loyalty
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"ecommerce-saas/internal/order"
+	"ecommerce-saas/internal/user"
+
+	"github.com/google/uuid"
+)
+
+// EventHandler earns, reverses and restores points and tracks referrals in
+// response to order, return, review and signup events. It implements
+// order.EventListener, returns.RefundListener, reviews.ApprovalListener and
+// user.SignupListener.
+type EventHandler struct {
+	service Service
+}
+
+// NewEventHandler creates an event handler backed by the loyalty service
+func NewEventHandler(service Service) *EventHandler {
+	return &EventHandler{service: service}
+}
+
+// OrderDelivered earns points for the delivered order and starts the return
+// window of a referral it qualifies
+func (h *EventHandler) OrderDelivered(ctx context.Context, o *order.Order) error {
+	activity := &OrderActivity{
+		TenantID:    o.TenantID,
+		OrderID:     o.ID,
+		UserID:      o.UserID,
+		OrderNumber: o.OrderNumber,
+		Items:       make([]OrderActivityItem, 0, len(o.Items)),
+	}
+	for _, item := range o.Items {
+		activity.Items = append(activity.Items, OrderActivityItem{
+			ProductID: item.ProductID,
+			Amount:    item.TotalPrice - item.DiscountAmount,
+		})
+	}
+
+	_, earnErr := h.service.EarnForOrder(ctx, activity)
+
+	deliveredAt := time.Now()
+	if o.DeliveredAt != nil {
+		deliveredAt = *o.DeliveredAt
+	}
+	_, referralErr := h.service.QualifyReferral(ctx, &ReferralOrder{
+		TenantID:        o.TenantID,
+		OrderID:         o.ID,
+		UserID:          o.UserID,
+		OrderNumber:     o.OrderNumber,
+		Amount:          o.TotalAmount,
+		ShippingAddress: o.ShippingAddress,
+		DeliveredAt:     deliveredAt,
+	})
+	return errors.Join(earnErr, referralErr)
+}
+
+// OrderCancelled gives back points spent on the order and cancels the
+// referral it was to qualify
+func (h *EventHandler) OrderCancelled(ctx context.Context, o *order.Order) error {
+	reason := fmt.Sprintf("Order %s cancelled", o.OrderNumber)
+	_, restoreErr := h.service.RestoreRedeemedPoints(ctx, o.TenantID, o.ID, reason)
+	_, referralErr := h.service.CancelReferralForOrder(ctx, o.TenantID, o.ID, reason)
+	return errors.Join(restoreErr, referralErr)
+}
+
+// OrderReturned takes back everything earned on the order and cancels the
+// referral it qualified
+func (h *EventHandler) OrderReturned(ctx context.Context, o *order.Order) error {
+	reason := fmt.Sprintf("Order %s returned", o.OrderNumber)
+	_, reverseErr := h.service.ReverseForOrder(ctx, &ReverseOrderRequest{
+		TenantID:  o.TenantID,
+		OrderID:   o.ID,
+		Reference: "order_returned:" + o.ID.String(),
+		Reason:    reason,
+	})
+	_, referralErr := h.service.CancelReferralForOrder(ctx, o.TenantID, o.ID, reason)
+	return errors.Join(reverseErr, referralErr)
+}
+
+// OrderRefunded takes back points in proportion to the refunded amount. Each
+// refund is reversed once, so further partial refunds of the same order take
+// back more. A full refund also cancels the referral the order qualified.
+func (h *EventHandler) OrderRefunded(ctx context.Context, o *order.Order, refundID uuid.UUID, amount float64) error {
+	reason := fmt.Sprintf("Order %s refunded", o.OrderNumber)
+	_, reverseErr := h.service.ReverseForOrder(ctx, &ReverseOrderRequest{
+		TenantID:  o.TenantID,
+		OrderID:   o.ID,
+		Amount:    amount,
+		Reference: "refund:" + refundID.String(),
+		Reason:    reason,
+	})
+
+	var referralErr error
+	if amount >= o.TotalAmount-0.005 {
+		_, referralErr = h.service.CancelReferralForOrder(ctx, o.TenantID, o.ID, reason)
+	}
+	return errors.Join(reverseErr, referralErr)
+}
+
+// ReturnRefunded takes back points for the items refunded by a return
+func (h *EventHandler) ReturnRefunded(ctx context.Context, tenantID, orderID, returnID uuid.UUID, amount float64) error {
+	_, err := h.service.ReverseForOrder(ctx, &ReverseOrderRequest{
+		TenantID:  tenantID,
+		OrderID:   orderID,
+		Amount:    amount,
+		Reference: "return:" + returnID.String(),
+		Reason:    "Points reversed for returned items",
+	})
+	return err
+}
+
+// ReviewApproved awards the review bonus
+func (h *EventHandler) ReviewApproved(ctx context.Context, tenantID, reviewID, userID uuid.UUID) error {
+	_, err := h.service.AwardBonus(ctx, &AwardBonusRequest{
+		TenantID:    tenantID,
+		UserID:      userID,
+		Source:      SourceReview,
+		Reference:   "review:" + reviewID.String(),
+		Description: "Thanks for your review",
+	})
+	return err
+}
+
+// UserRegistered attributes a new customer to the referral code they signed
+// up with
+func (h *EventHandler) UserRegistered(ctx context.Context, u *user.User, signup *user.SignupDetails) error {
+	if signup.ReferralCode == "" || u.TenantID == nil {
+		return nil
+	}
+
+	_, err := h.service.AttributeReferral(ctx, &AttributeReferralRequest{
+		TenantID:  *u.TenantID,
+		Code:      signup.ReferralCode,
+		RefereeID: u.ID,
+		IPAddress: signup.IPAddress,
+		UserAgent: signup.UserAgent,
+	})
+	return err
+}
@@ -1,11 +1,14 @@
 package loyalty
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // Handler handles HTTP requests for loyalty operations
@@ -40,6 +43,10 @@ func (h *Handler) CreateProgram(c *gin.Context) {
 
 	program, err := h.service.CreateProgram(c.Request.Context(), &req)
 	if err != nil {
+		if errors.Is(err, ErrInvalidSettings) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -96,6 +103,10 @@ func (h *Handler) UpdateProgram(c *gin.Context) {
 
 	program, err := h.service.UpdateProgram(c.Request.Context(), tenantID.(uuid.UUID), programID, &req)
 	if err != nil {
+		if errors.Is(err, ErrInvalidSettings) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		if err.Error() == "loyalty program not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
@@ -393,6 +404,10 @@ func (h *Handler) RedeemPoints(c *gin.Context) {
 
 	transaction, err := h.service.RedeemPoints(c.Request.Context(), req)
 	if err != nil {
+		if errors.Is(err, ErrInsufficientPoints) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -736,6 +751,686 @@ func (h *Handler) GetStats(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
+// Automatic Earning
+
+// GetEarningRules returns a program's earning rules
+func (h *Handler) GetEarningRules(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	programID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid program ID"})
+		return
+	}
+
+	rules, err := h.service.GetEarningRules(c.Request.Context(), tenantID.(uuid.UUID), programID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "loyalty program not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// UpdateEarningRules replaces a program's earning rules
+func (h *Handler) UpdateEarningRules(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	programID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid program ID"})
+		return
+	}
+
+	var rules EarningRules
+	if err := c.ShouldBindJSON(&rules); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := h.service.UpdateEarningRules(c.Request.Context(), tenantID.(uuid.UUID), programID, &rules)
+	if err != nil {
+		if errors.Is(err, ErrInvalidSettings) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "loyalty program not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// ReleasePendingPoints makes points whose holding period has passed spendable
+func (h *Handler) ReleasePendingPoints(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	result, err := h.service.ReleasePendingPoints(c.Request.Context(), tenantID.(uuid.UUID), time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// AwardBirthdayBonuses awards today's birthday bonuses
+func (h *Handler) AwardBirthdayBonuses(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	result, err := h.service.AwardBirthdayBonuses(c.Request.Context(), tenantID.(uuid.UUID), time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// Points Expiry and Redemption
+
+// GetPointsRules returns a program's expiry and redemption rules
+func (h *Handler) GetPointsRules(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	programID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid program ID"})
+		return
+	}
+
+	rules, err := h.service.GetPointsRules(c.Request.Context(), tenantID.(uuid.UUID), programID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "loyalty program not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// UpdatePointsRules replaces a program's expiry and redemption rules
+func (h *Handler) UpdatePointsRules(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	programID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid program ID"})
+		return
+	}
+
+	var rules PointsRules
+	if err := c.ShouldBindJSON(&rules); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := h.service.UpdatePointsRules(c.Request.Context(), tenantID.(uuid.UUID), programID, &rules)
+	if err != nil {
+		if errors.Is(err, ErrInvalidSettings) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "loyalty program not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// ExpirePoints forfeits points past their expiry
+func (h *Handler) ExpirePoints(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	result, err := h.service.ExpirePoints(c.Request.Context(), tenantID.(uuid.UUID), time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// SendExpiryReminders reminds customers of points that expire soon
+func (h *Handler) SendExpiryReminders(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	result, err := h.service.SendExpiryReminders(c.Request.Context(), tenantID.(uuid.UUID), time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// Referrals
+
+// GetReferralRules returns a program's referral rules
+func (h *Handler) GetReferralRules(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	programID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid program ID"})
+		return
+	}
+
+	rules, err := h.service.GetReferralRules(c.Request.Context(), tenantID.(uuid.UUID), programID)
+	if err != nil {
+		h.referralError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// UpdateReferralRules replaces a program's referral rules
+func (h *Handler) UpdateReferralRules(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	programID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid program ID"})
+		return
+	}
+
+	var rules ReferralRules
+	if err := c.ShouldBindJSON(&rules); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := h.service.UpdateReferralRules(c.Request.Context(), tenantID.(uuid.UUID), programID, &rules)
+	if err != nil {
+		h.referralError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// GetReferralCode returns a customer's referral code and link
+func (h *Handler) GetReferralCode(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	var req struct {
+		UserID uuid.UUID `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	code, err := h.service.GetReferralCode(c.Request.Context(), tenantID.(uuid.UUID), req.UserID)
+	if err != nil {
+		h.referralError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, code)
+}
+
+// TrackReferralClick counts a visit through a referral link
+func (h *Handler) TrackReferralClick(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	code, err := h.service.TrackReferralClick(c.Request.Context(), tenantID.(uuid.UUID), c.Param("code"))
+	if err != nil {
+		h.referralError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": code.Code, "link": code.Link})
+}
+
+// AttributeReferral attributes a new customer to a referral code, e.g. one
+// entered with their first order
+func (h *Handler) AttributeReferral(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	var req AttributeReferralRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.TenantID = tenantID.(uuid.UUID)
+	req.IPAddress = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+
+	referral, err := h.service.AttributeReferral(c.Request.Context(), &req)
+	if err != nil {
+		h.referralError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, referral)
+}
+
+// ListReferrals lists referrals, optionally by status or referrer
+func (h *Handler) ListReferrals(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	var req ListReferralsRequest
+	if status := c.Query("status"); status != "" {
+		req.Status = &status
+	}
+	if referrerIDStr := c.Query("referrer_id"); referrerIDStr != "" {
+		if referrerID, err := uuid.Parse(referrerIDStr); err == nil {
+			req.ReferrerID = &referrerID
+		}
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil {
+			req.Limit = limit
+		}
+	}
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if offset, err := strconv.Atoi(offsetStr); err == nil {
+			req.Offset = offset
+		}
+	}
+
+	response, err := h.service.ListReferrals(c.Request.Context(), tenantID.(uuid.UUID), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetReferral retrieves a referral by ID
+func (h *Handler) GetReferral(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	referralID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid referral ID"})
+		return
+	}
+
+	referral, err := h.service.GetReferral(c.Request.Context(), tenantID.(uuid.UUID), referralID)
+	if err != nil {
+		h.referralError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, referral)
+}
+
+// RejectReferral stops a referral from being rewarded
+func (h *Handler) RejectReferral(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	referralID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid referral ID"})
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	referral, err := h.service.RejectReferral(c.Request.Context(), tenantID.(uuid.UUID), referralID, req.Reason)
+	if err != nil {
+		h.referralError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, referral)
+}
+
+// IssueReferralRewards rewards referrals whose return window has closed
+func (h *Handler) IssueReferralRewards(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	result, err := h.service.IssueReferralRewards(c.Request.Context(), tenantID.(uuid.UUID), time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// Loyalty Tiers
+
+// ListTiers lists a program's tiers from the lowest rank up
+func (h *Handler) ListTiers(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	programID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid program ID"})
+		return
+	}
+
+	tiers, err := h.service.ListTiers(c.Request.Context(), tenantID.(uuid.UUID), programID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tiers)
+}
+
+// CreateTier adds a tier to a program
+func (h *Handler) CreateTier(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	programID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid program ID"})
+		return
+	}
+
+	var req CreateTierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.TenantID = tenantID.(uuid.UUID)
+	req.ProgramID = programID
+
+	tier, err := h.service.CreateTier(c.Request.Context(), &req)
+	if err != nil {
+		h.tierError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, tier)
+}
+
+// GetTier retrieves a tier by ID
+func (h *Handler) GetTier(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	tierID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tier ID"})
+		return
+	}
+
+	tier, err := h.service.GetTier(c.Request.Context(), tenantID.(uuid.UUID), tierID)
+	if err != nil {
+		h.tierError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tier)
+}
+
+// UpdateTier updates a tier's thresholds and benefits
+func (h *Handler) UpdateTier(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	tierID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tier ID"})
+		return
+	}
+
+	var req UpdateTierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tier, err := h.service.UpdateTier(c.Request.Context(), tenantID.(uuid.UUID), tierID, &req)
+	if err != nil {
+		h.tierError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tier)
+}
+
+// DeleteTier deletes a tier no account holds
+func (h *Handler) DeleteTier(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	tierID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tier ID"})
+		return
+	}
+
+	if err := h.service.DeleteTier(c.Request.Context(), tenantID.(uuid.UUID), tierID); err != nil {
+		h.tierError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// GetTierRules returns how a program's accounts qualify for tiers
+func (h *Handler) GetTierRules(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	programID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid program ID"})
+		return
+	}
+
+	rules, err := h.service.GetTierRules(c.Request.Context(), tenantID.(uuid.UUID), programID)
+	if err != nil {
+		h.tierError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// UpdateTierRules replaces a program's tier qualification rules
+func (h *Handler) UpdateTierRules(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	programID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid program ID"})
+		return
+	}
+
+	var rules TierRules
+	if err := c.ShouldBindJSON(&rules); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := h.service.UpdateTierRules(c.Request.Context(), tenantID.(uuid.UUID), programID, &rules)
+	if err != nil {
+		h.tierError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// EvaluateAccountTier re-evaluates one account's tier now
+func (h *Handler) EvaluateAccountTier(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	accountID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return
+	}
+
+	evaluation, err := h.service.EvaluateAccountTier(c.Request.Context(), tenantID.(uuid.UUID), accountID, time.Now())
+	if err != nil {
+		h.tierError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, evaluation)
+}
+
+// EvaluateTiers re-evaluates the tiers of all active accounts
+func (h *Handler) EvaluateTiers(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	result, err := h.service.EvaluateTiers(c.Request.Context(), tenantID.(uuid.UUID), time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// tierError maps tier errors to HTTP responses
+func (h *Handler) tierError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, ErrInvalidSettings), errors.Is(err, ErrNoTiers):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrTierCodeTaken), errors.Is(err, ErrTierInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (h *Handler) referralError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, ErrReferralCodeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, ErrInvalidSettings), errors.Is(err, ErrReferralsDisabled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrAlreadyReferred), errors.Is(err, ErrNotNewCustomer), errors.Is(err, ErrReferralClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
 // RegisterRoutes registers all loyalty routes
 func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 	loyalty := router.Group("/loyalty")
@@ -749,6 +1444,16 @@ func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 			programs.PUT("/:id", h.UpdateProgram)
 			programs.DELETE("/:id", h.DeleteProgram)
 			programs.GET("/:id/rewards", h.ListProgramRewards)
+			programs.GET("/:id/rules", h.GetEarningRules)
+			programs.PUT("/:id/rules", h.UpdateEarningRules)
+			programs.GET("/:id/tiers", h.ListTiers)
+			programs.POST("/:id/tiers", h.CreateTier)
+			programs.GET("/:id/tier-rules", h.GetTierRules)
+			programs.PUT("/:id/tier-rules", h.UpdateTierRules)
+			programs.GET("/:id/points-rules", h.GetPointsRules)
+			programs.PUT("/:id/points-rules", h.UpdatePointsRules)
+			programs.GET("/:id/referral-rules", h.GetReferralRules)
+			programs.PUT("/:id/referral-rules", h.UpdateReferralRules)
 		}
 
 		// Loyalty Accounts
@@ -756,12 +1461,38 @@ func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 		{
 			accounts.POST("", h.CreateAccount)
 			accounts.GET("", h.ListAccounts)
+			accounts.POST("/release-pending", h.ReleasePendingPoints) // Run periodically
+			accounts.POST("/birthday-bonuses", h.AwardBirthdayBonuses) // Run daily
+			accounts.POST("/evaluate-tiers", h.EvaluateTiers)          // Run nightly
+			accounts.POST("/expire-points", h.ExpirePoints)            // Run daily
+			accounts.POST("/expiry-reminders", h.SendExpiryReminders)  // Run daily
 			accounts.GET("/:id", h.GetAccount)
 			accounts.PUT("/:id", h.UpdateAccount)
 			accounts.POST("/:id/earn", h.EarnPoints)
 			accounts.POST("/:id/redeem", h.RedeemPoints)
 			accounts.POST("/:id/redeem-reward", h.RedeemReward)
 			accounts.GET("/:id/transactions", h.ListAccountTransactions)
+			accounts.POST("/:id/evaluate-tier", h.EvaluateAccountTier)
+		}
+
+		// Loyalty Tiers
+		tiers := loyalty.Group("/tiers")
+		{
+			tiers.GET("/:id", h.GetTier)
+			tiers.PUT("/:id", h.UpdateTier)
+			tiers.DELETE("/:id", h.DeleteTier)
+		}
+
+		// Referrals
+		referrals := loyalty.Group("/referrals")
+		{
+			referrals.GET("", h.ListReferrals)
+			referrals.POST("/codes", h.GetReferralCode)
+			referrals.POST("/track/:code", h.TrackReferralClick)
+			referrals.POST("/attribute", h.AttributeReferral)
+			referrals.POST("/issue-rewards", h.IssueReferralRewards) // Run daily
+			referrals.GET("/:id", h.GetReferral)
+			referrals.POST("/:id/reject", h.RejectReferral)
 		}
 
 		// Loyalty Transactions
@@ -27,9 +27,17 @@ type LoyaltyAccount struct {
 	TenantID  uuid.UUID `json:"tenant_id" gorm:"type:uuid;not null;index"`
 	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
 	ProgramID uuid.UUID `json:"program_id" gorm:"type:uuid;not null"`
-	Points    int       `json:"points" gorm:"default:0"`
+	Points    int       `json:"points" gorm:"default:0"` // Available to spend
+	PendingPoints int   `json:"pending_points" gorm:"default:0"` // Earned but not yet spendable
 	Tier      string    `json:"tier" gorm:"default:'bronze'"`
 	Status    string    `json:"status" gorm:"default:'active'"`
+	Birthday  *time.Time `json:"birthday,omitempty"` // Only month and day are used
+
+	// Tier qualification, refreshed by tier evaluation
+	TierQualifyingValue float64    `json:"tier_qualifying_value"` // Points or spend within the window
+	TierGraceUntil      *time.Time `json:"tier_grace_until,omitempty"` // Current tier is kept until then
+	TierChangedAt       *time.Time `json:"tier_changed_at,omitempty"`
+	TierEvaluatedAt     *time.Time `json:"tier_evaluated_at,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
@@ -43,11 +51,24 @@ type LoyaltyTransaction struct {
 	ID          uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
 	TenantID    uuid.UUID `json:"tenant_id" gorm:"type:uuid;not null;index"`
 	AccountID   uuid.UUID `json:"account_id" gorm:"type:uuid;not null"`
-	Type        string    `json:"type" gorm:"not null"` // earned, redeemed, expired, adjusted
+	Type        string    `json:"type" gorm:"not null"` // earned, redeemed, expired, adjusted, reversed
 	Points      int       `json:"points" gorm:"not null"`
 	Description string    `json:"description"`
-	OrderID     *uuid.UUID `json:"order_id,omitempty" gorm:"type:uuid"`
+	OrderID     *uuid.UUID `json:"order_id,omitempty" gorm:"type:uuid;index"`
 	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+
+	Status      string    `json:"status" gorm:"default:'posted';index"` // pending, posted, reversed
+	Source      string    `json:"source,omitempty" gorm:"index"` // order, first_order, birthday, review, referral
+	Reference   string    `json:"reference,omitempty" gorm:"index"` // Keeps automatic awards from repeating
+	AvailableAt *time.Time `json:"available_at,omitempty"` // When pending points become spendable
+	BaseAmount  float64   `json:"base_amount,omitempty"` // Order value the points were earned on
+	ReversedPoints int    `json:"reversed_points,omitempty"` // Taken back after returns and refunds
+
+	// Credits are lots that are spent oldest first and expire on their own
+	RemainingPoints int        `json:"remaining_points,omitempty" gorm:"default:0;index"` // Not yet spent, expired or reversed
+	ReminderSentAt  *time.Time `json:"reminder_sent_at,omitempty"` // Expiry reminder
+	CashValue       float64    `json:"cash_value,omitempty"` // What redeemed points paid for
+	Lots            []LotAllocation `json:"lots,omitempty" gorm:"serializer:json"` // Lots a debit drew from
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 
@@ -55,6 +76,13 @@ type LoyaltyTransaction struct {
 	Account *LoyaltyAccount `json:"account,omitempty" gorm:"foreignKey:AccountID"`
 }
 
+// LotAllocation is the part of a debit taken from one lot. LotID is nil for
+// points held before lots were tracked.
+type LotAllocation struct {
+	LotID  *uuid.UUID `json:"lot_id,omitempty"`
+	Points int        `json:"points"`
+}
+
 // LoyaltyReward represents available rewards
 type LoyaltyReward struct {
 	ID          uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
@@ -74,6 +102,83 @@ type LoyaltyReward struct {
 	Program *LoyaltyProgram `json:"program,omitempty" gorm:"foreignKey:ProgramID"`
 }
 
+// LoyaltyTier defines a membership level of a program. Accounts reach a tier
+// when their qualifying points or spend within the program's window meets
+// the threshold.
+type LoyaltyTier struct {
+	ID                uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TenantID          uuid.UUID `json:"tenant_id" gorm:"type:uuid;not null;index"`
+	ProgramID         uuid.UUID `json:"program_id" gorm:"type:uuid;not null;index"`
+	Name              string    `json:"name" gorm:"not null"`
+	Code              string    `json:"code" gorm:"not null"` // Stored on accounts, e.g. "gold"
+	Rank              int       `json:"rank" gorm:"not null"` // Higher ranks are better tiers
+	Threshold         float64   `json:"threshold" gorm:"default:0"`
+	EarningMultiplier float64   `json:"earning_multiplier" gorm:"default:1"` // Applied to points earned on orders
+	FreeShipping      bool      `json:"free_shipping" gorm:"default:false"`
+	Benefits          string    `json:"benefits"` // Shown to customers
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// ReferralCode is a customer's personal invite code. Shared links carry the
+// code so clicks and signups can be attributed to the customer.
+type ReferralCode struct {
+	ID            uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TenantID      uuid.UUID  `json:"tenant_id" gorm:"type:uuid;not null;uniqueIndex:idx_referral_codes_code"`
+	ProgramID     uuid.UUID  `json:"program_id" gorm:"type:uuid;not null;uniqueIndex:idx_referral_codes_user"`
+	UserID        uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_referral_codes_user"` // One code per customer and program
+	Code          string     `json:"code" gorm:"not null;uniqueIndex:idx_referral_codes_code"`
+	Link          string     `json:"link,omitempty" gorm:"-"` // Built from the program's link URL
+	Clicks        int        `json:"clicks" gorm:"default:0"`
+	Signups       int        `json:"signups" gorm:"default:0"`
+	LastClickedAt *time.Time `json:"last_clicked_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// Referral attributes a new customer (the referee) to the customer whose code
+// they used (the referrer). Both are rewarded once the referee's first order
+// is delivered and its return window has passed.
+type Referral struct {
+	ID                uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TenantID          uuid.UUID  `json:"tenant_id" gorm:"type:uuid;not null;uniqueIndex:idx_referrals_referee"`
+	ProgramID         uuid.UUID  `json:"program_id" gorm:"type:uuid;not null"`
+	CodeID            uuid.UUID  `json:"code_id" gorm:"type:uuid;not null;index"`
+	ReferrerID        uuid.UUID  `json:"referrer_id" gorm:"type:uuid;not null;index"`
+	RefereeID         uuid.UUID  `json:"referee_id" gorm:"type:uuid;not null;uniqueIndex:idx_referrals_referee"` // A customer is only ever referred once
+	Status            string     `json:"status" gorm:"not null;index"` // pending, qualified, rewarded, rejected, cancelled
+	AttributedOn      string     `json:"attributed_on"` // signup or first_order
+	IPAddress         string     `json:"ip_address,omitempty"`
+	DeviceFingerprint string     `json:"device_fingerprint,omitempty"`
+	FraudFlags        []string   `json:"fraud_flags,omitempty" gorm:"serializer:json"`
+	Reason            string     `json:"reason,omitempty"` // Why it was rejected or cancelled
+
+	// Qualifying order
+	OrderID     *uuid.UUID `json:"order_id,omitempty" gorm:"type:uuid;index"`
+	OrderNumber string     `json:"order_number,omitempty"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+	EligibleAt  *time.Time `json:"eligible_at,omitempty" gorm:"index"` // When the return window closes
+
+	// Rewards, set as each side is issued
+	ReferrerReward *IssuedReward `json:"referrer_reward,omitempty" gorm:"serializer:json"`
+	RefereeReward  *IssuedReward `json:"referee_reward,omitempty" gorm:"serializer:json"`
+	RewardedAt     *time.Time    `json:"rewarded_at,omitempty"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+}
+
+// IssuedReward records what one side of a referral received
+type IssuedReward struct {
+	Type          string     `json:"type"` // points, store_credit or coupon
+	Points        int        `json:"points,omitempty"`
+	Amount        float64    `json:"amount,omitempty"`
+	Currency      string     `json:"currency,omitempty"`       // Store credit
+	Code          string     `json:"code,omitempty"`           // Coupon code
+	TransactionID *uuid.UUID `json:"transaction_id,omitempty"` // Points or store credit transaction
+	IssuedAt      time.Time  `json:"issued_at"`
+}
+
 // Request/Response DTOs
 
 // Program requests
@@ -114,9 +219,10 @@ type CreateAccountRequest struct {
 }
 
 type UpdateAccountRequest struct {
-	TenantID uuid.UUID `json:"tenant_id"`
-	Status   *string   `json:"status,omitempty"`
-	Tier     *string   `json:"tier,omitempty"`
+	TenantID uuid.UUID  `json:"tenant_id"`
+	Status   *string    `json:"status,omitempty"`
+	Tier     *string    `json:"tier,omitempty"`
+	Birthday *time.Time `json:"birthday,omitempty"`
 }
 
 type ListAccountsRequest struct {
@@ -169,6 +275,28 @@ type ListAccountTransactionsResponse struct {
 	Total        int64                `json:"total"`
 }
 
+// Tier requests
+type CreateTierRequest struct {
+	TenantID          uuid.UUID `json:"-"`
+	ProgramID         uuid.UUID `json:"-"`
+	Name              string    `json:"name" binding:"required"`
+	Code              string    `json:"code" binding:"required"`
+	Rank              int       `json:"rank" binding:"min=0"`
+	Threshold         float64   `json:"threshold" binding:"min=0"`
+	EarningMultiplier float64   `json:"earning_multiplier" binding:"min=0"`
+	FreeShipping      bool      `json:"free_shipping"`
+	Benefits          string    `json:"benefits"`
+}
+
+type UpdateTierRequest struct {
+	Name              *string  `json:"name"`
+	Rank              *int     `json:"rank" binding:"omitempty,min=0"`
+	Threshold         *float64 `json:"threshold" binding:"omitempty,min=0"`
+	EarningMultiplier *float64 `json:"earning_multiplier" binding:"omitempty,min=0"`
+	FreeShipping      *bool    `json:"free_shipping"`
+	Benefits          *string  `json:"benefits"`
+}
+
 // Reward requests
 type CreateRewardRequest struct {
 	TenantID    uuid.UUID `json:"tenant_id"`
@@ -253,6 +381,28 @@ type RedeemRewardRequest struct {
 	RewardID uuid.UUID `json:"reward_id" binding:"required"`
 }
 
+// Referral requests
+type AttributeReferralRequest struct {
+	TenantID  uuid.UUID  `json:"tenant_id"`
+	Code      string     `json:"code" binding:"required"`
+	RefereeID uuid.UUID  `json:"referee_id" binding:"required"`
+	OrderID   *uuid.UUID `json:"order_id,omitempty"` // Set when the code is entered on the first order
+	IPAddress string     `json:"-"` // As seen by the server; the device fingerprint is derived from both
+	UserAgent string     `json:"-"`
+}
+
+type ListReferralsRequest struct {
+	Status     *string    `json:"status"`
+	ReferrerID *uuid.UUID `json:"referrer_id"`
+	Limit      int        `json:"limit"`
+	Offset     int        `json:"offset"`
+}
+
+type ListReferralsResponse struct {
+	Referrals []*Referral `json:"referrals"`
+	Total     int64       `json:"total"`
+}
+
 // Legacy types for backward compatibility
 type CreateLoyaltyProgramRequest = CreateProgramRequest
 type UpdateLoyaltyProgramRequest = UpdateProgramRequest
@@ -283,4 +433,15 @@ func (LoyaltyTransaction) TableName() string {
 
 func (LoyaltyReward) TableName() string {
 	return "loyalty_rewards"
-}
\ No newline at end of file
+}
+
+func (LoyaltyTier) TableName() string {
+	return "loyalty_tiers"
+}
+func (ReferralCode) TableName() string {
+	return "loyalty_referral_codes"
+}
+
+func (Referral) TableName() string {
+	return "loyalty_referrals"
+}
@@ -3,6 +3,8 @@ package loyalty
 import (
 	"gorm.io/gorm"
 	"github.com/gin-gonic/gin"
+	"ecommerce-saas/internal/discount"
+	"ecommerce-saas/internal/notification"
 )
 
 // Module represents the loyalty module
@@ -18,7 +20,8 @@ func NewModule(db *gorm.DB) *Module {
 	repo := NewGormRepository(db)
 
 	// Initialize service
-	service := NewService(repo)
+	notifications := notification.NewService(notification.NewRepository(db))
+	service := NewService(repo, notifications, discount.NewService(discount.NewRepository(db), notifications, nil))
 
 	// Initialize handler
 	handler := NewHandler(service)
@@ -42,5 +45,8 @@ func (m *Module) Migrate(db *gorm.DB) error {
 		&LoyaltyAccount{},
 		&LoyaltyTransaction{},
 		&LoyaltyReward{},
+		&LoyaltyTier{},
+		&ReferralCode{},
+		&Referral{},
 	)
 }
\ No newline at end of file
@@ -0,0 +1,557 @@
+package loyalty
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"ecommerce-saas/internal/notification"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const expiryBatchSize = 500
+
+var (
+	ErrInsufficientPoints  = errors.New("insufficient points")
+	ErrPointsNotRedeemable = errors.New("points cannot be redeemed")
+)
+
+// PointsRules configures when points expire and what they are worth when
+// spent at checkout. Zero values switch the corresponding rule off.
+type PointsRules struct {
+	ExpiryDays      int     `json:"expiry_days"`       // Each lot expires this long after it is earned
+	ReminderDays    int     `json:"reminder_days"`     // How long before expiry customers are reminded
+	PointValue      float64 `json:"point_value"`       // Currency value of one point at checkout
+	MaxOrderPercent float64 `json:"max_order_percent"` // Share of an order points may pay for; 0 allows all of it
+	MinRedeemPoints int     `json:"min_redeem_points"`
+}
+
+// Validate checks the rules for values that cannot be applied
+func (r *PointsRules) Validate() error {
+	if r.ExpiryDays < 0 || r.ReminderDays < 0 || r.PointValue < 0 || r.MinRedeemPoints < 0 {
+		return fmt.Errorf("%w: days, values and minimums cannot be negative", ErrInvalidSettings)
+	}
+	if r.MaxOrderPercent < 0 || r.MaxOrderPercent > 100 {
+		return fmt.Errorf("%w: max order percent must be between 0 and 100", ErrInvalidSettings)
+	}
+	if r.ExpiryDays > 0 && r.ReminderDays >= r.ExpiryDays {
+		return fmt.Errorf("%w: reminders must be sent before points expire", ErrInvalidSettings)
+	}
+	return nil
+}
+
+// Redeemable reports whether points can be spent at checkout
+func (r *PointsRules) Redeemable() bool {
+	return r.PointValue > 0
+}
+
+// lotExpiry returns when a lot earned at the given time expires, nil if
+// points never expire
+func (r *PointsRules) lotExpiry(earnedAt time.Time) *time.Time {
+	if r.ExpiryDays <= 0 {
+		return nil
+	}
+	expiresAt := earnedAt.AddDate(0, 0, r.ExpiryDays)
+	return &expiresAt
+}
+
+// PointsQuote is what a customer's points are worth against an order
+type PointsQuote struct {
+	AccountID  uuid.UUID `json:"account_id"`
+	Balance    int       `json:"balance"`
+	PointValue float64   `json:"point_value"`
+	MaxPoints  int       `json:"max_points"` // Most points the order can take
+	Points     int       `json:"points"`     // Points that will be used
+	Amount     float64   `json:"amount"`
+}
+
+// PointsRedemption spends points as payment for an order. Reference keeps a
+// retried checkout from spending twice.
+type PointsRedemption struct {
+	TenantID    uuid.UUID
+	UserID      uuid.UUID
+	Points      int
+	Amount      float64
+	OrderID     uuid.UUID
+	OrderNumber string
+	Reference   string
+}
+
+// PointsExpiryResult summarises a run of the points expiry job
+type PointsExpiryResult struct {
+	Expired int `json:"expired"`
+	Points  int `json:"points"`
+	Failed  int `json:"failed"`
+}
+
+// ExpiryReminderResult summarises a run of the expiry reminder job
+type ExpiryReminderResult struct {
+	Sent   int `json:"sent"`
+	Failed int `json:"failed"`
+}
+
+// GetPointsRules returns the expiry and redemption rules of a program
+func (s *ServiceImpl) GetPointsRules(ctx context.Context, tenantID, programID uuid.UUID) (*PointsRules, error) {
+	program, err := s.repo.GetProgram(tenantID, programID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := program.ParseSettings()
+	if err != nil {
+		return nil, err
+	}
+	return &settings.Points, nil
+}
+
+// UpdatePointsRules replaces a program's expiry and redemption rules. New
+// expiry periods apply to points earned from then on.
+func (s *ServiceImpl) UpdatePointsRules(ctx context.Context, tenantID, programID uuid.UUID, rules *PointsRules) (*PointsRules, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	program, err := s.repo.GetProgram(tenantID, programID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := withSetting(program.Settings, "points", rules)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parseSettings(settings); err != nil {
+		return nil, err
+	}
+	program.Settings = settings
+
+	if _, err := s.repo.UpdateProgram(program); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// QuotePoints values up to the requested points against an order amount
+func (s *ServiceImpl) QuotePoints(ctx context.Context, tenantID, userID uuid.UUID, points int, orderAmount float64) (*PointsQuote, error) {
+	return quotePoints(s.repo, tenantID, userID, points, orderAmount)
+}
+
+// ExpirePoints forfeits what is left of lots past their expiry. Meant to run
+// daily; each run handles one batch.
+func (s *ServiceImpl) ExpirePoints(ctx context.Context, tenantID uuid.UUID, now time.Time) (*PointsExpiryResult, error) {
+	due, err := s.repo.GetExpiredLots(tenantID, now, expiryBatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PointsExpiryResult{}
+	for _, expired := range due {
+		points := 0
+		err := s.repo.Transaction(func(repo Repository) error {
+			account, err := repo.GetAccountForUpdate(tenantID, expired.AccountID)
+			if err != nil {
+				return err
+			}
+			lot, err := repo.GetTransactionForUpdate(tenantID, expired.ID)
+			if err != nil {
+				return err
+			}
+			if lot.Status != TransactionPosted || lot.RemainingPoints <= 0 || lot.ExpiresAt == nil || lot.ExpiresAt.After(now) {
+				return nil
+			}
+
+			points = lot.RemainingPoints
+			lot.RemainingPoints = 0
+			account.Points = max(account.Points-points, 0)
+
+			if _, err := repo.UpdateTransaction(lot); err != nil {
+				return err
+			}
+			lotID := lot.ID
+			if _, err := repo.CreateTransaction(&LoyaltyTransaction{
+				TenantID:    tenantID,
+				AccountID:   account.ID,
+				Type:        TypeExpired,
+				Status:      TransactionPosted,
+				Source:      lot.Source,
+				Reference:   "expiry:" + lot.ID.String(),
+				Points:      -points,
+				Lots:        []LotAllocation{{LotID: &lotID, Points: points}},
+				Description: fmt.Sprintf("%d points expired", points),
+			}); err != nil {
+				return err
+			}
+			_, err = repo.UpdateAccount(account)
+			return err
+		})
+		if err != nil {
+			result.Failed++
+			continue
+		}
+		if points > 0 {
+			result.Expired++
+			result.Points += points
+		}
+	}
+
+	return result, nil
+}
+
+// SendExpiryReminders emails customers whose points expire within their
+// program's reminder window, once per lot. Meant to run daily.
+func (s *ServiceImpl) SendExpiryReminders(ctx context.Context, tenantID uuid.UUID, now time.Time) (*ExpiryReminderResult, error) {
+	result := &ExpiryReminderResult{}
+	if s.notifications == nil {
+		return result, nil
+	}
+
+	programs, err := s.repo.ListActivePrograms(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, program := range programs {
+		settings, err := program.ParseSettings()
+		if err != nil || settings.Points.ExpiryDays <= 0 || settings.Points.ReminderDays <= 0 {
+			continue
+		}
+
+		lots, err := s.repo.GetExpiringLots(tenantID, program.ID, now, now.AddDate(0, 0, settings.Points.ReminderDays))
+		if err != nil {
+			return nil, err
+		}
+
+		// Lots come grouped by account, earliest expiry first
+		for start := 0; start < len(lots); {
+			end := start
+			points := 0
+			lotIDs := []uuid.UUID{}
+			for end < len(lots) && lots[end].AccountID == lots[start].AccountID {
+				points += lots[end].RemainingPoints
+				lotIDs = append(lotIDs, lots[end].ID)
+				end++
+			}
+			first := lots[start]
+			start = end
+
+			account, err := s.repo.GetAccount(tenantID, first.AccountID)
+			if err == nil {
+				err = s.notifyPointsExpiry(account, points, *first.ExpiresAt)
+			}
+			if err == nil {
+				err = s.repo.MarkReminderSent(tenantID, lotIDs, now)
+			}
+			if err != nil {
+				result.Failed++
+				continue
+			}
+			result.Sent++
+		}
+	}
+
+	return result, nil
+}
+
+// RestoreRedeemedPoints gives back points spent on an order that was
+// cancelled. Points return to the lots they came from; points whose lot has
+// expired in the meantime come back as a new lot with the full expiry period.
+func (s *ServiceImpl) RestoreRedeemedPoints(ctx context.Context, tenantID, orderID uuid.UUID, reason string) ([]*LoyaltyTransaction, error) {
+	redemptions, err := s.repo.GetOrderRedemptions(tenantID, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var restorations []*LoyaltyTransaction
+	for _, redemption := range redemptions {
+		err := s.repo.Transaction(func(repo Repository) error {
+			account, err := repo.GetAccountForUpdate(tenantID, redemption.AccountID)
+			if err != nil {
+				return err
+			}
+			locked, err := repo.GetTransactionForUpdate(tenantID, redemption.ID)
+			if err != nil {
+				return err
+			}
+			if locked.Status != TransactionPosted {
+				return nil
+			}
+
+			points := -locked.Points
+			relot := 0
+			for _, allocation := range locked.Lots {
+				if allocation.LotID == nil {
+					continue
+				}
+				lot, err := repo.GetTransactionForUpdate(tenantID, *allocation.LotID)
+				if err != nil {
+					return err
+				}
+				if lot.Status != TransactionPosted || (lot.ExpiresAt != nil && !lot.ExpiresAt.After(now)) {
+					relot += allocation.Points
+					continue
+				}
+				lot.RemainingPoints += allocation.Points
+				if _, err := repo.UpdateTransaction(lot); err != nil {
+					return err
+				}
+			}
+
+			restoration := &LoyaltyTransaction{
+				TenantID:        tenantID,
+				AccountID:       account.ID,
+				Type:            TypeRestored,
+				Status:          TransactionPosted,
+				Source:          SourceCheckout,
+				Reference:       "restore:" + locked.ID.String(),
+				Points:          points,
+				OrderID:         locked.OrderID,
+				RemainingPoints: relot,
+				Description:     reason,
+			}
+			if relot > 0 {
+				restoration.ExpiresAt = programSettings(repo, tenantID, account.ProgramID).Points.lotExpiry(now)
+			}
+
+			locked.Status = TransactionReversed
+			if _, err := repo.UpdateTransaction(locked); err != nil {
+				return err
+			}
+			if _, err := repo.CreateTransaction(restoration); err != nil {
+				return err
+			}
+			restorations = append(restorations, restoration)
+
+			account.Points += points
+			_, err = repo.UpdateAccount(account)
+			return err
+		})
+		if err != nil {
+			return restorations, err
+		}
+	}
+
+	return restorations, nil
+}
+
+// notifyPointsExpiry emails the customer about points that are about to expire
+func (s *ServiceImpl) notifyPointsExpiry(account *LoyaltyAccount, points int, expiresAt time.Time) error {
+	customer, err := s.repo.GetCustomer(account.TenantID, account.UserID)
+	if err != nil {
+		return err
+	}
+
+	variables := map[string]interface{}{
+		"customer_name":  strings.TrimSpace(customer.FirstName + " " + customer.LastName),
+		"points":         points,
+		"expires_at":     expiresAt.Format("2006-01-02"),
+		"points_balance": account.Points,
+	}
+
+	req := &notification.SendNotificationRequest{
+		Type:       notification.TypeEmail,
+		Channel:    notification.ChannelPointsExpiry,
+		Recipients: []string{customer.Email},
+		Variables:  variables,
+		Priority:   notification.PriorityNormal,
+		UserID:     account.UserID.String(),
+	}
+
+	if template := s.template(account.TenantID, notification.ChannelPointsExpiry); template != nil {
+		req.TemplateID = template.ID.String()
+	} else {
+		req.Subject = fmt.Sprintf("%d of your points expire soon", points)
+		req.Content = fmt.Sprintf("Hi %s,\n\n%d of your %d points expire on %s. Use them on your next order before they are gone.",
+			variables["customer_name"], points, account.Points, variables["expires_at"])
+	}
+
+	_, err = s.notifications.SendNotification(account.TenantID, req)
+	return err
+}
+
+// PointsLedger spends points as part of a larger unit of work such as a
+// checkout. Use NewPointsLedger with a transaction so the points are only
+// spent if the order is placed.
+type PointsLedger struct {
+	repo Repository
+}
+
+// NewPointsLedger creates a points ledger on a database handle or transaction
+func NewPointsLedger(db *gorm.DB) *PointsLedger {
+	return &PointsLedger{repo: NewGormRepository(db)}
+}
+
+// Quote values up to the requested points against an order amount
+func (l *PointsLedger) Quote(ctx context.Context, tenantID, userID uuid.UUID, points int, orderAmount float64) (*PointsQuote, error) {
+	return quotePoints(l.repo, tenantID, userID, points, orderAmount)
+}
+
+// Redeem spends points from the customer's oldest lots. A reference that was
+// already redeemed returns nil.
+func (l *PointsLedger) Redeem(ctx context.Context, req *PointsRedemption) (*LoyaltyTransaction, error) {
+	if req.Points <= 0 {
+		return nil, nil
+	}
+
+	account, err := l.repo.GetAccountByUser(req.TenantID, req.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPointsNotRedeemable
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	orderID := req.OrderID
+	var transaction *LoyaltyTransaction
+	err = l.repo.Transaction(func(repo Repository) error {
+		locked, err := repo.GetAccountForUpdate(req.TenantID, account.ID)
+		if err != nil {
+			return err
+		}
+		if locked.Status != "active" {
+			return ErrPointsNotRedeemable
+		}
+		if req.Reference != "" {
+			done, err := repo.HasTransactionReference(req.TenantID, locked.ID, req.Reference)
+			if err != nil || done {
+				return err
+			}
+		}
+		if locked.Points < req.Points {
+			return ErrInsufficientPoints
+		}
+
+		transaction, err = debit(repo, locked, req.Points, &LoyaltyTransaction{
+			Type:        TypeRedeemed,
+			Source:      SourceCheckout,
+			Reference:   req.Reference,
+			OrderID:     &orderID,
+			CashValue:   req.Amount,
+			Description: fmt.Sprintf("Points used on order %s", req.OrderNumber),
+		})
+		if err != nil {
+			return err
+		}
+		_, err = repo.UpdateAccount(locked)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// quotePoints caps the requested points at the balance and the share of the
+// order points may pay for
+func quotePoints(repo Repository, tenantID, userID uuid.UUID, points int, orderAmount float64) (*PointsQuote, error) {
+	account, err := repo.GetAccountByUser(tenantID, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPointsNotRedeemable
+	}
+	if err != nil {
+		return nil, err
+	}
+	if account.Status != "active" || account.Program == nil || account.Program.Status != "active" {
+		return nil, ErrPointsNotRedeemable
+	}
+
+	settings, err := account.Program.ParseSettings()
+	if err != nil {
+		return nil, err
+	}
+	rules := &settings.Points
+	if !rules.Redeemable() {
+		return nil, ErrPointsNotRedeemable
+	}
+
+	payable := math.Max(orderAmount, 0)
+	if rules.MaxOrderPercent > 0 {
+		payable = payable * rules.MaxOrderPercent / 100
+	}
+
+	quote := &PointsQuote{
+		AccountID:  account.ID,
+		Balance:    account.Points,
+		PointValue: rules.PointValue,
+		MaxPoints:  max(min(account.Points, int(math.Floor(payable/rules.PointValue+1e-9))), 0),
+	}
+
+	quote.Points = min(max(points, 0), quote.MaxPoints)
+	if quote.Points > 0 && quote.Points < rules.MinRedeemPoints {
+		return quote, fmt.Errorf("%w: at least %d points must be used", ErrPointsNotRedeemable, rules.MinRedeemPoints)
+	}
+	quote.Amount = math.Min(math.Round(float64(quote.Points)*rules.PointValue*100)/100, payable)
+	return quote, nil
+}
+
+// debit takes points from a locked account, oldest lots first, and records
+// the transaction. The caller checks the balance and saves the account.
+func debit(repo Repository, account *LoyaltyAccount, points int, transaction *LoyaltyTransaction) (*LoyaltyTransaction, error) {
+	lots, err := spendLots(repo, account, points, uuid.Nil)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction.TenantID = account.TenantID
+	transaction.AccountID = account.ID
+	transaction.Status = TransactionPosted
+	transaction.Points = -points
+	transaction.Lots = lots
+	account.Points -= points
+
+	return repo.CreateTransaction(transaction)
+}
+
+// spendLots draws points from the account's lots oldest first, skipping the
+// given lot. Whatever the lots cannot cover comes from points held before
+// lots were tracked. Callers hold the account lock.
+func spendLots(repo Repository, account *LoyaltyAccount, points int, skip uuid.UUID) ([]LotAllocation, error) {
+	lots, err := repo.GetSpendableLots(account.TenantID, account.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var allocations []LotAllocation
+	remaining := points
+	for _, lot := range lots {
+		if remaining <= 0 {
+			break
+		}
+		if lot.ID == skip {
+			continue
+		}
+
+		taken := min(lot.RemainingPoints, remaining)
+		lot.RemainingPoints -= taken
+		if _, err := repo.UpdateTransaction(lot); err != nil {
+			return nil, err
+		}
+		lotID := lot.ID
+		allocations = append(allocations, LotAllocation{LotID: &lotID, Points: taken})
+		remaining -= taken
+	}
+	if remaining > 0 {
+		allocations = append(allocations, LotAllocation{Points: remaining})
+	}
+
+	return allocations, nil
+}
+
+// programSettings returns a program's settings, or none if they cannot be read
+func programSettings(repo Repository, tenantID, programID uuid.UUID) *ProgramSettings {
+	program, err := repo.GetProgram(tenantID, programID)
+	if err != nil {
+		return &ProgramSettings{}
+	}
+	settings, err := program.ParseSettings()
+	if err != nil {
+		return &ProgramSettings{}
+	}
+	return settings
+}
@@ -0,0 +1,349 @@
+package loyalty
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ledgerRepository keeps the accounts, lots and transactions the points
+// ledger touches in memory; other repository methods are not used
+type ledgerRepository struct {
+	Repository
+	account    *LoyaltyAccount
+	lots       []*LoyaltyTransaction
+	created    []*LoyaltyTransaction
+	references map[string]bool
+}
+
+func (r *ledgerRepository) GetAccountByUser(tenantID, userID uuid.UUID) (*LoyaltyAccount, error) {
+	if r.account == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.account, nil
+}
+
+func (r *ledgerRepository) GetSpendableLots(tenantID, accountID uuid.UUID) ([]*LoyaltyTransaction, error) {
+	var lots []*LoyaltyTransaction
+	for _, lot := range r.lots {
+		if lot.RemainingPoints > 0 {
+			lots = append(lots, lot)
+		}
+	}
+	return lots, nil
+}
+
+func (r *ledgerRepository) UpdateTransaction(transaction *LoyaltyTransaction) (*LoyaltyTransaction, error) {
+	return transaction, nil
+}
+
+func (r *ledgerRepository) CreateTransaction(transaction *LoyaltyTransaction) (*LoyaltyTransaction, error) {
+	r.created = append(r.created, transaction)
+	return transaction, nil
+}
+
+func (r *ledgerRepository) HasTransactionReference(tenantID, accountID uuid.UUID, reference string) (bool, error) {
+	return r.references[reference], nil
+}
+
+// lots returns posted lots with the given points left, oldest first
+func lots(remaining ...int) []*LoyaltyTransaction {
+	result := make([]*LoyaltyTransaction, len(remaining))
+	for i, points := range remaining {
+		result[i] = &LoyaltyTransaction{
+			ID:              uuid.UUID{15: byte(i + 1)},
+			Type:            TypeEarned,
+			Status:          TransactionPosted,
+			Points:          points,
+			RemainingPoints: points,
+		}
+	}
+	return result
+}
+
+func TestDebit(t *testing.T) {
+	tests := []struct {
+		name          string
+		balance       int
+		lots          []int
+		points        int
+		wantLots      []int // points left in each lot
+		wantAllocated []int // points taken from each allocation, lotless last
+		wantLotless   int   // points taken from before lots were tracked
+	}{
+		{
+			name:          "oldest lots are spent first",
+			balance:       80,
+			lots:          []int{30, 50},
+			points:        60,
+			wantLots:      []int{0, 20},
+			wantAllocated: []int{30, 30},
+		},
+		{
+			name:          "a lot covering the debit is drawn alone",
+			balance:       80,
+			lots:          []int{30, 50},
+			points:        10,
+			wantLots:      []int{20, 50},
+			wantAllocated: []int{10},
+		},
+		{
+			name:          "points older than lots cover the rest",
+			balance:       40,
+			lots:          []int{10},
+			points:        25,
+			wantLots:      []int{0},
+			wantAllocated: []int{10},
+			wantLotless:   15,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &ledgerRepository{lots: lots(tt.lots...)}
+			account := &LoyaltyAccount{ID: uuid.New(), TenantID: uuid.New(), Points: tt.balance}
+
+			transaction, err := debit(repo, account, tt.points, &LoyaltyTransaction{Type: TypeRedeemed})
+			if err != nil {
+				t.Fatalf("debit: %v", err)
+			}
+
+			if account.Points != tt.balance-tt.points {
+				t.Errorf("balance %d, want %d", account.Points, tt.balance-tt.points)
+			}
+			if transaction.Points != -tt.points || transaction.Status != TransactionPosted {
+				t.Errorf("transaction %d %s, want %d posted", transaction.Points, transaction.Status, -tt.points)
+			}
+			for i, lot := range repo.lots {
+				if lot.RemainingPoints != tt.wantLots[i] {
+					t.Errorf("lot %d has %d points left, want %d", i, lot.RemainingPoints, tt.wantLots[i])
+				}
+			}
+
+			lotless := 0
+			var allocated []int
+			for _, allocation := range transaction.Lots {
+				if allocation.LotID == nil {
+					lotless += allocation.Points
+					continue
+				}
+				allocated = append(allocated, allocation.Points)
+			}
+			if len(allocated) != len(tt.wantAllocated) {
+				t.Fatalf("allocated %v, want %v", allocated, tt.wantAllocated)
+			}
+			for i := range allocated {
+				if allocated[i] != tt.wantAllocated[i] {
+					t.Errorf("allocated %v, want %v", allocated, tt.wantAllocated)
+					break
+				}
+			}
+			if lotless != tt.wantLotless {
+				t.Errorf("%d points taken without a lot, want %d", lotless, tt.wantLotless)
+			}
+		})
+	}
+}
+
+func TestSpendLotsSkipsLot(t *testing.T) {
+	repo := &ledgerRepository{lots: lots(30, 50)}
+	account := &LoyaltyAccount{ID: uuid.New(), TenantID: uuid.New()}
+
+	allocations, err := spendLots(repo, account, 40, repo.lots[0].ID)
+	if err != nil {
+		t.Fatalf("spendLots: %v", err)
+	}
+	if len(allocations) != 1 || *allocations[0].LotID != repo.lots[1].ID || allocations[0].Points != 40 {
+		t.Fatalf("allocations %+v, want 40 points from the second lot", allocations)
+	}
+	if repo.lots[0].RemainingPoints != 30 {
+		t.Errorf("skipped lot has %d points left, want 30", repo.lots[0].RemainingPoints)
+	}
+}
+
+func TestCredit(t *testing.T) {
+	tests := []struct {
+		name        string
+		settings    ProgramSettings
+		source      string
+		reference   string
+		done        bool // the reference was credited before
+		wantStatus  string
+		wantPoints  int
+		wantPending int
+		wantExpiry  bool
+	}{
+		{
+			name:       "points post straight away without a holding period",
+			source:     SourceOrder,
+			wantStatus: TransactionPosted,
+			wantPoints: 100,
+		},
+		{
+			name:        "order points wait out the holding period",
+			settings:    ProgramSettings{Earning: EarningRules{PendingDays: 14}},
+			source:      SourceOrder,
+			wantStatus:  TransactionPending,
+			wantPending: 100,
+		},
+		{
+			name:       "bonuses skip the holding period",
+			settings:   ProgramSettings{Earning: EarningRules{PendingDays: 14}},
+			source:     SourceBirthday,
+			wantStatus: TransactionPosted,
+			wantPoints: 100,
+		},
+		{
+			name:       "lots expire when the program expires points",
+			settings:   ProgramSettings{Points: PointsRules{ExpiryDays: 365}},
+			source:     SourceOrder,
+			wantStatus: TransactionPosted,
+			wantPoints: 100,
+			wantExpiry: true,
+		},
+		{
+			name:      "a reference is credited once",
+			source:    SourceOrder,
+			reference: "order:1",
+			done:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &ledgerRepository{references: map[string]bool{tt.reference: tt.done}}
+			account := &LoyaltyAccount{ID: uuid.New(), TenantID: uuid.New()}
+			service := &ServiceImpl{repo: repo}
+
+			transaction, err := service.credit(repo, account, &tt.settings, &LoyaltyTransaction{
+				Points:    100,
+				Source:    tt.source,
+				Reference: tt.reference,
+			})
+			if err != nil {
+				t.Fatalf("credit: %v", err)
+			}
+
+			if tt.done {
+				if transaction != nil || len(repo.created) > 0 || account.Points != 0 {
+					t.Fatalf("credited %+v again", transaction)
+				}
+				return
+			}
+			if transaction.Status != tt.wantStatus || transaction.Type != TypeEarned {
+				t.Errorf("transaction %s %s, want %s %s", transaction.Type, transaction.Status, TypeEarned, tt.wantStatus)
+			}
+			if transaction.RemainingPoints != 100 {
+				t.Errorf("lot holds %d points, want 100", transaction.RemainingPoints)
+			}
+			if (transaction.ExpiresAt != nil) != tt.wantExpiry {
+				t.Errorf("lot expires at %v, want expiry %v", transaction.ExpiresAt, tt.wantExpiry)
+			}
+			if account.Points != tt.wantPoints || account.PendingPoints != tt.wantPending {
+				t.Errorf("account has %d points and %d pending, want %d and %d", account.Points, account.PendingPoints, tt.wantPoints, tt.wantPending)
+			}
+		})
+	}
+}
+
+func TestQuotePoints(t *testing.T) {
+	const rules = `{"points": {"point_value": 0.5, "max_order_percent": 50, "min_redeem_points": 20}}`
+
+	tests := []struct {
+		name        string
+		account     *LoyaltyAccount
+		points      int
+		orderAmount float64
+		wantMax     int
+		wantPoints  int
+		wantAmount  float64
+		wantErr     error
+	}{
+		{
+			name:        "points are capped at the balance",
+			account:     pointsAccount(60, rules),
+			points:      100,
+			orderAmount: 1000,
+			wantMax:     60,
+			wantPoints:  60,
+			wantAmount:  30,
+		},
+		{
+			name:        "points are capped at the share of the order they may pay",
+			account:     pointsAccount(1000, rules),
+			points:      1000,
+			orderAmount: 100,
+			wantMax:     100,
+			wantPoints:  100,
+			wantAmount:  50,
+		},
+		{
+			name:        "the whole order may be paid without a share",
+			account:     pointsAccount(1000, `{"points": {"point_value": 0.5}}`),
+			points:      1000,
+			orderAmount: 100,
+			wantMax:     200,
+			wantPoints:  200,
+			wantAmount:  100,
+		},
+		{
+			name:        "nothing requested quotes the maximum only",
+			account:     pointsAccount(60, rules),
+			points:      -5,
+			orderAmount: 1000,
+			wantMax:     60,
+		},
+		{
+			name:        "fewer than the minimum points cannot be used",
+			account:     pointsAccount(60, rules),
+			points:      10,
+			orderAmount: 1000,
+			wantErr:     ErrPointsNotRedeemable,
+		},
+		{
+			name:        "points without a value cannot be used",
+			account:     pointsAccount(60, `{"points": {"expiry_days": 30}}`),
+			points:      10,
+			orderAmount: 1000,
+			wantErr:     ErrPointsNotRedeemable,
+		},
+		{
+			name:        "customers without an account have no points",
+			points:      10,
+			orderAmount: 1000,
+			wantErr:     ErrPointsNotRedeemable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &ledgerRepository{account: tt.account}
+
+			quote, err := quotePoints(repo, uuid.New(), uuid.New(), tt.points, tt.orderAmount)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("quote failed with %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("quote: %v", err)
+			}
+			if quote.MaxPoints != tt.wantMax || quote.Points != tt.wantPoints || quote.Amount != tt.wantAmount {
+				t.Errorf("quote max %d points %d amount %.2f, want max %d points %d amount %.2f",
+					quote.MaxPoints, quote.Points, quote.Amount, tt.wantMax, tt.wantPoints, tt.wantAmount)
+			}
+		})
+	}
+}
+
+func pointsAccount(points int, settings string) *LoyaltyAccount {
+	return &LoyaltyAccount{
+		ID:      uuid.New(),
+		Points:  points,
+		Status:  "active",
+		Program: &LoyaltyProgram{Status: "active", Settings: settings},
+	}
+}
@@ -0,0 +1,786 @@
+package loyalty
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	"ecommerce-saas/internal/discount"
+	"ecommerce-saas/internal/notification"
+	"ecommerce-saas/internal/order"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Referral statuses
+const (
+	ReferralPending   = "pending"   // Attributed, waiting for the first order to be delivered
+	ReferralQualified = "qualified" // First order delivered, waiting out the return window
+	ReferralRewarded  = "rewarded"
+	ReferralRejected  = "rejected"  // Failed a fraud check or rejected by the merchant
+	ReferralCancelled = "cancelled" // First order cancelled, returned or too small
+)
+
+// Where a referral was attributed
+const (
+	AttributedOnSignup     = "signup"
+	AttributedOnFirstOrder = "first_order"
+)
+
+// Referral reward types
+const (
+	RewardPoints      = "points"
+	RewardStoreCredit = "store_credit"
+	RewardCoupon      = "coupon"
+)
+
+// Fraud checks that reject a referral
+const (
+	FraudSelfReferral = "self_referral"
+	FraudSameDevice   = "same_device"
+	FraudSameIP       = "same_ip"
+	FraudSameAddress  = "same_address"
+)
+
+const (
+	referralBatchSize    = 200
+	referralCodeLength   = 8
+	referralCodeRounds   = 5
+	referralCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	referralCouponPrefix = "REF-"
+)
+
+var (
+	ErrReferralsDisabled    = errors.New("referrals are not enabled")
+	ErrReferralCodeNotFound = errors.New("referral code not found")
+	ErrAlreadyReferred      = errors.New("customer has already been referred")
+	ErrNotNewCustomer       = errors.New("only new customers can be referred")
+	ErrReferralClosed       = errors.New("referral can no longer be changed")
+)
+
+// ReferralReward is what one side of a referral receives. Points default to
+// the program's referral bonus; coupons are single-use codes generated for
+// DiscountID, which must be a code discount.
+type ReferralReward struct {
+	Type       string     `json:"type,omitempty"` // points, store_credit or coupon; empty gives nothing
+	Points     int        `json:"points,omitempty"`
+	Amount     float64    `json:"amount,omitempty"` // Store credit in the order's currency
+	DiscountID *uuid.UUID `json:"discount_id,omitempty"`
+}
+
+// Validate checks the reward for values that cannot be issued
+func (r *ReferralReward) Validate() error {
+	switch r.Type {
+	case "":
+	case RewardPoints:
+		if r.Points < 0 {
+			return fmt.Errorf("%w: referral points cannot be negative", ErrInvalidSettings)
+		}
+	case RewardStoreCredit:
+		if r.Amount <= 0 {
+			return fmt.Errorf("%w: store credit rewards need a positive amount", ErrInvalidSettings)
+		}
+	case RewardCoupon:
+		if r.DiscountID == nil {
+			return fmt.Errorf("%w: coupon rewards need a discount", ErrInvalidSettings)
+		}
+	default:
+		return fmt.Errorf("%w: unknown referral reward type %q", ErrInvalidSettings, r.Type)
+	}
+	return nil
+}
+
+// ReferralRules configures the referral program. Fraud checks are on unless
+// explicitly allowed, e.g. for households that share a device or address.
+type ReferralRules struct {
+	Enabled          bool           `json:"enabled"`
+	LinkURL          string         `json:"link_url,omitempty"` // Storefront URL the code is added to as ?ref=
+	ReturnWindowDays int            `json:"return_window_days"` // Wait after delivery before rewarding
+	MinOrderAmount   float64        `json:"min_order_amount"`   // First order value needed to qualify
+	ReferrerReward   ReferralReward `json:"referrer_reward"`
+	RefereeReward    ReferralReward `json:"referee_reward"`
+	AllowSameDevice  bool           `json:"allow_same_device"`
+	AllowSameIP      bool           `json:"allow_same_ip"`
+	AllowSameAddress bool           `json:"allow_same_address"`
+}
+
+// Validate checks the rules for values that cannot be applied
+func (r *ReferralRules) Validate() error {
+	if r.ReturnWindowDays < 0 || r.MinOrderAmount < 0 {
+		return fmt.Errorf("%w: return window and minimum order cannot be negative", ErrInvalidSettings)
+	}
+	if r.LinkURL != "" {
+		link, err := url.Parse(r.LinkURL)
+		if err != nil || (link.Scheme != "http" && link.Scheme != "https") || link.Host == "" {
+			return fmt.Errorf("%w: referral link URL must be an absolute http(s) URL", ErrInvalidSettings)
+		}
+	}
+	if err := r.ReferrerReward.Validate(); err != nil {
+		return err
+	}
+	return r.RefereeReward.Validate()
+}
+
+// link returns the shareable link for a code, empty without a link URL
+func (r *ReferralRules) link(code string) string {
+	if r.LinkURL == "" {
+		return ""
+	}
+	link, err := url.Parse(r.LinkURL)
+	if err != nil {
+		return ""
+	}
+	query := link.Query()
+	query.Set("ref", code)
+	link.RawQuery = query.Encode()
+	return link.String()
+}
+
+// ReferralOrder is what referrals need to know about a delivered order
+type ReferralOrder struct {
+	TenantID        uuid.UUID
+	OrderID         uuid.UUID
+	UserID          uuid.UUID
+	OrderNumber     string
+	Amount          float64
+	ShippingAddress order.Address
+	DeliveredAt     time.Time
+}
+
+// ReferralRewardResult summarises a run of the referral reward job
+type ReferralRewardResult struct {
+	Rewarded  int `json:"rewarded"`
+	Cancelled int `json:"cancelled"`
+	Failed    int `json:"failed"`
+}
+
+// GetReferralRules returns the referral rules of a program
+func (s *ServiceImpl) GetReferralRules(ctx context.Context, tenantID, programID uuid.UUID) (*ReferralRules, error) {
+	program, err := s.repo.GetProgram(tenantID, programID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := program.ParseSettings()
+	if err != nil {
+		return nil, err
+	}
+	return &settings.Referrals, nil
+}
+
+// UpdateReferralRules replaces a program's referral rules. Referrals that
+// already qualified keep the return window they qualified with.
+func (s *ServiceImpl) UpdateReferralRules(ctx context.Context, tenantID, programID uuid.UUID, rules *ReferralRules) (*ReferralRules, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	program, err := s.repo.GetProgram(tenantID, programID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := withSetting(program.Settings, "referrals", rules)
+	if err != nil {
+		return nil, err
+	}
+	program.Settings = settings
+
+	if _, err := s.repo.UpdateProgram(program); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// GetReferralCode returns the customer's referral code in the tenant's active
+// program, creating one the first time it is asked for
+func (s *ServiceImpl) GetReferralCode(ctx context.Context, tenantID, userID uuid.UUID) (*ReferralCode, error) {
+	program, err := s.repo.GetActiveProgram(tenantID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrReferralsDisabled
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := program.ParseSettings()
+	if err != nil {
+		return nil, err
+	}
+	rules := &settings.Referrals
+	if !rules.Enabled {
+		return nil, ErrReferralsDisabled
+	}
+
+	code, err := s.repo.GetUserReferralCode(tenantID, program.ID, userID)
+	if err == nil {
+		code.Link = rules.link(code.Code)
+		return code, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	for round := 0; round < referralCodeRounds; round++ {
+		candidate, err := newReferralCode()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate referral code: %w", err)
+		}
+		if _, err := s.repo.GetReferralCode(tenantID, candidate); err == nil {
+			continue
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+
+		code, err := s.repo.CreateReferralCode(&ReferralCode{
+			TenantID:  tenantID,
+			ProgramID: program.ID,
+			UserID:    userID,
+			Code:      candidate,
+		})
+		if err != nil {
+			return nil, err
+		}
+		code.Link = rules.link(code.Code)
+		return code, nil
+	}
+	return nil, errors.New("could not generate a unique referral code")
+}
+
+// TrackReferralClick counts a visit through a referral link
+func (s *ServiceImpl) TrackReferralClick(ctx context.Context, tenantID uuid.UUID, code string) (*ReferralCode, error) {
+	referralCode, err := s.repo.GetReferralCode(tenantID, normalizeReferralCode(code))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrReferralCodeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := s.repo.RecordReferralClick(tenantID, referralCode.ID, now); err != nil {
+		return nil, err
+	}
+	referralCode.Clicks++
+	referralCode.LastClickedAt = &now
+	referralCode.Link = programSettings(s.repo, tenantID, referralCode.ProgramID).Referrals.link(referralCode.Code)
+	return referralCode, nil
+}
+
+// AttributeReferral records that a new customer joined through a referral
+// code, at signup or with their first order. Referrals failing the device or
+// IP checks are stored as rejected so merchants can review them.
+func (s *ServiceImpl) AttributeReferral(ctx context.Context, req *AttributeReferralRequest) (*Referral, error) {
+	code, err := s.repo.GetReferralCode(req.TenantID, normalizeReferralCode(req.Code))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrReferralCodeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	program, err := s.repo.GetProgram(req.TenantID, code.ProgramID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrReferralsDisabled
+	}
+	if err != nil {
+		return nil, err
+	}
+	settings, err := program.ParseSettings()
+	if err != nil {
+		return nil, err
+	}
+	if program.Status != "active" || !settings.Referrals.Enabled {
+		return nil, ErrReferralsDisabled
+	}
+
+	if _, err := s.repo.GetReferralByReferee(req.TenantID, req.RefereeID); err == nil {
+		return nil, ErrAlreadyReferred
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	referral := &Referral{
+		TenantID:          req.TenantID,
+		ProgramID:         program.ID,
+		CodeID:            code.ID,
+		ReferrerID:        code.UserID,
+		RefereeID:         req.RefereeID,
+		Status:            ReferralPending,
+		AttributedOn:      AttributedOnSignup,
+		IPAddress:         req.IPAddress,
+	}
+	if req.UserAgent != "" && req.IPAddress != "" {
+		referral.DeviceFingerprint = deviceFingerprint(req.UserAgent, req.IPAddress)
+	}
+
+	// Only the referee's first order can carry the code
+	var firstOrder *order.Order
+	if req.OrderID != nil {
+		firstOrder, err = s.repo.GetOrder(req.TenantID, *req.OrderID)
+		if err != nil {
+			return nil, err
+		}
+		if firstOrder.UserID != req.RefereeID {
+			return nil, gorm.ErrRecordNotFound
+		}
+		prior, err := s.repo.CountPriorOrders(req.TenantID, req.RefereeID, firstOrder.ID)
+		if err != nil {
+			return nil, err
+		}
+		if prior > 0 {
+			return nil, ErrNotNewCustomer
+		}
+		referral.AttributedOn = AttributedOnFirstOrder
+		referral.OrderID = &firstOrder.ID
+		referral.OrderNumber = firstOrder.OrderNumber
+	} else {
+		orders, err := s.repo.CountCustomerOrders(req.TenantID, req.RefereeID)
+		if err != nil {
+			return nil, err
+		}
+		if orders > 0 {
+			return nil, ErrNotNewCustomer
+		}
+	}
+
+	flags, err := s.referralFraudFlags(&settings.Referrals, referral)
+	if err != nil {
+		return nil, err
+	}
+	if len(flags) > 0 {
+		referral.Status = ReferralRejected
+		referral.FraudFlags = flags
+		referral.Reason = "Failed fraud checks: " + strings.Join(flags, ", ")
+	}
+
+	created, err := s.repo.CreateReferral(referral)
+	if err != nil {
+		return nil, err
+	}
+	if created.Status == ReferralRejected {
+		return created, nil
+	}
+	if err := s.repo.IncrementReferralSignups(req.TenantID, code.ID); err != nil {
+		return nil, err
+	}
+
+	// The order may already have been delivered by the time the code is entered
+	if firstOrder != nil && firstOrder.Status == order.StatusDelivered {
+		deliveredAt := time.Now()
+		if firstOrder.DeliveredAt != nil {
+			deliveredAt = *firstOrder.DeliveredAt
+		}
+		qualified, err := s.QualifyReferral(ctx, &ReferralOrder{
+			TenantID:        firstOrder.TenantID,
+			OrderID:         firstOrder.ID,
+			UserID:          firstOrder.UserID,
+			OrderNumber:     firstOrder.OrderNumber,
+			Amount:          firstOrder.TotalAmount,
+			ShippingAddress: firstOrder.ShippingAddress,
+			DeliveredAt:     deliveredAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if qualified != nil {
+			return qualified, nil
+		}
+	}
+	return created, nil
+}
+
+// QualifyReferral starts the return window once the referee's first order is
+// delivered. The shipping address is checked against the referrer's orders
+// here since it is only known once the referee orders. Returns nil when the
+// order does not qualify a referral.
+func (s *ServiceImpl) QualifyReferral(ctx context.Context, o *ReferralOrder) (*Referral, error) {
+	if o.UserID == uuid.Nil {
+		return nil, nil
+	}
+
+	referral, err := s.repo.GetReferralByReferee(o.TenantID, o.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if referral.Status != ReferralPending {
+		return nil, nil
+	}
+
+	if referral.OrderID != nil {
+		if *referral.OrderID != o.OrderID {
+			return nil, nil
+		}
+	} else {
+		prior, err := s.repo.CountPriorOrders(o.TenantID, o.UserID, o.OrderID)
+		if err != nil {
+			return nil, err
+		}
+		if prior > 0 {
+			return nil, nil
+		}
+	}
+
+	rules := &programSettings(s.repo, o.TenantID, referral.ProgramID).Referrals
+	orderID, deliveredAt := o.OrderID, o.DeliveredAt
+	referral.OrderID = &orderID
+	referral.OrderNumber = o.OrderNumber
+	referral.DeliveredAt = &deliveredAt
+
+	shared := false
+	if !rules.AllowSameAddress && o.ShippingAddress.Address1 != "" {
+		shared, err = s.repo.SharesShippingAddress(o.TenantID, referral.ReferrerID, o.ShippingAddress)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case shared:
+		referral.Status = ReferralRejected
+		referral.FraudFlags = append(referral.FraudFlags, FraudSameAddress)
+		referral.Reason = "Failed fraud checks: " + FraudSameAddress
+	case o.Amount < rules.MinOrderAmount:
+		referral.Status = ReferralCancelled
+		referral.Reason = fmt.Sprintf("Order %s is below the %.2f minimum", o.OrderNumber, rules.MinOrderAmount)
+	default:
+		eligibleAt := deliveredAt.AddDate(0, 0, rules.ReturnWindowDays)
+		referral.Status = ReferralQualified
+		referral.EligibleAt = &eligibleAt
+	}
+
+	return s.repo.UpdateReferral(referral)
+}
+
+// CancelReferralForOrder cancels a referral that has not been rewarded yet
+// when its qualifying order is cancelled, returned or refunded. Returns nil
+// when the order carries no open referral.
+func (s *ServiceImpl) CancelReferralForOrder(ctx context.Context, tenantID, orderID uuid.UUID, reason string) (*Referral, error) {
+	referral, err := s.repo.GetOrderReferral(tenantID, orderID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if referral.Status != ReferralPending && referral.Status != ReferralQualified {
+		return nil, nil
+	}
+
+	referral.Status = ReferralCancelled
+	referral.Reason = reason
+	return s.repo.UpdateReferral(referral)
+}
+
+// IssueReferralRewards rewards both sides of referrals whose return window
+// has closed. Each side is saved as soon as it is issued so a failed run
+// never rewards anyone twice. Meant to run daily; each run handles one batch.
+func (s *ServiceImpl) IssueReferralRewards(ctx context.Context, tenantID uuid.UUID, now time.Time) (*ReferralRewardResult, error) {
+	due, err := s.repo.GetDueReferrals(tenantID, now, referralBatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ReferralRewardResult{}
+	for _, referral := range due {
+		if err := s.rewardReferral(ctx, referral, now); err != nil {
+			result.Failed++
+			continue
+		}
+		switch referral.Status {
+		case ReferralRewarded:
+			result.Rewarded++
+		case ReferralCancelled:
+			result.Cancelled++
+		}
+	}
+
+	return result, nil
+}
+
+// ListReferrals lists a tenant's referrals, newest first
+func (s *ServiceImpl) ListReferrals(ctx context.Context, tenantID uuid.UUID, req *ListReferralsRequest) (*ListReferralsResponse, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+
+	referrals, total, err := s.repo.ListReferrals(tenantID, req.Status, req.ReferrerID, limit, req.Offset)
+	if err != nil {
+		return nil, err
+	}
+	return &ListReferralsResponse{
+		Referrals: referrals,
+		Total:     total,
+	}, nil
+}
+
+func (s *ServiceImpl) GetReferral(ctx context.Context, tenantID, referralID uuid.UUID) (*Referral, error) {
+	return s.repo.GetReferral(tenantID, referralID)
+}
+
+// RejectReferral stops a referral from being rewarded
+func (s *ServiceImpl) RejectReferral(ctx context.Context, tenantID, referralID uuid.UUID, reason string) (*Referral, error) {
+	referral, err := s.repo.GetReferral(tenantID, referralID)
+	if err != nil {
+		return nil, err
+	}
+	if referral.Status != ReferralPending && referral.Status != ReferralQualified {
+		return nil, ErrReferralClosed
+	}
+
+	if reason == "" {
+		reason = "Rejected by the merchant"
+	}
+	referral.Status = ReferralRejected
+	referral.Reason = reason
+	return s.repo.UpdateReferral(referral)
+}
+
+// rewardReferral issues whichever rewards are still outstanding, or cancels
+// the referral if the order did not stay delivered
+func (s *ServiceImpl) rewardReferral(ctx context.Context, referral *Referral, now time.Time) error {
+	o, err := s.repo.GetOrder(referral.TenantID, *referral.OrderID)
+	if err != nil {
+		return err
+	}
+	if o.Status != order.StatusDelivered {
+		referral.Status = ReferralCancelled
+		referral.Reason = fmt.Sprintf("Order %s was %s", o.OrderNumber, o.Status)
+		_, err := s.repo.UpdateReferral(referral)
+		return err
+	}
+
+	rules := &programSettings(s.repo, referral.TenantID, referral.ProgramID).Referrals
+	sides := []struct {
+		userID      uuid.UUID
+		reward      *ReferralReward
+		issued      **IssuedReward
+		reference   string
+		description string
+	}{
+		{referral.ReferrerID, &rules.ReferrerReward, &referral.ReferrerReward, "referrer", "Thanks for referring a friend"},
+		{referral.RefereeID, &rules.RefereeReward, &referral.RefereeReward, "referee", "Welcome reward for joining through a referral"},
+	}
+
+	var issued []int
+	for i, side := range sides {
+		if side.reward.Type == "" || *side.issued != nil {
+			continue
+		}
+
+		reward, err := s.issueReferralReward(ctx, referral, side.userID, side.reward, o.Currency,
+			fmt.Sprintf("referral:%s:%s", referral.ID, side.reference), side.description)
+		if err != nil {
+			return err
+		}
+		*side.issued = reward
+		if _, err := s.repo.UpdateReferral(referral); err != nil {
+			return err
+		}
+		issued = append(issued, i)
+	}
+
+	referral.Status = ReferralRewarded
+	referral.RewardedAt = &now
+	if _, err := s.repo.UpdateReferral(referral); err != nil {
+		return err
+	}
+
+	// Emails are best effort; the rewards are already issued
+	for _, i := range issued {
+		_ = s.notifyReferralReward(referral.TenantID, sides[i].userID, *sides[i].issued)
+	}
+	return nil
+}
+
+// issueReferralReward gives one side of a referral its reward
+func (s *ServiceImpl) issueReferralReward(ctx context.Context, referral *Referral, userID uuid.UUID, reward *ReferralReward, currency, reference, description string) (*IssuedReward, error) {
+	issued := &IssuedReward{Type: reward.Type, IssuedAt: time.Now()}
+
+	switch reward.Type {
+	case RewardPoints:
+		transaction, err := s.AwardBonus(ctx, &AwardBonusRequest{
+			TenantID:    referral.TenantID,
+			UserID:      userID,
+			Source:      SourceReferral,
+			Reference:   reference,
+			Points:      reward.Points,
+			Description: description,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if transaction != nil {
+			issued.Points = transaction.Points
+			issued.TransactionID = &transaction.ID
+		}
+
+	case RewardStoreCredit:
+		if s.discounts == nil {
+			return nil, errors.New("store credit rewards are not available")
+		}
+		transaction, err := s.discounts.AddStoreCredit(ctx, discount.AddStoreCreditRequest{
+			TenantID:    referral.TenantID,
+			CustomerID:  userID,
+			Amount:      reward.Amount,
+			Currency:    currency,
+			Description: description,
+			Reference:   reference,
+		})
+		if err != nil {
+			return nil, err
+		}
+		issued.Amount = reward.Amount
+		issued.Currency = currency
+		issued.TransactionID = &transaction.ID
+
+	case RewardCoupon:
+		if s.discounts == nil {
+			return nil, errors.New("coupon rewards are not available")
+		}
+		batch, err := s.discounts.GenerateCodes(ctx, discount.GenerateCodesRequest{
+			TenantID:   referral.TenantID,
+			DiscountID: *reward.DiscountID,
+			Quantity:   1,
+			Prefix:     referralCouponPrefix,
+			Reference:  reference,
+		})
+		if err != nil {
+			return nil, err
+		}
+		codes, err := s.discounts.GetDiscountCodes(ctx, referral.TenantID, *reward.DiscountID, discount.DiscountCodeFilter{BatchID: &batch.ID, Limit: 1})
+		if err != nil {
+			return nil, err
+		}
+		if len(codes) == 0 {
+			return nil, errors.New("generated coupon code not found")
+		}
+		issued.Code = codes[0].Code
+
+	default:
+		return nil, fmt.Errorf("%w: unknown referral reward type %q", ErrInvalidSettings, reward.Type)
+	}
+
+	return issued, nil
+}
+
+// referralFraudFlags runs the checks that are known at attribution time: self
+// referrals and a device or IP address the referrer has used
+func (s *ServiceImpl) referralFraudFlags(rules *ReferralRules, referral *Referral) ([]string, error) {
+	if referral.ReferrerID == referral.RefereeID {
+		return []string{FraudSelfReferral}, nil
+	}
+
+	var flags []string
+	if !rules.AllowSameDevice && referral.DeviceFingerprint != "" {
+		shared, err := s.repo.SharesDevice(referral.ReferrerID, referral.DeviceFingerprint)
+		if err != nil {
+			return nil, err
+		}
+		if shared {
+			flags = append(flags, FraudSameDevice)
+		}
+	}
+	if !rules.AllowSameIP && referral.IPAddress != "" {
+		shared, err := s.repo.SharesIPAddress(referral.ReferrerID, referral.IPAddress)
+		if err != nil {
+			return nil, err
+		}
+		if shared {
+			flags = append(flags, FraudSameIP)
+		}
+	}
+	return flags, nil
+}
+
+// notifyReferralReward emails a customer about the reward they received
+func (s *ServiceImpl) notifyReferralReward(tenantID, userID uuid.UUID, reward *IssuedReward) error {
+	if s.notifications == nil {
+		return nil
+	}
+
+	customer, err := s.repo.GetCustomer(tenantID, userID)
+	if err != nil {
+		return err
+	}
+
+	variables := map[string]interface{}{
+		"customer_name": strings.TrimSpace(customer.FirstName + " " + customer.LastName),
+		"reward_type":   reward.Type,
+		"reward":        reward.describe(),
+		"points":        reward.Points,
+		"amount":        reward.Amount,
+		"currency":      reward.Currency,
+		"coupon_code":   reward.Code,
+	}
+
+	req := &notification.SendNotificationRequest{
+		Type:       notification.TypeEmail,
+		Channel:    notification.ChannelReferralReward,
+		Recipients: []string{customer.Email},
+		Variables:  variables,
+		Priority:   notification.PriorityNormal,
+		UserID:     userID.String(),
+	}
+
+	if template := s.template(tenantID, notification.ChannelReferralReward); template != nil {
+		req.TemplateID = template.ID.String()
+	} else {
+		req.Subject = "You've earned a referral reward"
+		req.Content = fmt.Sprintf("Hi %s,\n\nThanks to your referral you've received %s.",
+			variables["customer_name"], variables["reward"])
+	}
+
+	_, err = s.notifications.SendNotification(tenantID, req)
+	return err
+}
+
+// describe words the reward for customer emails
+func (r *IssuedReward) describe() string {
+	switch r.Type {
+	case RewardPoints:
+		return fmt.Sprintf("%d points", r.Points)
+	case RewardStoreCredit:
+		return strings.TrimSpace(fmt.Sprintf("%.2f %s in store credit", r.Amount, r.Currency))
+	case RewardCoupon:
+		return fmt.Sprintf("the coupon code %s", r.Code)
+	}
+	return "a reward"
+}
+
+// newReferralCode generates a random code without easily confused characters
+func newReferralCode() (string, error) {
+	code := make([]byte, referralCodeLength)
+	max := big.NewInt(int64(len(referralCodeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = referralCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+func normalizeReferralCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// deviceFingerprint derives a fingerprint the same way the security module
+// does for logins, so signups can be matched against the referrer's devices
+func deviceFingerprint(userAgent, ipAddress string) string {
+	hash := sha256.Sum256([]byte(userAgent + ipAddress))
+	return hex.EncodeToString(hash[:])
+}
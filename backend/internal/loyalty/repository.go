@@ -3,9 +3,16 @@ package loyalty
 import (
 	"database/sql"
 	"fmt"
+	"strings"
+	"time"
+
+	"ecommerce-saas/internal/order"
+	"ecommerce-saas/internal/product"
+	"ecommerce-saas/internal/user"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Repository defines the loyalty repository interface
@@ -39,6 +46,58 @@ type Repository interface {
 
 	// Analytics
 	GetStats(tenantID uuid.UUID) (*LoyaltyStats, error)
+
+	// Automatic earning
+	Transaction(fn func(repo Repository) error) error
+	GetActiveProgram(tenantID uuid.UUID) (*LoyaltyProgram, error)
+	GetAccountForUpdate(tenantID, accountID uuid.UUID) (*LoyaltyAccount, error)
+	GetTransactionForUpdate(tenantID, transactionID uuid.UUID) (*LoyaltyTransaction, error)
+	HasTransactionReference(tenantID, accountID uuid.UUID, reference string) (bool, error)
+	GetOrderEarnings(tenantID, orderID uuid.UUID) ([]*LoyaltyTransaction, error)
+	GetReleasableTransactions(tenantID uuid.UUID, now time.Time, limit int) ([]*LoyaltyTransaction, error)
+	GetBirthdayAccounts(tenantID uuid.UUID, month time.Month, days []int) ([]*LoyaltyAccount, error)
+	CountPriorOrders(tenantID, userID, orderID uuid.UUID) (int64, error)
+	GetProducts(tenantID uuid.UUID, productIDs []uuid.UUID) ([]product.Product, error)
+
+	// Tiers
+	CreateTier(tier *LoyaltyTier) (*LoyaltyTier, error)
+	GetTier(tenantID, tierID uuid.UUID) (*LoyaltyTier, error)
+	GetTierByCode(tenantID, programID uuid.UUID, code string) (*LoyaltyTier, error)
+	UpdateTier(tier *LoyaltyTier) (*LoyaltyTier, error)
+	DeleteTier(tenantID, tierID uuid.UUID) error
+	ListTiers(tenantID, programID uuid.UUID) ([]*LoyaltyTier, error)
+	CountTierAccounts(tenantID, programID uuid.UUID, code string) (int64, error)
+	ListActivePrograms(tenantID uuid.UUID) ([]*LoyaltyProgram, error)
+	ListProgramAccounts(tenantID, programID uuid.UUID, afterID *uuid.UUID, limit int) ([]*LoyaltyAccount, error)
+	SumQualifyingPoints(tenantID, accountID uuid.UUID, since *time.Time) (float64, error)
+	SumQualifyingSpend(tenantID, userID uuid.UUID, since *time.Time) (float64, error)
+	GetCustomer(tenantID, userID uuid.UUID) (*user.User, error)
+
+	// Point lots
+	GetSpendableLots(tenantID, accountID uuid.UUID) ([]*LoyaltyTransaction, error)
+	GetExpiredLots(tenantID uuid.UUID, now time.Time, limit int) ([]*LoyaltyTransaction, error)
+	GetExpiringLots(tenantID, programID uuid.UUID, from, until time.Time) ([]*LoyaltyTransaction, error)
+	MarkReminderSent(tenantID uuid.UUID, lotIDs []uuid.UUID, sentAt time.Time) error
+	GetOrderRedemptions(tenantID, orderID uuid.UUID) ([]*LoyaltyTransaction, error)
+
+	// Referrals
+	CreateReferralCode(code *ReferralCode) (*ReferralCode, error)
+	GetReferralCode(tenantID uuid.UUID, code string) (*ReferralCode, error)
+	GetUserReferralCode(tenantID, programID, userID uuid.UUID) (*ReferralCode, error)
+	RecordReferralClick(tenantID, codeID uuid.UUID, at time.Time) error
+	IncrementReferralSignups(tenantID, codeID uuid.UUID) error
+	CreateReferral(referral *Referral) (*Referral, error)
+	GetReferral(tenantID, referralID uuid.UUID) (*Referral, error)
+	GetReferralByReferee(tenantID, refereeID uuid.UUID) (*Referral, error)
+	GetOrderReferral(tenantID, orderID uuid.UUID) (*Referral, error)
+	UpdateReferral(referral *Referral) (*Referral, error)
+	ListReferrals(tenantID uuid.UUID, status *string, referrerID *uuid.UUID, limit, offset int) ([]*Referral, int64, error)
+	GetDueReferrals(tenantID uuid.UUID, now time.Time, limit int) ([]*Referral, error)
+	GetOrder(tenantID, orderID uuid.UUID) (*order.Order, error)
+	CountCustomerOrders(tenantID, userID uuid.UUID) (int64, error)
+	SharesDevice(userID uuid.UUID, fingerprint string) (bool, error)
+	SharesIPAddress(userID uuid.UUID, ipAddress string) (bool, error)
+	SharesShippingAddress(tenantID, userID uuid.UUID, address order.Address) (bool, error)
 }
 
 // GormRepository implements Repository using GORM
@@ -259,4 +318,417 @@ func (r *GormRepository) GetStats(tenantID uuid.UUID) (*LoyaltyStats, error) {
 	r.db.Model(&LoyaltyTransaction{}).Where("tenant_id = ? AND type = 'redeemed'", tenantID).Count(&stats.TotalRedemptions)
 
 	return stats, nil
-}
\ No newline at end of file
+}
+
+// Automatic earning
+
+// Transaction runs fn against a repository bound to a single database transaction
+func (r *GormRepository) Transaction(fn func(repo Repository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&GormRepository{db: tx})
+	})
+}
+
+// GetActiveProgram returns the tenant's oldest active program, which customers
+// are enrolled in automatically
+func (r *GormRepository) GetActiveProgram(tenantID uuid.UUID) (*LoyaltyProgram, error) {
+	var program LoyaltyProgram
+	err := r.db.Where("tenant_id = ? AND status = ?", tenantID, "active").Order("created_at ASC").First(&program).Error
+	if err != nil {
+		return nil, err
+	}
+	return &program, nil
+}
+
+// GetAccountForUpdate loads an account with a row lock; use inside Transaction
+func (r *GormRepository) GetAccountForUpdate(tenantID, accountID uuid.UUID) (*LoyaltyAccount, error) {
+	var account LoyaltyAccount
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("tenant_id = ? AND id = ?", tenantID, accountID).First(&account).Error
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// GetTransactionForUpdate loads a transaction with a row lock; use inside Transaction
+func (r *GormRepository) GetTransactionForUpdate(tenantID, transactionID uuid.UUID) (*LoyaltyTransaction, error) {
+	var transaction LoyaltyTransaction
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("tenant_id = ? AND id = ?", tenantID, transactionID).First(&transaction).Error
+	if err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+func (r *GormRepository) HasTransactionReference(tenantID, accountID uuid.UUID, reference string) (bool, error) {
+	var count int64
+	err := r.db.Model(&LoyaltyTransaction{}).
+		Where("tenant_id = ? AND account_id = ? AND reference = ?", tenantID, accountID, reference).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetOrderEarnings returns the points earned on an order that can still be reversed
+func (r *GormRepository) GetOrderEarnings(tenantID, orderID uuid.UUID) ([]*LoyaltyTransaction, error) {
+	var transactions []*LoyaltyTransaction
+	err := r.db.Where("tenant_id = ? AND order_id = ? AND type = ? AND source IN ? AND status IN ?",
+		tenantID, orderID, TypeEarned, []string{SourceOrder, SourceFirstOrder}, []string{TransactionPending, TransactionPosted}).
+		Order("created_at ASC").Find(&transactions).Error
+	return transactions, err
+}
+
+// GetReleasableTransactions returns pending earnings whose holding period has ended
+func (r *GormRepository) GetReleasableTransactions(tenantID uuid.UUID, now time.Time, limit int) ([]*LoyaltyTransaction, error) {
+	var transactions []*LoyaltyTransaction
+	err := r.db.Where("tenant_id = ? AND status = ? AND available_at <= ?", tenantID, TransactionPending, now).
+		Order("available_at ASC").Limit(limit).Find(&transactions).Error
+	return transactions, err
+}
+
+// GetBirthdayAccounts returns active accounts with a birthday on any of the
+// given days of the month
+func (r *GormRepository) GetBirthdayAccounts(tenantID uuid.UUID, month time.Month, days []int) ([]*LoyaltyAccount, error) {
+	var accounts []*LoyaltyAccount
+	err := r.db.Where("tenant_id = ? AND status = ? AND birthday IS NOT NULL", tenantID, "active").
+		Where("EXTRACT(MONTH FROM birthday) = ? AND EXTRACT(DAY FROM birthday) IN ?", int(month), days).
+		Find(&accounts).Error
+	return accounts, err
+}
+
+// CountPriorOrders counts the customer's orders placed before the given one,
+// ignoring cancelled orders
+func (r *GormRepository) CountPriorOrders(tenantID, userID, orderID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&order.Order{}).
+		Where("tenant_id = ? AND user_id = ? AND id <> ? AND status <> ?", tenantID, userID, orderID, order.StatusCancelled).
+		Where("created_at < (?)", r.db.Model(&order.Order{}).Select("created_at").Where("id = ?", orderID)).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *GormRepository) GetProducts(tenantID uuid.UUID, productIDs []uuid.UUID) ([]product.Product, error) {
+	var products []product.Product
+	if len(productIDs) == 0 {
+		return products, nil
+	}
+	err := r.db.Where("tenant_id = ? AND id IN ?", tenantID, productIDs).Find(&products).Error
+	return products, err
+}
+
+// Tiers
+func (r *GormRepository) CreateTier(tier *LoyaltyTier) (*LoyaltyTier, error) {
+	err := r.db.Create(tier).Error
+	return tier, err
+}
+
+func (r *GormRepository) GetTier(tenantID, tierID uuid.UUID) (*LoyaltyTier, error) {
+	var tier LoyaltyTier
+	err := r.db.Where("tenant_id = ? AND id = ?", tenantID, tierID).First(&tier).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tier, nil
+}
+
+func (r *GormRepository) GetTierByCode(tenantID, programID uuid.UUID, code string) (*LoyaltyTier, error) {
+	var tier LoyaltyTier
+	err := r.db.Where("tenant_id = ? AND program_id = ? AND code = ?", tenantID, programID, code).First(&tier).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tier, nil
+}
+
+func (r *GormRepository) UpdateTier(tier *LoyaltyTier) (*LoyaltyTier, error) {
+	err := r.db.Save(tier).Error
+	return tier, err
+}
+
+func (r *GormRepository) DeleteTier(tenantID, tierID uuid.UUID) error {
+	return r.db.Where("tenant_id = ? AND id = ?", tenantID, tierID).Delete(&LoyaltyTier{}).Error
+}
+
+// ListTiers returns a program's tiers from the lowest rank up
+func (r *GormRepository) ListTiers(tenantID, programID uuid.UUID) ([]*LoyaltyTier, error) {
+	var tiers []*LoyaltyTier
+	err := r.db.Where("tenant_id = ? AND program_id = ?", tenantID, programID).
+		Order("rank ASC").Order("threshold ASC").Find(&tiers).Error
+	return tiers, err
+}
+
+func (r *GormRepository) CountTierAccounts(tenantID, programID uuid.UUID, code string) (int64, error) {
+	var count int64
+	err := r.db.Model(&LoyaltyAccount{}).
+		Where("tenant_id = ? AND program_id = ? AND tier = ?", tenantID, programID, code).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *GormRepository) ListActivePrograms(tenantID uuid.UUID) ([]*LoyaltyProgram, error) {
+	var programs []*LoyaltyProgram
+	err := r.db.Where("tenant_id = ? AND status = ?", tenantID, "active").Order("created_at ASC").Find(&programs).Error
+	return programs, err
+}
+
+// ListProgramAccounts pages through a program's active accounts by ID
+func (r *GormRepository) ListProgramAccounts(tenantID, programID uuid.UUID, afterID *uuid.UUID, limit int) ([]*LoyaltyAccount, error) {
+	var accounts []*LoyaltyAccount
+	query := r.db.Where("tenant_id = ? AND program_id = ? AND status = ?", tenantID, programID, "active")
+	if afterID != nil {
+		query = query.Where("id > ?", *afterID)
+	}
+	err := query.Order("id ASC").Limit(limit).Find(&accounts).Error
+	return accounts, err
+}
+
+// SumQualifyingPoints adds up points earned since the given time, less
+// anything reversed; nil counts all history. Points still pending (e.g.
+// until the order's return window closes) don't count yet.
+func (r *GormRepository) SumQualifyingPoints(tenantID, accountID uuid.UUID, since *time.Time) (float64, error) {
+	var total sql.NullFloat64
+	query := r.db.Model(&LoyaltyTransaction{}).
+		Select("SUM(points - COALESCE(reversed_points, 0))").
+		Where("tenant_id = ? AND account_id = ? AND type = ? AND COALESCE(status, ?) NOT IN ?", tenantID, accountID, TypeEarned, TransactionPosted, []string{TransactionPending, TransactionReversed})
+	if since != nil {
+		query = query.Where("created_at >= ?", *since)
+	}
+	err := query.Scan(&total).Error
+	return total.Float64, err
+}
+
+// SumQualifyingSpend adds up what the customer paid for items on orders
+// delivered since the given time; nil counts all history
+func (r *GormRepository) SumQualifyingSpend(tenantID, userID uuid.UUID, since *time.Time) (float64, error) {
+	var total sql.NullFloat64
+	query := r.db.Model(&order.Order{}).
+		Select("SUM(subtotal_amount - discount_amount)").
+		Where("tenant_id = ? AND user_id = ? AND status = ?", tenantID, userID, order.StatusDelivered)
+	if since != nil {
+		query = query.Where("delivered_at >= ?", *since)
+	}
+	err := query.Scan(&total).Error
+	return total.Float64, err
+}
+
+// GetCustomer returns the customer behind an account, for notifications
+func (r *GormRepository) GetCustomer(tenantID, userID uuid.UUID) (*user.User, error) {
+	var customer user.User
+	err := r.db.Where("tenant_id = ? AND id = ?", tenantID, userID).First(&customer).Error
+	if err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
+
+// Point lots
+
+// GetSpendableLots returns an account's posted lots that still hold points,
+// oldest first; callers hold the account lock
+func (r *GormRepository) GetSpendableLots(tenantID, accountID uuid.UUID) ([]*LoyaltyTransaction, error) {
+	var lots []*LoyaltyTransaction
+	err := r.db.Where("tenant_id = ? AND account_id = ? AND status = ? AND remaining_points > 0", tenantID, accountID, TransactionPosted).
+		Order("created_at ASC").Order("id ASC").Find(&lots).Error
+	return lots, err
+}
+
+// GetExpiredLots returns posted lots past their expiry that still hold points
+func (r *GormRepository) GetExpiredLots(tenantID uuid.UUID, now time.Time, limit int) ([]*LoyaltyTransaction, error) {
+	var lots []*LoyaltyTransaction
+	err := r.db.Where("tenant_id = ? AND status = ? AND remaining_points > 0 AND expires_at <= ?", tenantID, TransactionPosted, now).
+		Order("expires_at ASC").Limit(limit).Find(&lots).Error
+	return lots, err
+}
+
+// GetExpiringLots returns a program's lots expiring within the window whose
+// owners have not been reminded yet
+func (r *GormRepository) GetExpiringLots(tenantID, programID uuid.UUID, from, until time.Time) ([]*LoyaltyTransaction, error) {
+	var lots []*LoyaltyTransaction
+	err := r.db.Where("tenant_id = ? AND status = ? AND remaining_points > 0 AND reminder_sent_at IS NULL", tenantID, TransactionPosted).
+		Where("expires_at > ? AND expires_at <= ?", from, until).
+		Where("account_id IN (?)", r.db.Model(&LoyaltyAccount{}).Select("id").
+			Where("tenant_id = ? AND program_id = ? AND status = ?", tenantID, programID, "active")).
+		Order("account_id ASC").Order("expires_at ASC").Find(&lots).Error
+	return lots, err
+}
+
+func (r *GormRepository) MarkReminderSent(tenantID uuid.UUID, lotIDs []uuid.UUID, sentAt time.Time) error {
+	if len(lotIDs) == 0 {
+		return nil
+	}
+	return r.db.Model(&LoyaltyTransaction{}).
+		Where("tenant_id = ? AND id IN ?", tenantID, lotIDs).
+		Update("reminder_sent_at", sentAt).Error
+}
+
+// GetOrderRedemptions returns points spent on an order that have not been restored
+func (r *GormRepository) GetOrderRedemptions(tenantID, orderID uuid.UUID) ([]*LoyaltyTransaction, error) {
+	var transactions []*LoyaltyTransaction
+	err := r.db.Where("tenant_id = ? AND order_id = ? AND type = ? AND source = ? AND status = ?",
+		tenantID, orderID, TypeRedeemed, SourceCheckout, TransactionPosted).
+		Order("created_at ASC").Find(&transactions).Error
+	return transactions, err
+}
+
+// Referrals
+func (r *GormRepository) CreateReferralCode(code *ReferralCode) (*ReferralCode, error) {
+	err := r.db.Create(code).Error
+	return code, err
+}
+
+func (r *GormRepository) GetReferralCode(tenantID uuid.UUID, code string) (*ReferralCode, error) {
+	var referralCode ReferralCode
+	err := r.db.Where("tenant_id = ? AND code = ?", tenantID, code).First(&referralCode).Error
+	if err != nil {
+		return nil, err
+	}
+	return &referralCode, nil
+}
+
+func (r *GormRepository) GetUserReferralCode(tenantID, programID, userID uuid.UUID) (*ReferralCode, error) {
+	var referralCode ReferralCode
+	err := r.db.Where("tenant_id = ? AND program_id = ? AND user_id = ?", tenantID, programID, userID).First(&referralCode).Error
+	if err != nil {
+		return nil, err
+	}
+	return &referralCode, nil
+}
+
+func (r *GormRepository) RecordReferralClick(tenantID, codeID uuid.UUID, at time.Time) error {
+	return r.db.Model(&ReferralCode{}).Where("tenant_id = ? AND id = ?", tenantID, codeID).
+		Updates(map[string]interface{}{"clicks": gorm.Expr("clicks + 1"), "last_clicked_at": at}).Error
+}
+
+func (r *GormRepository) IncrementReferralSignups(tenantID, codeID uuid.UUID) error {
+	return r.db.Model(&ReferralCode{}).Where("tenant_id = ? AND id = ?", tenantID, codeID).
+		UpdateColumn("signups", gorm.Expr("signups + 1")).Error
+}
+
+func (r *GormRepository) CreateReferral(referral *Referral) (*Referral, error) {
+	err := r.db.Create(referral).Error
+	return referral, err
+}
+
+func (r *GormRepository) GetReferral(tenantID, referralID uuid.UUID) (*Referral, error) {
+	var referral Referral
+	err := r.db.Where("tenant_id = ? AND id = ?", tenantID, referralID).First(&referral).Error
+	if err != nil {
+		return nil, err
+	}
+	return &referral, nil
+}
+
+func (r *GormRepository) GetReferralByReferee(tenantID, refereeID uuid.UUID) (*Referral, error) {
+	var referral Referral
+	err := r.db.Where("tenant_id = ? AND referee_id = ?", tenantID, refereeID).First(&referral).Error
+	if err != nil {
+		return nil, err
+	}
+	return &referral, nil
+}
+
+func (r *GormRepository) GetOrderReferral(tenantID, orderID uuid.UUID) (*Referral, error) {
+	var referral Referral
+	err := r.db.Where("tenant_id = ? AND order_id = ?", tenantID, orderID).First(&referral).Error
+	if err != nil {
+		return nil, err
+	}
+	return &referral, nil
+}
+
+func (r *GormRepository) UpdateReferral(referral *Referral) (*Referral, error) {
+	err := r.db.Save(referral).Error
+	return referral, err
+}
+
+func (r *GormRepository) ListReferrals(tenantID uuid.UUID, status *string, referrerID *uuid.UUID, limit, offset int) ([]*Referral, int64, error) {
+	var referrals []*Referral
+	var total int64
+
+	query := r.db.Model(&Referral{}).Where("tenant_id = ?", tenantID)
+	if status != nil {
+		query = query.Where("status = ?", *status)
+	}
+	if referrerID != nil {
+		query = query.Where("referrer_id = ?", *referrerID)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&referrals).Error
+	return referrals, total, err
+}
+
+// GetDueReferrals returns qualified referrals whose return window has closed
+func (r *GormRepository) GetDueReferrals(tenantID uuid.UUID, now time.Time, limit int) ([]*Referral, error) {
+	var referrals []*Referral
+	err := r.db.Where("tenant_id = ? AND status = ? AND eligible_at <= ?", tenantID, ReferralQualified, now).
+		Order("eligible_at ASC").Limit(limit).Find(&referrals).Error
+	return referrals, err
+}
+
+func (r *GormRepository) GetOrder(tenantID, orderID uuid.UUID) (*order.Order, error) {
+	var o order.Order
+	err := r.db.Where("tenant_id = ? AND id = ?", tenantID, orderID).First(&o).Error
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// CountCustomerOrders counts the customer's orders, ignoring cancelled ones
+func (r *GormRepository) CountCustomerOrders(tenantID, userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&order.Order{}).
+		Where("tenant_id = ? AND user_id = ? AND status <> ?", tenantID, userID, order.StatusCancelled).
+		Count(&count).Error
+	return count, err
+}
+
+// SharesDevice reports whether the fingerprint was seen on one of the user's
+// logins or trusted devices. The tables belong to the security module.
+func (r *GormRepository) SharesDevice(userID uuid.UUID, fingerprint string) (bool, error) {
+	return anyRows(
+		r.db.Table("login_attempts").Select("1").Where("user_id = ? AND device_fingerprint = ?", userID, fingerprint),
+		r.db.Table("trusted_devices").Select("1").Where("user_id = ? AND fingerprint = ?", userID, fingerprint),
+	)
+}
+
+// SharesIPAddress reports whether the user logged in from, or trusted a device
+// at, the IP address
+func (r *GormRepository) SharesIPAddress(userID uuid.UUID, ipAddress string) (bool, error) {
+	return anyRows(
+		r.db.Table("login_attempts").Select("1").Where("user_id = ? AND ip_address = ?", userID, ipAddress),
+		r.db.Table("trusted_devices").Select("1").Where("user_id = ? AND (ip_address = ? OR last_ip_address = ?)", userID, ipAddress, ipAddress),
+	)
+}
+
+// SharesShippingAddress reports whether any of the user's orders shipped to
+// the address. Street, city and postal code are compared case-insensitively.
+func (r *GormRepository) SharesShippingAddress(tenantID, userID uuid.UUID, address order.Address) (bool, error) {
+	var count int64
+	err := r.db.Model(&order.Order{}).
+		Where("tenant_id = ? AND user_id = ?", tenantID, userID).
+		Where("LOWER(TRIM(shipping_address1)) = ? AND LOWER(TRIM(shipping_city)) = ?",
+			strings.ToLower(strings.TrimSpace(address.Address1)), strings.ToLower(strings.TrimSpace(address.City))).
+		Where("LOWER(TRIM(COALESCE(shipping_postal_code, ''))) = ?", strings.ToLower(strings.TrimSpace(address.PostalCode))).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// anyRows reports whether any of the queries returns a row
+func anyRows(queries ...*gorm.DB) (bool, error) {
+	for _, query := range queries {
+		var found []int
+		if err := query.Limit(1).Scan(&found).Error; err != nil {
+			return false, err
+		}
+		if len(found) > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
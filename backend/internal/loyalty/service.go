@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"time"
 
+	"ecommerce-saas/internal/discount"
+	"ecommerce-saas/internal/notification"
+
 	"github.com/google/uuid"
 )
 
@@ -48,22 +51,72 @@ type Service interface {
 	// Analytics
 	GetStats(ctx context.Context, tenantID uuid.UUID) (*LoyaltyStats, error)
 	GetAnalytics(ctx context.Context, tenantID uuid.UUID) (*LoyaltyAnalytics, error)
+
+	// Automatic earning
+	GetEarningRules(ctx context.Context, tenantID, programID uuid.UUID) (*EarningRules, error)
+	UpdateEarningRules(ctx context.Context, tenantID, programID uuid.UUID, rules *EarningRules) (*EarningRules, error)
+	EarnForOrder(ctx context.Context, activity *OrderActivity) ([]*LoyaltyTransaction, error)
+	ReverseForOrder(ctx context.Context, req *ReverseOrderRequest) ([]*LoyaltyTransaction, error)
+	AwardBonus(ctx context.Context, req *AwardBonusRequest) (*LoyaltyTransaction, error)
+	ReleasePendingPoints(ctx context.Context, tenantID uuid.UUID, now time.Time) (*ReleaseResult, error)
+	AwardBirthdayBonuses(ctx context.Context, tenantID uuid.UUID, now time.Time) (*BirthdayBonusResult, error)
+
+	// Tiers
+	CreateTier(ctx context.Context, req *CreateTierRequest) (*LoyaltyTier, error)
+	GetTier(ctx context.Context, tenantID, tierID uuid.UUID) (*LoyaltyTier, error)
+	UpdateTier(ctx context.Context, tenantID, tierID uuid.UUID, req *UpdateTierRequest) (*LoyaltyTier, error)
+	DeleteTier(ctx context.Context, tenantID, tierID uuid.UUID) error
+	ListTiers(ctx context.Context, tenantID, programID uuid.UUID) ([]*LoyaltyTier, error)
+	GetTierRules(ctx context.Context, tenantID, programID uuid.UUID) (*TierRules, error)
+	UpdateTierRules(ctx context.Context, tenantID, programID uuid.UUID, rules *TierRules) (*TierRules, error)
+	GetCustomerTier(ctx context.Context, tenantID, userID uuid.UUID) (*LoyaltyTier, error)
+	EvaluateAccountTier(ctx context.Context, tenantID, accountID uuid.UUID, now time.Time) (*TierEvaluation, error)
+	EvaluateTiers(ctx context.Context, tenantID uuid.UUID, now time.Time) (*TierEvaluationResult, error)
+
+	// Points expiry and redemption
+	GetPointsRules(ctx context.Context, tenantID, programID uuid.UUID) (*PointsRules, error)
+	UpdatePointsRules(ctx context.Context, tenantID, programID uuid.UUID, rules *PointsRules) (*PointsRules, error)
+	QuotePoints(ctx context.Context, tenantID, userID uuid.UUID, points int, orderAmount float64) (*PointsQuote, error)
+	ExpirePoints(ctx context.Context, tenantID uuid.UUID, now time.Time) (*PointsExpiryResult, error)
+	SendExpiryReminders(ctx context.Context, tenantID uuid.UUID, now time.Time) (*ExpiryReminderResult, error)
+	RestoreRedeemedPoints(ctx context.Context, tenantID, orderID uuid.UUID, reason string) ([]*LoyaltyTransaction, error)
+
+	// Referrals
+	GetReferralRules(ctx context.Context, tenantID, programID uuid.UUID) (*ReferralRules, error)
+	UpdateReferralRules(ctx context.Context, tenantID, programID uuid.UUID, rules *ReferralRules) (*ReferralRules, error)
+	GetReferralCode(ctx context.Context, tenantID, userID uuid.UUID) (*ReferralCode, error)
+	TrackReferralClick(ctx context.Context, tenantID uuid.UUID, code string) (*ReferralCode, error)
+	AttributeReferral(ctx context.Context, req *AttributeReferralRequest) (*Referral, error)
+	QualifyReferral(ctx context.Context, o *ReferralOrder) (*Referral, error)
+	CancelReferralForOrder(ctx context.Context, tenantID, orderID uuid.UUID, reason string) (*Referral, error)
+	IssueReferralRewards(ctx context.Context, tenantID uuid.UUID, now time.Time) (*ReferralRewardResult, error)
+	ListReferrals(ctx context.Context, tenantID uuid.UUID, req *ListReferralsRequest) (*ListReferralsResponse, error)
+	GetReferral(ctx context.Context, tenantID, referralID uuid.UUID) (*Referral, error)
+	RejectReferral(ctx context.Context, tenantID, referralID uuid.UUID, reason string) (*Referral, error)
 }
 
 // ServiceImpl implements the loyalty service
 type ServiceImpl struct {
-	repo Repository
+	repo          Repository
+	notifications notification.Service // Tier change, expiry and referral emails; optional
+	discounts     discount.Service     // Store credit and coupon referral rewards; optional
 }
 
-// NewService creates a new loyalty service
-func NewService(repo Repository) Service {
+// NewService creates a new loyalty service. notifications and discounts may be nil.
+func NewService(repo Repository, notifications notification.Service, discounts discount.Service) Service {
 	return &ServiceImpl{
-		repo: repo,
+		repo:          repo,
+		notifications: notifications,
+		discounts:     discounts,
 	}
 }
 
 // Program management
 func (s *ServiceImpl) CreateProgram(ctx context.Context, req *CreateProgramRequest) (*LoyaltyProgram, error) {
+	if _, err := parseSettings(req.Settings); err != nil {
+		return nil, err
+	}
+
 	program := &LoyaltyProgram{
 		TenantID:    req.TenantID,
 		Name:        req.Name,
@@ -95,6 +148,9 @@ func (s *ServiceImpl) UpdateProgram(ctx context.Context, tenantID, programID uui
 		program.Type = *req.Type
 	}
 	if req.Settings != nil {
+		if _, err := parseSettings(*req.Settings); err != nil {
+			return nil, err
+		}
 		program.Settings = *req.Settings
 	}
 	if req.Status != nil {
@@ -121,12 +177,17 @@ func (s *ServiceImpl) ListPrograms(ctx context.Context, tenantID uuid.UUID, req
 
 // Account management
 func (s *ServiceImpl) CreateAccount(ctx context.Context, req *CreateAccountRequest) (*LoyaltyAccount, error) {
+	tier, err := s.entryTier(req.TenantID, req.ProgramID)
+	if err != nil {
+		return nil, err
+	}
+
 	account := &LoyaltyAccount{
 		TenantID:  req.TenantID,
 		UserID:    req.UserID,
 		ProgramID: req.ProgramID,
 		Points:    0,
-		Tier:      "bronze",
+		Tier:      tier,
 		Status:    "active",
 	}
 	return s.repo.CreateAccount(account)
@@ -154,6 +215,9 @@ func (s *ServiceImpl) UpdateAccount(ctx context.Context, tenantID, accountID uui
 	if req.Tier != nil {
 		account.Tier = *req.Tier
 	}
+	if req.Birthday != nil {
+		account.Birthday = req.Birthday
+	}
 
 	// Save updated account
 	updatedAccount, err := s.repo.UpdateAccount(account)
@@ -184,30 +248,34 @@ func (s *ServiceImpl) ListAccounts(ctx context.Context, tenantID uuid.UUID, req
 
 // Points operations
 func (s *ServiceImpl) EarnPoints(ctx context.Context, req *EarnPointsRequest) (*LoyaltyTransaction, error) {
-	// Get account
-	account, err := s.repo.GetAccount(req.TenantID, req.AccountID)
-	if err != nil {
-		return nil, err
-	}
-
-	// Create transaction
-	transaction := &LoyaltyTransaction{
-		TenantID:    req.TenantID,
-		AccountID:   req.AccountID,
-		Type:        "earned",
-		Points:      int(req.Points),
-		Description: req.Reason,
-		OrderID:     req.OrderID,
-	}
-
-	transaction, err = s.repo.CreateTransaction(transaction)
-	if err != nil {
-		return nil, err
-	}
-
-	// Update account points
-	account.Points += int(req.Points)
-	_, err = s.repo.UpdateAccount(account)
+	var transaction *LoyaltyTransaction
+	err := s.repo.Transaction(func(repo Repository) error {
+		// Get account
+		account, err := repo.GetAccountForUpdate(req.TenantID, req.AccountID)
+		if err != nil {
+			return err
+		}
+
+		// Create transaction as a new lot
+		transaction, err = repo.CreateTransaction(&LoyaltyTransaction{
+			TenantID:        req.TenantID,
+			AccountID:       req.AccountID,
+			Type:            TypeEarned,
+			Points:          int(req.Points),
+			RemainingPoints: int(req.Points),
+			ExpiresAt:       programSettings(repo, req.TenantID, account.ProgramID).Points.lotExpiry(time.Now()),
+			Description:     req.Reason,
+			OrderID:         req.OrderID,
+		})
+		if err != nil {
+			return err
+		}
+
+		// Update account points
+		account.Points += int(req.Points)
+		_, err = repo.UpdateAccount(account)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -216,35 +284,33 @@ func (s *ServiceImpl) EarnPoints(ctx context.Context, req *EarnPointsRequest) (*
 }
 
 func (s *ServiceImpl) RedeemPoints(ctx context.Context, req *RedeemPointsRequest) (*LoyaltyTransaction, error) {
-	// Get account
-	account, err := s.repo.GetAccount(req.TenantID, req.AccountID)
-	if err != nil {
-		return nil, err
-	}
-
-	// Check if account has enough points
-	if account.Points < int(req.Points) {
-		return nil, fmt.Errorf("insufficient points")
-	}
-
-	// Create transaction
-	transaction := &LoyaltyTransaction{
-		TenantID:    req.TenantID,
-		AccountID:   req.AccountID,
-		Type:        "redeemed",
-		Points:      -int(req.Points),
-		Description: req.Reason,
-		OrderID:     req.OrderID,
-	}
-
-	transaction, err = s.repo.CreateTransaction(transaction)
-	if err != nil {
-		return nil, err
-	}
-
-	// Update account points
-	account.Points -= int(req.Points)
-	_, err = s.repo.UpdateAccount(account)
+	var transaction *LoyaltyTransaction
+	err := s.repo.Transaction(func(repo Repository) error {
+		// Get account
+		account, err := repo.GetAccountForUpdate(req.TenantID, req.AccountID)
+		if err != nil {
+			return err
+		}
+
+		// Check if account has enough points
+		if account.Points < int(req.Points) {
+			return ErrInsufficientPoints
+		}
+
+		// Spend the oldest lots first
+		transaction, err = debit(repo, account, int(req.Points), &LoyaltyTransaction{
+			Type:        TypeRedeemed,
+			Description: req.Reason,
+			OrderID:     req.OrderID,
+		})
+		if err != nil {
+			return err
+		}
+
+		// Update account points
+		_, err = repo.UpdateAccount(account)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -442,28 +508,39 @@ func (s *ServiceImpl) ListRewards(ctx context.Context, tenantID uuid.UUID, req *
 
 // Points operations
 func (s *ServiceImpl) AdjustPoints(ctx context.Context, req *AdjustPointsRequest) (*LoyaltyTransaction, error) {
-	// Create transaction
-	transaction := &LoyaltyTransaction{
-		TenantID:    req.TenantID,
-		AccountID:   req.AccountID,
-		Type:        "adjusted",
-		Points:      int(req.Points),
-		Description: req.Reason,
-	}
-
-	transaction, err := s.repo.CreateTransaction(transaction)
-	if err != nil {
-		return nil, err
-	}
-
-	// Update account points
-	account, err := s.repo.GetAccount(req.TenantID, req.AccountID)
-	if err != nil {
-		return nil, err
-	}
-
-	account.Points += int(req.Points)
-	_, err = s.repo.UpdateAccount(account)
+	var transaction *LoyaltyTransaction
+	err := s.repo.Transaction(func(repo Repository) error {
+		account, err := repo.GetAccountForUpdate(req.TenantID, req.AccountID)
+		if err != nil {
+			return err
+		}
+
+		// Deductions spend the oldest lots; additions start a new lot
+		if req.Points < 0 {
+			transaction, err = debit(repo, account, int(-req.Points), &LoyaltyTransaction{
+				Type:        TypeAdjusted,
+				Description: req.Reason,
+			})
+		} else {
+			transaction, err = repo.CreateTransaction(&LoyaltyTransaction{
+				TenantID:        req.TenantID,
+				AccountID:       req.AccountID,
+				Type:            TypeAdjusted,
+				Points:          int(req.Points),
+				RemainingPoints: int(req.Points),
+				ExpiresAt:       programSettings(repo, req.TenantID, account.ProgramID).Points.lotExpiry(time.Now()),
+				Description:     req.Reason,
+			})
+			account.Points += int(req.Points)
+		}
+		if err != nil {
+			return err
+		}
+
+		// Update account points
+		_, err = repo.UpdateAccount(account)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,519 @@
+package loyalty
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"ecommerce-saas/internal/discount"
+	"ecommerce-saas/internal/notification"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Tier qualification metrics
+const (
+	TierMetricPoints = "points"
+	TierMetricSpend  = "spend"
+)
+
+// Outcomes of a tier evaluation
+const (
+	TierUnchanged    = "unchanged"
+	TierUpgraded     = "upgraded"
+	TierDowngraded   = "downgraded"
+	TierGraceStarted = "grace_started"
+	TierAssigned     = "assigned" // The account's tier was not one of the program's
+)
+
+const tierEvaluationBatchSize = 200
+
+var (
+	ErrNoTiers       = errors.New("loyalty program has no tiers")
+	ErrTierCodeTaken = errors.New("tier code is already used in this program")
+	ErrTierInUse     = errors.New("tier is assigned to accounts")
+)
+
+// TierRules configures how accounts qualify for tiers
+type TierRules struct {
+	Metric     string `json:"metric,omitempty"` // points (default) or spend
+	WindowDays int    `json:"window_days"`      // Rolling qualification window; 0 counts all history
+	GraceDays  int    `json:"grace_days"`       // How long a tier is kept after dropping below it
+}
+
+// Validate checks the rules for values that cannot be applied
+func (r *TierRules) Validate() error {
+	if r.Metric != "" && r.Metric != TierMetricPoints && r.Metric != TierMetricSpend {
+		return fmt.Errorf("%w: tier metric must be points or spend", ErrInvalidSettings)
+	}
+	if r.WindowDays < 0 || r.GraceDays < 0 {
+		return fmt.Errorf("%w: tier window and grace days cannot be negative", ErrInvalidSettings)
+	}
+	return nil
+}
+
+// TierEvaluation is the outcome of re-evaluating one account
+type TierEvaluation struct {
+	AccountID       uuid.UUID  `json:"account_id"`
+	PreviousTier    string     `json:"previous_tier"`
+	Tier            string     `json:"tier"`
+	QualifiedTier   string     `json:"qualified_tier"` // What the qualifying value alone earns
+	QualifyingValue float64    `json:"qualifying_value"`
+	GraceUntil      *time.Time `json:"grace_until,omitempty"`
+	Change          string     `json:"change"`
+}
+
+// TierEvaluationResult summarises a run of the tier evaluation job
+type TierEvaluationResult struct {
+	Evaluated  int `json:"evaluated"`
+	Upgraded   int `json:"upgraded"`
+	Downgraded int `json:"downgraded"`
+	Assigned   int `json:"assigned"`
+	InGrace    int `json:"in_grace"`
+	Failed     int `json:"failed"`
+}
+
+// decideTier picks the account's tier for a qualifying value. Upgrades apply
+// at once; a downgrade starts the grace period and only applies once it has
+// passed without the account qualifying again. An account whose tier is not
+// one of the program's is placed in the tier it qualifies for. tiers must be
+// sorted by rank.
+func decideTier(account *LoyaltyAccount, tiers []*LoyaltyTier, value float64, graceDays int, now time.Time) (*LoyaltyTier, *LoyaltyTier, *time.Time, string) {
+	qualified := tiers[0]
+	var current *LoyaltyTier
+	for _, tier := range tiers {
+		if value >= tier.Threshold {
+			qualified = tier
+		}
+		if tier.Code == account.Tier {
+			current = tier
+		}
+	}
+
+	switch {
+	case current == nil:
+		return qualified, qualified, nil, TierAssigned
+	case qualified.Rank > current.Rank:
+		return qualified, qualified, nil, TierUpgraded
+	case qualified.Rank == current.Rank:
+		return current, qualified, nil, TierUnchanged
+	case graceDays <= 0:
+		return qualified, qualified, nil, TierDowngraded
+	case account.TierGraceUntil == nil:
+		graceUntil := now.AddDate(0, 0, graceDays)
+		return current, qualified, &graceUntil, TierGraceStarted
+	case now.Before(*account.TierGraceUntil):
+		return current, qualified, account.TierGraceUntil, TierUnchanged
+	default:
+		return qualified, qualified, nil, TierDowngraded
+	}
+}
+
+// Tier management
+
+func (s *ServiceImpl) CreateTier(ctx context.Context, req *CreateTierRequest) (*LoyaltyTier, error) {
+	if _, err := s.repo.GetProgram(req.TenantID, req.ProgramID); err != nil {
+		return nil, err
+	}
+
+	code := strings.ToLower(strings.TrimSpace(req.Code))
+	if _, err := s.repo.GetTierByCode(req.TenantID, req.ProgramID, code); err == nil {
+		return nil, ErrTierCodeTaken
+	}
+
+	multiplier := req.EarningMultiplier
+	if multiplier == 0 {
+		multiplier = 1
+	}
+
+	return s.repo.CreateTier(&LoyaltyTier{
+		TenantID:          req.TenantID,
+		ProgramID:         req.ProgramID,
+		Name:              req.Name,
+		Code:              code,
+		Rank:              req.Rank,
+		Threshold:         req.Threshold,
+		EarningMultiplier: multiplier,
+		FreeShipping:      req.FreeShipping,
+		Benefits:          req.Benefits,
+	})
+}
+
+func (s *ServiceImpl) GetTier(ctx context.Context, tenantID, tierID uuid.UUID) (*LoyaltyTier, error) {
+	return s.repo.GetTier(tenantID, tierID)
+}
+
+// UpdateTier changes a tier's thresholds and benefits. The code stays fixed
+// because accounts refer to it; changes take effect at the next evaluation.
+func (s *ServiceImpl) UpdateTier(ctx context.Context, tenantID, tierID uuid.UUID, req *UpdateTierRequest) (*LoyaltyTier, error) {
+	tier, err := s.repo.GetTier(tenantID, tierID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		tier.Name = *req.Name
+	}
+	if req.Rank != nil {
+		tier.Rank = *req.Rank
+	}
+	if req.Threshold != nil {
+		tier.Threshold = *req.Threshold
+	}
+	if req.EarningMultiplier != nil {
+		tier.EarningMultiplier = *req.EarningMultiplier
+	}
+	if req.FreeShipping != nil {
+		tier.FreeShipping = *req.FreeShipping
+	}
+	if req.Benefits != nil {
+		tier.Benefits = *req.Benefits
+	}
+
+	return s.repo.UpdateTier(tier)
+}
+
+// DeleteTier removes a tier no account holds
+func (s *ServiceImpl) DeleteTier(ctx context.Context, tenantID, tierID uuid.UUID) error {
+	tier, err := s.repo.GetTier(tenantID, tierID)
+	if err != nil {
+		return err
+	}
+
+	count, err := s.repo.CountTierAccounts(tenantID, tier.ProgramID, tier.Code)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: %d account(s) hold %s", ErrTierInUse, count, tier.Name)
+	}
+
+	return s.repo.DeleteTier(tenantID, tierID)
+}
+
+func (s *ServiceImpl) ListTiers(ctx context.Context, tenantID, programID uuid.UUID) ([]*LoyaltyTier, error) {
+	return s.repo.ListTiers(tenantID, programID)
+}
+
+// GetTierRules returns how a program's accounts qualify for tiers
+func (s *ServiceImpl) GetTierRules(ctx context.Context, tenantID, programID uuid.UUID) (*TierRules, error) {
+	program, err := s.repo.GetProgram(tenantID, programID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := program.ParseSettings()
+	if err != nil {
+		return nil, err
+	}
+	return &settings.Tiers, nil
+}
+
+// UpdateTierRules replaces a program's tier qualification rules
+func (s *ServiceImpl) UpdateTierRules(ctx context.Context, tenantID, programID uuid.UUID, rules *TierRules) (*TierRules, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	program, err := s.repo.GetProgram(tenantID, programID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := withSetting(program.Settings, "tiers", rules)
+	if err != nil {
+		return nil, err
+	}
+	program.Settings = settings
+
+	if _, err := s.repo.UpdateProgram(program); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// GetCustomerTier returns the tier of a customer's active account, or nil
+func (s *ServiceImpl) GetCustomerTier(ctx context.Context, tenantID, userID uuid.UUID) (*LoyaltyTier, error) {
+	account, err := s.repo.GetAccountByUser(tenantID, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if account.Status != "active" || account.Program == nil || account.Program.Status != "active" {
+		return nil, nil
+	}
+
+	tier, err := s.repo.GetTierByCode(tenantID, account.ProgramID, account.Tier)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return tier, err
+}
+
+// entryTier returns the code of the program's lowest tier, which new accounts
+// start in. Programs without tiers leave it to the column default.
+func (s *ServiceImpl) entryTier(tenantID, programID uuid.UUID) (string, error) {
+	tiers, err := s.repo.ListTiers(tenantID, programID)
+	if err != nil {
+		return "", err
+	}
+	if len(tiers) == 0 {
+		return "", nil
+	}
+	return tiers[0].Code, nil
+}
+
+// Tier evaluation
+
+// EvaluateAccountTier re-evaluates one account's tier now
+func (s *ServiceImpl) EvaluateAccountTier(ctx context.Context, tenantID, accountID uuid.UUID, now time.Time) (*TierEvaluation, error) {
+	account, err := s.repo.GetAccount(tenantID, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	tiers, err := s.repo.ListTiers(tenantID, account.ProgramID)
+	if err != nil {
+		return nil, err
+	}
+	if len(tiers) == 0 {
+		return nil, ErrNoTiers
+	}
+
+	settings, err := account.Program.ParseSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.evaluateAccount(account, tiers, &settings.Tiers, now)
+}
+
+// EvaluateTiers re-evaluates every active account of the tenant's active
+// programs. Meant to run nightly.
+func (s *ServiceImpl) EvaluateTiers(ctx context.Context, tenantID uuid.UUID, now time.Time) (*TierEvaluationResult, error) {
+	programs, err := s.repo.ListActivePrograms(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TierEvaluationResult{}
+	for _, program := range programs {
+		tiers, err := s.repo.ListTiers(tenantID, program.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(tiers) == 0 {
+			continue
+		}
+		settings, err := program.ParseSettings()
+		if err != nil {
+			return nil, err
+		}
+
+		var afterID *uuid.UUID
+		for {
+			accounts, err := s.repo.ListProgramAccounts(tenantID, program.ID, afterID, tierEvaluationBatchSize)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, account := range accounts {
+				evaluation, err := s.evaluateAccount(account, tiers, &settings.Tiers, now)
+				if err != nil {
+					result.Failed++
+					continue
+				}
+				result.Evaluated++
+				switch evaluation.Change {
+				case TierUpgraded:
+					result.Upgraded++
+				case TierDowngraded:
+					result.Downgraded++
+				case TierAssigned:
+					result.Assigned++
+				}
+				if evaluation.GraceUntil != nil {
+					result.InGrace++
+				}
+			}
+
+			if len(accounts) < tierEvaluationBatchSize {
+				break
+			}
+			afterID = &accounts[len(accounts)-1].ID
+		}
+	}
+
+	return result, nil
+}
+
+func (s *ServiceImpl) evaluateAccount(account *LoyaltyAccount, tiers []*LoyaltyTier, rules *TierRules, now time.Time) (*TierEvaluation, error) {
+	var since *time.Time
+	if rules.WindowDays > 0 {
+		windowStart := now.AddDate(0, 0, -rules.WindowDays)
+		since = &windowStart
+	}
+
+	var value float64
+	var err error
+	if rules.Metric == TierMetricSpend {
+		value, err = s.repo.SumQualifyingSpend(account.TenantID, account.UserID, since)
+	} else {
+		value, err = s.repo.SumQualifyingPoints(account.TenantID, account.ID, since)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	evaluation := &TierEvaluation{AccountID: account.ID, QualifyingValue: value}
+	var previous, tier *LoyaltyTier
+	var locked *LoyaltyAccount
+
+	err = s.repo.Transaction(func(repo Repository) error {
+		locked, err = repo.GetAccountForUpdate(account.TenantID, account.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, candidate := range tiers {
+			if candidate.Code == locked.Tier {
+				previous = candidate
+			}
+		}
+
+		var qualified *LoyaltyTier
+		var graceUntil *time.Time
+		tier, qualified, graceUntil, evaluation.Change = decideTier(locked, tiers, value, rules.GraceDays, now)
+
+		evaluation.PreviousTier = locked.Tier
+		evaluation.Tier = tier.Code
+		evaluation.QualifiedTier = qualified.Code
+		evaluation.GraceUntil = graceUntil
+
+		if tier.Code != locked.Tier {
+			locked.TierChangedAt = &now
+		}
+		locked.Tier = tier.Code
+		locked.TierGraceUntil = graceUntil
+		locked.TierQualifyingValue = value
+		locked.TierEvaluatedAt = &now
+
+		_, err = repo.UpdateAccount(locked)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Placing an account with an unknown tier is bookkeeping, not news
+	if evaluation.Change != TierUnchanged && evaluation.Change != TierAssigned {
+		// The tier has changed either way; a failed email is not worth failing over
+		_ = s.notifyTierChange(locked, evaluation, previous, tier)
+	}
+
+	return evaluation, nil
+}
+
+// notifyTierChange emails the customer about an upgrade, a downgrade or the
+// start of a grace period
+func (s *ServiceImpl) notifyTierChange(account *LoyaltyAccount, evaluation *TierEvaluation, previous, tier *LoyaltyTier) error {
+	if s.notifications == nil {
+		return nil
+	}
+
+	customer, err := s.repo.GetCustomer(account.TenantID, account.UserID)
+	if err != nil {
+		return err
+	}
+
+	variables := map[string]interface{}{
+		"customer_name":      strings.TrimSpace(customer.FirstName + " " + customer.LastName),
+		"change":             evaluation.Change,
+		"tier_name":          tier.Name,
+		"tier_benefits":      tier.Benefits,
+		"previous_tier_name": "",
+		"qualifying_value":   fmt.Sprintf("%.2f", evaluation.QualifyingValue),
+		"grace_until":        "",
+	}
+	if previous != nil {
+		variables["previous_tier_name"] = previous.Name
+	}
+	if evaluation.GraceUntil != nil {
+		variables["grace_until"] = evaluation.GraceUntil.Format("2006-01-02")
+	}
+
+	req := &notification.SendNotificationRequest{
+		Type:       notification.TypeEmail,
+		Channel:    notification.ChannelLoyaltyTier,
+		Recipients: []string{customer.Email},
+		Variables:  variables,
+		Priority:   notification.PriorityNormal,
+		UserID:     account.UserID.String(),
+	}
+
+	if template := s.template(account.TenantID, notification.ChannelLoyaltyTier); template != nil {
+		req.TemplateID = template.ID.String()
+	} else {
+		req.Subject, req.Content = defaultTierChangeEmail(variables)
+	}
+
+	_, err = s.notifications.SendNotification(account.TenantID, req)
+	return err
+}
+
+// template picks the tenant's own active email template for a channel over a
+// system default
+func (s *ServiceImpl) template(tenantID uuid.UUID, channel string) *notification.NotificationTemplate {
+	templates, err := s.notifications.ListTemplates(tenantID, notification.TypeEmail, channel)
+	if err != nil || len(templates) == 0 {
+		return nil
+	}
+	for _, template := range templates {
+		if !template.IsDefault {
+			return template
+		}
+	}
+	return templates[0]
+}
+
+func defaultTierChangeEmail(variables map[string]interface{}) (string, string) {
+	name := variables["customer_name"]
+	tierName := variables["tier_name"]
+
+	switch variables["change"] {
+	case TierUpgraded:
+		return fmt.Sprintf("Welcome to %s", tierName),
+			fmt.Sprintf("Hi %s,\n\nCongratulations, you are now a %s member.\n\n%s", name, tierName, variables["tier_benefits"])
+	case TierGraceStarted:
+		return fmt.Sprintf("Keep your %s status", tierName),
+			fmt.Sprintf("Hi %s,\n\nYou no longer meet the requirements for %s. Qualify again before %s to keep your status.", name, tierName, variables["grace_until"])
+	default:
+		return fmt.Sprintf("Your membership is now %s", tierName),
+			fmt.Sprintf("Hi %s,\n\nYour membership has changed from %s to %s.", name, variables["previous_tier_name"], tierName)
+	}
+}
+
+// TierResolver exposes customers' tiers to discount evaluation; it implements
+// discount.TierResolver
+type TierResolver struct {
+	service Service
+}
+
+// NewTierResolver creates a tier resolver backed by the loyalty service
+func NewTierResolver(service Service) *TierResolver {
+	return &TierResolver{service: service}
+}
+
+// CustomerTier returns the customer's tier and its benefits, or nil
+func (r *TierResolver) CustomerTier(ctx context.Context, tenantID, customerID uuid.UUID) (*discount.LoyaltyTier, error) {
+	tier, err := r.service.GetCustomerTier(ctx, tenantID, customerID)
+	if err != nil || tier == nil {
+		return nil, err
+	}
+	return &discount.LoyaltyTier{Code: tier.Code, Name: tier.Name, FreeShipping: tier.FreeShipping}, nil
+}
@@ -0,0 +1,137 @@
+package metafield
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Handler handles HTTP requests for metafields
+type Handler struct {
+	service Service
+}
+
+// NewHandler creates a new metafield handler
+func NewHandler(service Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers metafield routes
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	metafields := router.Group("/metafields")
+	{
+		metafields.GET("", h.ListMetafields)
+		metafields.PUT("", h.SetMetafield)
+		metafields.GET("/:id", h.GetMetafield)
+		metafields.DELETE("/:id", h.DeleteMetafield)
+	}
+}
+
+// ListMetafields handles GET /api/metafields?owner_type=&owner_id=&namespace=
+func (h *Handler) ListMetafields(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	var filter MetafieldFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	metafields, err := h.service.ListMetafields(c.Request.Context(), tenantID.(uuid.UUID), filter)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": metafields})
+}
+
+// GetMetafield handles GET /api/metafields/:id
+func (h *Handler) GetMetafield(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	metafieldID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid metafield ID"})
+		return
+	}
+
+	metafield, err := h.service.GetMetafield(c.Request.Context(), tenantID.(uuid.UUID), metafieldID)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": metafield})
+}
+
+// SetMetafield handles PUT /api/metafields
+func (h *Handler) SetMetafield(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	var req SetMetafieldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	metafield, err := h.service.SetMetafield(c.Request.Context(), tenantID.(uuid.UUID), req)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Metafield saved successfully",
+		"data":    metafield,
+	})
+}
+
+// DeleteMetafield handles DELETE /api/metafields/:id
+func (h *Handler) DeleteMetafield(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
+		return
+	}
+
+	metafieldID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid metafield ID"})
+		return
+	}
+
+	if err := h.service.DeleteMetafield(c.Request.Context(), tenantID.(uuid.UUID), metafieldID); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Metafield deleted successfully"})
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrMetafieldNotFound), errors.Is(err, ErrOwnerNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInvalidMetafield):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,181 @@
+package metafield
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Records metafields can be attached to
+const (
+	OwnerProduct  = "product"
+	OwnerVariant  = "variant"
+	OwnerOrder    = "order"
+	OwnerCustomer = "customer"
+)
+
+// ValueType is the type of a metafield value
+type ValueType string
+
+const (
+	TypeText    ValueType = "text"
+	TypeNumber  ValueType = "number"
+	TypeBoolean ValueType = "boolean"
+	TypeDate    ValueType = "date"
+	TypeURL     ValueType = "url"
+	TypeJSON    ValueType = "json"
+)
+
+// Largest encoded value accepted, so integration data stays metadata
+const maxValueBytes = 64 * 1024
+
+var (
+	ErrMetafieldNotFound = errors.New("metafield not found")
+	ErrOwnerNotFound     = errors.New("metafield owner not found")
+	ErrInvalidMetafield  = errors.New("invalid metafield")
+)
+
+// Namespaces and keys, e.g. "erp" and "supplier_code"
+var identifierPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+
+// Metafield is a typed value that apps and integrations attach to a product,
+// variant, order or customer. A namespace keeps the fields of different apps
+// apart; an owner has at most one field per namespace and key.
+type Metafield struct {
+	ID        uuid.UUID   `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TenantID  uuid.UUID   `json:"tenant_id" gorm:"type:uuid;not null;index"`
+	OwnerType string      `json:"owner_type" gorm:"not null"`
+	OwnerID   uuid.UUID   `json:"owner_id" gorm:"type:uuid;not null"`
+	Namespace string      `json:"namespace" gorm:"not null"`
+	Key       string      `json:"key" gorm:"not null"`
+	Type      ValueType   `json:"type" gorm:"not null"`
+	Value     interface{} `json:"value" gorm:"serializer:json;type:jsonb"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+func (Metafield) TableName() string {
+	return "metafields"
+}
+
+// SetMetafieldRequest creates or replaces the metafield of an owner with the
+// namespace and key
+type SetMetafieldRequest struct {
+	OwnerType string      `json:"owner_type" binding:"required"`
+	OwnerID   uuid.UUID   `json:"owner_id" binding:"required"`
+	Namespace string      `json:"namespace" binding:"required"`
+	Key       string      `json:"key" binding:"required"`
+	Type      ValueType   `json:"type" binding:"required"`
+	Value     interface{} `json:"value"`
+}
+
+// MetafieldFilter selects the metafields of an owner
+type MetafieldFilter struct {
+	OwnerType string    `form:"owner_type" binding:"required"`
+	OwnerID   uuid.UUID `form:"owner_id" binding:"required"`
+	Namespace string    `form:"namespace"`
+}
+
+// validOwnerType tells whether metafields can be attached to an owner type
+func validOwnerType(ownerType string) bool {
+	switch ownerType {
+	case OwnerProduct, OwnerVariant, OwnerOrder, OwnerCustomer:
+		return true
+	}
+	return false
+}
+
+// normalizeValue checks a value against its type and returns it in stored
+// form: strings for text, dates (YYYY-MM-DD) and URLs, float64 for numbers,
+// bool for booleans and any JSON value for json
+func normalizeValue(valueType ValueType, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, fmt.Errorf("%w: value is required", ErrInvalidMetafield)
+	}
+
+	switch valueType {
+	case TypeText:
+		if text, ok := value.(string); ok {
+			return text, nil
+		}
+	case TypeNumber:
+		switch number := value.(type) {
+		case float64:
+			return number, nil
+		case string:
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(number), 64); err == nil {
+				return parsed, nil
+			}
+		}
+	case TypeBoolean:
+		switch flag := value.(type) {
+		case bool:
+			return flag, nil
+		case string:
+			if parsed, err := strconv.ParseBool(strings.TrimSpace(flag)); err == nil {
+				return parsed, nil
+			}
+		}
+	case TypeDate:
+		if text, ok := value.(string); ok {
+			for _, layout := range []string{"2006-01-02", time.RFC3339} {
+				if date, err := time.Parse(layout, strings.TrimSpace(text)); err == nil {
+					return date.Format("2006-01-02"), nil
+				}
+			}
+		}
+	case TypeURL:
+		if text, ok := value.(string); ok {
+			parsed, err := url.Parse(strings.TrimSpace(text))
+			if err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != "" {
+				return parsed.String(), nil
+			}
+		}
+	case TypeJSON:
+		return value, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidMetafield, valueType)
+	}
+	return nil, fmt.Errorf("%w: value must be a %s", ErrInvalidMetafield, valueType)
+}
+
+// newMetafield validates a request into the fields of a metafield
+func newMetafield(req *SetMetafieldRequest) (*Metafield, error) {
+	if !validOwnerType(req.OwnerType) {
+		return nil, fmt.Errorf("%w: unknown owner type %q", ErrInvalidMetafield, req.OwnerType)
+	}
+
+	namespace := strings.ToLower(strings.TrimSpace(req.Namespace))
+	key := strings.ToLower(strings.TrimSpace(req.Key))
+	if !identifierPattern.MatchString(namespace) || !identifierPattern.MatchString(key) {
+		return nil, fmt.Errorf("%w: namespace and key must be lowercase letters, digits, '_', '.' or '-'", ErrInvalidMetafield)
+	}
+
+	value, err := normalizeValue(req.Type, req.Value)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMetafield, err)
+	}
+	if len(encoded) > maxValueBytes {
+		return nil, fmt.Errorf("%w: value is larger than %d bytes", ErrInvalidMetafield, maxValueBytes)
+	}
+
+	return &Metafield{
+		OwnerType: req.OwnerType,
+		OwnerID:   req.OwnerID,
+		Namespace: namespace,
+		Key:       key,
+		Type:      req.Type,
+		Value:     value,
+	}, nil
+}
@@ -0,0 +1,41 @@
+package metafield
+
+import (
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Module represents the metafield module
+type Module struct {
+	repository Repository
+	service    Service
+	handler    *Handler
+}
+
+// NewModule creates a new metafield module instance
+func NewModule(db *gorm.DB) *Module {
+	repo := NewRepository(db)
+	svc := NewService(repo)
+	handler := NewHandler(svc)
+
+	return &Module{
+		repository: repo,
+		service:    svc,
+		handler:    handler,
+	}
+}
+
+// RegisterRoutes registers all metafield routes
+func (m *Module) RegisterRoutes(router *gin.RouterGroup) {
+	m.handler.RegisterRoutes(router)
+}
+
+// GetHandler returns the metafield handler
+func (m *Module) GetHandler() *Handler {
+	return m.handler
+}
+
+// GetService returns the metafield service
+func (m *Module) GetService() Service {
+	return m.service
+}
@@ -0,0 +1,106 @@
+package metafield
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository defines the metafield repository interface
+type Repository interface {
+	List(tenantID uuid.UUID, filter MetafieldFilter) ([]*Metafield, error)
+	Get(tenantID, metafieldID uuid.UUID) (*Metafield, error)
+	Upsert(metafield *Metafield) (*Metafield, error)
+	Delete(tenantID, metafieldID uuid.UUID) error
+	OwnerExists(tenantID uuid.UUID, ownerType string, ownerID uuid.UUID) (bool, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new metafield repository
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) List(tenantID uuid.UUID, filter MetafieldFilter) ([]*Metafield, error) {
+	query := r.db.Where("tenant_id = ? AND owner_type = ? AND owner_id = ?", tenantID, filter.OwnerType, filter.OwnerID)
+	if filter.Namespace != "" {
+		query = query.Where("namespace = ?", filter.Namespace)
+	}
+
+	var metafields []*Metafield
+	if err := query.Order("namespace, key").Find(&metafields).Error; err != nil {
+		return nil, fmt.Errorf("failed to list metafields: %w", err)
+	}
+	return metafields, nil
+}
+
+func (r *repository) Get(tenantID, metafieldID uuid.UUID) (*Metafield, error) {
+	var metafield Metafield
+	err := r.db.Where("tenant_id = ? AND id = ?", tenantID, metafieldID).First(&metafield).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMetafieldNotFound
+		}
+		return nil, fmt.Errorf("failed to get metafield: %w", err)
+	}
+	return &metafield, nil
+}
+
+// Upsert inserts a metafield or replaces the type and value of the owner's
+// field with the same namespace and key
+func (r *repository) Upsert(metafield *Metafield) (*Metafield, error) {
+	err := r.db.Clauses(
+		clause.OnConflict{
+			Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "owner_type"}, {Name: "owner_id"}, {Name: "namespace"}, {Name: "key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"type", "value", "updated_at"}),
+		},
+		clause.Returning{},
+	).Create(metafield).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to save metafield: %w", err)
+	}
+	return metafield, nil
+}
+
+func (r *repository) Delete(tenantID, metafieldID uuid.UUID) error {
+	result := r.db.Where("tenant_id = ? AND id = ?", tenantID, metafieldID).Delete(&Metafield{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete metafield: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrMetafieldNotFound
+	}
+	return nil
+}
+
+// OwnerExists tells whether the record a metafield is attached to belongs to
+// the tenant. Variants belong to a tenant through their product.
+func (r *repository) OwnerExists(tenantID uuid.UUID, ownerType string, ownerID uuid.UUID) (bool, error) {
+	var query *gorm.DB
+	switch ownerType {
+	case OwnerProduct:
+		query = r.db.Table("products").Where("tenant_id = ? AND id = ?", tenantID, ownerID)
+	case OwnerVariant:
+		query = r.db.Table("product_variants v").
+			Joins("JOIN products p ON p.id = v.product_id").
+			Where("p.tenant_id = ? AND v.id = ?", tenantID, ownerID)
+	case OwnerOrder:
+		query = r.db.Table("orders").Where("tenant_id = ? AND id = ?", tenantID, ownerID)
+	case OwnerCustomer:
+		query = r.db.Table("users").Where("tenant_id = ? AND id = ?", tenantID, ownerID)
+	default:
+		return false, nil
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check metafield owner: %w", err)
+	}
+	return count > 0, nil
+}
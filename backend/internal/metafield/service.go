@@ -0,0 +1,64 @@
+package metafield
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Service defines the metafield service interface
+type Service interface {
+	ListMetafields(ctx context.Context, tenantID uuid.UUID, filter MetafieldFilter) ([]*Metafield, error)
+	GetMetafield(ctx context.Context, tenantID, metafieldID uuid.UUID) (*Metafield, error)
+	SetMetafield(ctx context.Context, tenantID uuid.UUID, req SetMetafieldRequest) (*Metafield, error)
+	DeleteMetafield(ctx context.Context, tenantID, metafieldID uuid.UUID) error
+}
+
+type service struct {
+	repo Repository
+}
+
+// NewService creates a new metafield service
+func NewService(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+func (s *service) ListMetafields(ctx context.Context, tenantID uuid.UUID, filter MetafieldFilter) ([]*Metafield, error) {
+	if !validOwnerType(filter.OwnerType) {
+		return nil, ErrInvalidMetafield
+	}
+	return s.repo.List(tenantID, filter)
+}
+
+func (s *service) GetMetafield(ctx context.Context, tenantID, metafieldID uuid.UUID) (*Metafield, error) {
+	return s.repo.Get(tenantID, metafieldID)
+}
+
+// SetMetafield validates a value against its type and stores it on the owner,
+// replacing any field with the same namespace and key
+func (s *service) SetMetafield(ctx context.Context, tenantID uuid.UUID, req SetMetafieldRequest) (*Metafield, error) {
+	metafield, err := newMetafield(&req)
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := s.repo.OwnerExists(tenantID, metafield.OwnerType, metafield.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrOwnerNotFound
+	}
+
+	now := time.Now()
+	metafield.ID = uuid.New()
+	metafield.TenantID = tenantID
+	metafield.CreatedAt = now
+	metafield.UpdatedAt = now
+	return s.repo.Upsert(metafield)
+}
+
+func (s *service) DeleteMetafield(ctx context.Context, tenantID, metafieldID uuid.UUID) error {
+	return s.repo.Delete(tenantID, metafieldID)
+}
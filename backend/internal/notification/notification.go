@@ -25,6 +25,10 @@ const (
 	ChannelMarketing         = "marketing"
 	ChannelAbandonedCart     = "abandoned_cart"
 	ChannelShippingUpdate    = "shipping_update"
+	ChannelGiftCard          = "gift_card"
+	ChannelLoyaltyTier       = "loyalty_tier"
+	ChannelPointsExpiry      = "loyalty_points_expiry"
+	ChannelReferralReward    = "loyalty_referral_reward"
 )
 
 // Notification statuses
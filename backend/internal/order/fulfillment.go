@@ -0,0 +1,150 @@
+package order
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Shipment statuses reported by the shipping module (normalised carrier events)
+const (
+	ShipmentPickedUp       = "picked_up"
+	ShipmentInTransit      = "in_transit"
+	ShipmentOutForDelivery = "out_for_delivery"
+	ShipmentDelivered      = "delivered"
+	ShipmentFailed         = "failed"
+	ShipmentReturned       = "returned"
+)
+
+// EventListener is told when an order is delivered, cancelled, returned or
+// refunded so other modules (such as loyalty) can react. Each refund carries
+// its own ID so listeners can tell a repeated event from a further partial
+// refund. Listener errors never fail the order update.
+type EventListener interface {
+	OrderDelivered(ctx context.Context, order *Order) error
+	OrderCancelled(ctx context.Context, order *Order) error
+	OrderReturned(ctx context.Context, order *Order) error
+	OrderRefunded(ctx context.Context, order *Order, refundID uuid.UUID, amount float64) error
+}
+
+// ShipmentSync applies carrier tracking events to orders. It only depends on
+// the repository so the shipping module can use it without the full order
+// service and its payment/inventory dependencies.
+type ShipmentSync struct {
+	repository Repository
+	listener   EventListener
+}
+
+// NewShipmentSync creates a new shipment sync
+func NewShipmentSync(repository Repository) *ShipmentSync {
+	return &ShipmentSync{repository: repository}
+}
+
+// SetEventListener registers a listener for deliveries and returns
+func (s *ShipmentSync) SetEventListener(listener EventListener) {
+	s.listener = listener
+}
+
+// ApplyShipmentStatus moves the order and its fulfillment status forward for a
+// shipment event. Events that would move an order backwards (e.g. a late
+// in-transit scan after delivery) are ignored.
+func (s *ShipmentSync) ApplyShipmentStatus(ctx context.Context, tenantID, orderID uuid.UUID, status, trackingNumber string, occurredAt time.Time) error {
+	order, err := s.repository.GetOrderByID(tenantID, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to get order: %w", err)
+	}
+
+	if order.Status == StatusCancelled || order.Status == StatusReturned {
+		return nil
+	}
+
+	oldStatus := order.Status
+	oldFulfillment := order.FulfillmentStatus
+
+	switch status {
+	case ShipmentPickedUp, ShipmentInTransit, ShipmentOutForDelivery:
+		if order.Status == StatusConfirmed || order.Status == StatusProcessing {
+			order.Status = StatusShipped
+			order.ShippedAt = &occurredAt
+		}
+		if order.Status == StatusShipped {
+			order.FulfillmentStatus = FulfillmentShipped
+		}
+	case ShipmentDelivered:
+		if order.Status == StatusDelivered {
+			return nil
+		}
+		if order.ShippedAt == nil {
+			order.ShippedAt = &occurredAt
+		}
+		order.Status = StatusDelivered
+		order.FulfillmentStatus = FulfillmentDelivered
+		order.DeliveredAt = &occurredAt
+	case ShipmentFailed:
+		if order.Status == StatusDelivered {
+			return nil
+		}
+		order.FulfillmentStatus = FulfillmentFailed
+	case ShipmentReturned:
+		order.Status = StatusReturned
+		order.FulfillmentStatus = FulfillmentReturned
+	default:
+		return nil
+	}
+
+	if order.TrackingNumber == "" {
+		order.TrackingNumber = trackingNumber
+	}
+
+	if order.Status == oldStatus && order.FulfillmentStatus == oldFulfillment {
+		return nil
+	}
+
+	order.UpdatedAt = time.Now()
+	if _, err := s.repository.UpdateOrder(order); err != nil {
+		return fmt.Errorf("failed to update order: %w", err)
+	}
+
+	history := &OrderHistory{
+		ID:                    uuid.New(),
+		OrderID:               order.ID,
+		TenantID:              order.TenantID,
+		FromStatus:            oldStatus,
+		ToStatus:              order.Status,
+		FromFulfillmentStatus: oldFulfillment,
+		ToFulfillmentStatus:   order.FulfillmentStatus,
+		Action:                "shipment_updated",
+		Description:           fmt.Sprintf("Shipment %s: %s", trackingNumber, status),
+		ChangedByType:         "system",
+		Metadata: map[string]interface{}{
+			"tracking_number": trackingNumber,
+			"shipment_status": status,
+		},
+		CreatedAt: time.Now(),
+	}
+
+	// History is informational; don't fail the status update over it
+	_, _ = s.repository.CreateOrderHistory(history)
+
+	notifyStatusChange(ctx, s.listener, order, oldStatus)
+
+	return nil
+}
+
+// notifyStatusChange tells the listener about deliveries, cancellations and returns
+func notifyStatusChange(ctx context.Context, listener EventListener, order *Order, oldStatus OrderStatus) {
+	if listener == nil || order.Status == oldStatus {
+		return
+	}
+
+	switch order.Status {
+	case StatusDelivered:
+		_ = listener.OrderDelivered(ctx, order)
+	case StatusCancelled:
+		_ = listener.OrderCancelled(ctx, order)
+	case StatusReturned:
+		_ = listener.OrderReturned(ctx, order)
+	}
+}
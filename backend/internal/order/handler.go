@@ -577,6 +577,8 @@ func (h *Handler) GetOrderInvoice(c *gin.Context) {
 		"tax":        order.TaxAmount,
 		"shipping":   order.ShippingAmount,
 		"discount":   order.DiscountAmount,
+		"points":     order.PointsAmount,
+		"gift_card":  order.GiftCardAmount,
 		"total":      order.TotalAmount,
 		"currency":   order.Currency,
 	}
@@ -52,6 +52,8 @@ const (
 	FulfillmentPacked    FulfillmentStatus = "packed"
 	FulfillmentShipped   FulfillmentStatus = "shipped"
 	FulfillmentDelivered FulfillmentStatus = "delivered"
+	FulfillmentFailed    FulfillmentStatus = "delivery_failed"
+	FulfillmentReturned  FulfillmentStatus = "returned"
 )
 
 // Order represents an order in the system
@@ -79,6 +81,8 @@ type Order struct {
 	TaxAmount      float64 `json:"tax_amount" gorm:"default:0"`
 	ShippingAmount float64 `json:"shipping_amount" gorm:"default:0"`
 	DiscountAmount float64 `json:"discount_amount" gorm:"default:0"`
+	PointsAmount   float64 `json:"points_amount" gorm:"default:0"`    // Paid with loyalty points
+	GiftCardAmount float64 `json:"gift_card_amount" gorm:"default:0"` // Paid with gift cards and store credit
 	TotalAmount    float64 `json:"total_amount" gorm:"not null"`
 	Currency       string  `json:"currency" gorm:"default:BDT"`
 	
@@ -121,6 +125,7 @@ type OrderItem struct {
 	UnitPrice    float64 `json:"unit_price" gorm:"not null"`
 	Quantity     int     `json:"quantity" gorm:"not null"`
 	TotalPrice   float64 `json:"total_price" gorm:"not null"`
+	DiscountAmount float64 `json:"discount_amount" gorm:"default:0"` // Promotions allocated to this line
 	
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
@@ -198,9 +203,10 @@ func (o *Order) IsRefundable() bool {
 		   (o.Status == StatusCancelled || o.Status == StatusReturned)
 }
 
-// CalculateTotal recalculates the total amount
+// CalculateTotal recalculates the amount left to pay after discounts, loyalty
+// points and gift cards
 func (o *Order) CalculateTotal() {
-	o.TotalAmount = o.SubtotalAmount + o.TaxAmount + o.ShippingAmount - o.DiscountAmount
+	o.TotalAmount = o.SubtotalAmount + o.TaxAmount + o.ShippingAmount - o.DiscountAmount - o.PointsAmount - o.GiftCardAmount
 	if o.TotalAmount < 0 {
 		o.TotalAmount = 0
 	}
@@ -0,0 +1,146 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PaymentListener is told when an order's payment succeeds or fails so other
+// modules (such as discounts, which hold gift card balance for the order) can
+// settle what they reserved. Listener errors never fail the payment update.
+type PaymentListener interface {
+	OrderPaid(ctx context.Context, order *Order) error
+	OrderPaymentFailed(ctx context.Context, order *Order) error
+}
+
+// EventListeners fans order events out to several listeners
+type EventListeners []EventListener
+
+func (l EventListeners) OrderDelivered(ctx context.Context, order *Order) error {
+	var errs []error
+	for _, listener := range l {
+		errs = append(errs, listener.OrderDelivered(ctx, order))
+	}
+	return errors.Join(errs...)
+}
+
+func (l EventListeners) OrderCancelled(ctx context.Context, order *Order) error {
+	var errs []error
+	for _, listener := range l {
+		errs = append(errs, listener.OrderCancelled(ctx, order))
+	}
+	return errors.Join(errs...)
+}
+
+func (l EventListeners) OrderReturned(ctx context.Context, order *Order) error {
+	var errs []error
+	for _, listener := range l {
+		errs = append(errs, listener.OrderReturned(ctx, order))
+	}
+	return errors.Join(errs...)
+}
+
+func (l EventListeners) OrderRefunded(ctx context.Context, order *Order, refundID uuid.UUID, amount float64) error {
+	var errs []error
+	for _, listener := range l {
+		errs = append(errs, listener.OrderRefunded(ctx, order, refundID, amount))
+	}
+	return errors.Join(errs...)
+}
+
+// PaymentSync applies payment gateway outcomes to orders. Like ShipmentSync it
+// only depends on the repository so the payment module can use it without the
+// full order service.
+type PaymentSync struct {
+	repository Repository
+	listener   PaymentListener
+}
+
+// NewPaymentSync creates a new payment sync
+func NewPaymentSync(repository Repository) *PaymentSync {
+	return &PaymentSync{repository: repository}
+}
+
+// SetPaymentListener registers a listener for paid and failed payments
+func (s *PaymentSync) SetPaymentListener(listener PaymentListener) {
+	s.listener = listener
+}
+
+// PaymentSucceeded marks the order paid and confirms it if it was still
+// pending. Repeated notifications for a paid order are ignored.
+func (s *PaymentSync) PaymentSucceeded(ctx context.Context, tenantID, orderID uuid.UUID) error {
+	order, err := s.repository.GetOrderByID(tenantID, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to get order: %w", err)
+	}
+	if order.PaymentStatus == PaymentPaid || order.PaymentStatus == PaymentRefunded {
+		return nil
+	}
+
+	oldStatus, oldPaymentStatus := order.Status, order.PaymentStatus
+	order.PaymentStatus = PaymentPaid
+	if order.Status == StatusPending {
+		order.Status = StatusConfirmed
+	}
+	if err := s.update(order, oldStatus, oldPaymentStatus, "payment_succeeded", "Payment succeeded"); err != nil {
+		return err
+	}
+
+	if s.listener != nil {
+		_ = s.listener.OrderPaid(ctx, order)
+	}
+	return nil
+}
+
+// PaymentFailed marks the order's payment failed. An order that was already
+// paid, e.g. by a retried payment, is left alone.
+func (s *PaymentSync) PaymentFailed(ctx context.Context, tenantID, orderID uuid.UUID) error {
+	order, err := s.repository.GetOrderByID(tenantID, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to get order: %w", err)
+	}
+	if order.PaymentStatus != PaymentPending && order.PaymentStatus != PaymentAuthorized {
+		return nil
+	}
+
+	oldStatus, oldPaymentStatus := order.Status, order.PaymentStatus
+	order.PaymentStatus = PaymentFailed
+	if err := s.update(order, oldStatus, oldPaymentStatus, "payment_failed", "Payment failed"); err != nil {
+		return err
+	}
+
+	if s.listener != nil {
+		_ = s.listener.OrderPaymentFailed(ctx, order)
+	}
+	return nil
+}
+
+// update saves the order and records the payment change in its history
+func (s *PaymentSync) update(order *Order, oldStatus OrderStatus, oldPaymentStatus PaymentStatus, action, description string) error {
+	order.UpdatedAt = time.Now()
+	if _, err := s.repository.UpdateOrder(order); err != nil {
+		return fmt.Errorf("failed to update order: %w", err)
+	}
+
+	history := &OrderHistory{
+		ID:                uuid.New(),
+		OrderID:           order.ID,
+		TenantID:          order.TenantID,
+		FromStatus:        oldStatus,
+		ToStatus:          order.Status,
+		FromPaymentStatus: oldPaymentStatus,
+		ToPaymentStatus:   order.PaymentStatus,
+		Action:            action,
+		Description:       description,
+		ChangedByType:     "system",
+		CreatedAt:         time.Now(),
+	}
+
+	// History is informational; don't fail the payment update over it
+	_, _ = s.repository.CreateOrderHistory(history)
+	return nil
+}
@@ -34,6 +34,8 @@ type Service struct {
 	paymentService      PaymentService
 	inventoryService    InventoryService
 	notificationService NotificationService
+	listener            EventListener
+	paymentListener     PaymentListener
 }
 
 // NewService creates a new order service
@@ -49,6 +51,16 @@ func NewService(repo Repository, db *gorm.DB, productService ProductService, dis
 	}
 }
 
+// SetEventListener registers a listener for deliveries, cancellations, returns and refunds
+func (s *Service) SetEventListener(listener EventListener) {
+	s.listener = listener
+}
+
+// SetPaymentListener registers a listener for paid orders
+func (s *Service) SetPaymentListener(listener PaymentListener) {
+	s.paymentListener = listener
+}
+
 // Note: DTOs removed - using domain models directly
 
 // CreateOrder creates a new order
@@ -255,6 +267,8 @@ func (s *Service) UpdateOrderStatus(ctx context.Context, tenantID, orderID uuid.
 		// s.logger.Error("Failed to create order history", "error", err)
 	}
 
+	notifyStatusChange(ctx, s.listener, order, oldStatus)
+
 	// Send notifications based on status
 	switch status {
 	case StatusShipped:
@@ -277,6 +291,7 @@ func (s *Service) CancelOrder(tenantID uuid.UUID, orderID string, reason string)
 		return nil, fmt.Errorf("order cannot be cancelled in current status: %s", order.Status)
 	}
 
+	oldStatus := order.Status
 	order.Status = StatusCancelled
 	order.UpdatedAt = time.Now()
 
@@ -291,10 +306,17 @@ func (s *Service) CancelOrder(tenantID uuid.UUID, orderID string, reason string)
 
 	// TODO: Handle refund if payment was processed
 
+	updated, err := s.repository.UpdateOrder(order)
+	if err != nil {
+		return nil, err
+	}
+
+	notifyStatusChange(context.Background(), s.listener, order, oldStatus)
+
 	// Send order cancellation notification
 	go s.sendOrderCancellationNotification(context.Background(), order)
 
-	return s.repository.UpdateOrder(order)
+	return updated, nil
 }
 
 // ListOrders retrieves orders with filtering and pagination
@@ -325,7 +347,16 @@ func (s *Service) ProcessPayment(tenantID uuid.UUID, orderID string) (*Order, er
 	order.Status = StatusConfirmed
 	order.UpdatedAt = time.Now()
 
-	return s.repository.UpdateOrder(order)
+	updated, err := s.repository.UpdateOrder(order)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.paymentListener != nil {
+		_ = s.paymentListener.OrderPaid(context.Background(), order)
+	}
+
+	return updated, nil
 }
 
 // RefundOrder processes a refund for an order
@@ -346,7 +377,10 @@ func (s *Service) RefundOrder(ctx context.Context, tenantID, orderID uuid.UUID,
 		return nil, fmt.Errorf("failed to process refund: %w", err)
 	}
 
+	refundID := uuid.New()
+
 	// Update order status
+	oldStatus := order.Status
 	order.PaymentStatus = PaymentRefunded
 	order.Status = StatusCancelled
 	order.UpdatedAt = time.Now()
@@ -371,9 +405,14 @@ func (s *Service) RefundOrder(ctx context.Context, tenantID, orderID uuid.UUID,
 		// s.logger.Error("Failed to create order history", "error", err)
 	}
 
+	if s.listener != nil {
+		_ = s.listener.OrderRefunded(ctx, order, refundID, amount)
+	}
+	notifyStatusChange(ctx, s.listener, order, oldStatus)
+
 	// Create a payment response for the refund
 	payment := &Payment{
-		ID:       uuid.New(),
+		ID:       refundID,
 		TenantID: tenantID,
 		OrderID:  orderID,
 		Amount:   amount,
@@ -413,6 +452,12 @@ func (s *Service) TrackOrder(tenantID uuid.UUID, orderNumber string) (map[string
 
 // generateOrderNumber generates a unique order number
 func (s *Service) generateOrderNumber(tenantID uuid.UUID) string {
+	return GenerateOrderNumber()
+}
+
+// GenerateOrderNumber returns a new order number for orders created outside
+// this service (e.g. by checkout)
+func GenerateOrderNumber() string {
 	timestamp := time.Now().Unix()
 	random := rand.Intn(1000)
 	return fmt.Sprintf("ORD-%d-%03d", timestamp, random)
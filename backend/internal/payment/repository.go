@@ -10,6 +10,7 @@ type Repository interface {
 	GetByID(tenantID, paymentID uuid.UUID) (*Payment, error)
 	GetByOrderID(tenantID, orderID uuid.UUID) ([]*Payment, error)
 	GetByTransactionID(transactionID string) (*Payment, error)
+	GetLatestByOrderID(orderID uuid.UUID) (*Payment, error)
 	Update(payment *Payment) error
 	Delete(tenantID, paymentID uuid.UUID) error
 	List(tenantID uuid.UUID, orderID *uuid.UUID, offset, limit int) ([]*Payment, int64, error)
@@ -57,6 +58,14 @@ func (r *repository) GetByTransactionID(transactionID string) (*Payment, error)
 	return &payment, err
 }
 
+// GetLatestByOrderID finds the most recent payment attempt for an order, for
+// gateway callbacks that only carry the order ID as their transaction ID
+func (r *repository) GetLatestByOrderID(orderID uuid.UUID) (*Payment, error) {
+	var payment Payment
+	err := r.db.Where("order_id = ?", orderID).Order("created_at DESC").First(&payment).Error
+	return &payment, err
+}
+
 func (r *repository) Update(payment *Payment) error {
 	return r.db.Save(payment).Error
 }
@@ -28,6 +28,17 @@ type Service interface {
 	// SSLCommerz specific methods
 	InitiateSSLCommerzPayment(ctx context.Context, payment *Payment) (*SSLCommerzPaymentResponse, error)
 	ValidateSSLCommerzPayment(ctx context.Context, ipnData *SSLCommerzIPNResponse) error
+
+	// Order integration
+	SetOrderListener(listener OrderListener)
+}
+
+// OrderListener is told when a payment for an order succeeds or fails, so
+// the order is marked paid and what checkout reserved for it is settled.
+// Listener errors never fail the payment update.
+type OrderListener interface {
+	PaymentSucceeded(ctx context.Context, tenantID, orderID uuid.UUID) error
+	PaymentFailed(ctx context.Context, tenantID, orderID uuid.UUID) error
 }
 
 type service struct {
@@ -39,6 +50,8 @@ type service struct {
 	sslCommerzStorePass  string
 	sslCommerzSandbox    bool
 	sslCommerzBaseURL    string
+
+	orderListener OrderListener
 }
 
 func NewService(repository Repository) Service {
@@ -52,6 +65,24 @@ func NewService(repository Repository) Service {
 	}
 }
 
+// SetOrderListener registers a listener for succeeded and failed payments
+func (s *service) SetOrderListener(listener OrderListener) {
+	s.orderListener = listener
+}
+
+// notifyOrder tells the order listener about a payment that settled
+func (s *service) notifyOrder(ctx context.Context, payment *Payment) {
+	if s.orderListener == nil {
+		return
+	}
+	switch payment.Status {
+	case StatusSucceeded:
+		_ = s.orderListener.PaymentSucceeded(ctx, payment.TenantID, payment.OrderID)
+	case StatusFailed, StatusCancelled:
+		_ = s.orderListener.PaymentFailed(ctx, payment.TenantID, payment.OrderID)
+	}
+}
+
 func (s *service) CreatePayment(ctx context.Context, req *CreatePaymentRequest) (*CreatePaymentResponse, error) {
 	if err := s.validator.Struct(req); err != nil {
 		return nil, fmt.Errorf("validation failed: %w", err)
@@ -206,16 +237,57 @@ func (s *service) ProcessPayment(ctx context.Context, req *ProcessPaymentRequest
 	if err := s.repository.Update(payment); err != nil {
 		return nil, err
 	}
+	s.notifyOrder(ctx, payment)
 	return payment, nil
 }
 
 func (s *service) ValidateSSLCommerzPayment(ctx context.Context, ipnData *SSLCommerzIPNResponse) error {
 	// This would involve validating the IPN data with SSLCommerz
 	// For now, we'll do basic validation
-	if ipnData.Status == "VALID" {
+	var status string
+	switch ipnData.Status {
+	case "VALID", "VALIDATED":
+		status = StatusSucceeded
+	case "FAILED":
+		status = StatusFailed
+	case "CANCELLED":
+		status = StatusCancelled
+	default:
+		return fmt.Errorf("invalid payment status: %s", ipnData.Status)
+	}
+
+	// Payments are initiated with the order ID as the transaction ID
+	orderID, err := uuid.Parse(ipnData.TransactionID)
+	if err != nil {
+		return fmt.Errorf("invalid transaction ID: %w", err)
+	}
+	payment, err := s.repository.GetLatestByOrderID(orderID)
+	if err != nil {
+		return fmt.Errorf("payment not found: %w", err)
+	}
+
+	// Gateways retry IPNs; only the first one settles the payment
+	if payment.Status == status {
 		return nil
 	}
-	return fmt.Errorf("invalid payment status: %s", ipnData.Status)
+	if payment.Status != StatusPending && payment.Status != StatusProcessing {
+		return fmt.Errorf("payment already %s", payment.Status)
+	}
+
+	payment.Status = status
+	now := time.Now()
+	payment.ProcessedAt = &now
+	if status != StatusSucceeded {
+		payment.FailureReason = ipnData.Error
+	}
+	gatewayResponseJSON, _ := json.Marshal(ipnData)
+	payment.GatewayResponse = string(gatewayResponseJSON)
+
+	if err := s.repository.Update(payment); err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+	s.notifyOrder(ctx, payment)
+	return nil
 }
 
 func (s *service) GetPayment(ctx context.Context, paymentID string) (*Payment, error) {
@@ -322,6 +394,8 @@ func (s *service) UpdatePayment(ctx context.Context, id string, updates map[stri
 		return nil, err
 	}
 
+	oldStatus := payment.Status
+
 	// Apply updates to payment object
 	for key, value := range updates {
 		switch key {
@@ -345,6 +419,10 @@ func (s *service) UpdatePayment(ctx context.Context, id string, updates map[stri
 		return nil, fmt.Errorf("failed to update payment: %w", err)
 	}
 
+	if payment.Status != oldStatus {
+		s.notifyOrder(ctx, payment)
+	}
+
 	return payment, nil
 }
 
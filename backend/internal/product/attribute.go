@@ -0,0 +1,228 @@
+package product
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AttributeType is the value type of a product attribute
+type AttributeType string
+
+const (
+	AttributeText      AttributeType = "text"
+	AttributeNumber    AttributeType = "number"
+	AttributeEnum      AttributeType = "enum"
+	AttributeBoolean   AttributeType = "boolean"
+	AttributeDate      AttributeType = "date"
+	AttributeReference AttributeType = "reference"
+)
+
+// Records a reference attribute can point to
+const (
+	ReferenceProduct  = "product"
+	ReferenceCategory = "category"
+)
+
+var (
+	ErrAttributeNotFound    = errors.New("attribute not found")
+	ErrAttributeSetNotFound = errors.New("attribute set not found")
+	ErrInvalidAttribute     = errors.New("invalid attribute")
+	ErrAttributeInUse       = errors.New("attribute is used by an attribute set")
+)
+
+// Attribute codes are the keys of Product.Attributes and of search filters
+var attributeCodePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+
+// AttributeDefinition describes a structured product attribute such as
+// warranty, material or RAM. Values are stored on the product under Code.
+type AttributeDefinition struct {
+	ID            uuid.UUID     `json:"id" gorm:"primarykey"`
+	TenantID      uuid.UUID     `json:"tenant_id" gorm:"not null;index"`
+	Code          string        `json:"code" gorm:"not null"`
+	Name          string        `json:"name" gorm:"not null"`
+	Type          AttributeType `json:"type" gorm:"not null"`
+	Options       []string      `json:"options,omitempty" gorm:"serializer:json"` // allowed values of an enum
+	ReferenceType string        `json:"reference_type,omitempty"`                 // product or category, for references
+	Unit          string        `json:"unit,omitempty"`                           // shown after numbers, e.g. GB
+	Filterable    bool          `json:"filterable" gorm:"default:false"`          // offered as a search facet
+	SortOrder     int           `json:"sort_order" gorm:"default:0"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AttributeSet groups the attributes products of a category carry. A category
+// without a set uses the set of its nearest ancestor that has one.
+type AttributeSet struct {
+	ID          uuid.UUID          `json:"id" gorm:"primarykey"`
+	TenantID    uuid.UUID          `json:"tenant_id" gorm:"not null;index"`
+	Name        string             `json:"name" gorm:"not null"`
+	Description string             `json:"description,omitempty"`
+	Attributes  []AttributeSetItem `json:"attributes" gorm:"serializer:json"` // in display order
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AttributeSetItem is an attribute of a set
+type AttributeSetItem struct {
+	AttributeID uuid.UUID `json:"attribute_id"`
+	Required    bool      `json:"required"`
+}
+
+// CategoryAttribute is an attribute as products of a category carry it
+type CategoryAttribute struct {
+	AttributeDefinition
+	Required bool `json:"required"`
+}
+
+// Validate checks a definition before it is saved and tidies its fields
+func (d *AttributeDefinition) Validate() error {
+	d.Code = strings.TrimSpace(d.Code)
+	d.Name = strings.TrimSpace(d.Name)
+	d.Unit = strings.TrimSpace(d.Unit)
+
+	if !attributeCodePattern.MatchString(d.Code) {
+		return fmt.Errorf("%w: code must be lowercase letters, digits and underscores, starting with a letter", ErrInvalidAttribute)
+	}
+	if d.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidAttribute)
+	}
+
+	switch d.Type {
+	case AttributeText, AttributeNumber, AttributeBoolean, AttributeDate:
+	case AttributeEnum:
+		var options []string
+		for _, option := range d.Options {
+			option = strings.TrimSpace(option)
+			if option != "" && !contains(options, option) {
+				options = append(options, option)
+			}
+		}
+		if len(options) == 0 {
+			return fmt.Errorf("%w: an enum needs at least one option", ErrInvalidAttribute)
+		}
+		d.Options = options
+	case AttributeReference:
+		if d.ReferenceType != ReferenceProduct && d.ReferenceType != ReferenceCategory {
+			return fmt.Errorf("%w: reference type must be %s or %s", ErrInvalidAttribute, ReferenceProduct, ReferenceCategory)
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidAttribute, d.Type)
+	}
+
+	if d.Type != AttributeEnum {
+		d.Options = nil
+	}
+	if d.Type != AttributeReference {
+		d.ReferenceType = ""
+	}
+	return nil
+}
+
+// NormalizeValue checks a value against the attribute type and returns it in
+// stored form: strings for text, enums, dates (YYYY-MM-DD) and references,
+// float64 for numbers and bool for booleans. Nil and blank values return nil,
+// meaning the attribute is not set.
+func (d *AttributeDefinition) NormalizeValue(value interface{}) (interface{}, error) {
+	if text, ok := value.(string); ok {
+		value = strings.TrimSpace(text)
+		if value == "" {
+			return nil, nil
+		}
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	switch d.Type {
+	case AttributeText:
+		if text, ok := value.(string); ok {
+			return text, nil
+		}
+	case AttributeNumber:
+		switch number := value.(type) {
+		case float64:
+			return number, nil
+		case int:
+			return float64(number), nil
+		case string:
+			if parsed, err := strconv.ParseFloat(number, 64); err == nil {
+				return parsed, nil
+			}
+		}
+	case AttributeEnum:
+		if text, ok := value.(string); ok {
+			for _, option := range d.Options {
+				if strings.EqualFold(option, text) {
+					return option, nil
+				}
+			}
+			return nil, fmt.Errorf("%w: %s must be one of %s", ErrInvalidAttribute, d.Code, strings.Join(d.Options, ", "))
+		}
+	case AttributeBoolean:
+		switch flag := value.(type) {
+		case bool:
+			return flag, nil
+		case string:
+			if parsed, err := strconv.ParseBool(flag); err == nil {
+				return parsed, nil
+			}
+		}
+	case AttributeDate:
+		if text, ok := value.(string); ok {
+			for _, layout := range []string{"2006-01-02", time.RFC3339} {
+				if date, err := time.Parse(layout, text); err == nil {
+					return date.Format("2006-01-02"), nil
+				}
+			}
+		}
+	case AttributeReference:
+		if text, ok := value.(string); ok {
+			if id, err := uuid.Parse(text); err == nil {
+				return id.String(), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %s must be a %s", ErrInvalidAttribute, d.Code, d.Type)
+}
+
+// Validate checks a set before it is saved
+func (s *AttributeSet) Validate() error {
+	s.Name = strings.TrimSpace(s.Name)
+	s.Description = strings.TrimSpace(s.Description)
+	if s.Name == "" {
+		return fmt.Errorf("%w: attribute set name is required", ErrInvalidAttribute)
+	}
+
+	seen := make(map[uuid.UUID]bool, len(s.Attributes))
+	for _, item := range s.Attributes {
+		if item.AttributeID == uuid.Nil {
+			return fmt.Errorf("%w: attribute set item needs an attribute ID", ErrInvalidAttribute)
+		}
+		if seen[item.AttributeID] {
+			return fmt.Errorf("%w: attribute %s is listed twice", ErrInvalidAttribute, item.AttributeID)
+		}
+		seen[item.AttributeID] = true
+	}
+	if s.Attributes == nil {
+		s.Attributes = []AttributeSetItem{}
+	}
+	return nil
+}
+
+// HasAttribute tells whether the set includes an attribute
+func (s *AttributeSet) HasAttribute(attributeID uuid.UUID) bool {
+	for _, item := range s.Attributes {
+		if item.AttributeID == attributeID {
+			return true
+		}
+	}
+	return false
+}
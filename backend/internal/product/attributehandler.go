@@ -0,0 +1,283 @@
+package product
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// registerAttributeRoutes registers attribute definition and attribute set
+// routes
+func (h *Handler) registerAttributeRoutes(router *gin.RouterGroup) {
+	attributes := router.Group("/attributes")
+	{
+		attributes.GET("", h.ListAttributes)
+		attributes.POST("", h.CreateAttribute)
+		attributes.PUT("/:id", h.UpdateAttribute)
+		attributes.DELETE("/:id", h.DeleteAttribute)
+	}
+
+	sets := router.Group("/attribute-sets")
+	{
+		sets.GET("", h.ListAttributeSets)
+		sets.POST("", h.CreateAttributeSet)
+		sets.GET("/:id", h.GetAttributeSet)
+		sets.PUT("/:id", h.UpdateAttributeSet)
+		sets.DELETE("/:id", h.DeleteAttributeSet)
+	}
+
+	router.GET("/categories/:id/attributes", h.GetCategoryAttributes)
+}
+
+// ListAttributes handles GET /api/attributes
+func (h *Handler) ListAttributes(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	definitions, err := h.service.ListAttributes(tenantID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch attributes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": definitions})
+}
+
+// CreateAttribute handles POST /api/attributes
+func (h *Handler) CreateAttribute(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	var definition AttributeDefinition
+	if err := c.ShouldBindJSON(&definition); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	created, err := h.service.CreateAttribute(tenantID.(uuid.UUID), &definition)
+	if err != nil {
+		c.JSON(attributeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Attribute created successfully",
+		"data":    created,
+	})
+}
+
+// UpdateAttribute handles PUT /api/attributes/:id
+func (h *Handler) UpdateAttribute(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	attributeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attribute ID"})
+		return
+	}
+
+	var definition AttributeDefinition
+	if err := c.ShouldBindJSON(&definition); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	updated, err := h.service.UpdateAttribute(tenantID.(uuid.UUID), attributeID, &definition)
+	if err != nil {
+		c.JSON(attributeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Attribute updated successfully",
+		"data":    updated,
+	})
+}
+
+// DeleteAttribute handles DELETE /api/attributes/:id
+func (h *Handler) DeleteAttribute(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	attributeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attribute ID"})
+		return
+	}
+
+	if err := h.service.DeleteAttribute(tenantID.(uuid.UUID), attributeID); err != nil {
+		c.JSON(attributeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Attribute deleted successfully"})
+}
+
+// ListAttributeSets handles GET /api/attribute-sets
+func (h *Handler) ListAttributeSets(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	sets, err := h.service.ListAttributeSets(tenantID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch attribute sets"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": sets})
+}
+
+// GetAttributeSet handles GET /api/attribute-sets/:id
+func (h *Handler) GetAttributeSet(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	setID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attribute set ID"})
+		return
+	}
+
+	set, err := h.service.GetAttributeSet(tenantID.(uuid.UUID), setID)
+	if err != nil {
+		c.JSON(attributeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": set})
+}
+
+// CreateAttributeSet handles POST /api/attribute-sets
+func (h *Handler) CreateAttributeSet(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	var set AttributeSet
+	if err := c.ShouldBindJSON(&set); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	created, err := h.service.CreateAttributeSet(tenantID.(uuid.UUID), &set)
+	if err != nil {
+		c.JSON(attributeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Attribute set created successfully",
+		"data":    created,
+	})
+}
+
+// UpdateAttributeSet handles PUT /api/attribute-sets/:id
+func (h *Handler) UpdateAttributeSet(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	setID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attribute set ID"})
+		return
+	}
+
+	var set AttributeSet
+	if err := c.ShouldBindJSON(&set); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	updated, err := h.service.UpdateAttributeSet(tenantID.(uuid.UUID), setID, &set)
+	if err != nil {
+		c.JSON(attributeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Attribute set updated successfully",
+		"data":    updated,
+	})
+}
+
+// DeleteAttributeSet handles DELETE /api/attribute-sets/:id
+func (h *Handler) DeleteAttributeSet(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	setID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attribute set ID"})
+		return
+	}
+
+	if err := h.service.DeleteAttributeSet(tenantID.(uuid.UUID), setID); err != nil {
+		c.JSON(attributeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Attribute set deleted successfully"})
+}
+
+// GetCategoryAttributes handles GET /api/categories/:id/attributes
+func (h *Handler) GetCategoryAttributes(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	categoryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	attributes, err := h.service.GetCategoryAttributes(tenantID.(uuid.UUID), categoryID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": attributes})
+}
+
+func attributeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrAttributeNotFound), errors.Is(err, ErrAttributeSetNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrAttributeInUse):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
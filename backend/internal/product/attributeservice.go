@@ -0,0 +1,308 @@
+package product
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ListAttributes returns the attribute definitions of a tenant
+func (s *Service) ListAttributes(tenantID uuid.UUID) ([]*AttributeDefinition, error) {
+	return s.repo.ListAttributeDefinitions(tenantID)
+}
+
+// CreateAttribute adds an attribute definition
+func (s *Service) CreateAttribute(tenantID uuid.UUID, definition *AttributeDefinition) (*AttributeDefinition, error) {
+	definition.ID = uuid.New()
+	definition.TenantID = tenantID
+	if err := definition.Validate(); err != nil {
+		return nil, err
+	}
+
+	if exists, err := s.repo.AttributeCodeExists(tenantID, definition.Code); err != nil {
+		return nil, err
+	} else if exists {
+		return nil, fmt.Errorf("%w: code %q is already used", ErrInvalidAttribute, definition.Code)
+	}
+
+	return s.repo.SaveAttributeDefinition(definition)
+}
+
+// UpdateAttribute updates an attribute definition. The code, type and
+// reference type are fixed once created since stored values depend on them.
+func (s *Service) UpdateAttribute(tenantID, attributeID uuid.UUID, definition *AttributeDefinition) (*AttributeDefinition, error) {
+	existing, err := s.repo.FindAttributeDefinition(tenantID, attributeID)
+	if err != nil {
+		return nil, err
+	}
+
+	if definition.Code != "" && definition.Code != existing.Code {
+		return nil, fmt.Errorf("%w: code cannot be changed", ErrInvalidAttribute)
+	}
+	if definition.Type != "" && definition.Type != existing.Type {
+		return nil, fmt.Errorf("%w: type cannot be changed", ErrInvalidAttribute)
+	}
+
+	wasFilterable := existing.Filterable
+	if definition.Name != "" {
+		existing.Name = definition.Name
+	}
+	if definition.Options != nil {
+		existing.Options = definition.Options
+	}
+	existing.Unit = definition.Unit
+	existing.Filterable = definition.Filterable
+	existing.SortOrder = definition.SortOrder
+	if err := existing.Validate(); err != nil {
+		return nil, err
+	}
+	existing.UpdatedAt = time.Now()
+
+	updated, err := s.repo.UpdateAttributeDefinition(existing)
+	if err != nil {
+		return nil, err
+	}
+
+	// Search documents only carry filterable attributes
+	if updated.Filterable != wasFilterable {
+		productIDs, err := s.repo.ProductIDsWithAttribute(tenantID, updated.Code)
+		if err != nil {
+			return nil, err
+		}
+		if len(productIDs) > 0 {
+			s.productsChanged(tenantID, productIDs...)
+		}
+	}
+	return updated, nil
+}
+
+// DeleteAttribute removes an attribute definition and its values from
+// products. Attributes still in an attribute set cannot be deleted.
+func (s *Service) DeleteAttribute(tenantID, attributeID uuid.UUID) error {
+	definition, err := s.repo.FindAttributeDefinition(tenantID, attributeID)
+	if err != nil {
+		return err
+	}
+
+	productIDs, err := s.repo.DeleteAttributeDefinition(tenantID, definition)
+	if err != nil {
+		return err
+	}
+	if len(productIDs) > 0 {
+		s.productsChanged(tenantID, productIDs...)
+	}
+	return nil
+}
+
+// ListAttributeSets returns the attribute sets of a tenant
+func (s *Service) ListAttributeSets(tenantID uuid.UUID) ([]*AttributeSet, error) {
+	return s.repo.ListAttributeSets(tenantID)
+}
+
+// GetAttributeSet retrieves an attribute set by ID
+func (s *Service) GetAttributeSet(tenantID, setID uuid.UUID) (*AttributeSet, error) {
+	return s.repo.FindAttributeSet(tenantID, setID)
+}
+
+// CreateAttributeSet adds an attribute set
+func (s *Service) CreateAttributeSet(tenantID uuid.UUID, set *AttributeSet) (*AttributeSet, error) {
+	set.ID = uuid.New()
+	set.TenantID = tenantID
+	if err := s.validateAttributeSet(tenantID, set); err != nil {
+		return nil, err
+	}
+
+	return s.repo.SaveAttributeSet(set)
+}
+
+// UpdateAttributeSet replaces the name and attributes of a set. Products are
+// checked against the new set the next time they are saved.
+func (s *Service) UpdateAttributeSet(tenantID, setID uuid.UUID, set *AttributeSet) (*AttributeSet, error) {
+	existing, err := s.repo.FindAttributeSet(tenantID, setID)
+	if err != nil {
+		return nil, err
+	}
+
+	existing.Name = set.Name
+	existing.Description = set.Description
+	existing.Attributes = set.Attributes
+	if err := s.validateAttributeSet(tenantID, existing); err != nil {
+		return nil, err
+	}
+	existing.UpdatedAt = time.Now()
+
+	return s.repo.UpdateAttributeSet(existing)
+}
+
+// DeleteAttributeSet removes an attribute set from its categories and deletes
+// it
+func (s *Service) DeleteAttributeSet(tenantID, setID uuid.UUID) error {
+	return s.repo.DeleteAttributeSet(tenantID, setID)
+}
+
+// GetCategoryAttributes returns the attributes products of a category carry,
+// from the category's own or inherited attribute set, in set order
+func (s *Service) GetCategoryAttributes(tenantID, categoryID uuid.UUID) ([]CategoryAttribute, error) {
+	if exists, err := s.repo.CategoryExists(tenantID, categoryID); err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, errors.New("category not found")
+	}
+
+	set, err := s.repo.FindCategoryAttributeSet(tenantID, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	if set == nil {
+		return []CategoryAttribute{}, nil
+	}
+
+	definitions, err := s.attributesByID(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes := make([]CategoryAttribute, 0, len(set.Attributes))
+	for _, item := range set.Attributes {
+		if definition, ok := definitions[item.AttributeID]; ok {
+			attributes = append(attributes, CategoryAttribute{
+				AttributeDefinition: *definition,
+				Required:            item.Required,
+			})
+		}
+	}
+	return attributes, nil
+}
+
+// validateProductAttributes checks the attribute values of a product against
+// the definitions and stores them normalised. A product in a category with an
+// attribute set may only carry the set's attributes and must fill its
+// required ones.
+func (s *Service) validateProductAttributes(tenantID uuid.UUID, product *Product) error {
+	var set *AttributeSet
+	if product.CategoryID != uuid.Nil {
+		var err error
+		if set, err = s.repo.FindCategoryAttributeSet(tenantID, product.CategoryID); err != nil {
+			return err
+		}
+	}
+	if set == nil && len(product.Attributes) == 0 {
+		product.Attributes = nil
+		return nil
+	}
+
+	definitions, err := s.repo.ListAttributeDefinitions(tenantID)
+	if err != nil {
+		return err
+	}
+	byCode := make(map[string]*AttributeDefinition, len(definitions))
+	byID := make(map[uuid.UUID]*AttributeDefinition, len(definitions))
+	for _, definition := range definitions {
+		byCode[definition.Code] = definition
+		byID[definition.ID] = definition
+	}
+
+	values := make(map[string]interface{}, len(product.Attributes))
+	for code, value := range product.Attributes {
+		definition, ok := byCode[code]
+		if !ok {
+			return fmt.Errorf("%w: unknown attribute %q", ErrInvalidAttribute, code)
+		}
+		if set != nil && !set.HasAttribute(definition.ID) {
+			return fmt.Errorf("%w: %s is not an attribute of the %s set", ErrInvalidAttribute, code, set.Name)
+		}
+
+		normalized, err := definition.NormalizeValue(value)
+		if err != nil {
+			return err
+		}
+		if normalized == nil {
+			continue
+		}
+		if definition.Type == AttributeReference {
+			if err := s.checkReference(tenantID, definition, normalized.(string)); err != nil {
+				return err
+			}
+		}
+		values[code] = normalized
+	}
+
+	if set != nil {
+		for _, item := range set.Attributes {
+			definition, ok := byID[item.AttributeID]
+			if item.Required && ok && values[definition.Code] == nil {
+				return fmt.Errorf("%w: %s is required", ErrInvalidAttribute, definition.Name)
+			}
+		}
+	}
+
+	product.Attributes = values
+	if len(values) == 0 {
+		product.Attributes = nil
+	}
+	return nil
+}
+
+// checkReference checks that a reference attribute points to a record of the
+// tenant
+func (s *Service) checkReference(tenantID uuid.UUID, definition *AttributeDefinition, value string) error {
+	id := uuid.MustParse(value)
+
+	var exists bool
+	var err error
+	switch definition.ReferenceType {
+	case ReferenceProduct:
+		exists, err = s.repo.ProductExists(tenantID, id)
+	case ReferenceCategory:
+		exists, err = s.repo.CategoryExists(tenantID, id)
+	}
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: %s refers to an unknown %s", ErrInvalidAttribute, definition.Code, definition.ReferenceType)
+	}
+	return nil
+}
+
+// validateAttributeSet checks that every attribute of a set exists
+func (s *Service) validateAttributeSet(tenantID uuid.UUID, set *AttributeSet) error {
+	if err := set.Validate(); err != nil {
+		return err
+	}
+
+	definitions, err := s.attributesByID(tenantID)
+	if err != nil {
+		return err
+	}
+	for _, item := range set.Attributes {
+		if _, ok := definitions[item.AttributeID]; !ok {
+			return fmt.Errorf("%w: attribute %s does not exist", ErrInvalidAttribute, item.AttributeID)
+		}
+	}
+	return nil
+}
+
+// validateCategoryAttributeSet checks the attribute set assigned to a category
+func (s *Service) validateCategoryAttributeSet(tenantID uuid.UUID, category *Category) error {
+	if category.AttributeSetID == nil {
+		return nil
+	}
+	_, err := s.repo.FindAttributeSet(tenantID, *category.AttributeSetID)
+	return err
+}
+
+func (s *Service) attributesByID(tenantID uuid.UUID) (map[uuid.UUID]*AttributeDefinition, error) {
+	definitions, err := s.repo.ListAttributeDefinitions(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uuid.UUID]*AttributeDefinition, len(definitions))
+	for _, definition := range definitions {
+		byID[definition.ID] = definition
+	}
+	return byID, nil
+}
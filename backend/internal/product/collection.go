@@ -0,0 +1,261 @@
+package product
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CollectionType tells how a collection gets its products
+type CollectionType string
+
+const (
+	CollectionManual CollectionType = "manual" // hand-picked products in a set order
+	CollectionSmart  CollectionType = "smart"  // the products matching its rules, kept up to date
+)
+
+// Smart collection rule fields
+const (
+	RuleTag           = "tag"            // has the tag Value
+	RulePrice         = "price"          // priced within Min and Max
+	RuleCategory      = "category"       // in category Value or its subcategories
+	RuleVendor        = "vendor"         // made by vendor Value
+	RuleStock         = "stock"          // Value in_stock or out_of_stock
+	RuleCreatedWithin = "created_within" // created within the last Days days
+)
+
+// How the rules of a smart collection combine
+const (
+	RuleMatchAll = "all"
+	RuleMatchAny = "any"
+)
+
+// Stock rule values
+const (
+	StockInStock    = "in_stock"
+	StockOutOfStock = "out_of_stock"
+)
+
+// Collection product sort orders; manual is only for manual collections
+const (
+	CollectionSortManual    = "manual"
+	CollectionSortNewest    = "newest"
+	CollectionSortName      = "name"
+	CollectionSortPriceAsc  = "price_asc"
+	CollectionSortPriceDesc = "price_desc"
+	CollectionSortRating    = "rating"
+)
+
+// Longest period a created within rule can look back
+const maxCreatedWithinDays = 3650
+
+var (
+	ErrCollectionNotFound = errors.New("collection not found")
+	ErrInvalidCollection  = errors.New("invalid collection")
+)
+
+// Collection groups products for the storefront, discounts, menus and search
+// facets. Smart collection membership is recalculated whenever products or
+// the rules change.
+type Collection struct {
+	ID          uuid.UUID      `json:"id" gorm:"primarykey"`
+	TenantID    uuid.UUID      `json:"tenant_id" gorm:"not null;index"`
+	Title       string         `json:"title" gorm:"not null"`
+	Slug        string         `json:"slug" gorm:"not null"`
+	Description string         `json:"description,omitempty"`
+	Image       string         `json:"image,omitempty"`
+	Type        CollectionType `json:"type" gorm:"default:manual"`
+
+	// Smart collections only
+	Rules     []CollectionRule `json:"rules,omitempty" gorm:"serializer:json"`
+	RuleMatch string           `json:"rule_match,omitempty" gorm:"default:all"` // all or any
+
+	SortBy      string `json:"sort_by"`      // order of the collection's products
+	IsPublished bool   `json:"is_published"` // shown on the storefront
+
+	// SEO
+	MetaTitle       string `json:"meta_title,omitempty"`
+	MetaDescription string `json:"meta_description,omitempty"`
+	MetaKeywords    string `json:"meta_keywords,omitempty"`
+
+	// Number of member products, filled in when listing
+	ProductCount int64 `json:"product_count" gorm:"->;-:migration"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CollectionRule is a condition products of a smart collection meet
+type CollectionRule struct {
+	Field string   `json:"field"`           // tag, price, category, vendor, stock, created_within
+	Value string   `json:"value,omitempty"` // tag, category ID, vendor or stock value
+	Min   *float64 `json:"min,omitempty"`   // price range, either bound may be empty
+	Max   *float64 `json:"max,omitempty"`
+	Days  int      `json:"days,omitempty"` // created within
+}
+
+// CollectionProduct is a member of a collection
+type CollectionProduct struct {
+	CollectionID uuid.UUID `json:"collection_id" gorm:"primarykey"`
+	ProductID    uuid.UUID `json:"product_id" gorm:"primarykey"`
+	Position     int       `json:"position"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// CollectionListFilter narrows a collection listing
+type CollectionListFilter struct {
+	Type        CollectionType `json:"type,omitempty"`
+	IsPublished *bool          `json:"is_published,omitempty"`
+	Search      string         `json:"search,omitempty"`
+}
+
+// IsSmart tells whether the collection is rule based
+func (c *Collection) IsSmart() bool {
+	return c.Type == CollectionSmart
+}
+
+// HasRule tells whether a smart collection has a rule on field
+func (c *Collection) HasRule(field string) bool {
+	for _, rule := range c.Rules {
+		if rule.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the collection and normalises its rules and defaults
+func (c *Collection) Validate() error {
+	c.Title = strings.TrimSpace(c.Title)
+	if c.Title == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidCollection)
+	}
+	c.Description = strings.TrimSpace(c.Description)
+	c.MetaTitle = strings.TrimSpace(c.MetaTitle)
+	c.MetaDescription = strings.TrimSpace(c.MetaDescription)
+	c.MetaKeywords = strings.TrimSpace(c.MetaKeywords)
+
+	switch c.Type {
+	case "":
+		c.Type = CollectionManual
+	case CollectionManual, CollectionSmart:
+	default:
+		return fmt.Errorf("%w: type must be %s or %s", ErrInvalidCollection, CollectionManual, CollectionSmart)
+	}
+
+	if !c.IsSmart() {
+		if len(c.Rules) > 0 {
+			return fmt.Errorf("%w: only smart collections have rules", ErrInvalidCollection)
+		}
+		c.Rules = nil
+		c.RuleMatch = RuleMatchAll
+		if c.SortBy == "" {
+			c.SortBy = CollectionSortManual
+		}
+	} else {
+		if len(c.Rules) == 0 {
+			return fmt.Errorf("%w: smart collections need at least one rule", ErrInvalidCollection)
+		}
+		for i := range c.Rules {
+			if err := c.Rules[i].validate(); err != nil {
+				return err
+			}
+		}
+		if c.RuleMatch == "" {
+			c.RuleMatch = RuleMatchAll
+		}
+		if c.RuleMatch != RuleMatchAll && c.RuleMatch != RuleMatchAny {
+			return fmt.Errorf("%w: rule_match must be %s or %s", ErrInvalidCollection, RuleMatchAll, RuleMatchAny)
+		}
+		if c.SortBy == "" {
+			c.SortBy = CollectionSortNewest
+		}
+		if c.SortBy == CollectionSortManual {
+			return fmt.Errorf("%w: smart collections cannot be sorted manually", ErrInvalidCollection)
+		}
+	}
+
+	switch c.SortBy {
+	case CollectionSortManual, CollectionSortNewest, CollectionSortName, CollectionSortPriceAsc, CollectionSortPriceDesc, CollectionSortRating:
+	default:
+		return fmt.Errorf("%w: unknown sort order %q", ErrInvalidCollection, c.SortBy)
+	}
+	return nil
+}
+
+// validate checks a rule and normalises its value, clearing the fields its
+// field doesn't use
+func (r *CollectionRule) validate() error {
+	value := strings.TrimSpace(r.Value)
+	minPrice, maxPrice, days := r.Min, r.Max, r.Days
+	*r = CollectionRule{Field: r.Field}
+
+	switch r.Field {
+	case RuleTag:
+		r.Value = strings.ToLower(value)
+		if r.Value == "" {
+			return fmt.Errorf("%w: tag rules need a tag", ErrInvalidCollection)
+		}
+	case RulePrice:
+		if minPrice == nil && maxPrice == nil {
+			return fmt.Errorf("%w: price rules need a minimum or a maximum", ErrInvalidCollection)
+		}
+		if (minPrice != nil && *minPrice < 0) || (maxPrice != nil && *maxPrice < 0) {
+			return fmt.Errorf("%w: price bounds cannot be negative", ErrInvalidCollection)
+		}
+		if minPrice != nil && maxPrice != nil && *minPrice > *maxPrice {
+			return fmt.Errorf("%w: minimum price is above the maximum", ErrInvalidCollection)
+		}
+		r.Min, r.Max = minPrice, maxPrice
+	case RuleCategory:
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return fmt.Errorf("%w: invalid category ID %q", ErrInvalidCollection, value)
+		}
+		r.Value = id.String()
+	case RuleVendor:
+		r.Value = value
+		if r.Value == "" {
+			return fmt.Errorf("%w: vendor rules need a vendor", ErrInvalidCollection)
+		}
+	case RuleStock:
+		r.Value = value
+		if r.Value != StockInStock && r.Value != StockOutOfStock {
+			return fmt.Errorf("%w: stock rules must be %s or %s", ErrInvalidCollection, StockInStock, StockOutOfStock)
+		}
+	case RuleCreatedWithin:
+		if days < 1 || days > maxCreatedWithinDays {
+			return fmt.Errorf("%w: created within rules need 1 to %d days", ErrInvalidCollection, maxCreatedWithinDays)
+		}
+		r.Days = days
+	default:
+		return fmt.Errorf("%w: unknown rule field %q", ErrInvalidCollection, r.Field)
+	}
+	return nil
+}
+
+// GetSEOTitle returns the title for search engines
+func (c *Collection) GetSEOTitle() string {
+	if c.MetaTitle != "" {
+		return c.MetaTitle
+	}
+	return c.Title
+}
+
+// GetSEODescription returns the description for search engines
+func (c *Collection) GetSEODescription() string {
+	if c.MetaDescription != "" {
+		return c.MetaDescription
+	}
+	if c.Description != "" {
+		desc := c.Description
+		if len(desc) > 155 {
+			desc = desc[:152] + "..."
+		}
+		return desc
+	}
+	return "Shop " + c.Title
+}
@@ -0,0 +1,439 @@
+package product
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// createCollectionRequest is a collection with the products a manual
+// collection starts with
+type createCollectionRequest struct {
+	Collection
+	ProductIDs []uuid.UUID `json:"product_ids"`
+}
+
+// collectionProductsRequest names products of a manual collection
+type collectionProductsRequest struct {
+	ProductIDs []uuid.UUID `json:"product_ids" binding:"required"`
+}
+
+// registerCollectionRoutes registers collection management routes
+func (h *Handler) registerCollectionRoutes(router *gin.RouterGroup) {
+	collections := router.Group("/collections")
+	{
+		collections.GET("", h.ListCollections)
+		collections.POST("", h.CreateCollection)
+		collections.POST("/refresh", h.RefreshSmartCollections) // Run daily
+		collections.GET("/:id", h.GetCollection)
+		collections.PUT("/:id", h.UpdateCollection)
+		collections.DELETE("/:id", h.DeleteCollection)
+		collections.GET("/:id/products", h.GetCollectionProducts)
+		collections.POST("/:id/products", h.AddCollectionProducts)
+		collections.DELETE("/:id/products", h.RemoveCollectionProducts)
+		collections.PUT("/:id/products/order", h.ReorderCollectionProducts)
+	}
+}
+
+// ListCollections handles GET /api/collections
+func (h *Handler) ListCollections(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	offset, limit, ok := collectionPage(c)
+	if !ok {
+		return
+	}
+
+	filter := CollectionListFilter{
+		Type:   CollectionType(c.Query("type")),
+		Search: c.Query("search"),
+	}
+	if published := c.Query("is_published"); published != "" {
+		if value, err := strconv.ParseBool(published); err == nil {
+			filter.IsPublished = &value
+		}
+	}
+
+	collections, total, err := h.service.ListCollections(tenantID.(uuid.UUID), filter, offset, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch collections"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"collections": collections,
+			"total":       total,
+			"offset":      offset,
+			"limit":       limit,
+		},
+	})
+}
+
+// CreateCollection handles POST /api/collections
+func (h *Handler) CreateCollection(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	var req createCollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	collection, err := h.service.CreateCollection(tenantID.(uuid.UUID), &req.Collection, req.ProductIDs)
+	if err != nil {
+		c.JSON(collectionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Collection created successfully",
+		"data":    collection,
+	})
+}
+
+// GetCollection handles GET /api/collections/:id
+func (h *Handler) GetCollection(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	collectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection ID"})
+		return
+	}
+
+	collection, err := h.service.GetCollection(tenantID.(uuid.UUID), collectionID)
+	if err != nil {
+		c.JSON(collectionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": collection})
+}
+
+// UpdateCollection handles PUT /api/collections/:id
+func (h *Handler) UpdateCollection(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	collectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection ID"})
+		return
+	}
+
+	var collection Collection
+	if err := c.ShouldBindJSON(&collection); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	updated, err := h.service.UpdateCollection(tenantID.(uuid.UUID), collectionID, &collection)
+	if err != nil {
+		c.JSON(collectionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Collection updated successfully",
+		"data":    updated,
+	})
+}
+
+// DeleteCollection handles DELETE /api/collections/:id
+func (h *Handler) DeleteCollection(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	collectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection ID"})
+		return
+	}
+
+	if err := h.service.DeleteCollection(tenantID.(uuid.UUID), collectionID); err != nil {
+		c.JSON(collectionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Collection deleted successfully"})
+}
+
+// GetCollectionProducts handles GET /api/collections/:id/products
+func (h *Handler) GetCollectionProducts(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	collectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection ID"})
+		return
+	}
+
+	offset, limit, ok := collectionPage(c)
+	if !ok {
+		return
+	}
+
+	collection, err := h.service.GetCollection(tenantID.(uuid.UUID), collectionID)
+	if err != nil {
+		c.JSON(collectionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	products, total, err := h.service.ListCollectionProducts(tenantID.(uuid.UUID), collection, productListFilter(c), offset, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"products": products,
+			"total":    total,
+			"offset":   offset,
+			"limit":    limit,
+		},
+	})
+}
+
+// AddCollectionProducts handles POST /api/collections/:id/products
+func (h *Handler) AddCollectionProducts(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	collectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection ID"})
+		return
+	}
+
+	var req collectionProductsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	collection, err := h.service.AddCollectionProducts(tenantID.(uuid.UUID), collectionID, req.ProductIDs)
+	if err != nil {
+		c.JSON(collectionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Products added to collection",
+		"data":    collection,
+	})
+}
+
+// RemoveCollectionProducts handles DELETE /api/collections/:id/products
+func (h *Handler) RemoveCollectionProducts(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	collectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection ID"})
+		return
+	}
+
+	var req collectionProductsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	collection, err := h.service.RemoveCollectionProducts(tenantID.(uuid.UUID), collectionID, req.ProductIDs)
+	if err != nil {
+		c.JSON(collectionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Products removed from collection",
+		"data":    collection,
+	})
+}
+
+// ReorderCollectionProducts handles PUT /api/collections/:id/products/order
+func (h *Handler) ReorderCollectionProducts(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	collectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection ID"})
+		return
+	}
+
+	var req collectionProductsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	if err := h.service.ReorderCollectionProducts(tenantID.(uuid.UUID), collectionID, req.ProductIDs); err != nil {
+		c.JSON(collectionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Collection products reordered"})
+}
+
+// RefreshSmartCollections handles POST /api/collections/refresh
+func (h *Handler) RefreshSmartCollections(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	changed, err := h.service.RefreshSmartCollections(tenantID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh collections"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Smart collections refreshed",
+		"data":    gin.H{"changed_products": changed},
+	})
+}
+
+// GetPublicCollections handles GET /public/collections
+func (h *Handler) GetPublicCollections(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	offset, limit, ok := collectionPage(c)
+	if !ok {
+		return
+	}
+
+	published := true
+	filter := CollectionListFilter{IsPublished: &published, Search: c.Query("search")}
+	collections, total, err := h.service.ListCollections(tenantID.(uuid.UUID), filter, offset, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch collections"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"collections": collections,
+			"total":       total,
+			"offset":      offset,
+			"limit":       limit,
+		},
+	})
+}
+
+// GetPublicCollection handles GET /public/collections/:slug with a page of
+// the collection's active products
+func (h *Handler) GetPublicCollection(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	offset, limit, ok := collectionPage(c)
+	if !ok {
+		return
+	}
+
+	collection, err := h.service.GetCollectionBySlug(tenantID.(uuid.UUID), c.Param("slug"))
+	if err != nil || !collection.IsPublished {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Collection not found"})
+		return
+	}
+
+	filter := productListFilter(c)
+	filter.Status = "active" // Only show active products publicly
+	filter.Type = ""
+	products, total, err := h.service.ListCollectionProducts(tenantID.(uuid.UUID), collection, filter, offset, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"collection": collection,
+			"products":   products,
+			"total":      total,
+			"offset":     offset,
+			"limit":      limit,
+			"seo":        collection.SEO(products),
+		},
+	})
+}
+
+// collectionPage parses the offset and limit of a listing, answering the
+// request itself when they are invalid
+func collectionPage(c *gin.Context) (int, int, bool) {
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset parameter"})
+		return 0, 0, false
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+		return 0, 0, false
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if limit < 1 {
+		limit = 20
+	}
+	return offset, limit, true
+}
+
+func collectionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrCollectionNotFound), errors.Is(err, ErrProductNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInvalidCollection):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,324 @@
+package product
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+)
+
+// ListCollections returns a page of collections
+func (s *Service) ListCollections(tenantID uuid.UUID, filter CollectionListFilter, offset, limit int) ([]*Collection, int64, error) {
+	return s.repo.ListCollections(tenantID, filter, offset, limit)
+}
+
+// GetCollection retrieves a collection by ID
+func (s *Service) GetCollection(tenantID, collectionID uuid.UUID) (*Collection, error) {
+	return s.repo.FindCollection(tenantID, collectionID)
+}
+
+// GetCollectionBySlug retrieves a collection by slug
+func (s *Service) GetCollectionBySlug(tenantID uuid.UUID, slug string) (*Collection, error) {
+	return s.repo.FindCollectionBySlug(tenantID, slug)
+}
+
+// ListCollectionProducts returns a page of a collection's products, in the
+// collection's sort order unless the filter asks for another
+func (s *Service) ListCollectionProducts(tenantID uuid.UUID, collection *Collection, filter ProductListFilter, offset, limit int) ([]*Product, int64, error) {
+	return s.repo.ListCollectionProducts(tenantID, collection, filter, offset, limit)
+}
+
+// CreateCollection creates a collection. Manual collections start with the
+// given products in the given order; smart collections are filled with the
+// products matching their rules.
+func (s *Service) CreateCollection(tenantID uuid.UUID, collection *Collection, productIDs []uuid.UUID) (*Collection, error) {
+	collection.ID = uuid.New()
+	collection.TenantID = tenantID
+	if err := collection.Validate(); err != nil {
+		return nil, err
+	}
+	if collection.IsSmart() && len(productIDs) > 0 {
+		return nil, fmt.Errorf("%w: products of smart collections follow their rules", ErrInvalidCollection)
+	}
+	if err := s.validateCollectionRules(tenantID, collection); err != nil {
+		return nil, err
+	}
+
+	slug, err := s.uniqueCollectionSlug(tenantID, collection.ID, collection.Slug, collection.Title)
+	if err != nil {
+		return nil, err
+	}
+	collection.Slug = slug
+
+	saved, err := s.repo.SaveCollection(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []uuid.UUID
+	if saved.IsSmart() {
+		changed, err = s.repo.SyncCollection(saved, nil)
+	} else if len(productIDs) > 0 {
+		changed, err = s.repo.AddCollectionProducts(tenantID, saved.ID, productIDs)
+	}
+	if err != nil {
+		// Don't leave a half-made collection behind
+		if _, deleteErr := s.repo.DeleteCollection(tenantID, saved.ID); deleteErr != nil {
+			log.Printf("Failed to remove collection %s after failed creation: %v", saved.ID, deleteErr)
+		}
+		return nil, err
+	}
+
+	s.notifyProductsChanged(tenantID, changed...)
+	return s.repo.FindCollection(tenantID, saved.ID)
+}
+
+// UpdateCollection updates a collection. The type is fixed once created;
+// smart collections are re-matched against their rules.
+func (s *Service) UpdateCollection(tenantID, collectionID uuid.UUID, collection *Collection) (*Collection, error) {
+	existing, err := s.repo.FindCollection(tenantID, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	if collection.Type != "" && collection.Type != existing.Type {
+		return nil, fmt.Errorf("%w: type cannot be changed", ErrInvalidCollection)
+	}
+
+	// Members are indexed with the collection's title and only while it is
+	// published, so they need reindexing when either changes
+	labelChanged := (collection.Title != "" && collection.Title != existing.Title) ||
+		collection.IsPublished != existing.IsPublished
+
+	if collection.Title != "" {
+		existing.Title = collection.Title
+	}
+	if collection.Description != "" {
+		existing.Description = collection.Description
+	}
+	if collection.Image != "" {
+		existing.Image = collection.Image
+	}
+	if collection.Rules != nil {
+		existing.Rules = collection.Rules
+	}
+	if collection.RuleMatch != "" {
+		existing.RuleMatch = collection.RuleMatch
+	}
+	if collection.SortBy != "" {
+		existing.SortBy = collection.SortBy
+	}
+	existing.IsPublished = collection.IsPublished
+	if collection.MetaTitle != "" {
+		existing.MetaTitle = collection.MetaTitle
+	}
+	if collection.MetaDescription != "" {
+		existing.MetaDescription = collection.MetaDescription
+	}
+	if collection.MetaKeywords != "" {
+		existing.MetaKeywords = collection.MetaKeywords
+	}
+	if err := existing.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.validateCollectionRules(tenantID, existing); err != nil {
+		return nil, err
+	}
+
+	if collection.Slug != "" && collection.Slug != existing.Slug {
+		slug, err := s.uniqueCollectionSlug(tenantID, existing.ID, collection.Slug, existing.Title)
+		if err != nil {
+			return nil, err
+		}
+		existing.Slug = slug
+	}
+
+	updated, err := s.repo.UpdateCollection(existing)
+	if err != nil {
+		return nil, err
+	}
+
+	// Products that just left a smart collection are among the changed ones
+	// though no longer members
+	var changed []uuid.UUID
+	if updated.IsSmart() {
+		if changed, err = s.repo.SyncCollection(updated, nil); err != nil {
+			return nil, err
+		}
+	}
+	if labelChanged {
+		members, err := s.repo.CollectionProductIDs(updated.ID)
+		if err != nil {
+			return nil, err
+		}
+		changed = append(changed, members...)
+	}
+
+	s.notifyProductsChanged(tenantID, changed...)
+	return s.repo.FindCollection(tenantID, updated.ID)
+}
+
+// DeleteCollection deletes a collection, leaving its products in place
+func (s *Service) DeleteCollection(tenantID, collectionID uuid.UUID) error {
+	productIDs, err := s.repo.DeleteCollection(tenantID, collectionID)
+	if err != nil {
+		return err
+	}
+
+	s.notifyProductsChanged(tenantID, productIDs...)
+	return nil
+}
+
+// AddCollectionProducts appends products to the end of a manual collection
+func (s *Service) AddCollectionProducts(tenantID, collectionID uuid.UUID, productIDs []uuid.UUID) (*Collection, error) {
+	collection, err := s.manualCollection(tenantID, collectionID, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	added, err := s.repo.AddCollectionProducts(tenantID, collection.ID, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyProductsChanged(tenantID, added...)
+	return s.repo.FindCollection(tenantID, collection.ID)
+}
+
+// RemoveCollectionProducts removes products from a manual collection
+func (s *Service) RemoveCollectionProducts(tenantID, collectionID uuid.UUID, productIDs []uuid.UUID) (*Collection, error) {
+	collection, err := s.manualCollection(tenantID, collectionID, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	removed, err := s.repo.RemoveCollectionProducts(collection.ID, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyProductsChanged(tenantID, removed...)
+	return s.repo.FindCollection(tenantID, collection.ID)
+}
+
+// ReorderCollectionProducts puts the given products of a manual collection
+// first, in the given order
+func (s *Service) ReorderCollectionProducts(tenantID, collectionID uuid.UUID, productIDs []uuid.UUID) error {
+	collection, err := s.manualCollection(tenantID, collectionID, productIDs)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.ReorderCollectionProducts(collection.ID, productIDs)
+}
+
+// RefreshSmartCollections re-matches every smart collection of a tenant
+// against all products, and returns the number of products that joined or
+// left one. Product writes keep collections up to date on their own; this
+// catches what time changes, such as created within rules, and stock moved
+// by orders. Run daily.
+func (s *Service) RefreshSmartCollections(tenantID uuid.UUID) (int, error) {
+	collections, err := s.repo.ListSmartCollections(tenantID)
+	if err != nil {
+		return 0, err
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	var changed []uuid.UUID
+	for _, collection := range collections {
+		productIDs, err := s.repo.SyncCollection(collection, nil)
+		if err != nil {
+			return 0, err
+		}
+		for _, id := range productIDs {
+			if !seen[id] {
+				seen[id] = true
+				changed = append(changed, id)
+			}
+		}
+	}
+
+	s.notifyProductsChanged(tenantID, changed...)
+	return len(changed), nil
+}
+
+// manualCollection loads a collection whose members are edited by hand
+func (s *Service) manualCollection(tenantID, collectionID uuid.UUID, productIDs []uuid.UUID) (*Collection, error) {
+	if len(productIDs) == 0 {
+		return nil, fmt.Errorf("%w: no products given", ErrInvalidCollection)
+	}
+
+	collection, err := s.repo.FindCollection(tenantID, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	if collection.IsSmart() {
+		return nil, fmt.Errorf("%w: products of smart collections follow their rules", ErrInvalidCollection)
+	}
+	return collection, nil
+}
+
+// validateCollectionRules checks that category rules name categories of the
+// tenant
+func (s *Service) validateCollectionRules(tenantID uuid.UUID, collection *Collection) error {
+	for _, rule := range collection.Rules {
+		if rule.Field != RuleCategory {
+			continue
+		}
+		if exists, err := s.repo.CategoryExists(tenantID, uuid.MustParse(rule.Value)); err != nil {
+			return err
+		} else if !exists {
+			return fmt.Errorf("%w: category %s not found", ErrInvalidCollection, rule.Value)
+		}
+	}
+	return nil
+}
+
+// uniqueCollectionSlug returns a slug for the collection from the requested
+// slug, or from its title when none was requested, that no other collection
+// of the tenant uses
+func (s *Service) uniqueCollectionSlug(tenantID, collectionID uuid.UUID, requested, title string) (string, error) {
+	base := requested
+	if base == "" {
+		base = title
+	}
+	slug := s.generateSlug(base)
+
+	if exists, err := s.repo.CollectionSlugExists(tenantID, slug, collectionID); err != nil {
+		return "", err
+	} else if !exists {
+		return slug, nil
+	}
+
+	for i := 1; i <= 100; i++ {
+		newSlug := fmt.Sprintf("%s-%d", slug, i)
+		if exists, err := s.repo.CollectionSlugExists(tenantID, newSlug, collectionID); err == nil && !exists {
+			return newSlug, nil
+		}
+	}
+	return slug + "-" + uuid.New().String()[:8], nil
+}
+
+// syncSmartCollections re-matches the given products against every smart
+// collection of the tenant, or all products when productIDs is nil, and
+// returns the products that joined or left one
+func (s *Service) syncSmartCollections(tenantID uuid.UUID, productIDs []uuid.UUID, filter func(*Collection) bool) []uuid.UUID {
+	collections, err := s.repo.ListSmartCollections(tenantID)
+	if err != nil {
+		log.Printf("Failed to load smart collections of tenant %s: %v", tenantID, err)
+		return nil
+	}
+
+	var changed []uuid.UUID
+	for _, collection := range collections {
+		if filter != nil && !filter(collection) {
+			continue
+		}
+		ids, err := s.repo.SyncCollection(collection, productIDs)
+		if err != nil {
+			log.Printf("Failed to sync smart collection %s: %v", collection.ID, err)
+			continue
+		}
+		changed = append(changed, ids...)
+	}
+	return changed
+}
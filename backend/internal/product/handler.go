@@ -7,6 +7,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+
+	"ecommerce-saas/internal/tenant"
 )
 
 // Handler handles HTTP requests for product operations
@@ -251,35 +253,7 @@ func (h *Handler) ListProducts(c *gin.Context) {
 	}
 
 	// Regular product listing with filters
-	var filter ProductListFilter
-	
-	if status := c.Query("status"); status != "" {
-		filter.Status = ProductStatus(status)
-	}
-	if productType := c.Query("product_type"); productType != "" {
-		filter.Type = ProductType(productType)
-	}
-	if categoryID := c.Query("category_id"); categoryID != "" {
-		if id, err := uuid.Parse(categoryID); err == nil {
-			filter.CategoryID = &id
-		}
-	}
-	if minPrice := c.Query("min_price"); minPrice != "" {
-		if price, err := strconv.ParseFloat(minPrice, 64); err == nil {
-			filter.MinPrice = &price
-		}
-	}
-	if maxPrice := c.Query("max_price"); maxPrice != "" {
-		if price, err := strconv.ParseFloat(maxPrice, 64); err == nil {
-			filter.MaxPrice = &price
-		}
-	}
-	if inStock := c.Query("in_stock"); inStock != "" {
-		if stock, err := strconv.ParseBool(inStock); err == nil {
-			filter.InStock = &stock
-		}
-	}
-	filter.Search = c.Query("search")
+	filter := productListFilter(c)
 
 	// Parse pagination
 	offsetStr := c.DefaultQuery("offset", "0")
@@ -322,6 +296,46 @@ func (h *Handler) ListProducts(c *gin.Context) {
 	})
 }
 
+// productListFilter reads the product listing filters from the query string
+func productListFilter(c *gin.Context) ProductListFilter {
+	var filter ProductListFilter
+	
+	if status := c.Query("status"); status != "" {
+		filter.Status = ProductStatus(status)
+	}
+	if productType := c.Query("product_type"); productType != "" {
+		filter.Type = ProductType(productType)
+	}
+	if categoryID := c.Query("category_id"); categoryID != "" {
+		if id, err := uuid.Parse(categoryID); err == nil {
+			filter.CategoryID = &id
+		}
+	}
+	if minPrice := c.Query("min_price"); minPrice != "" {
+		if price, err := strconv.ParseFloat(minPrice, 64); err == nil {
+			filter.MinPrice = &price
+		}
+	}
+	if maxPrice := c.Query("max_price"); maxPrice != "" {
+		if price, err := strconv.ParseFloat(maxPrice, 64); err == nil {
+			filter.MaxPrice = &price
+		}
+	}
+	if inStock := c.Query("in_stock"); inStock != "" {
+		if stock, err := strconv.ParseBool(inStock); err == nil {
+			filter.InStock = &stock
+		}
+	}
+	if minRating := c.Query("min_rating"); minRating != "" {
+		if rating, err := strconv.ParseFloat(minRating, 64); err == nil {
+			filter.MinRating = &rating
+		}
+	}
+	filter.Search = c.Query("search")
+	filter.Sort = c.Query("sort")
+	return filter
+}
+
 // DeleteProduct handles DELETE /api/products/:id
 func (h *Handler) DeleteProduct(c *gin.Context) {
 	tenantID, exists := c.Get("tenant_id")
@@ -488,7 +502,7 @@ func (h *Handler) CreateProductVariant(c *gin.Context) {
 
 	createdVariant, err := h.service.CreateProductVariant(tenantID.(uuid.UUID), productID, &variant)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(variantErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -524,7 +538,7 @@ func (h *Handler) GetProductVariants(c *gin.Context) {
 	})
 }
 
-// UpdateProductVariant handles PUT /api/products/:id/variants/:variantId
+// UpdateProductVariant handles PUT /api/products/:id/variants/:variant_id
 func (h *Handler) UpdateProductVariant(c *gin.Context) {
 	tenantID, exists := c.Get("tenant_id")
 	if !exists {
@@ -539,7 +553,7 @@ func (h *Handler) UpdateProductVariant(c *gin.Context) {
 		return
 	}
 
-	variantIDStr := c.Param("variantId")
+	variantIDStr := c.Param("variant_id")
 	variantID, err := uuid.Parse(variantIDStr)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant ID"})
@@ -554,7 +568,7 @@ func (h *Handler) UpdateProductVariant(c *gin.Context) {
 
 	updatedVariant, err := h.service.UpdateProductVariant(tenantID.(uuid.UUID), productID, variantID, &variant)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(variantErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -564,7 +578,7 @@ func (h *Handler) UpdateProductVariant(c *gin.Context) {
 	})
 }
 
-// DeleteProductVariant handles DELETE /api/products/:id/variants/:variantId
+// DeleteProductVariant handles DELETE /api/products/:id/variants/:variant_id
 func (h *Handler) DeleteProductVariant(c *gin.Context) {
 	tenantID, exists := c.Get("tenant_id")
 	if !exists {
@@ -579,7 +593,7 @@ func (h *Handler) DeleteProductVariant(c *gin.Context) {
 		return
 	}
 
-	variantIDStr := c.Param("variantId")
+	variantIDStr := c.Param("variant_id")
 	variantID, err := uuid.Parse(variantIDStr)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant ID"})
@@ -779,12 +793,10 @@ func (h *Handler) HandleProductOperations(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "Products updated successfully"})
 		return
 	case "import":
-		// TODO: Implement product import functionality
-		c.JSON(http.StatusNotImplemented, gin.H{"error": "Import functionality not implemented"})
+		h.StartProductImport(c)
 		return
 	case "export":
-		// TODO: Implement product export functionality
-		c.JSON(http.StatusNotImplemented, gin.H{"error": "Export functionality not implemented"})
+		h.ExportProducts(c)
 		return
 	default:
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid operation type"})
@@ -804,6 +816,9 @@ func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 		products.DELETE("/:id", h.DeleteProduct)
 		products.GET("/slug/:slug", h.GetProductBySlug)
 		
+		// Import and export
+		h.registerImportExportRoutes(products)
+		
 		// Bulk operations
 		products.POST("/bulk", h.HandleProductBulk)
 		
@@ -819,6 +834,10 @@ func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 		products.GET("/:id/variants", h.GetProductVariants)
 		products.PUT("/:id/variants/:variant_id", h.UpdateProductVariant)
 		products.DELETE("/:id/variants/:variant_id", h.DeleteProductVariant)
+		products.PATCH("/:id/variants", h.BulkUpdateVariants)
+		products.POST("/:id/variants/generate", h.GenerateVariants)
+		products.GET("/:id/variants/lookup", h.GetVariantByOptions)
+		products.PUT("/:id/options", h.UpdateProductOptions)
 	}
 
 	// Category routes
@@ -831,6 +850,12 @@ func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 		categories.DELETE("/:id", h.DeleteCategory)
 	}
 
+	// Attribute definitions and sets
+	h.registerAttributeRoutes(router)
+
+	// Manual and smart collections
+	h.registerCollectionRoutes(router)
+
 	// Note: Public routes are registered separately in routes.go setupPublicProductRoutes
 	// to avoid duplicate registration conflicts
 }
@@ -978,7 +1003,13 @@ func (h *Handler) GetPublicProducts(c *gin.Context) {
 			filter.CategoryID = &id
 		}
 	}
+	if minRating := c.Query("min_rating"); minRating != "" {
+		if rating, err := strconv.ParseFloat(minRating, 64); err == nil {
+			filter.MinRating = &rating
+		}
+	}
 	filter.Search = c.Query("search")
+	filter.Sort = c.Query("sort")
 
 	// Parse pagination
 	offsetStr := c.DefaultQuery("offset", "0")
@@ -1041,7 +1072,10 @@ func (h *Handler) GetPublicProduct(c *gin.Context) {
 	}
 
 	include := c.Query("include")
-	responseData := gin.H{"product": product}
+	responseData := gin.H{
+		"product": product,
+		"seo":     product.SEO(tenantCurrency(c)),
+	}
 	
 	if strings.Contains(include, "variants") {
 		variants, _ := h.service.GetProductVariants(tenantID.(uuid.UUID), productIDStr)
@@ -1060,6 +1094,16 @@ func (h *Handler) GetPublicProduct(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": responseData})
 }
 
+// tenantCurrency returns the currency of the storefront's tenant
+func tenantCurrency(c *gin.Context) string {
+	if value, exists := c.Get("tenant"); exists {
+		if t, ok := value.(*tenant.Tenant); ok && t.Currency != "" {
+			return t.Currency
+		}
+	}
+	return "BDT"
+}
+
 // GetPublicCategories handles GET /public/categories
 func (h *Handler) GetPublicCategories(c *gin.Context) {
 	tenantID, exists := c.Get("tenant_id")
@@ -0,0 +1,651 @@
+package product
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/xuri/excelize/v2"
+)
+
+// File formats of product imports and exports
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// ImportJobStatus is the state of a product import job
+type ImportJobStatus string
+
+const (
+	ImportPending   ImportJobStatus = "pending"
+	ImportRunning   ImportJobStatus = "running"
+	ImportCompleted ImportJobStatus = "completed"
+	ImportFailed    ImportJobStatus = "failed"
+)
+
+const (
+	maxImportFileSize   = 20 << 20
+	maxImportRows       = 20000
+	maxImportErrors     = 1000 // errors kept on a job; later failures are only counted
+	importProgressEvery = 25   // products imported between progress saves
+
+	// staleImportAfter is how long a running job may go without saving
+	// progress before it is taken for lost with the worker that ran it
+	staleImportAfter = 15 * time.Minute
+
+	// listSeparator joins the values of list cells such as images and tags
+	listSeparator = "|"
+	// categoryPathSeparator joins the category names of a category path
+	categoryPathSeparator = " > "
+)
+
+var (
+	ErrImportJobNotFound = errors.New("import job not found")
+	ErrInvalidImportFile = errors.New("invalid import file")
+)
+
+// ImportJob is a product import run by the worker. The uploaded file is kept
+// on the job until the worker claims it. A dry run validates every row
+// without writing anything.
+type ImportJob struct {
+	ID       uuid.UUID       `json:"id" gorm:"primarykey"`
+	TenantID uuid.UUID       `json:"tenant_id" gorm:"not null;index"`
+	FileName string          `json:"file_name"`
+	Format   string          `json:"format"`
+	DryRun   bool            `json:"dry_run"`
+	Status   ImportJobStatus `json:"status"`
+	Data     []byte          `json:"-" gorm:"column:file_data"`
+
+	// Progress
+	TotalRows       int `json:"total_rows"`
+	ProcessedRows   int `json:"processed_rows"`
+	FailedRows      int `json:"failed_rows"`
+	ProductsCreated int `json:"products_created"`
+	ProductsUpdated int `json:"products_updated"`
+	VariantsCreated int `json:"variants_created"`
+	VariantsUpdated int `json:"variants_updated"`
+
+	// Row-level validation errors, and the error that stopped the job
+	Errors []ImportRowError `json:"errors,omitempty" gorm:"serializer:json"`
+	Error  string           `json:"error,omitempty"`
+
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName specifies the table name for ImportJob
+func (ImportJob) TableName() string {
+	return "product_import_jobs"
+}
+
+// ImportRowError reports a row of an import file that was not imported.
+// Rows are numbered as in a spreadsheet, the header being row 1.
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// productColumns is the column schema shared by imports and exports. Files
+// have a row per variant and the rows of a product share its handle; product
+// columns are read from the first row of a product and may be left empty on
+// the others. Products without variants take a single row.
+var productColumns = []string{
+	"handle", "name", "description", "type", "status", "category", "tags", "vendor",
+	"price", "compare_price", "cost_price",
+	"sku", "barcode", "inventory_quantity", "track_quantity", "allow_backorder",
+	"weight", "length", "width", "height",
+	"meta_title", "meta_description", "meta_keywords",
+	"images", "attributes", "options",
+	"gift_card_denominations", "gift_card_validity_days",
+	"variant_name", "variant_sku", "variant_barcode",
+	"variant_price", "variant_compare_price", "variant_cost_price",
+	"variant_inventory_quantity", "variant_track_quantity", "variant_allow_backorder",
+	"variant_weight", "variant_length", "variant_width", "variant_height",
+	"variant_options", "variant_image", "variant_images", "variant_is_default",
+}
+
+func isVariantColumn(column string) bool {
+	return strings.HasPrefix(column, "variant_")
+}
+
+// importRow is a data row of an import file by column. Columns the file does
+// not have are missing from cells and leave the fields they map to unchanged;
+// empty cells clear them.
+type importRow struct {
+	number int
+	cells  map[string]string
+}
+
+func (r importRow) has(column string) bool {
+	_, ok := r.cells[column]
+	return ok
+}
+
+func (r importRow) value(column string) string {
+	return r.cells[column]
+}
+
+// hasVariant reports whether the row sets any variant column
+func (r importRow) hasVariant() bool {
+	for column, value := range r.cells {
+		if isVariantColumn(column) && value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// readImportFile reads the rows of a CSV or XLSX import file and checks its
+// header against the column schema
+func readImportFile(format string, data []byte) ([]importRow, error) {
+	var records [][]string
+	switch format {
+	case FormatCSV:
+		reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+		reader.FieldsPerRecord = -1
+		var err error
+		if records, err = reader.ReadAll(); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+		}
+	case FormatXLSX:
+		file, err := excelize.OpenReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+		}
+		defer file.Close()
+		sheets := file.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, fmt.Errorf("%w: workbook has no sheets", ErrInvalidImportFile)
+		}
+		if records, err = file.GetRows(sheets[0], excelize.Options{RawCellValue: true}); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+		}
+	default:
+		return nil, fmt.Errorf("%w: format must be csv or xlsx", ErrInvalidImportFile)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: file is empty", ErrInvalidImportFile)
+	}
+
+	header, err := importHeader(records[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(records)-1 > maxImportRows {
+		return nil, fmt.Errorf("%w: files are limited to %d rows", ErrInvalidImportFile, maxImportRows)
+	}
+
+	rows := make([]importRow, 0, len(records)-1)
+	for i, record := range records[1:] {
+		row := importRow{number: i + 2, cells: make(map[string]string, len(header))}
+		blank := true
+		for position, column := range header {
+			if column == "" {
+				continue
+			}
+			value := ""
+			if position < len(record) {
+				value = strings.TrimSpace(record[position])
+			}
+			row.cells[column] = value
+			blank = blank && value == ""
+		}
+		if !blank {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+// importHeader maps header cells to schema columns. Headers are matched
+// case-insensitively with spaces read as underscores; blank header cells
+// mark columns to skip.
+func importHeader(cells []string) ([]string, error) {
+	known := make(map[string]bool, len(productColumns))
+	for _, column := range productColumns {
+		known[column] = true
+	}
+
+	header := make([]string, len(cells))
+	seen := make(map[string]bool, len(cells))
+	var unknown []string
+	for i, cell := range cells {
+		column := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(cell)), " ", "_")
+		if column == "" {
+			continue
+		}
+		if !known[column] {
+			unknown = append(unknown, cell)
+			continue
+		}
+		if seen[column] {
+			return nil, fmt.Errorf("%w: duplicate column %s", ErrInvalidImportFile, column)
+		}
+		seen[column] = true
+		header[i] = column
+	}
+
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("%w: unknown columns %s", ErrInvalidImportFile, strings.Join(unknown, ", "))
+	}
+	if !seen["handle"] && !seen["sku"] {
+		return nil, fmt.Errorf("%w: a handle or sku column is required", ErrInvalidImportFile)
+	}
+	return header, nil
+}
+
+// groupImportRows groups rows by product: by handle, or by product SKU for
+// rows without one. Groups keep the order of their first row.
+func groupImportRows(rows []importRow) [][]importRow {
+	var groups [][]importRow
+	positions := make(map[string]int)
+	for _, row := range rows {
+		var key string
+		switch {
+		case row.value("handle") != "":
+			key = "handle:" + strings.ToLower(row.value("handle"))
+		case row.value("sku") != "":
+			key = "sku:" + row.value("sku")
+		default:
+			groups = append(groups, []importRow{row})
+			continue
+		}
+
+		if position, ok := positions[key]; ok {
+			groups[position] = append(groups[position], row)
+			continue
+		}
+		positions[key] = len(groups)
+		groups = append(groups, []importRow{row})
+	}
+	return groups
+}
+
+// rowReader applies the cells of a row to product and variant fields and
+// collects the cells it cannot parse
+type rowReader struct {
+	row    importRow
+	errors []ImportRowError
+}
+
+func (r *rowReader) fail(column, message string) {
+	r.errors = append(r.errors, ImportRowError{Row: r.row.number, Column: column, Message: message})
+}
+
+func (r *rowReader) string(column string, field *string) {
+	if r.row.has(column) {
+		*field = r.row.value(column)
+	}
+}
+
+func (r *rowReader) float(column string, field *float64) {
+	if !r.row.has(column) {
+		return
+	}
+	value, err := parseImportFloat(r.row.value(column))
+	if err != nil {
+		r.fail(column, err.Error())
+		return
+	}
+	*field = value
+}
+
+func (r *rowReader) int(column string, field *int) {
+	if !r.row.has(column) {
+		return
+	}
+	value := r.row.value(column)
+	if value == "" {
+		*field = 0
+		return
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		r.fail(column, "must be a whole number")
+		return
+	}
+	*field = parsed
+}
+
+func (r *rowReader) bool(column string, field *bool) {
+	if !r.row.has(column) {
+		return
+	}
+	switch strings.ToLower(r.row.value(column)) {
+	case "true", "yes", "y", "1":
+		*field = true
+	case "false", "no", "n", "0", "":
+		*field = false
+	default:
+		r.fail(column, "must be true or false")
+	}
+}
+
+func (r *rowReader) list(column string, field *[]string) {
+	if r.row.has(column) {
+		*field = splitList(r.row.value(column))
+	}
+}
+
+// json decodes a JSON cell into target and reports whether it did. Empty
+// cells leave target at its zero value.
+func (r *rowReader) json(column string, target interface{}) bool {
+	if !r.row.has(column) {
+		return false
+	}
+	if err := jsonCell(r.row.value(column), target); err != nil {
+		r.fail(column, "must be valid JSON")
+		return false
+	}
+	return true
+}
+
+func jsonCell(value string, target interface{}) error {
+	if value == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(value), target)
+}
+
+// readProduct applies the product columns of the row except handle and
+// category, which need lookups
+func (r *rowReader) readProduct(product *Product) {
+	r.string("name", &product.Name)
+	r.string("description", &product.Description)
+	if r.row.has("type") {
+		productType := ProductType(strings.ToLower(r.row.value("type")))
+		switch productType {
+		case "":
+			product.Type = TypePhysical
+		case TypePhysical, TypeDigital, TypeService, TypeGiftCard:
+			product.Type = productType
+		default:
+			r.fail("type", "must be physical, digital, service or gift_card")
+		}
+	}
+	if r.row.has("status") {
+		status := ProductStatus(strings.ToLower(r.row.value("status")))
+		switch status {
+		case "":
+			product.Status = StatusDraft
+		case StatusDraft, StatusActive, StatusInactive, StatusArchived:
+			product.Status = status
+		default:
+			r.fail("status", "must be draft, active, inactive or archived")
+		}
+	}
+	r.list("tags", &product.Tags)
+	r.string("vendor", &product.Vendor)
+
+	r.float("price", &product.Price)
+	r.float("compare_price", &product.ComparePrice)
+	r.float("cost_price", &product.CostPrice)
+	r.string("sku", &product.SKU)
+	r.string("barcode", &product.Barcode)
+	r.int("inventory_quantity", &product.InventoryQuantity)
+	r.bool("track_quantity", &product.TrackQuantity)
+	r.bool("allow_backorder", &product.AllowBackorder)
+	r.float("weight", &product.Weight)
+	r.float("length", &product.Length)
+	r.float("width", &product.Width)
+	r.float("height", &product.Height)
+
+	r.string("meta_title", &product.MetaTitle)
+	r.string("meta_description", &product.MetaDescription)
+	r.string("meta_keywords", &product.MetaKeywords)
+
+	if r.row.has("images") {
+		product.Images = splitList(r.row.value("images"))
+		product.FeaturedImage = ""
+		if len(product.Images) > 0 {
+			product.FeaturedImage = product.Images[0]
+		}
+	}
+
+	var attributes map[string]interface{}
+	if r.json("attributes", &attributes) {
+		product.Attributes = attributes
+	}
+	var options []ProductOption
+	if r.json("options", &options) {
+		product.Options = options
+	}
+
+	if r.row.has("gift_card_denominations") {
+		denominations, err := parseFloatList(r.row.value("gift_card_denominations"))
+		if err != nil {
+			r.fail("gift_card_denominations", err.Error())
+		} else {
+			product.GiftCardDenominations = denominations
+		}
+	}
+	r.int("gift_card_validity_days", &product.GiftCardValidityDays)
+}
+
+// readVariant applies the variant columns of the row
+func (r *rowReader) readVariant(variant *ProductVariant) {
+	r.string("variant_name", &variant.Name)
+	r.string("variant_sku", &variant.SKU)
+	r.string("variant_barcode", &variant.Barcode)
+	r.float("variant_price", &variant.Price)
+	r.float("variant_compare_price", &variant.ComparePrice)
+	r.float("variant_cost_price", &variant.CostPrice)
+	r.int("variant_inventory_quantity", &variant.InventoryQuantity)
+	r.bool("variant_track_quantity", &variant.TrackQuantity)
+	r.bool("variant_allow_backorder", &variant.AllowBackorder)
+	r.float("variant_weight", &variant.Weight)
+	r.float("variant_length", &variant.Length)
+	r.float("variant_width", &variant.Width)
+	r.float("variant_height", &variant.Height)
+	r.string("variant_image", &variant.Image)
+	r.list("variant_images", &variant.Images)
+	r.bool("variant_is_default", &variant.IsDefault)
+
+	var options map[string]string
+	if r.json("variant_options", &options) {
+		variant.Options = options
+	}
+}
+
+func parseImportFloat(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(parsed) || math.IsInf(parsed, 0) {
+		return 0, errors.New("must be a number")
+	}
+	return parsed, nil
+}
+
+func parseFloatList(value string) ([]float64, error) {
+	var values []float64
+	for _, part := range splitList(value) {
+		parsed, err := parseImportFloat(part)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, parsed)
+	}
+	return values, nil
+}
+
+func splitList(value string) []string {
+	var values []string
+	for _, part := range strings.Split(value, listSeparator) {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+	return values
+}
+
+// splitCategoryPath splits a path such as "Clothing > Shirts" into names
+func splitCategoryPath(path string) []string {
+	var names []string
+	for _, name := range strings.Split(path, strings.TrimSpace(categoryPathSeparator)) {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// exportRows renders a product as rows of the column schema
+func exportRows(product *Product, categoryPath string) [][]string {
+	cells := map[string]string{
+		"handle":                  product.Slug,
+		"name":                    product.Name,
+		"description":             product.Description,
+		"type":                    string(product.Type),
+		"status":                  string(product.Status),
+		"category":                categoryPath,
+		"tags":                    strings.Join(product.Tags, listSeparator),
+		"vendor":                  product.Vendor,
+		"price":                   formatImportFloat(product.Price),
+		"compare_price":           formatOptionalFloat(product.ComparePrice),
+		"cost_price":              formatOptionalFloat(product.CostPrice),
+		"sku":                     product.SKU,
+		"barcode":                 product.Barcode,
+		"inventory_quantity":      strconv.Itoa(product.InventoryQuantity),
+		"track_quantity":          strconv.FormatBool(product.TrackQuantity),
+		"allow_backorder":         strconv.FormatBool(product.AllowBackorder),
+		"weight":                  formatOptionalFloat(product.Weight),
+		"length":                  formatOptionalFloat(product.Length),
+		"width":                   formatOptionalFloat(product.Width),
+		"height":                  formatOptionalFloat(product.Height),
+		"meta_title":              product.MetaTitle,
+		"meta_description":        product.MetaDescription,
+		"meta_keywords":           product.MetaKeywords,
+		"images":                  strings.Join(product.Images, listSeparator),
+		"attributes":              formatJSONCell(product.Attributes, len(product.Attributes) == 0),
+		"options":                 formatJSONCell(product.Options, len(product.Options) == 0),
+		"gift_card_denominations": formatFloatList(product.GiftCardDenominations),
+		"gift_card_validity_days": "",
+	}
+	if product.GiftCardValidityDays != 0 {
+		cells["gift_card_validity_days"] = strconv.Itoa(product.GiftCardValidityDays)
+	}
+
+	if len(product.Variants) == 0 {
+		return [][]string{schemaRow(cells)}
+	}
+
+	rows := make([][]string, 0, len(product.Variants))
+	for i := range product.Variants {
+		variant := &product.Variants[i]
+		if i > 0 {
+			cells = map[string]string{"handle": product.Slug}
+		}
+		cells["variant_name"] = variant.Name
+		cells["variant_sku"] = variant.SKU
+		cells["variant_barcode"] = variant.Barcode
+		cells["variant_price"] = formatOptionalFloat(variant.Price)
+		cells["variant_compare_price"] = formatOptionalFloat(variant.ComparePrice)
+		cells["variant_cost_price"] = formatOptionalFloat(variant.CostPrice)
+		cells["variant_inventory_quantity"] = strconv.Itoa(variant.InventoryQuantity)
+		cells["variant_track_quantity"] = strconv.FormatBool(variant.TrackQuantity)
+		cells["variant_allow_backorder"] = strconv.FormatBool(variant.AllowBackorder)
+		cells["variant_weight"] = formatOptionalFloat(variant.Weight)
+		cells["variant_length"] = formatOptionalFloat(variant.Length)
+		cells["variant_width"] = formatOptionalFloat(variant.Width)
+		cells["variant_height"] = formatOptionalFloat(variant.Height)
+		cells["variant_options"] = formatJSONCell(variant.Options, len(variant.Options) == 0)
+		cells["variant_image"] = variant.Image
+		cells["variant_images"] = strings.Join(variant.Images, listSeparator)
+		cells["variant_is_default"] = strconv.FormatBool(variant.IsDefault)
+		rows = append(rows, schemaRow(cells))
+	}
+	return rows
+}
+
+func schemaRow(cells map[string]string) []string {
+	row := make([]string, len(productColumns))
+	for i, column := range productColumns {
+		row[i] = cells[column]
+	}
+	return row
+}
+
+func formatImportFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// formatOptionalFloat leaves unset amounts and measurements empty
+func formatOptionalFloat(value float64) string {
+	if value == 0 {
+		return ""
+	}
+	return formatImportFloat(value)
+}
+
+func formatFloatList(values []float64) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = formatImportFloat(value)
+	}
+	return strings.Join(parts, listSeparator)
+}
+
+func formatJSONCell(value interface{}, empty bool) string {
+	if empty {
+		return ""
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// writeExportFile writes rows of the column schema as a CSV or XLSX file
+func writeExportFile(format string, rows [][]string) ([]byte, error) {
+	switch format {
+	case FormatCSV:
+		var buf bytes.Buffer
+		writer := csv.NewWriter(&buf)
+		if err := writer.Write(productColumns); err != nil {
+			return nil, err
+		}
+		if err := writer.WriteAll(rows); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case FormatXLSX:
+		file := excelize.NewFile()
+		defer file.Close()
+		sheet := "Products"
+		if err := file.SetSheetName("Sheet1", sheet); err != nil {
+			return nil, err
+		}
+		header := productColumns
+		for i, row := range append([][]string{header}, rows...) {
+			cell, err := excelize.CoordinatesToCellName(1, i+1)
+			if err != nil {
+				return nil, err
+			}
+			// Cells are written as text so values read back unchanged
+			if err := file.SetSheetRow(sheet, cell, &row); err != nil {
+				return nil, err
+			}
+		}
+		buf, err := file.WriteToBuffer()
+		if err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, errors.New("format must be csv or xlsx")
+	}
+}
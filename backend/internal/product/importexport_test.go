@@ -0,0 +1,327 @@
+package product
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadImportFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		data     string
+		wantRows []map[string]string
+		wantNums []int
+		wantErr  string // part of the error; every error wraps ErrInvalidImportFile
+	}{
+		{
+			name:     "header cells match case-insensitively with spaces as underscores",
+			format:   FormatCSV,
+			data:     "Handle,Name,Variant SKU\nshirt,Shirt,SH-1\n",
+			wantRows: []map[string]string{{"handle": "shirt", "name": "Shirt", "variant_sku": "SH-1"}},
+			wantNums: []int{2},
+		},
+		{
+			name:     "a byte order mark is ignored",
+			format:   FormatCSV,
+			data:     "\xef\xbb\xbfhandle,name\nshirt,Shirt\n",
+			wantRows: []map[string]string{{"handle": "shirt", "name": "Shirt"}},
+			wantNums: []int{2},
+		},
+		{
+			name:     "blank rows are skipped but keep their numbers",
+			format:   FormatCSV,
+			data:     "handle,name\nshirt,Shirt\n,\nhat,Hat\n",
+			wantRows: []map[string]string{{"handle": "shirt", "name": "Shirt"}, {"handle": "hat", "name": "Hat"}},
+			wantNums: []int{2, 4},
+		},
+		{
+			name:     "short rows read missing cells as empty and cells are trimmed",
+			format:   FormatCSV,
+			data:     "sku,name,price\n  SH-1 ,Shirt\n",
+			wantRows: []map[string]string{{"sku": "SH-1", "name": "Shirt", "price": ""}},
+			wantNums: []int{2},
+		},
+		{
+			name:     "blank header cells skip their column",
+			format:   FormatCSV,
+			data:     "handle,,name\nshirt,ignored,Shirt\n",
+			wantRows: []map[string]string{{"handle": "shirt", "name": "Shirt"}},
+			wantNums: []int{2},
+		},
+		{
+			name:    "unknown columns are rejected",
+			format:  FormatCSV,
+			data:    "handle,colour\nshirt,red\n",
+			wantErr: "unknown columns colour",
+		},
+		{
+			name:    "duplicate columns are rejected",
+			format:  FormatCSV,
+			data:    "handle,Name,name\nshirt,a,b\n",
+			wantErr: "duplicate column name",
+		},
+		{
+			name:    "a handle or sku column is required",
+			format:  FormatCSV,
+			data:    "name,price\nShirt,10\n",
+			wantErr: "a handle or sku column is required",
+		},
+		{
+			name:    "empty files are rejected",
+			format:  FormatCSV,
+			data:    "",
+			wantErr: "file is empty",
+		},
+		{
+			name:    "malformed csv is rejected",
+			format:  FormatCSV,
+			data:    "handle,name\n\"shirt,Shirt\n",
+			wantErr: "extraneous or missing",
+		},
+		{
+			name:    "other formats are rejected",
+			format:  "json",
+			data:    "[]",
+			wantErr: "format must be csv or xlsx",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := readImportFile(tt.format, []byte(tt.data))
+			if tt.wantErr != "" {
+				if !errors.Is(err, ErrInvalidImportFile) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readImportFile: %v", err)
+			}
+
+			if len(rows) != len(tt.wantRows) {
+				t.Fatalf("read %d rows, want %d", len(rows), len(tt.wantRows))
+			}
+			for i, row := range rows {
+				if !reflect.DeepEqual(row.cells, tt.wantRows[i]) {
+					t.Errorf("row %d cells %v, want %v", i, row.cells, tt.wantRows[i])
+				}
+				if row.number != tt.wantNums[i] {
+					t.Errorf("row %d numbered %d, want %d", i, row.number, tt.wantNums[i])
+				}
+			}
+		})
+	}
+}
+
+func TestExportReadsBack(t *testing.T) {
+	product := &Product{
+		Slug:    "shirt",
+		Name:    "Shirt",
+		Type:    TypePhysical,
+		Status:  StatusActive,
+		Price:   19.99,
+		Tags:    []string{"summer", "cotton"},
+		Options: []ProductOption{{Name: "Size", Values: []OptionValue{{Value: "M"}, {Value: "L"}}}},
+		Variants: []ProductVariant{
+			{Name: "M", SKU: "SH-M", Price: 19.99, Options: map[string]string{"Size": "M"}},
+			{Name: "L", SKU: "SH-L", Price: 21.5, Options: map[string]string{"Size": "L"}},
+		},
+	}
+
+	for _, format := range []string{FormatCSV, FormatXLSX} {
+		t.Run(format, func(t *testing.T) {
+			data, err := writeExportFile(format, exportRows(product, "Clothing > Shirts"))
+			if err != nil {
+				t.Fatalf("writeExportFile: %v", err)
+			}
+			rows, err := readImportFile(format, data)
+			if err != nil {
+				t.Fatalf("readImportFile: %v", err)
+			}
+
+			groups := groupImportRows(rows)
+			if len(groups) != 1 || len(groups[0]) != 2 {
+				t.Fatalf("read back %d products, want one with two rows", len(groups))
+			}
+
+			imported := &Product{}
+			reader := &rowReader{row: groups[0][0]}
+			reader.readProduct(imported)
+			var variant ProductVariant
+			reader.readVariant(&variant)
+			if len(reader.errors) > 0 {
+				t.Fatalf("read errors %+v", reader.errors)
+			}
+			if imported.Name != product.Name || imported.Price != product.Price || !reflect.DeepEqual(imported.Tags, product.Tags) {
+				t.Errorf("read back %+v", imported)
+			}
+			if !reflect.DeepEqual(imported.Options, product.Options) {
+				t.Errorf("options %+v, want %+v", imported.Options, product.Options)
+			}
+			if groups[0][0].value("category") != "Clothing > Shirts" {
+				t.Errorf("category %q", groups[0][0].value("category"))
+			}
+			if variant.SKU != "SH-M" || variant.Price != 19.99 || variant.Options["Size"] != "M" {
+				t.Errorf("variant %+v", variant)
+			}
+		})
+	}
+}
+
+func TestGroupImportRows(t *testing.T) {
+	row := func(number int, handle, sku string) importRow {
+		return importRow{number: number, cells: map[string]string{"handle": handle, "sku": sku}}
+	}
+
+	tests := []struct {
+		name string
+		rows []importRow
+		want [][]int // row numbers of each group
+	}{
+		{
+			name: "rows of a handle group together in order of first appearance",
+			rows: []importRow{row(2, "shirt", ""), row(3, "hat", ""), row(4, "Shirt", "")},
+			want: [][]int{{2, 4}, {3}},
+		},
+		{
+			name: "rows without a handle group by sku",
+			rows: []importRow{row(2, "", "SH-1"), row(3, "", "SH-1"), row(4, "", "HT-1")},
+			want: [][]int{{2, 3}, {4}},
+		},
+		{
+			name: "a handle takes precedence over the sku",
+			rows: []importRow{row(2, "shirt", "SH-1"), row(3, "", "SH-1")},
+			want: [][]int{{2}, {3}},
+		},
+		{
+			name: "rows without handle or sku are products of their own",
+			rows: []importRow{row(2, "", ""), row(3, "", "")},
+			want: [][]int{{2}, {3}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got [][]int
+			for _, group := range groupImportRows(tt.rows) {
+				var numbers []int
+				for _, row := range group {
+					numbers = append(numbers, row.number)
+				}
+				got = append(got, numbers)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("groups %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRowReader(t *testing.T) {
+	tests := []struct {
+		name       string
+		cells      map[string]string
+		check      func(t *testing.T, product *Product)
+		wantErrors []string // columns that fail
+	}{
+		{
+			name:  "values are parsed into their fields",
+			cells: map[string]string{"price": "12.5", "inventory_quantity": "7", "track_quantity": "yes", "tags": "a | b ||", "status": "Active", "images": "1.jpg|2.jpg"},
+			check: func(t *testing.T, product *Product) {
+				if product.Price != 12.5 || product.InventoryQuantity != 7 || !product.TrackQuantity || product.Status != StatusActive {
+					t.Errorf("read %+v", product)
+				}
+				if !reflect.DeepEqual(product.Tags, []string{"a", "b"}) {
+					t.Errorf("tags %v", product.Tags)
+				}
+				if product.FeaturedImage != "1.jpg" {
+					t.Errorf("featured image %q", product.FeaturedImage)
+				}
+			},
+		},
+		{
+			name:  "empty cells clear their fields",
+			cells: map[string]string{"vendor": "", "price": "", "track_quantity": "", "type": ""},
+			check: func(t *testing.T, product *Product) {
+				if product.Vendor != "" || product.Price != 0 || product.TrackQuantity || product.Type != TypePhysical {
+					t.Errorf("read %+v", product)
+				}
+			},
+		},
+		{
+			name:  "missing columns leave their fields alone",
+			cells: map[string]string{"name": "Renamed"},
+			check: func(t *testing.T, product *Product) {
+				if product.Name != "Renamed" || product.Vendor != "Acme" || product.Price != 10 || !product.TrackQuantity {
+					t.Errorf("read %+v", product)
+				}
+			},
+		},
+		{
+			name: "unparseable cells are reported by column",
+			cells: map[string]string{
+				"price":                   "ten",
+				"inventory_quantity":      "1.5",
+				"allow_backorder":         "maybe",
+				"status":                  "live",
+				"type":                    "box",
+				"attributes":              "{",
+				"gift_card_denominations": "10|x",
+			},
+			wantErrors: []string{"allow_backorder", "attributes", "gift_card_denominations", "inventory_quantity", "price", "status", "type"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product := &Product{Vendor: "Acme", Price: 10, TrackQuantity: true}
+			reader := &rowReader{row: importRow{number: 2, cells: tt.cells}}
+			reader.readProduct(product)
+
+			var columns []string
+			for _, err := range reader.errors {
+				if err.Row != 2 {
+					t.Errorf("error %+v reported on the wrong row", err)
+				}
+				columns = append(columns, err.Column)
+			}
+			sortStrings(columns)
+			if !reflect.DeepEqual(columns, tt.wantErrors) {
+				t.Errorf("failed columns %v, want %v", columns, tt.wantErrors)
+			}
+			if tt.check != nil {
+				tt.check(t, product)
+			}
+		})
+	}
+}
+
+func TestSplitCategoryPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"Clothing > Shirts", []string{"Clothing", "Shirts"}},
+		{"Clothing>Shirts>Polo", []string{"Clothing", "Shirts", "Polo"}},
+		{" Clothing >  > Shirts ", []string{"Clothing", "Shirts"}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		if got := splitCategoryPath(tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitCategoryPath(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func sortStrings(values []string) {
+	for i := 1; i < len(values); i++ {
+		for j := i; j > 0 && values[j] < values[j-1]; j-- {
+			values[j], values[j-1] = values[j-1], values[j]
+		}
+	}
+}
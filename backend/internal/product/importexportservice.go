@@ -0,0 +1,549 @@
+package product
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const exportBatchSize = 200
+
+// Handles of imported products are used as their slugs
+var productHandlePattern = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+// StartProductImport reads an import file and records a pending job for it,
+// whose rows the worker imports with RunPendingImports. Unreadable files and
+// headers are rejected here; rows that cannot be imported are reported on
+// the job.
+func (s *Service) StartProductImport(tenantID uuid.UUID, fileName, format string, data []byte, dryRun bool) (*ImportJob, error) {
+	if len(data) > maxImportFileSize {
+		return nil, fmt.Errorf("%w: files are limited to %d MB", ErrInvalidImportFile, maxImportFileSize>>20)
+	}
+	rows, err := readImportFile(format, data)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &ImportJob{
+		ID:        uuid.New(),
+		TenantID:  tenantID,
+		FileName:  fileName,
+		Format:    format,
+		DryRun:    dryRun,
+		Status:    ImportPending,
+		Data:      data,
+		TotalRows: len(rows),
+	}
+	if _, err := s.repo.SaveImportJob(job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// RunPendingImports imports the tenant's pending import jobs one after the
+// other and returns the number run. A job another worker claimed first is
+// skipped.
+func (s *Service) RunPendingImports(tenantID uuid.UUID) (int, error) {
+	jobs, err := s.repo.ListPendingImportJobs(tenantID)
+	if err != nil {
+		return 0, err
+	}
+
+	run := 0
+	for _, job := range jobs {
+		claimed, err := s.repo.ClaimImportJob(job)
+		if err != nil {
+			return run, err
+		}
+		if !claimed {
+			continue
+		}
+
+		data := job.Data
+		job.Data = nil
+		run++
+
+		rows, err := readImportFile(job.Format, data)
+		if err != nil {
+			// The file was read when the job was started; this only fails if
+			// the parser changed since
+			importer := &productImporter{service: s, job: job}
+			importer.finish(err)
+			continue
+		}
+		s.runImport(job, rows)
+	}
+	return run, nil
+}
+
+// FailStaleImports fails the tenant's running import jobs that stopped
+// saving progress, such as those of a worker that was restarted, so they
+// don't stay running forever. It returns the number of jobs failed.
+func (s *Service) FailStaleImports(tenantID uuid.UUID) (int64, error) {
+	return s.repo.FailStaleImportJobs(tenantID, time.Now().Add(-staleImportAfter), "import stopped before it finished; start it again")
+}
+
+// GetImportJob returns an import job with its progress and row errors
+func (s *Service) GetImportJob(tenantID, jobID uuid.UUID) (*ImportJob, error) {
+	return s.repo.FindImportJob(tenantID, jobID)
+}
+
+// ListImportJobs returns the tenant's import jobs, newest first
+func (s *Service) ListImportJobs(tenantID uuid.UUID, offset, limit int) ([]*ImportJob, int64, error) {
+	return s.repo.ListImportJobs(tenantID, offset, limit)
+}
+
+// ExportProducts renders the products matching filter in the import column
+// schema, so an export can be edited and imported back unchanged
+func (s *Service) ExportProducts(tenantID uuid.UUID, format string, filter ProductListFilter) ([]byte, string, error) {
+	if format != FormatCSV && format != FormatXLSX {
+		return nil, "", errors.New("format must be csv or xlsx")
+	}
+
+	categories, err := s.repo.ListCategories(tenantID)
+	if err != nil {
+		return nil, "", err
+	}
+	paths := categoryPaths(categories)
+
+	var rows [][]string
+	afterID := uuid.Nil
+	for {
+		products, err := s.repo.ListProductsForExport(tenantID, filter, afterID, exportBatchSize)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, product := range products {
+			rows = append(rows, exportRows(product, paths[product.CategoryID])...)
+		}
+		if len(products) < exportBatchSize {
+			break
+		}
+		afterID = products[len(products)-1].ID
+	}
+
+	data, err := writeExportFile(format, rows)
+	if err != nil {
+		return nil, "", err
+	}
+	filename := fmt.Sprintf("products_export_%s.%s", time.Now().Format("20060102_150405"), format)
+	return data, filename, nil
+}
+
+// runImport imports the rows of a claimed job product by product, saving
+// progress as it goes
+func (s *Service) runImport(job *ImportJob, rows []importRow) {
+	importer := &productImporter{service: s, job: job}
+	defer func() {
+		if r := recover(); r != nil {
+			importer.finish(fmt.Errorf("import stopped: %v", r))
+		}
+	}()
+
+	if err := importer.loadCategories(); err != nil {
+		importer.finish(err)
+		return
+	}
+	for i, group := range groupImportRows(rows) {
+		importer.importProduct(group)
+		job.ProcessedRows += len(group)
+		if (i+1)%importProgressEvery == 0 {
+			importer.flush()
+		}
+	}
+	importer.finish(nil)
+}
+
+func (s *Service) saveImportJob(job *ImportJob) {
+	if err := s.repo.UpdateImportJob(job); err != nil {
+		log.Printf("Failed to save progress of product import %s: %v", job.ID, err)
+	}
+}
+
+// productImporter imports the products of an import job. Products are
+// written one at a time, each with its variants in a transaction, so a row
+// error only skips the product it belongs to.
+type productImporter struct {
+	service    *Service
+	job        *ImportJob
+	categories map[string]uuid.UUID // category IDs by lower-cased path
+	changed    []uuid.UUID          // products written since the last flush
+}
+
+func (i *productImporter) loadCategories() error {
+	categories, err := i.service.repo.ListCategories(i.job.TenantID)
+	if err != nil {
+		return err
+	}
+	i.categories = make(map[string]uuid.UUID, len(categories))
+	for id, path := range categoryPaths(categories) {
+		i.categories[strings.ToLower(path)] = id
+	}
+	return nil
+}
+
+// flush saves the job's progress and tells listeners about the products
+// written since the last flush
+func (i *productImporter) flush() {
+	i.service.saveImportJob(i.job)
+	if len(i.changed) > 0 {
+		i.service.productsChanged(i.job.TenantID, i.changed...)
+		i.changed = nil
+	}
+}
+
+func (i *productImporter) finish(err error) {
+	completedAt := time.Now()
+	i.job.Status = ImportCompleted
+	i.job.CompletedAt = &completedAt
+	if err != nil {
+		i.job.Status = ImportFailed
+		i.job.Error = err.Error()
+	}
+	i.flush()
+}
+
+// fail records the rows of a product that was not imported
+func (i *productImporter) fail(rows []importRow, errs []ImportRowError) {
+	i.job.FailedRows += len(rows)
+	for _, err := range errs {
+		if len(i.job.Errors) >= maxImportErrors {
+			return
+		}
+		i.job.Errors = append(i.job.Errors, err)
+	}
+}
+
+// importProduct validates and writes the product a group of rows describes
+func (i *productImporter) importProduct(rows []importRow) {
+	first := rows[0]
+	if errs := checkProductRows(rows); len(errs) > 0 {
+		i.fail(rows, errs)
+		return
+	}
+	rowError := func(column string, err error) {
+		i.fail(rows, []ImportRowError{{Row: first.number, Column: column, Message: err.Error()}})
+	}
+
+	product, create, err := i.findProduct(first)
+	if err != nil {
+		rowError("", err)
+		return
+	}
+
+	reader := &rowReader{row: first}
+	reader.readProduct(product)
+	if first.has("category") {
+		categoryID, err := i.resolveCategory(first.value("category"))
+		if err != nil {
+			reader.fail("category", err.Error())
+		} else {
+			product.CategoryID = categoryID
+		}
+	}
+	if len(reader.errors) > 0 {
+		i.fail(rows, reader.errors)
+		return
+	}
+
+	if create && product.Slug == "" {
+		slug := i.service.generateSlug(product.Name)
+		if exists, err := i.service.repo.SlugExists(i.job.TenantID, slug); err != nil {
+			rowError("", err)
+			return
+		} else if exists {
+			slug = i.service.generateUniqueSlug(i.job.TenantID, slug)
+		}
+		product.Slug = slug
+	}
+	if err := i.service.validateImportedProduct(product); err != nil {
+		rowError("", err)
+		return
+	}
+
+	created, updated, errs := importVariants(product, rows)
+	if len(errs) > 0 {
+		i.fail(rows, errs)
+		return
+	}
+
+	if !i.job.DryRun {
+		product.UpdatedAt = time.Now()
+		if err := i.service.repo.ImportProduct(product, create, created, updated); err != nil {
+			rowError("", err)
+			return
+		}
+		i.changed = append(i.changed, product.ID)
+	}
+
+	if create {
+		i.job.ProductsCreated++
+	} else {
+		i.job.ProductsUpdated++
+	}
+	i.job.VariantsCreated += len(created)
+	i.job.VariantsUpdated += len(updated)
+}
+
+// findProduct returns the product a row updates, matched by handle and then
+// by SKU, or a new product when there is none
+func (i *productImporter) findProduct(row importRow) (*Product, bool, error) {
+	tenantID := i.job.TenantID
+	handle := row.value("handle")
+	if handle != "" && !productHandlePattern.MatchString(handle) {
+		return nil, false, errors.New("handle may only contain lower-case letters, digits and hyphens")
+	}
+
+	if handle != "" {
+		product, err := i.service.repo.FindProductBySlug(tenantID, handle)
+		if err == nil {
+			return product, false, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, err
+		}
+	}
+	if sku := row.value("sku"); sku != "" {
+		product, err := i.service.repo.FindProductBySKU(tenantID, sku)
+		if err == nil {
+			// A handle matching no product renames the one found by SKU
+			if handle != "" {
+				product.Slug = handle
+			}
+			return product, false, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, err
+		}
+	}
+
+	return &Product{
+		ID:            uuid.New(),
+		TenantID:      tenantID,
+		Slug:          handle,
+		Type:          TypePhysical,
+		Status:        StatusDraft,
+		TrackQuantity: true,
+	}, true, nil
+}
+
+// resolveCategory returns the category at a path such as "Clothing > Shirts",
+// creating the categories missing from it. Dry runs only pretend to create
+// them.
+func (i *productImporter) resolveCategory(path string) (uuid.UUID, error) {
+	names := splitCategoryPath(path)
+	if len(names) == 0 {
+		return uuid.Nil, nil
+	}
+
+	var parentID *uuid.UUID
+	for depth, name := range names {
+		key := strings.ToLower(strings.Join(names[:depth+1], categoryPathSeparator))
+		id, ok := i.categories[key]
+		if !ok {
+			id = uuid.New()
+			if !i.job.DryRun {
+				category, err := i.service.CreateCategory(i.job.TenantID, &Category{Name: name, ParentID: parentID, IsActive: true})
+				if err != nil {
+					return uuid.Nil, err
+				}
+				id = category.ID
+			}
+			i.categories[key] = id
+		}
+		parentID = &id
+	}
+	return *parentID, nil
+}
+
+// validateImportedProduct runs the checks of CreateProduct and UpdateProduct
+// on an imported product
+func (s *Service) validateImportedProduct(product *Product) error {
+	if err := product.ValidateProductData(); err != nil {
+		return err
+	}
+	if err := s.validator.Struct(product); err != nil {
+		return err
+	}
+	if err := s.validateProductAttributes(product.TenantID, product); err != nil {
+		return err
+	}
+	if err := validateProductOptions(product.Options); err != nil {
+		return err
+	}
+	if len(product.Options) == 0 {
+		product.Options = nil
+	}
+
+	// Gift cards are issued on demand, there is no stock to count
+	if product.IsGiftCard() {
+		product.TrackQuantity = false
+	}
+	return nil
+}
+
+// checkProductRows checks that the rows after the first of a product leave
+// its product columns empty or repeat them
+func checkProductRows(rows []importRow) []ImportRowError {
+	var errs []ImportRowError
+	for _, row := range rows[1:] {
+		for _, column := range productColumns {
+			if column == "handle" || isVariantColumn(column) {
+				continue
+			}
+			if value := row.value(column); value != "" && value != rows[0].value(column) {
+				errs = append(errs, ImportRowError{
+					Row:     row.number,
+					Column:  column,
+					Message: fmt.Sprintf("differs from row %d of the same product", rows[0].number),
+				})
+			}
+		}
+	}
+	return errs
+}
+
+// importVariants applies the variant rows of a product to its variants,
+// matched by variant SKU and then by options. Variants the file leaves out
+// are kept, but must still fit the product's option definitions.
+func importVariants(product *Product, rows []importRow) (created, updated []*ProductVariant, errs []ImportRowError) {
+	variants := make([]*ProductVariant, 0, len(product.Variants)+len(rows))
+	for i := range product.Variants {
+		variants = append(variants, &product.Variants[i])
+	}
+	imported := make(map[uuid.UUID]int) // row numbers of imported variants
+
+	for _, row := range rows {
+		if !row.hasVariant() {
+			continue
+		}
+
+		variant := matchImportedVariant(product, variants, row)
+		if variant == nil {
+			variant = &ProductVariant{ID: uuid.New(), ProductID: product.ID, TrackQuantity: true}
+			variants = append(variants, variant)
+			created = append(created, variant)
+		} else if number, ok := imported[variant.ID]; ok {
+			errs = append(errs, ImportRowError{Row: row.number, Message: fmt.Sprintf("variant is already imported by row %d", number)})
+			continue
+		} else {
+			updated = append(updated, variant)
+		}
+		imported[variant.ID] = row.number
+
+		reader := &rowReader{row: row}
+		reader.readVariant(variant)
+		if len(reader.errors) > 0 {
+			errs = append(errs, reader.errors...)
+			continue
+		}
+		options, err := normalizeVariantOptions(product.Options, variant.Options)
+		if err != nil {
+			errs = append(errs, ImportRowError{Row: row.number, Column: "variant_options", Message: err.Error()})
+			continue
+		}
+		variant.Options = options
+		if variant.Name == "" && len(product.Options) > 0 {
+			variant.Name = variantOptionsName(product.Options, options)
+		}
+		if variant.Name == "" {
+			errs = append(errs, ImportRowError{Row: row.number, Column: "variant_name", Message: "variant name is required"})
+			continue
+		}
+		variant.UpdatedAt = time.Now()
+	}
+
+	// Variants the file leaves out follow changes to the option definitions
+	for i := range product.Variants {
+		variant := &product.Variants[i]
+		if _, ok := imported[variant.ID]; ok {
+			continue
+		}
+		options, err := normalizeVariantOptions(product.Options, variant.Options)
+		if err != nil {
+			errs = append(errs, ImportRowError{Row: rows[0].number, Column: "options", Message: fmt.Sprintf("variant %s: %v", variant.GetDisplayName(), err)})
+			continue
+		}
+		if variantOptionsKey(options) != variantOptionsKey(variant.Options) {
+			variant.Options = options
+			variant.UpdatedAt = time.Now()
+			updated = append(updated, variant)
+		}
+	}
+
+	seen := make(map[string]bool, len(variants))
+	for _, variant := range variants {
+		if len(variant.Options) == 0 {
+			continue
+		}
+		key := variantOptionsKey(variant.Options)
+		if seen[key] {
+			number, ok := imported[variant.ID]
+			if !ok {
+				number = rows[0].number
+			}
+			errs = append(errs, ImportRowError{Row: number, Column: "variant_options", Message: ErrDuplicateVariant.Error()})
+		}
+		seen[key] = true
+	}
+	return created, updated, errs
+}
+
+// matchImportedVariant returns the variant a row updates: the one with its
+// variant SKU, or else the one with its options
+func matchImportedVariant(product *Product, variants []*ProductVariant, row importRow) *ProductVariant {
+	if sku := row.value("variant_sku"); sku != "" {
+		for _, variant := range variants {
+			if variant.SKU == sku {
+				return variant
+			}
+		}
+		return nil
+	}
+
+	var options map[string]string
+	if err := jsonCell(row.value("variant_options"), &options); err != nil || len(options) == 0 {
+		return nil
+	}
+	normalized, err := normalizeVariantOptions(product.Options, options)
+	if err != nil {
+		return nil
+	}
+	key := variantOptionsKey(normalized)
+	for _, variant := range variants {
+		if len(variant.Options) > 0 && variantOptionsKey(variant.Options) == key {
+			return variant
+		}
+	}
+	return nil
+}
+
+// categoryPaths returns the path of every category, e.g. "Clothing > Shirts"
+func categoryPaths(categories []*Category) map[uuid.UUID]string {
+	byID := make(map[uuid.UUID]*Category, len(categories))
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+
+	paths := make(map[uuid.UUID]string, len(categories))
+	for _, category := range categories {
+		names := []string{category.Name}
+		parentID := category.ParentID
+		for depth := 0; parentID != nil && depth < 32; depth++ {
+			parent, ok := byID[*parentID]
+			if !ok {
+				break
+			}
+			names = append([]string{parent.Name}, names...)
+			parentID = parent.ParentID
+		}
+		paths[category.ID] = strings.Join(names, categoryPathSeparator)
+	}
+	return paths
+}
@@ -45,20 +45,15 @@ func (s *InventoryService) ReserveStock(tenantID, productID uuid.UUID, quantity
 
 // RestoreStock restores inventory (e.g., when an order is cancelled)
 func (s *InventoryService) RestoreStock(tenantID, productID uuid.UUID, quantity int) error {
-	// Get current product
-	product, err := s.repo.FindProductByID(tenantID, productID)
-	if err != nil {
-		return fmt.Errorf("failed to get product: %w", err)
-	}
+	return s.RestoreVariantStock(tenantID, productID, nil, quantity)
+}
 
-	// Only restore if tracking quantity
-	if product.TrackQuantity {
-		newQuantity := product.InventoryQuantity + quantity
-		if err := s.repo.UpdateInventory(tenantID, productID, newQuantity); err != nil {
-			return fmt.Errorf("failed to restore inventory: %w", err)
-		}
+// RestoreVariantStock restores inventory of a variant, or of the product
+// when variantID is nil (e.g., when a parcel returns to origin)
+func (s *InventoryService) RestoreVariantStock(tenantID, productID uuid.UUID, variantID *uuid.UUID, quantity int) error {
+	if err := s.repo.IncrementInventory(tenantID, productID, variantID, quantity); err != nil {
+		return fmt.Errorf("failed to restore inventory: %w", err)
 	}
-
 	return nil
 }
 
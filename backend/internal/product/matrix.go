@@ -0,0 +1,282 @@
+package product
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Largest variant matrix a product can generate
+const maxMatrixVariants = 250
+
+var (
+	ErrVariantNotFound      = errors.New("variant not found")
+	ErrInvalidVariantOption = errors.New("invalid variant options")
+	ErrDuplicateVariant     = errors.New("a variant with these options already exists")
+)
+
+var (
+	swatchColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+	skuTokenPattern    = regexp.MustCompile(`\{([^{}]+)\}`)
+	skuUnsafePattern   = regexp.MustCompile(`[^A-Z0-9]+`)
+)
+
+// ProductOption is an option the variants of a product vary by, such as size
+// or colour. Options and their values are shown in slice order.
+type ProductOption struct {
+	Name   string        `json:"name"`
+	Values []OptionValue `json:"values"`
+}
+
+// OptionValue is a value of a product option
+type OptionValue struct {
+	Value  string `json:"value"`
+	Swatch string `json:"swatch,omitempty"` // hex colour such as #c0392b, or an image URL
+}
+
+// GenerateVariantsRequest creates the variants missing from a product's
+// option matrix
+type GenerateVariantsRequest struct {
+	// SKU of each variant; {sku} is the product SKU and {<option name>} the
+	// option value, e.g. "{sku}-{size}-{color}". Defaults to the product SKU
+	// followed by every option value.
+	SKUPattern        string   `json:"sku_pattern,omitempty"`
+	Price             *float64 `json:"price,omitempty"` // defaults to the product price
+	InventoryQuantity int      `json:"inventory_quantity,omitempty"`
+	RemoveUnlisted    bool     `json:"remove_unlisted,omitempty"` // delete variants outside the matrix, except those on orders
+}
+
+// GenerateVariantsResult reports what generating a matrix changed
+type GenerateVariantsResult struct {
+	Created  []*ProductVariant `json:"created"`
+	Existing int               `json:"existing"`
+	Removed  int               `json:"removed"`
+	Kept     []uuid.UUID       `json:"kept,omitempty"` // unlisted variants kept because orders reference them
+}
+
+// BulkUpdateVariantsRequest applies price and stock changes across a product's
+// variants, in order
+type BulkUpdateVariantsRequest struct {
+	Updates []VariantBulkUpdate `json:"updates" binding:"required,min=1"`
+}
+
+// VariantBulkUpdate changes the variants it selects: those listed in
+// VariantIDs, else those whose options include Options (e.g. every red
+// variant), else all of them
+type VariantBulkUpdate struct {
+	VariantIDs          []uuid.UUID       `json:"variant_ids,omitempty"`
+	Options             map[string]string `json:"options,omitempty"`
+	Price               *float64          `json:"price,omitempty"`
+	ComparePrice        *float64          `json:"compare_price,omitempty"`
+	CostPrice           *float64          `json:"cost_price,omitempty"`
+	InventoryQuantity   *int              `json:"inventory_quantity,omitempty"`   // sets the stock
+	InventoryAdjustment *int              `json:"inventory_adjustment,omitempty"` // adds to the stock
+}
+
+// validateProductOptions checks option definitions and tidies their names and
+// values
+func validateProductOptions(options []ProductOption) error {
+	names := make(map[string]bool, len(options))
+	combinations := 1
+	for i := range options {
+		option := &options[i]
+		option.Name = strings.TrimSpace(option.Name)
+		if option.Name == "" {
+			return fmt.Errorf("%w: option name is required", ErrInvalidVariantOption)
+		}
+		if names[strings.ToLower(option.Name)] {
+			return fmt.Errorf("%w: option %s is listed twice", ErrInvalidVariantOption, option.Name)
+		}
+		names[strings.ToLower(option.Name)] = true
+
+		if len(option.Values) == 0 {
+			return fmt.Errorf("%w: option %s needs at least one value", ErrInvalidVariantOption, option.Name)
+		}
+		values := make(map[string]bool, len(option.Values))
+		for j := range option.Values {
+			value := &option.Values[j]
+			value.Value = strings.TrimSpace(value.Value)
+			value.Swatch = strings.TrimSpace(value.Swatch)
+			if value.Value == "" {
+				return fmt.Errorf("%w: option %s has an empty value", ErrInvalidVariantOption, option.Name)
+			}
+			if values[strings.ToLower(value.Value)] {
+				return fmt.Errorf("%w: option %s lists %s twice", ErrInvalidVariantOption, option.Name, value.Value)
+			}
+			values[strings.ToLower(value.Value)] = true
+			if value.Swatch != "" && !validSwatch(value.Swatch) {
+				return fmt.Errorf("%w: swatch of %s must be a hex colour or an image URL", ErrInvalidVariantOption, value.Value)
+			}
+		}
+		combinations *= len(option.Values)
+	}
+
+	if combinations > maxMatrixVariants {
+		return fmt.Errorf("%w: options make %d variants, at most %d are allowed", ErrInvalidVariantOption, combinations, maxMatrixVariants)
+	}
+	return nil
+}
+
+func validSwatch(swatch string) bool {
+	if swatchColorPattern.MatchString(swatch) {
+		return true
+	}
+	parsed, err := url.Parse(swatch)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+// normalizeVariantOptions checks variant options against the product's option
+// definitions and returns them with the defined spelling. A variant needs a
+// value for every option and nothing else. Products without definitions keep
+// free-form options.
+func normalizeVariantOptions(definitions []ProductOption, options map[string]string) (map[string]string, error) {
+	normalized, err := matchVariantOptions(definitions, options)
+	if err != nil {
+		return nil, err
+	}
+	for _, option := range definitions {
+		if _, ok := normalized[option.Name]; !ok {
+			return nil, fmt.Errorf("%w: %s is required", ErrInvalidVariantOption, option.Name)
+		}
+	}
+	return normalized, nil
+}
+
+// matchVariantOptions normalises a possibly partial selection such as
+// {"color": "red"}, e.g. for matching the variants whose options include it
+func matchVariantOptions(definitions []ProductOption, options map[string]string) (map[string]string, error) {
+	normalized := make(map[string]string, len(options))
+	for key, value := range options {
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if len(definitions) == 0 {
+			if key != "" && value != "" {
+				normalized[key] = value
+			}
+			continue
+		}
+
+		option := findOption(definitions, key)
+		if option == nil {
+			return nil, fmt.Errorf("%w: %s is not an option of this product", ErrInvalidVariantOption, key)
+		}
+		defined := findOptionValue(option, value)
+		if defined == "" {
+			return nil, fmt.Errorf("%w: %s is not a value of %s", ErrInvalidVariantOption, value, option.Name)
+		}
+		normalized[option.Name] = defined
+	}
+	return normalized, nil
+}
+
+func findOption(definitions []ProductOption, name string) *ProductOption {
+	name = strings.TrimSpace(name)
+	for i := range definitions {
+		if strings.EqualFold(definitions[i].Name, name) {
+			return &definitions[i]
+		}
+	}
+	return nil
+}
+
+func findOptionValue(option *ProductOption, value string) string {
+	value = strings.TrimSpace(value)
+	for _, defined := range option.Values {
+		if strings.EqualFold(defined.Value, value) {
+			return defined.Value
+		}
+	}
+	return ""
+}
+
+// variantMatrix returns every combination of option values, ordered by the
+// first option, then the second and so on
+func variantMatrix(definitions []ProductOption) []map[string]string {
+	if len(definitions) == 0 {
+		return nil
+	}
+
+	combinations := []map[string]string{{}}
+	for _, option := range definitions {
+		next := make([]map[string]string, 0, len(combinations)*len(option.Values))
+		for _, combination := range combinations {
+			for _, value := range option.Values {
+				extended := make(map[string]string, len(combination)+1)
+				for key, v := range combination {
+					extended[key] = v
+				}
+				extended[option.Name] = value.Value
+				next = append(next, extended)
+			}
+		}
+		combinations = next
+	}
+	return combinations
+}
+
+// variantOptionsKey identifies a combination of option values regardless of
+// map order
+func variantOptionsKey(options map[string]string) string {
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = key + "=" + options[key]
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// variantOptionsName names a variant after its option values in option order,
+// e.g. "Size: M, Color: Red"
+func variantOptionsName(definitions []ProductOption, options map[string]string) string {
+	parts := make([]string, 0, len(definitions))
+	for _, option := range definitions {
+		if value, ok := options[option.Name]; ok {
+			parts = append(parts, option.Name+": "+value)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// variantSKU fills a SKU pattern for a combination of option values. Option
+// values are upper-cased with runs of other characters replaced by "-".
+func variantSKU(pattern, productSKU string, definitions []ProductOption, options map[string]string) string {
+	if pattern == "" {
+		tokens := []string{"{sku}"}
+		for _, option := range definitions {
+			tokens = append(tokens, "{"+option.Name+"}")
+		}
+		pattern = strings.Join(tokens, "-")
+	}
+
+	sku := skuTokenPattern.ReplaceAllStringFunc(pattern, func(token string) string {
+		name := strings.TrimSpace(token[1 : len(token)-1])
+		if strings.EqualFold(name, "sku") {
+			return productSKU
+		}
+		if option := findOption(definitions, name); option != nil {
+			return strings.Trim(skuUnsafePattern.ReplaceAllString(strings.ToUpper(options[option.Name]), "-"), "-")
+		}
+		return ""
+	})
+	return strings.Trim(sku, "-")
+}
+
+// variantHasOptions tells whether a variant's options include every selected
+// option value
+func variantHasOptions(variant *ProductVariant, selected map[string]string) bool {
+	for key, value := range selected {
+		if variant.Options[key] != value {
+			return false
+		}
+	}
+	return true
+}
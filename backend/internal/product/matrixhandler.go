@@ -0,0 +1,150 @@
+package product
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// UpdateProductOptions handles PUT /api/products/:id/options
+func (h *Handler) UpdateProductOptions(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req struct {
+		Options []ProductOption `json:"options"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	product, err := h.service.UpdateProductOptions(tenantID.(uuid.UUID), productID, req.Options)
+	if err != nil {
+		c.JSON(variantErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Product options updated successfully",
+		"data":    product,
+	})
+}
+
+// GenerateVariants handles POST /api/products/:id/variants/generate
+func (h *Handler) GenerateVariants(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req GenerateVariantsRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	result, err := h.service.GenerateVariants(tenantID.(uuid.UUID), productID, &req)
+	if err != nil {
+		c.JSON(variantErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Product variants generated successfully",
+		"data":    result,
+	})
+}
+
+// BulkUpdateVariants handles PATCH /api/products/:id/variants
+func (h *Handler) BulkUpdateVariants(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req BulkUpdateVariantsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	variants, err := h.service.BulkUpdateVariants(tenantID.(uuid.UUID), productID, &req)
+	if err != nil {
+		c.JSON(variantErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Product variants updated successfully",
+		"data":    variants,
+	})
+}
+
+// GetVariantByOptions handles GET /api/products/:id/variants/lookup?option.size=M&option.color=Red
+func (h *Handler) GetVariantByOptions(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	options := make(map[string]string)
+	for key, values := range c.Request.URL.Query() {
+		if name := strings.TrimPrefix(key, "option."); name != key && name != "" && len(values) > 0 {
+			options[name] = values[0]
+		}
+	}
+
+	variant, err := h.service.GetVariantByOptions(tenantID.(uuid.UUID), productID, options)
+	if err != nil {
+		c.JSON(variantErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": variant})
+}
+
+func variantErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrVariantNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrDuplicateVariant):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -0,0 +1,278 @@
+package product
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UpdateProductOptions replaces the option definitions of a product. Options
+// and values may be reordered or given swatches; every existing variant must
+// still have a defined value of every option.
+func (s *Service) UpdateProductOptions(tenantID, productID uuid.UUID, options []ProductOption) (*Product, error) {
+	product, err := s.repo.FindProductByID(tenantID, productID)
+	if err != nil {
+		return nil, errors.New("product not found")
+	}
+
+	if err := s.applyProductOptions(product, options); err != nil {
+		return nil, err
+	}
+	product.UpdatedAt = time.Now()
+
+	updated, err := s.repo.UpdateProduct(product)
+	if err != nil {
+		return nil, err
+	}
+	s.productsChanged(tenantID, productID)
+	return updated, nil
+}
+
+// GenerateVariants creates a variant for every combination of option values
+// the product has no variant for yet, and optionally removes the variants
+// outside the matrix that no order references
+func (s *Service) GenerateVariants(tenantID, productID uuid.UUID, req *GenerateVariantsRequest) (*GenerateVariantsResult, error) {
+	product, err := s.repo.FindProductByID(tenantID, productID)
+	if err != nil {
+		return nil, errors.New("product not found")
+	}
+	if len(product.Options) == 0 {
+		return nil, fmt.Errorf("%w: the product has no options to generate variants from", ErrInvalidVariantOption)
+	}
+	if req.Price != nil && *req.Price < 0 {
+		return nil, errors.New("price cannot be negative")
+	}
+	if req.InventoryQuantity < 0 {
+		return nil, errors.New("inventory quantity cannot be negative")
+	}
+
+	existing := make(map[string]bool, len(product.Variants))
+	for _, variant := range product.Variants {
+		existing[variantOptionsKey(variant.Options)] = true
+	}
+
+	price := product.Price
+	if req.Price != nil {
+		price = *req.Price
+	}
+
+	result := &GenerateVariantsResult{Created: []*ProductVariant{}}
+	matrix := make(map[string]bool)
+	now := time.Now()
+	for _, options := range variantMatrix(product.Options) {
+		key := variantOptionsKey(options)
+		matrix[key] = true
+		if existing[key] {
+			result.Existing++
+			continue
+		}
+
+		result.Created = append(result.Created, &ProductVariant{
+			ID:                uuid.New(),
+			ProductID:         productID,
+			Name:              variantOptionsName(product.Options, options),
+			SKU:               variantSKU(req.SKUPattern, product.SKU, product.Options, options),
+			Price:             price,
+			InventoryQuantity: req.InventoryQuantity,
+			TrackQuantity:     product.TrackQuantity,
+			AllowBackorder:    product.AllowBackorder,
+			Options:           options,
+			CreatedAt:         now,
+			UpdatedAt:         now,
+		})
+	}
+
+	var unlisted []uuid.UUID
+	if req.RemoveUnlisted {
+		for _, variant := range product.Variants {
+			if !matrix[variantOptionsKey(variant.Options)] {
+				unlisted = append(unlisted, variant.ID)
+			}
+		}
+	}
+
+	kept, err := s.repo.SaveVariantMatrix(tenantID, productID, result.Created, unlisted)
+	if err != nil {
+		return nil, err
+	}
+	result.Removed = len(unlisted) - len(kept)
+	result.Kept = kept
+
+	if len(result.Created) > 0 || result.Removed > 0 {
+		s.productsChanged(tenantID, productID)
+	}
+	return result, nil
+}
+
+// BulkUpdateVariants applies price and stock changes across the variant
+// matrix of a product. All changes are saved together or not at all.
+func (s *Service) BulkUpdateVariants(tenantID, productID uuid.UUID, req *BulkUpdateVariantsRequest) ([]*ProductVariant, error) {
+	product, err := s.repo.FindProductByID(tenantID, productID)
+	if err != nil {
+		return nil, errors.New("product not found")
+	}
+
+	variants := make([]*ProductVariant, len(product.Variants))
+	byID := make(map[uuid.UUID]*ProductVariant, len(product.Variants))
+	for i := range product.Variants {
+		variants[i] = &product.Variants[i]
+		byID[variants[i].ID] = variants[i]
+	}
+
+	changed := make(map[uuid.UUID]bool)
+	for _, update := range req.Updates {
+		selected, err := selectVariants(product, variants, byID, update)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, variant := range selected {
+			if update.Price != nil {
+				variant.Price = *update.Price
+			}
+			if update.ComparePrice != nil {
+				variant.ComparePrice = *update.ComparePrice
+			}
+			if update.CostPrice != nil {
+				variant.CostPrice = *update.CostPrice
+			}
+			if update.InventoryQuantity != nil {
+				variant.InventoryQuantity = *update.InventoryQuantity
+			}
+			if update.InventoryAdjustment != nil {
+				variant.InventoryQuantity += *update.InventoryAdjustment
+			}
+			changed[variant.ID] = true
+		}
+	}
+
+	var updated []*ProductVariant
+	now := time.Now()
+	for _, variant := range variants {
+		if !changed[variant.ID] {
+			continue
+		}
+		if variant.Price < 0 || variant.CostPrice < 0 {
+			return nil, fmt.Errorf("variant %s: prices cannot be negative", variant.GetDisplayName())
+		}
+		if variant.ComparePrice > 0 && variant.Price >= variant.ComparePrice {
+			return nil, fmt.Errorf("variant %s: compare price must be higher than selling price", variant.GetDisplayName())
+		}
+		if variant.InventoryQuantity < 0 {
+			return nil, fmt.Errorf("variant %s: inventory quantity cannot be negative", variant.GetDisplayName())
+		}
+		variant.UpdatedAt = now
+		updated = append(updated, variant)
+	}
+
+	if err := s.repo.UpdateProductVariants(updated); err != nil {
+		return nil, err
+	}
+	if len(updated) > 0 {
+		s.productsChanged(tenantID, productID)
+	}
+	return updated, nil
+}
+
+// GetVariantByOptions returns the variant of a product with a value for
+// every option
+func (s *Service) GetVariantByOptions(tenantID, productID uuid.UUID, options map[string]string) (*ProductVariant, error) {
+	product, err := s.repo.FindProductByID(tenantID, productID)
+	if err != nil {
+		return nil, errors.New("product not found")
+	}
+
+	normalized, err := normalizeVariantOptions(product.Options, options)
+	if err != nil {
+		return nil, err
+	}
+	if len(normalized) == 0 {
+		return nil, fmt.Errorf("%w: options are required", ErrInvalidVariantOption)
+	}
+	return s.repo.FindVariantByOptions(tenantID, productID, normalized)
+}
+
+// applyProductOptions sets the option definitions of a product, rewriting the
+// options of variants whose spelling differs from the new definitions
+func (s *Service) applyProductOptions(product *Product, options []ProductOption) error {
+	if err := validateProductOptions(options); err != nil {
+		return err
+	}
+
+	var rewritten []*ProductVariant
+	for i := range product.Variants {
+		variant := &product.Variants[i]
+		normalized, err := normalizeVariantOptions(options, variant.Options)
+		if err != nil {
+			return fmt.Errorf("variant %s: %w", variant.GetDisplayName(), err)
+		}
+		if variantOptionsKey(normalized) != variantOptionsKey(variant.Options) {
+			variant.Options = normalized
+			variant.UpdatedAt = time.Now()
+			rewritten = append(rewritten, variant)
+		}
+	}
+	if err := s.repo.UpdateProductVariants(rewritten); err != nil {
+		return err
+	}
+
+	product.Options = options
+	if len(options) == 0 {
+		product.Options = nil
+	}
+	return nil
+}
+
+// prepareVariantOptions normalises the options of a variant being saved and
+// checks that no other variant of the product has them
+func (s *Service) prepareVariantOptions(tenantID uuid.UUID, product *Product, variant *ProductVariant) error {
+	options, err := normalizeVariantOptions(product.Options, variant.Options)
+	if err != nil {
+		return err
+	}
+	variant.Options = options
+	if variant.Name == "" && len(product.Options) > 0 {
+		variant.Name = variantOptionsName(product.Options, options)
+	}
+	if len(options) == 0 {
+		return nil
+	}
+
+	existing, err := s.repo.FindVariantByOptions(tenantID, product.ID, options)
+	if err != nil && !errors.Is(err, ErrVariantNotFound) {
+		return err
+	}
+	if existing != nil && existing.ID != variant.ID {
+		return ErrDuplicateVariant
+	}
+	return nil
+}
+
+// selectVariants returns the variants a bulk update applies to
+func selectVariants(product *Product, variants []*ProductVariant, byID map[uuid.UUID]*ProductVariant, update VariantBulkUpdate) ([]*ProductVariant, error) {
+	if len(update.VariantIDs) > 0 {
+		selected := make([]*ProductVariant, 0, len(update.VariantIDs))
+		for _, id := range update.VariantIDs {
+			variant, ok := byID[id]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrVariantNotFound, id)
+			}
+			selected = append(selected, variant)
+		}
+		return selected, nil
+	}
+
+	options, err := matchVariantOptions(product.Options, update.Options)
+	if err != nil {
+		return nil, err
+	}
+	var selected []*ProductVariant
+	for _, variant := range variants {
+		if variantHasOptions(variant, options) {
+			selected = append(selected, variant)
+		}
+	}
+	return selected, nil
+}
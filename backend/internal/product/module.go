@@ -28,6 +28,11 @@ func NewModule(db *gorm.DB) *Module {
 	}
 }
 
+// SetChangeListener tells listener about product and category writes
+func (m *Module) SetChangeListener(listener ChangeListener) {
+	m.Service.SetChangeListener(listener)
+}
+
 // RegisterRoutes registers all product routes with the router
 func (m *Module) RegisterRoutes(router *gin.RouterGroup) {
 	m.Handler.RegisterRoutes(router)
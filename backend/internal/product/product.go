@@ -3,6 +3,7 @@ package product
 import (
 	"errors"
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
@@ -32,6 +33,7 @@ const (
 	TypePhysical ProductType = "physical"
 	TypeDigital  ProductType = "digital"
 	TypeService  ProductType = "service"
+	TypeGiftCard ProductType = "gift_card"
 )
 
 // Product represents a product in the system
@@ -74,6 +76,25 @@ type Product struct {
 	// Categories and tags
 	CategoryID uuid.UUID `json:"category_id,omitempty" gorm:"index"`
 	Tags       []string  `json:"tags,omitempty" gorm:"serializer:json"`
+	Vendor     string    `json:"vendor,omitempty"`
+	
+	// Options the variants vary by, with their values and swatches. Variants
+	// must carry one defined value of every option.
+	Options []ProductOption `json:"options,omitempty" gorm:"serializer:json"`
+	
+	// Structured attributes by attribute code, checked against the attribute
+	// definitions and the category's attribute set on save
+	Attributes map[string]interface{} `json:"attributes,omitempty" gorm:"serializer:json"`
+	
+	// Gift card settings, used when Type is gift_card
+	GiftCardDenominations []float64 `json:"gift_card_denominations,omitempty" gorm:"serializer:json"` // Amounts the customer can choose from
+	GiftCardValidityDays  int       `json:"gift_card_validity_days,omitempty"`                        // 0 means issued cards never expire
+	
+	// Summary of approved reviews, kept up to date by the reviews module and
+	// never written by product saves
+	RatingAverage      *float64    `json:"rating_average,omitempty" gorm:"->"`
+	ReviewCount        int         `json:"review_count" gorm:"->"`
+	RatingDistribution map[int]int `json:"rating_distribution,omitempty" gorm:"->;serializer:json"` // Approved reviews per star rating
 	
 	// Timestamps
 	CreatedAt time.Time `json:"created_at"`
@@ -106,7 +127,7 @@ type ProductVariant struct {
 	Width  float64 `json:"width,omitempty"`  // in cm
 	Height float64 `json:"height,omitempty"` // in cm
 	
-	// Variant options (e.g., size: "Large", color: "Red")
+	// Variant options (e.g., size: "Large", color: "Red"), unique per product
 	Options map[string]string `json:"options" gorm:"serializer:json"`
 	
 	// Images specific to this variant
@@ -143,6 +164,9 @@ type Category struct {
 	SortOrder   int       `json:"sort_order" gorm:"default:0"`
 	IsActive    bool      `json:"is_active" gorm:"default:true"`
 	
+	// Attributes of the category's products; nil inherits the parent's set
+	AttributeSetID *uuid.UUID `json:"attribute_set_id,omitempty" gorm:"index"`
+	
 	// SEO
 	MetaTitle       string `json:"meta_title,omitempty"`
 	MetaDescription string `json:"meta_description,omitempty"`
@@ -338,6 +362,20 @@ func (p *Product) ValidateProductData() error {
 		return errors.New("inventory quantity cannot be negative")
 	}
 	
+	if p.Type == TypeGiftCard {
+		if len(p.GiftCardDenominations) == 0 {
+			return errors.New("gift card products need at least one denomination")
+		}
+		for _, amount := range p.GiftCardDenominations {
+			if amount <= 0 {
+				return errors.New("gift card denominations must be positive")
+			}
+		}
+		if p.GiftCardValidityDays < 0 {
+			return errors.New("gift card validity cannot be negative")
+		}
+	}
+	
 	return nil
 }
 
@@ -347,7 +385,7 @@ func (p *Product) CalculateShippingWeight() float64 {
 		return p.Weight
 	}
 	// Default weight for digital products
-	if p.Type == TypeDigital {
+	if p.Type == TypeDigital || p.Type == TypeGiftCard {
 		return 0
 	}
 	// Default weight if not specified (in grams)
@@ -398,6 +436,21 @@ func (p *Product) IsPhysical() bool {
 	return p.Type == TypePhysical
 }
 
+// IsGiftCard checks if product sells gift cards
+func (p *Product) IsGiftCard() bool {
+	return p.Type == TypeGiftCard
+}
+
+// AcceptsGiftCardAmount checks the amount is one of the gift card denominations
+func (p *Product) AcceptsGiftCardAmount(amount float64) bool {
+	for _, denomination := range p.GiftCardDenominations {
+		if math.Abs(denomination-amount) < 0.005 {
+			return true
+		}
+	}
+	return false
+}
+
 // IsService checks if product is a service
 func (p *Product) IsService() bool {
 	return p.Type == TypeService
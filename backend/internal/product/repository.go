@@ -1,8 +1,15 @@
 package product
 
 import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Repository defines the product repository interface
@@ -17,10 +24,15 @@ type Repository interface {
 	SlugExists(tenantID uuid.UUID, slug string) (bool, error)
 	ProductExists(tenantID, productID uuid.UUID) (bool, error)
 	UpdateInventory(tenantID, productID uuid.UUID, quantity int) error
+	IncrementInventory(tenantID, productID uuid.UUID, variantID *uuid.UUID, quantity int) error
 	GetProductsByCategoryID(tenantID, categoryID uuid.UUID, offset, limit int) ([]*Product, int64, error)
 	GetLowStockProducts(tenantID uuid.UUID, threshold int) ([]*Product, error)
 	BulkUpdateProducts(tenantID uuid.UUID, productIDs []uuid.UUID, updates map[string]interface{}) error
 	BulkDeleteProducts(tenantID uuid.UUID, productIDs []uuid.UUID) error
+	FindProductBySKU(tenantID uuid.UUID, sku string) (*Product, error)
+	ListProductsForExport(tenantID uuid.UUID, filter ProductListFilter, afterID uuid.UUID, limit int) ([]*Product, error)
+	ImportProduct(product *Product, create bool, createdVariants, updatedVariants []*ProductVariant) error
+	UpdateReviewSummary(tenantID, productID uuid.UUID, average *float64, count int, distribution map[int]int) error
 
 	// Category operations
 	SaveCategory(category *Category) (*Category, error)
@@ -42,6 +54,49 @@ type Repository interface {
 	GetProductVariant(tenantID, variantID uuid.UUID) (*ProductVariant, error)
 	UpdateProductVariant(variant *ProductVariant) (*ProductVariant, error)
 	DeleteProductVariant(tenantID, variantID uuid.UUID) error
+	FindVariantByOptions(tenantID, productID uuid.UUID, options map[string]string) (*ProductVariant, error)
+	SaveVariantMatrix(tenantID, productID uuid.UUID, created []*ProductVariant, unlisted []uuid.UUID) ([]uuid.UUID, error)
+	UpdateProductVariants(variants []*ProductVariant) error
+
+	// Attribute operations
+	ListAttributeDefinitions(tenantID uuid.UUID) ([]*AttributeDefinition, error)
+	FindAttributeDefinition(tenantID, attributeID uuid.UUID) (*AttributeDefinition, error)
+	AttributeCodeExists(tenantID uuid.UUID, code string) (bool, error)
+	SaveAttributeDefinition(definition *AttributeDefinition) (*AttributeDefinition, error)
+	UpdateAttributeDefinition(definition *AttributeDefinition) (*AttributeDefinition, error)
+	DeleteAttributeDefinition(tenantID uuid.UUID, definition *AttributeDefinition) ([]uuid.UUID, error)
+	ProductIDsWithAttribute(tenantID uuid.UUID, code string) ([]uuid.UUID, error)
+	ListAttributeSets(tenantID uuid.UUID) ([]*AttributeSet, error)
+	FindAttributeSet(tenantID, setID uuid.UUID) (*AttributeSet, error)
+	SaveAttributeSet(set *AttributeSet) (*AttributeSet, error)
+	UpdateAttributeSet(set *AttributeSet) (*AttributeSet, error)
+	DeleteAttributeSet(tenantID, setID uuid.UUID) error
+	FindCategoryAttributeSet(tenantID, categoryID uuid.UUID) (*AttributeSet, error)
+
+	// Import job operations
+	SaveImportJob(job *ImportJob) (*ImportJob, error)
+	UpdateImportJob(job *ImportJob) error
+	FindImportJob(tenantID, jobID uuid.UUID) (*ImportJob, error)
+	ListImportJobs(tenantID uuid.UUID, offset, limit int) ([]*ImportJob, int64, error)
+	ListPendingImportJobs(tenantID uuid.UUID) ([]*ImportJob, error)
+	ClaimImportJob(job *ImportJob) (bool, error)
+	FailStaleImportJobs(tenantID uuid.UUID, before time.Time, message string) (int64, error)
+
+	// Collection operations
+	SaveCollection(collection *Collection) (*Collection, error)
+	UpdateCollection(collection *Collection) (*Collection, error)
+	FindCollection(tenantID, collectionID uuid.UUID) (*Collection, error)
+	FindCollectionBySlug(tenantID uuid.UUID, slug string) (*Collection, error)
+	CollectionSlugExists(tenantID uuid.UUID, slug string, excludeID uuid.UUID) (bool, error)
+	ListCollections(tenantID uuid.UUID, filter CollectionListFilter, offset, limit int) ([]*Collection, int64, error)
+	ListSmartCollections(tenantID uuid.UUID) ([]*Collection, error)
+	DeleteCollection(tenantID, collectionID uuid.UUID) ([]uuid.UUID, error)
+	ListCollectionProducts(tenantID uuid.UUID, collection *Collection, filter ProductListFilter, offset, limit int) ([]*Product, int64, error)
+	CollectionProductIDs(collectionID uuid.UUID) ([]uuid.UUID, error)
+	AddCollectionProducts(tenantID, collectionID uuid.UUID, productIDs []uuid.UUID) ([]uuid.UUID, error)
+	RemoveCollectionProducts(collectionID uuid.UUID, productIDs []uuid.UUID) ([]uuid.UUID, error)
+	ReorderCollectionProducts(collectionID uuid.UUID, productIDs []uuid.UUID) error
+	SyncCollection(collection *Collection, productIDs []uuid.UUID) ([]uuid.UUID, error)
 
 	// Statistics and aggregations
 	GetProductStats(tenantID uuid.UUID) (*ProductStats, error)
@@ -110,9 +165,42 @@ func (r *repository) ListProducts(tenantID uuid.UUID, filter ProductListFilter,
 	var products []*Product
 	var total int64
 
-	query := r.db.Model(&Product{}).Where("tenant_id = ?", tenantID)
+	query := applyProductFilter(r.db.Model(&Product{}).Where("tenant_id = ?", tenantID), filter)
+
+	// Get total count
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated results with preloads
+	if err := query.Preload("Category").
+		Offset(offset).Limit(limit).
+		Order(productOrder(filter.Sort)).
+		Find(&products).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return products, total, nil
+}
+
+// productOrder returns the ORDER BY clause of a product list sort
+func productOrder(sort string) string {
+	switch sort {
+	case "rating":
+		return "rating_average DESC NULLS LAST, review_count DESC, created_at DESC"
+	case "price_asc":
+		return "price ASC, created_at DESC"
+	case "price_desc":
+		return "price DESC, created_at DESC"
+	case "name":
+		return "name ASC, created_at DESC"
+	default:
+		return "created_at DESC"
+	}
+}
 
-	// Apply filters
+// applyProductFilter narrows a products query to a list filter
+func applyProductFilter(query *gorm.DB, filter ProductListFilter) *gorm.DB {
 	if filter.Status != "" {
 		query = query.Where("status = ?", filter.Status)
 	}
@@ -135,25 +223,86 @@ func (r *repository) ListProducts(tenantID uuid.UUID, filter ProductListFilter,
 			query = query.Where("track_quantity = true AND inventory_quantity <= 0")
 		}
 	}
+	if filter.MinRating != nil {
+		query = query.Where("rating_average >= ?", *filter.MinRating)
+	}
 	if filter.Search != "" {
 		query = query.Where("name ILIKE ? OR description ILIKE ? OR sku ILIKE ?",
 			"%"+filter.Search+"%", "%"+filter.Search+"%", "%"+filter.Search+"%")
 	}
+	return query
+}
 
-	// Get total count
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
+// UpdateReviewSummary stores the summary of a product's approved reviews.
+// The columns are read-only on Product, so this writes them by table.
+func (r *repository) UpdateReviewSummary(tenantID, productID uuid.UUID, average *float64, count int, distribution map[int]int) error {
+	var encoded *string
+	if distribution != nil {
+		data, err := json.Marshal(distribution)
+		if err != nil {
+			return err
+		}
+		value := string(data)
+		encoded = &value
 	}
 
-	// Get paginated results with preloads
-	if err := query.Preload("Category").
-		Offset(offset).Limit(limit).
-		Order("created_at DESC").
-		Find(&products).Error; err != nil {
-		return nil, 0, err
+	return r.db.Table("products").
+		Where("id = ? AND tenant_id = ?", productID, tenantID).
+		Updates(map[string]interface{}{
+			"rating_average":      average,
+			"review_count":        count,
+			"rating_distribution": gorm.Expr("?::jsonb", encoded),
+		}).Error
+}
+
+// FindProductBySKU retrieves a product by its own SKU
+func (r *repository) FindProductBySKU(tenantID uuid.UUID, sku string) (*Product, error) {
+	var product Product
+	err := r.db.Preload("Variants").Preload("Category").
+		Order("created_at ASC").
+		First(&product, "sku = ? AND tenant_id = ?", sku, tenantID).Error
+	if err != nil {
+		return nil, err
 	}
+	return &product, nil
+}
 
-	return products, total, nil
+// ListProductsForExport returns the next batch of products after afterID in
+// ID order, with their variants
+func (r *repository) ListProductsForExport(tenantID uuid.UUID, filter ProductListFilter, afterID uuid.UUID, limit int) ([]*Product, error) {
+	var products []*Product
+	query := applyProductFilter(r.db.Model(&Product{}).Where("tenant_id = ? AND id > ?", tenantID, afterID), filter)
+	err := query.Preload("Variants", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC, id ASC")
+	}).Order("id ASC").Limit(limit).Find(&products).Error
+	return products, err
+}
+
+// ImportProduct writes an imported product and its new and changed variants
+// in one transaction
+func (r *repository) ImportProduct(product *Product, create bool, createdVariants, updatedVariants []*ProductVariant) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		write := tx.Omit(clause.Associations)
+		if create {
+			if err := write.Create(product).Error; err != nil {
+				return err
+			}
+		} else if err := write.Save(product).Error; err != nil {
+			return err
+		}
+
+		if len(createdVariants) > 0 {
+			if err := tx.Create(&createdVariants).Error; err != nil {
+				return variantError(err)
+			}
+		}
+		for _, variant := range updatedVariants {
+			if err := tx.Save(variant).Error; err != nil {
+				return variantError(err)
+			}
+		}
+		return nil
+	})
 }
 
 // SlugExists checks if a product slug exists for a tenant
@@ -177,6 +326,22 @@ func (r *repository) UpdateInventory(tenantID, productID uuid.UUID, quantity int
 		Update("inventory_quantity", quantity).Error
 }
 
+// IncrementInventory adds quantity to the stock of a product, or of its
+// variant when given, in one statement so concurrent updates are not lost.
+// Items whose quantity isn't tracked are left alone.
+func (r *repository) IncrementInventory(tenantID, productID uuid.UUID, variantID *uuid.UUID, quantity int) error {
+	if variantID != nil {
+		return r.db.Model(&ProductVariant{}).
+			Where("id = ? AND product_id = ? AND track_quantity = ?", *variantID, productID, true).
+			Where("product_id IN (?)", r.db.Model(&Product{}).Select("id").Where("tenant_id = ?", tenantID)).
+			Update("inventory_quantity", gorm.Expr("inventory_quantity + ?", quantity)).Error
+	}
+
+	return r.db.Model(&Product{}).
+		Where("id = ? AND tenant_id = ? AND track_quantity = ?", productID, tenantID, true).
+		Update("inventory_quantity", gorm.Expr("inventory_quantity + ?", quantity)).Error
+}
+
 // GetProductsByCategoryID returns products in a specific category
 func (r *repository) GetProductsByCategoryID(tenantID, categoryID uuid.UUID, offset, limit int) ([]*Product, int64, error) {
 	var products []*Product
@@ -303,7 +468,7 @@ func (r *repository) GetCategoryChildren(tenantID, parentID uuid.UUID) ([]*Categ
 // SaveProductVariant creates a new product variant
 func (r *repository) SaveProductVariant(variant *ProductVariant) (*ProductVariant, error) {
 	if err := r.db.Create(variant).Error; err != nil {
-		return nil, err
+		return nil, variantError(err)
 	}
 	return variant, nil
 }
@@ -335,14 +500,89 @@ func (r *repository) GetProductVariant(tenantID, variantID uuid.UUID) (*ProductV
 // UpdateProductVariant updates a product variant
 func (r *repository) UpdateProductVariant(variant *ProductVariant) (*ProductVariant, error) {
 	if err := r.db.Save(variant).Error; err != nil {
-		return nil, err
+		return nil, variantError(err)
 	}
 	return variant, nil
 }
 
 // DeleteProductVariant deletes a product variant
 func (r *repository) DeleteProductVariant(tenantID, variantID uuid.UUID) error {
-	return r.db.Delete(&ProductVariant{}, "id = ? AND tenant_id = ?", variantID, tenantID).Error
+	return r.db.Where("id = ? AND product_id IN (SELECT id FROM products WHERE tenant_id = ?)", variantID, tenantID).
+		Delete(&ProductVariant{}).Error
+}
+
+// FindVariantByOptions returns the variant of a product with exactly these
+// options, using the unique (product_id, options) index
+func (r *repository) FindVariantByOptions(tenantID, productID uuid.UUID, options map[string]string) (*ProductVariant, error) {
+	encoded, err := json.Marshal(options)
+	if err != nil {
+		return nil, err
+	}
+
+	var variant ProductVariant
+	err = r.db.Joins("JOIN products ON product_variants.product_id = products.id").
+		Where("products.tenant_id = ? AND product_variants.product_id = ? AND product_variants.options = ?::jsonb", tenantID, productID, string(encoded)).
+		Where("jsonb_typeof(product_variants.options) = 'object' AND product_variants.options <> '{}'::jsonb").
+		First(&variant).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrVariantNotFound
+		}
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// SaveVariantMatrix creates the generated variants of a product and deletes
+// its unlisted variants together. Unlisted variants that order items still
+// reference are kept, and their IDs returned, so order history keeps its
+// variants. Nothing is written if a new variant duplicates the options of
+// another variant of the product.
+func (r *repository) SaveVariantMatrix(tenantID, productID uuid.UUID, created []*ProductVariant, unlisted []uuid.UUID) ([]uuid.UUID, error) {
+	var kept []uuid.UUID
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if len(created) > 0 {
+			if err := tx.Create(&created).Error; err != nil {
+				return variantError(err)
+			}
+		}
+		if len(unlisted) == 0 {
+			return nil
+		}
+
+		if err := tx.Where("id IN ? AND product_id = ? AND product_id IN (SELECT id FROM products WHERE tenant_id = ?)", unlisted, productID, tenantID).
+			Where("NOT EXISTS (SELECT 1 FROM order_items oi WHERE oi.variant_id = product_variants.id)").
+			Delete(&ProductVariant{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&ProductVariant{}).Where("id IN ?", unlisted).Pluck("id", &kept).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return kept, nil
+}
+
+// UpdateProductVariants saves changes to variants together
+func (r *repository) UpdateProductVariants(variants []*ProductVariant) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, variant := range variants {
+			if err := tx.Save(variant).Error; err != nil {
+				return variantError(err)
+			}
+		}
+		return nil
+	})
+}
+
+// variantError reports a clash with the unique (product_id, options) index
+// as ErrDuplicateVariant
+func variantError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "idx_product_variants_product_options" {
+		return ErrDuplicateVariant
+	}
+	return err
 }
 
 // Statistics and aggregations
@@ -405,3 +645,652 @@ func (r *repository) GetProductStats(tenantID uuid.UUID) (*ProductStats, error)
 
 	return products, total, nil
 }
+
+// Attribute operations
+
+// ListAttributeDefinitions returns the attribute definitions of a tenant
+func (r *repository) ListAttributeDefinitions(tenantID uuid.UUID) ([]*AttributeDefinition, error) {
+	var definitions []*AttributeDefinition
+	err := r.db.Where("tenant_id = ?", tenantID).
+		Order("sort_order ASC, name ASC").
+		Find(&definitions).Error
+	return definitions, err
+}
+
+// FindAttributeDefinition retrieves an attribute definition by ID
+func (r *repository) FindAttributeDefinition(tenantID, attributeID uuid.UUID) (*AttributeDefinition, error) {
+	var definition AttributeDefinition
+	err := r.db.First(&definition, "id = ? AND tenant_id = ?", attributeID, tenantID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAttributeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &definition, nil
+}
+
+// AttributeCodeExists checks if an attribute code is taken for a tenant
+func (r *repository) AttributeCodeExists(tenantID uuid.UUID, code string) (bool, error) {
+	var count int64
+	err := r.db.Model(&AttributeDefinition{}).Where("tenant_id = ? AND code = ?", tenantID, code).Count(&count).Error
+	return count > 0, err
+}
+
+// SaveAttributeDefinition creates a new attribute definition
+func (r *repository) SaveAttributeDefinition(definition *AttributeDefinition) (*AttributeDefinition, error) {
+	if err := r.db.Create(definition).Error; err != nil {
+		return nil, err
+	}
+	return definition, nil
+}
+
+// UpdateAttributeDefinition updates an attribute definition
+func (r *repository) UpdateAttributeDefinition(definition *AttributeDefinition) (*AttributeDefinition, error) {
+	if err := r.db.Save(definition).Error; err != nil {
+		return nil, err
+	}
+	return definition, nil
+}
+
+// DeleteAttributeDefinition deletes an attribute definition that no set uses
+// and removes its values from products, returning the products changed
+func (r *repository) DeleteAttributeDefinition(tenantID uuid.UUID, definition *AttributeDefinition) ([]uuid.UUID, error) {
+	var productIDs []uuid.UUID
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var sets int64
+		err := tx.Model(&AttributeSet{}).
+			Where("tenant_id = ? AND attributes @> ?::jsonb", tenantID, `[{"attribute_id":"`+definition.ID.String()+`"}]`).
+			Count(&sets).Error
+		if err != nil {
+			return err
+		}
+		if sets > 0 {
+			return ErrAttributeInUse
+		}
+
+		err = tx.Model(&Product{}).
+			Where("tenant_id = ? AND jsonb_exists(attributes, ?)", tenantID, definition.Code).
+			Pluck("id", &productIDs).Error
+		if err != nil {
+			return err
+		}
+		if len(productIDs) > 0 {
+			err = tx.Model(&Product{}).
+				Where("tenant_id = ? AND id IN ?", tenantID, productIDs).
+				Update("attributes", gorm.Expr("attributes - ?", definition.Code)).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Where("tenant_id = ?", tenantID).Delete(&AttributeDefinition{}, definition.ID).Error
+	})
+	return productIDs, err
+}
+
+// ProductIDsWithAttribute returns the products that have a value for an
+// attribute
+func (r *repository) ProductIDsWithAttribute(tenantID uuid.UUID, code string) ([]uuid.UUID, error) {
+	var productIDs []uuid.UUID
+	err := r.db.Model(&Product{}).
+		Where("tenant_id = ? AND jsonb_exists(attributes, ?)", tenantID, code).
+		Pluck("id", &productIDs).Error
+	return productIDs, err
+}
+
+// ListAttributeSets returns the attribute sets of a tenant
+func (r *repository) ListAttributeSets(tenantID uuid.UUID) ([]*AttributeSet, error) {
+	var sets []*AttributeSet
+	err := r.db.Where("tenant_id = ?", tenantID).Order("name ASC").Find(&sets).Error
+	return sets, err
+}
+
+// FindAttributeSet retrieves an attribute set by ID
+func (r *repository) FindAttributeSet(tenantID, setID uuid.UUID) (*AttributeSet, error) {
+	var set AttributeSet
+	err := r.db.First(&set, "id = ? AND tenant_id = ?", setID, tenantID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAttributeSetNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+// SaveAttributeSet creates a new attribute set
+func (r *repository) SaveAttributeSet(set *AttributeSet) (*AttributeSet, error) {
+	if err := r.db.Create(set).Error; err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// UpdateAttributeSet updates an attribute set
+func (r *repository) UpdateAttributeSet(set *AttributeSet) (*AttributeSet, error) {
+	if err := r.db.Save(set).Error; err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// DeleteAttributeSet deletes an attribute set; its categories fall back to
+// their parents' sets
+func (r *repository) DeleteAttributeSet(tenantID, setID uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&Category{}).
+			Where("tenant_id = ? AND attribute_set_id = ?", tenantID, setID).
+			Update("attribute_set_id", nil).Error
+		if err != nil {
+			return err
+		}
+
+		result := tx.Where("tenant_id = ?", tenantID).Delete(&AttributeSet{}, setID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrAttributeSetNotFound
+		}
+		return nil
+	})
+}
+
+// FindCategoryAttributeSet returns the attribute set of a category or of its
+// nearest ancestor with one; nil when there is none
+func (r *repository) FindCategoryAttributeSet(tenantID, categoryID uuid.UUID) (*AttributeSet, error) {
+	var setIDs []uuid.UUID
+	err := r.db.Raw(`
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id, attribute_set_id, 0 AS depth
+			FROM categories WHERE id = ? AND tenant_id = ?
+			UNION ALL
+			SELECT c.id, c.parent_id, c.attribute_set_id, a.depth + 1
+			FROM categories c JOIN ancestors a ON c.id = a.parent_id
+			WHERE a.attribute_set_id IS NULL AND a.depth < 32
+		)
+		SELECT attribute_set_id FROM ancestors
+		WHERE attribute_set_id IS NOT NULL
+		ORDER BY depth ASC
+		LIMIT 1`, categoryID, tenantID).
+		Scan(&setIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(setIDs) == 0 {
+		return nil, nil
+	}
+	return r.FindAttributeSet(tenantID, setIDs[0])
+}
+
+// Import job operations
+
+// SaveImportJob creates an import job
+func (r *repository) SaveImportJob(job *ImportJob) (*ImportJob, error) {
+	if err := r.db.Create(job).Error; err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// UpdateImportJob saves the progress of an import job
+func (r *repository) UpdateImportJob(job *ImportJob) error {
+	return r.db.Save(job).Error
+}
+
+// FindImportJob retrieves an import job with its row errors
+func (r *repository) FindImportJob(tenantID, jobID uuid.UUID) (*ImportJob, error) {
+	var job ImportJob
+	err := r.db.Omit("file_data").First(&job, "id = ? AND tenant_id = ?", jobID, tenantID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrImportJobNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListImportJobs returns import jobs newest first, without their row errors
+func (r *repository) ListImportJobs(tenantID uuid.UUID, offset, limit int) ([]*ImportJob, int64, error) {
+	var jobs []*ImportJob
+	var total int64
+
+	query := r.db.Model(&ImportJob{}).Where("tenant_id = ?", tenantID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Omit("errors", "file_data").Order("created_at DESC").
+		Offset(offset).Limit(limit).Find(&jobs).Error; err != nil {
+		return nil, 0, err
+	}
+	return jobs, total, nil
+}
+
+// ListPendingImportJobs returns the import jobs waiting for the worker,
+// oldest first, with their files
+func (r *repository) ListPendingImportJobs(tenantID uuid.UUID) ([]*ImportJob, error) {
+	var jobs []*ImportJob
+	err := r.db.Where("tenant_id = ? AND status = ?", tenantID, ImportPending).
+		Order("created_at").Find(&jobs).Error
+	return jobs, err
+}
+
+// ClaimImportJob marks a pending import job running and drops its file,
+// which the caller already holds. It reports false when another worker
+// claimed the job first.
+func (r *repository) ClaimImportJob(job *ImportJob) (bool, error) {
+	startedAt := time.Now()
+	result := r.db.Model(&ImportJob{}).
+		Where("id = ? AND status = ?", job.ID, ImportPending).
+		Updates(map[string]interface{}{
+			"status":     ImportRunning,
+			"started_at": startedAt,
+			"file_data":  nil,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	job.Status = ImportRunning
+	job.StartedAt = &startedAt
+	return true, nil
+}
+
+// FailStaleImportJobs fails the running import jobs that last saved progress
+// before the given time
+func (r *repository) FailStaleImportJobs(tenantID uuid.UUID, before time.Time, message string) (int64, error) {
+	result := r.db.Model(&ImportJob{}).
+		Where("tenant_id = ? AND status = ? AND updated_at < ?", tenantID, ImportRunning, before).
+		Updates(map[string]interface{}{
+			"status":       ImportFailed,
+			"error":        message,
+			"completed_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// Collection operations
+
+// collectionCounts selects collections with the number of their products
+func (r *repository) collectionCounts() *gorm.DB {
+	return r.db.Model(&Collection{}).
+		Select("collections.*, (SELECT COUNT(*) FROM collection_products cp WHERE cp.collection_id = collections.id) AS product_count")
+}
+
+// SaveCollection creates a new collection
+func (r *repository) SaveCollection(collection *Collection) (*Collection, error) {
+	if err := r.db.Create(collection).Error; err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
+
+// UpdateCollection saves changes to a collection
+func (r *repository) UpdateCollection(collection *Collection) (*Collection, error) {
+	if err := r.db.Save(collection).Error; err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
+
+// FindCollection retrieves a collection by ID
+func (r *repository) FindCollection(tenantID, collectionID uuid.UUID) (*Collection, error) {
+	var collection Collection
+	err := r.collectionCounts().
+		First(&collection, "collections.id = ? AND collections.tenant_id = ?", collectionID, tenantID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCollectionNotFound
+		}
+		return nil, err
+	}
+	return &collection, nil
+}
+
+// FindCollectionBySlug retrieves a collection by slug
+func (r *repository) FindCollectionBySlug(tenantID uuid.UUID, slug string) (*Collection, error) {
+	var collection Collection
+	err := r.collectionCounts().
+		First(&collection, "collections.slug = ? AND collections.tenant_id = ?", slug, tenantID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCollectionNotFound
+		}
+		return nil, err
+	}
+	return &collection, nil
+}
+
+// CollectionSlugExists checks if a slug is taken by another collection
+func (r *repository) CollectionSlugExists(tenantID uuid.UUID, slug string, excludeID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.Model(&Collection{}).
+		Where("tenant_id = ? AND slug = ? AND id <> ?", tenantID, slug, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ListCollections returns a page of collections by title
+func (r *repository) ListCollections(tenantID uuid.UUID, filter CollectionListFilter, offset, limit int) ([]*Collection, int64, error) {
+	var collections []*Collection
+	var total int64
+
+	if err := applyCollectionFilter(r.db.Model(&Collection{}), tenantID, filter).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := applyCollectionFilter(r.collectionCounts(), tenantID, filter).
+		Order("collections.title ASC").
+		Offset(offset).Limit(limit).
+		Find(&collections).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return collections, total, nil
+}
+
+func applyCollectionFilter(query *gorm.DB, tenantID uuid.UUID, filter CollectionListFilter) *gorm.DB {
+	query = query.Where("collections.tenant_id = ?", tenantID)
+	if filter.Type != "" {
+		query = query.Where("collections.type = ?", filter.Type)
+	}
+	if filter.IsPublished != nil {
+		query = query.Where("collections.is_published = ?", *filter.IsPublished)
+	}
+	if filter.Search != "" {
+		query = query.Where("collections.title ILIKE ?", "%"+filter.Search+"%")
+	}
+	return query
+}
+
+// ListSmartCollections returns every smart collection of a tenant
+func (r *repository) ListSmartCollections(tenantID uuid.UUID) ([]*Collection, error) {
+	var collections []*Collection
+	err := r.db.Where("tenant_id = ? AND type = ?", tenantID, CollectionSmart).Find(&collections).Error
+	return collections, err
+}
+
+// DeleteCollection deletes a collection and returns the products it held
+func (r *repository) DeleteCollection(tenantID, collectionID uuid.UUID) ([]uuid.UUID, error) {
+	var productIDs []uuid.UUID
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("collection_products").Where("collection_id = ?", collectionID).
+			Pluck("product_id", &productIDs).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id = ? AND tenant_id = ?", collectionID, tenantID).Delete(&Collection{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrCollectionNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return productIDs, nil
+}
+
+// ListCollectionProducts returns a page of a collection's products in the
+// collection's sort order
+func (r *repository) ListCollectionProducts(tenantID uuid.UUID, collection *Collection, filter ProductListFilter, offset, limit int) ([]*Product, int64, error) {
+	var products []*Product
+	var total int64
+
+	query := applyProductFilter(r.db.Model(&Product{}).
+		Where("tenant_id = ? AND id IN (SELECT product_id FROM collection_products WHERE collection_id = ?)", tenantID, collection.ID), filter)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	sortBy := collection.SortBy
+	if filter.Sort != "" {
+		sortBy = filter.Sort
+	}
+	if sortBy == CollectionSortManual {
+		query = query.Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "(SELECT position FROM collection_products cp WHERE cp.collection_id = ? AND cp.product_id = products.id), created_at DESC",
+			Vars: []interface{}{collection.ID},
+		}})
+	} else {
+		query = query.Order(productOrder(sortBy))
+	}
+
+	if err := query.Preload("Category").Offset(offset).Limit(limit).Find(&products).Error; err != nil {
+		return nil, 0, err
+	}
+	return products, total, nil
+}
+
+// CollectionProductIDs returns the IDs of a collection's products
+func (r *repository) CollectionProductIDs(collectionID uuid.UUID) ([]uuid.UUID, error) {
+	var productIDs []uuid.UUID
+	err := r.db.Table("collection_products").Where("collection_id = ?", collectionID).
+		Pluck("product_id", &productIDs).Error
+	return productIDs, err
+}
+
+// AddCollectionProducts appends products to a manual collection in the given
+// order and returns the ones that weren't in it yet
+func (r *repository) AddCollectionProducts(tenantID, collectionID uuid.UUID, productIDs []uuid.UUID) ([]uuid.UUID, error) {
+	var added []uuid.UUID
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var known []uuid.UUID
+		if err := tx.Table("products").Where("tenant_id = ? AND id IN ?", tenantID, productIDs).
+			Pluck("id", &known).Error; err != nil {
+			return err
+		}
+		var existing []uuid.UUID
+		if err := tx.Table("collection_products").Where("collection_id = ? AND product_id IN ?", collectionID, productIDs).
+			Pluck("product_id", &existing).Error; err != nil {
+			return err
+		}
+		var position int
+		if err := tx.Table("collection_products").Where("collection_id = ?", collectionID).
+			Select("COALESCE(MAX(position), 0)").Scan(&position).Error; err != nil {
+			return err
+		}
+
+		skip := make(map[uuid.UUID]bool, len(existing))
+		for _, id := range existing {
+			skip[id] = true
+		}
+		valid := make(map[uuid.UUID]bool, len(known))
+		for _, id := range known {
+			valid[id] = true
+		}
+
+		var members []CollectionProduct
+		for _, id := range productIDs {
+			if !valid[id] {
+				return ErrProductNotFound
+			}
+			if skip[id] {
+				continue
+			}
+			skip[id] = true
+			position++
+			members = append(members, CollectionProduct{CollectionID: collectionID, ProductID: id, Position: position})
+			added = append(added, id)
+		}
+		if len(members) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(members, 500).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// RemoveCollectionProducts removes products from a collection and returns
+// the ones that were in it
+func (r *repository) RemoveCollectionProducts(collectionID uuid.UUID, productIDs []uuid.UUID) ([]uuid.UUID, error) {
+	var removed []uuid.UUID
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("collection_products").Where("collection_id = ? AND product_id IN ?", collectionID, productIDs).
+			Pluck("product_id", &removed).Error; err != nil {
+			return err
+		}
+		if len(removed) == 0 {
+			return nil
+		}
+		return tx.Where("collection_id = ? AND product_id IN ?", collectionID, removed).
+			Delete(&CollectionProduct{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// ReorderCollectionProducts puts the given products first in the given order,
+// keeping the others after them in their current order
+func (r *repository) ReorderCollectionProducts(collectionID uuid.UUID, productIDs []uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&CollectionProduct{}).
+			Where("collection_id = ? AND product_id NOT IN ?", collectionID, productIDs).
+			Update("position", gorm.Expr("position + ?", len(productIDs))).Error; err != nil {
+			return err
+		}
+		for i, id := range productIDs {
+			if err := tx.Model(&CollectionProduct{}).
+				Where("collection_id = ? AND product_id = ?", collectionID, id).
+				Update("position", i+1).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SyncCollection brings the members of a smart collection in line with its
+// rules, for the given products or for all products when productIDs is nil,
+// and returns the products that joined or left
+func (r *repository) SyncCollection(collection *Collection, productIDs []uuid.UUID) ([]uuid.UUID, error) {
+	if productIDs != nil && len(productIDs) == 0 {
+		return nil, nil
+	}
+
+	var changed []uuid.UUID
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		condition, args := collectionRulesSQL(collection)
+		matching := tx.Table("products").Where("products.tenant_id = ?", collection.TenantID).Where(condition, args...)
+		current := tx.Table("collection_products").Where("collection_id = ?", collection.ID)
+		if productIDs != nil {
+			matching = matching.Where("products.id IN ?", productIDs)
+			current = current.Where("product_id IN ?", productIDs)
+		}
+
+		var matched, members []uuid.UUID
+		if err := matching.Pluck("products.id", &matched).Error; err != nil {
+			return err
+		}
+		if err := current.Pluck("product_id", &members).Error; err != nil {
+			return err
+		}
+
+		isMember := make(map[uuid.UUID]bool, len(members))
+		for _, id := range members {
+			isMember[id] = true
+		}
+		var joined []CollectionProduct
+		for _, id := range matched {
+			if isMember[id] {
+				delete(isMember, id)
+				continue
+			}
+			joined = append(joined, CollectionProduct{CollectionID: collection.ID, ProductID: id})
+			changed = append(changed, id)
+		}
+		var left []uuid.UUID
+		for id := range isMember {
+			left = append(left, id)
+			changed = append(changed, id)
+		}
+
+		if len(left) > 0 {
+			if err := tx.Where("collection_id = ? AND product_id IN ?", collection.ID, left).
+				Delete(&CollectionProduct{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(joined) > 0 {
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(joined, 500).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+// collectionRulesSQL returns the condition products meet to belong to a
+// smart collection
+func collectionRulesSQL(collection *Collection) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	for _, rule := range collection.Rules {
+		switch rule.Field {
+		case RuleTag:
+			conditions = append(conditions, `EXISTS (
+				SELECT 1 FROM jsonb_array_elements_text(CASE WHEN jsonb_typeof(products.tags) = 'array' THEN products.tags ELSE '[]'::jsonb END) AS tag
+				WHERE LOWER(tag) = ?)`)
+			args = append(args, rule.Value)
+		case RulePrice:
+			switch {
+			case rule.Min != nil && rule.Max != nil:
+				conditions = append(conditions, "products.price BETWEEN ? AND ?")
+				args = append(args, *rule.Min, *rule.Max)
+			case rule.Min != nil:
+				conditions = append(conditions, "products.price >= ?")
+				args = append(args, *rule.Min)
+			case rule.Max != nil:
+				conditions = append(conditions, "products.price <= ?")
+				args = append(args, *rule.Max)
+			}
+		case RuleCategory:
+			conditions = append(conditions, `products.category_id IN (
+				WITH RECURSIVE tree AS (
+					SELECT id FROM categories WHERE id = ?
+					UNION
+					SELECT c.id FROM categories c JOIN tree ON c.parent_id = tree.id
+				)
+				SELECT id FROM tree)`)
+			args = append(args, rule.Value)
+		case RuleVendor:
+			conditions = append(conditions, "LOWER(products.vendor) = LOWER(?)")
+			args = append(args, rule.Value)
+		case RuleStock:
+			inStock := "(products.track_quantity = false OR products.inventory_quantity > 0)"
+			if rule.Value == StockInStock {
+				conditions = append(conditions, inStock)
+			} else {
+				conditions = append(conditions, "NOT "+inStock)
+			}
+		case RuleCreatedWithin:
+			conditions = append(conditions, "products.created_at >= NOW() - make_interval(days => ?)")
+			args = append(args, rule.Days)
+		}
+	}
+	if len(conditions) == 0 {
+		return "FALSE", nil
+	}
+
+	separator := " AND "
+	if collection.RuleMatch == RuleMatchAny {
+		separator = " OR "
+	}
+	return "(" + strings.Join(conditions, separator) + ")", args
+}
@@ -0,0 +1,37 @@
+package product
+
+import (
+	"context"
+	"math"
+
+	"ecommerce-saas/internal/reviews"
+)
+
+// ReviewSummaryChanged copies a product's review summary onto the product and
+// reindexes it. It implements reviews.SummaryListener.
+func (s *Service) ReviewSummaryChanged(ctx context.Context, summary *reviews.ReviewSummary) error {
+	if summary.ProductID == nil {
+		return nil
+	}
+
+	var average *float64
+	var distribution map[int]int
+	if summary.ApprovedReviews > 0 {
+		rounded := math.Round(summary.AverageRating*100) / 100
+		average = &rounded
+		distribution = map[int]int{
+			1: summary.Rating1Count,
+			2: summary.Rating2Count,
+			3: summary.Rating3Count,
+			4: summary.Rating4Count,
+			5: summary.Rating5Count,
+		}
+	}
+
+	if err := s.repo.UpdateReviewSummary(summary.TenantID, *summary.ProductID, average, summary.ApprovedReviews, distribution); err != nil {
+		return err
+	}
+
+	s.productsChanged(summary.TenantID, *summary.ProductID)
+	return nil
+}
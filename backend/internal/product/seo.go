@@ -0,0 +1,157 @@
+package product
+
+import (
+	"math"
+	"strings"
+)
+
+const schemaOrg = "https://schema.org"
+
+// PageSEO is the SEO output of a storefront page
+type PageSEO struct {
+	Title       string                 `json:"title"`
+	Description string                 `json:"description"`
+	Keywords    string                 `json:"keywords,omitempty"`
+	Image       string                 `json:"image,omitempty"`
+	JSONLD      map[string]interface{} `json:"json_ld"`
+}
+
+// SEO returns the meta tags and schema.org Product structured data of the
+// product, with prices in the given currency
+func (p *Product) SEO(currency string) *PageSEO {
+	return &PageSEO{
+		Title:       p.GetSEOTitle(),
+		Description: p.GetSEODescription(),
+		Keywords:    p.MetaKeywords,
+		Image:       p.GetMainImage(),
+		JSONLD:      p.structuredData(currency),
+	}
+}
+
+func (p *Product) structuredData(currency string) map[string]interface{} {
+	data := map[string]interface{}{
+		"@context":    schemaOrg,
+		"@type":       "Product",
+		"name":        p.Name,
+		"description": p.GetSEODescription(),
+		"offers":      p.structuredOffer(currency),
+	}
+	if len(p.Images) > 0 {
+		data["image"] = p.Images
+	} else if image := p.GetMainImage(); image != "" {
+		data["image"] = []string{image}
+	}
+	if p.SKU != "" {
+		data["sku"] = p.SKU
+	}
+	if p.Barcode != "" {
+		data["gtin"] = p.Barcode
+	}
+	if p.Category != nil && p.Category.Name != "" {
+		data["category"] = p.Category.Name
+	}
+	if p.ReviewCount > 0 && p.RatingAverage != nil {
+		data["aggregateRating"] = map[string]interface{}{
+			"@type":       "AggregateRating",
+			"ratingValue": *p.RatingAverage,
+			"reviewCount": p.ReviewCount,
+			"bestRating":  5,
+			"worstRating": 1,
+		}
+	}
+	return data
+}
+
+// structuredOffer returns an Offer, or an AggregateOffer when the variants
+// are priced differently
+func (p *Product) structuredOffer(currency string) map[string]interface{} {
+	offer := map[string]interface{}{
+		"priceCurrency": strings.ToUpper(currency),
+		"availability":  schemaOrg + "/" + p.availability(),
+	}
+
+	low, high := p.offerPrices()
+	if low == high {
+		offer["@type"] = "Offer"
+		offer["price"] = low
+		return offer
+	}
+	offer["@type"] = "AggregateOffer"
+	offer["lowPrice"] = low
+	offer["highPrice"] = high
+	offer["offerCount"] = len(p.Variants)
+	return offer
+}
+
+// offerPrices returns the lowest and highest price a customer can pay, taking
+// variants without their own price at the product price
+func (p *Product) offerPrices() (float64, float64) {
+	if !p.HasVariants() {
+		return p.Price, p.Price
+	}
+
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, variant := range p.Variants {
+		price := variant.Price
+		if price <= 0 {
+			price = p.Price
+		}
+		low = math.Min(low, price)
+		high = math.Max(high, price)
+	}
+	return low, high
+}
+
+// availability returns the schema.org ItemAvailability of the product
+func (p *Product) availability() string {
+	switch {
+	case p.Status != ProductStatusActive:
+		return "Discontinued"
+	case p.IsInStock():
+		return "InStock"
+	case p.AllowBackorder:
+		return "BackOrder"
+	default:
+		return "OutOfStock"
+	}
+}
+
+// SEO returns the meta tags and schema.org CollectionPage structured data of
+// the collection, listing the products shown on the page
+func (c *Collection) SEO(products []*Product) *PageSEO {
+	items := make([]map[string]interface{}, 0, len(products))
+	for i, product := range products {
+		item := map[string]interface{}{
+			"@type":    "ListItem",
+			"position": i + 1,
+			"name":     product.Name,
+		}
+		if image := product.GetMainImage(); image != "" {
+			item["image"] = image
+		}
+		items = append(items, item)
+	}
+
+	data := map[string]interface{}{
+		"@context":    schemaOrg,
+		"@type":       "CollectionPage",
+		"name":        c.GetSEOTitle(),
+		"description": c.GetSEODescription(),
+		"mainEntity": map[string]interface{}{
+			"@type":           "ItemList",
+			"numberOfItems":   len(items),
+			"itemListElement": items,
+		},
+	}
+	if c.Image != "" {
+		data["image"] = c.Image
+	}
+
+	return &PageSEO{
+		Title:       c.GetSEOTitle(),
+		Description: c.GetSEODescription(),
+		Keywords:    c.MetaKeywords,
+		Image:       c.Image,
+		JSONLD:      data,
+	}
+}
@@ -3,6 +3,7 @@ package product
 import (
 	"errors"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
@@ -18,12 +19,23 @@ type ProductListFilter struct {
 	MaxPrice   *float64      `json:"max_price,omitempty"`
 	InStock    *bool         `json:"in_stock,omitempty"`
 	Search     string        `json:"search,omitempty"`
+	MinRating  *float64      `json:"min_rating,omitempty"`
+	Sort       string        `json:"sort,omitempty"` // newest (default), rating, price_asc or price_desc
+}
+
+// ChangeListener is told after products or categories are written so derived
+// data such as search indexes can follow. Listener errors never fail the write.
+type ChangeListener interface {
+	ProductsChanged(tenantID uuid.UUID, productIDs []uuid.UUID) error
+	ProductsDeleted(tenantID uuid.UUID, productIDs []uuid.UUID) error
+	CategoriesChanged(tenantID uuid.UUID, categoryIDs []uuid.UUID) error
 }
 
 // Service handles product business logic
 type Service struct {
 	repo      Repository
 	validator *validator.Validate
+	listener  ChangeListener
 }
 
 // NewService creates a new product service
@@ -34,6 +46,11 @@ func NewService(repo Repository) *Service {
 	}
 }
 
+// SetChangeListener registers a listener for product and category writes
+func (s *Service) SetChangeListener(listener ChangeListener) {
+	s.listener = listener
+}
+
 // CreateProduct creates a new product
 func (s *Service) CreateProduct(tenantID uuid.UUID, product *Product) (*Product, error) {
 	// Set tenant ID and generate new ID
@@ -70,15 +87,34 @@ func (s *Service) CreateProduct(tenantID uuid.UUID, product *Product) (*Product,
 		}
 	}
 
+	// Validate attributes against the category's attribute set
+	if err := s.validateProductAttributes(tenantID, product); err != nil {
+		return nil, err
+	}
+
+	// Validate variant option definitions
+	if err := validateProductOptions(product.Options); err != nil {
+		return nil, err
+	}
+	if len(product.Options) == 0 {
+		product.Options = nil
+	}
+
 	// Trim string fields
 	product.Name = strings.TrimSpace(product.Name)
 	product.Description = strings.TrimSpace(product.Description)
 	product.SKU = strings.TrimSpace(product.SKU)
 	product.Barcode = strings.TrimSpace(product.Barcode)
+	product.Vendor = strings.TrimSpace(product.Vendor)
 	product.MetaTitle = strings.TrimSpace(product.MetaTitle)
 	product.MetaDescription = strings.TrimSpace(product.MetaDescription)
 	product.MetaKeywords = strings.TrimSpace(product.MetaKeywords)
 
+	// Gift cards are issued on demand, there is no stock to count
+	if product.IsGiftCard() {
+		product.TrackQuantity = false
+	}
+
 	// Set default status if not provided
 	if product.Status == "" {
 		product.Status = StatusDraft
@@ -89,7 +125,7 @@ func (s *Service) CreateProduct(tenantID uuid.UUID, product *Product) (*Product,
 		product.FeaturedImage = product.Images[0]
 	}
 
-	return s.repo.SaveProduct(product)
+	return s.saveProduct(product)
 }
 
 // GetProduct retrieves a product by ID
@@ -206,9 +242,35 @@ func (s *Service) UpdateProduct(tenantID, productID uuid.UUID, product *Product)
 		}
 		existingProduct.CategoryID = product.CategoryID
 	}
+	if product.Vendor != "" {
+		existingProduct.Vendor = strings.TrimSpace(product.Vendor)
+	}
 	if product.Tags != nil {
 		existingProduct.Tags = product.Tags
 	}
+	if product.Attributes != nil {
+		existingProduct.Attributes = product.Attributes
+	}
+	if err := s.validateProductAttributes(tenantID, existingProduct); err != nil {
+		return nil, err
+	}
+	if product.Options != nil {
+		if err := s.applyProductOptions(existingProduct, product.Options); err != nil {
+			return nil, err
+		}
+	}
+	if product.GiftCardDenominations != nil {
+		existingProduct.GiftCardDenominations = product.GiftCardDenominations
+	}
+	if product.GiftCardValidityDays > 0 {
+		existingProduct.GiftCardValidityDays = product.GiftCardValidityDays
+	}
+	if existingProduct.IsGiftCard() {
+		if err := existingProduct.ValidateProductData(); err != nil {
+			return nil, err
+		}
+		existingProduct.TrackQuantity = false
+	}
 	if product.Images != nil {
 		existingProduct.Images = product.Images
 		// Update featured image
@@ -221,7 +283,7 @@ func (s *Service) UpdateProduct(tenantID, productID uuid.UUID, product *Product)
 
 	existingProduct.UpdatedAt = time.Now()
 
-	return s.repo.SaveProduct(existingProduct)
+	return s.saveProduct(existingProduct)
 }
 
 // ListProducts returns a paginated list of products
@@ -236,7 +298,12 @@ func (s *Service) DeleteProduct(tenantID uuid.UUID, id string) error {
 		return errors.New("invalid product ID")
 	}
 
-	return s.repo.DeleteProduct(tenantID, productID)
+	if err := s.repo.DeleteProduct(tenantID, productID); err != nil {
+		return err
+	}
+
+	s.productsDeleted(tenantID, productID)
+	return nil
 }
 
 // UpdateInventory updates product inventory
@@ -246,7 +313,12 @@ func (s *Service) UpdateInventory(tenantID uuid.UUID, id string, quantity int) e
 		return errors.New("invalid product ID")
 	}
 
-	return s.repo.UpdateInventory(tenantID, productID, quantity)
+	if err := s.repo.UpdateInventory(tenantID, productID, quantity); err != nil {
+		return err
+	}
+
+	s.productsChanged(tenantID, productID)
+	return nil
 }
 
 // CreateCategory creates a new category
@@ -280,6 +352,11 @@ func (s *Service) CreateCategory(tenantID uuid.UUID, category *Category) (*Categ
 		}
 	}
 
+	// Validate attribute set if provided
+	if err := s.validateCategoryAttributeSet(tenantID, category); err != nil {
+		return nil, err
+	}
+
 	// Trim string fields
 	category.Name = strings.TrimSpace(category.Name)
 	category.Description = strings.TrimSpace(category.Description)
@@ -379,7 +456,12 @@ func (s *Service) BulkDeleteProducts(tenantID uuid.UUID, productIDs []string) er
 		return errors.New("no valid product IDs provided")
 	}
 
-	return s.repo.BulkDeleteProducts(tenantID, uuidIDs)
+	if err := s.repo.BulkDeleteProducts(tenantID, uuidIDs); err != nil {
+		return err
+	}
+
+	s.productsDeleted(tenantID, uuidIDs...)
+	return nil
 }
 
 // GetProductAnalytics returns analytics data for a specific product
@@ -466,7 +548,12 @@ func (s *Service) BulkUpdateProducts(tenantID uuid.UUID, productIDs []string, up
 		return errors.New("no valid update fields provided")
 	}
 
-	return s.repo.BulkUpdateProducts(tenantID, uuidIDs, validUpdates)
+	if err := s.repo.BulkUpdateProducts(tenantID, uuidIDs, validUpdates); err != nil {
+		return err
+	}
+
+	s.productsChanged(tenantID, uuidIDs...)
+	return nil
 }
 
 // DuplicateProduct creates a copy of an existing product
@@ -510,9 +597,10 @@ func (s *Service) DuplicateProduct(tenantID uuid.UUID, productIDStr string) (*Pr
 		Images:            original.Images,
 		CategoryID:        original.CategoryID,
 		Tags:              original.Tags,
+		Vendor:            original.Vendor,
 	}
 
-	return s.repo.SaveProduct(duplicate)
+	return s.saveProduct(duplicate)
 }
 
 // SearchProducts performs search across products
@@ -556,7 +644,12 @@ func (s *Service) UpdateProductStatus(tenantID uuid.UUID, productIDStr string, s
 		"status": status,
 	}
 
-	return s.repo.BulkUpdateProducts(tenantID, []uuid.UUID{productID}, updates)
+	if err := s.repo.BulkUpdateProducts(tenantID, []uuid.UUID{productID}, updates); err != nil {
+		return err
+	}
+
+	s.productsChanged(tenantID, productID)
+	return nil
 }
 
 // Product Variant methods
@@ -573,9 +666,8 @@ func (s *Service) CreateProductVariant(tenantID, productID uuid.UUID, variant *P
 	}
 
 	// Validate product exists
-	if exists, err := s.repo.ProductExists(tenantID, productID); err != nil {
-		return nil, err
-	} else if !exists {
+	product, err := s.repo.FindProductByID(tenantID, productID)
+	if err != nil {
 		return nil, errors.New("product not found")
 	}
 
@@ -584,11 +676,16 @@ func (s *Service) CreateProductVariant(tenantID, productID uuid.UUID, variant *P
 		return nil, errors.New("compare price must be higher than selling price")
 	}
 
+	// Options must be defined by the product and unique among its variants
+	if err := s.prepareVariantOptions(tenantID, product, variant); err != nil {
+		return nil, err
+	}
+
 	// Trim string fields
 	variant.SKU = strings.TrimSpace(variant.SKU)
 	variant.Barcode = strings.TrimSpace(variant.Barcode)
 
-	return s.repo.SaveProductVariant(variant)
+	return s.saveProductVariant(tenantID, variant)
 }
 
 // GetProductVariants returns all variants for a product
@@ -607,6 +704,26 @@ func (s *Service) GetProductVariants(tenantID uuid.UUID, productIDStr string) ([
 	return s.repo.FindProductVariants(tenantID, productID)
 }
 
+// ShippingWeight returns the shipping weight in grams of one unit of a
+// product, or of its variant when given and the variant has its own weight
+func (s *Service) ShippingWeight(tenantID, productID uuid.UUID, variantID *uuid.UUID) (float64, error) {
+	if variantID != nil {
+		variant, err := s.repo.GetProductVariant(tenantID, *variantID)
+		if err != nil {
+			return 0, err
+		}
+		if variant != nil && variant.ProductID == productID && variant.Weight > 0 {
+			return variant.Weight, nil
+		}
+	}
+
+	product, err := s.repo.FindProductByID(tenantID, productID)
+	if err != nil {
+		return 0, err
+	}
+	return product.CalculateShippingWeight(), nil
+}
+
 // UpdateProductVariant updates an existing product variant
 func (s *Service) UpdateProductVariant(tenantID, productID, variantID uuid.UUID, variant *ProductVariant) (*ProductVariant, error) {
 	// Get existing variant
@@ -614,7 +731,7 @@ func (s *Service) UpdateProductVariant(tenantID, productID, variantID uuid.UUID,
 	if err != nil {
 		return nil, err
 	}
-	if existingVariant == nil {
+	if existingVariant == nil || existingVariant.ProductID != productID {
 		return nil, errors.New("variant not found")
 	}
 
@@ -669,9 +786,22 @@ func (s *Service) UpdateProductVariant(tenantID, productID, variantID uuid.UUID,
 		return nil, errors.New("compare price must be higher than selling price")
 	}
 
+	product, err := s.repo.FindProductByID(tenantID, productID)
+	if err != nil {
+		return nil, errors.New("product not found")
+	}
+	if err := s.prepareVariantOptions(tenantID, product, existingVariant); err != nil {
+		return nil, err
+	}
+
 	existingVariant.UpdatedAt = time.Now()
 
-	return s.repo.SaveProductVariant(existingVariant)
+	updated, err := s.repo.UpdateProductVariant(existingVariant)
+	if err != nil {
+		return nil, err
+	}
+	s.productsChanged(tenantID, productID)
+	return updated, nil
 }
 
 // DeleteProductVariant deletes a product variant
@@ -692,7 +822,12 @@ func (s *Service) DeleteProductVariant(tenantID uuid.UUID, productIDStr, variant
 		return errors.New("product not found")
 	}
 
-	return s.repo.DeleteProductVariant(tenantID, variantID)
+	if err := s.repo.DeleteProductVariant(tenantID, variantID); err != nil {
+		return err
+	}
+
+	s.productsChanged(tenantID, productID)
+	return nil
 }
 
 // Category management methods
@@ -734,7 +869,9 @@ func (s *Service) UpdateCategory(tenantID, categoryID uuid.UUID, category *Categ
 	if category.Image != "" {
 		existingCategory.Image = category.Image
 	}
+	parentChanged := false
 	if category.ParentID != nil && *category.ParentID != uuid.Nil {
+		parentChanged = existingCategory.ParentID == nil || *existingCategory.ParentID != *category.ParentID
 		// Validate parent category if provided
 		if exists, err := s.repo.CategoryExists(tenantID, *category.ParentID); err != nil {
 			return nil, err
@@ -746,6 +883,17 @@ func (s *Service) UpdateCategory(tenantID, categoryID uuid.UUID, category *Categ
 	if category.SortOrder > 0 {
 		existingCategory.SortOrder = category.SortOrder
 	}
+	if category.AttributeSetID != nil {
+		// A nil UUID clears the set so the category inherits its parent's
+		if *category.AttributeSetID == uuid.Nil {
+			existingCategory.AttributeSetID = nil
+		} else {
+			if err := s.validateCategoryAttributeSet(tenantID, category); err != nil {
+				return nil, err
+			}
+			existingCategory.AttributeSetID = category.AttributeSetID
+		}
+	}
 	existingCategory.IsActive = category.IsActive
 	if category.MetaTitle != "" {
 		existingCategory.MetaTitle = strings.TrimSpace(category.MetaTitle)
@@ -756,7 +904,26 @@ func (s *Service) UpdateCategory(tenantID, categoryID uuid.UUID, category *Categ
 
 	existingCategory.UpdatedAt = time.Now()
 
-	return s.repo.SaveCategory(existingCategory)
+	saved, err := s.repo.SaveCategory(existingCategory)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.listener != nil {
+		if err := s.listener.CategoriesChanged(tenantID, []uuid.UUID{categoryID}); err != nil {
+			log.Printf("Failed to process change of category %s: %v", categoryID, err)
+		}
+	}
+
+	// Category rules cover subcategories, so moving a category can move its
+	// products in or out of smart collections
+	if parentChanged {
+		moved := s.syncSmartCollections(tenantID, nil, func(collection *Collection) bool {
+			return collection.HasRule(RuleCategory)
+		})
+		s.notifyProductsChanged(tenantID, moved...)
+	}
+	return saved, nil
 }
 
 // DeleteCategory deletes a category
@@ -803,3 +970,53 @@ func (s *Service) GetCategoryChildren(tenantID uuid.UUID, categoryIDStr string)
 
 	return s.repo.GetCategoryChildren(tenantID, categoryID)
 }
+
+// Change notification helpers
+
+func (s *Service) saveProduct(product *Product) (*Product, error) {
+	saved, err := s.repo.SaveProduct(product)
+	if err != nil {
+		return nil, err
+	}
+
+	s.productsChanged(saved.TenantID, saved.ID)
+	return saved, nil
+}
+
+func (s *Service) saveProductVariant(tenantID uuid.UUID, variant *ProductVariant) (*ProductVariant, error) {
+	saved, err := s.repo.SaveProductVariant(variant)
+	if err != nil {
+		return nil, err
+	}
+
+	s.productsChanged(tenantID, saved.ProductID)
+	return saved, nil
+}
+
+// productsChanged moves the products in or out of smart collections as their
+// rules now say and tells the listener, about collection moves as well
+func (s *Service) productsChanged(tenantID uuid.UUID, productIDs ...uuid.UUID) {
+	if len(productIDs) == 0 {
+		return
+	}
+	moved := s.syncSmartCollections(tenantID, productIDs, nil)
+	s.notifyProductsChanged(tenantID, append(productIDs, moved...)...)
+}
+
+func (s *Service) notifyProductsChanged(tenantID uuid.UUID, productIDs ...uuid.UUID) {
+	if s.listener == nil || len(productIDs) == 0 {
+		return
+	}
+	if err := s.listener.ProductsChanged(tenantID, productIDs); err != nil {
+		log.Printf("Failed to process change of %d products: %v", len(productIDs), err)
+	}
+}
+
+func (s *Service) productsDeleted(tenantID uuid.UUID, productIDs ...uuid.UUID) {
+	if s.listener == nil {
+		return
+	}
+	if err := s.listener.ProductsDeleted(tenantID, productIDs); err != nil {
+		log.Printf("Failed to process deletion of %d products: %v", len(productIDs), err)
+	}
+}
@@ -13,9 +13,9 @@ type Module struct {
 }
 
 // NewModule creates a new returns module
-func NewModule(db *gorm.DB) *Module {
+func NewModule(db *gorm.DB, refundListener RefundListener) *Module {
 	repo := NewRepository(db)
-	svc := NewService(repo)
+	svc := NewService(repo, refundListener)
 	handler := NewHandler(svc)
 
 	return &Module{
@@ -74,9 +74,16 @@ type TrackingEvent struct {
 	Location    string    `json:"location,omitempty"`
 }
 
+// RefundListener is told when a completed return refunds the customer, e.g.
+// so loyalty can take back the points earned on the refunded items
+type RefundListener interface {
+	ReturnRefunded(ctx context.Context, tenantID, orderID, returnID uuid.UUID, amount float64) error
+}
+
 // service implements the Service interface
 type service struct {
-	repo Repository
+	repo           Repository
+	refundListener RefundListener
 	// Add external service dependencies here
 	// orderService OrderService
 	// paymentService PaymentService
@@ -85,9 +92,10 @@ type service struct {
 }
 
 // NewService creates a new return service
-func NewService(repo Repository) Service {
+func NewService(repo Repository, refundListener RefundListener) Service {
 	return &service{
-		repo: repo,
+		repo:           repo,
+		refundListener: refundListener,
 	}
 }
 
@@ -338,6 +346,11 @@ func (s *service) CompleteReturn(ctx context.Context, tenantID, returnID uuid.UU
 		return nil, fmt.Errorf("failed to complete return: %w", err)
 	}
 	
+	// Listeners must not fail a return that is already completed
+	if s.refundListener != nil && refundAmount > 0 {
+		_ = s.refundListener.ReturnRefunded(ctx, tenantID, return_.OrderID, return_.ID, refundAmount)
+	}
+	
 	// TODO: Process refund payment
 	// TODO: Update inventory
 	// TODO: Send notification to customer
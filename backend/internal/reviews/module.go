@@ -13,9 +13,9 @@ type Module struct {
 }
 
 // NewModule creates a new reviews module instance
-func NewModule(db *gorm.DB) *Module {
+func NewModule(db *gorm.DB, listener ApprovalListener, summaries SummaryListener) *Module {
 	repo := NewRepository(db)
-	svc := NewService(repo)
+	svc := NewService(repo, listener, summaries)
 	handler := NewHandler(svc)
 
 	return &Module{
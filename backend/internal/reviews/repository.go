@@ -387,10 +387,14 @@ func (r *repository) RecalculateReviewSummary(ctx context.Context, tenantID, pro
 			Count  int `json:"count"`
 		}
 		
+		approved := func() *gorm.DB {
+			return tx.Model(&Review{}).
+				Where("tenant_id = ? AND product_id = ? AND status = ?", tenantID, productID, StatusApproved)
+		}
+		
 		var ratings []ratingCount
-		err := tx.Model(&Review{}).
+		err := approved().
 			Select("rating, COUNT(*) as count").
-			Where("tenant_id = ? AND product_id = ? AND status = ?", tenantID, productID, StatusApproved).
 			Group("rating").
 			Find(&ratings).Error
 		
@@ -399,7 +403,7 @@ func (r *repository) RecalculateReviewSummary(ctx context.Context, tenantID, pro
 		}
 		
 		// Calculate summary statistics
-		var totalReviews, verifiedCount, withPhotosCount int64
+		var totalReviews, verifiedCount, withPhotosCount, withVideosCount int64
 		var ratingCounts [6]int // Index 0 unused, 1-5 for ratings
 		var totalPoints int
 		
@@ -411,16 +415,16 @@ func (r *repository) RecalculateReviewSummary(ctx context.Context, tenantID, pro
 			}
 		}
 		
-		// Count verified reviews and reviews with photos
-		tx.Model(&Review{}).
-			Where("tenant_id = ? AND product_id = ? AND status = ? AND is_verified = ?", 
-				tenantID, productID, StatusApproved, true).
-			Count(&verifiedCount)
-		
-		tx.Model(&Review{}).
-			Where("tenant_id = ? AND product_id = ? AND status = ? AND JSON_LENGTH(images) > 0", 
-				tenantID, productID, StatusApproved).
-			Count(&withPhotosCount)
+		// Count verified reviews and reviews with photos or videos
+		if err := approved().Where("is_verified = ?", true).Count(&verifiedCount).Error; err != nil {
+			return err
+		}
+		if err := approved().Where(hasMediaSQL("images")).Count(&withPhotosCount).Error; err != nil {
+			return err
+		}
+		if err := approved().Where(hasMediaSQL("videos")).Count(&withVideosCount).Error; err != nil {
+			return err
+		}
 		
 		// Calculate average rating
 		var avgRating float64
@@ -440,13 +444,43 @@ func (r *repository) RecalculateReviewSummary(ctx context.Context, tenantID, pro
 			"rating_5_count":    ratingCounts[5],
 			"verified_reviews":  verifiedCount,
 			"with_photos":       withPhotosCount,
+			"with_videos":       withVideosCount,
 			"updated_at":        time.Now(),
 		}
 		
-		return r.UpdateReviewSummary(ctx, tenantID, productID, updates)
+		result := tx.Model(&ReviewSummary{}).
+			Where("tenant_id = ? AND product_id = ? AND type = ?", tenantID, productID, TypeProduct).
+			Updates(updates)
+		if result.Error != nil || result.RowsAffected > 0 {
+			return result.Error
+		}
+		
+		// First summary of the product
+		return tx.Create(&ReviewSummary{
+			ID:              uuid.New(),
+			TenantID:        tenantID,
+			ProductID:       &productID,
+			Type:            TypeProduct,
+			TotalReviews:    int(totalReviews),
+			ApprovedReviews: int(totalReviews),
+			AverageRating:   avgRating,
+			Rating1Count:    ratingCounts[1],
+			Rating2Count:    ratingCounts[2],
+			Rating3Count:    ratingCounts[3],
+			Rating4Count:    ratingCounts[4],
+			Rating5Count:    ratingCounts[5],
+			VerifiedReviews: int(verifiedCount),
+			WithPhotos:      int(withPhotosCount),
+			WithVideos:      int(withVideosCount),
+		}).Error
 	})
 }
 
+// hasMediaSQL matches reviews with at least one entry in a JSON list column
+func hasMediaSQL(column string) string {
+	return column + " IS NOT NULL AND " + column + "::text NOT IN ('', 'null', '[]')"
+}
+
 // Review invitation operations
 func (r *repository) CreateInvitation(ctx context.Context, invitation *ReviewInvitation) error {
 	return r.db.WithContext(ctx).Create(invitation).Error
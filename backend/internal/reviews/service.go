@@ -3,6 +3,7 @@ package reviews
 import (
 	"context"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/google/uuid"
@@ -58,14 +59,28 @@ type Service interface {
 	GetReviewTrends(ctx context.Context, tenantID uuid.UUID, period string) (*ReviewTrends, error)
 }
 
+// ApprovalListener is told when a customer's review is approved, e.g. so
+// loyalty can award a review bonus
+type ApprovalListener interface {
+	ReviewApproved(ctx context.Context, tenantID, reviewID, userID uuid.UUID) error
+}
+
+// SummaryListener is told after a product's review summary was recalculated,
+// e.g. so the catalog can show and rank by it
+type SummaryListener interface {
+	ReviewSummaryChanged(ctx context.Context, summary *ReviewSummary) error
+}
+
 // service implements the Service interface
 type service struct {
-	repo Repository
+	repo      Repository
+	listener  ApprovalListener
+	summaries SummaryListener
 }
 
 // NewService creates a new reviews service
-func NewService(repo Repository) Service {
-	return &service{repo: repo}
+func NewService(repo Repository, listener ApprovalListener, summaries SummaryListener) Service {
+	return &service{repo: repo, listener: listener, summaries: summaries}
 }
 
 // Request/Response DTOs
@@ -363,6 +378,10 @@ func (s *service) UpdateReview(ctx context.Context, tenantID, reviewID uuid.UUID
 		return nil, err
 	}
 
+	if req.Rating != nil {
+		s.refreshProductSummaries(ctx, tenantID, reviewID)
+	}
+
 	return s.repo.GetReviewByID(ctx, tenantID, reviewID)
 }
 
@@ -379,7 +398,7 @@ func (s *service) DeleteReview(ctx context.Context, tenantID, reviewID uuid.UUID
 		return err
 	}
 
-	// TODO: Update product review summary after deletion
+	s.refreshProductSummaries(ctx, tenantID, reviewID)
 	return nil
 }
 
@@ -396,10 +415,42 @@ func (s *service) ApproveReview(ctx context.Context, tenantID, reviewID uuid.UUI
 		return err
 	}
 	
-	// TODO: Update review summary and send notifications
+	s.notifyApproved(ctx, tenantID, reviewID)
+	s.refreshProductSummaries(ctx, tenantID, reviewID)
 	return nil
 }
 
+// notifyApproved tells the listener about an approved review by a registered
+// customer. Listener errors don't undo the approval.
+func (s *service) notifyApproved(ctx context.Context, tenantID, reviewID uuid.UUID) {
+	if s.listener == nil {
+		return
+	}
+
+	review, err := s.repo.GetReviewByID(ctx, tenantID, reviewID)
+	if err != nil || review.UserID == nil {
+		return
+	}
+	_ = s.listener.ReviewApproved(ctx, tenantID, review.ID, *review.UserID)
+}
+
+// refreshProductSummaries recalculates the review summaries of the products
+// the reviews are about after their status or rating changed. A summary that
+// can't be refreshed doesn't undo the change.
+func (s *service) refreshProductSummaries(ctx context.Context, tenantID uuid.UUID, reviewIDs ...uuid.UUID) {
+	refreshed := make(map[uuid.UUID]bool)
+	for _, reviewID := range reviewIDs {
+		review, err := s.repo.GetReviewByID(ctx, tenantID, reviewID)
+		if err != nil || review.ProductID == nil || refreshed[*review.ProductID] {
+			continue
+		}
+		refreshed[*review.ProductID] = true
+		if _, err := s.RefreshReviewSummary(ctx, tenantID, *review.ProductID); err != nil {
+			log.Printf("Failed to refresh review summary of product %s: %v", *review.ProductID, err)
+		}
+	}
+}
+
 func (s *service) RejectReview(ctx context.Context, tenantID, reviewID uuid.UUID, moderatorID uuid.UUID, reason string) error {
 	now := time.Now()
 	updates := map[string]interface{}{
@@ -409,7 +460,12 @@ func (s *service) RejectReview(ctx context.Context, tenantID, reviewID uuid.UUID
 		"moderation_note": reason,
 	}
 	
-	return s.repo.UpdateReview(ctx, tenantID, reviewID, updates)
+	if err := s.repo.UpdateReview(ctx, tenantID, reviewID, updates); err != nil {
+		return err
+	}
+	
+	s.refreshProductSummaries(ctx, tenantID, reviewID)
+	return nil
 }
 
 func (s *service) MarkAsSpam(ctx context.Context, tenantID, reviewID uuid.UUID, moderatorID uuid.UUID) error {
@@ -420,7 +476,12 @@ func (s *service) MarkAsSpam(ctx context.Context, tenantID, reviewID uuid.UUID,
 		"moderated_at": &now,
 	}
 	
-	return s.repo.UpdateReview(ctx, tenantID, reviewID, updates)
+	if err := s.repo.UpdateReview(ctx, tenantID, reviewID, updates); err != nil {
+		return err
+	}
+	
+	s.refreshProductSummaries(ctx, tenantID, reviewID)
+	return nil
 }
 
 func (s *service) BulkModerateReviews(ctx context.Context, tenantID uuid.UUID, req BulkModerationRequest) error {
@@ -456,8 +517,12 @@ func (s *service) BulkModerateReviews(ctx context.Context, tenantID uuid.UUID, r
 		if err := s.repo.UpdateReview(ctx, tenantID, reviewID, updates); err != nil {
 			return fmt.Errorf("failed to update review %s: %w", reviewID, err)
 		}
+		if req.Action == "approve" {
+			s.notifyApproved(ctx, tenantID, reviewID)
+		}
 	}
 
+	s.refreshProductSummaries(ctx, tenantID, req.ReviewIDs...)
 	return nil
 }
 
@@ -525,88 +590,25 @@ func (s *service) GetReviewSummary(ctx context.Context, tenantID, productID uuid
 	return s.repo.GetReviewSummary(ctx, tenantID, productID)
 }
 
+// RefreshReviewSummary recalculates a product's summary from its approved
+// reviews and passes it on to the summary listener
 func (s *service) RefreshReviewSummary(ctx context.Context, tenantID, productID uuid.UUID) (*ReviewSummary, error) {
-	// Get all approved reviews for the product
-	filter := ReviewFilter{
-		ProductID: &productID,
-		Status:    []ReviewStatus{StatusApproved},
-		Limit:     1000, // Get all reviews
-	}
-	
-	reviews, err := s.repo.GetReviews(ctx, tenantID, filter)
-	if err != nil {
+	if err := s.repo.RecalculateReviewSummary(ctx, tenantID, productID); err != nil {
 		return nil, err
 	}
 
-	// Calculate statistics
-	var totalRating float64
-	ratingCounts := make(map[int]int)
-
-	for _, review := range reviews {
-		totalRating += float64(review.Rating)
-		ratingCounts[review.Rating]++
-	}
-
-	var avgRating float64
-	if len(reviews) > 0 {
-		avgRating = totalRating / float64(len(reviews))
-	}
-
-	summary := &ReviewSummary{
-		ProductID:      &productID,
-		TotalReviews:   len(reviews),
-		AverageRating:  avgRating,
-		Rating1Count:   ratingCounts[1],
-		Rating2Count:   ratingCounts[2],
-		Rating3Count:   ratingCounts[3],
-		Rating4Count:   ratingCounts[4],
-		Rating5Count:   ratingCounts[5],
-		UpdatedAt:      time.Now(),
-	}
-
-	// Try to get existing summary
-	existing, err := s.repo.GetReviewSummary(ctx, tenantID, productID)
-	if err != nil || existing == nil {
-		// Create new summary
-		summary.ID = uuid.New()
-		summary.TenantID = tenantID
-		summary.Type = TypeProduct
-		summary.CreatedAt = time.Now()
-		err := s.repo.CreateReviewSummary(ctx, summary)
-		if err != nil {
-			return nil, err
-		}
-		return summary, nil
-	}
-
-	// Update existing summary
-	updates := map[string]interface{}{
-		"total_reviews":   summary.TotalReviews,
-		"average_rating":  summary.AverageRating,
-		"rating_1_count":  summary.Rating1Count,
-		"rating_2_count":  summary.Rating2Count,
-		"rating_3_count":  summary.Rating3Count,
-		"rating_4_count":  summary.Rating4Count,
-		"rating_5_count":  summary.Rating5Count,
-		"updated_at":      summary.UpdatedAt,
-	}
-
-	err = s.repo.UpdateReviewSummary(ctx, tenantID, productID, updates)
+	summary, err := s.repo.GetReviewSummary(ctx, tenantID, productID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Return updated summary
-	existing.TotalReviews = summary.TotalReviews
-	existing.AverageRating = summary.AverageRating
-	existing.Rating1Count = summary.Rating1Count
-	existing.Rating2Count = summary.Rating2Count
-	existing.Rating3Count = summary.Rating3Count
-	existing.Rating4Count = summary.Rating4Count
-	existing.Rating5Count = summary.Rating5Count
-	existing.UpdatedAt = summary.UpdatedAt
+	if s.summaries != nil {
+		if err := s.summaries.ReviewSummaryChanged(ctx, summary); err != nil {
+			log.Printf("Failed to process review summary of product %s: %v", productID, err)
+		}
+	}
 
-	return existing, nil
+	return summary, nil
 }
 
 func (s *service) GetReviewStats(ctx context.Context, tenantID uuid.UUID, period string) (*ReviewStats, error) {
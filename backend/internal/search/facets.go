@@ -0,0 +1,947 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Facet identifiers; variant option facets are "option.<key>" and product
+// attribute facets "attribute.<code>"
+const (
+	FacetCategory     = "category"
+	FacetCollection   = "collection"
+	FacetPrice        = "price"
+	FacetAvailability = "availability"
+	FacetRating       = "rating"
+	FacetOption       = "option"
+	FacetAttribute    = "attribute"
+)
+
+// Availability facet values
+const (
+	AvailabilityInStock    = "in_stock"
+	AvailabilityOutOfStock = "out_of_stock"
+)
+
+const (
+	optionFacetPrefix    = FacetOption + "."
+	attributeFacetPrefix = FacetAttribute + "."
+)
+
+// Number of buckets the price histogram aims for
+const priceBuckets = 5
+
+// inStockSQL tells whether product p can be bought now
+const inStockSQL = "(p.track_quantity = FALSE OR p.allow_backorder = TRUE OR p.inventory_quantity > 0)"
+
+// applyFacetFilters adds the facet selections of a search, leaving out the
+// selection of the facet named by except. Counting a facet without its own
+// selection shows what ticking another of its values would add.
+func applyFacetFilters(query *gorm.DB, req *ProductSearchRequest, except string) *gorm.DB {
+	if except != FacetCategory && len(req.CategoryIDs) > 0 {
+		query = query.Where(`p.category_id IN (
+			WITH RECURSIVE tree AS (
+				SELECT id FROM categories WHERE id IN ?
+				UNION
+				SELECT c.id FROM categories c JOIN tree ON c.parent_id = tree.id
+			)
+			SELECT id FROM tree)`, req.CategoryIDs)
+	}
+
+	if except != FacetCollection && len(req.CollectionIDs) > 0 {
+		query = query.Where(`EXISTS (
+			SELECT 1 FROM collection_products cp
+			JOIN collections col ON col.id = cp.collection_id AND col.is_published = TRUE
+			WHERE cp.product_id = p.id AND cp.collection_id IN ?)`, req.CollectionIDs)
+	}
+
+	if except != FacetPrice && len(req.PriceRanges) > 0 {
+		var conditions []string
+		var args []interface{}
+		for _, value := range req.PriceRanges {
+			from, to, err := parsePriceRange(value)
+			if err != nil {
+				continue
+			}
+			condition, conditionArgs := priceRangeSQL(from, to)
+			conditions = append(conditions, condition)
+			args = append(args, conditionArgs...)
+		}
+		if len(conditions) > 0 {
+			query = query.Where("("+strings.Join(conditions, " OR ")+")", args...)
+		}
+	}
+
+	// Selecting both availability values is the same as selecting neither
+	if except != FacetAvailability && len(req.Availability) == 1 {
+		if req.Availability[0] == AvailabilityInStock {
+			query = query.Where(inStockSQL)
+		} else {
+			query = query.Where("NOT " + inStockSQL)
+		}
+	}
+
+	if except != FacetRating && req.Rating != nil {
+		query = query.Where("p.rating_average >= ?", *req.Rating)
+	}
+
+	if except != FacetOption {
+		condition, args := variantOptionSQL(req.Options, strings.TrimPrefix(except, optionFacetPrefix))
+		if condition != "" {
+			query = query.Where("EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = p.id AND "+condition+")", args...)
+		}
+	}
+
+	if except != FacetAttribute {
+		skipCode := ""
+		if strings.HasPrefix(except, attributeFacetPrefix) {
+			skipCode = strings.TrimPrefix(except, attributeFacetPrefix)
+		}
+		for _, code := range selectedOptionKeys(req.Attributes) {
+			if code != skipCode {
+				query = query.Where("p.attributes ->> ? IN ?", code, req.Attributes[code])
+			}
+		}
+	}
+
+	return query
+}
+
+// variantOptionSQL matches variant v against every selected option except
+// skipKey. All options must hold on the same variant, so "size M" and "colour
+// red" only match products that have a red M.
+func variantOptionSQL(options map[string][]string, skipKey string) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	for _, key := range selectedOptionKeys(options) {
+		if key == skipKey {
+			continue
+		}
+		conditions = append(conditions, "v.options ->> ? IN ?")
+		args = append(args, key, options[key])
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// productFacets counts the category, collection, price, availability,
+// rating, variant option and attribute values of the matched products
+func (r *repository) productFacets(ctx context.Context, tenantID uuid.UUID, matched *gorm.DB, req *ProductSearchRequest) ([]Filter, error) {
+	var facets []Filter
+
+	category, err := r.categoryFacet(ctx, tenantID, matched, req)
+	if err != nil {
+		return nil, err
+	}
+	if category != nil {
+		facets = append(facets, *category)
+	}
+
+	collection, err := collectionFacet(matched, req)
+	if err != nil {
+		return nil, err
+	}
+	if collection != nil {
+		facets = append(facets, *collection)
+	}
+
+	price, err := priceFacet(matched, req)
+	if err != nil {
+		return nil, err
+	}
+	if price != nil {
+		facets = append(facets, *price)
+	}
+
+	availability, err := availabilityFacet(matched, req)
+	if err != nil {
+		return nil, err
+	}
+	if availability != nil {
+		facets = append(facets, *availability)
+	}
+
+	rating, err := r.ratingFacet(ctx, matched, req)
+	if err != nil {
+		return nil, err
+	}
+	if rating != nil {
+		facets = append(facets, *rating)
+	}
+
+	options, err := optionFacets(matched, req)
+	if err != nil {
+		return nil, err
+	}
+	facets = append(facets, options...)
+
+	attributes, err := attributeFacets(matched, req)
+	if err != nil {
+		return nil, err
+	}
+	facets = append(facets, attributes...)
+
+	// Brands are not modelled in the catalog, so there is no brand facet
+	return facets, nil
+}
+
+// categoryFacet returns the category tree of the matched products. A
+// category's count includes the products of its subcategories.
+func (r *repository) categoryFacet(ctx context.Context, tenantID uuid.UUID, matched *gorm.DB, req *ProductSearchRequest) (*Filter, error) {
+	var counts []struct {
+		CategoryID *string
+		Count      int64
+	}
+	err := applyFacetFilters(matched, req, FacetCategory).
+		Select("p.category_id, COUNT(*) AS count").
+		Group("p.category_id").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count categories: %w", err)
+	}
+
+	direct := make(map[string]int64)
+	for _, count := range counts {
+		if count.CategoryID != nil {
+			direct[*count.CategoryID] = count.Count
+		}
+	}
+	if len(direct) == 0 {
+		return nil, nil
+	}
+
+	nodes, err := r.categoryNodes(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return categoryFilter(nodes, direct, true, req), nil
+}
+
+// collectionFacet counts the matched products in each published collection
+func collectionFacet(matched *gorm.DB, req *ProductSearchRequest) (*Filter, error) {
+	var counts []struct {
+		ID    string
+		Title string
+		Count int64
+	}
+	err := applyFacetFilters(matched, req, FacetCollection).
+		Joins("JOIN collection_products cp ON cp.product_id = p.id").
+		Joins("JOIN collections col ON col.id = cp.collection_id AND col.is_published = TRUE").
+		Select("col.id, col.title, COUNT(*) AS count").
+		Group("col.id, col.title").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count collections: %w", err)
+	}
+
+	nodes := make([]collectionNode, 0, len(counts))
+	byID := make(map[string]int64, len(counts))
+	for _, count := range counts {
+		nodes = append(nodes, collectionNode{ID: count.ID, Title: count.Title})
+		byID[count.ID] = count.Count
+	}
+	return collectionFilter(nodes, byID, req), nil
+}
+
+// priceFacet returns a histogram of the matched prices in round-width buckets
+func priceFacet(matched *gorm.DB, req *ProductSearchRequest) (*Filter, error) {
+	var bounds struct {
+		Min *float64
+		Max *float64
+	}
+	err := applyFacetFilters(matched, req, FacetPrice).
+		Select("MIN(p.price) AS min, MAX(p.price) AS max").
+		Scan(&bounds).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get price range: %w", err)
+	}
+	if bounds.Min == nil || bounds.Max == nil {
+		return nil, nil
+	}
+
+	width := priceBucketWidth(*bounds.Min, *bounds.Max)
+	var buckets []priceBucket
+	err = applyFacetFilters(matched, req, FacetPrice).
+		Select("FLOOR(p.price / ?)::bigint AS bucket, COUNT(*) AS count", width).
+		Group("bucket").
+		Order("bucket ASC").
+		Scan(&buckets).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count prices: %w", err)
+	}
+
+	return priceFilter(width, buckets, req), nil
+}
+
+// availabilityFacet counts matched products in and out of stock
+func availabilityFacet(matched *gorm.DB, req *ProductSearchRequest) (*Filter, error) {
+	var counts []struct {
+		InStock bool
+		Count   int64
+	}
+	err := applyFacetFilters(matched, req, FacetAvailability).
+		Select(inStockSQL + " AS in_stock, COUNT(*) AS count").
+		Group("in_stock").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count availability: %w", err)
+	}
+
+	var inStock, outOfStock int64
+	for _, count := range counts {
+		if count.InStock {
+			inStock = count.Count
+		} else {
+			outOfStock = count.Count
+		}
+	}
+	return availabilityFilter(inStock, outOfStock, req), nil
+}
+
+// ratingFacet counts matched products rated N stars and up
+func (r *repository) ratingFacet(ctx context.Context, matched *gorm.DB, req *ProductSearchRequest) (*Filter, error) {
+	rated := applyFacetFilters(matched, req, FacetRating).Select("p.rating_average AS rating")
+
+	var counts struct {
+		Four  int64
+		Three int64
+		Two   int64
+		One   int64
+	}
+	err := r.db.WithContext(ctx).Table("(?) AS rated", rated).
+		Select(`COUNT(*) FILTER (WHERE rating >= 4) AS four,
+			COUNT(*) FILTER (WHERE rating >= 3) AS three,
+			COUNT(*) FILTER (WHERE rating >= 2) AS two,
+			COUNT(*) FILTER (WHERE rating >= 1) AS one`).
+		Scan(&counts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count ratings: %w", err)
+	}
+
+	return ratingFilter(map[int]int64{4: counts.Four, 3: counts.Three, 2: counts.Two, 1: counts.One}, req), nil
+}
+
+// optionFacets returns one facet per variant option key (size, colour, ...)
+// counting the matched products that have a variant with each value
+func optionFacets(matched *gorm.DB, req *ProductSearchRequest) ([]Filter, error) {
+	type optionCount struct {
+		Key   string
+		Value string
+		Count int64
+	}
+
+	count := func(except, skipKey string, where func(*gorm.DB) *gorm.DB) ([]optionCount, error) {
+		query := applyFacetFilters(matched, req, except).
+			Joins("JOIN product_variants v ON v.product_id = p.id").
+			Joins("CROSS JOIN LATERAL jsonb_each_text(CASE WHEN jsonb_typeof(v.options) = 'object' THEN v.options ELSE '{}'::jsonb END) AS opt(key, value)")
+		if condition, args := variantOptionSQL(req.Options, skipKey); condition != "" {
+			query = query.Where(condition, args...)
+		}
+
+		var counts []optionCount
+		err := where(query).
+			Select("opt.key, opt.value, COUNT(DISTINCT p.id) AS count").
+			Group("opt.key, opt.value").
+			Scan(&counts).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to count variant options: %w", err)
+		}
+		return counts, nil
+	}
+
+	// Keys without a selection are counted against every selected option
+	selectedKeys := selectedOptionKeys(req.Options)
+	counts, err := count(FacetOption, "", func(query *gorm.DB) *gorm.DB {
+		if len(selectedKeys) > 0 {
+			return query.Where("opt.key NOT IN ?", selectedKeys)
+		}
+		return query
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// A selected key is counted against the other selected options only
+	for _, key := range selectedKeys {
+		keyCounts, err := count(optionFacetPrefix+key, key, func(query *gorm.DB) *gorm.DB {
+			return query.Where("opt.key = ?", key)
+		})
+		if err != nil {
+			return nil, err
+		}
+		counts = append(counts, keyCounts...)
+	}
+
+	byKey := make(map[string]map[string]int64)
+	for _, c := range counts {
+		if byKey[c.Key] == nil {
+			byKey[c.Key] = make(map[string]int64)
+		}
+		byKey[c.Key][c.Value] = c.Count
+	}
+	return optionFilters(byKey, req), nil
+}
+
+// attributeFacets returns one facet per filterable product attribute counting
+// the matched products with each value
+func attributeFacets(matched *gorm.DB, req *ProductSearchRequest) ([]Filter, error) {
+	type attributeCount struct {
+		Code  string
+		Value string
+		Count int64
+	}
+
+	count := func(except string, where func(*gorm.DB) *gorm.DB) ([]attributeCount, error) {
+		query := applyFacetFilters(matched, req, except).
+			Joins("CROSS JOIN LATERAL jsonb_each_text(CASE WHEN jsonb_typeof(p.attributes) = 'object' THEN p.attributes ELSE '{}'::jsonb END) AS attr(code, value)").
+			Joins("JOIN attribute_definitions d ON d.tenant_id = p.tenant_id AND d.code = attr.code AND d.filterable = TRUE")
+
+		var counts []attributeCount
+		err := where(query).
+			Select("attr.code, attr.value, COUNT(*) AS count").
+			Group("attr.code, attr.value").
+			Scan(&counts).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to count attributes: %w", err)
+		}
+		return counts, nil
+	}
+
+	// Codes without a selection are counted against every selected attribute
+	selectedCodes := selectedOptionKeys(req.Attributes)
+	counts, err := count("", func(query *gorm.DB) *gorm.DB {
+		if len(selectedCodes) > 0 {
+			return query.Where("attr.code NOT IN ?", selectedCodes)
+		}
+		return query
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// A selected code is counted against the other selected attributes only
+	for _, code := range selectedCodes {
+		codeCounts, err := count(attributeFacetPrefix+code, func(query *gorm.DB) *gorm.DB {
+			return query.Where("attr.code = ?", code)
+		})
+		if err != nil {
+			return nil, err
+		}
+		counts = append(counts, codeCounts...)
+	}
+
+	byCode := make(map[string]map[string]int64)
+	for _, c := range counts {
+		if byCode[c.Code] == nil {
+			byCode[c.Code] = make(map[string]int64)
+		}
+		byCode[c.Code][c.Value] = c.Count
+	}
+	return attributeFilters(byCode, req), nil
+}
+
+// Facet builders shared by the search index backends
+
+// categoryNode is a category as needed to build the category facet tree
+type categoryNode struct {
+	ID       string
+	ParentID *string
+	Name     string
+}
+
+// categoryNodes returns every category of a tenant in display order
+func (r *repository) categoryNodes(ctx context.Context, tenantID uuid.UUID) ([]categoryNode, error) {
+	var nodes []categoryNode
+	err := r.db.WithContext(ctx).Table("categories").
+		Select("id, parent_id, name").
+		Where("tenant_id = ?", tenantID).
+		Order("sort_order ASC, name ASC").
+		Scan(&nodes).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+	return nodes, nil
+}
+
+// categoryFilter builds the category facet tree. With rollup the counts are
+// per category and are summed up the tree; without it each count already
+// includes the subcategories.
+func categoryFilter(nodes []categoryNode, counts map[string]int64, rollup bool, req *ProductSearchRequest) *Filter {
+	known := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		known[node.ID] = true
+	}
+	children := make(map[string][]int)
+	for i, node := range nodes {
+		parent := ""
+		if node.ParentID != nil && known[*node.ParentID] {
+			parent = *node.ParentID
+		}
+		children[parent] = append(children[parent], i)
+	}
+
+	selected := make(map[string]bool, len(req.CategoryIDs))
+	for _, id := range req.CategoryIDs {
+		selected[id] = true
+	}
+
+	visited := make(map[string]bool)
+	var build func(parent string) ([]FilterValue, int64)
+	build = func(parent string) ([]FilterValue, int64) {
+		var values []FilterValue
+		var total int64
+		for _, i := range children[parent] {
+			node := nodes[i]
+			if visited[node.ID] {
+				continue
+			}
+			visited[node.ID] = true
+
+			subtree, subtotal := build(node.ID)
+			count := counts[node.ID]
+			if rollup {
+				count += subtotal
+			}
+			total += count
+			if count == 0 {
+				continue
+			}
+			values = append(values, FilterValue{
+				Value:    node.ID,
+				Label:    node.Name,
+				Count:    count,
+				Selected: selected[node.ID],
+				Children: subtree,
+			})
+		}
+		return values, total
+	}
+
+	values, _ := build("")
+	if len(values) == 0 {
+		return nil
+	}
+	return &Filter{
+		ID:       FacetCategory,
+		Type:     FacetCategory,
+		Name:     "Category",
+		Values:   values,
+		IsActive: true,
+	}
+}
+
+// collectionNode is a published collection as needed to label the collection
+// facet
+type collectionNode struct {
+	ID    string
+	Title string
+}
+
+// collectionNodes returns the published collections of a tenant
+func (r *repository) collectionNodes(ctx context.Context, tenantID uuid.UUID) ([]collectionNode, error) {
+	var nodes []collectionNode
+	err := r.db.WithContext(ctx).Table("collections").
+		Select("id, title").
+		Where("tenant_id = ? AND is_published = TRUE", tenantID).
+		Order("title ASC").
+		Scan(&nodes).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collections: %w", err)
+	}
+	return nodes, nil
+}
+
+// collectionFilter builds the collection facet from product counts by
+// collection, largest first
+func collectionFilter(nodes []collectionNode, counts map[string]int64, req *ProductSearchRequest) *Filter {
+	var values []FilterValue
+	for _, node := range nodes {
+		if counts[node.ID] == 0 {
+			continue
+		}
+		values = append(values, FilterValue{
+			Value:    node.ID,
+			Label:    node.Title,
+			Count:    counts[node.ID],
+			Selected: containsString(req.CollectionIDs, node.ID),
+		})
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if values[i].Count != values[j].Count {
+			return values[i].Count > values[j].Count
+		}
+		return values[i].Label < values[j].Label
+	})
+
+	return &Filter{
+		ID:       FacetCollection,
+		Type:     FacetCollection,
+		Name:     "Collection",
+		Values:   values,
+		IsActive: true,
+	}
+}
+
+// priceBucket counts the prices in [Bucket*width, (Bucket+1)*width)
+type priceBucket struct {
+	Bucket int64
+	Count  int64
+}
+
+// priceFilter builds the price facet from buckets sorted by Bucket
+func priceFilter(width float64, buckets []priceBucket, req *ProductSearchRequest) *Filter {
+	var values []FilterValue
+	for _, bucket := range buckets {
+		if bucket.Count == 0 {
+			continue
+		}
+		from := float64(bucket.Bucket) * width
+		to := from + width
+		values = append(values, FilterValue{
+			Value:    formatPrice(from) + "-" + formatPrice(to),
+			Label:    formatPrice(from) + " - " + formatPrice(to),
+			Count:    bucket.Count,
+			Selected: priceRangeSelected(req.PriceRanges, from, to),
+			From:     &from,
+			To:       &to,
+		})
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	return &Filter{
+		ID:       FacetPrice,
+		Type:     "range",
+		Name:     "Price",
+		Values:   values,
+		IsActive: true,
+	}
+}
+
+// availabilityFilter builds the availability facet, in stock first
+func availabilityFilter(inStock, outOfStock int64, req *ProductSearchRequest) *Filter {
+	var values []FilterValue
+	if inStock > 0 {
+		values = append(values, FilterValue{
+			Value:    AvailabilityInStock,
+			Label:    "In stock",
+			Count:    inStock,
+			Selected: containsString(req.Availability, AvailabilityInStock),
+		})
+	}
+	if outOfStock > 0 {
+		values = append(values, FilterValue{
+			Value:    AvailabilityOutOfStock,
+			Label:    "Out of stock",
+			Count:    outOfStock,
+			Selected: containsString(req.Availability, AvailabilityOutOfStock),
+		})
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	return &Filter{
+		ID:       FacetAvailability,
+		Type:     FacetAvailability,
+		Name:     "Availability",
+		Values:   values,
+		IsActive: true,
+	}
+}
+
+// ratingFilter builds the rating facet from the number of products rated N
+// stars and up, best first
+func ratingFilter(counts map[int]int64, req *ProductSearchRequest) *Filter {
+	var values []FilterValue
+	for stars := 4; stars >= 1; stars-- {
+		if counts[stars] == 0 {
+			continue
+		}
+		values = append(values, FilterValue{
+			Value:    strconv.Itoa(stars),
+			Label:    fmt.Sprintf("%d stars & up", stars),
+			Count:    counts[stars],
+			Selected: req.Rating != nil && *req.Rating == float64(stars),
+		})
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	return &Filter{
+		ID:       FacetRating,
+		Type:     FacetRating,
+		Name:     "Rating",
+		Values:   values,
+		IsActive: true,
+	}
+}
+
+// optionFilters builds one facet per option key from product counts by key
+// and value, most common values first
+func optionFilters(counts map[string]map[string]int64, req *ProductSearchRequest) []Filter {
+	return valueFilters(FacetOption, counts, req.Options)
+}
+
+// attributeFilters builds one facet per attribute code from product counts by
+// code and value. The service names and orders them from the definitions.
+func attributeFilters(counts map[string]map[string]int64, req *ProductSearchRequest) []Filter {
+	return valueFilters(FacetAttribute, counts, req.Attributes)
+}
+
+// valueFilters builds the facets of a keyed facet type, one per key, marking
+// the selected values
+func valueFilters(facetType string, counts map[string]map[string]int64, selected map[string][]string) []Filter {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	facets := make([]Filter, 0, len(keys))
+	for _, key := range keys {
+		var values []FilterValue
+		for value, count := range counts[key] {
+			if count == 0 {
+				continue
+			}
+			values = append(values, FilterValue{
+				Value:    value,
+				Label:    value,
+				Count:    count,
+				Selected: containsString(selected[key], value),
+			})
+		}
+		if len(values) == 0 {
+			continue
+		}
+		sort.Slice(values, func(i, j int) bool {
+			if values[i].Count != values[j].Count {
+				return values[i].Count > values[j].Count
+			}
+			return values[i].Value < values[j].Value
+		})
+		facets = append(facets, Filter{
+			ID:       facetType + "." + key,
+			Type:     facetType,
+			Name:     key,
+			Values:   values,
+			IsActive: true,
+		})
+	}
+	return facets
+}
+
+// attributeField is a filterable product attribute as needed to name, order
+// and label its facet
+type attributeField struct {
+	Code      string
+	Name      string
+	Type      string
+	Unit      string
+	SortOrder int
+}
+
+// labelAttributeFacets names the attribute facets after their definitions and
+// puts them after the other facets in the definitions' order. Facets of
+// attributes that are no longer filterable are dropped.
+func labelAttributeFacets(facets []Filter, fields []attributeField) []Filter {
+	position := make(map[string]int, len(fields))
+	for i, field := range fields {
+		position[field.Code] = i
+	}
+
+	labelled := make([]Filter, 0, len(facets))
+	var attributes []Filter
+	for _, facet := range facets {
+		if facet.Type != FacetAttribute {
+			labelled = append(labelled, facet)
+			continue
+		}
+		i, ok := position[strings.TrimPrefix(facet.ID, attributeFacetPrefix)]
+		if !ok {
+			continue
+		}
+		field := fields[i]
+		facet.Name = field.Name
+		for j := range facet.Values {
+			facet.Values[j].Label = attributeValueLabel(field, facet.Values[j].Value)
+		}
+		attributes = append(attributes, facet)
+	}
+
+	sort.SliceStable(attributes, func(i, j int) bool {
+		return position[strings.TrimPrefix(attributes[i].ID, attributeFacetPrefix)] <
+			position[strings.TrimPrefix(attributes[j].ID, attributeFacetPrefix)]
+	})
+	return append(labelled, attributes...)
+}
+
+// attributeValueLabel shows booleans as yes or no and numbers with their unit
+func attributeValueLabel(field attributeField, value string) string {
+	switch {
+	case field.Type == "boolean" && value == "true":
+		return "Yes"
+	case field.Type == "boolean" && value == "false":
+		return "No"
+	case field.Type == "number" && field.Unit != "":
+		return value + " " + field.Unit
+	}
+	return value
+}
+
+// filterableSelections drops the attribute selections of codes that are not
+// filterable, which search documents do not carry
+func filterableSelections(selected map[string][]string, fields []attributeField) map[string][]string {
+	if len(selected) == 0 {
+		return selected
+	}
+
+	codes := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		codes[field.Code] = true
+	}
+	kept := make(map[string][]string, len(selected))
+	for code, values := range selected {
+		if codes[code] {
+			kept[code] = values
+		}
+	}
+	return kept
+}
+
+// documentAttributes returns the filterable attribute values of a product as
+// strings, formatted the way Postgres renders them with ->>
+func documentAttributes(raw *string, filterable map[string]bool) map[string][]string {
+	attributes := map[string][]string{}
+	if raw == nil {
+		return attributes
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(*raw), &values); err != nil {
+		return attributes
+	}
+	for code, value := range values {
+		if !filterable[code] {
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			attributes[code] = []string{v}
+		case float64:
+			attributes[code] = []string{strconv.FormatFloat(v, 'f', -1, 64)}
+		case bool:
+			attributes[code] = []string{strconv.FormatBool(v)}
+		}
+	}
+	return attributes
+}
+
+// selectedOptionKeys returns the option keys or attribute codes with at least
+// one selected value, sorted so generated SQL is stable
+func selectedOptionKeys(options map[string][]string) []string {
+	var keys []string
+	for key, values := range options {
+		if len(values) > 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// parsePriceRange parses "from-to"; either bound may be left empty
+func parsePriceRange(value string) (*float64, *float64, error) {
+	parts := strings.SplitN(strings.TrimSpace(value), "-", 2)
+	if len(parts) != 2 {
+		return nil, nil, fmt.Errorf("invalid price range %q", value)
+	}
+
+	var bounds [2]*float64
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		bound, err := strconv.ParseFloat(part, 64)
+		if err != nil || bound < 0 {
+			return nil, nil, fmt.Errorf("invalid price range %q", value)
+		}
+		bounds[i] = &bound
+	}
+
+	if bounds[0] == nil && bounds[1] == nil {
+		return nil, nil, fmt.Errorf("invalid price range %q", value)
+	}
+	if bounds[0] != nil && bounds[1] != nil && *bounds[0] >= *bounds[1] {
+		return nil, nil, fmt.Errorf("invalid price range %q", value)
+	}
+	return bounds[0], bounds[1], nil
+}
+
+// priceRangeSQL matches from <= price < to
+func priceRangeSQL(from, to *float64) (string, []interface{}) {
+	switch {
+	case from != nil && to != nil:
+		return "(p.price >= ? AND p.price < ?)", []interface{}{*from, *to}
+	case from != nil:
+		return "p.price >= ?", []interface{}{*from}
+	default:
+		return "p.price < ?", []interface{}{*to}
+	}
+}
+
+func priceRangeSelected(ranges []string, from, to float64) bool {
+	for _, value := range ranges {
+		rangeFrom, rangeTo, err := parsePriceRange(value)
+		if err == nil && rangeFrom != nil && rangeTo != nil &&
+			formatPrice(*rangeFrom) == formatPrice(from) && formatPrice(*rangeTo) == formatPrice(to) {
+			return true
+		}
+	}
+	return false
+}
+
+// priceBucketWidth picks a 1, 2 or 5 times a power of ten width that splits
+// the price span into about priceBuckets buckets
+func priceBucketWidth(min, max float64) float64 {
+	span := max - min
+	if span <= 0 {
+		span = math.Max(max, 1)
+	}
+
+	raw := span / priceBuckets
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, step := range []float64{1, 2, 5} {
+		if raw <= step*magnitude {
+			return step * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+func formatPrice(value float64) string {
+	return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
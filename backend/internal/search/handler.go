@@ -1,6 +1,7 @@
 package search
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -8,6 +9,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // Handler handles search-related HTTP requests
@@ -37,6 +39,22 @@ func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 		// Search filters
 		search.GET("/filters", h.GetFilters)        // GET /search/filters
 		search.POST("/filters", h.ManageFilters)    // POST /search/filters
+		// Search synonyms
+		search.GET("/synonyms", h.ListSynonyms)         // GET /search/synonyms
+		search.POST("/synonyms", h.CreateSynonym)       // POST /search/synonyms
+		search.PUT("/synonyms/:id", h.UpdateSynonym)    // PUT /search/synonyms/:id
+		search.DELETE("/synonyms/:id", h.DeleteSynonym) // DELETE /search/synonyms/:id
+		// Merchandising rules
+		search.GET("/rules", h.ListRules)               // GET /search/rules
+		search.POST("/rules", h.CreateRule)             // POST /search/rules
+		search.PUT("/rules/:id", h.UpdateRule)          // PUT /search/rules/:id
+		search.DELETE("/rules/:id", h.DeleteRule)       // DELETE /search/rules/:id
+		// Click tracking
+		search.POST("/click", h.RecordClick)            // POST /search/click
+		// Search index
+		search.POST("/reindex", h.Reindex)              // POST /search/reindex
+		search.POST("/index/sync", h.SyncIndex)         // POST /search/index/sync
+		search.GET("/index/health", h.IndexHealth)      // GET /search/index/health
 	}
 }
 
@@ -98,7 +116,7 @@ func (h *Handler) Search(c *gin.Context) {
 	}
 
 	// Perform search
-	response, err := h.service.Search(c.Request.Context(), req)
+	response, err := h.service.Search(c, req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -108,14 +126,44 @@ func (h *Handler) Search(c *gin.Context) {
 }
 
 // SearchProducts performs product-specific search with advanced filters
-// GET /search/products?q=query&category_id=uuid&brand_id=uuid&tags=tag1,tag2&min_price=10&max_price=100&in_stock=true&on_sale=true&min_rating=4&sort_by=price_asc&offset=0&limit=20&include_facets=true
+// GET /search/products?q=query&category_id=uuid1,uuid2&collection_id=uuid1,uuid2&tags=tag1,tag2&min_price=10&max_price=100&price=0-500,500-1000&availability=in_stock&on_sale=true&min_rating=4&option.size=M,L&attribute.ram=8,16&sort_by=price_asc&offset=0&limit=20&include_facets=true
 func (h *Handler) SearchProducts(c *gin.Context) {
 	// Parse query parameters
 	req := &ProductSearchRequest{
-		Query:      c.Query("q"),
-		CategoryID: c.Query("category_id"),
-		BrandID:    c.Query("brand_id"),
-		SortBy:     c.Query("sort_by"),
+		Query:   c.Query("q"),
+		BrandID: c.Query("brand_id"),
+		SortBy:  c.Query("sort_by"),
+	}
+
+	// Parse facet selections
+	if categories := c.Query("category_id"); categories != "" {
+		req.CategoryIDs = strings.Split(categories, ",")
+	}
+	if collections := c.Query("collection_id"); collections != "" {
+		req.CollectionIDs = strings.Split(collections, ",")
+	}
+	if prices := c.Query("price"); prices != "" {
+		req.PriceRanges = strings.Split(prices, ",")
+	}
+	if availability := c.Query("availability"); availability != "" {
+		req.Availability = strings.Split(availability, ",")
+	}
+	for key, values := range c.Request.URL.Query() {
+		if len(values) == 0 || values[0] == "" {
+			continue
+		}
+		if option := strings.TrimPrefix(key, optionFacetPrefix); option != key && option != "" {
+			if req.Options == nil {
+				req.Options = make(map[string][]string)
+			}
+			req.Options[option] = strings.Split(values[0], ",")
+		}
+		if code := strings.TrimPrefix(key, attributeFacetPrefix); code != key && code != "" {
+			if req.Attributes == nil {
+				req.Attributes = make(map[string][]string)
+			}
+			req.Attributes[code] = strings.Split(values[0], ",")
+		}
 	}
 
 	// Parse tags
@@ -178,7 +226,7 @@ func (h *Handler) SearchProducts(c *gin.Context) {
 	}
 
 	// Perform product search
-	response, err := h.service.SearchProducts(c.Request.Context(), req)
+	response, err := h.service.SearchProducts(c, req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -210,7 +258,7 @@ func (h *Handler) GetSuggestions(c *gin.Context) {
 	}
 
 	// Get suggestions
-	response, err := h.service.GetSuggestions(c.Request.Context(), req)
+	response, err := h.service.GetSuggestions(c, req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -254,7 +302,7 @@ func (h *Handler) GetAnalytics(c *gin.Context) {
 	}
 
 	// Get analytics
-	response, err := h.service.GetAnalytics(c.Request.Context(), req)
+	response, err := h.service.GetAnalytics(c, req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -272,7 +320,7 @@ func (h *Handler) GetFilters(c *gin.Context) {
 	}
 
 	// Get filters
-	response, err := h.service.GetFilters(c.Request.Context(), searchType)
+	response, err := h.service.GetFilters(c, searchType)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -299,7 +347,115 @@ func (h *Handler) ManageFilters(c *gin.Context) {
 	}
 
 	// Manage filters
-	response, err := h.service.ManageFilters(c.Request.Context(), &req)
+	response, err := h.service.ManageFilters(c, &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ListSynonyms returns the search synonyms of the tenant
+// GET /search/synonyms
+func (h *Handler) ListSynonyms(c *gin.Context) {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	response, err := h.service.ListSynonyms(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateSynonym adds a synonym group
+// POST /search/synonyms
+func (h *Handler) CreateSynonym(c *gin.Context) {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	var req SynonymRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	synonym, err := h.service.CreateSynonym(c, &req)
+	if err != nil {
+		c.JSON(synonymErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, synonym)
+}
+
+// UpdateSynonym replaces the terms of a synonym group
+// PUT /search/synonyms/:id
+func (h *Handler) UpdateSynonym(c *gin.Context) {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid synonym ID"})
+		return
+	}
+
+	var req SynonymRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	synonym, err := h.service.UpdateSynonym(c, id, &req)
+	if err != nil {
+		c.JSON(synonymErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, synonym)
+}
+
+// DeleteSynonym removes a synonym group
+// DELETE /search/synonyms/:id
+func (h *Handler) DeleteSynonym(c *gin.Context) {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid synonym ID"})
+		return
+	}
+
+	if err := h.service.DeleteSynonym(c, id); err != nil {
+		c.JSON(synonymErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Synonym deleted successfully"})
+}
+
+// ListRules returns the merchandising rules of the tenant
+// GET /search/rules
+func (h *Handler) ListRules(c *gin.Context) {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	response, err := h.service.ListRules(c)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -308,8 +464,185 @@ func (h *Handler) ManageFilters(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// CreateRule adds a merchandising rule
+// POST /search/rules
+func (h *Handler) CreateRule(c *gin.Context) {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	var req SearchRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := h.service.CreateRule(c, &req)
+	if err != nil {
+		c.JSON(ruleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateRule replaces a merchandising rule
+// PUT /search/rules/:id
+func (h *Handler) UpdateRule(c *gin.Context) {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rule ID"})
+		return
+	}
+
+	var req SearchRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := h.service.UpdateRule(c, id, &req)
+	if err != nil {
+		c.JSON(ruleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteRule removes a merchandising rule
+// DELETE /search/rules/:id
+func (h *Handler) DeleteRule(c *gin.Context) {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rule ID"})
+		return
+	}
+
+	if err := h.service.DeleteRule(c, id); err != nil {
+		c.JSON(ruleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Search rule deleted successfully"})
+}
+
+// RecordClick records a click on a search result for click-through analytics
+// POST /search/click
+func (h *Handler) RecordClick(c *gin.Context) {
+	var req SearchClickRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.RecordClick(c, &req); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrSearchNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Click recorded"})
+}
+
+// Reindex rebuilds the product search index of the tenant
+// POST /search/reindex
+func (h *Handler) Reindex(c *gin.Context) {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	response, err := h.service.Reindex(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// SyncIndex indexes the products the search index has fallen behind on
+// POST /search/index/sync
+func (h *Handler) SyncIndex(c *gin.Context) {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	response, err := h.service.SyncIndex(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// IndexHealth reports whether the search index is reachable and up to date
+// GET /search/index/health
+func (h *Handler) IndexHealth(c *gin.Context) {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	health, err := h.service.IndexHealth(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := http.StatusOK
+	if !health.Healthy {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, health)
+}
+
 // Helper functions
 
+func isAdmin(c *gin.Context) bool {
+	userRole, exists := c.Get("user_role")
+	return exists && userRole == "admin"
+}
+
+func synonymErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrSynonymNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInvalidSynonym):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func ruleErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrRuleNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInvalidRule):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 func parseDate(dateStr string) (time.Time, error) {
 	// Try different date formats
 	formats := []string{
@@ -0,0 +1,347 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Search index backends
+const (
+	BackendPostgres    = "postgres"
+	BackendMeilisearch = "meilisearch"
+	BackendMemory      = "memory"
+)
+
+// An index more than this far behind the catalog is reported unhealthy
+const maxIndexLag = 15 * time.Minute
+
+// Products loaded and indexed per batch during a reindex or sync
+const indexBatchSize = 500
+
+// SearchIndex is a product search engine. The Postgres index is the default;
+// other backends hold a copy of the active catalog that the Indexer keeps up
+// to date from product change events.
+type SearchIndex interface {
+	// Name returns the backend name
+	Name() string
+
+	// Index adds or replaces product documents
+	Index(ctx context.Context, tenantID uuid.UUID, documents []ProductDocument) error
+
+	// Delete removes products from the index
+	Delete(ctx context.Context, tenantID uuid.UUID, productIDs []uuid.UUID) error
+
+	// Query searches products, returning the page of results, the facets when
+	// requested and the total number of matches
+	Query(ctx context.Context, tenantID uuid.UUID, req *ProductSearchRequest) ([]*SearchResult, []Filter, int64, error)
+
+	// Suggest returns product autocomplete suggestions
+	Suggest(ctx context.Context, tenantID uuid.UUID, query string, limit int) ([]Suggestion, error)
+
+	// Facets returns the facets of the whole catalog
+	Facets(ctx context.Context, tenantID uuid.UUID) ([]Filter, error)
+
+	// SetSynonyms replaces the synonym groups used to expand queries
+	SetSynonyms(ctx context.Context, tenantID uuid.UUID, synonyms []SearchSynonym) error
+
+	// Pending returns products whose indexed copy is missing or out of date
+	Pending(ctx context.Context, tenantID uuid.UUID, limit int) ([]uuid.UUID, error)
+
+	// Health reports whether the index is reachable and how far behind it is
+	Health(ctx context.Context, tenantID uuid.UUID) (*IndexHealth, error)
+}
+
+// ProductDocument is the searchable copy of an active product
+type ProductDocument struct {
+	ID            uuid.UUID           `json:"id"`
+	TenantID      uuid.UUID           `json:"tenant_id"`
+	Name          string              `json:"name"`
+	Slug          string              `json:"slug"`
+	Description   string              `json:"description"`
+	SKU           string              `json:"sku"`
+	Barcode       string              `json:"barcode"`
+	Tags          []string            `json:"tags"`
+	CategoryID    *uuid.UUID          `json:"category_id,omitempty"`
+	CategoryIDs   []string            `json:"category_ids"` // the category and its ancestors, root first
+	CategoryPath  string              `json:"category_path"`
+	CollectionIDs []string            `json:"collection_ids"` // published collections, by title
+	Collections   []string            `json:"collections"`    // titles of CollectionIDs
+	Price         float64             `json:"price"`
+	ComparePrice  *float64            `json:"compare_price,omitempty"`
+	OnSale        bool                `json:"on_sale"`
+	InStock       bool                `json:"in_stock"`
+	Rating        *float64            `json:"rating,omitempty"` // average approved review rating
+	ReviewCount   int                 `json:"review_count"`
+	Margin        *float64            `json:"margin,omitempty"` // gross margin as a share of the price
+	Options       map[string][]string `json:"options"`
+	Attributes    map[string][]string `json:"attributes"` // filterable attribute values as strings
+	FeaturedImage string              `json:"featured_image"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+}
+
+// searchResult converts the document to a product search result
+func (d *ProductDocument) searchResult(score float64, match string) *SearchResult {
+	price := d.Price
+	metadata := map[string]interface{}{
+		"slug":     d.Slug,
+		"sku":      d.SKU,
+		"in_stock": d.InStock,
+		"match":    match,
+	}
+	if d.OnSale && d.ComparePrice != nil {
+		metadata["compare_price"] = *d.ComparePrice
+	}
+	if d.CategoryPath != "" {
+		metadata["category_path"] = d.CategoryPath
+	}
+	if d.Rating != nil {
+		metadata["average_rating"] = *d.Rating
+		metadata["review_count"] = d.ReviewCount
+	}
+
+	return &SearchResult{
+		ID:          d.ID.String(),
+		Type:        "product",
+		Title:       d.Name,
+		Description: d.Description,
+		URL:         fmt.Sprintf("/products/%s", d.ID),
+		ImageURL:    d.FeaturedImage,
+		Price:       &price,
+		Score:       score,
+		Metadata:    metadata,
+		CreatedAt:   d.CreatedAt,
+		UpdatedAt:   d.UpdatedAt,
+	}
+}
+
+// IndexHealth reports the state of a tenant's search index
+type IndexHealth struct {
+	Backend       string     `json:"backend"`
+	Healthy       bool       `json:"healthy"`
+	Products      int64      `json:"products"`  // active products in the catalog
+	Documents     int64      `json:"documents"` // documents held by the index
+	Pending       int64      `json:"pending"`   // products waiting to be indexed or removed
+	LagSeconds    int64      `json:"lag_seconds"`
+	LastIndexedAt *time.Time `json:"last_indexed_at,omitempty"`
+	Indexing      bool       `json:"indexing"`
+	Error         string     `json:"error,omitempty"`
+}
+
+// IndexConfig selects and configures the search index backend
+type IndexConfig struct {
+	Backend     string
+	URL         string
+	APIKey      string
+	IndexPrefix string
+}
+
+// NewIndex creates the search index for a backend; an empty backend is
+// Postgres
+func NewIndex(db *gorm.DB, cfg IndexConfig) (SearchIndex, error) {
+	repo := NewRepository(db)
+
+	switch strings.ToLower(cfg.Backend) {
+	case "", BackendPostgres:
+		return NewPostgresIndex(repo), nil
+	case BackendMeilisearch:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("search backend %s requires a URL", cfg.Backend)
+		}
+		return NewMeilisearchIndex(repo, cfg.URL, cfg.APIKey, cfg.IndexPrefix), nil
+	case BackendMemory:
+		return NewMemoryIndex(), nil
+	default:
+		return nil, fmt.Errorf("unsupported search backend: %s", cfg.Backend)
+	}
+}
+
+// IndexResult reports a reindex or sync run
+type IndexResult struct {
+	Indexed int64 `json:"indexed"`
+	Deleted int64 `json:"deleted"`
+}
+
+// Indexer keeps a search index in step with the catalog. It listens to
+// product change events for incremental updates and can rebuild or catch up
+// a tenant's index.
+type Indexer struct {
+	index SearchIndex
+	repo  Repository
+}
+
+// NewIndexer creates an indexer writing to index
+func NewIndexer(index SearchIndex, repo Repository) *Indexer {
+	return &Indexer{
+		index: index,
+		repo:  repo,
+	}
+}
+
+// ProductsChanged re-indexes changed products. Products that are no longer
+// active are removed from the index.
+func (i *Indexer) ProductsChanged(tenantID uuid.UUID, productIDs []uuid.UUID) error {
+	_, err := i.indexProducts(context.Background(), tenantID, productIDs)
+	return err
+}
+
+// ProductsDeleted removes deleted products from the index
+func (i *Indexer) ProductsDeleted(tenantID uuid.UUID, productIDs []uuid.UUID) error {
+	if len(productIDs) == 0 {
+		return nil
+	}
+	return i.index.Delete(context.Background(), tenantID, productIDs)
+}
+
+// CategoriesChanged re-indexes the products of the categories and their
+// subcategories, whose category paths may have changed
+func (i *Indexer) CategoriesChanged(tenantID uuid.UUID, categoryIDs []uuid.UUID) error {
+	ctx := context.Background()
+	productIDs, err := i.repo.ProductIDsInCategories(ctx, tenantID, categoryIDs)
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(productIDs); start += indexBatchSize {
+		end := start + indexBatchSize
+		if end > len(productIDs) {
+			end = len(productIDs)
+		}
+		if _, err := i.indexProducts(ctx, tenantID, productIDs[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reindex rebuilds the index of every active product of a tenant
+func (i *Indexer) Reindex(ctx context.Context, tenantID uuid.UUID) (*IndexResult, error) {
+	synonyms, err := i.repo.ListSynonyms(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if err := i.index.SetSynonyms(ctx, tenantID, synonyms); err != nil {
+		return nil, fmt.Errorf("failed to set synonyms: %w", err)
+	}
+
+	result := &IndexResult{}
+	after := uuid.Nil
+	for {
+		productIDs, err := i.repo.ListProductIDs(ctx, tenantID, after, indexBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(productIDs) == 0 {
+			break
+		}
+
+		batch, err := i.indexProducts(ctx, tenantID, productIDs)
+		if err != nil {
+			return nil, err
+		}
+		result.Indexed += batch.Indexed
+		result.Deleted += batch.Deleted
+		after = productIDs[len(productIDs)-1]
+	}
+
+	// Products that left the catalog while the index was not listening
+	synced, err := i.Sync(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	result.Indexed += synced.Indexed
+	result.Deleted += synced.Deleted
+
+	return result, nil
+}
+
+// Sync indexes the products the index reports as pending, catching up on
+// changes missed while it was unreachable
+func (i *Indexer) Sync(ctx context.Context, tenantID uuid.UUID) (*IndexResult, error) {
+	result := &IndexResult{}
+	seen := make(map[uuid.UUID]bool)
+	for {
+		pending, err := i.index.Pending(ctx, tenantID, indexBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pending products: %w", err)
+		}
+
+		// Stop when a batch brings nothing new, so products the index keeps
+		// reporting cannot loop forever
+		var productIDs []uuid.UUID
+		for _, id := range pending {
+			if !seen[id] {
+				seen[id] = true
+				productIDs = append(productIDs, id)
+			}
+		}
+		if len(productIDs) == 0 {
+			return result, nil
+		}
+
+		batch, err := i.indexProducts(ctx, tenantID, productIDs)
+		if err != nil {
+			return nil, err
+		}
+		result.Indexed += batch.Indexed
+		result.Deleted += batch.Deleted
+	}
+}
+
+// Health reports the state of a tenant's index
+func (i *Indexer) Health(ctx context.Context, tenantID uuid.UUID) (*IndexHealth, error) {
+	return i.index.Health(ctx, tenantID)
+}
+
+// indexProducts indexes the active products among productIDs and removes the
+// others from the index
+func (i *Indexer) indexProducts(ctx context.Context, tenantID uuid.UUID, productIDs []uuid.UUID) (*IndexResult, error) {
+	if len(productIDs) == 0 {
+		return &IndexResult{}, nil
+	}
+
+	documents, err := i.repo.GetProductDocuments(ctx, tenantID, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	active := make(map[uuid.UUID]bool, len(documents))
+	for _, document := range documents {
+		active[document.ID] = true
+	}
+	var removed []uuid.UUID
+	for _, id := range productIDs {
+		if !active[id] {
+			removed = append(removed, id)
+		}
+	}
+
+	if len(documents) > 0 {
+		if err := i.index.Index(ctx, tenantID, documents); err != nil {
+			return nil, fmt.Errorf("failed to index products: %w", err)
+		}
+	}
+	if len(removed) > 0 {
+		if err := i.index.Delete(ctx, tenantID, removed); err != nil {
+			return nil, fmt.Errorf("failed to remove products from index: %w", err)
+		}
+	}
+
+	return &IndexResult{
+		Indexed: int64(len(documents)),
+		Deleted: int64(len(removed)),
+	}, nil
+}
+
+// logIndexError logs a failed index update without failing the caller
+func logIndexError(action string, err error) {
+	if err != nil {
+		log.Printf("Failed to %s: %v", action, err)
+	}
+}
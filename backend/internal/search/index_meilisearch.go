@@ -0,0 +1,699 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Attributes of the Meilisearch product index. Searchable attributes are in
+// ranking order, matching the weights of the Postgres search documents.
+var (
+	meiliSearchableAttributes = []string{"name", "sku", "barcode", "tags", "category_path", "description"}
+	meiliFilterableAttributes = []string{"id", "category_ids", "collection_ids", "price", "on_sale", "in_stock", "rating", "rating_stars", "tags", "option_values", "attribute_values"}
+	meiliSortableAttributes   = []string{"price", "created_unix", "rating", "review_count"}
+)
+
+// Meilisearch attribute counted by each facet
+var meiliFacetAttributes = map[string]string{
+	FacetCategory:     "category_ids",
+	FacetCollection:   "collection_ids",
+	FacetPrice:        "price",
+	FacetAvailability: "in_stock",
+	FacetRating:       "rating_stars",
+	FacetOption:       "option_values",
+	FacetAttribute:    "attribute_values",
+}
+
+// Hits re-ranked in process when merchandising boosts apply, since boosts
+// cannot be expressed as Meilisearch ranking rules
+const meiliBoostWindow = 1000
+
+var errMeiliIndexNotFound = errors.New("meilisearch index not found")
+
+// meilisearchIndex keeps one Meilisearch index per tenant. Meilisearch applies
+// writes asynchronously; a product counts as indexed once its write is
+// accepted, and search_index_states records when that was so missed updates
+// can be synced and lag reported.
+type meilisearchIndex struct {
+	repo       Repository
+	baseURL    string
+	apiKey     string
+	prefix     string
+	client     *http.Client
+	configured sync.Map // tenant index uid to true once its settings are applied
+}
+
+// NewMeilisearchIndex creates a search index stored in a Meilisearch server
+func NewMeilisearchIndex(repo Repository, baseURL, apiKey, prefix string) SearchIndex {
+	return &meilisearchIndex{
+		repo:    repo,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		prefix:  prefix,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// meiliDocument is a product document with the derived fields Meilisearch
+// filters and sorts on
+type meiliDocument struct {
+	ProductDocument
+	OptionValues    []string `json:"option_values"`    // "key=value" for every variant option
+	AttributeValues []string `json:"attribute_values"` // "code=value" for every filterable attribute
+	RatingStars     *int     `json:"rating_stars,omitempty"`
+	CreatedUnix     int64    `json:"created_unix"`
+}
+
+type meiliQuery struct {
+	IndexUID             string   `json:"indexUid"`
+	Q                    string   `json:"q"`
+	Filter               string   `json:"filter,omitempty"`
+	Facets               []string `json:"facets,omitempty"`
+	Sort                 []string `json:"sort,omitempty"`
+	Offset               int      `json:"offset"`
+	Limit                int      `json:"limit"`
+	AttributesToRetrieve []string `json:"attributesToRetrieve,omitempty"`
+	ShowRankingScore     bool     `json:"showRankingScore,omitempty"`
+}
+
+type meiliResult struct {
+	Hits []struct {
+		ProductDocument
+		RankingScore float64 `json:"_rankingScore"`
+	} `json:"hits"`
+	EstimatedTotalHits int64                       `json:"estimatedTotalHits"`
+	FacetDistribution  map[string]map[string]int64 `json:"facetDistribution"`
+	FacetStats         map[string]struct {
+		Min float64 `json:"min"`
+		Max float64 `json:"max"`
+	} `json:"facetStats"`
+}
+
+func (m *meilisearchIndex) Name() string {
+	return BackendMeilisearch
+}
+
+func (m *meilisearchIndex) Index(ctx context.Context, tenantID uuid.UUID, documents []ProductDocument) error {
+	if len(documents) == 0 {
+		return nil
+	}
+	if err := m.ensureIndex(ctx, tenantID); err != nil {
+		return err
+	}
+
+	payload := make([]meiliDocument, len(documents))
+	productIDs := make([]uuid.UUID, len(documents))
+	for i, document := range documents {
+		payload[i] = meiliDocument{
+			ProductDocument: document,
+			OptionValues:    []string{},
+			AttributeValues: []string{},
+			CreatedUnix:     document.CreatedAt.Unix(),
+		}
+		for key, values := range document.Options {
+			for _, value := range values {
+				payload[i].OptionValues = append(payload[i].OptionValues, key+"="+value)
+			}
+		}
+		for code, values := range document.Attributes {
+			for _, value := range values {
+				payload[i].AttributeValues = append(payload[i].AttributeValues, code+"="+value)
+			}
+		}
+		if document.Rating != nil {
+			stars := int(math.Floor(*document.Rating))
+			payload[i].RatingStars = &stars
+		}
+		productIDs[i] = document.ID
+	}
+
+	if err := m.do(ctx, http.MethodPost, "/indexes/"+m.indexUID(tenantID)+"/documents?primaryKey=id", payload, nil); err != nil {
+		return err
+	}
+	return m.repo.MarkIndexed(ctx, m.Name(), tenantID, productIDs)
+}
+
+func (m *meilisearchIndex) Delete(ctx context.Context, tenantID uuid.UUID, productIDs []uuid.UUID) error {
+	if len(productIDs) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(productIDs))
+	for i, id := range productIDs {
+		ids[i] = id.String()
+	}
+	err := m.do(ctx, http.MethodPost, "/indexes/"+m.indexUID(tenantID)+"/documents/delete-batch", ids, nil)
+	if err != nil && !errors.Is(err, errMeiliIndexNotFound) {
+		return err
+	}
+	return m.repo.ClearIndexed(ctx, m.Name(), tenantID, productIDs)
+}
+
+func (m *meilisearchIndex) Query(ctx context.Context, tenantID uuid.UUID, req *ProductSearchRequest) ([]*SearchResult, []Filter, int64, error) {
+	tokens := tokenize(req.Query)
+	if len(tokens) == 0 {
+		return []*SearchResult{}, nil, 0, nil
+	}
+	return m.search(ctx, tenantID, strings.Join(tokens, " "), req)
+}
+
+func (m *meilisearchIndex) Suggest(ctx context.Context, tenantID uuid.UUID, query string, limit int) ([]Suggestion, error) {
+	results, err := m.multiSearch(ctx, []meiliQuery{{
+		IndexUID:             m.indexUID(tenantID),
+		Q:                    normalizeSearchText(query),
+		Limit:                limit,
+		AttributesToRetrieve: []string{"name"},
+		ShowRankingScore:     true,
+	}})
+	if errors.Is(err, errMeiliIndexNotFound) {
+		return []Suggestion{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]Suggestion, 0, len(results[0].Hits))
+	for _, hit := range results[0].Hits {
+		suggestions = append(suggestions, Suggestion{
+			Text:  hit.Name,
+			Type:  "product",
+			Score: hit.RankingScore,
+		})
+	}
+	return suggestions, nil
+}
+
+// Facets counts the whole catalog with a placeholder search
+func (m *meilisearchIndex) Facets(ctx context.Context, tenantID uuid.UUID) ([]Filter, error) {
+	_, facets, _, err := m.search(ctx, tenantID, "", &ProductSearchRequest{IncludeFacets: true})
+	return facets, err
+}
+
+// SetSynonyms maps every term of a group to the others; a one-way group only
+// maps its first term
+func (m *meilisearchIndex) SetSynonyms(ctx context.Context, tenantID uuid.UUID, synonyms []SearchSynonym) error {
+	if err := m.ensureIndex(ctx, tenantID); err != nil {
+		return err
+	}
+
+	mapped := make(map[string][]string)
+	for _, synonym := range synonyms {
+		terms := make([]string, 0, len(synonym.Terms))
+		for _, term := range synonym.Terms {
+			if words := tokenize(term); len(words) > 0 {
+				terms = appendUnique(terms, strings.Join(words, " "))
+			}
+		}
+		for i, term := range terms {
+			if synonym.OneWay && i > 0 {
+				break
+			}
+			for _, other := range terms {
+				if other != term {
+					mapped[term] = appendUnique(mapped[term], other)
+				}
+			}
+		}
+	}
+
+	return m.do(ctx, http.MethodPut, "/indexes/"+m.indexUID(tenantID)+"/settings/synonyms", mapped, nil)
+}
+
+func (m *meilisearchIndex) Pending(ctx context.Context, tenantID uuid.UUID, limit int) ([]uuid.UUID, error) {
+	return m.repo.PendingProducts(ctx, m.Name(), tenantID, limit)
+}
+
+func (m *meilisearchIndex) Health(ctx context.Context, tenantID uuid.UUID) (*IndexHealth, error) {
+	health := &IndexHealth{Backend: m.Name()}
+
+	var stats struct {
+		NumberOfDocuments int64 `json:"numberOfDocuments"`
+		IsIndexing        bool  `json:"isIndexing"`
+	}
+	if err := m.do(ctx, http.MethodGet, "/health", nil, nil); err != nil {
+		health.Error = err.Error()
+	} else if err := m.do(ctx, http.MethodGet, "/indexes/"+m.indexUID(tenantID)+"/stats", nil, &stats); err != nil && !errors.Is(err, errMeiliIndexNotFound) {
+		health.Error = err.Error()
+	}
+
+	state, err := m.repo.IndexStatus(ctx, m.Name(), tenantID)
+	if err != nil {
+		return nil, err
+	}
+	applyIndexState(health, state)
+
+	if health.Error == "" {
+		health.Documents = stats.NumberOfDocuments
+		health.Indexing = stats.IsIndexing
+	}
+	return health, nil
+}
+
+// search runs the query with its facet selections. Each selected facet is
+// counted by a further query without its own selection, all sent in one
+// multi-search request.
+func (m *meilisearchIndex) search(ctx context.Context, tenantID uuid.UUID, q string, req *ProductSearchRequest) ([]*SearchResult, []Filter, int64, error) {
+	uid := m.indexUID(tenantID)
+
+	main := meiliQuery{
+		IndexUID:         uid,
+		Q:                q,
+		Filter:           meiliFilter(req, ""),
+		Sort:             meiliSort(req.SortBy),
+		Offset:           req.Offset,
+		Limit:            req.Limit,
+		ShowRankingScore: true,
+	}
+	boosted := len(req.Boosts) > 0 && len(main.Sort) == 0
+	if boosted {
+		main.Offset = 0
+		main.Limit = meiliBoostWindow
+	}
+	queries := []meiliQuery{main}
+
+	// Facet to the index of the query its counts come from
+	sources := map[string]int{}
+	if req.IncludeFacets {
+		queries[0].Facets = []string{"category_ids", "collection_ids", "price", "in_stock", "rating_stars", "option_values", "attribute_values"}
+
+		var selected []string
+		if len(req.CategoryIDs) > 0 {
+			selected = append(selected, FacetCategory)
+		}
+		if len(req.CollectionIDs) > 0 {
+			selected = append(selected, FacetCollection)
+		}
+		if len(req.PriceRanges) > 0 {
+			selected = append(selected, FacetPrice)
+		}
+		if len(req.Availability) == 1 {
+			selected = append(selected, FacetAvailability)
+		}
+		if req.Rating != nil {
+			selected = append(selected, FacetRating)
+		}
+		for _, key := range selectedOptionKeys(req.Options) {
+			selected = append(selected, optionFacetPrefix+key)
+		}
+		for _, code := range selectedOptionKeys(req.Attributes) {
+			selected = append(selected, attributeFacetPrefix+code)
+		}
+
+		for _, facet := range selected {
+			attribute := meiliFacetAttributes[facet]
+			if strings.HasPrefix(facet, optionFacetPrefix) {
+				attribute = meiliFacetAttributes[FacetOption]
+			} else if strings.HasPrefix(facet, attributeFacetPrefix) {
+				attribute = meiliFacetAttributes[FacetAttribute]
+			}
+			sources[facet] = len(queries)
+			queries = append(queries, meiliQuery{
+				IndexUID: uid,
+				Q:        q,
+				Filter:   meiliFilter(req, facet),
+				Facets:   []string{attribute},
+				Limit:    0,
+			})
+		}
+	}
+
+	results, err := m.multiSearch(ctx, queries)
+	if errors.Is(err, errMeiliIndexNotFound) {
+		return []*SearchResult{}, nil, 0, nil
+	}
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	hits := make([]*SearchResult, 0, len(results[0].Hits))
+	for _, hit := range results[0].Hits {
+		score := hit.RankingScore
+		if boosted {
+			score *= boostFactor(&hit.ProductDocument, req.Boosts)
+		}
+		hits = append(hits, hit.searchResult(score, MatchFullText))
+	}
+	if boosted {
+		sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+		hits = hits[min(req.Offset, len(hits)):min(req.Offset+req.Limit, len(hits))]
+	}
+	total := results[0].EstimatedTotalHits
+
+	if !req.IncludeFacets {
+		return hits, nil, total, nil
+	}
+
+	source := func(facet string) *meiliResult {
+		if i, ok := sources[facet]; ok {
+			return &results[i]
+		}
+		return &results[0]
+	}
+
+	var facets []Filter
+
+	if counts := source(FacetCategory).FacetDistribution["category_ids"]; len(counts) > 0 {
+		nodes, err := m.repo.CategoryNodes(ctx, tenantID)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		// Documents list every ancestor category, so counts are already rolled up
+		if facet := categoryFilter(nodes, counts, false, req); facet != nil {
+			facets = append(facets, *facet)
+		}
+	}
+
+	if counts := source(FacetCollection).FacetDistribution["collection_ids"]; len(counts) > 0 {
+		nodes, err := m.repo.CollectionNodes(ctx, tenantID)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		if facet := collectionFilter(nodes, counts, req); facet != nil {
+			facets = append(facets, *facet)
+		}
+	}
+
+	price, err := m.priceFacet(ctx, uid, q, source(FacetPrice), req)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if price != nil {
+		facets = append(facets, *price)
+	}
+
+	availability := source(FacetAvailability).FacetDistribution["in_stock"]
+	if facet := availabilityFilter(availability["true"], availability["false"], req); facet != nil {
+		facets = append(facets, *facet)
+	}
+
+	ratings := make(map[int]int64)
+	for value, count := range source(FacetRating).FacetDistribution["rating_stars"] {
+		stars, err := strconv.Atoi(value)
+		if err != nil {
+			continue
+		}
+		for threshold := 1; threshold <= 4 && threshold <= stars; threshold++ {
+			ratings[threshold] += count
+		}
+	}
+	if facet := ratingFilter(ratings, req); facet != nil {
+		facets = append(facets, *facet)
+	}
+
+	options := make(map[string]map[string]int64)
+	meiliValueCounts(options, results[0].FacetDistribution["option_values"], req.Options, "")
+	for _, key := range selectedOptionKeys(req.Options) {
+		distribution := source(optionFacetPrefix + key).FacetDistribution
+		meiliValueCounts(options, distribution["option_values"], req.Options, key)
+	}
+	facets = append(facets, optionFilters(options, req)...)
+
+	attributes := make(map[string]map[string]int64)
+	meiliValueCounts(attributes, results[0].FacetDistribution["attribute_values"], req.Attributes, "")
+	for _, code := range selectedOptionKeys(req.Attributes) {
+		distribution := source(attributeFacetPrefix + code).FacetDistribution
+		meiliValueCounts(attributes, distribution["attribute_values"], req.Attributes, code)
+	}
+	facets = append(facets, attributeFilters(attributes, req)...)
+
+	return hits, facets, total, nil
+}
+
+// meiliValueCounts adds "key=value" facet counts to counts by key and value.
+// With only set just that key is added; otherwise keys with a selection are
+// skipped, since they are counted by their own query.
+func meiliValueCounts(counts map[string]map[string]int64, distribution map[string]int64, selected map[string][]string, only string) {
+	for value, count := range distribution {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if only != "" && parts[0] != only {
+			continue
+		}
+		if only == "" && len(selected[parts[0]]) > 0 {
+			continue
+		}
+		if counts[parts[0]] == nil {
+			counts[parts[0]] = make(map[string]int64)
+		}
+		counts[parts[0]][parts[1]] = count
+	}
+}
+
+// priceFacet buckets prices between the bounds Meilisearch reports, counting
+// each bucket with a further query
+func (m *meilisearchIndex) priceFacet(ctx context.Context, uid, q string, source *meiliResult, req *ProductSearchRequest) (*Filter, error) {
+	stats, ok := source.FacetStats["price"]
+	if !ok {
+		return nil, nil
+	}
+
+	width := priceBucketWidth(stats.Min, stats.Max)
+	first := int64(math.Floor(stats.Min / width))
+	last := int64(math.Floor(stats.Max / width))
+
+	filter := meiliFilter(req, FacetPrice)
+	var queries []meiliQuery
+	for bucket := first; bucket <= last; bucket++ {
+		from := float64(bucket) * width
+		condition := fmt.Sprintf("price >= %s AND price < %s", meiliNumber(from), meiliNumber(from+width))
+		if filter != "" {
+			condition = filter + " AND " + condition
+		}
+		queries = append(queries, meiliQuery{IndexUID: uid, Q: q, Filter: condition, Limit: 0})
+	}
+
+	results, err := m.multiSearch(ctx, queries)
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := make([]priceBucket, len(results))
+	for i, result := range results {
+		buckets[i] = priceBucket{Bucket: first + int64(i), Count: result.EstimatedTotalHits}
+	}
+	return priceFilter(width, buckets, req), nil
+}
+
+// ensureIndex applies the index settings once per tenant and process
+func (m *meilisearchIndex) ensureIndex(ctx context.Context, tenantID uuid.UUID) error {
+	uid := m.indexUID(tenantID)
+	if _, ok := m.configured.Load(uid); ok {
+		return nil
+	}
+
+	settings := map[string]interface{}{
+		"searchableAttributes": meiliSearchableAttributes,
+		"filterableAttributes": meiliFilterableAttributes,
+		"sortableAttributes":   meiliSortableAttributes,
+		"faceting":             map[string]int{"maxValuesPerFacet": 1000},
+	}
+	if err := m.do(ctx, http.MethodPatch, "/indexes/"+uid+"/settings", settings, nil); err != nil {
+		return err
+	}
+
+	m.configured.Store(uid, true)
+	return nil
+}
+
+func (m *meilisearchIndex) indexUID(tenantID uuid.UUID) string {
+	return m.prefix + "products_" + tenantID.String()
+}
+
+func (m *meilisearchIndex) multiSearch(ctx context.Context, queries []meiliQuery) ([]meiliResult, error) {
+	var response struct {
+		Results []meiliResult `json:"results"`
+	}
+	if err := m.do(ctx, http.MethodPost, "/multi-search", map[string]interface{}{"queries": queries}, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Results) != len(queries) {
+		return nil, fmt.Errorf("meilisearch returned %d results for %d queries", len(response.Results), len(queries))
+	}
+	return response.Results, nil
+}
+
+// do sends a request to Meilisearch and decodes the response into out
+func (m *meilisearchIndex) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, m.baseURL+path, &payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if m.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+m.apiKey)
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("meilisearch request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(resp.Body); err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr struct {
+			Message string `json:"message"`
+			Code    string `json:"code"`
+		}
+		_ = json.Unmarshal(buf.Bytes(), &apiErr)
+		if apiErr.Code == "index_not_found" {
+			return errMeiliIndexNotFound
+		}
+		return fmt.Errorf("meilisearch API error: status %d: %s", resp.StatusCode, apiErr.Message)
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(buf.Bytes(), out); err != nil {
+			return fmt.Errorf("failed to decode meilisearch response: %w", err)
+		}
+	}
+	return nil
+}
+
+// meiliFilter builds the Meilisearch filter of a search, leaving out the
+// selection of the facet named by except like applyFacetFilters does
+func meiliFilter(req *ProductSearchRequest, except string) string {
+	var conditions []string
+
+	if req.MinPrice != nil {
+		conditions = append(conditions, "price >= "+meiliNumber(*req.MinPrice))
+	}
+	if req.MaxPrice != nil {
+		conditions = append(conditions, "price <= "+meiliNumber(*req.MaxPrice))
+	}
+	if req.OnSale != nil && *req.OnSale {
+		conditions = append(conditions, "on_sale = true")
+	}
+	if len(req.ExcludeIDs) > 0 {
+		conditions = append(conditions, "id NOT IN "+meiliList(req.ExcludeIDs))
+	}
+	if len(req.Tags) > 0 {
+		tags := make([]string, len(req.Tags))
+		for i, tag := range req.Tags {
+			tags[i] = strings.ToLower(strings.TrimSpace(tag))
+		}
+		conditions = append(conditions, "tags IN "+meiliList(tags))
+	}
+
+	if except != FacetCategory && len(req.CategoryIDs) > 0 {
+		conditions = append(conditions, "category_ids IN "+meiliList(req.CategoryIDs))
+	}
+
+	if except != FacetCollection && len(req.CollectionIDs) > 0 {
+		conditions = append(conditions, "collection_ids IN "+meiliList(req.CollectionIDs))
+	}
+
+	if except != FacetPrice && len(req.PriceRanges) > 0 {
+		var ranges []string
+		for _, value := range req.PriceRanges {
+			from, to, err := parsePriceRange(value)
+			if err != nil {
+				continue
+			}
+			switch {
+			case from != nil && to != nil:
+				ranges = append(ranges, "(price >= "+meiliNumber(*from)+" AND price < "+meiliNumber(*to)+")")
+			case from != nil:
+				ranges = append(ranges, "price >= "+meiliNumber(*from))
+			default:
+				ranges = append(ranges, "price < "+meiliNumber(*to))
+			}
+		}
+		if len(ranges) > 0 {
+			conditions = append(conditions, "("+strings.Join(ranges, " OR ")+")")
+		}
+	}
+
+	if except != FacetAvailability && len(req.Availability) == 1 {
+		conditions = append(conditions, "in_stock = "+strconv.FormatBool(req.Availability[0] == AvailabilityInStock))
+	}
+
+	if except != FacetRating && req.Rating != nil {
+		conditions = append(conditions, "rating >= "+meiliNumber(*req.Rating))
+	}
+
+	if except != FacetOption {
+		skipKey := strings.TrimPrefix(except, optionFacetPrefix)
+		for _, key := range selectedOptionKeys(req.Options) {
+			if key == skipKey {
+				continue
+			}
+			values := make([]string, len(req.Options[key]))
+			for i, value := range req.Options[key] {
+				values[i] = key + "=" + value
+			}
+			conditions = append(conditions, "option_values IN "+meiliList(values))
+		}
+	}
+
+	if except != FacetAttribute {
+		skipCode := ""
+		if strings.HasPrefix(except, attributeFacetPrefix) {
+			skipCode = strings.TrimPrefix(except, attributeFacetPrefix)
+		}
+		for _, code := range selectedOptionKeys(req.Attributes) {
+			if code == skipCode {
+				continue
+			}
+			values := make([]string, len(req.Attributes[code]))
+			for i, value := range req.Attributes[code] {
+				values[i] = code + "=" + value
+			}
+			conditions = append(conditions, "attribute_values IN "+meiliList(values))
+		}
+	}
+
+	return strings.Join(conditions, " AND ")
+}
+
+func meiliSort(sortBy string) []string {
+	switch sortBy {
+	case "price_asc":
+		return []string{"price:asc"}
+	case "price_desc":
+		return []string{"price:desc"}
+	case "newest":
+		return []string{"created_unix:desc"}
+	case "rating":
+		return []string{"rating:desc", "review_count:desc"}
+	default:
+		return nil
+	}
+}
+
+// meiliList quotes values as a filter array; JSON string escaping is what
+// Meilisearch filters expect
+func meiliList(values []string) string {
+	encoded, _ := json.Marshal(values)
+	return string(encoded)
+}
+
+func meiliNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
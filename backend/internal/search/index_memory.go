@@ -0,0 +1,495 @@
+package search
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Field weights of the in-memory index, in line with the Postgres document
+// weights A to D
+var memoryFieldWeights = []float64{1.0, 0.4, 0.2, 0.1}
+
+// memoryIndex holds documents in process memory. It is meant for tests and
+// local development: nothing survives a restart and every query scans the
+// tenant's documents.
+type memoryIndex struct {
+	mu        sync.RWMutex
+	documents map[uuid.UUID]map[uuid.UUID]ProductDocument
+	synonyms  map[uuid.UUID][]SearchSynonym
+	indexedAt map[uuid.UUID]time.Time
+}
+
+// NewMemoryIndex creates an empty in-memory search index
+func NewMemoryIndex() SearchIndex {
+	return &memoryIndex{
+		documents: make(map[uuid.UUID]map[uuid.UUID]ProductDocument),
+		synonyms:  make(map[uuid.UUID][]SearchSynonym),
+		indexedAt: make(map[uuid.UUID]time.Time),
+	}
+}
+
+func (m *memoryIndex) Name() string {
+	return BackendMemory
+}
+
+func (m *memoryIndex) Index(ctx context.Context, tenantID uuid.UUID, documents []ProductDocument) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.documents[tenantID] == nil {
+		m.documents[tenantID] = make(map[uuid.UUID]ProductDocument)
+	}
+	for _, document := range documents {
+		m.documents[tenantID][document.ID] = document
+	}
+	m.indexedAt[tenantID] = time.Now()
+	return nil
+}
+
+func (m *memoryIndex) Delete(ctx context.Context, tenantID uuid.UUID, productIDs []uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, id := range productIDs {
+		delete(m.documents[tenantID], id)
+	}
+	return nil
+}
+
+func (m *memoryIndex) Query(ctx context.Context, tenantID uuid.UUID, req *ProductSearchRequest) ([]*SearchResult, []Filter, int64, error) {
+	tokens := tokenize(req.Query)
+	if len(tokens) == 0 {
+		return []*SearchResult{}, nil, 0, nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	terms := expandSynonyms(tokens, m.synonyms[tenantID])
+	scores := make(map[uuid.UUID]float64)
+	var matched []ProductDocument
+	for _, document := range m.documents[tenantID] {
+		if score, ok := terms.memoryScore(&document); ok {
+			scores[document.ID] = score * boostFactor(&document, req.Boosts)
+			matched = append(matched, document)
+		}
+	}
+
+	var results []ProductDocument
+	for _, document := range matched {
+		if memoryFilter(&document, req, "") {
+			results = append(results, document)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		a, b := &results[i], &results[j]
+		switch req.SortBy {
+		case "price_asc":
+			return a.Price < b.Price
+		case "price_desc":
+			return a.Price > b.Price
+		case "newest":
+			return a.CreatedAt.After(b.CreatedAt)
+		case "rating":
+			if a.Rating == nil || b.Rating == nil {
+				return a.Rating != nil
+			}
+			if *a.Rating != *b.Rating {
+				return *a.Rating > *b.Rating
+			}
+			return a.ReviewCount > b.ReviewCount
+		default:
+			if scores[a.ID] != scores[b.ID] {
+				return scores[a.ID] > scores[b.ID]
+			}
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+	})
+
+	total := int64(len(results))
+	page := make([]*SearchResult, 0, req.Limit)
+	for i := req.Offset; i < len(results) && i < req.Offset+req.Limit; i++ {
+		page = append(page, results[i].searchResult(scores[results[i].ID], MatchFullText))
+	}
+
+	var facets []Filter
+	if req.IncludeFacets {
+		facets = memoryFacets(matched, req)
+	}
+	return page, facets, total, nil
+}
+
+func (m *memoryIndex) Suggest(ctx context.Context, tenantID uuid.UUID, query string, limit int) ([]Suggestion, error) {
+	results, _, _, err := m.Query(ctx, tenantID, &ProductSearchRequest{Query: query, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]Suggestion, 0, len(results))
+	for _, result := range results {
+		suggestions = append(suggestions, Suggestion{
+			Text:  result.Title,
+			Type:  "product",
+			Score: result.Score,
+		})
+	}
+	return suggestions, nil
+}
+
+func (m *memoryIndex) Facets(ctx context.Context, tenantID uuid.UUID) ([]Filter, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	documents := make([]ProductDocument, 0, len(m.documents[tenantID]))
+	for _, document := range m.documents[tenantID] {
+		documents = append(documents, document)
+	}
+	return memoryFacets(documents, &ProductSearchRequest{}), nil
+}
+
+func (m *memoryIndex) SetSynonyms(ctx context.Context, tenantID uuid.UUID, synonyms []SearchSynonym) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.synonyms[tenantID] = synonyms
+	return nil
+}
+
+// Pending is always empty: the in-memory index cannot tell what it missed
+func (m *memoryIndex) Pending(ctx context.Context, tenantID uuid.UUID, limit int) ([]uuid.UUID, error) {
+	return nil, nil
+}
+
+func (m *memoryIndex) Health(ctx context.Context, tenantID uuid.UUID) (*IndexHealth, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	health := &IndexHealth{
+		Backend:   BackendMemory,
+		Healthy:   true,
+		Products:  int64(len(m.documents[tenantID])),
+		Documents: int64(len(m.documents[tenantID])),
+	}
+	if indexedAt, ok := m.indexedAt[tenantID]; ok {
+		health.LastIndexedAt = &indexedAt
+	}
+	return health, nil
+}
+
+// memoryScore matches a document the way the Postgres tsquery does: every slot
+// must match a word, the last one as a prefix. The score sums the weight of
+// the best field each slot matched.
+func (t searchTerms) memoryScore(document *ProductDocument) (float64, bool) {
+	fields := [][]string{
+		tokenize(strings.Join([]string{document.Name, document.SKU, document.Barcode}, " ")),
+		tokenize(strings.Join(document.Tags, " ")),
+		tokenize(document.CategoryPath),
+		tokenize(document.Description),
+	}
+
+	var score float64
+	for i, alternatives := range t {
+		prefix := i == len(t)-1
+		best := 0.0
+		for _, alternative := range alternatives {
+			words := strings.Fields(alternative)
+			for f, field := range fields {
+				if memoryFieldWeights[f] > best && containsWords(field, words, prefix && len(words) == 1) {
+					best = memoryFieldWeights[f]
+				}
+			}
+		}
+		if best == 0 {
+			return 0, false
+		}
+		score += best
+	}
+	return score, true
+}
+
+// containsWords tells whether every word occurs in field, the last one
+// possibly as a prefix
+func containsWords(field, words []string, prefix bool) bool {
+	for i, word := range words {
+		found := false
+		for _, candidate := range field {
+			if candidate == word || (prefix && i == len(words)-1 && strings.HasPrefix(candidate, word)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// memoryFilter tells whether a document passes the filters of a search,
+// leaving out the selection of the facet named by except
+func memoryFilter(document *ProductDocument, req *ProductSearchRequest, except string) bool {
+	if req.MinPrice != nil && document.Price < *req.MinPrice {
+		return false
+	}
+	if req.MaxPrice != nil && document.Price > *req.MaxPrice {
+		return false
+	}
+	if req.OnSale != nil && *req.OnSale && !document.OnSale {
+		return false
+	}
+	if containsString(req.ExcludeIDs, document.ID.String()) {
+		return false
+	}
+	if len(req.Tags) > 0 {
+		found := false
+		for _, tag := range req.Tags {
+			if containsString(document.Tags, strings.ToLower(strings.TrimSpace(tag))) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if except != FacetCategory && len(req.CategoryIDs) > 0 {
+		found := false
+		for _, id := range req.CategoryIDs {
+			if containsString(document.CategoryIDs, id) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if except != FacetCollection && len(req.CollectionIDs) > 0 {
+		found := false
+		for _, id := range req.CollectionIDs {
+			if containsString(document.CollectionIDs, id) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if except != FacetPrice && len(req.PriceRanges) > 0 {
+		found := false
+		for _, value := range req.PriceRanges {
+			from, to, err := parsePriceRange(value)
+			if err != nil {
+				continue
+			}
+			if (from == nil || document.Price >= *from) && (to == nil || document.Price < *to) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if except != FacetAvailability && len(req.Availability) == 1 {
+		if document.InStock != (req.Availability[0] == AvailabilityInStock) {
+			return false
+		}
+	}
+
+	if except != FacetRating && req.Rating != nil {
+		if document.Rating == nil || *document.Rating < *req.Rating {
+			return false
+		}
+	}
+
+	if except != FacetOption {
+		skipKey := strings.TrimPrefix(except, optionFacetPrefix)
+		for _, key := range selectedOptionKeys(req.Options) {
+			if key == skipKey {
+				continue
+			}
+			found := false
+			for _, value := range req.Options[key] {
+				if containsString(document.Options[key], value) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+
+	if except != FacetAttribute {
+		skipCode := ""
+		if strings.HasPrefix(except, attributeFacetPrefix) {
+			skipCode = strings.TrimPrefix(except, attributeFacetPrefix)
+		}
+		for _, code := range selectedOptionKeys(req.Attributes) {
+			if code == skipCode {
+				continue
+			}
+			found := false
+			for _, value := range req.Attributes[code] {
+				if containsString(document.Attributes[code], value) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// memoryFacets counts the facets of matched documents, each facet without its
+// own selection
+func memoryFacets(matched []ProductDocument, req *ProductSearchRequest) []Filter {
+	filtered := func(except string) []*ProductDocument {
+		var documents []*ProductDocument
+		for i := range matched {
+			if memoryFilter(&matched[i], req, except) {
+				documents = append(documents, &matched[i])
+			}
+		}
+		return documents
+	}
+
+	var facets []Filter
+
+	// Documents list their ancestor categories, so counts are rolled up and
+	// the tree can be rebuilt from the documents' category paths
+	categoryCounts := make(map[string]int64)
+	var nodes []categoryNode
+	seen := make(map[string]bool)
+	for _, document := range filtered(FacetCategory) {
+		names := strings.Split(document.CategoryPath, " > ")
+		for i, id := range document.CategoryIDs {
+			categoryCounts[id]++
+			if seen[id] || len(names) != len(document.CategoryIDs) {
+				continue
+			}
+			seen[id] = true
+			node := categoryNode{ID: id, Name: names[i]}
+			if i > 0 {
+				node.ParentID = &document.CategoryIDs[i-1]
+			}
+			nodes = append(nodes, node)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	if facet := categoryFilter(nodes, categoryCounts, false, req); facet != nil {
+		facets = append(facets, *facet)
+	}
+
+	collectionCounts := make(map[string]int64)
+	var collections []collectionNode
+	for _, document := range filtered(FacetCollection) {
+		for i, id := range document.CollectionIDs {
+			if collectionCounts[id] == 0 && i < len(document.Collections) {
+				collections = append(collections, collectionNode{ID: id, Title: document.Collections[i]})
+			}
+			collectionCounts[id]++
+		}
+	}
+	if facet := collectionFilter(collections, collectionCounts, req); facet != nil {
+		facets = append(facets, *facet)
+	}
+
+	if documents := filtered(FacetPrice); len(documents) > 0 {
+		min, max := documents[0].Price, documents[0].Price
+		for _, document := range documents {
+			min = math.Min(min, document.Price)
+			max = math.Max(max, document.Price)
+		}
+		width := priceBucketWidth(min, max)
+		counts := make(map[int64]int64)
+		for _, document := range documents {
+			counts[int64(math.Floor(document.Price/width))]++
+		}
+		var buckets []priceBucket
+		for bucket, count := range counts {
+			buckets = append(buckets, priceBucket{Bucket: bucket, Count: count})
+		}
+		sort.Slice(buckets, func(i, j int) bool { return buckets[i].Bucket < buckets[j].Bucket })
+		if facet := priceFilter(width, buckets, req); facet != nil {
+			facets = append(facets, *facet)
+		}
+	}
+
+	var inStock, outOfStock int64
+	for _, document := range filtered(FacetAvailability) {
+		if document.InStock {
+			inStock++
+		} else {
+			outOfStock++
+		}
+	}
+	if facet := availabilityFilter(inStock, outOfStock, req); facet != nil {
+		facets = append(facets, *facet)
+	}
+
+	ratings := make(map[int]int64)
+	for _, document := range filtered(FacetRating) {
+		for stars := 1; stars <= 4; stars++ {
+			if document.Rating != nil && *document.Rating >= float64(stars) {
+				ratings[stars]++
+			}
+		}
+	}
+	if facet := ratingFilter(ratings, req); facet != nil {
+		facets = append(facets, *facet)
+	}
+
+	options := make(map[string]map[string]int64)
+	addValues := func(counts map[string]map[string]int64, documents []*ProductDocument, field func(*ProductDocument) map[string][]string, include func(key string) bool) {
+		for _, document := range documents {
+			for key, values := range field(document) {
+				if !include(key) {
+					continue
+				}
+				if counts[key] == nil {
+					counts[key] = make(map[string]int64)
+				}
+				for _, value := range values {
+					counts[key][value]++
+				}
+			}
+		}
+	}
+	documentOptions := func(document *ProductDocument) map[string][]string { return document.Options }
+	addValues(options, filtered(""), documentOptions, func(key string) bool { return len(req.Options[key]) == 0 })
+	for _, selected := range selectedOptionKeys(req.Options) {
+		key := selected
+		addValues(options, filtered(optionFacetPrefix+key), documentOptions, func(k string) bool { return k == key })
+	}
+	facets = append(facets, optionFilters(options, req)...)
+
+	attributes := make(map[string]map[string]int64)
+	attributeValues := func(document *ProductDocument) map[string][]string { return document.Attributes }
+	addValues(attributes, filtered(""), attributeValues, func(code string) bool { return len(req.Attributes[code]) == 0 })
+	for _, selected := range selectedOptionKeys(req.Attributes) {
+		code := selected
+		addValues(attributes, filtered(attributeFacetPrefix+code), attributeValues, func(c string) bool { return c == code })
+	}
+	facets = append(facets, attributeFilters(attributes, req)...)
+
+	return facets
+}
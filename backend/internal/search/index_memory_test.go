@@ -0,0 +1,135 @@
+package search
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// rankingCatalog indexes one product matching "saree" in each document field,
+// plus a second name match that is newer than the first
+func rankingCatalog(t *testing.T) (SearchIndex, uuid.UUID, map[string]ProductDocument) {
+	t.Helper()
+
+	tenantID := uuid.New()
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	documents := map[string]ProductDocument{
+		"name":        {Name: "Red Cotton Saree", Price: 2500, CreatedAt: created},
+		"newer name":  {Name: "Silk Saree", Price: 9000, CreatedAt: created.AddDate(0, 1, 0)},
+		"tag":         {Name: "Jamdani", Tags: []string{"saree"}, Price: 7000, CreatedAt: created},
+		"category":    {Name: "Tangail", CategoryPath: "Women > Saree", Price: 3000, CreatedAt: created},
+		"description": {Name: "Blouse", Description: "Goes well with any saree", Price: 800, CreatedAt: created},
+		"unrelated":   {Name: "Panjabi", Description: "Eid collection", Price: 1500, CreatedAt: created},
+	}
+
+	var batch []ProductDocument
+	for key, document := range documents {
+		document.ID = uuid.New()
+		document.TenantID = tenantID
+		documents[key] = document
+		batch = append(batch, document)
+	}
+
+	index := NewMemoryIndex()
+	if err := index.Index(context.Background(), tenantID, batch); err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	return index, tenantID, documents
+}
+
+// resultKeys maps results back to the catalog keys
+func resultKeys(results []*SearchResult, documents map[string]ProductDocument) []string {
+	keys := make([]string, 0, len(results))
+	for _, result := range results {
+		for key, document := range documents {
+			if document.ID.String() == result.ID {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+func TestMemoryIndexRanking(t *testing.T) {
+	index, tenantID, documents := rankingCatalog(t)
+
+	tests := []struct {
+		name      string
+		req       ProductSearchRequest
+		want      []string
+		wantTotal int64
+	}{
+		{
+			name:      "field weights, then newest first",
+			req:       ProductSearchRequest{Query: "saree", Limit: 10},
+			want:      []string{"newer name", "name", "tag", "category", "description"},
+			wantTotal: 5,
+		},
+		{
+			name:      "every word must match",
+			req:       ProductSearchRequest{Query: "red saree", Limit: 10},
+			want:      []string{"name"},
+			wantTotal: 1,
+		},
+		{
+			name:      "the last word matches as a prefix",
+			req:       ProductSearchRequest{Query: "cotton sar", Limit: 10},
+			want:      []string{"name"},
+			wantTotal: 1,
+		},
+		{
+			name:      "earlier words must match whole",
+			req:       ProductSearchRequest{Query: "sar cotton", Limit: 10},
+			want:      []string{},
+			wantTotal: 0,
+		},
+		{
+			name:      "page of the ranking",
+			req:       ProductSearchRequest{Query: "saree", Offset: 1, Limit: 2},
+			want:      []string{"name", "tag"},
+			wantTotal: 5,
+		},
+		{
+			name:      "explicit sort replaces relevance",
+			req:       ProductSearchRequest{Query: "saree", SortBy: "price_asc", Limit: 10},
+			want:      []string{"description", "name", "category", "tag", "newer name"},
+			wantTotal: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, _, total, err := index.Query(context.Background(), tenantID, &tt.req)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			if got := resultKeys(results, documents); !reflect.DeepEqual(got, tt.want) || total != tt.wantTotal {
+				t.Errorf("Query(%q) = %q (total %d), want %q (total %d)", tt.req.Query, got, total, tt.want, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestMemoryIndexSynonymsAndTenants(t *testing.T) {
+	index, tenantID, documents := rankingCatalog(t)
+	ctx := context.Background()
+
+	if err := index.SetSynonyms(ctx, tenantID, []SearchSynonym{{Terms: []string{"sari", "saree"}}}); err != nil {
+		t.Fatalf("SetSynonyms() error = %v", err)
+	}
+	results, _, total, err := index.Query(ctx, tenantID, &ProductSearchRequest{Query: "sari", Limit: 10})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if total != 5 || resultKeys(results, documents)[0] != "newer name" {
+		t.Errorf("synonym search = %q (total %d), want the saree ranking", resultKeys(results, documents), total)
+	}
+
+	_, _, total, err = index.Query(ctx, uuid.New(), &ProductSearchRequest{Query: "saree", Limit: 10})
+	if err != nil || total != 0 {
+		t.Errorf("other tenant's search found %d products, error %v", total, err)
+	}
+}
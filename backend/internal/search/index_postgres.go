@@ -0,0 +1,89 @@
+package search
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// postgresIndex searches the product_search_documents table. Database
+// triggers keep the documents current, so indexing only refreshes documents
+// on request and deleted products disappear with their rows.
+type postgresIndex struct {
+	repo Repository
+}
+
+// NewPostgresIndex creates the default search index backed by Postgres full
+// text search
+func NewPostgresIndex(repo Repository) SearchIndex {
+	return &postgresIndex{repo: repo}
+}
+
+func (p *postgresIndex) Name() string {
+	return BackendPostgres
+}
+
+func (p *postgresIndex) Index(ctx context.Context, tenantID uuid.UUID, documents []ProductDocument) error {
+	productIDs := make([]uuid.UUID, len(documents))
+	for i, document := range documents {
+		productIDs[i] = document.ID
+	}
+	return p.repo.RefreshDocuments(ctx, tenantID, productIDs)
+}
+
+// Delete is a no-op: search queries only match active products and documents
+// are removed with their products
+func (p *postgresIndex) Delete(ctx context.Context, tenantID uuid.UUID, productIDs []uuid.UUID) error {
+	return nil
+}
+
+func (p *postgresIndex) Query(ctx context.Context, tenantID uuid.UUID, req *ProductSearchRequest) ([]*SearchResult, []Filter, int64, error) {
+	return p.repo.SearchProducts(ctx, tenantID, req)
+}
+
+func (p *postgresIndex) Suggest(ctx context.Context, tenantID uuid.UUID, query string, limit int) ([]Suggestion, error) {
+	return p.repo.GetSuggestions(ctx, tenantID, query, "product", limit)
+}
+
+func (p *postgresIndex) Facets(ctx context.Context, tenantID uuid.UUID) ([]Filter, error) {
+	return p.repo.GetFilters(ctx, tenantID, "product")
+}
+
+// SetSynonyms is a no-op: synonyms are read from search_synonyms per query
+func (p *postgresIndex) SetSynonyms(ctx context.Context, tenantID uuid.UUID, synonyms []SearchSynonym) error {
+	return nil
+}
+
+func (p *postgresIndex) Pending(ctx context.Context, tenantID uuid.UUID, limit int) ([]uuid.UUID, error) {
+	return p.repo.PendingDocuments(ctx, tenantID, limit)
+}
+
+func (p *postgresIndex) Health(ctx context.Context, tenantID uuid.UUID) (*IndexHealth, error) {
+	health := &IndexHealth{Backend: BackendPostgres}
+
+	state, err := p.repo.DocumentStatus(ctx, tenantID)
+	if err != nil {
+		health.Error = err.Error()
+		return health, nil
+	}
+
+	applyIndexState(health, state)
+	return health, nil
+}
+
+// applyIndexState fills in counts and lag from the catalog's view of an index;
+// an index is healthy when nothing has waited longer than maxIndexLag
+func applyIndexState(health *IndexHealth, state *IndexState) {
+	health.Products = state.Products
+	health.Documents = state.Documents
+	health.Pending = state.Pending
+	health.LastIndexedAt = state.LastIndexedAt
+
+	var lag time.Duration
+	if state.Pending > 0 && state.OldestPending != nil {
+		lag = time.Since(*state.OldestPending)
+	}
+	health.LagSeconds = int64(lag.Seconds())
+	health.Healthy = health.Error == "" && lag <= maxIndexLag
+}
@@ -0,0 +1,269 @@
+package search
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Boosted relevance never drops below this share of the unboosted relevance,
+// so a negative boost buries products without hiding them
+const minBoostFactor = 0.1
+
+// Queries searched at least lowClickRateMinSearches times with results whose
+// results are clicked in fewer than lowClickRateThreshold of searches are
+// reported as low click-through
+const (
+	lowClickRateMinSearches = 5
+	lowClickRateThreshold   = 0.1
+)
+
+// merchandising is what the rules matching a query do to its results
+type merchandising struct {
+	ruleIDs     []string
+	pinned      []string
+	hidden      []string
+	boosts      []SearchBoost
+	redirectURL string
+}
+
+// matchRules combines the active rules matching query. Rules come in priority
+// order: their pins are shown in that order and the first redirect wins.
+func matchRules(query string, rules []SearchRule, now time.Time) *merchandising {
+	tokens := tokenize(query)
+	result := &merchandising{}
+	for _, rule := range rules {
+		if !rule.live(now) || !rule.matches(tokens) {
+			continue
+		}
+
+		result.ruleIDs = append(result.ruleIDs, rule.ID.String())
+		result.pinned = appendUnique(result.pinned, rule.PinnedProductIDs...)
+		result.hidden = appendUnique(result.hidden, rule.HiddenProductIDs...)
+		result.boosts = append(result.boosts, rule.Boosts...)
+		if result.redirectURL == "" {
+			result.redirectURL = rule.RedirectURL
+		}
+	}
+
+	// Hiding wins over pinning
+	pinned := result.pinned[:0]
+	for _, id := range result.pinned {
+		if !containsString(result.hidden, id) {
+			pinned = append(pinned, id)
+		}
+	}
+	result.pinned = pinned
+
+	return result
+}
+
+// live tells whether the rule is active and within its schedule
+func (r *SearchRule) live(now time.Time) bool {
+	if !r.IsActive {
+		return false
+	}
+	if r.StartsAt != nil && now.Before(*r.StartsAt) {
+		return false
+	}
+	if r.EndsAt != nil && !now.Before(*r.EndsAt) {
+		return false
+	}
+	return true
+}
+
+// matches compares the rule query with the tokens of a search query
+func (r *SearchRule) matches(tokens []string) bool {
+	ruleTokens := tokenize(r.Query)
+	if len(ruleTokens) == 0 {
+		return false
+	}
+
+	if r.MatchType == RuleMatchContains {
+		for i := range tokens {
+			if hasPrefix(tokens[i:], ruleTokens) {
+				return true
+			}
+		}
+		return false
+	}
+	return len(tokens) == len(ruleTokens) && hasPrefix(tokens, ruleTokens)
+}
+
+// boostSQL returns the relevance multiplier of product p for boosts
+func boostSQL(boosts []SearchBoost) (string, []interface{}) {
+	var terms []string
+	var args []interface{}
+	for _, boost := range boosts {
+		switch boost.Type {
+		case BoostTag:
+			terms = append(terms, `CASE WHEN EXISTS (
+				SELECT 1 FROM jsonb_array_elements_text(CASE WHEN jsonb_typeof(p.tags) = 'array' THEN p.tags ELSE '[]'::jsonb END) AS tag
+				WHERE LOWER(tag) = ?) THEN ? ELSE 0 END`)
+			args = append(args, strings.ToLower(boost.Value), boost.Weight)
+		case BoostCategory:
+			terms = append(terms, `CASE WHEN p.category_id IN (
+				WITH RECURSIVE tree AS (
+					SELECT id FROM categories WHERE id = ?
+					UNION
+					SELECT c.id FROM categories c JOIN tree ON c.parent_id = tree.id
+				)
+				SELECT id FROM tree) THEN ? ELSE 0 END`)
+			args = append(args, boost.Value, boost.Weight)
+		case BoostInStock:
+			terms = append(terms, "CASE WHEN "+inStockSQL+" THEN ? ELSE 0 END")
+			args = append(args, boost.Weight)
+		case BoostMargin:
+			terms = append(terms, "? * "+marginSQL)
+			args = append(args, boost.Weight)
+		case BoostRating:
+			terms = append(terms, "? * "+ratingSignalSQL)
+			args = append(args, boost.Weight)
+		}
+	}
+	if len(terms) == 0 {
+		return "1", nil
+	}
+
+	args = append(args, minBoostFactor)
+	return "GREATEST(1 + " + strings.Join(terms, " + ") + ", ?)", args
+}
+
+// marginSQL is the gross margin of product p as a share of its price, 0 when
+// the cost is unknown
+const marginSQL = "GREATEST(COALESCE((p.price - p.cost_price) / NULLIF(p.price, 0), 0), 0)"
+
+// ratingConfidenceReviews is the review count at which a product's rating
+// counts for half its weight in rating boosts
+const ratingConfidenceReviews = 10
+
+// ratingSignalSQL is the rating of product p scaled to 0-1 and damped for
+// products with few reviews, matching ratingSignal
+const ratingSignalSQL = "COALESCE(p.rating_average, 0) / 5 * p.review_count / (p.review_count + 10.0)"
+
+// ratingSignal is the rating of a document scaled to 0-1 and damped for
+// products with few reviews, so one 5 star review doesn't outrank hundreds
+// of 4.8 star ones
+func ratingSignal(document *ProductDocument) float64 {
+	if document.Rating == nil || document.ReviewCount <= 0 {
+		return 0
+	}
+	count := float64(document.ReviewCount)
+	return *document.Rating / 5 * count / (count + ratingConfidenceReviews)
+}
+
+// boostFactor is the relevance multiplier of a document for boosts, matching
+// boostSQL for the backends that rank in Go
+func boostFactor(document *ProductDocument, boosts []SearchBoost) float64 {
+	factor := 1.0
+	for _, boost := range boosts {
+		switch boost.Type {
+		case BoostTag:
+			if containsString(document.Tags, strings.ToLower(boost.Value)) {
+				factor += boost.Weight
+			}
+		case BoostCategory:
+			if containsString(document.CategoryIDs, boost.Value) {
+				factor += boost.Weight
+			}
+		case BoostInStock:
+			if document.InStock {
+				factor += boost.Weight
+			}
+		case BoostMargin:
+			if document.Margin != nil {
+				factor += boost.Weight * math.Max(*document.Margin, 0)
+			}
+		case BoostRating:
+			factor += boost.Weight * ratingSignal(document)
+		}
+	}
+	return math.Max(factor, minBoostFactor)
+}
+
+// newSearchRule validates a rule request into the fields of a rule
+func newSearchRule(req *SearchRuleRequest) (*SearchRule, error) {
+	query := strings.Join(tokenize(req.Query), " ")
+	if query == "" {
+		return nil, fmt.Errorf("%w: query is required", ErrInvalidRule)
+	}
+
+	rule := &SearchRule{
+		Name:             strings.TrimSpace(req.Name),
+		Query:            query,
+		MatchType:        req.MatchType,
+		PinnedProductIDs: []string{},
+		HiddenProductIDs: []string{},
+		Boosts:           []SearchBoost{},
+		RedirectURL:      strings.TrimSpace(req.RedirectURL),
+		Priority:         req.Priority,
+		IsActive:         req.IsActive == nil || *req.IsActive,
+		StartsAt:         req.StartsAt,
+		EndsAt:           req.EndsAt,
+	}
+	if rule.Name == "" {
+		rule.Name = req.Query
+	}
+	if rule.MatchType == "" {
+		rule.MatchType = RuleMatchExact
+	}
+	if rule.MatchType != RuleMatchExact && rule.MatchType != RuleMatchContains {
+		return nil, fmt.Errorf("%w: unknown match type %q", ErrInvalidRule, rule.MatchType)
+	}
+	if rule.StartsAt != nil && rule.EndsAt != nil && !rule.EndsAt.After(*rule.StartsAt) {
+		return nil, fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidRule)
+	}
+
+	for _, ids := range []struct {
+		from []string
+		to   *[]string
+	}{{req.PinnedProductIDs, &rule.PinnedProductIDs}, {req.HiddenProductIDs, &rule.HiddenProductIDs}} {
+		for _, id := range ids.from {
+			parsed, err := uuid.Parse(strings.TrimSpace(id))
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid product ID %q", ErrInvalidRule, id)
+			}
+			*ids.to = appendUnique(*ids.to, parsed.String())
+		}
+	}
+
+	for _, boost := range req.Boosts {
+		switch boost.Type {
+		case BoostTag:
+			boost.Value = strings.ToLower(strings.TrimSpace(boost.Value))
+			if boost.Value == "" {
+				return nil, fmt.Errorf("%w: tag boost needs a tag", ErrInvalidRule)
+			}
+		case BoostCategory:
+			parsed, err := uuid.Parse(strings.TrimSpace(boost.Value))
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid category ID %q", ErrInvalidRule, boost.Value)
+			}
+			boost.Value = parsed.String()
+		case BoostInStock, BoostMargin, BoostRating:
+			boost.Value = ""
+		default:
+			return nil, fmt.Errorf("%w: unknown boost type %q", ErrInvalidRule, boost.Type)
+		}
+		if boost.Weight == 0 || boost.Weight < -1 || boost.Weight > 10 {
+			return nil, fmt.Errorf("%w: boost weight must be between -1 and 10 and not 0", ErrInvalidRule)
+		}
+		rule.Boosts = append(rule.Boosts, boost)
+	}
+
+	if rule.RedirectURL != "" {
+		parsed, err := url.Parse(rule.RedirectURL)
+		if err != nil || (!strings.HasPrefix(rule.RedirectURL, "/") && parsed.Scheme != "https" && parsed.Scheme != "http") {
+			return nil, fmt.Errorf("%w: redirect URL must be a path or an http(s) URL", ErrInvalidRule)
+		}
+	}
+
+	if len(rule.PinnedProductIDs) == 0 && len(rule.HiddenProductIDs) == 0 && len(rule.Boosts) == 0 && rule.RedirectURL == "" {
+		return nil, fmt.Errorf("%w: a rule must pin, hide, boost or redirect", ErrInvalidRule)
+	}
+	return rule, nil
+}
@@ -0,0 +1,108 @@
+package search
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestBoostFactor(t *testing.T) {
+	margin := 0.4
+	document := &ProductDocument{
+		Tags:        []string{"eid", "cotton"},
+		CategoryIDs: []string{"women"},
+		InStock:     true,
+		Margin:      &margin,
+	}
+
+	tests := []struct {
+		name   string
+		boosts []SearchBoost
+		want   float64
+	}{
+		{"no boosts", nil, 1},
+		{"tag, matched case-insensitively", []SearchBoost{{Type: BoostTag, Value: "Eid", Weight: 2}}, 3},
+		{"tag the product lacks", []SearchBoost{{Type: BoostTag, Value: "winter", Weight: 2}}, 1},
+		{"category", []SearchBoost{{Type: BoostCategory, Value: "women", Weight: 0.5}}, 1.5},
+		{"in stock", []SearchBoost{{Type: BoostInStock, Weight: 1}}, 2},
+		{"margin scales the weight", []SearchBoost{{Type: BoostMargin, Weight: 1}}, 1.4},
+		{"boosts add up", []SearchBoost{{Type: BoostTag, Value: "cotton", Weight: 1}, {Type: BoostInStock, Weight: 1}}, 3},
+		{"a burying boost is floored", []SearchBoost{{Type: BoostTag, Value: "eid", Weight: -5}}, minBoostFactor},
+	}
+
+	for _, tt := range tests {
+		if got := boostFactor(document, tt.boosts); !floatEqual(got, tt.want) {
+			t.Errorf("boostFactor(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBoostsReorderMemoryResults(t *testing.T) {
+	index, tenantID, documents := rankingCatalog(t)
+
+	tests := []struct {
+		name   string
+		boosts []SearchBoost
+		want   []string
+	}{
+		{
+			name:   "a tag boost lifts a weak match",
+			boosts: []SearchBoost{{Type: BoostTag, Value: "saree", Weight: 3}},
+			want:   []string{"tag", "newer name", "name", "category", "description"},
+		},
+		{
+			// A buried product still matches, it only sinks
+			name:   "a negative boost buries a strong match",
+			boosts: []SearchBoost{{Type: BoostTag, Value: "saree", Weight: -5}},
+			want:   []string{"newer name", "name", "category", "description", "tag"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &ProductSearchRequest{Query: "saree", Limit: 10, Boosts: tt.boosts}
+			results, _, _, err := index.Query(context.Background(), tenantID, req)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			if got := resultKeys(results, documents); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Query() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRatingSignal(t *testing.T) {
+	rating := func(r float64) *float64 { return &r }
+
+	tests := []struct {
+		name     string
+		document ProductDocument
+		want     float64
+	}{
+		{"unrated", ProductDocument{}, 0},
+		{"rating without reviews", ProductDocument{Rating: rating(5)}, 0},
+		{"one review counts for little", ProductDocument{Rating: rating(5), ReviewCount: 1}, 1.0 / 11},
+		{"half weight at the confidence count", ProductDocument{Rating: rating(5), ReviewCount: ratingConfidenceReviews}, 0.5},
+		{"many reviews", ProductDocument{Rating: rating(4), ReviewCount: 990}, 0.8 * 0.99},
+	}
+
+	for _, tt := range tests {
+		if got := ratingSignal(&tt.document); !floatEqual(got, tt.want) {
+			t.Errorf("ratingSignal(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// One 5 star review must not outrank hundreds of 4.8 star ones
+	few := &ProductDocument{Rating: rating(5), ReviewCount: 1}
+	many := &ProductDocument{Rating: rating(4.8), ReviewCount: 300}
+	boosts := []SearchBoost{{Type: BoostRating, Weight: 1}}
+	if boostFactor(few, boosts) >= boostFactor(many, boosts) {
+		t.Errorf("boostFactor() favours a single 5 star review over 300 4.8 star ones")
+	}
+}
+
+func floatEqual(a, b float64) bool {
+	diff := a - b
+	return diff < 0.001 && diff > -0.001
+}
@@ -8,21 +8,27 @@ import (
 // Module represents the search module
 type Module struct {
 	handler *Handler
+	indexer *Indexer
 }
 
-// NewModule creates a new search module
-func NewModule(db *gorm.DB) *Module {
+// NewModule creates a new search module. Product searches go to index; a nil
+// index searches Postgres.
+func NewModule(db *gorm.DB, index SearchIndex) *Module {
 	// Initialize repository
 	repo := NewRepository(db)
+	if index == nil {
+		index = NewPostgresIndex(repo)
+	}
 	
 	// Initialize service
-	service := NewService(repo)
+	service := NewService(repo, index)
 	
 	// Initialize handler
 	handler := NewHandler(service)
 	
 	return &Module{
 		handler: handler,
+		indexer: NewIndexer(index, repo),
 	}
 }
 
@@ -34,4 +40,10 @@ func (m *Module) RegisterRoutes(router *gin.RouterGroup) {
 // GetHandler returns the search handler
 func (m *Module) GetHandler() *Handler {
 	return m.handler
-}
\ No newline at end of file
+}
+
+// GetIndexer returns the indexer that keeps the search index up to date with
+// product changes
+func (m *Module) GetIndexer() *Indexer {
+	return m.indexer
+}
@@ -0,0 +1,135 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Minimum pg_trgm word similarity for a fuzzy match to count as a result
+const fuzzyThreshold = 0.4
+
+// searchNormalizer maps Bangla digits to ASCII so "১২" and "12" match alike
+// and drops the zero-width joiners that vary between Bangla keyboards
+var searchNormalizer = strings.NewReplacer(
+	"০", "0", "১", "1", "২", "2", "৩", "3", "৪", "4",
+	"৫", "5", "৬", "6", "৭", "7", "৮", "8", "৯", "9",
+	"\u200c", "", "\u200d", "",
+)
+
+// normalizeSearchText mirrors the search_normalize database function used when
+// indexing: lower case, ASCII digits and no zero-width joiners
+func normalizeSearchText(text string) string {
+	return strings.ToLower(strings.TrimSpace(searchNormalizer.Replace(text)))
+}
+
+// tokenize splits normalised text into words. Combining marks are part of a
+// word so Bangla vowel signs and the hasanta do not break words apart.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(normalizeSearchText(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && !unicode.IsMark(r)
+	})
+}
+
+// searchTerms is a parsed query. Every slot must match; a slot matches when any
+// of its alternatives (the typed words or one of their synonyms) does.
+type searchTerms [][]string
+
+// expandSynonyms parses tokens into slots, replacing the longest run of tokens
+// that equals a synonym term with every term of its group
+func expandSynonyms(tokens []string, synonyms []SearchSynonym) searchTerms {
+	type group struct {
+		terms  []string
+		inputs [][]string
+	}
+
+	var groups []group
+	for _, synonym := range synonyms {
+		var g group
+		for i, term := range synonym.Terms {
+			words := tokenize(term)
+			if len(words) == 0 {
+				continue
+			}
+			g.terms = append(g.terms, strings.Join(words, " "))
+			if !synonym.OneWay || i == 0 {
+				g.inputs = append(g.inputs, words)
+			}
+		}
+		if len(g.terms) > 1 && len(g.inputs) > 0 {
+			groups = append(groups, g)
+		}
+	}
+
+	var terms searchTerms
+	for i := 0; i < len(tokens); {
+		length := 0
+		var alternatives []string
+		for _, g := range groups {
+			for _, input := range g.inputs {
+				if len(input) < length || !hasPrefix(tokens[i:], input) {
+					continue
+				}
+				if len(input) > length {
+					length = len(input)
+					alternatives = nil
+				}
+				alternatives = appendUnique(alternatives, g.terms...)
+			}
+		}
+
+		if length == 0 {
+			terms = append(terms, []string{tokens[i]})
+			i++
+			continue
+		}
+		terms = append(terms, alternatives)
+		i += length
+	}
+
+	return terms
+}
+
+// tsquery builds a tsquery expression and its arguments. Each alternative is
+// parsed with the english configuration for stemming and the simple one for
+// Bangla; the last word is also matched as a prefix for search-as-you-type.
+func (t searchTerms) tsquery() (string, []interface{}) {
+	var slots []string
+	var args []interface{}
+
+	for i, alternatives := range t {
+		var parts []string
+		for _, alternative := range alternatives {
+			parts = append(parts, "plainto_tsquery('english', ?)", "plainto_tsquery('simple', ?)")
+			args = append(args, alternative, alternative)
+
+			if i == len(t)-1 && !strings.Contains(alternative, " ") {
+				parts = append(parts, "to_tsquery('simple', ?)")
+				args = append(args, alternative+":*")
+			}
+		}
+		slots = append(slots, "("+strings.Join(parts, " || ")+")")
+	}
+
+	return strings.Join(slots, " && "), args
+}
+
+func hasPrefix(tokens, prefix []string) bool {
+	if len(prefix) > len(tokens) {
+		return false
+	}
+	for i := range prefix {
+		if tokens[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func appendUnique(values []string, items ...string) []string {
+	for _, item := range items {
+		if !containsString(values, item) {
+			values = append(values, item)
+		}
+	}
+	return values
+}
@@ -0,0 +1,73 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Cotton T-Shirt, XL", []string{"cotton", "t", "shirt", "xl"}},
+		{"  iPhone 15 Pro  ", []string{"iphone", "15", "pro"}},
+		{"শাড়ি ১২", []string{"শাড়ি", "12"}}, // Vowel signs stay in the word; Bangla digits become ASCII
+		{"কম্পিউটার", []string{"কম্পিউটার"}},  // The hasanta does not split a word
+		{"র‍যাব", []string{"রযাব"}},           // Zero-width joiners are dropped
+		{"!!!", []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestExpandSynonyms(t *testing.T) {
+	synonyms := []SearchSynonym{
+		{Terms: []string{"tee", "t shirt"}},
+		{Terms: []string{"mobile", "phone", "মোবাইল"}},
+		{Terms: []string{"mobile phone", "smartphone"}},
+		{Terms: []string{"saree", "শাড়ি"}, OneWay: true},
+	}
+
+	tests := []struct {
+		query string
+		want  searchTerms
+	}{
+		{"red tee", searchTerms{{"red"}, {"tee", "t shirt"}}},
+		{"t shirt", searchTerms{{"tee", "t shirt"}}},
+		{"phone case", searchTerms{{"mobile", "phone", "মোবাইল"}, {"case"}}},
+		// The longest matching run wins
+		{"mobile phone cover", searchTerms{{"mobile phone", "smartphone"}, {"cover"}}},
+		// One-way synonyms only expand their first term
+		{"saree", searchTerms{{"saree", "শাড়ি"}}},
+		{"শাড়ি", searchTerms{{"শাড়ি"}}},
+	}
+
+	for _, tt := range tests {
+		if got := expandSynonyms(tokenize(tt.query), synonyms); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expandSynonyms(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSearchTermsTsquery(t *testing.T) {
+	query, args := searchTerms{{"red"}, {"tee", "t shirt"}}.tsquery()
+
+	// Every slot must match; within a slot any alternative may
+	if slots := strings.Split(query, " && "); len(slots) != 2 {
+		t.Fatalf("tsquery = %q, want two slots", query)
+	}
+	// Only single words of the last slot are matched as a prefix
+	want := []interface{}{"red", "red", "tee", "tee", "tee:*", "t shirt", "t shirt"}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %q, want %q", args, want)
+	}
+	if got := strings.Count(query, "?"); got != len(args) {
+		t.Errorf("tsquery has %d placeholders for %d args", got, len(args))
+	}
+}
@@ -2,6 +2,8 @@ package search
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -74,31 +76,68 @@ func (r *repository) Search(ctx context.Context, tenantID uuid.UUID, query *Sear
 	return results, total, nil
 }
 
-// SearchProducts performs product-specific search with advanced filters
-func (r *repository) SearchProducts(ctx context.Context, tenantID uuid.UUID, req *ProductSearchRequest) ([]*SearchResult, int64, error) {
-	var results []*SearchResult
+// SearchProducts performs product-specific search with advanced filters.
+// Products are matched against their full-text search document and ranked by
+// ts_rank_cd; when nothing matches, trigram similarity catches misspellings.
+// Facets are counted over the matched products before facet selections.
+func (r *repository) SearchProducts(ctx context.Context, tenantID uuid.UUID, req *ProductSearchRequest) ([]*SearchResult, []Filter, int64, error) {
+	synonyms, err := r.listSynonyms(ctx, tenantID)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	tokens := tokenize(req.Query)
+	if len(tokens) == 0 {
+		return []*SearchResult{}, nil, 0, nil
+	}
+
+	base := r.db.WithContext(ctx).Table("products p").
+		Joins("JOIN product_search_documents d ON d.product_id = p.id").
+		Where("p.tenant_id = ? AND p.status = ?", tenantID, "active")
+	base = applyProductFilters(base, req).Session(&gorm.Session{})
 
-	// Build query
-	query := r.db.WithContext(ctx).Table("products p").
-		Select("p.id, p.name as title, p.description, p.price, p.image_url, p.created_at, p.updated_at").
-		Where("p.tenant_id = ? AND p.deleted_at IS NULL", tenantID)
+	tsquery, scoreArgs := expandSynonyms(tokens, synonyms).tsquery()
+	matched := base.Where("d.document @@ ("+tsquery+")", scoreArgs...)
+	scoreSQL, match := "ts_rank_cd(d.document, "+tsquery+", 32)", MatchFullText
 
-	// Add search conditions
-	if req.Query != "" {
-		searchTerm := "%" + strings.ToLower(req.Query) + "%"
-		query = query.Where("(LOWER(p.name) LIKE ? OR LOWER(p.description) LIKE ? OR LOWER(p.sku) LIKE ?)", 
-			searchTerm, searchTerm, searchTerm)
+	var matches int64
+	if err := matched.Count(&matches).Error; err != nil {
+		return nil, nil, 0, fmt.Errorf("failed to count products: %w", err)
+	}
+	if matches == 0 {
+		text := strings.Join(tokens, " ")
+		matched = base.Where("word_similarity(?, d.search_text) >= ?", text, fuzzyThreshold)
+		scoreSQL, scoreArgs, match = "word_similarity(?, d.search_text)", []interface{}{text}, MatchFuzzy
+	}
+	matched = matched.Session(&gorm.Session{})
+
+	// Merchandising boosts scale relevance; copy the arguments, which the
+	// match condition above still refers to
+	if len(req.Boosts) > 0 {
+		boost, boostArgs := boostSQL(req.Boosts)
+		scoreSQL = "(" + scoreSQL + ") * " + boost
+		scoreArgs = append(append([]interface{}{}, scoreArgs...), boostArgs...)
 	}
 
-	// Add filters
-	if req.CategoryID != "" {
-		query = query.Where("p.category_id = ?", req.CategoryID)
+	results, total, err := r.rankProducts(applyFacetFilters(matched, req, ""), scoreSQL, scoreArgs, match, req)
+	if err != nil {
+		return nil, nil, 0, err
 	}
 
-	if req.BrandID != "" {
-		query = query.Where("p.brand_id = ?", req.BrandID)
+	var facets []Filter
+	if req.IncludeFacets {
+		facets, err = r.productFacets(ctx, tenantID, matched, req)
+		if err != nil {
+			return nil, nil, 0, err
+		}
 	}
 
+	return results, facets, total, nil
+}
+
+// applyProductFilters adds the filters of a product search that are not
+// facets
+func applyProductFilters(query *gorm.DB, req *ProductSearchRequest) *gorm.DB {
 	if req.MinPrice != nil {
 		query = query.Where("p.price >= ?", *req.MinPrice)
 	}
@@ -107,30 +146,41 @@ func (r *repository) SearchProducts(ctx context.Context, tenantID uuid.UUID, req
 		query = query.Where("p.price <= ?", *req.MaxPrice)
 	}
 
-	if req.InStock != nil && *req.InStock {
-		query = query.Where("p.stock_quantity > 0")
-	}
-
 	if req.OnSale != nil && *req.OnSale {
-		query = query.Where("p.sale_price IS NOT NULL AND p.sale_price < p.price")
+		query = query.Where("p.compare_price IS NOT NULL AND p.compare_price > p.price")
 	}
 
-	if req.Rating != nil {
-		query = query.Where("p.average_rating >= ?", *req.Rating)
+	if len(req.ExcludeIDs) > 0 {
+		query = query.Where("p.id NOT IN ?", req.ExcludeIDs)
 	}
 
-	// Add tag filters
 	if len(req.Tags) > 0 {
-		query = query.Joins("JOIN product_tags pt ON p.id = pt.product_id").
-			Joins("JOIN tags t ON pt.tag_id = t.id").
-			Where("t.name IN ?", req.Tags)
+		tags := make([]string, len(req.Tags))
+		for i, tag := range req.Tags {
+			tags[i] = strings.ToLower(strings.TrimSpace(tag))
+		}
+		query = query.Where(`EXISTS (
+			SELECT 1 FROM jsonb_array_elements_text(CASE WHEN jsonb_typeof(p.tags) = 'array' THEN p.tags ELSE '[]'::jsonb END) AS tag
+			WHERE LOWER(tag) IN ?)`, tags)
 	}
 
-	// Count total
+	return query
+}
+
+// rankProducts counts, sorts and pages matched products, scoring each with
+// scoreSQL
+func (r *repository) rankProducts(query *gorm.DB, scoreSQL string, scoreArgs []interface{}, match string, req *ProductSearchRequest) ([]*SearchResult, int64, error) {
 	var total int64
 	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, fmt.Errorf("failed to count products: %w", err)
 	}
+	if total == 0 {
+		return []*SearchResult{}, 0, nil
+	}
+
+	query = query.Select(`p.id, p.name AS title, p.slug, p.description, p.sku, p.price, p.compare_price,
+		p.featured_image, p.inventory_quantity, p.track_quantity, p.allow_backorder, d.category_path,
+		p.rating_average AS average_rating, p.review_count, p.created_at, p.updated_at, `+scoreSQL+` AS score`, scoreArgs...)
 
 	// Add sorting
 	switch req.SortBy {
@@ -141,10 +191,9 @@ func (r *repository) SearchProducts(ctx context.Context, tenantID uuid.UUID, req
 	case "newest":
 		query = query.Order("p.created_at DESC")
 	case "rating":
-		query = query.Order("p.average_rating DESC")
+		query = query.Order("p.rating_average DESC NULLS LAST").Order("p.review_count DESC")
 	default:
-		// Relevance sorting (simplified)
-		query = query.Order("p.name ASC")
+		query = query.Order("score DESC").Order("p.created_at DESC")
 	}
 
 	// Add pagination
@@ -152,13 +201,23 @@ func (r *repository) SearchProducts(ctx context.Context, tenantID uuid.UUID, req
 
 	// Execute query
 	var products []struct {
-		ID          string    `json:"id"`
-		Title       string    `json:"title"`
-		Description string    `json:"description"`
-		Price       float64   `json:"price"`
-		ImageURL    string    `json:"image_url"`
-		CreatedAt   time.Time `json:"created_at"`
-		UpdatedAt   time.Time `json:"updated_at"`
+		ID                string
+		Title             string
+		Slug              string
+		Description       string
+		SKU               string `gorm:"column:sku"`
+		Price             float64
+		ComparePrice      *float64
+		FeaturedImage     string
+		InventoryQuantity int
+		TrackQuantity     bool
+		AllowBackorder    bool
+		CategoryPath      *string
+		AverageRating     *float64
+		ReviewCount       int
+		Score             float64
+		CreatedAt         time.Time
+		UpdatedAt         time.Time
 	}
 
 	if err := query.Scan(&products).Error; err != nil {
@@ -166,20 +225,39 @@ func (r *repository) SearchProducts(ctx context.Context, tenantID uuid.UUID, req
 	}
 
 	// Convert to search results
+	results := make([]*SearchResult, 0, len(products))
 	for _, product := range products {
-		result := &SearchResult{
+		price := product.Price
+		metadata := map[string]interface{}{
+			"slug":     product.Slug,
+			"sku":      product.SKU,
+			"in_stock": !product.TrackQuantity || product.AllowBackorder || product.InventoryQuantity > 0,
+			"match":    match,
+		}
+		if product.ComparePrice != nil && *product.ComparePrice > product.Price {
+			metadata["compare_price"] = *product.ComparePrice
+		}
+		if product.CategoryPath != nil {
+			metadata["category_path"] = *product.CategoryPath
+		}
+		if product.AverageRating != nil {
+			metadata["average_rating"] = *product.AverageRating
+			metadata["review_count"] = product.ReviewCount
+		}
+
+		results = append(results, &SearchResult{
 			ID:          product.ID,
 			Type:        "product",
 			Title:       product.Title,
 			Description: product.Description,
 			URL:         fmt.Sprintf("/products/%s", product.ID),
-			ImageURL:    product.ImageURL,
-			Price:       &product.Price,
-			Score:       1.0, // Simplified scoring
+			ImageURL:    product.FeaturedImage,
+			Price:       &price,
+			Score:       product.Score,
+			Metadata:    metadata,
 			CreatedAt:   product.CreatedAt,
 			UpdatedAt:   product.UpdatedAt,
-		}
-		results = append(results, result)
+		})
 	}
 
 	return results, total, nil
@@ -193,22 +271,31 @@ func (r *repository) GetSuggestions(ctx context.Context, tenantID uuid.UUID, que
 
 	switch searchType {
 	case "product", "":
-		// Get product suggestions
-		var productNames []string
-		err := r.db.WithContext(ctx).Table("products").
-			Select("DISTINCT name").
-			Where("tenant_id = ? AND deleted_at IS NULL AND LOWER(name) LIKE ?", tenantID, searchTerm).
+		// Get product suggestions: names starting with the query first, then
+		// close trigram matches so misspelt queries still get suggestions
+		text := strings.Join(tokenize(query), " ")
+		var products []struct {
+			Name  string
+			Score float64
+		}
+		err := r.db.WithContext(ctx).Table("products p").
+			Select("p.name, MAX(CASE WHEN LOWER(p.name) LIKE ? THEN 1 ELSE word_similarity(?, d.search_text) END) AS score", searchTerm, text).
+			Joins("JOIN product_search_documents d ON d.product_id = p.id").
+			Where("p.tenant_id = ? AND p.status = ?", tenantID, "active").
+			Where("(LOWER(p.name) LIKE ? OR word_similarity(?, d.search_text) >= ?)", searchTerm, text, fuzzyThreshold).
+			Group("p.name").
+			Order("score DESC").
 			Limit(limit).
-			Pluck("name", &productNames)
+			Scan(&products).Error
 		if err != nil {
 			return nil, fmt.Errorf("failed to get product suggestions: %w", err)
 		}
 
-		for i, name := range productNames {
+		for _, product := range products {
 			suggestions = append(suggestions, Suggestion{
-				Text:  name,
+				Text:  product.Name,
 				Type:  "product",
-				Score: float64(limit - i), // Simple scoring
+				Score: product.Score,
 			})
 		}
 
@@ -217,9 +304,9 @@ func (r *repository) GetSuggestions(ctx context.Context, tenantID uuid.UUID, que
 		var categoryNames []string
 		err := r.db.WithContext(ctx).Table("categories").
 			Select("DISTINCT name").
-			Where("tenant_id = ? AND deleted_at IS NULL AND LOWER(name) LIKE ?", tenantID, searchTerm).
+			Where("tenant_id = ? AND is_active = ? AND LOWER(name) LIKE ?", tenantID, true, searchTerm).
 			Limit(limit).
-			Pluck("name", &categoryNames)
+			Pluck("name", &categoryNames).Error
 		if err != nil {
 			return nil, fmt.Errorf("failed to get category suggestions: %w", err)
 		}
@@ -260,7 +347,7 @@ func (r *repository) GetSearchAnalytics(ctx context.Context, tenantID uuid.UUID,
 	// Get top queries
 	var topQueries []QueryStat
 	err = r.db.WithContext(ctx).Table("search_logs").
-		Select("query, COUNT(*) as count, AVG(results) as result_count").
+		Select("query, COUNT(*) as count, AVG(results) as result_count, AVG(clicked::int) as click_rate").
 		Where("tenant_id = ? AND created_at BETWEEN ? AND ?", tenantID, req.StartDate, req.EndDate).
 		Group("query").
 		Order("count DESC").
@@ -275,7 +362,7 @@ func (r *repository) GetSearchAnalytics(ctx context.Context, tenantID uuid.UUID,
 	var noResults []QueryStat
 	err = r.db.WithContext(ctx).Table("search_logs").
 		Select("query, COUNT(*) as count").
-		Where("tenant_id = ? AND created_at BETWEEN ? AND ? AND results = 0", tenantID, req.StartDate, req.EndDate).
+		Where("tenant_id = ? AND created_at BETWEEN ? AND ? AND results = 0 AND NOT redirected", tenantID, req.StartDate, req.EndDate).
 		Group("query").
 		Order("count DESC").
 		Limit(req.Limit).
@@ -285,6 +372,21 @@ func (r *repository) GetSearchAnalytics(ctx context.Context, tenantID uuid.UUID,
 	}
 	response.NoResults = noResults
 
+	// Get queries whose results are seldom clicked
+	var lowClickRate []QueryStat
+	err = r.db.WithContext(ctx).Table("search_logs").
+		Select("query, COUNT(*) as count, AVG(results) as result_count, AVG(clicked::int) as click_rate").
+		Where("tenant_id = ? AND created_at BETWEEN ? AND ? AND results > 0 AND NOT redirected", tenantID, req.StartDate, req.EndDate).
+		Group("query").
+		Having("COUNT(*) >= ? AND AVG(clicked::int) < ?", lowClickRateMinSearches, lowClickRateThreshold).
+		Order("count DESC").
+		Limit(req.Limit).
+		Scan(&lowClickRate).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get low click rate queries: %w", err)
+	}
+	response.LowClickRate = lowClickRate
+
 	// Calculate metrics
 	var uniqueQueries int64
 	err = r.db.WithContext(ctx).Table("search_logs").
@@ -306,12 +408,24 @@ func (r *repository) GetSearchAnalytics(ctx context.Context, tenantID uuid.UUID,
 
 	var noResultsCount int64
 	err = r.db.WithContext(ctx).Model(&SearchLog{}).
-		Where("tenant_id = ? AND created_at BETWEEN ? AND ? AND results = 0", tenantID, req.StartDate, req.EndDate).
+		Where("tenant_id = ? AND created_at BETWEEN ? AND ? AND results = 0 AND NOT redirected", tenantID, req.StartDate, req.EndDate).
 		Count(&noResultsCount).Error
 	if err != nil {
 		return nil, fmt.Errorf("failed to get no results count: %w", err)
 	}
 
+	var clicks struct {
+		ClickThroughRate float64
+		AveragePosition  float64
+	}
+	err = r.db.WithContext(ctx).Table("search_logs").
+		Select("COALESCE(AVG(clicked::int), 0) as click_through_rate, COALESCE(AVG(position), 0) as average_position").
+		Where("tenant_id = ? AND created_at BETWEEN ? AND ? AND NOT redirected", tenantID, req.StartDate, req.EndDate).
+		Scan(&clicks).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get click metrics: %w", err)
+	}
+
 	noResultsRate := float64(0)
 	if totalSearches > 0 {
 		noResultsRate = float64(noResultsCount) / float64(totalSearches) * 100
@@ -322,67 +436,25 @@ func (r *repository) GetSearchAnalytics(ctx context.Context, tenantID uuid.UUID,
 		UniqueQueries:    uniqueQueries,
 		AverageResults:   avgResults,
 		NoResultsRate:    noResultsRate,
-		ClickThroughRate: 0, // Would need click tracking
-		AveragePosition:  0, // Would need position tracking
+		ClickThroughRate: clicks.ClickThroughRate * 100,
+		AveragePosition:  clicks.AveragePosition,
 	}
 
 	return response, nil
 }
 
-// GetFilters returns available search filters
+// GetFilters returns available search filters with counts over the whole
+// active catalog
 func (r *repository) GetFilters(ctx context.Context, tenantID uuid.UUID, searchType string) ([]Filter, error) {
-	var filters []Filter
-
-	// This is a simplified implementation
-	// In a real system, you'd have a filters table
 	switch searchType {
 	case "product":
-		// Category filter
-		var categories []struct {
-			ID   string `json:"id"`
-			Name string `json:"name"`
-		}
-		err := r.db.WithContext(ctx).Table("categories").
-			Select("id, name").
-			Where("tenant_id = ? AND deleted_at IS NULL", tenantID).
-			Scan(&categories).Error
-		if err != nil {
-			return nil, fmt.Errorf("failed to get categories: %w", err)
-		}
-
-		var categoryValues []FilterValue
-		for _, cat := range categories {
-			categoryValues = append(categoryValues, FilterValue{
-				Value: cat.ID,
-				Label: cat.Name,
-				Count: 0, // Would need to count products
-			})
-		}
-
-		filters = append(filters, Filter{
-			ID:       "category",
-			Type:     "category",
-			Name:     "Category",
-			Values:   categoryValues,
-			IsActive: true,
-		})
-
-		// Price range filter
-		filters = append(filters, Filter{
-			ID:   "price",
-			Type: "range",
-			Name: "Price Range",
-			Values: []FilterValue{
-				{Value: "0-50", Label: "Under $50", Count: 0},
-				{Value: "50-100", Label: "$50 - $100", Count: 0},
-				{Value: "100-200", Label: "$100 - $200", Count: 0},
-				{Value: "200+", Label: "Over $200", Count: 0},
-			},
-			IsActive: true,
-		})
+		catalog := r.db.WithContext(ctx).Table("products p").
+			Where("p.tenant_id = ? AND p.status = ?", tenantID, "active").
+			Session(&gorm.Session{})
+		return r.productFacets(ctx, tenantID, catalog, &ProductSearchRequest{})
 	}
 
-	return filters, nil
+	return []Filter{}, nil
 }
 
 // ManageFilter manages search filters (create, update, delete)
@@ -392,8 +464,138 @@ func (r *repository) ManageFilter(ctx context.Context, tenantID uuid.UUID, req *
 	return nil
 }
 
+// ListSynonyms returns the search synonyms of a tenant
+func (r *repository) ListSynonyms(ctx context.Context, tenantID uuid.UUID) ([]SearchSynonym, error) {
+	return r.listSynonyms(ctx, tenantID)
+}
+
+// GetSynonym returns a search synonym by ID
+func (r *repository) GetSynonym(ctx context.Context, tenantID, id uuid.UUID) (*SearchSynonym, error) {
+	var synonym SearchSynonym
+	err := r.db.WithContext(ctx).Where("tenant_id = ? AND id = ?", tenantID, id).First(&synonym).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSynonymNotFound
+		}
+		return nil, fmt.Errorf("failed to get synonym: %w", err)
+	}
+	return &synonym, nil
+}
+
+// CreateSynonym creates a search synonym
+func (r *repository) CreateSynonym(ctx context.Context, synonym *SearchSynonym) error {
+	return r.db.WithContext(ctx).Create(synonym).Error
+}
+
+// UpdateSynonym updates a search synonym
+func (r *repository) UpdateSynonym(ctx context.Context, synonym *SearchSynonym) error {
+	return r.db.WithContext(ctx).Save(synonym).Error
+}
+
+// DeleteSynonym deletes a search synonym
+func (r *repository) DeleteSynonym(ctx context.Context, tenantID, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).Where("tenant_id = ? AND id = ?", tenantID, id).Delete(&SearchSynonym{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete synonym: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrSynonymNotFound
+	}
+	return nil
+}
+
+// ListRules returns the merchandising rules of a tenant
+func (r *repository) ListRules(ctx context.Context, tenantID uuid.UUID) ([]SearchRule, error) {
+	var rules []SearchRule
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ?", tenantID).
+		Order("priority DESC, created_at ASC").
+		Find(&rules).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list search rules: %w", err)
+	}
+	return rules, nil
+}
+
+// ListActiveRules returns the active merchandising rules of a tenant in
+// priority order. Schedules are checked by the caller.
+func (r *repository) ListActiveRules(ctx context.Context, tenantID uuid.UUID) ([]SearchRule, error) {
+	var rules []SearchRule
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND is_active = ?", tenantID, true).
+		Order("priority DESC, created_at ASC").
+		Find(&rules).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list search rules: %w", err)
+	}
+	return rules, nil
+}
+
+// GetRule returns a merchandising rule by ID
+func (r *repository) GetRule(ctx context.Context, tenantID, id uuid.UUID) (*SearchRule, error) {
+	var rule SearchRule
+	err := r.db.WithContext(ctx).Where("tenant_id = ? AND id = ?", tenantID, id).First(&rule).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRuleNotFound
+		}
+		return nil, fmt.Errorf("failed to get search rule: %w", err)
+	}
+	return &rule, nil
+}
+
+// CreateRule creates a merchandising rule
+func (r *repository) CreateRule(ctx context.Context, rule *SearchRule) error {
+	return r.db.WithContext(ctx).Create(rule).Error
+}
+
+// UpdateRule updates a merchandising rule
+func (r *repository) UpdateRule(ctx context.Context, rule *SearchRule) error {
+	return r.db.WithContext(ctx).Save(rule).Error
+}
+
+// DeleteRule deletes a merchandising rule
+func (r *repository) DeleteRule(ctx context.Context, tenantID, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).Where("tenant_id = ? AND id = ?", tenantID, id).Delete(&SearchRule{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete search rule: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrRuleNotFound
+	}
+	return nil
+}
+
+// RecordClick marks a logged search as clicked. The first click sets the
+// position.
+func (r *repository) RecordClick(ctx context.Context, tenantID, searchID uuid.UUID, position *int) error {
+	result := r.db.WithContext(ctx).Model(&SearchLog{}).
+		Where("tenant_id = ? AND id = ?", tenantID, searchID).
+		Updates(map[string]interface{}{
+			"clicked":  true,
+			"position": gorm.Expr("COALESCE(position, ?)", position),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to record search click: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrSearchNotFound
+	}
+	return nil
+}
+
 // Helper methods
 
+func (r *repository) listSynonyms(ctx context.Context, tenantID uuid.UUID) ([]SearchSynonym, error) {
+	var synonyms []SearchSynonym
+	err := r.db.WithContext(ctx).Where("tenant_id = ?", tenantID).Order("created_at ASC").Find(&synonyms).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get synonyms: %w", err)
+	}
+	return synonyms, nil
+}
+
+
 func (r *repository) searchProducts(ctx context.Context, tenantID uuid.UUID, query *SearchQuery) ([]*SearchResult, int64, error) {
 	// Convert to ProductSearchRequest
 	productReq := &ProductSearchRequest{
@@ -402,7 +604,8 @@ func (r *repository) searchProducts(ctx context.Context, tenantID uuid.UUID, que
 		Limit:  query.Limit,
 		SortBy: query.SortBy,
 	}
-	return r.SearchProducts(ctx, tenantID, productReq)
+	results, _, total, err := r.SearchProducts(ctx, tenantID, productReq)
+	return results, total, err
 }
 
 func (r *repository) searchCategories(ctx context.Context, tenantID uuid.UUID, query *SearchQuery) ([]*SearchResult, int64, error) {
@@ -420,8 +623,8 @@ func (r *repository) searchCategories(ctx context.Context, tenantID uuid.UUID, q
 
 	err := r.db.WithContext(ctx).Table("categories").
 		Select("id, name, description, created_at, updated_at").
-		Where("tenant_id = ? AND deleted_at IS NULL AND (LOWER(name) LIKE ? OR LOWER(description) LIKE ?)", 
-			tenantID, searchTerm, searchTerm).
+		Where("tenant_id = ? AND is_active = ? AND (LOWER(name) LIKE ? OR LOWER(description) LIKE ?)", 
+			tenantID, true, searchTerm, searchTerm).
 		Offset(query.Offset).Limit(query.Limit).
 		Scan(&categories).Error
 	if err != nil {
@@ -468,4 +671,400 @@ func (r *repository) paginateResults(results []*SearchResult, offset, limit int)
 	}
 
 	return results[offset:end]
-}
\ No newline at end of file
+}
+
+// Search index support
+
+// IndexState counts how far a search index is behind the catalog
+type IndexState struct {
+	Products      int64
+	Documents     int64
+	Pending       int64
+	OldestPending *time.Time
+	LastIndexedAt *time.Time
+}
+
+// FilterableAttributes returns the product attributes offered as search
+// facets in display order
+func (r *repository) FilterableAttributes(ctx context.Context, tenantID uuid.UUID) ([]attributeField, error) {
+	var fields []attributeField
+	err := r.db.WithContext(ctx).Table("attribute_definitions").
+		Select("code, name, type, unit, sort_order").
+		Where("tenant_id = ? AND filterable = ?", tenantID, true).
+		Order("sort_order ASC, name ASC").
+		Scan(&fields).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get filterable attributes: %w", err)
+	}
+	return fields, nil
+}
+
+// GetProductDocuments loads the search documents of the active products among
+// productIDs; inactive and missing products are left out
+func (r *repository) GetProductDocuments(ctx context.Context, tenantID uuid.UUID, productIDs []uuid.UUID) ([]ProductDocument, error) {
+	if len(productIDs) == 0 {
+		return nil, nil
+	}
+
+	var products []struct {
+		ID            uuid.UUID
+		TenantID      uuid.UUID
+		Name          string
+		Slug          string
+		Description   string
+		SKU           string `gorm:"column:sku"`
+		Barcode       *string
+		Tags          *string
+		CategoryID    *uuid.UUID
+		Price         float64
+		ComparePrice  *float64
+		InStock       bool
+		Rating        *float64
+		ReviewCount   int
+		Margin        *float64
+		Attributes    *string
+		FeaturedImage *string
+		CreatedAt     time.Time
+		UpdatedAt     time.Time
+	}
+	err := r.db.WithContext(ctx).Table("products p").
+		Select(`p.id, p.tenant_id, p.name, p.slug, p.description, p.sku, p.barcode, p.tags::text AS tags,
+			p.category_id, p.price, p.compare_price, `+inStockSQL+` AS in_stock, p.rating_average AS rating, p.review_count,
+			CASE WHEN p.cost_price IS NOT NULL AND p.price > 0 THEN (p.price - p.cost_price) / p.price END AS margin,
+			p.attributes::text AS attributes, p.featured_image, p.created_at, p.updated_at`).
+		Where("p.tenant_id = ? AND p.id IN ? AND p.status = ?", tenantID, productIDs, "active").
+		Scan(&products).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get products: %w", err)
+	}
+	if len(products) == 0 {
+		return nil, nil
+	}
+
+	var variants []struct {
+		ProductID uuid.UUID
+		Options   *string
+	}
+	err = r.db.WithContext(ctx).Table("product_variants").
+		Select("product_id, options::text AS options").
+		Where("product_id IN ?", productIDs).
+		Scan(&variants).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product variants: %w", err)
+	}
+	options := make(map[uuid.UUID]map[string][]string)
+	for _, variant := range variants {
+		if variant.Options == nil {
+			continue
+		}
+		var values map[string]string
+		if err := json.Unmarshal([]byte(*variant.Options), &values); err != nil {
+			continue
+		}
+		if options[variant.ProductID] == nil {
+			options[variant.ProductID] = make(map[string][]string)
+		}
+		for key, value := range values {
+			options[variant.ProductID][key] = appendUnique(options[variant.ProductID][key], value)
+		}
+	}
+
+	fields, err := r.FilterableAttributes(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	filterable := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		filterable[field.Code] = true
+	}
+
+	nodes, err := r.categoryNodes(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]categoryNode, len(nodes))
+	for _, node := range nodes {
+		byID[node.ID] = node
+	}
+
+	var memberships []struct {
+		ProductID uuid.UUID
+		ID        string
+		Title     string
+	}
+	err = r.db.WithContext(ctx).Table("collection_products cp").
+		Select("cp.product_id, c.id, c.title").
+		Joins("JOIN collections c ON c.id = cp.collection_id").
+		Where("c.tenant_id = ? AND c.is_published = TRUE AND cp.product_id IN ?", tenantID, productIDs).
+		Order("c.title ASC").
+		Scan(&memberships).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product collections: %w", err)
+	}
+	collections := make(map[uuid.UUID][]collectionNode)
+	for _, membership := range memberships {
+		collections[membership.ProductID] = append(collections[membership.ProductID],
+			collectionNode{ID: membership.ID, Title: membership.Title})
+	}
+
+	documents := make([]ProductDocument, 0, len(products))
+	for _, product := range products {
+		document := ProductDocument{
+			ID:           product.ID,
+			TenantID:     product.TenantID,
+			Name:         product.Name,
+			Slug:         product.Slug,
+			Description:  product.Description,
+			SKU:          product.SKU,
+			CategoryID:   product.CategoryID,
+			CategoryIDs:  []string{},
+			CollectionIDs: []string{},
+			Collections:  []string{},
+			Price:        product.Price,
+			ComparePrice: product.ComparePrice,
+			OnSale:       product.ComparePrice != nil && *product.ComparePrice > product.Price,
+			InStock:      product.InStock,
+			Rating:       product.Rating,
+			ReviewCount:  product.ReviewCount,
+			Margin:       product.Margin,
+			Options:      options[product.ID],
+			CreatedAt:    product.CreatedAt,
+			UpdatedAt:    product.UpdatedAt,
+		}
+		if product.Barcode != nil {
+			document.Barcode = *product.Barcode
+		}
+		if product.FeaturedImage != nil {
+			document.FeaturedImage = *product.FeaturedImage
+		}
+		if document.Options == nil {
+			document.Options = map[string][]string{}
+		}
+		document.Attributes = documentAttributes(product.Attributes, filterable)
+		for _, collection := range collections[product.ID] {
+			document.CollectionIDs = append(document.CollectionIDs, collection.ID)
+			document.Collections = append(document.Collections, collection.Title)
+		}
+
+		document.Tags = []string{}
+		if product.Tags != nil {
+			var tags []string
+			if err := json.Unmarshal([]byte(*product.Tags), &tags); err == nil {
+				for _, tag := range tags {
+					document.Tags = appendUnique(document.Tags, strings.ToLower(strings.TrimSpace(tag)))
+				}
+			}
+		}
+
+		// Walk up to the root, guarding against cycles in broken trees
+		if product.CategoryID != nil {
+			var names []string
+			id := product.CategoryID.String()
+			for depth := 0; depth < 16; depth++ {
+				node, ok := byID[id]
+				if !ok {
+					break
+				}
+				document.CategoryIDs = append([]string{node.ID}, document.CategoryIDs...)
+				names = append([]string{node.Name}, names...)
+				if node.ParentID == nil {
+					break
+				}
+				id = *node.ParentID
+			}
+			document.CategoryPath = strings.Join(names, " > ")
+		}
+
+		documents = append(documents, document)
+	}
+
+	return documents, nil
+}
+
+// ListProductIDs returns active product IDs of a tenant in ID order, starting
+// after the given ID
+func (r *repository) ListProductIDs(ctx context.Context, tenantID uuid.UUID, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.WithContext(ctx).Table("products").
+		Where("tenant_id = ? AND status = ? AND id > ?", tenantID, "active", after).
+		Order("id ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list products: %w", err)
+	}
+	return ids, nil
+}
+
+// ProductIDsInCategories returns the products of the categories and their
+// subcategories
+func (r *repository) ProductIDsInCategories(ctx context.Context, tenantID uuid.UUID, categoryIDs []uuid.UUID) ([]uuid.UUID, error) {
+	if len(categoryIDs) == 0 {
+		return nil, nil
+	}
+
+	var ids []uuid.UUID
+	err := r.db.WithContext(ctx).Table("products").
+		Where(`tenant_id = ? AND category_id IN (
+			WITH RECURSIVE tree AS (
+				SELECT id FROM categories WHERE id IN ?
+				UNION
+				SELECT c.id FROM categories c JOIN tree ON c.parent_id = tree.id
+			)
+			SELECT id FROM tree)`, tenantID, categoryIDs).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list category products: %w", err)
+	}
+	return ids, nil
+}
+
+// ListTenantIDs returns the tenants that have products
+func (r *repository) ListTenantIDs(ctx context.Context) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.WithContext(ctx).Table("products").Distinct("tenant_id").Pluck("tenant_id", &ids).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %w", err)
+	}
+	return ids, nil
+}
+
+// CategoryNodes returns every category of a tenant in display order
+func (r *repository) CategoryNodes(ctx context.Context, tenantID uuid.UUID) ([]categoryNode, error) {
+	return r.categoryNodes(ctx, tenantID)
+}
+
+// CollectionNodes returns the published collections of a tenant
+func (r *repository) CollectionNodes(ctx context.Context, tenantID uuid.UUID) ([]collectionNode, error) {
+	return r.collectionNodes(ctx, tenantID)
+}
+
+// RefreshDocuments rebuilds the Postgres search documents of products. The
+// database triggers keep documents current; this is for backfills and for
+// recovering from writes made with the triggers disabled.
+func (r *repository) RefreshDocuments(ctx context.Context, tenantID uuid.UUID, productIDs []uuid.UUID) error {
+	if len(productIDs) == 0 {
+		return nil
+	}
+
+	err := r.db.WithContext(ctx).Exec(`SELECT refresh_product_search_document(id) FROM products WHERE tenant_id = ? AND id IN ?`,
+		tenantID, productIDs).Error
+	if err != nil {
+		return fmt.Errorf("failed to refresh search documents: %w", err)
+	}
+	return nil
+}
+
+// DocumentStatus reports the active products of a tenant without a Postgres
+// search document
+func (r *repository) DocumentStatus(ctx context.Context, tenantID uuid.UUID) (*IndexState, error) {
+	var status IndexState
+	err := r.db.WithContext(ctx).Table("products p").
+		Joins("LEFT JOIN product_search_documents d ON d.product_id = p.id").
+		Select(`COUNT(*) AS products,
+			COUNT(d.product_id) AS documents,
+			COUNT(*) FILTER (WHERE d.product_id IS NULL) AS pending,
+			MIN(p.updated_at) FILTER (WHERE d.product_id IS NULL) AS oldest_pending,
+			MAX(d.updated_at) AS last_indexed_at`).
+		Where("p.tenant_id = ? AND p.status = ?", tenantID, "active").
+		Scan(&status).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get search document status: %w", err)
+	}
+	return &status, nil
+}
+
+// PendingDocuments returns active products without a Postgres search document
+func (r *repository) PendingDocuments(ctx context.Context, tenantID uuid.UUID, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.WithContext(ctx).Table("products p").
+		Where("p.tenant_id = ? AND p.status = ?", tenantID, "active").
+		Where("NOT EXISTS (SELECT 1 FROM product_search_documents d WHERE d.product_id = p.id)").
+		Order("p.updated_at ASC").
+		Limit(limit).
+		Pluck("p.id", &ids).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending search documents: %w", err)
+	}
+	return ids, nil
+}
+
+// MarkIndexed records that products were sent to an external index
+func (r *repository) MarkIndexed(ctx context.Context, backend string, tenantID uuid.UUID, productIDs []uuid.UUID) error {
+	if len(productIDs) == 0 {
+		return nil
+	}
+
+	err := r.db.WithContext(ctx).Exec(`INSERT INTO search_index_states (backend, product_id, tenant_id, indexed_at)
+		SELECT ?, id, tenant_id, NOW() FROM products WHERE tenant_id = ? AND id IN ?
+		ON CONFLICT (backend, product_id) DO UPDATE SET indexed_at = EXCLUDED.indexed_at`,
+		backend, tenantID, productIDs).Error
+	if err != nil {
+		return fmt.Errorf("failed to record indexed products: %w", err)
+	}
+	return nil
+}
+
+// ClearIndexed records that products were removed from an external index
+func (r *repository) ClearIndexed(ctx context.Context, backend string, tenantID uuid.UUID, productIDs []uuid.UUID) error {
+	if len(productIDs) == 0 {
+		return nil
+	}
+
+	err := r.db.WithContext(ctx).Exec(`DELETE FROM search_index_states WHERE backend = ? AND tenant_id = ? AND product_id IN ?`,
+		backend, tenantID, productIDs).Error
+	if err != nil {
+		return fmt.Errorf("failed to record removed products: %w", err)
+	}
+	return nil
+}
+
+// pendingIndexSQL selects the products of tenant ? that an external index ?
+// has to add, update or remove, with the time they changed
+const pendingIndexSQL = `SELECT p.id, p.updated_at FROM products p
+	LEFT JOIN search_index_states s ON s.product_id = p.id AND s.backend = ?
+	WHERE p.tenant_id = ? AND (
+		(p.status = 'active' AND (s.product_id IS NULL OR s.indexed_at < p.updated_at))
+		OR (p.status <> 'active' AND s.product_id IS NOT NULL)
+	)`
+
+// IndexStatus reports how far an external index is behind the catalog
+func (r *repository) IndexStatus(ctx context.Context, backend string, tenantID uuid.UUID) (*IndexState, error) {
+	var status IndexState
+	err := r.db.WithContext(ctx).Table("products").
+		Where("tenant_id = ? AND status = ?", tenantID, "active").
+		Count(&status.Products).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count products: %w", err)
+	}
+
+	err = r.db.WithContext(ctx).Table("search_index_states").
+		Select("COUNT(*) AS documents, MAX(indexed_at) AS last_indexed_at").
+		Where("backend = ? AND tenant_id = ?", backend, tenantID).
+		Scan(&status).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get index state: %w", err)
+	}
+
+	err = r.db.WithContext(ctx).Raw(`SELECT COUNT(*) AS pending, MIN(updated_at) AS oldest_pending
+		FROM (`+pendingIndexSQL+`) AS pending`, backend, tenantID).
+		Scan(&status).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count pending products: %w", err)
+	}
+
+	return &status, nil
+}
+
+// PendingProducts returns the products an external index has to add, update
+// or remove, oldest change first
+func (r *repository) PendingProducts(ctx context.Context, backend string, tenantID uuid.UUID, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.WithContext(ctx).Raw(`SELECT id FROM (`+pendingIndexSQL+`) AS pending ORDER BY updated_at ASC LIMIT ?`,
+		backend, tenantID, limit).
+		Scan(&ids).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending products: %w", err)
+	}
+	return ids, nil
+}
@@ -1,11 +1,27 @@
 package search
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+var (
+	ErrSynonymNotFound = errors.New("synonym not found")
+	ErrInvalidSynonym  = errors.New("a synonym needs at least two different terms")
+	ErrRuleNotFound    = errors.New("search rule not found")
+	ErrInvalidRule     = errors.New("invalid search rule")
+	ErrSearchNotFound  = errors.New("search not found")
+)
+
+// Match types reported in SearchResult metadata
+const (
+	MatchFullText = "fulltext"
+	MatchFuzzy    = "fuzzy"
+	MatchPinned   = "pinned"
+)
+
 // SearchResult represents a search result item
 type SearchResult struct {
 	ID          string                 `json:"id"`
@@ -44,24 +60,40 @@ type SearchResponse struct {
 	Query       string                 `json:"query"`
 	Suggestions []string               `json:"suggestions,omitempty"`
 	Filters     map[string]interface{} `json:"filters,omitempty"`
-	Facets      map[string]interface{} `json:"facets,omitempty"`
+	Facets      []Filter               `json:"facets,omitempty"`
+	SearchID    string                 `json:"search_id,omitempty"`     // pass back when recording a click
+	RedirectURL string                 `json:"redirect_url,omitempty"`  // set when a rule sends the query elsewhere
+	Rules       []string               `json:"applied_rules,omitempty"` // IDs of the merchandising rules applied
 }
 
-// ProductSearchRequest represents product-specific search
+// ProductSearchRequest represents product-specific search. Facet selections
+// (categories, collections, price ranges, availability, rating, variant
+// options and product attributes) are ORed within a facet and ANDed across
+// facets.
 type ProductSearchRequest struct {
-	Query       string   `json:"query" validate:"required"`
-	CategoryID  string   `json:"category_id,omitempty"`
-	BrandID     string   `json:"brand_id,omitempty"`
-	Tags        []string `json:"tags,omitempty"`
-	MinPrice    *float64 `json:"min_price,omitempty"`
-	MaxPrice    *float64 `json:"max_price,omitempty"`
-	InStock     *bool    `json:"in_stock,omitempty"`
-	OnSale      *bool    `json:"on_sale,omitempty"`
-	Rating      *float64 `json:"min_rating,omitempty"`
-	SortBy      string   `json:"sort_by,omitempty"`
-	Offset      int      `json:"offset,omitempty"`
-	Limit       int      `json:"limit,omitempty"`
-	IncludeFacets bool   `json:"include_facets,omitempty"`
+	Query        string              `json:"query" validate:"required"`
+	CategoryID   string              `json:"category_id,omitempty"`
+	CategoryIDs  []string            `json:"category_ids,omitempty"`
+	CollectionIDs []string           `json:"collection_ids,omitempty"`
+	BrandID      string              `json:"brand_id,omitempty"`
+	Tags         []string            `json:"tags,omitempty"`
+	MinPrice     *float64            `json:"min_price,omitempty"`
+	MaxPrice     *float64            `json:"max_price,omitempty"`
+	PriceRanges  []string            `json:"price_ranges,omitempty"` // "from-to", either bound may be empty
+	InStock      *bool               `json:"in_stock,omitempty"`
+	Availability []string            `json:"availability,omitempty"` // in_stock, out_of_stock
+	OnSale       *bool               `json:"on_sale,omitempty"`
+	Rating       *float64            `json:"min_rating,omitempty"`
+	Options      map[string][]string `json:"options,omitempty"` // variant option key to accepted values
+	Attributes   map[string][]string `json:"attributes,omitempty"` // product attribute code to accepted values
+	SortBy       string              `json:"sort_by,omitempty"`
+	Offset       int                 `json:"offset,omitempty"`
+	Limit        int                 `json:"limit,omitempty"`
+	IncludeFacets bool               `json:"include_facets,omitempty"`
+
+	// Set from merchandising rules, never by clients
+	ExcludeIDs []string      `json:"-"`
+	Boosts     []SearchBoost `json:"-"`
 }
 
 // SuggestionRequest represents search suggestion parameters
@@ -97,6 +129,7 @@ type SearchAnalyticsRequest struct {
 type SearchAnalyticsResponse struct {
 	TopQueries    []QueryStat    `json:"top_queries,omitempty"`
 	NoResults     []QueryStat    `json:"no_results,omitempty"`
+	LowClickRate  []QueryStat    `json:"low_click_rate,omitempty"`
 	Trends        []TrendData    `json:"trends,omitempty"`
 	TotalSearches int64          `json:"total_searches"`
 	Period        string         `json:"period"`
@@ -109,6 +142,7 @@ type QueryStat struct {
 	Count       int64   `json:"count"`
 	ResultCount int64   `json:"result_count"`
 	ClickRate   float64 `json:"click_rate"`
+	RuleIDs     []string `json:"rule_ids,omitempty"` // active rules already matching the query
 }
 
 // TrendData represents search trend data
@@ -155,9 +189,13 @@ type Filter struct {
 
 // FilterValue represents a filter option
 type FilterValue struct {
-	Value string `json:"value"`
-	Label string `json:"label"`
-	Count int64  `json:"count"`
+	Value    string        `json:"value"`
+	Label    string        `json:"label"`
+	Count    int64         `json:"count"`
+	Selected bool          `json:"selected,omitempty"`
+	From     *float64      `json:"from,omitempty"`     // price buckets
+	To       *float64      `json:"to,omitempty"`       // price buckets, exclusive
+	Children []FilterValue `json:"children,omitempty"` // category tree
 }
 
 // SearchLog represents search activity logging
@@ -171,9 +209,132 @@ type SearchLog struct {
 	Results   int64     `json:"results"`
 	Clicked   bool      `json:"clicked" gorm:"default:false"`
 	Position  *int      `json:"position,omitempty"`
+	Redirected bool     `json:"redirected" gorm:"default:false"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
 func (SearchLog) TableName() string {
 	return "search_logs"
-}
\ No newline at end of file
+}
+
+// ProductSearchDocument is the indexed text of a product. Rows are written by
+// the refresh_product_search_document database function whenever a product or
+// its category changes; the application only reads them.
+type ProductSearchDocument struct {
+	ProductID    uuid.UUID `json:"product_id" gorm:"type:uuid;primary_key"`
+	TenantID     uuid.UUID `json:"tenant_id" gorm:"type:uuid;not null;index"`
+	CategoryPath string    `json:"category_path,omitempty"`
+	SearchText   string    `json:"search_text"`
+	Document     string    `json:"-" gorm:"type:tsvector"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func (ProductSearchDocument) TableName() string {
+	return "product_search_documents"
+}
+
+// SearchSynonym is a group of terms that match each other in product search.
+// A one-way synonym only expands its first term: with "phone, smartphone" a
+// search for "phone" also finds smartphones but not the other way round.
+type SearchSynonym struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TenantID  uuid.UUID `json:"tenant_id" gorm:"type:uuid;not null;index"`
+	Terms     []string  `json:"terms" gorm:"serializer:json;type:jsonb;not null"`
+	OneWay    bool      `json:"one_way" gorm:"default:false"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (SearchSynonym) TableName() string {
+	return "search_synonyms"
+}
+
+// SynonymRequest represents synonym creation and update
+type SynonymRequest struct {
+	Terms  []string `json:"terms" binding:"required"`
+	OneWay bool     `json:"one_way"`
+}
+
+// SynonymResponse represents the synonyms of a tenant
+type SynonymResponse struct {
+	Synonyms []SearchSynonym `json:"synonyms"`
+}
+
+// Search rule match types
+const (
+	RuleMatchExact    = "exact"    // the whole query equals the rule query
+	RuleMatchContains = "contains" // the rule query appears in the query as a phrase
+)
+
+// Search boost types
+const (
+	BoostTag      = "tag"
+	BoostCategory = "category"
+	BoostInStock  = "in_stock"
+	BoostMargin   = "margin"
+	BoostRating   = "rating"
+)
+
+// SearchRule merchandises the product results of matching queries. Pinned
+// products are shown first in the given order, hidden products never show,
+// boosts raise (or with a negative weight lower) the relevance of matching
+// products and a redirect sends the shopper to a page instead of results.
+type SearchRule struct {
+	ID               uuid.UUID     `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TenantID         uuid.UUID     `json:"tenant_id" gorm:"type:uuid;not null;index"`
+	Name             string        `json:"name" gorm:"not null"`
+	Query            string        `json:"query" gorm:"not null"`
+	MatchType        string        `json:"match_type" gorm:"default:'exact'"`
+	PinnedProductIDs []string      `json:"pinned_product_ids" gorm:"serializer:json;type:jsonb"`
+	HiddenProductIDs []string      `json:"hidden_product_ids" gorm:"serializer:json;type:jsonb"`
+	Boosts           []SearchBoost `json:"boosts" gorm:"serializer:json;type:jsonb"`
+	RedirectURL      string        `json:"redirect_url,omitempty"`
+	Priority         int           `json:"priority" gorm:"default:0"`
+	IsActive         bool          `json:"is_active" gorm:"default:true"`
+	StartsAt         *time.Time    `json:"starts_at,omitempty"`
+	EndsAt           *time.Time    `json:"ends_at,omitempty"`
+	CreatedAt        time.Time     `json:"created_at"`
+	UpdatedAt        time.Time     `json:"updated_at"`
+}
+
+func (SearchRule) TableName() string {
+	return "search_rules"
+}
+
+// SearchBoost multiplies the relevance of products matching it by 1 + Weight.
+// Margin boosts scale the weight by the product's margin (0 to 1) and rating
+// boosts by its review rating (0 to 1, damped for products with few reviews).
+type SearchBoost struct {
+	Type   string  `json:"type"`            // tag, category, in_stock, margin, rating
+	Value  string  `json:"value,omitempty"` // tag name or category ID
+	Weight float64 `json:"weight"`
+}
+
+// SearchRuleRequest represents search rule creation and update. A rule can be
+// started from an analytics report row with its query and a single action:
+// the name defaults to the query and the match type to exact.
+type SearchRuleRequest struct {
+	Name             string        `json:"name"`
+	Query            string        `json:"query" binding:"required"`
+	MatchType        string        `json:"match_type"`
+	PinnedProductIDs []string      `json:"pinned_product_ids"`
+	HiddenProductIDs []string      `json:"hidden_product_ids"`
+	Boosts           []SearchBoost `json:"boosts"`
+	RedirectURL      string        `json:"redirect_url"`
+	Priority         int           `json:"priority"`
+	IsActive         *bool         `json:"is_active"`
+	StartsAt         *time.Time    `json:"starts_at"`
+	EndsAt           *time.Time    `json:"ends_at"`
+}
+
+// SearchRuleResponse represents the search rules of a tenant
+type SearchRuleResponse struct {
+	Rules []SearchRule `json:"rules"`
+}
+
+// SearchClickRequest records a click on a search result
+type SearchClickRequest struct {
+	SearchID string `json:"search_id" binding:"required"`
+	ResultID string `json:"result_id"`
+	Position int    `json:"position"` // 1-based position of the result across pages
+}
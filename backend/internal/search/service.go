@@ -3,6 +3,7 @@ package search
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -27,28 +28,82 @@ type Service interface {
 	
 	// Get available filters
 	GetFilters(ctx context.Context, searchType string) (*FilterResponse, error)
+	
+	// Manage search synonyms
+	ListSynonyms(ctx context.Context) (*SynonymResponse, error)
+	CreateSynonym(ctx context.Context, req *SynonymRequest) (*SearchSynonym, error)
+	UpdateSynonym(ctx context.Context, id uuid.UUID, req *SynonymRequest) (*SearchSynonym, error)
+	DeleteSynonym(ctx context.Context, id uuid.UUID) error
+	
+	// Manage merchandising rules and record result clicks
+	ListRules(ctx context.Context) (*SearchRuleResponse, error)
+	CreateRule(ctx context.Context, req *SearchRuleRequest) (*SearchRule, error)
+	UpdateRule(ctx context.Context, id uuid.UUID, req *SearchRuleRequest) (*SearchRule, error)
+	DeleteRule(ctx context.Context, id uuid.UUID) error
+	RecordClick(ctx context.Context, req *SearchClickRequest) error
+	
+	// Rebuild, catch up and inspect the product search index
+	Reindex(ctx context.Context) (*IndexResult, error)
+	SyncIndex(ctx context.Context) (*IndexResult, error)
+	IndexHealth(ctx context.Context) (*IndexHealth, error)
 }
 
 // Repository defines the search repository interface
 type Repository interface {
 	Search(ctx context.Context, tenantID uuid.UUID, query *SearchQuery) ([]*SearchResult, int64, error)
-	SearchProducts(ctx context.Context, tenantID uuid.UUID, req *ProductSearchRequest) ([]*SearchResult, int64, error)
+	SearchProducts(ctx context.Context, tenantID uuid.UUID, req *ProductSearchRequest) ([]*SearchResult, []Filter, int64, error)
 	GetSuggestions(ctx context.Context, tenantID uuid.UUID, query string, searchType string, limit int) ([]Suggestion, error)
 	LogSearch(ctx context.Context, log *SearchLog) error
 	GetSearchAnalytics(ctx context.Context, tenantID uuid.UUID, req *SearchAnalyticsRequest) (*SearchAnalyticsResponse, error)
 	GetFilters(ctx context.Context, tenantID uuid.UUID, searchType string) ([]Filter, error)
 	ManageFilter(ctx context.Context, tenantID uuid.UUID, req *FilterRequest) error
+	ListSynonyms(ctx context.Context, tenantID uuid.UUID) ([]SearchSynonym, error)
+	GetSynonym(ctx context.Context, tenantID, id uuid.UUID) (*SearchSynonym, error)
+	CreateSynonym(ctx context.Context, synonym *SearchSynonym) error
+	UpdateSynonym(ctx context.Context, synonym *SearchSynonym) error
+	DeleteSynonym(ctx context.Context, tenantID, id uuid.UUID) error
+	ListRules(ctx context.Context, tenantID uuid.UUID) ([]SearchRule, error)
+	ListActiveRules(ctx context.Context, tenantID uuid.UUID) ([]SearchRule, error)
+	GetRule(ctx context.Context, tenantID, id uuid.UUID) (*SearchRule, error)
+	CreateRule(ctx context.Context, rule *SearchRule) error
+	UpdateRule(ctx context.Context, rule *SearchRule) error
+	DeleteRule(ctx context.Context, tenantID, id uuid.UUID) error
+	RecordClick(ctx context.Context, tenantID, searchID uuid.UUID, position *int) error
+
+	// Search index support
+	GetProductDocuments(ctx context.Context, tenantID uuid.UUID, productIDs []uuid.UUID) ([]ProductDocument, error)
+	ListProductIDs(ctx context.Context, tenantID uuid.UUID, after uuid.UUID, limit int) ([]uuid.UUID, error)
+	ProductIDsInCategories(ctx context.Context, tenantID uuid.UUID, categoryIDs []uuid.UUID) ([]uuid.UUID, error)
+	ListTenantIDs(ctx context.Context) ([]uuid.UUID, error)
+	CategoryNodes(ctx context.Context, tenantID uuid.UUID) ([]categoryNode, error)
+	CollectionNodes(ctx context.Context, tenantID uuid.UUID) ([]collectionNode, error)
+	FilterableAttributes(ctx context.Context, tenantID uuid.UUID) ([]attributeField, error)
+	RefreshDocuments(ctx context.Context, tenantID uuid.UUID, productIDs []uuid.UUID) error
+	DocumentStatus(ctx context.Context, tenantID uuid.UUID) (*IndexState, error)
+	PendingDocuments(ctx context.Context, tenantID uuid.UUID, limit int) ([]uuid.UUID, error)
+	MarkIndexed(ctx context.Context, backend string, tenantID uuid.UUID, productIDs []uuid.UUID) error
+	ClearIndexed(ctx context.Context, backend string, tenantID uuid.UUID, productIDs []uuid.UUID) error
+	IndexStatus(ctx context.Context, backend string, tenantID uuid.UUID) (*IndexState, error)
+	PendingProducts(ctx context.Context, backend string, tenantID uuid.UUID, limit int) ([]uuid.UUID, error)
 }
 
 // service implements the Service interface
 type service struct {
-	repo Repository
+	repo    Repository
+	index   SearchIndex
+	indexer *Indexer
 }
 
-// NewService creates a new search service
-func NewService(repo Repository) Service {
+// NewService creates a new search service. Product searches go to index; a
+// nil index searches Postgres.
+func NewService(repo Repository, index SearchIndex) Service {
+	if index == nil {
+		index = NewPostgresIndex(repo)
+	}
 	return &service{
-		repo: repo,
+		repo:    repo,
+		index:   index,
+		indexer: NewIndexer(index, repo),
 	}
 }
 
@@ -74,47 +129,25 @@ func (s *service) Search(ctx context.Context, req *SearchQuery) (*SearchResponse
 	}
 
 	// Perform search
-	results, total, err := s.repo.Search(ctx, tenantID, req)
+	response, err := s.search(ctx, tenantID, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search: %w", err)
 	}
 
 	// Log search activity
-	userID := s.getUserIDFromContext(ctx)
-	sessionID := s.getSessionIDFromContext(ctx)
-	log := &SearchLog{
-		TenantID:  tenantID,
-		UserID:    userID,
-		SessionID: sessionID,
-		Query:     req.Query,
-		Type:      req.Type,
-		Results:   total,
-		CreatedAt: time.Now(),
-	}
-	if err := s.repo.LogSearch(ctx, log); err != nil {
-		// Log error but don't fail the search
-		fmt.Printf("Failed to log search: %v", err)
-	}
+	response.SearchID = s.logSearch(ctx, tenantID, req.Query, req.Type, response)
 
 	// Get suggestions if no results
-	var suggestions []string
-	if total == 0 {
-		suggestionResults, err := s.repo.GetSuggestions(ctx, tenantID, req.Query, req.Type, 5)
+	if response.Total == 0 && response.RedirectURL == "" {
+		suggestionResults, err := s.suggest(ctx, tenantID, req.Query, req.Type, 5)
 		if err == nil {
 			for _, suggestion := range suggestionResults {
-				suggestions = append(suggestions, suggestion.Text)
+				response.Suggestions = append(response.Suggestions, suggestion.Text)
 			}
 		}
 	}
 
-	return &SearchResponse{
-		Results:     results,
-		Total:       total,
-		Offset:      req.Offset,
-		Limit:       req.Limit,
-		Query:       req.Query,
-		Suggestions: suggestions,
-	}, nil
+	return response, nil
 }
 
 // SearchProducts performs product-specific search with advanced filters
@@ -138,44 +171,25 @@ func (s *service) SearchProducts(ctx context.Context, req *ProductSearchRequest)
 		req.SortBy = "relevance"
 	}
 
+	// The single-value filters are facet selections of one value
+	if req.CategoryID != "" && !containsString(req.CategoryIDs, req.CategoryID) {
+		req.CategoryIDs = append(req.CategoryIDs, req.CategoryID)
+	}
+	if req.InStock != nil && *req.InStock && !containsString(req.Availability, AvailabilityInStock) {
+		req.Availability = append(req.Availability, AvailabilityInStock)
+	}
+	req.Availability = appendUnique(nil, req.Availability...)
+
 	// Perform product search
-	results, total, err := s.repo.SearchProducts(ctx, tenantID, req)
+	response, err := s.queryProducts(ctx, tenantID, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search products: %w", err)
 	}
 
 	// Log search activity
-	userID := s.getUserIDFromContext(ctx)
-	sessionID := s.getSessionIDFromContext(ctx)
-	log := &SearchLog{
-		TenantID:  tenantID,
-		UserID:    userID,
-		SessionID: sessionID,
-		Query:     req.Query,
-		Type:      "product",
-		Results:   total,
-		CreatedAt: time.Now(),
-	}
-	if err := s.repo.LogSearch(ctx, log); err != nil {
-		fmt.Printf("Failed to log search: %v", err)
-	}
+	response.SearchID = s.logSearch(ctx, tenantID, req.Query, "product", response)
 
-	// Get facets if requested
-	var facets map[string]interface{}
-	if req.IncludeFacets {
-		facets = make(map[string]interface{})
-		// This would be implemented based on the search results
-		// For now, return empty facets
-	}
-
-	return &SearchResponse{
-		Results: results,
-		Total:   total,
-		Offset:  req.Offset,
-		Limit:   req.Limit,
-		Query:   req.Query,
-		Facets:  facets,
-	}, nil
+	return response, nil
 }
 
 // GetSuggestions returns search suggestions/autocomplete
@@ -197,7 +211,7 @@ func (s *service) GetSuggestions(ctx context.Context, req *SuggestionRequest) (*
 	}
 
 	// Get suggestions
-	suggestions, err := s.repo.GetSuggestions(ctx, tenantID, req.Query, req.Type, req.Limit)
+	suggestions, err := s.suggest(ctx, tenantID, req.Query, req.Type, req.Limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get suggestions: %w", err)
 	}
@@ -235,6 +249,18 @@ func (s *service) GetAnalytics(ctx context.Context, req *SearchAnalyticsRequest)
 		return nil, fmt.Errorf("failed to get search analytics: %w", err)
 	}
 
+	// Show which problem queries a rule already handles
+	rules, err := s.repo.ListActiveRules(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, stats := range [][]QueryStat{analytics.TopQueries, analytics.NoResults, analytics.LowClickRate} {
+		for i := range stats {
+			stats[i].RuleIDs = matchRules(stats[i].Query, rules, now).ruleIDs
+		}
+	}
+
 	return analytics, nil
 }
 
@@ -268,8 +294,20 @@ func (s *service) GetFilters(ctx context.Context, searchType string) (*FilterRes
 		return nil, fmt.Errorf("tenant ID not found in context")
 	}
 
-	// Get filters
-	filters, err := s.repo.GetFilters(ctx, tenantID, searchType)
+	// Get filters; product facets come from the search index
+	var filters []Filter
+	var err error
+	if searchType == "product" {
+		filters, err = s.index.Facets(ctx, tenantID)
+		if err == nil {
+			var fields []attributeField
+			if fields, err = s.repo.FilterableAttributes(ctx, tenantID); err == nil {
+				filters = labelAttributeFacets(filters, fields)
+			}
+		}
+	} else {
+		filters, err = s.repo.GetFilters(ctx, tenantID, searchType)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get filters: %w", err)
 	}
@@ -279,8 +317,425 @@ func (s *service) GetFilters(ctx context.Context, searchType string) (*FilterRes
 	}, nil
 }
 
+// ListSynonyms returns the search synonyms of the tenant
+func (s *service) ListSynonyms(ctx context.Context) (*SynonymResponse, error) {
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		return nil, fmt.Errorf("tenant ID not found in context")
+	}
+
+	synonyms, err := s.repo.ListSynonyms(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SynonymResponse{
+		Synonyms: synonyms,
+	}, nil
+}
+
+// CreateSynonym adds a synonym group used to expand product search queries
+func (s *service) CreateSynonym(ctx context.Context, req *SynonymRequest) (*SearchSynonym, error) {
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		return nil, fmt.Errorf("tenant ID not found in context")
+	}
+
+	terms, err := validateSynonymTerms(req.Terms)
+	if err != nil {
+		return nil, err
+	}
+
+	synonym := &SearchSynonym{
+		ID:       uuid.New(),
+		TenantID: tenantID,
+		Terms:    terms,
+		OneWay:   req.OneWay,
+	}
+	if err := s.repo.CreateSynonym(ctx, synonym); err != nil {
+		return nil, fmt.Errorf("failed to create synonym: %w", err)
+	}
+
+	s.pushSynonyms(ctx, tenantID)
+	return synonym, nil
+}
+
+// UpdateSynonym replaces the terms of a synonym group
+func (s *service) UpdateSynonym(ctx context.Context, id uuid.UUID, req *SynonymRequest) (*SearchSynonym, error) {
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		return nil, fmt.Errorf("tenant ID not found in context")
+	}
+
+	terms, err := validateSynonymTerms(req.Terms)
+	if err != nil {
+		return nil, err
+	}
+
+	synonym, err := s.repo.GetSynonym(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	synonym.Terms = terms
+	synonym.OneWay = req.OneWay
+	if err := s.repo.UpdateSynonym(ctx, synonym); err != nil {
+		return nil, fmt.Errorf("failed to update synonym: %w", err)
+	}
+
+	s.pushSynonyms(ctx, tenantID)
+	return synonym, nil
+}
+
+// DeleteSynonym removes a synonym group
+func (s *service) DeleteSynonym(ctx context.Context, id uuid.UUID) error {
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		return fmt.Errorf("tenant ID not found in context")
+	}
+
+	if err := s.repo.DeleteSynonym(ctx, tenantID, id); err != nil {
+		return err
+	}
+
+	s.pushSynonyms(ctx, tenantID)
+	return nil
+}
+
+// ListRules returns the merchandising rules of the tenant
+func (s *service) ListRules(ctx context.Context) (*SearchRuleResponse, error) {
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		return nil, fmt.Errorf("tenant ID not found in context")
+	}
+
+	rules, err := s.repo.ListRules(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SearchRuleResponse{
+		Rules: rules,
+	}, nil
+}
+
+// CreateRule adds a merchandising rule
+func (s *service) CreateRule(ctx context.Context, req *SearchRuleRequest) (*SearchRule, error) {
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		return nil, fmt.Errorf("tenant ID not found in context")
+	}
+
+	rule, err := newSearchRule(req)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.ID = uuid.New()
+	rule.TenantID = tenantID
+	if err := s.repo.CreateRule(ctx, rule); err != nil {
+		return nil, fmt.Errorf("failed to create search rule: %w", err)
+	}
+	return rule, nil
+}
+
+// UpdateRule replaces a merchandising rule
+func (s *service) UpdateRule(ctx context.Context, id uuid.UUID, req *SearchRuleRequest) (*SearchRule, error) {
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		return nil, fmt.Errorf("tenant ID not found in context")
+	}
+
+	updated, err := newSearchRule(req)
+	if err != nil {
+		return nil, err
+	}
+
+	rule, err := s.repo.GetRule(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	updated.ID = rule.ID
+	updated.TenantID = rule.TenantID
+	updated.CreatedAt = rule.CreatedAt
+	if err := s.repo.UpdateRule(ctx, updated); err != nil {
+		return nil, fmt.Errorf("failed to update search rule: %w", err)
+	}
+	return updated, nil
+}
+
+// DeleteRule removes a merchandising rule
+func (s *service) DeleteRule(ctx context.Context, id uuid.UUID) error {
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		return fmt.Errorf("tenant ID not found in context")
+	}
+
+	return s.repo.DeleteRule(ctx, tenantID, id)
+}
+
+// RecordClick marks a logged search as clicked, for click-through analytics
+func (s *service) RecordClick(ctx context.Context, req *SearchClickRequest) error {
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		return fmt.Errorf("tenant ID not found in context")
+	}
+
+	searchID, err := uuid.Parse(req.SearchID)
+	if err != nil {
+		return ErrSearchNotFound
+	}
+
+	var position *int
+	if req.Position > 0 {
+		position = &req.Position
+	}
+	return s.repo.RecordClick(ctx, tenantID, searchID, position)
+}
+
+// Reindex rebuilds the search index of every product of the tenant
+func (s *service) Reindex(ctx context.Context) (*IndexResult, error) {
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		return nil, fmt.Errorf("tenant ID not found in context")
+	}
+
+	return s.indexer.Reindex(ctx, tenantID)
+}
+
+// SyncIndex indexes the products the search index has fallen behind on
+func (s *service) SyncIndex(ctx context.Context) (*IndexResult, error) {
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		return nil, fmt.Errorf("tenant ID not found in context")
+	}
+
+	return s.indexer.Sync(ctx, tenantID)
+}
+
+// IndexHealth reports whether the search index is reachable and up to date
+func (s *service) IndexHealth(ctx context.Context) (*IndexHealth, error) {
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		return nil, fmt.Errorf("tenant ID not found in context")
+	}
+
+	return s.indexer.Health(ctx, tenantID)
+}
+
 // Helper methods
 
+// search runs a global search. Products come from the search index with the
+// merchandising rules applied; matching categories are added to the first
+// page.
+func (s *service) search(ctx context.Context, tenantID uuid.UUID, req *SearchQuery) (*SearchResponse, error) {
+	searchType := strings.ToLower(req.Type)
+	if searchType != "" && searchType != "product" {
+		results, total, err := s.repo.Search(ctx, tenantID, req)
+		if err != nil {
+			return nil, err
+		}
+		return &SearchResponse{
+			Results: results,
+			Total:   total,
+			Offset:  req.Offset,
+			Limit:   req.Limit,
+			Query:   req.Query,
+		}, nil
+	}
+
+	response, err := s.queryProducts(ctx, tenantID, &ProductSearchRequest{
+		Query:  req.Query,
+		Offset: req.Offset,
+		Limit:  req.Limit,
+		SortBy: req.SortBy,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if searchType == "" && response.RedirectURL == "" {
+		categories, categoryTotal, err := s.repo.Search(ctx, tenantID, &SearchQuery{
+			Query: req.Query,
+			Type:  "category",
+			Limit: req.Limit,
+		})
+		if err == nil {
+			response.Total += categoryTotal
+			if req.Offset == 0 {
+				response.Results = append(response.Results, categories...)
+			}
+		}
+	}
+
+	return response, nil
+}
+
+// queryProducts searches the index with the merchandising rules matching the
+// query applied. Pinned products lead the results of unfiltered relevance
+// searches and count towards the total, but not towards the facets.
+func (s *service) queryProducts(ctx context.Context, tenantID uuid.UUID, req *ProductSearchRequest) (*SearchResponse, error) {
+	rules, err := s.repo.ListActiveRules(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	merch := matchRules(req.Query, rules, time.Now())
+
+	response := &SearchResponse{
+		Results: []*SearchResult{},
+		Offset:  req.Offset,
+		Limit:   req.Limit,
+		Query:   req.Query,
+		Rules:   merch.ruleIDs,
+	}
+	if merch.redirectURL != "" {
+		response.RedirectURL = merch.redirectURL
+		return response, nil
+	}
+
+	var fields []attributeField
+	if len(req.Attributes) > 0 || req.IncludeFacets {
+		if fields, err = s.repo.FilterableAttributes(ctx, tenantID); err != nil {
+			return nil, err
+		}
+	}
+
+	query := *req
+	query.Attributes = filterableSelections(req.Attributes, fields)
+	query.ExcludeIDs = appendUnique(append([]string{}, req.ExcludeIDs...), merch.hidden...)
+	query.Boosts = append(append([]SearchBoost{}, req.Boosts...), merch.boosts...)
+
+	var pinned []*SearchResult
+	if len(merch.pinned) > 0 && showsPins(req) {
+		pinned, err = s.pinnedResults(ctx, tenantID, merch.pinned)
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range pinned {
+			query.ExcludeIDs = appendUnique(query.ExcludeIDs, result.ID)
+		}
+	}
+
+	// Pinned products take the first positions; the index fills the rest
+	pinnedPage := pinned[min(req.Offset, len(pinned)):min(req.Offset+req.Limit, len(pinned))]
+	remaining := req.Limit - len(pinnedPage)
+	query.Offset = max(req.Offset-len(pinned), 0)
+	query.Limit = max(remaining, 1)
+
+	results, facets, total, err := s.index.Query(ctx, tenantID, &query)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) > remaining {
+		results = results[:remaining]
+	}
+
+	response.Results = append(append(response.Results, pinnedPage...), results...)
+	response.Total = total + int64(len(pinned))
+	response.Facets = labelAttributeFacets(facets, fields)
+	return response, nil
+}
+
+// showsPins tells whether pinned products lead the results: only relevance
+// searches without filters, which pinned products might not pass
+func showsPins(req *ProductSearchRequest) bool {
+	if req.SortBy != "" && req.SortBy != "relevance" {
+		return false
+	}
+	return req.CategoryID == "" && len(req.CategoryIDs) == 0 && len(req.CollectionIDs) == 0 && req.BrandID == "" && len(req.Tags) == 0 &&
+		req.MinPrice == nil && req.MaxPrice == nil && len(req.PriceRanges) == 0 && req.InStock == nil &&
+		len(req.Availability) == 0 && req.OnSale == nil && req.Rating == nil && len(req.Options) == 0 &&
+		len(req.Attributes) == 0
+}
+
+// pinnedResults returns the active pinned products in pin order
+func (s *service) pinnedResults(ctx context.Context, tenantID uuid.UUID, productIDs []string) ([]*SearchResult, error) {
+	ids := make([]uuid.UUID, 0, len(productIDs))
+	for _, id := range productIDs {
+		if parsed, err := uuid.Parse(id); err == nil {
+			ids = append(ids, parsed)
+		}
+	}
+
+	documents, err := s.repo.GetProductDocuments(ctx, tenantID, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*ProductDocument, len(documents))
+	for i := range documents {
+		byID[documents[i].ID] = &documents[i]
+	}
+
+	results := make([]*SearchResult, 0, len(documents))
+	for _, id := range ids {
+		if document, ok := byID[id]; ok {
+			results = append(results, document.searchResult(0, MatchPinned))
+		}
+	}
+	return results, nil
+}
+
+// logSearch records a search for analytics and returns its ID, which clients
+// send back with clicks. Logging never fails the search.
+func (s *service) logSearch(ctx context.Context, tenantID uuid.UUID, query, searchType string, response *SearchResponse) string {
+	log := &SearchLog{
+		ID:         uuid.New(),
+		TenantID:   tenantID,
+		UserID:     s.getUserIDFromContext(ctx),
+		SessionID:  s.getSessionIDFromContext(ctx),
+		Query:      query,
+		Type:       searchType,
+		Results:    response.Total,
+		Redirected: response.RedirectURL != "",
+		CreatedAt:  time.Now(),
+	}
+	if err := s.repo.LogSearch(ctx, log); err != nil {
+		fmt.Printf("Failed to log search: %v", err)
+		return ""
+	}
+	return log.ID.String()
+}
+
+// suggest returns product suggestions from the search index and other
+// suggestions from the database
+func (s *service) suggest(ctx context.Context, tenantID uuid.UUID, query, searchType string, limit int) ([]Suggestion, error) {
+	if searchType == "product" || searchType == "" {
+		return s.index.Suggest(ctx, tenantID, query, limit)
+	}
+	return s.repo.GetSuggestions(ctx, tenantID, query, searchType, limit)
+}
+
+// pushSynonyms sends the tenant's synonyms to the search index. A failure
+// leaves the index on the old synonyms until the next reindex.
+func (s *service) pushSynonyms(ctx context.Context, tenantID uuid.UUID) {
+	synonyms, err := s.repo.ListSynonyms(ctx, tenantID)
+	if err == nil {
+		err = s.index.SetSynonyms(ctx, tenantID, synonyms)
+	}
+	logIndexError("update search index synonyms", err)
+}
+
+// validateSynonymTerms trims the terms and drops duplicates, keeping the order
+// so the first term of a one-way synonym stays first
+func validateSynonymTerms(terms []string) ([]string, error) {
+	var cleaned []string
+	seen := make(map[string]bool)
+	for _, term := range terms {
+		key := strings.Join(tokenize(term), " ")
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		cleaned = append(cleaned, strings.TrimSpace(term))
+	}
+
+	if len(cleaned) < 2 {
+		return nil, ErrInvalidSynonym
+	}
+	return cleaned, nil
+}
+
 func (s *service) validateSearchQuery(req *SearchQuery) error {
 	if req.Query == "" {
 		return fmt.Errorf("query is required")
@@ -307,6 +762,29 @@ func (s *service) validateProductSearchRequest(req *ProductSearchRequest) error
 	if req.MinPrice != nil && req.MaxPrice != nil && *req.MinPrice > *req.MaxPrice {
 		return fmt.Errorf("min_price cannot be greater than max_price")
 	}
+	if req.BrandID != "" {
+		return fmt.Errorf("brand filter is not supported: products have no brand")
+	}
+	for _, id := range append([]string{req.CategoryID}, req.CategoryIDs...) {
+		if _, err := uuid.Parse(id); id != "" && err != nil {
+			return fmt.Errorf("invalid category ID %q", id)
+		}
+	}
+	for _, id := range req.CollectionIDs {
+		if _, err := uuid.Parse(id); err != nil {
+			return fmt.Errorf("invalid collection ID %q", id)
+		}
+	}
+	for _, value := range req.PriceRanges {
+		if _, _, err := parsePriceRange(value); err != nil {
+			return err
+		}
+	}
+	for _, value := range req.Availability {
+		if value != AvailabilityInStock && value != AvailabilityOutOfStock {
+			return fmt.Errorf("invalid availability %q", value)
+		}
+	}
 	return nil
 }
 
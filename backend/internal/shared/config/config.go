@@ -33,6 +33,12 @@ type Config struct {
 	// Payment configuration
 	Payment PaymentConfig `mapstructure:"payment"`
 	
+	// Search configuration
+	Search SearchConfig `mapstructure:"search"`
+	
+	// Printed documents configuration
+	Documents DocumentsConfig `mapstructure:"documents"`
+	
 	// Application configuration
 	App AppConfig `mapstructure:"app"`
 }
@@ -103,6 +109,20 @@ type PaymentProviderConfig struct {
 	Enabled      bool   `mapstructure:"enabled"`
 }
 
+type SearchConfig struct {
+	Backend     string `mapstructure:"backend"` // "postgres", "meilisearch" or "memory"
+	URL         string `mapstructure:"url"`
+	APIKey      string `mapstructure:"api_key"`
+	IndexPrefix string `mapstructure:"index_prefix"` // Prepended to external index names
+}
+
+// DocumentsConfig points at the TrueType fonts used for Bangla and other
+// non-Latin text on labels, packing slips and manifests
+type DocumentsConfig struct {
+	FontPath     string `mapstructure:"font_path"`
+	BoldFontPath string `mapstructure:"bold_font_path"` // Optional; regular is used for bold text without it
+}
+
 type AppConfig struct {
 	Name        string `mapstructure:"name"`
 	Environment string `mapstructure:"environment"`
@@ -197,6 +217,14 @@ func setDefaults() {
 	viper.SetDefault("storage.max_upload_size", 10485760) // 10MB
 	viper.SetDefault("storage.allowed_file_types", []string{"jpg", "jpeg", "png", "gif", "pdf", "doc", "docx"})
 
+	// Search defaults
+	viper.SetDefault("search.backend", "postgres")
+	viper.SetDefault("search.index_prefix", "")
+
+	// Documents defaults (Alpine font-noto-bengali package)
+	viper.SetDefault("documents.font_path", "/usr/share/fonts/noto/NotoSansBengali-Regular.ttf")
+	viper.SetDefault("documents.bold_font_path", "/usr/share/fonts/noto/NotoSansBengali-Bold.ttf")
+
 	// App defaults
 	viper.SetDefault("app.name", "E-commerce SaaS")
 	viper.SetDefault("app.environment", "development")
@@ -274,6 +302,28 @@ func loadFromEnv() {
 		viper.Set("jwt.secret_key", jwtSecret)
 	}
 
+	// Search
+	if searchBackend := os.Getenv("SEARCH_BACKEND"); searchBackend != "" {
+		viper.Set("search.backend", searchBackend)
+	}
+	if searchURL := os.Getenv("SEARCH_URL"); searchURL != "" {
+		viper.Set("search.url", searchURL)
+	}
+	if searchAPIKey := os.Getenv("SEARCH_API_KEY"); searchAPIKey != "" {
+		viper.Set("search.api_key", searchAPIKey)
+	}
+	if searchIndexPrefix := os.Getenv("SEARCH_INDEX_PREFIX"); searchIndexPrefix != "" {
+		viper.Set("search.index_prefix", searchIndexPrefix)
+	}
+
+	// Documents
+	if fontPath := os.Getenv("DOCUMENT_FONT_PATH"); fontPath != "" {
+		viper.Set("documents.font_path", fontPath)
+	}
+	if boldFontPath := os.Getenv("DOCUMENT_BOLD_FONT_PATH"); boldFontPath != "" {
+		viper.Set("documents.bold_font_path", boldFontPath)
+	}
+
 	// Environment
 	if env := os.Getenv("ENVIRONMENT"); env != "" {
 		viper.Set("app.environment", env)
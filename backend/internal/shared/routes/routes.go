@@ -1,6 +1,9 @@
 package routes
 
 import (
+	"log"
+	"sync"
+
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 
@@ -8,15 +11,18 @@ import (
 	"ecommerce-saas/internal/admin"
 	"ecommerce-saas/internal/analytics"
 	"ecommerce-saas/internal/billing"
-	// "ecommerce-saas/internal/cart" // Temporarily disabled due to interface compatibility issues
+	"ecommerce-saas/internal/cart"
+	"ecommerce-saas/internal/checkout"
 	"ecommerce-saas/internal/contact"
 	"ecommerce-saas/internal/content"
 	"ecommerce-saas/internal/discount"
 	"ecommerce-saas/internal/finance"
 	"ecommerce-saas/internal/loyalty"
 	"ecommerce-saas/internal/marketing"
+	"ecommerce-saas/internal/metafield"
 	"ecommerce-saas/internal/notification"
 	"ecommerce-saas/internal/observability"
+	"ecommerce-saas/internal/order"
 	"ecommerce-saas/internal/payment"
 	"ecommerce-saas/internal/product"
 	"ecommerce-saas/internal/returns"
@@ -40,6 +46,14 @@ type RouteConfig struct {
 	DB         *gorm.DB
 	Config     *config.Config
 	JWTManager *utils.JWTManager
+
+	// Shared so in-process search indexes see the indexer's writes
+	searchIndex     search.SearchIndex
+	searchIndexOnce sync.Once
+
+	// Loaded once for every shipping service
+	documentFonts     *shipping.DocumentFonts
+	documentFontsOnce sync.Once
 }
 
 // SetupRoutes configures all application routes
@@ -67,6 +81,7 @@ func SetupRoutes(r *gin.Engine, cfg *RouteConfig) {
 	
 	// Initialize user module
 	userModule := user.NewModule(cfg.DB, cfg.JWTManager)
+	userModule.SetSignupListener(newLoyaltyEvents(cfg))
 
 	// Public routes (no authentication required)
 	public := v1.Group("")
@@ -112,6 +127,7 @@ func SetupRoutes(r *gin.Engine, cfg *RouteConfig) {
 		setupDiscountRoutes(protected, cfg)
 		setupLoyaltyRoutes(protected, cfg)
 		setupMarketingRoutes(protected, cfg)
+		setupMetafieldRoutes(protected, cfg)
 		setupObservabilityRoutes(protected, cfg)
 		setupReviewsRoutes(protected, cfg)
 		setupSearchRoutes(protected, cfg)
@@ -128,6 +144,12 @@ func SetupRoutes(r *gin.Engine, cfg *RouteConfig) {
 	{
 		// Public product routes (no auth needed for browsing)
 		setupPublicProductRoutes(storefront, cfg)
+		
+		// Public order tracking
+		setupPublicShippingRoutes(storefront, cfg)
+
+		// Checkout sessions (guests and signed-in customers)
+		setupPublicCheckoutRoutes(storefront, cfg)
 	}
 
 }
@@ -144,6 +166,7 @@ func setupTenantRoutes(v1 *gin.RouterGroup, cfg *RouteConfig) {
 func setupProductRoutes(v1 *gin.RouterGroup, cfg *RouteConfig) {
 	// Initialize product module
 	productModule := product.NewModule(cfg.DB)
+	productModule.SetChangeListener(newSearchIndexer(cfg))
 	
 	// Register product routes
 	productModule.RegisterRoutes(v1)
@@ -166,6 +189,7 @@ func setupPublicProductRoutes(v1 *gin.RouterGroup, cfg *RouteConfig) {
 		public.GET("/products/slug/:slug", productModule.Handler.GetProductBySlug)
 		public.GET("/products/:id", productModule.Handler.GetPublicProduct)
 		public.GET("/products/:id/variants", productModule.Handler.GetProductVariants)
+		public.GET("/products/:id/variants/lookup", productModule.Handler.GetVariantByOptions)
 		
 		// Public category browsing
 		public.GET("/categories", productModule.Handler.GetPublicCategories)
@@ -173,6 +197,10 @@ func setupPublicProductRoutes(v1 *gin.RouterGroup, cfg *RouteConfig) {
 		public.GET("/categories/:id", productModule.Handler.GetCategory)
 		public.GET("/categories/:id/children", productModule.Handler.GetCategoryChildren)
 		
+		// Public collection browsing
+		public.GET("/collections", productModule.Handler.GetPublicCollections)
+		public.GET("/collections/:slug", productModule.Handler.GetPublicCollection)
+		
 		// TODO: Public order tracking (no auth required) - requires order module
 		// public.GET("/orders/track/:number", orderModule.Handler.TrackOrder)
 		// public.GET("/orders/number/:number", orderModule.Handler.GetOrderByNumber)
@@ -182,7 +210,7 @@ func setupPublicProductRoutes(v1 *gin.RouterGroup, cfg *RouteConfig) {
 		public.GET("/settings", settingsModule.GetHandler().GetPublicSettings)
 		
 		// Public search (no auth required)
-		searchModule := search.NewModule(cfg.DB)
+		searchModule := search.NewModule(cfg.DB, newSearchIndex(cfg))
 		public.GET("/search", searchModule.GetHandler().Search)
 		public.GET("/search/products", searchModule.GetHandler().SearchProducts)
 		public.GET("/search/suggestions", searchModule.GetHandler().GetSuggestions)
@@ -193,12 +221,15 @@ func setupOrderRoutes(v1 *gin.RouterGroup, cfg *RouteConfig) {
 	// TODO: Implement proper service dependencies
 	// For now, comment out order routes to avoid compilation errors
 	// orderModule := order.NewModule(cfg.DB, productService, discountService, paymentService, inventoryService, notificationService)
+	// orderModule.Service.SetEventListener(order.EventListeners{newLoyaltyEvents(cfg), newDiscountEvents(cfg)})
+	// orderModule.Service.SetPaymentListener(newDiscountEvents(cfg))
 	// orderModule.RegisterRoutes(v1)
 }
 
 func setupPaymentRoutes(v1 *gin.RouterGroup, cfg *RouteConfig) {
 	// Initialize payment module
 	paymentModule := payment.NewModule(cfg.DB)
+	paymentModule.Service.SetOrderListener(newPaymentSync(cfg))
 	
 	// Register payment routes
 	paymentModule.RegisterRoutes(v1)
@@ -222,7 +253,7 @@ func setupFinanceRoutes(v1 *gin.RouterGroup, cfg *RouteConfig) {
 
 func setupReturnsRoutes(v1 *gin.RouterGroup, cfg *RouteConfig) {
 	// Initialize returns module
-	returnsModule := returns.NewModule(cfg.DB)
+	returnsModule := returns.NewModule(cfg.DB, newLoyaltyEvents(cfg))
 	
 	// Register returns routes
 	returnsModule.RegisterRoutes(v1)
@@ -235,6 +266,11 @@ func setupAddressRoutes(v1 *gin.RouterGroup, cfg *RouteConfig) {
 	addressHandler := address.NewHandler(addressService)
 	
 	addressHandler.RegisterRoutes(v1)
+	
+	// Platform default carrier zone mappings are shared by every tenant
+	platform := v1.Group("")
+	platform.Use(middleware.RoleMiddleware("super_admin"))
+	addressHandler.RegisterPlatformRoutes(platform)
 }
 
 // Setup analytics routes
@@ -269,12 +305,25 @@ func setupContentRoutes(v1 *gin.RouterGroup, cfg *RouteConfig) {
 // Setup discount routes
 func setupDiscountRoutes(v1 *gin.RouterGroup, cfg *RouteConfig) {
 	discountRepo := discount.NewRepository(cfg.DB)
-	discountService := discount.NewService(discountRepo)
+	discountService := discount.NewService(discountRepo, notification.NewService(notification.NewRepository(cfg.DB)), newLoyaltyTiers(cfg))
 	discountHandler := discount.NewHandler(discountService)
 	
 	discountHandler.RegisterRoutes(v1)
 }
 
+// newDiscountEvents lets discounts settle gift card and store credit holds, and issue or drop purchased gift cards, when orders are paid, fail payment or are cancelled
+func newDiscountEvents(cfg *RouteConfig) *discount.EventHandler {
+	notifications := notification.NewService(notification.NewRepository(cfg.DB))
+	return discount.NewEventHandler(discount.NewService(discount.NewRepository(cfg.DB), notifications, nil))
+}
+
+// newPaymentSync marks orders paid or failed on payment outcomes and tells discounts
+func newPaymentSync(cfg *RouteConfig) *order.PaymentSync {
+	paymentSync := order.NewPaymentSync(order.NewRepository(cfg.DB))
+	paymentSync.SetPaymentListener(newDiscountEvents(cfg))
+	return paymentSync
+}
+
 // Setup marketing routes
 func setupMarketingRoutes(v1 *gin.RouterGroup, cfg *RouteConfig) {
 	marketingRepo := marketing.NewRepository(cfg.DB)
@@ -284,12 +333,19 @@ func setupMarketingRoutes(v1 *gin.RouterGroup, cfg *RouteConfig) {
 	marketingHandler.RegisterRoutes(v1)
 }
 
+// Setup metafield routes
+func setupMetafieldRoutes(v1 *gin.RouterGroup, cfg *RouteConfig) {
+	metafieldModule := metafield.NewModule(cfg.DB)
+	
+	metafieldModule.RegisterRoutes(v1)
+}
+
 
 
 // Setup reviews routes
 func setupReviewsRoutes(v1 *gin.RouterGroup, cfg *RouteConfig) {
 	reviewsRepo := reviews.NewRepository(cfg.DB)
-	reviewsService := reviews.NewService(reviewsRepo)
+	reviewsService := reviews.NewService(reviewsRepo, newLoyaltyEvents(cfg), newReviewSummaries(cfg))
 	reviewsHandler := reviews.NewHandler(reviewsService)
 	
 	reviewsHandler.RegisterRoutes(v1)
@@ -297,13 +353,64 @@ func setupReviewsRoutes(v1 *gin.RouterGroup, cfg *RouteConfig) {
 
 // Setup shipping routes
 func setupShippingRoutes(v1 *gin.RouterGroup, cfg *RouteConfig) {
-	shippingRepo := shipping.NewRepository(cfg.DB)
-	shippingService := shipping.NewService(shippingRepo)
-	shippingHandler := shipping.NewHandler(shippingService)
+	shippingHandler := shipping.NewHandler(newShippingService(cfg))
 	
 	shippingHandler.RegisterRoutes(v1)
 }
 
+// Setup public shipping routes (storefront order tracking)
+func setupPublicShippingRoutes(v1 *gin.RouterGroup, cfg *RouteConfig) {
+	shippingHandler := shipping.NewHandler(newShippingService(cfg))
+	
+	public := v1.Group("")
+	public.Use(middleware.TenantMiddleware(cfg.DB))
+	shippingHandler.RegisterPublicRoutes(public)
+}
+
+// Setup public checkout routes
+func setupPublicCheckoutRoutes(v1 *gin.RouterGroup, cfg *RouteConfig) {
+	shippingAdapter, taxAdapter := newCartAdapters(cfg)
+	carts := newCartService(cfg, shippingAdapter, taxAdapter)
+	checkoutModule := checkout.NewModule(cfg.DB, shippingAdapter, taxAdapter, newLoyaltyTiers(cfg), carts)
+	
+	public := v1.Group("")
+	public.Use(middleware.OptionalAuthMiddleware(cfg.JWTManager))
+	public.Use(middleware.TenantMiddleware(cfg.DB))
+	checkoutModule.RegisterRoutes(public)
+}
+
+// newShippingService wires shipping to address zones, order fulfillment and notifications
+func newShippingService(cfg *RouteConfig) *shipping.Service {
+	shippingRepo := shipping.NewRepository(cfg.DB)
+	shippingService := shipping.NewService(shippingRepo)
+	shippingService.SetZoneResolver(address.NewService(address.NewGormRepository(cfg.DB)))
+	shipmentSync := order.NewShipmentSync(order.NewRepository(cfg.DB))
+	shipmentSync.SetEventListener(newLoyaltyEvents(cfg))
+	shippingService.SetOrderUpdater(shipmentSync)
+	shippingService.SetNotificationService(notification.NewService(notification.NewRepository(cfg.DB)))
+	shippingService.SetStockRestorer(product.NewInventoryService(product.NewRepository(cfg.DB)))
+	shippingService.SetRTOAccounting(finance.NewCODLedger(finance.NewRepository(cfg.DB)))
+	shippingService.SetDocumentFonts(newDocumentFonts(cfg))
+	return shippingService
+}
+
+// newDocumentFonts loads the Unicode fonts for shipping documents; nil prints
+// Latin-1 text only
+func newDocumentFonts(cfg *RouteConfig) *shipping.DocumentFonts {
+	cfg.documentFontsOnce.Do(func() {
+		if cfg.Config == nil || cfg.Config.Documents.FontPath == "" {
+			return
+		}
+		fonts, err := shipping.LoadDocumentFonts(cfg.Config.Documents.FontPath, cfg.Config.Documents.BoldFontPath)
+		if err != nil {
+			log.Printf("Failed to load document fonts, Bangla text will not print: %v", err)
+			return
+		}
+		cfg.documentFonts = fonts
+	})
+	return cfg.documentFonts
+}
+
 // Setup support routes
 func setupSupportRoutes(v1 *gin.RouterGroup, cfg *RouteConfig) {
 	supportRepo := support.NewRepository(cfg.DB)
@@ -331,6 +438,24 @@ func setupLoyaltyRoutes(v1 *gin.RouterGroup, cfg *RouteConfig) {
 	loyaltyModule.RegisterRoutes(v1)
 }
 
+// newLoyaltyService builds the loyalty service shared by the other modules' integrations.
+// Its discount service only issues referral rewards, so it needs no tier resolver.
+func newLoyaltyService(cfg *RouteConfig) loyalty.Service {
+	notifications := notification.NewService(notification.NewRepository(cfg.DB))
+	discounts := discount.NewService(discount.NewRepository(cfg.DB), notifications, nil)
+	return loyalty.NewService(loyalty.NewGormRepository(cfg.DB), notifications, discounts)
+}
+
+// newLoyaltyEvents lets loyalty earn, reverse and restore points and track referrals on order, return, review and signup events
+func newLoyaltyEvents(cfg *RouteConfig) *loyalty.EventHandler {
+	return loyalty.NewEventHandler(newLoyaltyService(cfg))
+}
+
+// newLoyaltyTiers lets discounts and checkout apply loyalty tier promotions and benefits
+func newLoyaltyTiers(cfg *RouteConfig) *loyalty.TierResolver {
+	return loyalty.NewTierResolver(newLoyaltyService(cfg))
+}
+
 // Setup admin routes
 func setupAdminRoutes(v1 *gin.RouterGroup, cfg *RouteConfig) {
 	adminRepo := admin.NewRepository(cfg.DB)
@@ -351,21 +476,25 @@ func setupBillingRoutes(v1 *gin.RouterGroup, cfg *RouteConfig) {
 
 // Setup cart routes
 func setupCartRoutes(v1 *gin.RouterGroup, cfg *RouteConfig) {
-	// TODO: Fix service interface compatibility issues
-	// The cart module expects different interfaces than what the services provide
-	// Temporarily commented out to allow compilation
-	
-	// Initialize required service dependencies
-	// productModule := product.NewModule(cfg.DB)
-	// discountModule := discount.NewModule(cfg.DB)
-	// taxModule := tax.NewModule(cfg.DB)
-	// shippingModule := shipping.NewModule(cfg.DB)
-	
-	// Initialize cart module with dependencies
-	// cartModule := cart.NewModule(cfg.DB, productModule.Service, discountModule.GetService(), taxModule.GetService(), shippingModule.GetService())
+	shippingAdapter, taxAdapter := newCartAdapters(cfg)
+	cartHandler := cart.NewHandler(newCartService(cfg, shippingAdapter, taxAdapter))
 	
-	// Register cart routes
-	// cartModule.RegisterRoutes(v1)
+	cartHandler.RegisterRoutes(v1)
+}
+
+// newCartAdapters wires cart shipping quotes to product weights, and cart tax to the tax service
+func newCartAdapters(cfg *RouteConfig) (*cart.ShippingAdapter, *cart.TaxAdapter) {
+	shippingAdapter := cart.NewShippingAdapter(newShippingService(cfg))
+	shippingAdapter.SetItemWeigher(product.NewService(product.NewRepository(cfg.DB)))
+	taxAdapter := cart.NewTaxAdapter(tax.NewService(tax.NewGormRepository(cfg.DB)))
+	return shippingAdapter, taxAdapter
+}
+
+// newCartService wires carts to the catalog, discounts, tax and shipping
+func newCartService(cfg *RouteConfig, shippingAdapter *cart.ShippingAdapter, taxAdapter *cart.TaxAdapter) *cart.CartService {
+	productService := product.NewService(product.NewRepository(cfg.DB))
+	discounts := discount.NewService(discount.NewRepository(cfg.DB), notification.NewService(notification.NewRepository(cfg.DB)), newLoyaltyTiers(cfg))
+	return cart.NewCartService(cart.NewRepository(cfg.DB), cart.NewProductAdapter(productService), cart.NewDiscountAdapter(discounts), taxAdapter, shippingAdapter)
 }
 
 // Setup observability routes
@@ -380,12 +509,46 @@ func setupObservabilityRoutes(v1 *gin.RouterGroup, cfg *RouteConfig) {
 // Setup search routes
 func setupSearchRoutes(v1 *gin.RouterGroup, cfg *RouteConfig) {
 	// Initialize search module
-	searchModule := search.NewModule(cfg.DB)
+	searchModule := search.NewModule(cfg.DB, newSearchIndex(cfg))
 	
 	// Register search routes
 	searchModule.RegisterRoutes(v1)
 }
 
+// newSearchIndex returns the configured product search backend, built once per route setup;
+// nil falls back to Postgres
+func newSearchIndex(cfg *RouteConfig) search.SearchIndex {
+	cfg.searchIndexOnce.Do(func() {
+		if cfg.Config == nil {
+			return
+		}
+		index, err := search.NewIndex(cfg.DB, search.IndexConfig{
+			Backend:     cfg.Config.Search.Backend,
+			URL:         cfg.Config.Search.URL,
+			APIKey:      cfg.Config.Search.APIKey,
+			IndexPrefix: cfg.Config.Search.IndexPrefix,
+		})
+		if err != nil {
+			log.Printf("Failed to create search index, using Postgres: %v", err)
+			return
+		}
+		cfg.searchIndex = index
+	})
+	return cfg.searchIndex
+}
+
+// newSearchIndexer keeps the search index up to date with product and category changes
+func newSearchIndexer(cfg *RouteConfig) *search.Indexer {
+	return search.NewModule(cfg.DB, newSearchIndex(cfg)).GetIndexer()
+}
+
+// newReviewSummaries copies review summaries onto products and reindexes them
+func newReviewSummaries(cfg *RouteConfig) *product.Service {
+	productModule := product.NewModule(cfg.DB)
+	productModule.SetChangeListener(newSearchIndexer(cfg))
+	return productModule.Service
+}
+
 // Setup settings routes
 func setupSettingsRoutes(v1 *gin.RouterGroup, cfg *RouteConfig) {
 	// Initialize settings module
@@ -0,0 +1,387 @@
+package shipping
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Printable fulfillment documents: shipping labels, packing slips, pick
+// lists and carrier manifests. Each render function returns a complete PDF,
+// or an error when a barcode can't be encoded: a label that couldn't be
+// scanned is worse than no label.
+
+// LabelLayout selects the paper format for labels and packing slips
+type LabelLayout string
+
+const (
+	LabelLayoutA4      LabelLayout = "a4"          // Four 4x6 labels per A4 sheet
+	LabelLayoutThermal LabelLayout = "thermal_4x6" // One label per 4x6 thermal sheet
+)
+
+const (
+	docMargin       = 36.0
+	manifestRows    = 28
+	pickListRows    = 30
+	unassignedBin   = "UNASSIGNED"
+	documentTimeFmt = "02 Jan 2006 15:04"
+)
+
+// renderShippingLabels lays out one label per thermal page or four per A4 page
+func renderShippingLabels(labels []ShippingLabel, layout LabelLayout, fonts *DocumentFonts) ([]byte, error) {
+	doc := newPDFDocument(fonts)
+
+	if layout == LabelLayoutThermal {
+		for i := range labels {
+			page := doc.AddPage(pageThermalWidth, pageThermalHeight)
+			if err := drawLabel(page, 8, 8, pageThermalWidth-16, pageThermalHeight-16, &labels[i]); err != nil {
+				return nil, err
+			}
+		}
+		return doc.Bytes()
+	}
+
+	cellW := (pageA4Width - 2*docMargin/2) / 2
+	cellH := (pageA4Height - 2*docMargin/2) / 2
+	var page *pdfPage
+	for i := range labels {
+		slot := i % 4
+		if slot == 0 {
+			page = doc.AddPage(pageA4Width, pageA4Height)
+		}
+		x := docMargin/2 + float64(slot%2)*cellW
+		y := docMargin/2 + float64(slot/2)*cellH
+		if err := drawLabel(page, x+6, y+6, cellW-12, cellH-12, &labels[i]); err != nil {
+			return nil, err
+		}
+	}
+	return doc.Bytes()
+}
+
+func drawLabel(page *pdfPage, x, y, w, h float64, label *ShippingLabel) error {
+	page.Rect(x, y, w, h, false)
+
+	// Carrier header
+	page.Line(x, y+28, x+w, y+28, 1)
+	page.Text(x+8, y+19, 14, true, strings.ToUpper(string(label.Provider)))
+	page.Text(x+w-110, y+19, 8, false, label.CreatedAt.Format(documentTimeFmt))
+
+	// Tracking barcode
+	cursor := y + 38
+	if err := page.Barcode(x+12, cursor, w-24, 56, label.TrackingNumber); err != nil {
+		return fmt.Errorf("label %s: tracking number barcode: %w", label.ID, err)
+	}
+	cursor += 68
+	page.Text(x+12, cursor, 11, true, label.TrackingNumber)
+	cursor += 10
+	page.Line(x, cursor, x+w, cursor, 0.8)
+
+	// Receiver
+	cursor += 16
+	page.Text(x+8, cursor, 8, true, "SHIP TO")
+	cursor += 14
+	page.TextFit(x+8, cursor, 12, true, label.ReceiverName, w-16)
+	cursor += 14
+	page.Text(x+8, cursor, 10, false, label.ReceiverPhone)
+	for _, line := range wrapText(label.ReceiverAddress, int((w-16)/5)) {
+		cursor += 12
+		page.Text(x+8, cursor, 9, false, line)
+	}
+	if zone := carrierZoneLine(label); zone != "" {
+		cursor += 14
+		page.Text(x+8, cursor, 9, true, zone)
+	}
+
+	// Order and collection details
+	footer := y + h - 58
+	page.Line(x, footer, x+w, footer, 0.8)
+	orderRef := label.OrderNumber
+	if orderRef == "" {
+		orderRef = label.OrderID.String()[:8]
+	}
+	page.Text(x+8, footer+16, 9, false, "Order: "+orderRef)
+	page.Text(x+8, footer+30, 9, false, fmt.Sprintf("Weight: %.2f kg", label.Weight))
+	page.Text(x+8, footer+44, 9, false, fmt.Sprintf("Items: %d", packageItemCount(label.Items)))
+
+	collect := "PREPAID"
+	if label.CODAmount > 0 {
+		collect = fmt.Sprintf("COD %s %.2f", label.Currency, label.CODAmount)
+	}
+	page.Text(x+w/2, footer+30, 12, true, collect)
+	return nil
+}
+
+// renderPackingSlips produces one slip per label, grouped by order
+func renderPackingSlips(labels []ShippingLabel, layout LabelLayout, fonts *DocumentFonts) ([]byte, error) {
+	doc := newPDFDocument(fonts)
+
+	width, height := pageA4Width, pageA4Height
+	if layout == LabelLayoutThermal {
+		width, height = pageThermalWidth, pageThermalHeight
+	}
+	margin := docMargin
+	if layout == LabelLayoutThermal {
+		margin = 12
+	}
+	contentW := width - 2*margin
+
+	for i := range labels {
+		label := &labels[i]
+		page := doc.AddPage(width, height)
+
+		orderRef := label.OrderNumber
+		if orderRef == "" {
+			orderRef = label.OrderID.String()
+		}
+
+		cursor := margin + 16
+		page.Text(margin, cursor, 16, true, "PACKING SLIP")
+		cursor += 10
+		if err := page.Barcode(margin, cursor, minFloat(contentW, 220), 36, orderRef); err != nil {
+			return nil, fmt.Errorf("packing slip for label %s: order barcode: %w", label.ID, err)
+		}
+		cursor += 48
+		page.Text(margin, cursor, 10, true, "Order: "+orderRef)
+		cursor += 14
+		page.Text(margin, cursor, 9, false, fmt.Sprintf("%s  %s", strings.ToUpper(string(label.Provider)), label.TrackingNumber))
+
+		cursor += 22
+		page.Text(margin, cursor, 8, true, "SHIP TO")
+		cursor += 13
+		page.TextFit(margin, cursor, 10, true, label.ReceiverName, contentW)
+		for _, line := range wrapText(label.ReceiverAddress, int(contentW/5)) {
+			cursor += 12
+			page.Text(margin, cursor, 9, false, line)
+		}
+
+		// Item table: check box, SKU, description, bin, quantity
+		cursor += 24
+		colSKU := margin + 18
+		colName := colSKU + contentW*0.22
+		colBin := margin + contentW*0.72
+		colQty := margin + contentW - 24
+		page.Text(colSKU, cursor, 8, true, "SKU")
+		page.Text(colName, cursor, 8, true, "ITEM")
+		page.Text(colBin, cursor, 8, true, "BIN")
+		page.Text(colQty, cursor, 8, true, "QTY")
+		cursor += 4
+		page.Line(margin, cursor, margin+contentW, cursor, 0.8)
+
+		for _, item := range label.Items {
+			cursor += 16
+			if cursor > height-margin-40 {
+				page = doc.AddPage(width, height)
+				cursor = margin + 16
+			}
+			page.Rect(margin, cursor-8, 9, 9, false)
+			page.TextFit(colSKU, cursor, 9, false, item.SKU, colName-colSKU-6)
+			page.TextFit(colName, cursor, 9, false, item.Name, colBin-colName-6)
+			page.TextFit(colBin, cursor, 9, false, item.BinLocation, colQty-colBin-6)
+			page.Text(colQty, cursor, 9, true, fmt.Sprintf("%d", item.Quantity))
+		}
+
+		cursor += 10
+		page.Line(margin, cursor, margin+contentW, cursor, 0.8)
+		cursor += 14
+		page.Text(margin, cursor, 9, true, fmt.Sprintf("Total units: %d", packageItemCount(label.Items)))
+		if label.CODAmount > 0 {
+			page.Text(colBin, cursor, 9, true, fmt.Sprintf("COD %s %.2f", label.Currency, label.CODAmount))
+		}
+	}
+
+	return doc.Bytes()
+}
+
+// renderPickList prints the aggregated pick list as an A4 table
+func renderPickList(list *PickList, fonts *DocumentFonts) ([]byte, error) {
+	doc := newPDFDocument(fonts)
+	contentW := pageA4Width - 2*docMargin
+
+	colBin := docMargin
+	colSKU := colBin + 80
+	colName := colSKU + 100
+	colQty := colName + 190
+	colOrders := colQty + 40
+
+	var page *pdfPage
+	var cursor float64
+	newPage := func() {
+		page = doc.AddPage(pageA4Width, pageA4Height)
+		cursor = docMargin + 16
+		page.Text(docMargin, cursor, 16, true, "PICK LIST")
+		page.Text(docMargin+contentW-150, cursor, 9, false, list.GeneratedAt.Format(documentTimeFmt))
+		cursor += 16
+		page.Text(docMargin, cursor, 9, false, fmt.Sprintf("%d orders, %d units, %d lines", list.OrderCount, list.TotalUnits, len(list.Lines)))
+		cursor += 22
+		page.Text(colBin, cursor, 8, true, "BIN")
+		page.Text(colSKU, cursor, 8, true, "SKU")
+		page.Text(colName, cursor, 8, true, "ITEM")
+		page.Text(colQty, cursor, 8, true, "QTY")
+		page.Text(colOrders, cursor, 8, true, "ORDERS")
+		cursor += 4
+		page.Line(docMargin, cursor, docMargin+contentW, cursor, 0.8)
+	}
+
+	newPage()
+	for i, line := range list.Lines {
+		if i > 0 && i%pickListRows == 0 {
+			newPage()
+		}
+		cursor += 20
+		page.TextFit(colBin, cursor, 9, true, line.BinLocation, colSKU-colBin-6)
+		page.TextFit(colSKU, cursor, 9, false, line.SKU, colName-colSKU-6)
+		page.TextFit(colName, cursor, 9, false, line.Name, colQty-colName-6)
+		page.Text(colQty, cursor, 10, true, fmt.Sprintf("%d", line.Quantity))
+		page.TextFit(colOrders, cursor, 8, false, strings.Join(line.Orders, ", "), docMargin+contentW-colOrders)
+		page.Line(docMargin, cursor+6, docMargin+contentW, cursor+6, 0.3)
+	}
+
+	return doc.Bytes()
+}
+
+// renderManifest prints the carrier handover sheet with signature blocks
+func renderManifest(manifest *ShippingManifest, fonts *DocumentFonts) ([]byte, error) {
+	doc := newPDFDocument(fonts)
+	contentW := pageA4Width - 2*docMargin
+
+	colNo := docMargin
+	colTracking := colNo + 24
+	colOrder := colTracking + 110
+	colReceiver := colOrder + 70
+	colCity := colReceiver + 120
+	colWeight := colCity + 80
+	colCOD := colWeight + 50
+
+	var page *pdfPage
+	var cursor float64
+	newPage := func() error {
+		page = doc.AddPage(pageA4Width, pageA4Height)
+		cursor = docMargin + 16
+		page.Text(docMargin, cursor, 16, true, "CARRIER MANIFEST")
+		page.Text(docMargin+contentW-150, cursor, 12, true, strings.ToUpper(string(manifest.Provider)))
+		cursor += 8
+		if err := page.Barcode(docMargin, cursor, 200, 32, manifest.ManifestNumber); err != nil {
+			return fmt.Errorf("manifest %s: barcode: %w", manifest.ManifestNumber, err)
+		}
+		cursor += 44
+		page.Text(docMargin, cursor, 10, true, manifest.ManifestNumber)
+		page.Text(docMargin+contentW-150, cursor, 9, false, "Pickup: "+manifest.PickupDate.Format("02 Jan 2006"))
+		cursor += 24
+		page.Text(colNo, cursor, 8, true, "#")
+		page.Text(colTracking, cursor, 8, true, "TRACKING")
+		page.Text(colOrder, cursor, 8, true, "ORDER")
+		page.Text(colReceiver, cursor, 8, true, "RECEIVER")
+		page.Text(colCity, cursor, 8, true, "CITY")
+		page.Text(colWeight, cursor, 8, true, "KG")
+		page.Text(colCOD, cursor, 8, true, "COD")
+		cursor += 4
+		page.Line(docMargin, cursor, docMargin+contentW, cursor, 0.8)
+		return nil
+	}
+
+	if err := newPage(); err != nil {
+		return nil, err
+	}
+	for i, label := range manifest.Labels {
+		if i > 0 && i%manifestRows == 0 {
+			if err := newPage(); err != nil {
+				return nil, err
+			}
+		}
+		cursor += 18
+		page.Text(colNo, cursor, 8, false, fmt.Sprintf("%d", i+1))
+		page.TextFit(colTracking, cursor, 8, false, label.TrackingNumber, colOrder-colTracking-4)
+		page.TextFit(colOrder, cursor, 8, false, label.OrderNumber, colReceiver-colOrder-4)
+		page.TextFit(colReceiver, cursor, 8, false, label.ReceiverName, colCity-colReceiver-4)
+		page.TextFit(colCity, cursor, 8, false, label.ReceiverCity, colWeight-colCity-4)
+		page.Text(colWeight, cursor, 8, false, fmt.Sprintf("%.2f", label.Weight))
+		page.Text(colCOD, cursor, 8, false, fmt.Sprintf("%.2f", label.CODAmount))
+	}
+
+	// Totals and signatures go on the last page; start a new one if it is full
+	if cursor > pageA4Height-docMargin-150 {
+		if err := newPage(); err != nil {
+			return nil, err
+		}
+	}
+	cursor += 10
+	page.Line(docMargin, cursor, docMargin+contentW, cursor, 0.8)
+	cursor += 16
+	page.Text(docMargin, cursor, 10, true, fmt.Sprintf("Parcels: %d", manifest.TotalParcels))
+	page.Text(colCity, cursor, 10, true, fmt.Sprintf("%.2f kg", manifest.TotalWeight))
+	page.Text(colCOD-30, cursor, 10, true, fmt.Sprintf("COD %.2f", manifest.TotalCOD))
+
+	cursor += 70
+	half := contentW / 2
+	page.Line(docMargin, cursor, docMargin+half-30, cursor, 0.8)
+	page.Line(docMargin+half+30, cursor, docMargin+contentW, cursor, 0.8)
+	cursor += 12
+	page.Text(docMargin, cursor, 8, false, "Handed over by (name, signature)")
+	page.Text(docMargin+half+30, cursor, 8, false, "Received by carrier (name, signature)")
+	cursor += 30
+	page.Text(docMargin, cursor, 8, false, "Date / time: ______________________")
+	page.Text(docMargin+half+30, cursor, 8, false, "Parcels received: __________")
+
+	return doc.Bytes()
+}
+
+func carrierZoneLine(label *ShippingLabel) string {
+	var parts []string
+	if label.CarrierCityID != "" {
+		parts = append(parts, "City "+label.CarrierCityID)
+	}
+	if label.CarrierZoneID != "" {
+		parts = append(parts, "Zone "+label.CarrierZoneID)
+	}
+	if label.CarrierAreaID != "" {
+		parts = append(parts, "Area "+label.CarrierAreaID)
+	}
+	return strings.Join(parts, " / ")
+}
+
+func packageItemCount(items []PackageItem) int {
+	count := 0
+	for _, item := range items {
+		count += item.Quantity
+	}
+	return count
+}
+
+// wrapText splits text into lines of at most width characters
+func wrapText(text string, width int) []string {
+	if width < 10 {
+		width = 10
+	}
+
+	var lines []string
+	var current string
+	for _, word := range strings.Fields(text) {
+		if current == "" {
+			current = word
+			continue
+		}
+		if utf8.RuneCountInString(current)+1+utf8.RuneCountInString(word) > width {
+			lines = append(lines, current)
+			current = word
+			continue
+		}
+		current += " " + word
+	}
+	if current != "" {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// documentFilename builds a download name such as "labels-20250101-1504.pdf"
+func documentFilename(kind string, at time.Time) string {
+	return fmt.Sprintf("%s-%s.pdf", kind, at.Format("20060102-1504"))
+}
@@ -0,0 +1,414 @@
+package shipping
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Warehouse fulfillment: bulk label creation, label/packing slip printing,
+// pick lists and per-carrier pickup manifests
+
+const maxBulkLabels = 200
+
+// Request/Response Models
+
+type BulkCreateShippingLabelsRequest struct {
+	Labels []CreateShippingLabelRequest `json:"labels" binding:"required,min=1,dive"`
+}
+
+type BulkLabelResult struct {
+	OrderID string         `json:"order_id"`
+	Label   *ShippingLabel `json:"label,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+type BulkLabelResponse struct {
+	Created int               `json:"created"`
+	Failed  int               `json:"failed"`
+	Results []BulkLabelResult `json:"results"`
+}
+
+type PrintLabelsRequest struct {
+	LabelIDs []string    `json:"label_ids" binding:"required,min=1"`
+	Layout   LabelLayout `json:"layout"` // a4 (default), thermal_4x6
+}
+
+type PickListRequest struct {
+	LabelIDs []string `json:"label_ids" binding:"required,min=1"`
+}
+
+// PickList aggregates the items of several shipments for one warehouse walk
+type PickList struct {
+	GeneratedAt time.Time      `json:"generated_at"`
+	OrderCount  int            `json:"order_count"`
+	TotalUnits  int            `json:"total_units"`
+	Lines       []PickListLine `json:"lines"`
+}
+
+type PickListLine struct {
+	BinLocation string   `json:"bin_location"`
+	SKU         string   `json:"sku"`
+	Name        string   `json:"name"`
+	Quantity    int      `json:"quantity"`
+	Orders      []string `json:"orders"`
+}
+
+type CreateManifestRequest struct {
+	Provider   ShippingProvider `json:"provider" binding:"required"`
+	LabelIDs   []string         `json:"label_ids"` // Defaults to every unmanifested label for the provider
+	PickupDate *time.Time       `json:"pickup_date"`
+}
+
+type HandOverManifestRequest struct {
+	HandedOverTo string `json:"handed_over_to" binding:"required"`
+}
+
+// FullAddress formats the address on a single line for labels
+func (a Address) FullAddress() string {
+	var parts []string
+	for _, part := range []string{a.Street, a.City, a.State, a.PostalCode, a.Country} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Bulk Label Services
+
+// BulkCreateShippingLabels creates labels one by one; a failed order does not
+// stop the rest of the batch
+func (s *Service) BulkCreateShippingLabels(tenantID uuid.UUID, req BulkCreateShippingLabelsRequest) (*BulkLabelResponse, error) {
+	if len(req.Labels) > maxBulkLabels {
+		return nil, fmt.Errorf("at most %d labels can be created per batch", maxBulkLabels)
+	}
+
+	response := &BulkLabelResponse{Results: make([]BulkLabelResult, 0, len(req.Labels))}
+	for _, labelReq := range req.Labels {
+		result := BulkLabelResult{OrderID: labelReq.OrderID}
+
+		label, err := s.CreateShippingLabel(tenantID, labelReq)
+		if err != nil {
+			result.Error = err.Error()
+			response.Failed++
+		} else {
+			result.Label = label
+			response.Created++
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	return response, nil
+}
+
+// PrintShippingLabels renders the labels as one merged PDF and marks them printed
+func (s *Service) PrintShippingLabels(tenantID uuid.UUID, req PrintLabelsRequest) ([]byte, error) {
+	layout, err := parseLabelLayout(req.Layout)
+	if err != nil {
+		return nil, err
+	}
+
+	labels, ids, err := s.getLabelsForPrinting(tenantID, req.LabelIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	pdf, err := renderShippingLabels(labels, layout, s.documentFonts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repository.MarkLabelsPrinted(tenantID, ids, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to mark labels printed: %v", err)
+	}
+
+	return pdf, nil
+}
+
+// GeneratePackingSlips renders one packing slip per shipment
+func (s *Service) GeneratePackingSlips(tenantID uuid.UUID, req PrintLabelsRequest) ([]byte, error) {
+	layout, err := parseLabelLayout(req.Layout)
+	if err != nil {
+		return nil, err
+	}
+
+	labels, _, err := s.getLabelsForPrinting(tenantID, req.LabelIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(labels, func(i, j int) bool {
+		return labels[i].OrderNumber < labels[j].OrderNumber
+	})
+
+	return renderPackingSlips(labels, layout, s.documentFonts)
+}
+
+// GetPickList groups the items of the selected shipments by bin and SKU
+func (s *Service) GetPickList(tenantID uuid.UUID, req PickListRequest) (*PickList, error) {
+	labels, _, err := s.getLabelsForPrinting(tenantID, req.LabelIDs)
+	if err != nil {
+		return nil, err
+	}
+	return buildPickList(labels), nil
+}
+
+// GetPickListPDF renders the pick list for printing
+func (s *Service) GetPickListPDF(tenantID uuid.UUID, req PickListRequest) ([]byte, error) {
+	list, err := s.GetPickList(tenantID, req)
+	if err != nil {
+		return nil, err
+	}
+	return renderPickList(list, s.documentFonts)
+}
+
+// Manifest Services
+
+// CreateManifest groups a carrier's pending labels into a pickup manifest
+func (s *Service) CreateManifest(tenantID uuid.UUID, req CreateManifestRequest) (*ShippingManifest, error) {
+	var labels []ShippingLabel
+	if len(req.LabelIDs) > 0 {
+		ids, err := parseLabelIDs(req.LabelIDs)
+		if err != nil {
+			return nil, err
+		}
+		labels, err = s.repository.GetShippingLabelsByIDs(tenantID, ids)
+		if err != nil {
+			return nil, err
+		}
+		if len(labels) != len(ids) {
+			return nil, errors.New("one or more shipping labels not found")
+		}
+		for _, label := range labels {
+			if label.Provider != req.Provider {
+				return nil, fmt.Errorf("label %s belongs to %s, not %s", label.TrackingNumber, label.Provider, req.Provider)
+			}
+			if !label.CanBeManifested() {
+				return nil, fmt.Errorf("label %s is already manifested or shipped", label.TrackingNumber)
+			}
+		}
+	} else {
+		var err error
+		labels, err = s.repository.GetManifestableLabels(tenantID, req.Provider)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(labels) == 0 {
+		return nil, errors.New("no labels awaiting pickup for this provider")
+	}
+
+	now := time.Now()
+	pickupDate := now
+	if req.PickupDate != nil {
+		pickupDate = *req.PickupDate
+	}
+
+	sequence, err := s.repository.CountManifestsForDay(tenantID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &ShippingManifest{
+		TenantID:       tenantID,
+		Provider:       req.Provider,
+		ManifestNumber: fmt.Sprintf("MF-%s-%s-%03d", strings.ToUpper(string(req.Provider)), now.Format("20060102"), sequence+1),
+		PickupDate:     pickupDate,
+		Status:         "open",
+	}
+
+	ids := make([]uuid.UUID, 0, len(labels))
+	for _, label := range labels {
+		ids = append(ids, label.ID)
+		manifest.TotalParcels++
+		manifest.TotalWeight += label.Weight
+		manifest.TotalCOD += label.CODAmount
+	}
+
+	if _, err := s.repository.CreateManifest(manifest, ids); err != nil {
+		return nil, err
+	}
+
+	return s.repository.GetManifest(tenantID, manifest.ID)
+}
+
+func (s *Service) GetManifest(tenantID uuid.UUID, manifestID string) (*ShippingManifest, error) {
+	id, err := uuid.Parse(manifestID)
+	if err != nil {
+		return nil, errors.New("invalid manifest ID")
+	}
+	return s.repository.GetManifest(tenantID, id)
+}
+
+func (s *Service) GetManifests(tenantID uuid.UUID, provider ShippingProvider, offset, limit int) ([]ShippingManifest, int64, error) {
+	return s.repository.GetManifests(tenantID, provider, offset, limit)
+}
+
+// GetManifestPDF renders the handover sheet for the daily pickup
+func (s *Service) GetManifestPDF(tenantID uuid.UUID, manifestID string) (*ShippingManifest, []byte, error) {
+	manifest, err := s.GetManifest(tenantID, manifestID)
+	if err != nil {
+		return nil, nil, err
+	}
+	pdf, err := renderManifest(manifest, s.documentFonts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return manifest, pdf, nil
+}
+
+// HandOverManifest records the carrier pickup and marks its labels shipped
+func (s *Service) HandOverManifest(tenantID uuid.UUID, manifestID string, req HandOverManifestRequest) (*ShippingManifest, error) {
+	manifest, err := s.GetManifest(tenantID, manifestID)
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest.Status != "open" {
+		return nil, errors.New("manifest has already been handed over")
+	}
+
+	now := time.Now()
+	manifest.Status = "handed_over"
+	manifest.HandedOverAt = &now
+	manifest.HandedOverTo = req.HandedOverTo
+
+	// Save only the manifest row; label statuses are updated in bulk
+	labels := manifest.Labels
+	manifest.Labels = nil
+	if err := s.repository.HandOverManifest(manifest); err != nil {
+		return nil, err
+	}
+	manifest.Labels = labels
+	for i := range manifest.Labels {
+		if manifest.Labels[i].Status == "created" || manifest.Labels[i].Status == "printed" {
+			manifest.Labels[i].Status = "shipped"
+		}
+	}
+
+	return manifest, nil
+}
+
+// Helper methods
+
+func (s *Service) getLabelsForPrinting(tenantID uuid.UUID, labelIDs []string) ([]ShippingLabel, []uuid.UUID, error) {
+	if len(labelIDs) > maxBulkLabels {
+		return nil, nil, fmt.Errorf("at most %d labels can be printed at once", maxBulkLabels)
+	}
+
+	ids, err := parseLabelIDs(labelIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	labels, err := s.repository.GetShippingLabelsByIDs(tenantID, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(labels) != len(ids) {
+		return nil, nil, errors.New("one or more shipping labels not found")
+	}
+	for _, label := range labels {
+		if label.Status == "cancelled" {
+			return nil, nil, fmt.Errorf("label %s has been cancelled", label.TrackingNumber)
+		}
+	}
+
+	return labels, ids, nil
+}
+
+func parseLabelIDs(labelIDs []string) ([]uuid.UUID, error) {
+	seen := make(map[uuid.UUID]bool, len(labelIDs))
+	ids := make([]uuid.UUID, 0, len(labelIDs))
+	for _, raw := range labelIDs {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, errors.New("invalid label ID")
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func parseLabelLayout(layout LabelLayout) (LabelLayout, error) {
+	switch layout {
+	case "":
+		return LabelLayoutA4, nil
+	case LabelLayoutA4, LabelLayoutThermal:
+		return layout, nil
+	default:
+		return "", fmt.Errorf("unsupported label layout %q", layout)
+	}
+}
+
+// buildPickList merges identical SKUs in the same bin across orders. Lines
+// are sorted by bin so the picker walks the warehouse once; items without a
+// bin are listed last.
+func buildPickList(labels []ShippingLabel) *PickList {
+	list := &PickList{GeneratedAt: time.Now(), Lines: []PickListLine{}}
+	lines := make(map[string]*PickListLine)
+	orders := make(map[uuid.UUID]bool)
+
+	for _, label := range labels {
+		orders[label.OrderID] = true
+		orderRef := label.OrderNumber
+		if orderRef == "" {
+			orderRef = label.OrderID.String()[:8]
+		}
+
+		for _, item := range label.Items {
+			if item.Quantity <= 0 {
+				continue
+			}
+			bin := strings.TrimSpace(item.BinLocation)
+			if bin == "" {
+				bin = unassignedBin
+			}
+			sku := strings.TrimSpace(item.SKU)
+			key := bin + "|" + sku
+			if sku == "" {
+				key += "|" + strings.ToLower(item.Name)
+			}
+
+			line, ok := lines[key]
+			if !ok {
+				line = &PickListLine{BinLocation: bin, SKU: sku, Name: item.Name}
+				lines[key] = line
+			}
+			line.Quantity += item.Quantity
+			if len(line.Orders) == 0 || line.Orders[len(line.Orders)-1] != orderRef {
+				line.Orders = append(line.Orders, orderRef)
+			}
+			list.TotalUnits += item.Quantity
+		}
+	}
+
+	for _, line := range lines {
+		list.Lines = append(list.Lines, *line)
+	}
+	sort.Slice(list.Lines, func(i, j int) bool {
+		a, b := list.Lines[i], list.Lines[j]
+		if (a.BinLocation == unassignedBin) != (b.BinLocation == unassignedBin) {
+			return b.BinLocation == unassignedBin
+		}
+		if a.BinLocation != b.BinLocation {
+			return a.BinLocation < b.BinLocation
+		}
+		if a.SKU != b.SKU {
+			return a.SKU < b.SKU
+		}
+		return a.Name < b.Name
+	})
+
+	list.OrderCount = len(orders)
+	return list
+}
@@ -1,8 +1,11 @@
 package shipping
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -170,6 +173,113 @@ func (h *Handler) CreateShippingRate(c *gin.Context) {
 	})
 }
 
+func (h *Handler) GetLiveShippingRates(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	var req ShippingRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	rates, err := h.service.CalculateLiveShippingRates(c.Request.Context(), tenantID.(uuid.UUID), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": rates})
+}
+
+// Shipping Markup Rules
+
+func (h *Handler) CreateMarkupRule(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	var req ShippingMarkupRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	rule, err := h.service.CreateMarkupRule(tenantID.(uuid.UUID), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Markup rule created successfully",
+		"data":    rule,
+	})
+}
+
+func (h *Handler) GetMarkupRules(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	rules, err := h.service.GetMarkupRules(tenantID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch markup rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": rules})
+}
+
+func (h *Handler) UpdateMarkupRule(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	ruleID := c.Param("id")
+	var req ShippingMarkupRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	rule, err := h.service.UpdateMarkupRule(tenantID.(uuid.UUID), ruleID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Markup rule updated successfully",
+		"data":    rule,
+	})
+}
+
+func (h *Handler) DeleteMarkupRule(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	ruleID := c.Param("id")
+	if err := h.service.DeleteMarkupRule(tenantID.(uuid.UUID), ruleID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Markup rule deleted successfully"})
+}
+
 // Shipping Labels
 
 func (h *Handler) CreateShippingLabel(c *gin.Context) {
@@ -299,6 +409,237 @@ func (h *Handler) CancelShipment(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Shipment cancelled successfully"})
 }
 
+// Fulfillment
+
+func (h *Handler) BulkCreateShippingLabels(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	var req BulkCreateShippingLabelsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	result, err := h.service.BulkCreateShippingLabels(tenantID.(uuid.UUID), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := http.StatusCreated
+	if result.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+
+	c.JSON(status, gin.H{
+		"message": fmt.Sprintf("%d labels created, %d failed", result.Created, result.Failed),
+		"data":    result,
+	})
+}
+
+func (h *Handler) PrintShippingLabels(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	var req PrintLabelsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	pdf, err := h.service.PrintShippingLabels(tenantID.(uuid.UUID), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	writePDF(c, documentFilename("labels", time.Now()), pdf)
+}
+
+func (h *Handler) GetPackingSlips(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	var req PrintLabelsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	pdf, err := h.service.GeneratePackingSlips(tenantID.(uuid.UUID), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	writePDF(c, documentFilename("packing-slips", time.Now()), pdf)
+}
+
+func (h *Handler) GetPickList(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	var req PickListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	if c.Query("format") == "pdf" {
+		pdf, err := h.service.GetPickListPDF(tenantID.(uuid.UUID), req)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		writePDF(c, documentFilename("pick-list", time.Now()), pdf)
+		return
+	}
+
+	list, err := h.service.GetPickList(tenantID.(uuid.UUID), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+func (h *Handler) CreateManifest(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	var req CreateManifestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	manifest, err := h.service.CreateManifest(tenantID.(uuid.UUID), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Manifest created successfully",
+		"data":    manifest,
+	})
+}
+
+func (h *Handler) GetManifests(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset parameter"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+		return
+	}
+
+	if limit > 100 {
+		limit = 100
+	}
+
+	provider := ShippingProvider(c.Query("provider"))
+	manifests, total, err := h.service.GetManifests(tenantID.(uuid.UUID), provider, offset, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch manifests"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"manifests": manifests,
+			"total":     total,
+			"offset":    offset,
+			"limit":     limit,
+		},
+	})
+}
+
+func (h *Handler) GetManifest(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	manifestID := c.Param("id")
+
+	if c.Query("format") == "pdf" {
+		manifest, pdf, err := h.service.GetManifestPDF(tenantID.(uuid.UUID), manifestID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Manifest not found"})
+			return
+		}
+		writePDF(c, manifest.ManifestNumber+".pdf", pdf)
+		return
+	}
+
+	manifest, err := h.service.GetManifest(tenantID.(uuid.UUID), manifestID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Manifest not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": manifest})
+}
+
+func (h *Handler) HandOverManifest(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	var req HandOverManifestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	manifest, err := h.service.HandOverManifest(tenantID.(uuid.UUID), c.Param("id"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Manifest handed over successfully",
+		"data":    manifest,
+	})
+}
+
+func writePDF(c *gin.Context, filename string, pdf []byte) {
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
+
 // Package Tracking
 
 func (h *Handler) TrackPackage(c *gin.Context) {
@@ -325,6 +666,234 @@ func (h *Handler) GetTrackingHistory(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": history})
 }
 
+func (h *Handler) GetOrderTimeline(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	timeline, err := h.service.GetOrderTimeline(tenantID.(uuid.UUID), c.Param("orderId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": timeline})
+}
+
+// TrackOrderPublic serves the storefront tracking page (order number + phone)
+func (h *Handler) TrackOrderPublic(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	timeline, err := h.service.TrackOrderPublic(tenantID.(uuid.UUID), c.Query("order_number"), c.Query("phone"))
+	if err != nil {
+		if errors.Is(err, ErrShipmentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": timeline})
+}
+
+// Returns to Origin
+
+func (h *Handler) InitiateRTO(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	var req InitiateRTORequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	record, err := h.service.InitiateRTO(tenantID.(uuid.UUID), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Return to origin initiated",
+		"data":    record,
+	})
+}
+
+func (h *Handler) GetRTORecords(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset parameter"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+		return
+	}
+
+	if limit > 100 {
+		limit = 100
+	}
+
+	provider := ShippingProvider(c.Query("provider"))
+	records, total, err := h.service.GetRTORecords(tenantID.(uuid.UUID), c.Query("status"), provider, offset, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch RTO records"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"records": records,
+			"total":   total,
+			"offset":  offset,
+			"limit":   limit,
+		},
+	})
+}
+
+func (h *Handler) ReceiveRTO(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	var req ReceiveRTORequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	record, err := h.service.ReceiveRTO(c.Request.Context(), tenantID.(uuid.UUID), c.Param("id"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	message := "Return received successfully"
+	if record.ProcessingError != "" {
+		message = "Return received with errors; retry to complete the remaining steps"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"data":    record,
+	})
+}
+
+// GetRTOReport returns RTO rates by carrier, area or customer
+// (?group_by=carrier|area|customer&from=2006-01-02&to=2006-01-02)
+func (h *Handler) GetRTOReport(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	to := time.Now().Truncate(24 * time.Hour).AddDate(0, 0, 1)
+	from := to.AddDate(0, 0, -30)
+
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date, expected YYYY-MM-DD"})
+			return
+		}
+		from = parsed
+	}
+	if value := c.Query("to"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date, expected YYYY-MM-DD"})
+			return
+		}
+		to = parsed.AddDate(0, 0, 1)
+	}
+
+	report, err := h.service.GetRTOReport(tenantID.(uuid.UUID), c.Query("group_by"), from, to)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+func (h *Handler) GetCustomerRisk(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	profile, err := h.service.GetCustomerRisk(tenantID.(uuid.UUID), c.Query("phone"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": profile})
+}
+
+func (h *Handler) GetRiskProfiles(c *gin.Context) {
+	tenantID, exists := c.Get("tenant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset parameter"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+		return
+	}
+
+	if limit > 100 {
+		limit = 100
+	}
+
+	flaggedOnly := c.Query("flagged") == "true"
+	profiles, total, err := h.service.GetRiskProfiles(tenantID.(uuid.UUID), flaggedOnly, offset, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch customer risk profiles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"customers": profiles,
+			"total":     total,
+			"offset":    offset,
+			"limit":     limit,
+		},
+	})
+}
+
 // Address Validation
 
 func (h *Handler) ValidateAddress(c *gin.Context) {
@@ -486,6 +1055,11 @@ func (h *Handler) FedExWebhook(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Webhook processed successfully"})
 }
 
+// RegisterPublicRoutes registers storefront shipping routes
+func (h *Handler) RegisterPublicRoutes(router *gin.RouterGroup) {
+	router.GET("/track", h.TrackOrderPublic)
+}
+
 // RegisterRoutes registers all shipping routes
 func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 	shipping := router.Group("/shipping")
@@ -500,6 +1074,13 @@ func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 		// Shipping Rates
 		shipping.POST("/rates", h.GetShippingRates)
 		shipping.POST("/rates/create", h.CreateShippingRate)
+		shipping.POST("/rates/live", h.GetLiveShippingRates)
+
+		// Markup Rules
+		shipping.POST("/markup-rules", h.CreateMarkupRule)
+		shipping.GET("/markup-rules", h.GetMarkupRules)
+		shipping.PUT("/markup-rules/:id", h.UpdateMarkupRule)
+		shipping.DELETE("/markup-rules/:id", h.DeleteMarkupRule)
 
 		// Shipping Labels
 		shipping.POST("/labels", h.CreateShippingLabel)
@@ -507,9 +1088,28 @@ func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 		shipping.GET("/labels/:id", h.GetShippingLabel)
 		shipping.DELETE("/labels/:id/cancel", h.CancelShipment)
 
+		// Warehouse Fulfillment
+		shipping.POST("/labels/bulk", h.BulkCreateShippingLabels)
+		shipping.POST("/labels/print", h.PrintShippingLabels)
+		shipping.POST("/labels/packing-slips", h.GetPackingSlips)
+		shipping.POST("/labels/pick-list", h.GetPickList)
+		shipping.POST("/manifests", h.CreateManifest)
+		shipping.GET("/manifests", h.GetManifests)
+		shipping.GET("/manifests/:id", h.GetManifest)
+		shipping.POST("/manifests/:id/handover", h.HandOverManifest)
+
 		// Package Tracking (public)
 		shipping.GET("/track/:trackingNumber", h.TrackPackage)
 		shipping.GET("/track/:trackingNumber/history", h.GetTrackingHistory)
+		shipping.GET("/orders/:orderId/timeline", h.GetOrderTimeline)
+
+		// Returns to Origin
+		shipping.POST("/rto", h.InitiateRTO)
+		shipping.GET("/rto", h.GetRTORecords)
+		shipping.GET("/rto/report", h.GetRTOReport)
+		shipping.POST("/rto/:id/receive", h.ReceiveRTO)
+		shipping.GET("/customer-risk", h.GetCustomerRisk)
+		shipping.GET("/customer-risk/profiles", h.GetRiskProfiles)
 
 		// Address & Delivery
 		shipping.POST("/validate-address", h.ValidateAddress)
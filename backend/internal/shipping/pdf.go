@@ -0,0 +1,309 @@
+package shipping
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Minimal PDF writer for labels, packing slips, pick lists and manifests.
+//
+// Documents use the standard Helvetica fonts and a top-left origin in
+// points. Text outside Latin-1 is drawn with the embedded DocumentFonts (see
+// pdffont.go) and replaced with '?' when no font covers it.
+
+// Page sizes in points
+const (
+	pageA4Width       = 595.28
+	pageA4Height      = 841.89
+	pageThermalWidth  = 288 // 4in
+	pageThermalHeight = 432 // 6in
+)
+
+type pdfDocument struct {
+	pages []*pdfPage
+	fonts *DocumentFonts
+	usage map[*trueTypeFont]*fontUsage
+}
+
+type pdfPage struct {
+	doc     *pdfDocument
+	width   float64
+	height  float64
+	content bytes.Buffer
+}
+
+// newPDFDocument starts a document; fonts may be nil, in which case text
+// outside Latin-1 is replaced with '?'
+func newPDFDocument(fonts *DocumentFonts) *pdfDocument {
+	return &pdfDocument{fonts: fonts, usage: make(map[*trueTypeFont]*fontUsage)}
+}
+
+// AddPage appends a page of the given size in points
+func (d *pdfDocument) AddPage(width, height float64) *pdfPage {
+	page := &pdfPage{doc: d, width: width, height: height}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// Text draws a single line of text with its baseline at y
+func (p *pdfPage) Text(x, y, size float64, bold bool, text string) {
+	latin, unicode := "F1", "F3"
+	if bold {
+		latin, unicode = "F2", "F4"
+	}
+	font := p.doc.fonts.font(bold)
+	if font != nil {
+		text = reorderBengali(text)
+	}
+
+	fmt.Fprintf(&p.content, "BT %.2f %.2f Td", x, p.height-y)
+	var run strings.Builder
+	inUnicode := false
+	flush := func() {
+		if run.Len() == 0 {
+			return
+		}
+		if inUnicode {
+			fmt.Fprintf(&p.content, " /%s %.2f Tf <%s> Tj", unicode, size, run.String())
+		} else {
+			fmt.Fprintf(&p.content, " /%s %.2f Tf (%s) Tj", latin, size, run.String())
+		}
+		run.Reset()
+	}
+
+	for _, r := range text {
+		if r <= 255 || font == nil {
+			if inUnicode {
+				flush()
+				inUnicode = false
+			}
+			run.WriteString(pdfEscape(string(r)))
+			continue
+		}
+		glyph, ok := font.glyphs[r]
+		if !ok {
+			if r == zeroWidthJoiner || r == zeroWidthNonJoiner {
+				continue // Only steer shaping, which isn't done here
+			}
+			if inUnicode {
+				flush()
+				inUnicode = false
+			}
+			run.WriteByte('?')
+			continue
+		}
+		if !inUnicode {
+			flush()
+			inUnicode = true
+		}
+		p.doc.useGlyph(font, glyph, r)
+		fmt.Fprintf(&run, "%04X", glyph)
+	}
+	flush()
+	p.content.WriteString(" ET\n")
+}
+
+const (
+	zeroWidthNonJoiner = '\u200C'
+	zeroWidthJoiner    = '\u200D'
+)
+
+// useGlyph records a glyph for the font's width array and ToUnicode map
+func (d *pdfDocument) useGlyph(font *trueTypeFont, glyph uint16, r rune) {
+	usage, ok := d.usage[font]
+	if !ok {
+		usage = &fontUsage{font: font, glyphs: make(map[uint16][]rune)}
+		d.usage[font] = usage
+	}
+	if _, ok := usage.glyphs[glyph]; !ok {
+		usage.glyphs[glyph] = []rune{r}
+	}
+}
+
+// TextFit draws text truncated to roughly fit maxWidth
+func (p *pdfPage) TextFit(x, y, size float64, bold bool, text string, maxWidth float64) {
+	maxChars := int(maxWidth / (size * 0.5))
+	if runes := []rune(text); maxChars > 3 && len(runes) > maxChars {
+		text = string(runes[:maxChars-3]) + "..."
+	}
+	p.Text(x, y, size, bold, text)
+}
+
+// Line draws a straight line
+func (p *pdfPage) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, p.height-y1, x2, p.height-y2)
+}
+
+// Rect draws a rectangle whose top-left corner is (x, y)
+func (p *pdfPage) Rect(x, y, w, h float64, fill bool) {
+	op := "S"
+	if fill {
+		op = "f"
+	}
+	fmt.Fprintf(&p.content, "0.8 w %.2f %.2f %.2f %.2f re %s\n", x, p.height-y-h, w, h, op)
+}
+
+// Barcode draws data as a Code 128 (set B) barcode scaled to width w
+func (p *pdfPage) Barcode(x, y, w, h float64, data string) error {
+	modules, err := code128Modules(data)
+	if err != nil {
+		return err
+	}
+
+	total := 0
+	for _, m := range modules {
+		total += m
+	}
+	unit := w / float64(total)
+
+	cursor := x
+	for i, m := range modules {
+		width := float64(m) * unit
+		if i%2 == 0 {
+			fmt.Fprintf(&p.content, "%.3f %.2f %.3f %.2f re f\n", cursor, p.height-y-h, width, h)
+		}
+		cursor += width
+	}
+	return nil
+}
+
+// Bytes serialises the document
+func (d *pdfDocument) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	var offsets []int
+
+	writeObject := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+
+	// Objects 1-4: catalog, page tree, Helvetica fonts; then five objects per
+	// embedded font, then pages in pairs (page, content)
+	next := 5
+	fontResources := "/F1 3 0 R /F2 4 0 R"
+	var fontObjects []string
+	fontRefs := make(map[*trueTypeFont]int)
+	for _, bold := range []bool{false, true} {
+		font := d.fonts.font(bold)
+		usage, ok := d.usage[font]
+		if !ok {
+			continue
+		}
+		if _, ok := fontRefs[font]; !ok {
+			objects, err := usage.pdfObjects(next)
+			if err != nil {
+				return nil, fmt.Errorf("failed to embed font %s: %w", font.name, err)
+			}
+			fontRefs[font] = next
+			fontObjects = append(fontObjects, objects...)
+			next += len(objects)
+		}
+		name := "F3"
+		if bold {
+			name = "F4"
+		}
+		fontResources += fmt.Sprintf(" /%s %d 0 R", name, fontRefs[font])
+	}
+
+	pageObjects := make([]string, len(d.pages))
+	for i := range d.pages {
+		pageObjects[i] = fmt.Sprintf("%d 0 R", next+i*2)
+	}
+
+	writeObject("<< /Type /Catalog /Pages 2 0 R >>")
+	writeObject(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(pageObjects, " "), len(d.pages)))
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for _, object := range fontObjects {
+		writeObject(object)
+	}
+
+	for i, page := range d.pages {
+		writeObject(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << %s >> >> /Contents %d 0 R >>",
+			page.width, page.height, fontResources, next+i*2+1))
+		writeObject(streamObject(page.content.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return buf.Bytes(), nil
+}
+
+func pdfEscape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n' || r == '\r' || r == '\t':
+			b.WriteByte(' ')
+		case r < 32 || r > 255:
+			b.WriteByte('?')
+		case r > 126:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Code 128 bar/space widths for symbol values 0-106 (106 = stop)
+var code128Patterns = [...]string{
+	"212222", "222122", "222221", "121223", "121322", "131222", "122213", "122312", "132212", "221213",
+	"221312", "231212", "112232", "122132", "122231", "113222", "123122", "123221", "223211", "221132",
+	"221231", "213212", "223112", "312131", "311222", "321122", "321221", "312212", "322112", "322211",
+	"212123", "212321", "232121", "111323", "131123", "131321", "112313", "132113", "132311", "211313",
+	"231113", "231311", "112133", "112331", "132131", "113123", "113321", "133121", "313121", "211331",
+	"231131", "213113", "213311", "213131", "311123", "311321", "331121", "312113", "312311", "332111",
+	"314111", "221411", "431111", "111224", "111422", "121124", "121421", "141122", "141221", "112214",
+	"112412", "122114", "122411", "142112", "142211", "241211", "221114", "413111", "241112", "134111",
+	"111242", "121142", "121241", "114212", "124112", "124211", "411212", "421112", "421211", "212141",
+	"214121", "412121", "111143", "111341", "131141", "114113", "114311", "411113", "411311", "113141",
+	"114131", "311141", "411131", "211412", "211214", "211232", "2331112",
+}
+
+const (
+	code128StartB = 104
+	code128Stop   = 106
+)
+
+// code128Modules encodes data in code set B and returns alternating
+// bar/space widths, including quiet zones
+func code128Modules(data string) ([]int, error) {
+	if data == "" {
+		return nil, errors.New("barcode data is empty")
+	}
+
+	values := []int{code128StartB}
+	checksum := code128StartB
+	for i, r := range data {
+		if r < 32 || r > 126 {
+			return nil, fmt.Errorf("barcode character %q is not supported", r)
+		}
+		value := int(r) - 32
+		values = append(values, value)
+		checksum += value * (i + 1)
+	}
+	values = append(values, checksum%103, code128Stop)
+
+	// Leading quiet zone is expressed as a zero-width bar followed by a space
+	modules := []int{0, 10}
+	for _, value := range values {
+		for _, c := range code128Patterns[value] {
+			modules = append(modules, int(c-'0'))
+		}
+	}
+	modules = append(modules, 10)
+	return modules, nil
+}
@@ -0,0 +1,244 @@
+package shipping
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// testFont builds a minimal TrueType font mapping each rune to a glyph ID;
+// glyph i is 500+10i units wide at 1000 units per em
+func testFont(t *testing.T, glyphs map[rune]uint16) []byte {
+	t.Helper()
+
+	numGlyphs := 1
+	for _, glyph := range glyphs {
+		if int(glyph) >= numGlyphs {
+			numGlyphs = int(glyph) + 1
+		}
+	}
+	u16 := func(b []byte, at int, v int) { binary.BigEndian.PutUint16(b[at:], uint16(v)) }
+
+	head := make([]byte, 54)
+	u16(head, 18, 1000)
+	u16(head, 38, -200)
+	u16(head, 40, 1000)
+	u16(head, 42, 800)
+
+	hhea := make([]byte, 36)
+	u16(hhea, 4, 800)
+	u16(hhea, 6, -200)
+	u16(hhea, 34, numGlyphs)
+
+	maxp := make([]byte, 6)
+	binary.BigEndian.PutUint32(maxp, 0x00005000)
+	u16(maxp, 4, numGlyphs)
+
+	hmtx := make([]byte, numGlyphs*4)
+	for i := 0; i < numGlyphs; i++ {
+		u16(hmtx, i*4, 500+10*i)
+	}
+
+	// cmap format 4 with one segment per rune and the closing 0xFFFF segment
+	runes := make([]int, 0, len(glyphs))
+	for r := range glyphs {
+		runes = append(runes, int(r))
+	}
+	sort.Ints(runes)
+	segCount := len(runes) + 1
+	sub := make([]byte, 16+segCount*8)
+	u16(sub, 0, 4)
+	u16(sub, 2, len(sub))
+	u16(sub, 6, segCount*2)
+	for i := 0; i < segCount; i++ {
+		code, delta := 0xFFFF, 1
+		if i < len(runes) {
+			code, delta = runes[i], int(glyphs[rune(runes[i])])-runes[i]
+		}
+		u16(sub, 14+i*2, code)
+		u16(sub, 16+segCount*2+i*2, code)
+		u16(sub, 16+segCount*4+i*2, delta&0xFFFF)
+	}
+	cmap := make([]byte, 12, 12+len(sub))
+	u16(cmap, 2, 1)
+	u16(cmap, 4, 3)
+	u16(cmap, 6, 1)
+	binary.BigEndian.PutUint32(cmap[8:], 12)
+	cmap = append(cmap, sub...)
+
+	name := make([]byte, 18)
+	u16(name, 2, 1)
+	u16(name, 4, 18)
+	u16(name, 6, 1)
+	u16(name, 12, 6)
+	u16(name, 14, len("Test Sans"))
+	name = append(name, "Test Sans"...)
+
+	tables := []struct {
+		tag  string
+		data []byte
+	}{{"cmap", cmap}, {"head", head}, {"hhea", hhea}, {"hmtx", hmtx}, {"maxp", maxp}, {"name", name}}
+
+	var font bytes.Buffer
+	header := make([]byte, 12+len(tables)*16)
+	binary.BigEndian.PutUint32(header, 0x00010000)
+	u16(header, 4, len(tables))
+	offset := len(header)
+	for i, table := range tables {
+		record := header[12+i*16:]
+		copy(record, table.tag)
+		binary.BigEndian.PutUint32(record[8:], uint32(offset))
+		binary.BigEndian.PutUint32(record[12:], uint32(len(table.data)))
+		offset += len(table.data)
+	}
+	font.Write(header)
+	for _, table := range tables {
+		font.Write(table.data)
+	}
+	return font.Bytes()
+}
+
+func testDocumentFonts(t *testing.T) *DocumentFonts {
+	t.Helper()
+	font, err := parseTrueTypeFont(testFont(t, map[rune]uint16{'র': 1, 'হ': 2, 'ি': 3, 'ম': 4}))
+	if err != nil {
+		t.Fatalf("parseTrueTypeFont() error = %v", err)
+	}
+	return &DocumentFonts{regular: font, bold: font}
+}
+
+func TestParseTrueTypeFont(t *testing.T) {
+	font, err := parseTrueTypeFont(testFont(t, map[rune]uint16{'র': 1, 'ম': 2}))
+	if err != nil {
+		t.Fatalf("parseTrueTypeFont() error = %v", err)
+	}
+
+	if font.name != "TestSans" {
+		t.Errorf("name = %q, want TestSans", font.name)
+	}
+	if font.glyphs['র'] != 1 || font.glyphs['ম'] != 2 {
+		t.Errorf("glyphs = %v", font.glyphs)
+	}
+	if _, ok := font.glyphs['ক']; ok {
+		t.Error("unmapped rune has a glyph")
+	}
+	if got := font.width(2); got != 520 {
+		t.Errorf("width(2) = %d, want 520", got)
+	}
+
+	if _, err := parseTrueTypeFont([]byte("OTTO not a truetype font")); err == nil {
+		t.Error("parseTrueTypeFont() accepted a CFF font")
+	}
+}
+
+func TestPDFTextEmbedsUnicodeFont(t *testing.T) {
+	doc := newPDFDocument(testDocumentFonts(t))
+	page := doc.AddPage(pageThermalWidth, pageThermalHeight)
+	page.Text(10, 10, 12, false, "রহিম (Road 5)")
+
+	content := page.content.String()
+	// ি is drawn before হ; the Latin run stays in Helvetica
+	if !strings.Contains(content, "/F3 12.00 Tf <0001000300020004> Tj /F1 12.00 Tf ( \\(Road 5\\)) Tj") {
+		t.Errorf("content = %q", content)
+	}
+
+	pdf, err := doc.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() error = %v", err)
+	}
+	for _, want := range []string{
+		"/Subtype /Type0 /BaseFont /TestSans /Encoding /Identity-H",
+		"/CIDToGIDMap /Identity /DW 1000 /W [1 [510] 2 [520] 3 [530] 4 [540]]",
+		"/FontFile2",
+		"<0003> <09BF>",
+		"/F3 5 0 R /F4 5 0 R",
+	} {
+		if !strings.Contains(string(pdf), want) {
+			t.Errorf("PDF is missing %q", want)
+		}
+	}
+}
+
+func TestPDFTextWithoutUnicodeFont(t *testing.T) {
+	tests := []struct {
+		name  string
+		fonts *DocumentFonts
+		text  string
+		want  string
+	}{
+		{name: "no fonts", text: "রহিম", want: "/F1 9.00 Tf (????) Tj"},
+		{name: "rune missing from font", fonts: testDocumentFonts(t), text: "ক", want: "/F1 9.00 Tf (?) Tj"},
+		{name: "Latin-1", text: "Café", want: "/F1 9.00 Tf (Caf\\351) Tj"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := newPDFDocument(tt.fonts)
+			page := doc.AddPage(pageA4Width, pageA4Height)
+			page.Text(0, 0, 9, false, tt.text)
+			if content := page.content.String(); !strings.Contains(content, tt.want) {
+				t.Errorf("content = %q, want %q", content, tt.want)
+			}
+
+			pdf, err := doc.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes() error = %v", err)
+			}
+			if strings.Contains(string(pdf), "/Type0") {
+				t.Error("unused Unicode font was embedded")
+			}
+		})
+	}
+}
+
+func TestReorderBengali(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"কি", "িক"},
+		{"রহিম", "রিহম"},
+		{"ক্ষে", "েক্ষ"},
+		{"বো", "েবা"},
+		{"মৌ", "েমৗ"},
+		{"ঢাকা", "ঢাকা"},
+		{"ি", "ি"}, // No consonant to move in front of
+	}
+
+	for _, tt := range tests {
+		if got := reorderBengali(tt.text); got != tt.want {
+			t.Errorf("reorderBengali(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRenderReportsBarcodeErrors(t *testing.T) {
+	label := ShippingLabel{ID: uuid.New(), OrderID: uuid.New(), Provider: "pathao", TrackingNumber: "DA123456"}
+
+	if _, err := renderShippingLabels([]ShippingLabel{label}, LabelLayoutA4, nil); err != nil {
+		t.Fatalf("renderShippingLabels() error = %v", err)
+	}
+
+	label.TrackingNumber = "ট্র্যাক"
+	_, err := renderShippingLabels([]ShippingLabel{label}, LabelLayoutThermal, nil)
+	if err == nil || !strings.Contains(err.Error(), "tracking number barcode") {
+		t.Errorf("renderShippingLabels() error = %v, want a barcode error", err)
+	}
+
+	_, err = renderManifest(&ShippingManifest{ManifestNumber: ""}, nil)
+	if err == nil || !strings.Contains(err.Error(), "barcode data is empty") {
+		t.Errorf("renderManifest() error = %v, want a barcode error", err)
+	}
+}
+
+func TestWrapTextCountsRunes(t *testing.T) {
+	lines := wrapText("বাড়ি ১২ রোড ৫ গুলশান ঢাকা", 12)
+	want := []string{"বাড়ি ১২ রোড", "৫ গুলশান", "ঢাকা"}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Errorf("wrapText() = %q, want %q", lines, want)
+	}
+}
@@ -0,0 +1,445 @@
+package shipping
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// Unicode fonts for documents.
+//
+// Helvetica only covers Latin-1, so names and addresses in Bangla need a
+// TrueType font embedded in the PDF. Text is split into runs: Latin-1 is
+// written in Helvetica as before, everything else in the Unicode font as a
+// Type0 font with Identity-H encoding (glyph IDs as character codes) and a
+// ToUnicode map so the text can still be searched and copied.
+//
+// There is no shaping engine: glyphs come straight from the font's cmap.
+// Bengali pre-base vowel signs are moved in front of their consonant so
+// words read correctly; conjuncts print as consonant + hasanta.
+
+// DocumentFonts are the TrueType files used for text outside Latin-1
+type DocumentFonts struct {
+	regular *trueTypeFont
+	bold    *trueTypeFont
+}
+
+// LoadDocumentFonts reads and parses the regular and bold TrueType fonts.
+// The bold path is optional; regular is used for bold text without it.
+func LoadDocumentFonts(regularPath, boldPath string) (*DocumentFonts, error) {
+	data, err := os.ReadFile(regularPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document font: %w", err)
+	}
+	regular, err := parseTrueTypeFont(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse document font %s: %w", regularPath, err)
+	}
+
+	fonts := &DocumentFonts{regular: regular, bold: regular}
+	if boldPath != "" {
+		data, err := os.ReadFile(boldPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bold document font: %w", err)
+		}
+		if fonts.bold, err = parseTrueTypeFont(data); err != nil {
+			return nil, fmt.Errorf("failed to parse document font %s: %w", boldPath, err)
+		}
+	}
+	return fonts, nil
+}
+
+func (f *DocumentFonts) font(bold bool) *trueTypeFont {
+	if f == nil {
+		return nil
+	}
+	if bold {
+		return f.bold
+	}
+	return f.regular
+}
+
+// trueTypeFont is the subset of a TrueType file needed to embed it
+type trueTypeFont struct {
+	name       string // PostScript name
+	data       []byte
+	unitsPerEm float64
+	ascent     int
+	descent    int
+	bbox       [4]int
+	glyphs     map[rune]uint16
+	advances   []uint16
+}
+
+var errNotTrueType = errors.New("not a TrueType font")
+
+func parseTrueTypeFont(data []byte) (*trueTypeFont, error) {
+	if len(data) < 12 {
+		return nil, errNotTrueType
+	}
+	if version := binary.BigEndian.Uint32(data); version != 0x00010000 && version != 0x74727565 {
+		return nil, errNotTrueType // CFF-based OpenType fonts can't be embedded as FontFile2
+	}
+
+	tables := make(map[string][]byte)
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < numTables; i++ {
+		record := 12 + i*16
+		if record+16 > len(data) {
+			return nil, errNotTrueType
+		}
+		offset := int(binary.BigEndian.Uint32(data[record+8:]))
+		length := int(binary.BigEndian.Uint32(data[record+12:]))
+		if offset < 0 || length < 0 || offset+length > len(data) {
+			return nil, fmt.Errorf("table %s is out of bounds", data[record:record+4])
+		}
+		tables[string(data[record:record+4])] = data[offset : offset+length]
+	}
+	for _, tag := range []string{"head", "hhea", "hmtx", "maxp", "cmap"} {
+		if _, ok := tables[tag]; !ok {
+			return nil, fmt.Errorf("missing %s table", tag)
+		}
+	}
+
+	font := &trueTypeFont{data: data, name: "UnicodeFont"}
+
+	head := tables["head"]
+	hhea := tables["hhea"]
+	maxp := tables["maxp"]
+	if len(head) < 54 || len(hhea) < 36 || len(maxp) < 6 {
+		return nil, errNotTrueType
+	}
+	font.unitsPerEm = float64(binary.BigEndian.Uint16(head[18:]))
+	if font.unitsPerEm == 0 {
+		return nil, errors.New("font has no units per em")
+	}
+	for i := range font.bbox {
+		font.bbox[i] = int(int16(binary.BigEndian.Uint16(head[36+i*2:])))
+	}
+	font.ascent = int(int16(binary.BigEndian.Uint16(hhea[4:])))
+	font.descent = int(int16(binary.BigEndian.Uint16(hhea[6:])))
+
+	numGlyphs := int(binary.BigEndian.Uint16(maxp[4:]))
+	numMetrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	hmtx := tables["hmtx"]
+	if numMetrics == 0 || numMetrics > numGlyphs || len(hmtx) < numMetrics*4 {
+		return nil, errors.New("invalid horizontal metrics")
+	}
+	font.advances = make([]uint16, numGlyphs)
+	for i := range font.advances {
+		if i < numMetrics {
+			font.advances[i] = binary.BigEndian.Uint16(hmtx[i*4:])
+		} else {
+			font.advances[i] = font.advances[numMetrics-1]
+		}
+	}
+
+	glyphs, err := parseCmap(tables["cmap"], numGlyphs)
+	if err != nil {
+		return nil, err
+	}
+	font.glyphs = glyphs
+
+	if name := postScriptName(tables["name"]); name != "" {
+		font.name = name
+	}
+	return font, nil
+}
+
+// parseCmap reads the Unicode character map, preferring the full-range
+// format 12 subtable over the BMP-only format 4 one
+func parseCmap(cmap []byte, numGlyphs int) (map[rune]uint16, error) {
+	if len(cmap) < 4 {
+		return nil, errors.New("invalid cmap table")
+	}
+
+	var format4, format12 []byte
+	numTables := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := 0; i < numTables; i++ {
+		record := 4 + i*8
+		if record+8 > len(cmap) {
+			break
+		}
+		platform := binary.BigEndian.Uint16(cmap[record:])
+		encoding := binary.BigEndian.Uint16(cmap[record+2:])
+		offset := int(binary.BigEndian.Uint32(cmap[record+4:]))
+		if offset+4 > len(cmap) {
+			continue
+		}
+		unicode := platform == 0 || (platform == 3 && (encoding == 1 || encoding == 10))
+		if !unicode {
+			continue
+		}
+		switch binary.BigEndian.Uint16(cmap[offset:]) {
+		case 4:
+			format4 = cmap[offset:]
+		case 12:
+			format12 = cmap[offset:]
+		}
+	}
+
+	glyphs := make(map[rune]uint16)
+	add := func(r rune, glyph int) {
+		if glyph > 0 && glyph < numGlyphs {
+			glyphs[r] = uint16(glyph)
+		}
+	}
+
+	switch {
+	case format12 != nil:
+		if len(format12) < 16 {
+			return nil, errors.New("invalid cmap format 12 subtable")
+		}
+		groups := int(binary.BigEndian.Uint32(format12[12:]))
+		if 16+groups*12 > len(format12) {
+			return nil, errors.New("invalid cmap format 12 subtable")
+		}
+		for i := 0; i < groups; i++ {
+			group := format12[16+i*12:]
+			start := binary.BigEndian.Uint32(group)
+			end := binary.BigEndian.Uint32(group[4:])
+			glyph := binary.BigEndian.Uint32(group[8:])
+			if end < start || end > 0x10FFFF {
+				continue
+			}
+			for c := start; c <= end; c++ {
+				add(rune(c), int(glyph+c-start))
+			}
+		}
+	case format4 != nil:
+		if len(format4) < 14 {
+			return nil, errors.New("invalid cmap format 4 subtable")
+		}
+		segCount := int(binary.BigEndian.Uint16(format4[6:])) / 2
+		ends := 14
+		starts := ends + segCount*2 + 2
+		deltas := starts + segCount*2
+		rangeOffsets := deltas + segCount*2
+		if rangeOffsets+segCount*2 > len(format4) {
+			return nil, errors.New("invalid cmap format 4 subtable")
+		}
+		for i := 0; i < segCount; i++ {
+			end := int(binary.BigEndian.Uint16(format4[ends+i*2:]))
+			start := int(binary.BigEndian.Uint16(format4[starts+i*2:]))
+			delta := int(binary.BigEndian.Uint16(format4[deltas+i*2:]))
+			rangeOffset := int(binary.BigEndian.Uint16(format4[rangeOffsets+i*2:]))
+			for c := start; c <= end && c != 0xFFFF; c++ {
+				if rangeOffset == 0 {
+					add(rune(c), (c+delta)&0xFFFF)
+					continue
+				}
+				index := rangeOffsets + i*2 + rangeOffset + (c-start)*2
+				if index+2 > len(format4) {
+					continue
+				}
+				if glyph := int(binary.BigEndian.Uint16(format4[index:])); glyph != 0 {
+					add(rune(c), (glyph+delta)&0xFFFF)
+				}
+			}
+		}
+	default:
+		return nil, errors.New("font has no Unicode cmap")
+	}
+	return glyphs, nil
+}
+
+// postScriptName reads name ID 6 from the name table, if present
+func postScriptName(table []byte) string {
+	if len(table) < 6 {
+		return ""
+	}
+	count := int(binary.BigEndian.Uint16(table[2:]))
+	storage := int(binary.BigEndian.Uint16(table[4:]))
+	for i := 0; i < count; i++ {
+		record := 6 + i*12
+		if record+12 > len(table) {
+			break
+		}
+		platform := binary.BigEndian.Uint16(table[record:])
+		nameID := binary.BigEndian.Uint16(table[record+6:])
+		length := int(binary.BigEndian.Uint16(table[record+8:]))
+		offset := storage + int(binary.BigEndian.Uint16(table[record+10:]))
+		if nameID != 6 || offset+length > len(table) {
+			continue
+		}
+		raw := table[offset : offset+length]
+		var name string
+		switch platform {
+		case 1:
+			name = string(raw)
+		case 0, 3:
+			units := make([]uint16, len(raw)/2)
+			for j := range units {
+				units[j] = binary.BigEndian.Uint16(raw[j*2:])
+			}
+			name = string(utf16.Decode(units))
+		default:
+			continue
+		}
+		// PDF names can't hold spaces or delimiters
+		name = strings.Map(func(r rune) rune {
+			if r <= ' ' || r > '~' || strings.ContainsRune("()<>[]{}/%#", r) {
+				return -1
+			}
+			return r
+		}, name)
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// width returns the advance of a glyph in 1/1000 text space units
+func (f *trueTypeFont) width(glyph uint16) int {
+	if int(glyph) >= len(f.advances) {
+		return 0
+	}
+	return int(float64(f.advances[glyph]) * 1000 / f.unitsPerEm)
+}
+
+// fontUsage records the glyphs a document draws with a font, for the width
+// array and the ToUnicode map
+type fontUsage struct {
+	font   *trueTypeFont
+	glyphs map[uint16][]rune
+}
+
+// pdfObjects renders the Type0 font, its CID font, descriptor, font file
+// and ToUnicode map as objects first, first+1, ... first+4
+func (u *fontUsage) pdfObjects(first int) ([]string, error) {
+	f := u.font
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(f.data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(u.glyphs))
+	for glyph := range u.glyphs {
+		ids = append(ids, int(glyph))
+	}
+	sort.Ints(ids)
+
+	var widths strings.Builder
+	for _, id := range ids {
+		fmt.Fprintf(&widths, "%d [%d] ", id, f.width(uint16(id)))
+	}
+
+	scale := func(v int) int { return int(float64(v) * 1000 / f.unitsPerEm) }
+
+	return []string{
+		fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+			f.name, first+1, first+4),
+		fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /CIDToGIDMap /Identity /DW 1000 /W [%s] >>",
+			f.name, first+2, strings.TrimSpace(widths.String())),
+		fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+			f.name, scale(f.bbox[0]), scale(f.bbox[1]), scale(f.bbox[2]), scale(f.bbox[3]),
+			scale(f.ascent), scale(f.descent), scale(f.ascent), first+3),
+		fmt.Sprintf("<< /Length %d /Length1 %d /Filter /FlateDecode >>\nstream\n%s\nendstream", compressed.Len(), len(f.data), compressed.String()),
+		streamObject(toUnicodeCMap(ids, u.glyphs)),
+	}, nil
+}
+
+func streamObject(content string) string {
+	return fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content)
+}
+
+// toUnicodeCMap maps glyph IDs back to the text they were drawn for
+func toUnicodeCMap(ids []int, glyphs map[uint16][]rune) string {
+	var b strings.Builder
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	b.WriteString("/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	b.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	b.WriteString("1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+
+	// At most 100 entries per block
+	for start := 0; start < len(ids); start += 100 {
+		end := start + 100
+		if end > len(ids) {
+			end = len(ids)
+		}
+		fmt.Fprintf(&b, "%d beginbfchar\n", end-start)
+		for _, id := range ids[start:end] {
+			fmt.Fprintf(&b, "<%04X> <", id)
+			for _, unit := range utf16.Encode(glyphs[uint16(id)]) {
+				fmt.Fprintf(&b, "%04X", unit)
+			}
+			b.WriteString(">\n")
+		}
+		b.WriteString("endbfchar\n")
+	}
+
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return b.String()
+}
+
+// Bengali vowel signs written before the consonant they follow in text
+const (
+	bengaliSignI  = 'ি'
+	bengaliSignE  = 'ে'
+	bengaliSignAI = 'ৈ'
+	bengaliSignO  = 'ো' // E + AA
+	bengaliSignAU = 'ৌ' // E + AU length mark
+	bengaliSignAA = 'া'
+	bengaliAUMark = 'ৗ'
+	bengaliNukta  = '়'
+	bengaliVirama = '্'
+)
+
+func isBengaliConsonant(r rune) bool {
+	return (r >= 'ক' && r <= 'হ') || (r >= 'ড়' && r <= 'য়') || r == 'ৎ' || r == 'ৰ' || r == 'ৱ'
+}
+
+// reorderBengali puts pre-base vowel signs in visual order, in front of the
+// consonant cluster they belong to, and splits the two-part vowels O and AU
+func reorderBengali(text string) string {
+	if !strings.ContainsAny(text, string([]rune{bengaliSignI, bengaliSignE, bengaliSignAI, bengaliSignO, bengaliSignAU})) {
+		return text
+	}
+
+	out := make([]rune, 0, len(text))
+	cluster := -1 // Start of the current consonant cluster in out
+	insert := func(at int, r rune) {
+		out = append(out, 0)
+		copy(out[at+1:], out[at:])
+		out[at] = r
+	}
+
+	for _, r := range text {
+		switch {
+		case isBengaliConsonant(r):
+			if cluster < 0 || len(out) == 0 || out[len(out)-1] != bengaliVirama {
+				cluster = len(out)
+			}
+			out = append(out, r)
+		case r == bengaliNukta || r == bengaliVirama:
+			out = append(out, r)
+		case cluster >= 0 && (r == bengaliSignI || r == bengaliSignE || r == bengaliSignAI):
+			insert(cluster, r)
+			cluster = -1
+		case cluster >= 0 && r == bengaliSignO:
+			insert(cluster, bengaliSignE)
+			out = append(out, bengaliSignAA)
+			cluster = -1
+		case cluster >= 0 && r == bengaliSignAU:
+			insert(cluster, bengaliSignE)
+			out = append(out, bengaliAUMark)
+			cluster = -1
+		default:
+			out = append(out, r)
+			cluster = -1
+		}
+	}
+	return string(out)
+}
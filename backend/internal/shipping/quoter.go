@@ -0,0 +1,514 @@
+package shipping
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"ecommerce-saas/internal/address"
+)
+
+// RateQuoter fetches live delivery charges from a courier integration.
+// Each enabled ShippingProviderConfig is matched to the quoter registered
+// for its provider; quoters must honour ctx cancellation.
+type RateQuoter interface {
+	Provider() ShippingProvider
+	Quote(ctx context.Context, config *ShippingProviderConfig, req RateQuoteRequest) ([]CarrierQuote, error)
+}
+
+// CarrierZoneResolver maps a destination geo code to a courier's own
+// city/zone/area IDs; address.Service implements it
+type CarrierZoneResolver interface {
+	ResolveCarrierZone(ctx context.Context, tenantID uuid.UUID, provider, geoCode string) (*address.CarrierZoneMapping, error)
+}
+
+// RateQuoteRequest is the carrier-agnostic input handed to every quoter
+type RateQuoteRequest struct {
+	DestinationCountry string  `json:"destination_country"`
+	DestinationState   string  `json:"destination_state"`
+	DestinationCity    string  `json:"destination_city"`
+	DestinationArea    string  `json:"destination_area_code"`
+	PostalCode         string  `json:"postal_code"`
+	Weight             float64 `json:"weight"`
+	Length             float64 `json:"length"`
+	Width              float64 `json:"width"`
+	Height             float64 `json:"height"`
+	OrderValue         float64 `json:"order_value"`
+	CODAmount          float64 `json:"cod_amount"`
+
+	// Courier IDs for the destination, when a zone mapping exists
+	CarrierZone *address.CarrierZoneMapping `json:"carrier_zone,omitempty"`
+}
+
+// CarrierQuote is a single priced service level returned by a carrier
+type CarrierQuote struct {
+	Provider      ShippingProvider `json:"provider"`
+	Method        ShippingMethod   `json:"method"`
+	ServiceName   string           `json:"service_name"`
+	Cost          float64          `json:"cost"`
+	Currency      string           `json:"currency"`
+	EstimatedDays int              `json:"estimated_days"`
+}
+
+// QuoteMethodID returns a stable identifier for a live quote so that a
+// method chosen at checkout can be resolved again on the next request.
+func QuoteMethodID(provider ShippingProvider, method ShippingMethod) uuid.UUID {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte("shipping-quote:"+string(provider)+":"+string(method)))
+}
+
+// HashRateRequest builds the cache key used for quotes of a cart/destination.
+// It covers everything a carrier prices on, so a cached quote set is always
+// the answer for the request it is served to.
+func HashRateRequest(req ShippingRateRequest) string {
+	lines := make([]string, 0, len(req.Items))
+	for _, item := range req.Items {
+		variantID := ""
+		if item.VariantID != nil {
+			variantID = item.VariantID.String()
+		}
+		lines = append(lines, fmt.Sprintf("%s:%s:%d", item.ProductID, variantID, item.Quantity))
+	}
+	sort.Strings(lines)
+
+	raw := fmt.Sprintf("%s|%s|%s|%s|%s|%.3f|%.1f|%.1f|%.1f|%.2f|%.2f|%s",
+		strings.ToUpper(req.DestinationCountry), strings.ToLower(req.DestinationState),
+		strings.ToLower(req.DestinationCity), strings.ToLower(req.DestinationAreaCode), req.PostalCode,
+		req.Weight, req.Length, req.Width, req.Height, req.OrderValue, req.CODAmount,
+		strings.Join(lines, ","))
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// Quote cache
+
+type cachedQuotes struct {
+	quotes    []CarrierQuote
+	expiresAt time.Time
+}
+
+// quoteCache keeps carrier quotes per tenant and cart hash for a short TTL
+type quoteCache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	entries map[string]cachedQuotes
+}
+
+func newQuoteCache(ttl time.Duration) *quoteCache {
+	return &quoteCache{
+		ttl:     ttl,
+		entries: make(map[string]cachedQuotes),
+	}
+}
+
+func (c *quoteCache) key(tenantID uuid.UUID, hash string) string {
+	return tenantID.String() + ":" + hash
+}
+
+func (c *quoteCache) get(tenantID uuid.UUID, hash string) ([]CarrierQuote, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[c.key(tenantID, hash)]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.quotes, true
+}
+
+func (c *quoteCache) set(tenantID uuid.UUID, hash string, quotes []CarrierQuote) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for k, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[c.key(tenantID, hash)] = cachedQuotes{quotes: quotes, expiresAt: now.Add(c.ttl)}
+}
+
+// Courier quoters
+
+// httpQuoter is the shared transport used by the Bangladesh courier quoters.
+// Requests go to the courier's production API, or its sandbox when the
+// provider config is in sandbox mode. Tenants cannot point them elsewhere,
+// since the request carries the courier credentials.
+type httpQuoter struct {
+	provider      ShippingProvider
+	baseURL       string
+	sandboxURL    string
+	client        *http.Client
+	buildRequest  func(ctx context.Context, baseURL string, config *ShippingProviderConfig, req RateQuoteRequest) (*http.Request, error)
+	parseResponse func(body []byte) (float64, error)
+}
+
+func (q *httpQuoter) Provider() ShippingProvider {
+	return q.provider
+}
+
+func (q *httpQuoter) Quote(ctx context.Context, config *ShippingProviderConfig, req RateQuoteRequest) ([]CarrierQuote, error) {
+	baseURL := q.baseURL
+	if config.SandboxMode && q.sandboxURL != "" {
+		baseURL = q.sandboxURL
+	}
+
+	httpReq, err := q.buildRequest(ctx, baseURL, config, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := q.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(resp.Body); err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s rate API error: status %d", q.provider, resp.StatusCode)
+	}
+
+	cost, err := q.parseResponse(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("%s rate API: %w", q.provider, err)
+	}
+
+	return []CarrierQuote{{
+		Provider:      q.provider,
+		Method:        MethodStandard,
+		ServiceName:   config.Name,
+		Cost:          cost,
+		Currency:      "BDT",
+		EstimatedDays: estimatedDaysFor(config, req),
+	}}, nil
+}
+
+// NewPathaoQuoter quotes through the Pathao merchant price-plan API
+func NewPathaoQuoter(client *http.Client) RateQuoter {
+	return &httpQuoter{
+		provider:   ProviderPathao,
+		baseURL:    "https://api-hermes.pathao.com",
+		sandboxURL: "https://courier-api-sandbox.pathao.com",
+		client:     client,
+		buildRequest: func(ctx context.Context, baseURL string, config *ShippingProviderConfig, req RateQuoteRequest) (*http.Request, error) {
+			payload := map[string]interface{}{
+				"store_id":       settingString(config.Settings, "store_id"),
+				"item_type":      2,  // parcel
+				"delivery_type":  48, // normal delivery
+				"item_weight":    maxFloat(req.Weight, 0.5),
+				"recipient_city": zoneOrSetting(req.CarrierZone, config, "city"),
+				"recipient_zone": zoneOrSetting(req.CarrierZone, config, "zone"),
+			}
+			return newJSONRequest(ctx, http.MethodPost, baseURL+"/aladdin/api/v1/merchant/price-plan", payload, "Bearer "+config.APIKey)
+		},
+		parseResponse: func(body []byte) (float64, error) {
+			var resp struct {
+				Data struct {
+					Price      float64 `json:"price"`
+					FinalPrice float64 `json:"final_price"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(body, &resp); err != nil {
+				return 0, err
+			}
+			if resp.Data.FinalPrice > 0 {
+				return resp.Data.FinalPrice, nil
+			}
+			return resp.Data.Price, nil
+		},
+	}
+}
+
+// NewRedXQuoter quotes through the RedX charge calculator API
+func NewRedXQuoter(client *http.Client) RateQuoter {
+	return &httpQuoter{
+		provider:   ProviderRedX,
+		baseURL:    "https://openapi.redx.com.bd/v1.0.0-beta",
+		sandboxURL: "https://sandbox.redx.com.bd/v1.0.0-beta",
+		client:     client,
+		buildRequest: func(ctx context.Context, baseURL string, config *ShippingProviderConfig, req RateQuoteRequest) (*http.Request, error) {
+			params := url.Values{}
+			params.Set("delivery_area_id", zoneOrSetting(req.CarrierZone, config, "area"))
+			params.Set("pickup_area_id", settingString(config.Settings, "pickup_area_id"))
+			params.Set("cash_collection_amount", fmt.Sprintf("%.2f", req.CODAmount))
+			params.Set("weight", fmt.Sprintf("%.0f", maxFloat(req.Weight, 0.5)*1000)) // grams
+			httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/charge/charge_calculator?"+params.Encode(), nil)
+			if err != nil {
+				return nil, err
+			}
+			httpReq.Header.Set("API-ACCESS-TOKEN", "Bearer "+config.APIKey)
+			return httpReq, nil
+		},
+		parseResponse: func(body []byte) (float64, error) {
+			var resp struct {
+				DeliveryCharge float64 `json:"deliveryCharge"`
+				CODCharge      float64 `json:"codCharge"`
+			}
+			if err := json.Unmarshal(body, &resp); err != nil {
+				return 0, err
+			}
+			return resp.DeliveryCharge + resp.CODCharge, nil
+		},
+	}
+}
+
+// NewSteadfastQuoter quotes through the Steadfast price calculator
+func NewSteadfastQuoter(client *http.Client) RateQuoter {
+	return &httpQuoter{
+		provider: ProviderSteadfast,
+		baseURL:  "https://portal.packzy.com/api/v1",
+		client:   client,
+		buildRequest: func(ctx context.Context, baseURL string, config *ShippingProviderConfig, req RateQuoteRequest) (*http.Request, error) {
+			payload := map[string]interface{}{
+				"recipient_city": req.DestinationCity,
+				"weight":         maxFloat(req.Weight, 0.5),
+				"cod_amount":     req.CODAmount,
+			}
+			httpReq, err := newJSONRequest(ctx, http.MethodPost, baseURL+"/price_calculator", payload, "")
+			if err != nil {
+				return nil, err
+			}
+			httpReq.Header.Set("Api-Key", config.APIKey)
+			httpReq.Header.Set("Secret-Key", config.APISecret)
+			return httpReq, nil
+		},
+		parseResponse: func(body []byte) (float64, error) {
+			var resp struct {
+				DeliveryCharge float64 `json:"delivery_charge"`
+			}
+			if err := json.Unmarshal(body, &resp); err != nil {
+				return 0, err
+			}
+			return resp.DeliveryCharge, nil
+		},
+	}
+}
+
+// NewPaperflyQuoter quotes through the Paperfly merchant charge API
+func NewPaperflyQuoter(client *http.Client) RateQuoter {
+	return &httpQuoter{
+		provider: ProviderPaperfly,
+		baseURL:  "https://api.paperfly.com.bd",
+		client:   client,
+		buildRequest: func(ctx context.Context, baseURL string, config *ShippingProviderConfig, req RateQuoteRequest) (*http.Request, error) {
+			payload := map[string]interface{}{
+				"merchant_code":     settingString(config.Settings, "merchant_code"),
+				"customer_thana":    req.DestinationCity,
+				"customer_district": req.DestinationState,
+				"weight":            maxFloat(req.Weight, 0.5),
+				"product_price":     req.OrderValue,
+			}
+			httpReq, err := newJSONRequest(ctx, http.MethodPost, baseURL+"/merchant/api/service/charge_calculator.php", payload, "")
+			if err != nil {
+				return nil, err
+			}
+			httpReq.SetBasicAuth(config.APIKey, config.APISecret)
+			return httpReq, nil
+		},
+		parseResponse: func(body []byte) (float64, error) {
+			var resp struct {
+				Response struct {
+					TotalCharge float64 `json:"total_charge"`
+				} `json:"response"`
+			}
+			if err := json.Unmarshal(body, &resp); err != nil {
+				return 0, err
+			}
+			return resp.Response.TotalCharge, nil
+		},
+	}
+}
+
+// FakeQuoter returns canned quotes and is meant for tests and local development
+type FakeQuoter struct {
+	Carrier ShippingProvider
+	Quotes  []CarrierQuote
+	Delay   time.Duration
+	Err     error
+}
+
+// NewFakeQuoter creates a fake quoter that always returns the given quotes
+func NewFakeQuoter(provider ShippingProvider, quotes ...CarrierQuote) *FakeQuoter {
+	return &FakeQuoter{Carrier: provider, Quotes: quotes}
+}
+
+func (f *FakeQuoter) Provider() ShippingProvider {
+	return f.Carrier
+}
+
+func (f *FakeQuoter) Quote(ctx context.Context, config *ShippingProviderConfig, req RateQuoteRequest) ([]CarrierQuote, error) {
+	if f.Delay > 0 {
+		select {
+		case <-time.After(f.Delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	quotes := make([]CarrierQuote, len(f.Quotes))
+	for i, quote := range f.Quotes {
+		if quote.Provider == "" {
+			quote.Provider = f.Carrier
+		}
+		quotes[i] = quote
+	}
+	return quotes, nil
+}
+
+// Markup
+
+// ApplyMarkup adjusts a carrier cost according to the merchant's rule
+func (r *ShippingMarkupRule) ApplyMarkup(cost, orderValue float64) float64 {
+	if r.FreeShippingMin > 0 && orderValue >= r.FreeShippingMin {
+		return 0
+	}
+
+	cost = cost*(1+r.MarkupPercent/100) + r.MarkupFlat
+	if cost < 0 {
+		return 0
+	}
+	return cost
+}
+
+// Matches checks if the rule covers the given carrier
+func (r *ShippingMarkupRule) Matches(provider ShippingProvider) bool {
+	return r.IsActive && (r.Provider == "" || r.Provider == provider)
+}
+
+// selectMarkupRule picks the carrier-specific rule over a tenant-wide one
+func selectMarkupRule(rules []ShippingMarkupRule, provider ShippingProvider) *ShippingMarkupRule {
+	var fallback *ShippingMarkupRule
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.Matches(provider) {
+			continue
+		}
+		if rule.Provider == provider {
+			return rule
+		}
+		if fallback == nil {
+			fallback = rule
+		}
+	}
+	return fallback
+}
+
+// applyMarkupRules adjusts a carrier's cost by the rule that covers it, if any
+func applyMarkupRules(rules []ShippingMarkupRule, provider ShippingProvider, cost, orderValue float64) float64 {
+	if rule := selectMarkupRule(rules, provider); rule != nil {
+		return rule.ApplyMarkup(cost, orderValue)
+	}
+	return cost
+}
+
+// rankRates orders rates by price, then delivery time, and numbers them
+func rankRates(rates []ShippingRateResponse) {
+	sort.SliceStable(rates, func(i, j int) bool {
+		if rates[i].Cost != rates[j].Cost {
+			return rates[i].Cost < rates[j].Cost
+		}
+		return rates[i].EstimatedDays < rates[j].EstimatedDays
+	})
+	for i := range rates {
+		rates[i].Rank = i + 1
+	}
+}
+
+// Helpers
+
+func newJSONRequest(ctx context.Context, method, endpoint string, payload interface{}, authorization string) (*http.Request, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	return req, nil
+}
+
+func settingString(settings map[string]interface{}, key string) string {
+	switch value := settings[key].(type) {
+	case string:
+		return value
+	case float64:
+		return fmt.Sprintf("%.0f", value)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
+
+// zoneOrSetting prefers the mapped courier ID for the destination and falls
+// back to the provider's static settings (recipient_city_id, delivery_area_id, ...)
+func zoneOrSetting(zone *address.CarrierZoneMapping, config *ShippingProviderConfig, kind string) string {
+	if zone != nil {
+		switch kind {
+		case "city":
+			if zone.CarrierCityID != "" {
+				return zone.CarrierCityID
+			}
+		case "zone":
+			if zone.CarrierZoneID != "" {
+				return zone.CarrierZoneID
+			}
+		case "area":
+			if zone.CarrierAreaID != "" {
+				return zone.CarrierAreaID
+			}
+		}
+	}
+
+	switch kind {
+	case "city":
+		return settingString(config.Settings, "recipient_city_id")
+	case "zone":
+		return settingString(config.Settings, "recipient_zone_id")
+	default:
+		return settingString(config.Settings, "delivery_area_id")
+	}
+}
+
+func estimatedDaysFor(config *ShippingProviderConfig, req RateQuoteRequest) int {
+	if days, ok := config.Settings["estimated_days"].(float64); ok && days > 0 {
+		return int(days)
+	}
+	if pickupCity := settingString(config.Settings, "pickup_city"); pickupCity != "" &&
+		strings.EqualFold(pickupCity, req.DestinationCity) {
+		return 1
+	}
+	return 3
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
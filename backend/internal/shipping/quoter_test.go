@@ -0,0 +1,139 @@
+package shipping
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var testTenantID = uuid.New()
+
+func testQuoteService(timeout time.Duration, quoters ...RateQuoter) *Service {
+	s := &Service{
+		quoters:      make(map[ShippingProvider]RateQuoter),
+		quoteTimeout: timeout,
+		quoteCache:   newQuoteCache(time.Minute),
+	}
+	for _, quoter := range quoters {
+		s.RegisterQuoter(quoter)
+	}
+	return s
+}
+
+func TestQuoteProviders(t *testing.T) {
+	errDown := errors.New("courier API is down")
+	providers := []ShippingProviderConfig{
+		{Provider: ProviderPathao, Name: "Pathao"},
+		{Provider: ProviderRedX, Name: "RedX"},
+		{Provider: ProviderPaperfly, Name: "Paperfly"}, // No quoter registered
+	}
+
+	tests := []struct {
+		name          string
+		redx          *FakeQuoter
+		wantProviders []ShippingProvider
+		wantErr       error
+	}{
+		{
+			name:          "all carriers answer",
+			redx:          NewFakeQuoter(ProviderRedX, CarrierQuote{Method: MethodStandard, Cost: 80}),
+			wantProviders: []ShippingProvider{ProviderPathao, ProviderRedX},
+		},
+		{
+			name:          "a failing carrier is left out",
+			redx:          &FakeQuoter{Carrier: ProviderRedX, Err: errDown},
+			wantProviders: []ShippingProvider{ProviderPathao},
+			wantErr:       errDown,
+		},
+		{
+			name:          "a slow carrier is cut off at the timeout",
+			redx:          &FakeQuoter{Carrier: ProviderRedX, Delay: time.Second, Quotes: []CarrierQuote{{Cost: 80}}},
+			wantProviders: []ShippingProvider{ProviderPathao},
+			wantErr:       context.DeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := testQuoteService(50*time.Millisecond,
+				NewFakeQuoter(ProviderPathao, CarrierQuote{Method: MethodStandard, Cost: 60}), tt.redx)
+
+			start := time.Now()
+			quotes, err := s.quoteProviders(context.Background(), testTenantID, providers, ShippingRateRequest{})
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("quoteProviders() took %v, want it bounded by the timeout", elapsed)
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("quoteProviders() error = %v, want %v", err, tt.wantErr)
+			}
+			got := make(map[ShippingProvider]bool)
+			for _, quote := range quotes {
+				got[quote.Provider] = true
+			}
+			if len(got) != len(tt.wantProviders) {
+				t.Fatalf("quoted %v, want %v", got, tt.wantProviders)
+			}
+			for _, provider := range tt.wantProviders {
+				if !got[provider] {
+					t.Errorf("missing quote from %s", provider)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeShippingRates(t *testing.T) {
+	quotes := []CarrierQuote{
+		{Provider: ProviderPathao, Method: MethodStandard, ServiceName: "Pathao", Cost: 70, Currency: "BDT", EstimatedDays: 2},
+	}
+	tableRates := []ShippingRateResponse{
+		{Provider: ProviderPathao, Name: "Pathao table", Cost: 60, Source: "table"},
+		{Provider: ProviderRedX, Name: "RedX table", Cost: 80, Source: "table", EstimatedDays: 3},
+		{Provider: ProviderSteadfast, Name: "Free delivery", Cost: 0, IsFree: true, Source: "table"},
+	}
+	rules := []ShippingMarkupRule{{Name: "Handling", MarkupPercent: 10, IsActive: true}}
+
+	rates := mergeShippingRates(quotes, tableRates, rules, 500)
+
+	want := []struct {
+		provider ShippingProvider
+		cost     float64
+		source   string
+	}{
+		{ProviderSteadfast, 0, "table"}, // Free table rates stay free
+		{ProviderPathao, 77, "live"},    // The live quote replaces the table rate
+		{ProviderRedX, 88, "table"},     // Fallback gets the same markup
+	}
+	if len(rates) != len(want) {
+		t.Fatalf("got %d rates, want %d: %+v", len(rates), len(want), rates)
+	}
+	for i, w := range want {
+		rate := rates[i]
+		if rate.Provider != w.provider || rate.Source != w.source || !floatEqual(rate.Cost, w.cost) || rate.Rank != i+1 {
+			t.Errorf("rate %d = %s %s %.2f rank %d, want %s %s %.2f rank %d",
+				i, rate.Provider, rate.Source, rate.Cost, rate.Rank, w.provider, w.source, w.cost, i+1)
+		}
+	}
+	if rates[1].RateID != QuoteMethodID(ProviderPathao, MethodStandard) {
+		t.Error("live rate ID is not stable")
+	}
+}
+
+func TestMergeShippingRatesFreeShippingThreshold(t *testing.T) {
+	tableRates := []ShippingRateResponse{{Provider: ProviderRedX, Cost: 80, Source: "table"}}
+	rules := []ShippingMarkupRule{{Provider: ProviderRedX, FreeShippingMin: 1000, IsActive: true}}
+
+	rates := mergeShippingRates(nil, tableRates, rules, 1500)
+	if len(rates) != 1 || rates[0].Cost != 0 || !rates[0].IsFree {
+		t.Errorf("rates = %+v, want free RedX shipping", rates)
+	}
+}
+
+func floatEqual(a, b float64) bool {
+	diff := a - b
+	return diff < 0.001 && diff > -0.001
+}
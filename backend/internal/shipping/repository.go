@@ -1,6 +1,9 @@
 package shipping
 
 import (
+	"errors"
+	"time"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
@@ -165,6 +168,14 @@ func (r *Repository) GetShippingLabelsByOrder(tenantID, orderID uuid.UUID) ([]Sh
 	return labels, err
 }
 
+func (r *Repository) GetShippingLabelsByOrderNumber(tenantID uuid.UUID, orderNumber string) ([]ShippingLabel, error) {
+	var labels []ShippingLabel
+	err := r.db.Where("tenant_id = ? AND order_number = ? AND status <> ?", tenantID, orderNumber, "cancelled").
+		Order("created_at ASC").
+		Find(&labels).Error
+	return labels, err
+}
+
 func (r *Repository) GetShippingLabels(tenantID uuid.UUID, offset, limit int) ([]ShippingLabel, int64, error) {
 	var labels []ShippingLabel
 	var total int64
@@ -199,6 +210,16 @@ func (r *Repository) GetTrackingHistory(labelID uuid.UUID) ([]ShippingTracking,
 	return tracking, err
 }
 
+// GetShippingTrackingByEventID finds a carrier event already recorded for a label
+func (r *Repository) GetShippingTrackingByEventID(labelID uuid.UUID, eventID string) (*ShippingTracking, error) {
+	var tracking ShippingTracking
+	err := r.db.Where("label_id = ? AND event_id = ?", labelID, eventID).First(&tracking).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tracking, nil
+}
+
 func (r *Repository) GetLatestShippingTracking(labelID uuid.UUID) (*ShippingTracking, error) {
 	var tracking ShippingTracking
 	err := r.db.Where("label_id = ?", labelID).
@@ -260,6 +281,281 @@ func (r *Repository) GetActiveShippingProviders(tenantID uuid.UUID) ([]ShippingP
 	return providers, err
 }
 
+// Shipping Markup Rule Repository Methods
+
+func (r *Repository) CreateMarkupRule(rule *ShippingMarkupRule) (*ShippingMarkupRule, error) {
+	if err := r.db.Create(rule).Error; err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (r *Repository) UpdateMarkupRule(rule *ShippingMarkupRule) (*ShippingMarkupRule, error) {
+	if err := r.db.Save(rule).Error; err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (r *Repository) DeleteMarkupRule(tenantID, ruleID uuid.UUID) error {
+	return r.db.Where("tenant_id = ? AND id = ?", tenantID, ruleID).Delete(&ShippingMarkupRule{}).Error
+}
+
+func (r *Repository) GetMarkupRule(tenantID, ruleID uuid.UUID) (*ShippingMarkupRule, error) {
+	var rule ShippingMarkupRule
+	err := r.db.Where("tenant_id = ? AND id = ?", tenantID, ruleID).First(&rule).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *Repository) GetMarkupRules(tenantID uuid.UUID) ([]ShippingMarkupRule, error) {
+	var rules []ShippingMarkupRule
+	err := r.db.Where("tenant_id = ?", tenantID).
+		Order("provider ASC, created_at ASC").
+		Find(&rules).Error
+	return rules, err
+}
+
+func (r *Repository) GetActiveMarkupRules(tenantID uuid.UUID) ([]ShippingMarkupRule, error) {
+	var rules []ShippingMarkupRule
+	err := r.db.Where("tenant_id = ? AND is_active = ?", tenantID, true).
+		Order("created_at ASC").
+		Find(&rules).Error
+	return rules, err
+}
+
+// Fulfillment Repository Methods
+
+func (r *Repository) GetShippingLabelsByIDs(tenantID uuid.UUID, labelIDs []uuid.UUID) ([]ShippingLabel, error) {
+	var labels []ShippingLabel
+	err := r.db.Where("tenant_id = ? AND id IN ?", tenantID, labelIDs).
+		Order("provider ASC, created_at ASC").
+		Find(&labels).Error
+	return labels, err
+}
+
+func (r *Repository) GetManifestableLabels(tenantID uuid.UUID, provider ShippingProvider) ([]ShippingLabel, error) {
+	var labels []ShippingLabel
+	err := r.db.Where("tenant_id = ? AND provider = ? AND manifest_id IS NULL AND status IN ?",
+		tenantID, provider, []string{"created", "printed"}).
+		Order("created_at ASC").
+		Find(&labels).Error
+	return labels, err
+}
+
+func (r *Repository) MarkLabelsPrinted(tenantID uuid.UUID, labelIDs []uuid.UUID, printedAt time.Time) error {
+	return r.db.Model(&ShippingLabel{}).
+		Where("tenant_id = ? AND id IN ? AND status = ?", tenantID, labelIDs, "created").
+		Updates(map[string]interface{}{"status": "printed", "printed_at": printedAt}).Error
+}
+
+// CreateManifest saves the manifest and attaches its labels in one transaction
+func (r *Repository) CreateManifest(manifest *ShippingManifest, labelIDs []uuid.UUID) (*ShippingManifest, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(manifest).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&ShippingLabel{}).
+			Where("tenant_id = ? AND id IN ? AND manifest_id IS NULL", manifest.TenantID, labelIDs).
+			Update("manifest_id", manifest.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if int(result.RowsAffected) != len(labelIDs) {
+			return errors.New("some labels were added to another manifest")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// HandOverManifest closes the manifest and marks its labels shipped
+func (r *Repository) HandOverManifest(manifest *ShippingManifest) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(manifest).Error; err != nil {
+			return err
+		}
+		return tx.Model(&ShippingLabel{}).
+			Where("tenant_id = ? AND manifest_id = ? AND status IN ?", manifest.TenantID, manifest.ID, []string{"created", "printed"}).
+			Update("status", "shipped").Error
+	})
+}
+
+func (r *Repository) GetManifest(tenantID, manifestID uuid.UUID) (*ShippingManifest, error) {
+	var manifest ShippingManifest
+	err := r.db.Preload("Labels", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC")
+	}).Where("tenant_id = ? AND id = ?", tenantID, manifestID).First(&manifest).Error
+	if err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+func (r *Repository) GetManifests(tenantID uuid.UUID, provider ShippingProvider, offset, limit int) ([]ShippingManifest, int64, error) {
+	var manifests []ShippingManifest
+	var total int64
+
+	query := r.db.Model(&ShippingManifest{}).Where("tenant_id = ?", tenantID)
+	if provider != "" {
+		query = query.Where("provider = ?", provider)
+	}
+	query.Count(&total)
+
+	err := query.Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&manifests).Error
+	return manifests, total, err
+}
+
+func (r *Repository) CountManifestsForDay(tenantID uuid.UUID, day time.Time) (int64, error) {
+	var count int64
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	err := r.db.Model(&ShippingManifest{}).Unscoped().
+		Where("tenant_id = ? AND created_at >= ? AND created_at < ?", tenantID, start, start.AddDate(0, 0, 1)).
+		Count(&count).Error
+	return count, err
+}
+
+// RTO Repository Methods
+
+func (r *Repository) CreateRTORecord(record *RTORecord) (*RTORecord, error) {
+	if err := r.db.Create(record).Error; err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (r *Repository) UpdateRTORecord(record *RTORecord) (*RTORecord, error) {
+	if err := r.db.Save(record).Error; err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (r *Repository) GetRTORecord(tenantID, recordID uuid.UUID) (*RTORecord, error) {
+	var record RTORecord
+	err := r.db.Where("tenant_id = ? AND id = ?", tenantID, recordID).First(&record).Error
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *Repository) GetRTORecordByLabel(labelID uuid.UUID) (*RTORecord, error) {
+	var record RTORecord
+	err := r.db.Where("label_id = ?", labelID).First(&record).Error
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *Repository) GetRTORecords(tenantID uuid.UUID, status string, provider ShippingProvider, offset, limit int) ([]RTORecord, int64, error) {
+	var records []RTORecord
+	var total int64
+
+	query := r.db.Model(&RTORecord{}).Where("tenant_id = ?", tenantID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if provider != "" {
+		query = query.Where("provider = ?", provider)
+	}
+	query.Count(&total)
+
+	err := query.Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&records).Error
+	return records, total, err
+}
+
+// GetRTOReport aggregates shipments created in the period by carrier or area
+func (r *Repository) GetRTOReport(tenantID uuid.UUID, groupBy string, from, to time.Time) ([]RTOReportRow, error) {
+	key := "l.provider"
+	if groupBy == "area" {
+		key = "COALESCE(NULLIF(l.receiver_area, ''), NULLIF(l.receiver_city, ''), 'unknown')"
+	}
+
+	var rows []RTOReportRow
+	err := r.db.Table("shipping_labels AS l").
+		Select(key+" AS key, "+
+			"COUNT(l.id) AS shipments, "+
+			"SUM(CASE WHEN l.status = 'delivered' THEN 1 ELSE 0 END) AS delivered, "+
+			"COUNT(rto.id) AS rto_count, "+
+			"COALESCE(SUM(l.failed_attempts), 0) AS failed_attempts, "+
+			"COALESCE(SUM(rto.cod_amount), 0) AS rto_value, "+
+			"COALESCE(SUM(rto.return_fee), 0) AS return_fees").
+		Joins("LEFT JOIN rto_records AS rto ON rto.label_id = l.id AND rto.deleted_at IS NULL").
+		Where("l.tenant_id = ? AND l.deleted_at IS NULL AND l.status <> ?", tenantID, "cancelled").
+		Where("l.created_at >= ? AND l.created_at < ?", from, to).
+		Group(key).
+		Order("rto_count DESC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// GetCustomerRTOReport aggregates RTOs raised in the period by customer phone
+func (r *Repository) GetCustomerRTOReport(tenantID uuid.UUID, from, to time.Time) ([]RTOReportRow, error) {
+	var rows []RTOReportRow
+	err := r.db.Model(&RTORecord{}).
+		Select("customer_phone AS key, "+
+			"COUNT(id) AS rto_count, "+
+			"COALESCE(SUM(failed_attempts), 0) AS failed_attempts, "+
+			"COALESCE(SUM(cod_amount), 0) AS rto_value, "+
+			"COALESCE(SUM(return_fee), 0) AS return_fees").
+		Where("tenant_id = ? AND customer_phone <> ''", tenantID).
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Group("customer_phone").
+		Order("rto_count DESC").
+		Limit(100).
+		Scan(&rows).Error
+	return rows, err
+}
+
+// Customer Risk Repository Methods
+
+func (r *Repository) GetRiskProfile(tenantID uuid.UUID, phone string) (*CustomerRiskProfile, error) {
+	var profile CustomerRiskProfile
+	err := r.db.Where("tenant_id = ? AND phone = ?", tenantID, phone).First(&profile).Error
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func (r *Repository) SaveRiskProfile(profile *CustomerRiskProfile) (*CustomerRiskProfile, error) {
+	if err := r.db.Save(profile).Error; err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+func (r *Repository) GetRiskProfiles(tenantID uuid.UUID, flaggedOnly bool, offset, limit int) ([]CustomerRiskProfile, int64, error) {
+	var profiles []CustomerRiskProfile
+	var total int64
+
+	query := r.db.Model(&CustomerRiskProfile{}).Where("tenant_id = ? AND rto_count > 0", tenantID)
+	if flaggedOnly {
+		query = query.Where("is_flagged = ?", true)
+	}
+	query.Count(&total)
+
+	err := query.Order("rto_count DESC, refused_count DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&profiles).Error
+	return profiles, total, err
+}
+
 // Statistics Repository Methods
 
 func (r *Repository) GetShippingStats(tenantID uuid.UUID) (*ShippingStats, error) {
@@ -0,0 +1,485 @@
+package shipping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Default number of failed delivery attempts before a parcel is sent back;
+// override per carrier with the "max_delivery_attempts" provider setting
+const defaultMaxDeliveryAttempts = 3
+
+// StockRestorer puts returned items back into inventory, into the variant's
+// stock when variantID is set
+type StockRestorer interface {
+	RestoreVariantStock(tenantID, productID uuid.UUID, variantID *uuid.UUID, quantity int) error
+}
+
+// RTOAccounting books the money side of COD shipments: the receivable from the
+// courier when the parcel ships, and its reversal and the return fee when it
+// comes back
+type RTOAccounting interface {
+	BookCODReceivable(ctx context.Context, tenantID, orderID uuid.UUID, reference string, amount float64) error
+	ReverseCODReceivable(ctx context.Context, tenantID, orderID uuid.UUID, reference string, amount float64) error
+	RecordReturnFee(ctx context.Context, tenantID, orderID uuid.UUID, reference string, amount float64) error
+}
+
+// InitiateRTORequest raises a return to origin by hand, e.g. after the
+// customer cancels on the phone with the rider
+type InitiateRTORequest struct {
+	LabelID string    `json:"label_id" binding:"required"`
+	Reason  RTOReason `json:"reason" binding:"required,oneof=refused unreachable address_issue failed_attempts other"`
+	Notes   string    `json:"notes"`
+}
+
+// ReceiveRTORequest records the parcel arriving back at the warehouse
+type ReceiveRTORequest struct {
+	Condition string `json:"condition" binding:"omitempty,oneof=resellable damaged"`
+	Notes     string `json:"notes"`
+}
+
+// RTOReportRow is one carrier, area or customer in the RTO report
+type RTOReportRow struct {
+	Key            string  `json:"key"`
+	Shipments      int     `json:"shipments"`
+	Delivered      int     `json:"delivered"`
+	RTOCount       int     `json:"rto_count"`
+	FailedAttempts int     `json:"failed_attempts"`
+	RTOValue       float64 `json:"rto_value"`
+	ReturnFees     float64 `json:"return_fees"`
+	RTORate        float64 `json:"rto_rate" gorm:"-"`
+	RiskLevel      string  `json:"risk_level,omitempty" gorm:"-"`
+}
+
+type RTOReport struct {
+	GroupBy string         `json:"group_by"`
+	From    time.Time      `json:"from"`
+	To      time.Time      `json:"to"`
+	Rows    []RTOReportRow `json:"rows"`
+	Totals  RTOReportRow   `json:"totals"`
+}
+
+// SetStockRestorer enables automatic restock when an RTO parcel is received
+func (s *Service) SetStockRestorer(stockRestorer StockRestorer) {
+	s.stockRestorer = stockRestorer
+}
+
+// SetRTOAccounting enables COD receivable, COD reversal and return fee bookings
+func (s *Service) SetRTOAccounting(accounting RTOAccounting) {
+	s.rtoAccounting = accounting
+}
+
+// InitiateRTO starts a return to origin for a shipment
+func (s *Service) InitiateRTO(tenantID uuid.UUID, req InitiateRTORequest) (*RTORecord, error) {
+	labelID, err := uuid.Parse(req.LabelID)
+	if err != nil {
+		return nil, errors.New("invalid label ID")
+	}
+
+	label, err := s.repository.GetShippingLabel(tenantID, labelID)
+	if err != nil {
+		return nil, err
+	}
+	if label.Status == "cancelled" || label.Status == "delivered" {
+		return nil, fmt.Errorf("cannot return a %s shipment", label.Status)
+	}
+
+	return s.initiateRTO(label, req.Reason, RTOStatusInitiated, req.Notes)
+}
+
+// ReceiveRTO processes a returned parcel: restock, COD reversal, return fee and
+// order status. Each step is stamped on the record, so calling it again after
+// a failure only retries the steps that did not complete.
+func (s *Service) ReceiveRTO(ctx context.Context, tenantID uuid.UUID, recordID string, req ReceiveRTORequest) (*RTORecord, error) {
+	id, err := uuid.Parse(recordID)
+	if err != nil {
+		return nil, errors.New("invalid RTO ID")
+	}
+
+	record, err := s.repository.GetRTORecord(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if record.ReceivedAt == nil {
+		record.ReceivedAt = &now
+		record.Status = RTOStatusReceived
+		record.Condition = req.Condition
+		if record.Condition == "" {
+			record.Condition = "resellable"
+		}
+	}
+	if req.Notes != "" {
+		record.Notes = strings.TrimSpace(record.Notes + "\n" + req.Notes)
+	}
+
+	label, err := s.repository.GetShippingLabel(tenantID, record.LabelID)
+	if err != nil {
+		return nil, err
+	}
+
+	var failures []string
+
+	if record.RestockedAt == nil && record.Condition == "resellable" && s.stockRestorer != nil {
+		if err := s.restockItems(record, label.Items); err != nil {
+			failures = append(failures, err.Error())
+		} else {
+			record.RestockedAt = &now
+		}
+	}
+
+	if s.rtoAccounting != nil {
+		reference := record.TrackingNumber
+		// Only a receivable that was booked when the parcel shipped is reversed
+		if record.CODReversedAt == nil && label.CODBookedAt != nil {
+			if err := s.rtoAccounting.ReverseCODReceivable(ctx, tenantID, record.OrderID, reference, record.CODAmount); err != nil {
+				failures = append(failures, fmt.Sprintf("COD reversal: %v", err))
+			} else {
+				record.CODReversedAt = &now
+			}
+		}
+		if record.FeeChargedAt == nil {
+			if err := s.rtoAccounting.RecordReturnFee(ctx, tenantID, record.OrderID, reference, record.ReturnFee); err != nil {
+				failures = append(failures, fmt.Sprintf("return fee: %v", err))
+			} else {
+				record.FeeChargedAt = &now
+			}
+		}
+	}
+
+	record.ProcessingError = strings.Join(failures, "; ")
+	if _, err := s.repository.UpdateRTORecord(record); err != nil {
+		return nil, err
+	}
+
+	if label.Status != "returned" {
+		label.Status = "returned"
+		label.TrackingStatus = StatusReturned
+		if _, err := s.repository.UpdateShippingLabel(label); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.orderUpdater != nil {
+		if err := s.orderUpdater.ApplyShipmentStatus(ctx, tenantID, record.OrderID, string(StatusReturned), record.TrackingNumber, now); err != nil {
+			fmt.Printf("Failed to update order %s for RTO %s: %v\n", record.OrderID, record.TrackingNumber, err)
+		}
+	}
+
+	return record, nil
+}
+
+func (s *Service) GetRTORecords(tenantID uuid.UUID, status string, provider ShippingProvider, offset, limit int) ([]RTORecord, int64, error) {
+	return s.repository.GetRTORecords(tenantID, status, provider, offset, limit)
+}
+
+// GetRTOReport reports RTO rates by carrier, area or customer for shipments
+// created in [from, to)
+func (s *Service) GetRTOReport(tenantID uuid.UUID, groupBy string, from, to time.Time) (*RTOReport, error) {
+	var rows []RTOReportRow
+	var err error
+
+	switch groupBy {
+	case "", "carrier":
+		groupBy = "carrier"
+		rows, err = s.repository.GetRTOReport(tenantID, "provider", from, to)
+	case "area":
+		rows, err = s.repository.GetRTOReport(tenantID, "area", from, to)
+	case "customer":
+		rows, err = s.getCustomerRTOReport(tenantID, from, to)
+	default:
+		return nil, errors.New("group_by must be carrier, area or customer")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RTOReport{GroupBy: groupBy, From: from, To: to, Rows: rows, Totals: RTOReportRow{Key: "total"}}
+	for i := range report.Rows {
+		row := &report.Rows[i]
+		row.RTORate = rtoRate(row.Delivered, row.RTOCount)
+
+		report.Totals.Shipments += row.Shipments
+		report.Totals.Delivered += row.Delivered
+		report.Totals.RTOCount += row.RTOCount
+		report.Totals.FailedAttempts += row.FailedAttempts
+		report.Totals.RTOValue += row.RTOValue
+		report.Totals.ReturnFees += row.ReturnFees
+	}
+	report.Totals.RTORate = rtoRate(report.Totals.Delivered, report.Totals.RTOCount)
+
+	return report, nil
+}
+
+// getCustomerRTOReport combines RTOs in the period with each customer's
+// lifetime delivery history
+func (s *Service) getCustomerRTOReport(tenantID uuid.UUID, from, to time.Time) ([]RTOReportRow, error) {
+	rows, err := s.repository.GetCustomerRTOReport(tenantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range rows {
+		profile, err := s.repository.GetRiskProfile(tenantID, rows[i].Key)
+		if err != nil {
+			continue
+		}
+		rows[i].Delivered = profile.Delivered
+		rows[i].Shipments = profile.Delivered + profile.RTOCount
+		rows[i].RiskLevel = profile.RiskLevel
+	}
+	return rows, nil
+}
+
+// GetCustomerRisk returns the delivery risk for a phone number. Customers
+// without history are returned as low risk.
+func (s *Service) GetCustomerRisk(tenantID uuid.UUID, phone string) (*CustomerRiskProfile, error) {
+	normalized := normalizePhone(phone)
+	if normalized == "" {
+		return nil, errors.New("invalid phone number")
+	}
+
+	profile, err := s.repository.GetRiskProfile(tenantID, normalized)
+	if err != nil {
+		return &CustomerRiskProfile{TenantID: tenantID, Phone: normalized, RiskLevel: "low"}, nil
+	}
+	return profile, nil
+}
+
+func (s *Service) GetRiskProfiles(tenantID uuid.UUID, flaggedOnly bool, offset, limit int) ([]CustomerRiskProfile, int64, error) {
+	return s.repository.GetRiskProfiles(tenantID, flaggedOnly, offset, limit)
+}
+
+// handleDeliveryOutcome keeps failed-attempt counts, customer risk and RTOs in
+// step with carrier events. Called for every new event, advancing or not.
+func (s *Service) handleDeliveryOutcome(label *ShippingLabel, event *CarrierEvent, advanced bool) {
+	if label.Status == "cancelled" {
+		return
+	}
+
+	switch event.Status {
+	case StatusFailed:
+		s.updateRiskProfile(label.TenantID, label.ReceiverPhone, func(p *CustomerRiskProfile) {
+			p.FailedAttempts++
+		})
+		if maxAttempts := s.maxDeliveryAttempts(label); label.FailedAttempts >= maxAttempts {
+			reason := classifyRTOReason(label.DeliveryNotes, label.FailedAttempts, maxAttempts)
+			if _, err := s.initiateRTO(label, reason, RTOStatusInitiated, event.Description); err != nil {
+				fmt.Printf("Failed to initiate RTO for %s: %v\n", label.TrackingNumber, err)
+			}
+		}
+	case StatusReturned:
+		if !advanced {
+			return
+		}
+		reason := classifyRTOReason(event.Description+" "+label.DeliveryNotes, label.FailedAttempts, s.maxDeliveryAttempts(label))
+		if _, err := s.initiateRTO(label, reason, RTOStatusReturning, event.Description); err != nil {
+			fmt.Printf("Failed to record RTO for %s: %v\n", label.TrackingNumber, err)
+		}
+	case StatusDelivered:
+		if !advanced {
+			return
+		}
+		s.updateRiskProfile(label.TenantID, label.ReceiverPhone, func(p *CustomerRiskProfile) {
+			p.Delivered++
+		})
+	}
+}
+
+// initiateRTO creates the RTO record for a label, or moves an existing one
+// forward. The customer's risk profile is charged once per parcel.
+func (s *Service) initiateRTO(label *ShippingLabel, reason RTOReason, status, notes string) (*RTORecord, error) {
+	if existing, err := s.repository.GetRTORecordByLabel(label.ID); err == nil {
+		if existing.Status == RTOStatusInitiated && status == RTOStatusReturning {
+			existing.Status = RTOStatusReturning
+			existing.FailedAttempts = label.FailedAttempts
+			return s.repository.UpdateRTORecord(existing)
+		}
+		return existing, nil
+	}
+
+	record := &RTORecord{
+		ID:             uuid.New(),
+		TenantID:       label.TenantID,
+		LabelID:        label.ID,
+		OrderID:        label.OrderID,
+		OrderNumber:    label.OrderNumber,
+		Provider:       label.Provider,
+		TrackingNumber: label.TrackingNumber,
+		Status:         status,
+		Reason:         reason,
+		Notes:          notes,
+		FailedAttempts: label.FailedAttempts,
+		CustomerPhone:  normalizePhone(label.ReceiverPhone),
+		AreaKey:        label.ReceiverArea,
+		CODAmount:      label.CODAmount,
+		ReturnFee:      s.returnFee(label),
+		Currency:       label.Currency,
+	}
+	if record.AreaKey == "" {
+		record.AreaKey = label.ReceiverCity
+	}
+
+	if _, err := s.repository.CreateRTORecord(record); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	s.updateRiskProfile(label.TenantID, label.ReceiverPhone, func(p *CustomerRiskProfile) {
+		p.RTOCount++
+		p.RTOValue += label.CODAmount
+		if reason == RTOReasonRefused {
+			p.RefusedCount++
+		}
+		p.LastRTOAt = &now
+	})
+
+	return record, nil
+}
+
+// restockItems returns every item that can be matched to a product. Each item
+// is stamped on the record as soon as it is back in stock, so a retry after a
+// partial failure doesn't restock the others twice.
+func (s *Service) restockItems(record *RTORecord, items []PackageItem) error {
+	restocked := make(map[int]bool, len(record.RestockedItems))
+	for _, i := range record.RestockedItems {
+		restocked[i] = true
+	}
+
+	var failed []string
+	for i, item := range items {
+		if restocked[i] || item.ProductID == nil || item.Quantity <= 0 {
+			continue
+		}
+		if err := s.stockRestorer.RestoreVariantStock(record.TenantID, *item.ProductID, item.VariantID, item.Quantity); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", item.Name, err))
+			continue
+		}
+
+		record.RestockedItems = append(record.RestockedItems, i)
+		if _, err := s.repository.UpdateRTORecord(record); err != nil {
+			return fmt.Errorf("failed to record restock of %s: %w", item.Name, err)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("restock failed for %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// bookCODReceivable books what the courier owes for a COD parcel once it is
+// handed over. A failed booking is logged and leaves nothing to reverse on
+// return to origin.
+func (s *Service) bookCODReceivable(ctx context.Context, label *ShippingLabel) {
+	if s.rtoAccounting == nil || label.CODAmount <= 0 || label.CODBookedAt != nil {
+		return
+	}
+
+	if err := s.rtoAccounting.BookCODReceivable(ctx, label.TenantID, label.OrderID, label.TrackingNumber, label.CODAmount); err != nil {
+		fmt.Printf("Failed to book COD receivable for shipment %s: %v\n", label.TrackingNumber, err)
+		return
+	}
+
+	now := time.Now()
+	label.CODBookedAt = &now
+	if _, err := s.repository.UpdateShippingLabel(label); err != nil {
+		fmt.Printf("Failed to record COD booking for shipment %s: %v\n", label.TrackingNumber, err)
+	}
+}
+
+// updateRiskProfile applies change to the customer's profile and re-assesses it
+func (s *Service) updateRiskProfile(tenantID uuid.UUID, phone string, change func(*CustomerRiskProfile)) {
+	normalized := normalizePhone(phone)
+	if normalized == "" {
+		return
+	}
+
+	profile, err := s.repository.GetRiskProfile(tenantID, normalized)
+	if err != nil {
+		profile = &CustomerRiskProfile{ID: uuid.New(), TenantID: tenantID, Phone: normalized}
+	}
+
+	change(profile)
+	profile.Assess()
+
+	if _, err := s.repository.SaveRiskProfile(profile); err != nil {
+		fmt.Printf("Failed to update risk profile for %s: %v\n", normalized, err)
+	}
+}
+
+func (s *Service) maxDeliveryAttempts(label *ShippingLabel) int {
+	config, err := s.repository.GetShippingProvider(label.TenantID, string(label.Provider))
+	if err != nil {
+		return defaultMaxDeliveryAttempts
+	}
+	if attempts, err := strconv.Atoi(settingString(config.Settings, "max_delivery_attempts")); err == nil && attempts > 0 {
+		return attempts
+	}
+	return defaultMaxDeliveryAttempts
+}
+
+// returnFee uses the carrier's "rto_fee" (flat) or "rto_fee_percent" (of the
+// original charge) settings; most local couriers charge half the delivery fee
+func (s *Service) returnFee(label *ShippingLabel) float64 {
+	config, err := s.repository.GetShippingProvider(label.TenantID, string(label.Provider))
+	if err == nil {
+		if fee, err := strconv.ParseFloat(settingString(config.Settings, "rto_fee"), 64); err == nil && fee >= 0 {
+			return fee
+		}
+		if percent, err := strconv.ParseFloat(settingString(config.Settings, "rto_fee_percent"), 64); err == nil && percent >= 0 {
+			return label.Cost * percent / 100
+		}
+	}
+	return label.Cost * 0.5
+}
+
+// classifyRTOReason derives a reason from the rider's notes
+func classifyRTOReason(notes string, attempts, maxAttempts int) RTOReason {
+	text := strings.ToLower(notes)
+	switch {
+	case strings.Contains(text, "refus") || strings.Contains(text, "reject") || strings.Contains(text, "cancel"):
+		return RTOReasonRefused
+	case strings.Contains(text, "unreachable") || strings.Contains(text, "switched off") ||
+		strings.Contains(text, "not answering") || strings.Contains(text, "no answer") || strings.Contains(text, "phone"):
+		return RTOReasonUnreachable
+	case strings.Contains(text, "address"):
+		return RTOReasonAddressIssue
+	case attempts >= maxAttempts:
+		return RTOReasonFailedAttempts
+	default:
+		return RTOReasonOther
+	}
+}
+
+func rtoRate(delivered, rto int) float64 {
+	if delivered+rto == 0 {
+		return 0
+	}
+	return float64(rto) / float64(delivered+rto)
+}
+
+// normalizePhone keeps the last 10 digits so +8801711..., 8801711... and
+// 01711... map to the same customer
+func normalizePhone(phone string) string {
+	var digits strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	d := digits.String()
+	if len(d) < 6 {
+		return ""
+	}
+	if len(d) > 10 {
+		d = d[len(d)-10:]
+	}
+	return d
+}
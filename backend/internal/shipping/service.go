@@ -1,21 +1,84 @@
 package shipping
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/google/uuid"
+
+	"ecommerce-saas/internal/address"
+	"ecommerce-saas/internal/notification"
+)
+
+const (
+	defaultQuoteTimeout  = 5 * time.Second
+	defaultQuoteCacheTTL = 10 * time.Minute
 )
 
 type Service struct {
-	repository *Repository
+	repository   *Repository
+	quoters      map[ShippingProvider]RateQuoter
+	quoteTimeout time.Duration
+	quoteCache   *quoteCache
+	zoneResolver CarrierZoneResolver
+	// Unicode fonts for printed documents; nil prints Latin-1 only
+	documentFonts *DocumentFonts
+
+	orderUpdater        OrderFulfillmentUpdater
+	notificationService notification.Service
+	stockRestorer       StockRestorer
+	rtoAccounting       RTOAccounting
 }
 
 func NewService(repository *Repository) *Service {
-	return &Service{
-		repository: repository,
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	s := &Service{
+		repository:   repository,
+		quoters:      make(map[ShippingProvider]RateQuoter),
+		quoteTimeout: defaultQuoteTimeout,
+		quoteCache:   newQuoteCache(defaultQuoteCacheTTL),
 	}
+	s.RegisterQuoter(NewPathaoQuoter(client))
+	s.RegisterQuoter(NewRedXQuoter(client))
+	s.RegisterQuoter(NewSteadfastQuoter(client))
+	s.RegisterQuoter(NewPaperflyQuoter(client))
+
+	return s
+}
+
+// RegisterQuoter adds or replaces the live rate quoter for a provider
+func (s *Service) RegisterQuoter(quoter RateQuoter) {
+	s.quoters[quoter.Provider()] = quoter
+}
+
+// SetZoneResolver enables automatic courier zone lookup for quotes and labels
+func (s *Service) SetZoneResolver(resolver CarrierZoneResolver) {
+	s.zoneResolver = resolver
+}
+
+// SetOrderUpdater enables order fulfillment updates from carrier tracking events
+func (s *Service) SetOrderUpdater(updater OrderFulfillmentUpdater) {
+	s.orderUpdater = updater
+}
+
+// SetNotificationService enables customer SMS/email on key tracking events
+func (s *Service) SetNotificationService(notificationService notification.Service) {
+	s.notificationService = notificationService
+}
+
+// SetQuoteTimeout sets how long checkout waits for carrier quotes
+func (s *Service) SetQuoteTimeout(timeout time.Duration) {
+	s.quoteTimeout = timeout
+}
+
+// SetDocumentFonts sets the fonts used for Bangla and other non-Latin text
+// on labels, packing slips, pick lists and manifests
+func (s *Service) SetDocumentFonts(fonts *DocumentFonts) {
+	s.documentFonts = fonts
 }
 
 // Request/Response Models
@@ -64,28 +127,55 @@ type ShippingRateRequest struct {
 	DestinationCountry string  `json:"destination_country" binding:"required"`
 	DestinationState   string  `json:"destination_state"`
 	DestinationCity    string  `json:"destination_city"`
+	DestinationAreaCode string `json:"destination_area_code"` // Geo code from the address hierarchy
 	PostalCode         string  `json:"postal_code"`
 	Weight             float64 `json:"weight" binding:"required,min=0"`
 	Length             float64 `json:"length"`
 	Width              float64 `json:"width"`
 	Height             float64 `json:"height"`
 	OrderValue         float64 `json:"order_value" binding:"required,min=0"`
+	CODAmount          float64 `json:"cod_amount"`
+
+	// Cart lines the request was built from, when known. Set by the server
+	// only; they are part of the quote cache key.
+	Items []ShippingRateItem `json:"-"`
+}
+
+// ShippingRateItem is one cart line of a rate request
+type ShippingRateItem struct {
+	ProductID uuid.UUID
+	VariantID *uuid.UUID
+	Quantity  int
 }
 
 type ShippingRateResponse struct {
-	RateID        uuid.UUID        `json:"rate_id"`
-	Provider      ShippingProvider `json:"provider"`
-	Method        ShippingMethod   `json:"method"`
-	Name          string           `json:"name"`
-	Description   string           `json:"description"`
-	Cost          float64          `json:"cost"`
-	Currency      string           `json:"currency"`
-	EstimatedDays int              `json:"estimated_days"`
-	IsFree        bool             `json:"is_free"`
+	RateID            uuid.UUID        `json:"rate_id"`
+	Provider          ShippingProvider `json:"provider"`
+	Method            ShippingMethod   `json:"method"`
+	Name              string           `json:"name"`
+	Description       string           `json:"description"`
+	Cost              float64          `json:"cost"`
+	Currency          string           `json:"currency"`
+	EstimatedDays     int              `json:"estimated_days"`
+	EstimatedDelivery time.Time        `json:"estimated_delivery"`
+	IsFree            bool             `json:"is_free"`
+	Source            string           `json:"source"` // live, table
+	Rank              int              `json:"rank"`
+}
+
+type ShippingMarkupRuleRequest struct {
+	Provider        ShippingProvider `json:"provider"`
+	Name            string           `json:"name" binding:"required"`
+	MarkupPercent   float64          `json:"markup_percent"`
+	MarkupFlat      float64          `json:"markup_flat"`
+	FreeShippingMin float64          `json:"free_shipping_min" binding:"min=0"`
+	IsActive        *bool            `json:"is_active"` // Defaults to true on create
 }
 
 type CreateShippingLabelRequest struct {
 	OrderID         string           `json:"order_id" binding:"required"`
+	OrderNumber     string           `json:"order_number"`
+	CODAmount       float64          `json:"cod_amount" binding:"min=0"`
 	Provider        ShippingProvider `json:"provider" binding:"required"`
 	RateID          string           `json:"rate_id" binding:"required"`
 	SenderAddress   Address          `json:"sender_address" binding:"required"`
@@ -102,6 +192,7 @@ type Address struct {
 	State      string `json:"state"`
 	Country    string `json:"country" binding:"required"`
 	PostalCode string `json:"postal_code"`
+	AreaCode   string `json:"area_code"` // Geo code from the address hierarchy
 }
 
 type PackageDetails struct {
@@ -115,6 +206,10 @@ type PackageDetails struct {
 }
 
 type PackageItem struct {
+	ProductID   *uuid.UUID `json:"product_id,omitempty"` // Needed to restock on return to origin
+	VariantID   *uuid.UUID `json:"variant_id,omitempty"` // Restocked instead of the product when set
+	SKU         string  `json:"sku"`
+	BinLocation string  `json:"bin_location"`
 	Name        string  `json:"name"`
 	Quantity    int     `json:"quantity"`
 	Weight      float64 `json:"weight"`
@@ -319,15 +414,17 @@ func (s *Service) CalculateShippingRates(tenantID uuid.UUID, req ShippingRateReq
 				cost := rate.CalculateRate(req.Weight, req.Length, req.Width, req.Height, req.OrderValue)
 				
 				response := ShippingRateResponse{
-					RateID:        rate.ID,
-					Provider:      rate.Provider,
-					Method:        rate.Method,
-					Name:          rate.Name,
-					Description:   rate.Description,
-					Cost:          cost,
-					Currency:      "BDT",
-					EstimatedDays: rate.EstimatedDays,
-					IsFree:        cost == 0,
+					RateID:            rate.ID,
+					Provider:          rate.Provider,
+					Method:            rate.Method,
+					Name:              rate.Name,
+					Description:       rate.Description,
+					Cost:              cost,
+					Currency:          "BDT",
+					EstimatedDays:     rate.EstimatedDays,
+					EstimatedDelivery: rate.GetEstimatedDeliveryDate(),
+					IsFree:            cost == 0,
+					Source:            "table",
 				}
 				responses = append(responses, response)
 			}
@@ -337,6 +434,234 @@ func (s *Service) CalculateShippingRates(tenantID uuid.UUID, req ShippingRateReq
 	return responses, nil
 }
 
+// CalculateLiveShippingRates asks every enabled courier for a live quote in
+// parallel, falls back to the merchant's table rates for carriers that fail or
+// time out, applies markup rules and returns the options ranked by price.
+func (s *Service) CalculateLiveShippingRates(ctx context.Context, tenantID uuid.UUID, req ShippingRateRequest) ([]ShippingRateResponse, error) {
+	tableRates, tableErr := s.CalculateShippingRates(tenantID, req)
+
+	quotes, err := s.getCarrierQuotes(ctx, tenantID, req)
+	if err != nil && tableErr != nil {
+		return nil, tableErr
+	}
+
+	rules, err := s.repository.GetActiveMarkupRules(tenantID)
+	if err != nil {
+		rules = nil
+	}
+
+	return mergeShippingRates(quotes, tableRates, rules, req.OrderValue), nil
+}
+
+// mergeShippingRates prices the live quotes, fills in table rates for every
+// carrier without one, applies markup rules and ranks the result
+func mergeShippingRates(quotes []CarrierQuote, tableRates []ShippingRateResponse, rules []ShippingMarkupRule, orderValue float64) []ShippingRateResponse {
+	quoted := make(map[ShippingProvider]bool)
+	var responses []ShippingRateResponse
+	for _, quote := range quotes {
+		quoted[quote.Provider] = true
+
+		cost := applyMarkupRules(rules, quote.Provider, quote.Cost, orderValue)
+		responses = append(responses, ShippingRateResponse{
+			RateID:            QuoteMethodID(quote.Provider, quote.Method),
+			Provider:          quote.Provider,
+			Method:            quote.Method,
+			Name:              quote.ServiceName,
+			Cost:              cost,
+			Currency:          quote.Currency,
+			EstimatedDays:     quote.EstimatedDays,
+			EstimatedDelivery: time.Now().AddDate(0, 0, quote.EstimatedDays),
+			IsFree:            cost == 0,
+			Source:            "live",
+		})
+	}
+
+	// Table rates cover every carrier that has no live quote. Rates the
+	// merchant made free stay free.
+	for _, rate := range tableRates {
+		if quoted[rate.Provider] {
+			continue
+		}
+		if rate.Cost > 0 {
+			rate.Cost = applyMarkupRules(rules, rate.Provider, rate.Cost, orderValue)
+			rate.IsFree = rate.Cost == 0
+		}
+		responses = append(responses, rate)
+	}
+
+	rankRates(responses)
+	return responses
+}
+
+// ResolveShippingRate finds a previously offered rate by its ID so checkout
+// can price the method the customer picked
+func (s *Service) ResolveShippingRate(ctx context.Context, tenantID uuid.UUID, req ShippingRateRequest, rateID uuid.UUID) (*ShippingRateResponse, error) {
+	rates, err := s.CalculateLiveShippingRates(ctx, tenantID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range rates {
+		if rates[i].RateID == rateID {
+			return &rates[i], nil
+		}
+	}
+	return nil, errors.New("shipping method not available for this destination")
+}
+
+// getCarrierQuotes returns cached quotes for the cart or fetches them from
+// every active provider that has a registered quoter
+func (s *Service) getCarrierQuotes(ctx context.Context, tenantID uuid.UUID, req ShippingRateRequest) ([]CarrierQuote, error) {
+	hash := HashRateRequest(req)
+	if quotes, ok := s.quoteCache.get(tenantID, hash); ok {
+		return quotes, nil
+	}
+
+	providers, err := s.repository.GetActiveShippingProviders(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	quotes, lastErr := s.quoteProviders(ctx, tenantID, providers, req)
+	if len(quotes) == 0 {
+		if lastErr == nil {
+			lastErr = errors.New("no live carrier quotes available")
+		}
+		return nil, lastErr
+	}
+
+	// A carrier that failed or timed out may answer next time, so partial
+	// results are served but not cached
+	if lastErr != nil {
+		return quotes, nil
+	}
+
+	s.quoteCache.set(tenantID, hash, quotes)
+	return quotes, nil
+}
+
+// quoteProviders asks each provider's quoter in parallel and waits at most
+// the quote timeout. It returns the quotes that arrived and the last error,
+// so a non-nil error with quotes means some carriers are missing.
+func (s *Service) quoteProviders(ctx context.Context, tenantID uuid.UUID, providers []ShippingProviderConfig, req ShippingRateRequest) ([]CarrierQuote, error) {
+	quoteReq := RateQuoteRequest{
+		DestinationCountry: req.DestinationCountry,
+		DestinationState:   req.DestinationState,
+		DestinationCity:    req.DestinationCity,
+		DestinationArea:    req.DestinationAreaCode,
+		PostalCode:         req.PostalCode,
+		Weight:             req.Weight,
+		Length:             req.Length,
+		Width:              req.Width,
+		Height:             req.Height,
+		OrderValue:         req.OrderValue,
+		CODAmount:          req.CODAmount,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.quoteTimeout)
+	defer cancel()
+
+	type quoteResult struct {
+		quotes []CarrierQuote
+		err    error
+	}
+	results := make(chan quoteResult, len(providers))
+	pending := 0
+
+	for i := range providers {
+		config := providers[i]
+		quoter, ok := s.quoters[config.Provider]
+		if !ok {
+			continue
+		}
+		pending++
+		go func() {
+			providerReq := quoteReq
+			if zone, err := s.resolveCarrierZone(ctx, tenantID, config.Provider, req.DestinationAreaCode); err == nil {
+				providerReq.CarrierZone = zone
+			}
+			quotes, err := quoter.Quote(ctx, &config, providerReq)
+			results <- quoteResult{quotes: quotes, err: err}
+		}()
+	}
+
+	var quotes []CarrierQuote
+	var lastErr error
+	for ; pending > 0; pending-- {
+		select {
+		case result := <-results:
+			if result.err != nil {
+				lastErr = result.err
+				continue
+			}
+			quotes = append(quotes, result.quotes...)
+		case <-ctx.Done():
+			lastErr = ctx.Err()
+			pending = 1 // stop waiting; slow carriers fall back to table rates
+		}
+	}
+
+	return quotes, lastErr
+}
+
+// resolveCarrierZone looks up courier IDs for a geo code when a resolver is configured
+func (s *Service) resolveCarrierZone(ctx context.Context, tenantID uuid.UUID, provider ShippingProvider, areaCode string) (*address.CarrierZoneMapping, error) {
+	if s.zoneResolver == nil || areaCode == "" {
+		return nil, errors.New("carrier zone lookup not available")
+	}
+	return s.zoneResolver.ResolveCarrierZone(ctx, tenantID, string(provider), areaCode)
+}
+
+// Shipping Markup Rule Services
+
+func (s *Service) CreateMarkupRule(tenantID uuid.UUID, req ShippingMarkupRuleRequest) (*ShippingMarkupRule, error) {
+	rule := &ShippingMarkupRule{
+		TenantID:        tenantID,
+		Provider:        req.Provider,
+		Name:            req.Name,
+		MarkupPercent:   req.MarkupPercent,
+		MarkupFlat:      req.MarkupFlat,
+		FreeShippingMin: req.FreeShippingMin,
+		IsActive:        req.IsActive == nil || *req.IsActive,
+	}
+	return s.repository.CreateMarkupRule(rule)
+}
+
+func (s *Service) GetMarkupRules(tenantID uuid.UUID) ([]ShippingMarkupRule, error) {
+	return s.repository.GetMarkupRules(tenantID)
+}
+
+func (s *Service) UpdateMarkupRule(tenantID uuid.UUID, ruleID string, req ShippingMarkupRuleRequest) (*ShippingMarkupRule, error) {
+	id, err := uuid.Parse(ruleID)
+	if err != nil {
+		return nil, errors.New("invalid markup rule ID")
+	}
+
+	rule, err := s.repository.GetMarkupRule(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.Provider = req.Provider
+	rule.Name = req.Name
+	rule.MarkupPercent = req.MarkupPercent
+	rule.MarkupFlat = req.MarkupFlat
+	rule.FreeShippingMin = req.FreeShippingMin
+	if req.IsActive != nil {
+		rule.IsActive = *req.IsActive
+	}
+
+	return s.repository.UpdateMarkupRule(rule)
+}
+
+func (s *Service) DeleteMarkupRule(tenantID uuid.UUID, ruleID string) error {
+	id, err := uuid.Parse(ruleID)
+	if err != nil {
+		return errors.New("invalid markup rule ID")
+	}
+	return s.repository.DeleteMarkupRule(tenantID, id)
+}
+
 // Shipping Label Services
 
 func (s *Service) CreateShippingLabel(tenantID uuid.UUID, req CreateShippingLabelRequest) (*ShippingLabel, error) {
@@ -376,19 +701,47 @@ func (s *Service) CreateShippingLabel(tenantID uuid.UUID, req CreateShippingLabe
 		Cost:           cost,
 		Currency:       "BDT",
 		Status:         "created",
+		TrackingStatus:  StatusPending,
+		OrderNumber:     req.OrderNumber,
+		ReceiverName:    req.ReceiverAddress.Name,
+		ReceiverPhone:   req.ReceiverAddress.Phone,
+		ReceiverEmail:   req.ReceiverAddress.Email,
+		ReceiverAddress: req.ReceiverAddress.FullAddress(),
+		ReceiverCity:    req.ReceiverAddress.City,
+		ReceiverArea:    req.ReceiverAddress.AreaCode,
+		Weight:          req.PackageDetails.Weight,
+		CODAmount:       req.CODAmount,
+		Items:           req.PackageDetails.Items,
 		EstimatedDelivery: func() *time.Time {
 			t := time.Now().AddDate(0, 0, rate.EstimatedDays)
 			return &t
 		}(),
 	}
 
+	// Attach the courier's own city/zone/area IDs for the receiver
+	if req.ReceiverAddress.AreaCode != "" && s.zoneResolver != nil {
+		zone, err := s.resolveCarrierZone(context.Background(), tenantID, req.Provider, req.ReceiverAddress.AreaCode)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s zone for area %s: %w", req.Provider, req.ReceiverAddress.AreaCode, err)
+		}
+		label.CarrierCityID = zone.CarrierCityID
+		label.CarrierZoneID = zone.CarrierZoneID
+		label.CarrierAreaID = zone.CarrierAreaID
+	}
+
 	// Call provider API to create shipping label
 	err = s.createProviderLabel(label, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create label with provider: %v", err)
 	}
 
-	return s.repository.CreateShippingLabel(label)
+	created, err := s.repository.CreateShippingLabel(label)
+	if err != nil {
+		return nil, err
+	}
+
+	s.bookCODReceivable(context.Background(), created)
+	return created, nil
 }
 
 func (s *Service) GetShippingLabel(tenantID uuid.UUID, labelID string) (*ShippingLabel, error) {
@@ -644,281 +997,23 @@ func (s *Service) updateTrackingFromProvider(label *ShippingLabel) error {
 // Webhook Processing Methods
 
 func (s *Service) ProcessPathaoWebhook(payload map[string]interface{}) error {
-	// Extract tracking number and status from Pathao webhook
-	trackingNumber, ok := payload["tracking_number"].(string)
-	if !ok {
-		return errors.New("missing tracking_number in webhook payload")
-	}
-
-	status, ok := payload["status"].(string)
-	if !ok {
-		return errors.New("missing status in webhook payload")
-	}
-
-	// Find shipping label by tracking number
-	label, err := s.repository.GetShippingLabelByTrackingNumber(trackingNumber)
-	if err != nil {
-		return fmt.Errorf("failed to find shipping label: %w", err)
-	}
-
-	// Update tracking status based on Pathao status
-	var trackingStatus TrackingStatus
-	switch status {
-	case "picked_up":
-		trackingStatus = StatusPickedUp
-	case "in_transit":
-		trackingStatus = StatusInTransit
-	case "out_for_delivery":
-		trackingStatus = StatusOutForDelivery
-	case "delivered":
-		trackingStatus = StatusDelivered
-	case "failed":
-		trackingStatus = StatusFailed
-	case "returned":
-		trackingStatus = StatusReturned
-	default:
-		trackingStatus = StatusInTransit
-	}
-
-	// Create tracking update
-	tracking := &ShippingTracking{
-		ID:          uuid.New(),
-		LabelID:     label.ID,
-		Status:      string(trackingStatus),
-		Location:    getStringFromPayload(payload, "location"),
-		Description: getStringFromPayload(payload, "description"),
-		Timestamp:   time.Now(),
-		CreatedAt:   time.Now(),
-	}
-
-	_, err = s.repository.CreateShippingTracking(tracking)
-	return err
+	return s.ProcessCarrierWebhook(ProviderPathao, payload)
 }
 
 func (s *Service) ProcessRedXWebhook(payload map[string]interface{}) error {
-	// Extract tracking number and status from RedX webhook
-	trackingNumber, ok := payload["tracking_id"].(string)
-	if !ok {
-		return errors.New("missing tracking_id in webhook payload")
-	}
-
-	status, ok := payload["delivery_status"].(string)
-	if !ok {
-		return errors.New("missing delivery_status in webhook payload")
-	}
-
-	// Find shipping label by tracking number
-	label, err := s.repository.GetShippingLabelByTrackingNumber(trackingNumber)
-	if err != nil {
-		return fmt.Errorf("failed to find shipping label: %w", err)
-	}
-
-	// Update tracking status based on RedX status
-	var trackingStatus TrackingStatus
-	switch status {
-	case "PICKED_UP":
-		trackingStatus = StatusPickedUp
-	case "IN_TRANSIT":
-		trackingStatus = StatusInTransit
-	case "OUT_FOR_DELIVERY":
-		trackingStatus = StatusOutForDelivery
-	case "DELIVERED":
-		trackingStatus = StatusDelivered
-	case "DELIVERY_FAILED":
-		trackingStatus = StatusFailed
-	case "RETURNED":
-		trackingStatus = StatusReturned
-	default:
-		trackingStatus = StatusInTransit
-	}
-
-	// Create tracking update
-	tracking := &ShippingTracking{
-		ID:          uuid.New(),
-		LabelID:     label.ID,
-		Status:      string(trackingStatus),
-		Location:    getStringFromPayload(payload, "current_location"),
-		Description: getStringFromPayload(payload, "remarks"),
-		Timestamp:   time.Now(),
-		CreatedAt:   time.Now(),
-	}
-
-	_, err = s.repository.CreateShippingTracking(tracking)
-	return err
+	return s.ProcessCarrierWebhook(ProviderRedX, payload)
 }
 
 func (s *Service) ProcessPaperflyWebhook(payload map[string]interface{}) error {
-	// Extract tracking number and status from Paperfly webhook
-	trackingNumber, ok := payload["consignment_id"].(string)
-	if !ok {
-		return errors.New("missing consignment_id in webhook payload")
-	}
-
-	status, ok := payload["status"].(string)
-	if !ok {
-		return errors.New("missing status in webhook payload")
-	}
-
-	// Find shipping label by tracking number
-	label, err := s.repository.GetShippingLabelByTrackingNumber(trackingNumber)
-	if err != nil {
-		return fmt.Errorf("failed to find shipping label: %w", err)
-	}
-
-	// Update tracking status based on Paperfly status
-	var trackingStatus TrackingStatus
-	switch status {
-	case "picked":
-		trackingStatus = StatusPickedUp
-	case "in_transit":
-		trackingStatus = StatusInTransit
-	case "out_for_delivery":
-		trackingStatus = StatusOutForDelivery
-	case "delivered":
-		trackingStatus = StatusDelivered
-	case "failed":
-		trackingStatus = StatusFailed
-	case "returned":
-		trackingStatus = StatusReturned
-	default:
-		trackingStatus = StatusInTransit
-	}
-
-	// Create tracking update
-	tracking := &ShippingTracking{
-		ID:          uuid.New(),
-		LabelID:     label.ID,
-		Status:      string(trackingStatus),
-		Location:    getStringFromPayload(payload, "location"),
-		Description: getStringFromPayload(payload, "note"),
-		Timestamp:   time.Now(),
-		CreatedAt:   time.Now(),
-	}
-
-	_, err = s.repository.CreateShippingTracking(tracking)
-	return err
+	return s.ProcessCarrierWebhook(ProviderPaperfly, payload)
 }
 
 func (s *Service) ProcessDHLWebhook(payload map[string]interface{}) error {
-	// Extract tracking number and status from DHL webhook
-	trackingNumber, ok := payload["trackingNumber"].(string)
-	if !ok {
-		return errors.New("missing trackingNumber in webhook payload")
-	}
-
-	// DHL sends events array
-	events, ok := payload["events"].([]interface{})
-	if !ok || len(events) == 0 {
-		return errors.New("missing events in webhook payload")
-	}
-
-	// Get the latest event
-	latestEvent, ok := events[0].(map[string]interface{})
-	if !ok {
-		return errors.New("invalid event format in webhook payload")
-	}
-
-	status, ok := latestEvent["status"].(string)
-	if !ok {
-		return errors.New("missing status in event")
-	}
-
-	// Find shipping label by tracking number
-	label, err := s.repository.GetShippingLabelByTrackingNumber(trackingNumber)
-	if err != nil {
-		return fmt.Errorf("failed to find shipping label: %w", err)
-	}
-
-	// Update tracking status based on DHL status
-	var trackingStatus TrackingStatus
-	switch status {
-	case "transit":
-		trackingStatus = StatusInTransit
-	case "delivered":
-		trackingStatus = StatusDelivered
-	case "failure":
-		trackingStatus = StatusFailed
-	case "unknown":
-		trackingStatus = StatusPending
-	default:
-		trackingStatus = StatusInTransit
-	}
-
-	// Create tracking update
-	tracking := &ShippingTracking{
-		ID:          uuid.New(),
-		LabelID:     label.ID,
-		Status:      string(trackingStatus),
-		Location:    getStringFromPayload(latestEvent, "location"),
-		Description: getStringFromPayload(latestEvent, "description"),
-		Timestamp:   time.Now(),
-		CreatedAt:   time.Now(),
-	}
-
-	_, err = s.repository.CreateShippingTracking(tracking)
-	return err
+	return s.ProcessCarrierWebhook(ProviderDHL, payload)
 }
 
 func (s *Service) ProcessFedExWebhook(payload map[string]interface{}) error {
-	// Extract tracking number and status from FedEx webhook
-	trackingNumber, ok := payload["trackingNumber"].(string)
-	if !ok {
-		return errors.New("missing trackingNumber in webhook payload")
-	}
-
-	// FedEx sends scanEvents array
-	scanEvents, ok := payload["scanEvents"].([]interface{})
-	if !ok || len(scanEvents) == 0 {
-		return errors.New("missing scanEvents in webhook payload")
-	}
-
-	// Get the latest scan event
-	latestScan, ok := scanEvents[0].(map[string]interface{})
-	if !ok {
-		return errors.New("invalid scanEvent format in webhook payload")
-	}
-
-	status, ok := latestScan["eventType"].(string)
-	if !ok {
-		return errors.New("missing eventType in scanEvent")
-	}
-
-	// Find shipping label by tracking number
-	label, err := s.repository.GetShippingLabelByTrackingNumber(trackingNumber)
-	if err != nil {
-		return fmt.Errorf("failed to find shipping label: %w", err)
-	}
-
-	// Update tracking status based on FedEx status
-	var trackingStatus TrackingStatus
-	switch status {
-	case "PU":
-		trackingStatus = StatusPickedUp
-	case "IT":
-		trackingStatus = StatusInTransit
-	case "OD":
-		trackingStatus = StatusOutForDelivery
-	case "DL":
-		trackingStatus = StatusDelivered
-	case "DE":
-		trackingStatus = StatusFailed
-	default:
-		trackingStatus = StatusInTransit
-	}
-
-	// Create tracking update
-	tracking := &ShippingTracking{
-		ID:          uuid.New(),
-		LabelID:     label.ID,
-		Status:      string(trackingStatus),
-		Location:    getStringFromPayload(latestScan, "scanLocation"),
-		Description: getStringFromPayload(latestScan, "eventDescription"),
-		Timestamp:   time.Now(),
-		CreatedAt:   time.Now(),
-	}
-
-	_, err = s.repository.CreateShippingTracking(tracking)
-	return err
+	return s.ProcessCarrierWebhook(ProviderFedEx, payload)
 }
 
 // Helper function to safely extract string from payload
@@ -117,11 +117,36 @@ type ShippingLabel struct {
 	Cost         float64          `json:"cost" gorm:"not null"`
 	Currency     string           `json:"currency" gorm:"size:3;not null;default:'BDT'"`
 	Status       string           `json:"status" gorm:"size:50;not null;default:'created'"` // created, printed, shipped, delivered, failed
+	OrderNumber  string           `json:"order_number" gorm:"size:50"`
+	
+	// Snapshot used for printing labels, packing slips and manifests
+	ReceiverName    string        `json:"receiver_name" gorm:"size:255"`
+	ReceiverPhone   string        `json:"receiver_phone" gorm:"size:50"`
+	ReceiverEmail   string        `json:"receiver_email" gorm:"size:255"`
+	ReceiverAddress string        `json:"receiver_address" gorm:"type:text"`
+	ReceiverCity    string        `json:"receiver_city" gorm:"size:100"`
+	ReceiverArea    string        `json:"receiver_area_code" gorm:"size:150;index"`
+	Weight          float64       `json:"weight" gorm:"default:0"`
+	CODAmount       float64       `json:"cod_amount" gorm:"default:0"`
+	CODBookedAt     *time.Time    `json:"cod_booked_at"` // When the COD receivable was booked, see RTOAccounting
+	Items           []PackageItem `json:"items" gorm:"serializer:json"`
+	
+	// Fulfillment
+	PrintedAt  *time.Time `json:"printed_at"`
+	ManifestID *uuid.UUID `json:"manifest_id" gorm:"type:uuid;index"`
 	
 	// Provider specific data
 	ProviderOrderID   string `json:"provider_order_id" gorm:"size:100"`
+	CarrierCityID     string `json:"carrier_city_id" gorm:"size:50"`
+	CarrierZoneID     string `json:"carrier_zone_id" gorm:"size:50"`
+	CarrierAreaID     string `json:"carrier_area_id" gorm:"size:50"`
 	ProviderResponse  string `json:"provider_response" gorm:"type:json"`
 	
+	// Latest normalised carrier event
+	TrackingStatus TrackingStatus `json:"tracking_status" gorm:"size:50;default:'pending'"`
+	LastTrackedAt  *time.Time     `json:"last_tracked_at"`
+	FailedAttempts int            `json:"failed_attempts" gorm:"default:0"`
+	
 	// Delivery details
 	EstimatedDelivery *time.Time `json:"estimated_delivery"`
 	ActualDelivery    *time.Time `json:"actual_delivery"`
@@ -137,10 +162,13 @@ type ShippingTracking struct {
 	ID           uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
 	LabelID      uuid.UUID `json:"label_id" gorm:"type:uuid;not null;index"`
 	Status       string    `json:"status" gorm:"size:50;not null"`
+	Provider     ShippingProvider `json:"provider" gorm:"size:50"`
+	RawStatus    string    `json:"raw_status" gorm:"size:100"` // Carrier's own status code
 	Description  string    `json:"description" gorm:"type:text"`
 	Location     string    `json:"location" gorm:"size:255"`
 	Timestamp    time.Time `json:"timestamp" gorm:"not null"`
 	IsDelivered  bool      `json:"is_delivered" gorm:"default:false"`
+	EventID      string    `json:"event_id,omitempty" gorm:"size:100;index"` // See CarrierEvent.EventID
 	CreatedAt    time.Time `json:"created_at"`
 }
 
@@ -162,6 +190,115 @@ type ShippingProviderConfig struct {
 	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 }
 
+// ShippingManifest is the handover sheet for one carrier's pickup. Labels are
+// attached when the manifest is created and marked shipped on handover.
+type ShippingManifest struct {
+	ID             uuid.UUID        `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TenantID       uuid.UUID        `json:"tenant_id" gorm:"type:uuid;not null;index"`
+	Provider       ShippingProvider `json:"provider" gorm:"size:50;not null;index"`
+	ManifestNumber string           `json:"manifest_number" gorm:"size:50;not null;index"`
+	PickupDate     time.Time        `json:"pickup_date" gorm:"not null"`
+	Status         string           `json:"status" gorm:"size:50;not null;default:'open'"` // open, handed_over
+	TotalParcels   int              `json:"total_parcels" gorm:"default:0"`
+	TotalWeight    float64          `json:"total_weight" gorm:"default:0"`
+	TotalCOD       float64          `json:"total_cod" gorm:"default:0"`
+	HandedOverAt   *time.Time       `json:"handed_over_at"`
+	HandedOverTo   string           `json:"handed_over_to" gorm:"size:255"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt   `json:"deleted_at" gorm:"index"`
+
+	Labels []ShippingLabel `json:"labels,omitempty" gorm:"foreignKey:ManifestID"`
+}
+
+// RTOReason explains why a parcel is returning to origin
+type RTOReason string
+
+const (
+	RTOReasonRefused        RTOReason = "refused"
+	RTOReasonUnreachable    RTOReason = "unreachable"
+	RTOReasonAddressIssue   RTOReason = "address_issue"
+	RTOReasonFailedAttempts RTOReason = "failed_attempts"
+	RTOReasonOther          RTOReason = "other"
+)
+
+// RTO statuses
+const (
+	RTOStatusInitiated = "initiated" // Max attempts reached or raised manually
+	RTOStatusReturning = "returning" // Carrier confirmed the return leg
+	RTOStatusReceived  = "received"  // Back in the warehouse; restock and ledger done
+)
+
+// RTORecord tracks a return-to-origin from the first trigger until the parcel
+// is received back, including the money lost on it
+type RTORecord struct {
+	ID             uuid.UUID        `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TenantID       uuid.UUID        `json:"tenant_id" gorm:"type:uuid;not null;index"`
+	LabelID        uuid.UUID        `json:"label_id" gorm:"type:uuid;not null;uniqueIndex"`
+	OrderID        uuid.UUID        `json:"order_id" gorm:"type:uuid;not null;index"`
+	OrderNumber    string           `json:"order_number" gorm:"size:50"`
+	Provider       ShippingProvider `json:"provider" gorm:"size:50;not null;index"`
+	TrackingNumber string           `json:"tracking_number" gorm:"size:100;not null"`
+	Status         string           `json:"status" gorm:"size:50;not null;default:'initiated';index"`
+	Reason         RTOReason        `json:"reason" gorm:"size:50;not null"`
+	Notes          string           `json:"notes" gorm:"type:text"`
+	FailedAttempts int              `json:"failed_attempts" gorm:"default:0"`
+	CustomerPhone  string           `json:"customer_phone" gorm:"size:20;index"` // Normalised, see normalizePhone
+	AreaKey        string           `json:"area_key" gorm:"size:150"`
+
+	// Money lost on the parcel
+	CODAmount  float64 `json:"cod_amount" gorm:"default:0"`
+	ReturnFee  float64 `json:"return_fee" gorm:"default:0"`
+	Currency   string  `json:"currency" gorm:"size:3;default:'BDT'"`
+
+	// Receipt processing; each step is stamped so a retry skips finished steps
+	Condition      string     `json:"condition" gorm:"size:50"` // resellable, damaged
+	ReceivedAt     *time.Time `json:"received_at"`
+	RestockedAt    *time.Time `json:"restocked_at"`
+	RestockedItems []int      `json:"restocked_items,omitempty" gorm:"serializer:json"` // Positions in the label's items already put back
+	CODReversedAt  *time.Time `json:"cod_reversed_at"`
+	FeeChargedAt   *time.Time `json:"fee_charged_at"`
+	ProcessingError string    `json:"processing_error" gorm:"type:text"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+}
+
+// CustomerRiskProfile aggregates a customer's delivery history by phone so
+// repeat refusers can be flagged before another COD order ships
+type CustomerRiskProfile struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TenantID       uuid.UUID  `json:"tenant_id" gorm:"type:uuid;not null;uniqueIndex:idx_risk_tenant_phone"`
+	Phone          string     `json:"phone" gorm:"size:20;not null;uniqueIndex:idx_risk_tenant_phone"`
+	Delivered      int        `json:"delivered" gorm:"default:0"`
+	RTOCount       int        `json:"rto_count" gorm:"default:0"`
+	RefusedCount   int        `json:"refused_count" gorm:"default:0"`
+	FailedAttempts int        `json:"failed_attempts" gorm:"default:0"`
+	RTOValue       float64    `json:"rto_value" gorm:"default:0"`
+	RiskLevel      string     `json:"risk_level" gorm:"size:20;default:'low';index"` // low, medium, high
+	IsFlagged      bool       `json:"is_flagged" gorm:"default:false;index"`
+	LastRTOAt      *time.Time `json:"last_rto_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// ShippingMarkupRule adjusts live carrier quotes before they are shown at checkout.
+// An empty Provider applies the rule to every carrier without a specific rule.
+type ShippingMarkupRule struct {
+	ID              uuid.UUID        `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TenantID        uuid.UUID        `json:"tenant_id" gorm:"type:uuid;not null;index"`
+	Provider        ShippingProvider `json:"provider" gorm:"size:50;index"`
+	Name            string           `json:"name" gorm:"size:100;not null"`
+	MarkupPercent   float64          `json:"markup_percent" gorm:"default:0"`
+	MarkupFlat      float64          `json:"markup_flat" gorm:"default:0"`
+	FreeShippingMin float64          `json:"free_shipping_min" gorm:"default:0"` // Free shipping threshold
+	IsActive        bool             `json:"is_active"`
+	CreatedAt       time.Time        `json:"created_at"`
+	UpdatedAt       time.Time        `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt   `json:"deleted_at" gorm:"index"`
+}
+
 // Business Logic Methods
 
 // IsInternational checks if shipping zone covers international destinations
@@ -219,6 +356,35 @@ func (sr *ShippingRate) IsEligible(weight float64) bool {
 	return true
 }
 
+// CanBeManifested checks if the label is waiting for carrier pickup
+func (sl *ShippingLabel) CanBeManifested() bool {
+	return sl.ManifestID == nil && (sl.Status == "created" || sl.Status == "printed")
+}
+
+// RTORate is the share of completed deliveries that came back
+func (p *CustomerRiskProfile) RTORate() float64 {
+	completed := p.Delivered + p.RTOCount
+	if completed == 0 {
+		return 0
+	}
+	return float64(p.RTOCount) / float64(completed)
+}
+
+// Assess recalculates the risk level. Two refusals, or returning at least half
+// of three or more completed orders, flags the customer.
+func (p *CustomerRiskProfile) Assess() {
+	completed := p.Delivered + p.RTOCount
+	switch {
+	case p.RefusedCount >= 2 || (completed >= 3 && p.RTORate() >= 0.5):
+		p.RiskLevel = "high"
+	case p.RTOCount > 0 && p.RTORate() >= 0.25:
+		p.RiskLevel = "medium"
+	default:
+		p.RiskLevel = "low"
+	}
+	p.IsFlagged = p.RiskLevel == "high"
+}
+
 // IsDelivered checks if package has been delivered
 func (sl *ShippingLabel) IsDelivered() bool {
 	return sl.Status == "delivered" && sl.ActualDelivery != nil
@@ -0,0 +1,594 @@
+package shipping
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"ecommerce-saas/internal/notification"
+)
+
+// Carrier tracking normalisation
+//
+// Every carrier webhook is parsed into a CarrierEvent and its status mapped
+// onto TrackingStatus. RecordCarrierEvent then drives one pipeline for all
+// carriers: tracking history, label status, order fulfillment status and
+// customer notifications.
+
+// OrderFulfillmentUpdater propagates shipment progress to the order behind a
+// label; order.ShipmentSync implements it
+type OrderFulfillmentUpdater interface {
+	ApplyShipmentStatus(ctx context.Context, tenantID, orderID uuid.UUID, status, trackingNumber string, occurredAt time.Time) error
+}
+
+// CarrierEvent is a single carrier status update in carrier-neutral form
+type CarrierEvent struct {
+	Provider       ShippingProvider `json:"provider"`
+	TrackingNumber string           `json:"tracking_number"`
+	RawStatus      string           `json:"raw_status"`
+	Status         TrackingStatus   `json:"status"`
+	Location       string           `json:"location"`
+	Description    string           `json:"description"`
+	OccurredAt     time.Time        `json:"occurred_at"`
+	// EventID identifies the update across webhook retries: the carrier's
+	// event ID, or a hash of the payload when the carrier sends none
+	EventID string `json:"event_id,omitempty"`
+}
+
+// ErrShipmentNotFound is returned by public tracking when nothing matches
+var ErrShipmentNotFound = errors.New("no shipment found for this order and phone number")
+
+// TrackingTimeline is the unified tracking view of an order
+type TrackingTimeline struct {
+	OrderID     uuid.UUID          `json:"order_id"`
+	OrderNumber string             `json:"order_number"`
+	Shipments   []ShipmentTimeline `json:"shipments"`
+}
+
+type ShipmentTimeline struct {
+	LabelID           uuid.UUID        `json:"label_id"`
+	Provider          ShippingProvider `json:"provider"`
+	TrackingNumber    string           `json:"tracking_number"`
+	Status            TrackingStatus   `json:"status"`
+	ReceiverName      string           `json:"receiver_name,omitempty"`
+	EstimatedDelivery *time.Time       `json:"estimated_delivery,omitempty"`
+	DeliveredAt       *time.Time       `json:"delivered_at,omitempty"`
+	Events            []TimelineEvent  `json:"events"` // Newest first
+}
+
+type TimelineEvent struct {
+	Status      TrackingStatus `json:"status"`
+	Label       string         `json:"label"`
+	RawStatus   string         `json:"raw_status,omitempty"`
+	Location    string         `json:"location,omitempty"`
+	Description string         `json:"description,omitempty"`
+	OccurredAt  time.Time      `json:"occurred_at"`
+}
+
+// carrierStatusMap maps each carrier's status codes (normalised with
+// normalizeStatusKey) onto TrackingStatus
+var carrierStatusMap = map[ShippingProvider]map[string]TrackingStatus{
+	ProviderPathao: {
+		"pickup_requested":          StatusPending,
+		"assigned_for_pickup":       StatusPending,
+		"picked":                    StatusPickedUp,
+		"picked_up":                 StatusPickedUp,
+		"at_the_sorting_hub":        StatusInTransit,
+		"in_transit":                StatusInTransit,
+		"received_at_last_mile_hub": StatusInTransit,
+		"assigned_for_delivery":     StatusOutForDelivery,
+		"out_for_delivery":          StatusOutForDelivery,
+		"delivered":                 StatusDelivered,
+		"partial_delivery":          StatusDelivered,
+		"delivery_failed":           StatusFailed,
+		"failed":                    StatusFailed,
+		"on_hold":                   StatusFailed,
+		"return":                    StatusReturned,
+		"returned":                  StatusReturned,
+	},
+	ProviderRedX: {
+		"pickup_pending":   StatusPending,
+		"picked_up":        StatusPickedUp,
+		"in_transit":       StatusInTransit,
+		"agent_hold":       StatusInTransit,
+		"out_for_delivery": StatusOutForDelivery,
+		"delivered":        StatusDelivered,
+		"delivery_failed":  StatusFailed,
+		"agent_returning":  StatusFailed,
+		"returned":         StatusReturned,
+	},
+	ProviderPaperfly: {
+		"picked":           StatusPickedUp,
+		"in_transit":       StatusInTransit,
+		"out_for_delivery": StatusOutForDelivery,
+		"delivered":        StatusDelivered,
+		"failed":           StatusFailed,
+		"returned":         StatusReturned,
+	},
+	ProviderSteadfast: {
+		"pending":           StatusPending,
+		"in_review":         StatusPending,
+		"delivered":         StatusDelivered,
+		"partial_delivered": StatusDelivered,
+		"hold":              StatusFailed,
+		"cancelled":         StatusReturned,
+	},
+	ProviderDHL: {
+		"pre_transit": StatusPending,
+		"unknown":     StatusPending,
+		"transit":     StatusInTransit,
+		"delivered":   StatusDelivered,
+		"failure":     StatusFailed,
+		"returned":    StatusReturned,
+	},
+	ProviderFedEx: {
+		"oc": StatusPending,
+		"pu": StatusPickedUp,
+		"it": StatusInTransit,
+		"ar": StatusInTransit,
+		"od": StatusOutForDelivery,
+		"dl": StatusDelivered,
+		"de": StatusFailed,
+		"rs": StatusReturned,
+	},
+}
+
+// trackingStatusRank orders statuses so late or duplicate scans never move a
+// shipment backwards; failed attempts rank with out-for-delivery so a retry
+// can follow them
+var trackingStatusRank = map[TrackingStatus]int{
+	StatusPending:        0,
+	StatusPickedUp:       1,
+	StatusInTransit:      2,
+	StatusOutForDelivery: 3,
+	StatusFailed:         3,
+	StatusDelivered:      4,
+	StatusReturned:       4,
+}
+
+// NormalizeCarrierStatus maps a carrier status code onto TrackingStatus.
+// Unknown codes are treated as in transit.
+func NormalizeCarrierStatus(provider ShippingProvider, rawStatus string) (TrackingStatus, bool) {
+	key := normalizeStatusKey(rawStatus)
+	if status, ok := carrierStatusMap[provider][key]; ok {
+		return status, true
+	}
+
+	// Carriers without a specific map often use the canonical names
+	for _, status := range []TrackingStatus{StatusPending, StatusPickedUp, StatusInTransit, StatusOutForDelivery, StatusDelivered, StatusFailed, StatusReturned} {
+		if key == string(status) {
+			return status, true
+		}
+	}
+
+	return StatusInTransit, false
+}
+
+// ParseCarrierWebhook extracts the tracking event from a carrier webhook payload
+func ParseCarrierWebhook(provider ShippingProvider, payload map[string]interface{}) (*CarrierEvent, error) {
+	event := &CarrierEvent{Provider: provider}
+	source := payload
+
+	switch provider {
+	case ProviderPathao:
+		event.TrackingNumber = firstPayloadString(payload, "tracking_number", "consignment_id")
+		event.RawStatus = firstPayloadString(payload, "order_status", "status")
+		event.Location = getStringFromPayload(payload, "location")
+		event.Description = getStringFromPayload(payload, "description")
+	case ProviderRedX:
+		event.TrackingNumber = getStringFromPayload(payload, "tracking_id")
+		event.RawStatus = firstPayloadString(payload, "delivery_status", "status")
+		event.Location = getStringFromPayload(payload, "current_location")
+		event.Description = getStringFromPayload(payload, "remarks")
+	case ProviderPaperfly:
+		event.TrackingNumber = getStringFromPayload(payload, "consignment_id")
+		event.RawStatus = getStringFromPayload(payload, "status")
+		event.Location = getStringFromPayload(payload, "location")
+		event.Description = getStringFromPayload(payload, "note")
+	case ProviderDHL, ProviderFedEx:
+		event.TrackingNumber = getStringFromPayload(payload, "trackingNumber")
+		key, statusKey, locationKey, descriptionKey := "events", "status", "location", "description"
+		if provider == ProviderFedEx {
+			key, statusKey, locationKey, descriptionKey = "scanEvents", "eventType", "scanLocation", "eventDescription"
+		}
+		events, ok := payload[key].([]interface{})
+		if !ok || len(events) == 0 {
+			return nil, fmt.Errorf("missing %s in webhook payload", key)
+		}
+		// The latest event comes first
+		latest, ok := events[0].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid %s format in webhook payload", key)
+		}
+		source = latest
+		event.RawStatus = getStringFromPayload(latest, statusKey)
+		event.Location = getStringFromPayload(latest, locationKey)
+		event.Description = getStringFromPayload(latest, descriptionKey)
+	default:
+		event.TrackingNumber = firstPayloadString(payload, "tracking_number", "consignment_id")
+		event.RawStatus = getStringFromPayload(payload, "status")
+		event.Location = getStringFromPayload(payload, "location")
+		event.Description = getStringFromPayload(payload, "description")
+	}
+
+	if event.TrackingNumber == "" {
+		return nil, errors.New("missing tracking number in webhook payload")
+	}
+	if event.RawStatus == "" {
+		return nil, errors.New("missing status in webhook payload")
+	}
+
+	event.Status, _ = NormalizeCarrierStatus(provider, event.RawStatus)
+	event.OccurredAt = payloadTime(source, "timestamp", "updated_at", "date", "eventTimestamp")
+	event.EventID = firstPayloadString(source, "event_id", "eventId")
+	if event.EventID == "" {
+		event.EventID = payloadHash(payload)
+	}
+
+	return event, nil
+}
+
+// Tracking Services
+
+// ProcessCarrierWebhook parses and records a webhook from any supported carrier
+func (s *Service) ProcessCarrierWebhook(provider ShippingProvider, payload map[string]interface{}) error {
+	event, err := ParseCarrierWebhook(provider, payload)
+	if err != nil {
+		return err
+	}
+	_, err = s.RecordCarrierEvent(context.Background(), event)
+	return err
+}
+
+// RecordCarrierEvent stores a normalised event and propagates it to the label,
+// the order and the customer. Redelivered events are ignored; out-of-order
+// events are kept in the history but do not change any status.
+func (s *Service) RecordCarrierEvent(ctx context.Context, event *CarrierEvent) (*ShippingTracking, error) {
+	label, err := s.repository.GetShippingLabelByTrackingNumber(event.TrackingNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find shipping label: %w", err)
+	}
+
+	if event.EventID != "" {
+		if existing, err := s.repository.GetShippingTrackingByEventID(label.ID, event.EventID); err == nil {
+			return existing, nil
+		}
+	} else if latest, err := s.repository.GetLatestShippingTracking(label.ID); err == nil {
+		if latest.RawStatus == event.RawStatus && latest.Timestamp.Equal(event.OccurredAt) {
+			return latest, nil
+		}
+	}
+
+	tracking := &ShippingTracking{
+		ID:          uuid.New(),
+		LabelID:     label.ID,
+		Status:      string(event.Status),
+		Provider:    event.Provider,
+		RawStatus:   event.RawStatus,
+		Location:    event.Location,
+		Description: event.Description,
+		Timestamp:   event.OccurredAt,
+		IsDelivered: event.Status == StatusDelivered,
+		EventID:     event.EventID,
+		CreatedAt:   time.Now(),
+	}
+
+	if _, err := s.repository.CreateShippingTracking(tracking); err != nil {
+		return nil, err
+	}
+
+	advanced := advancesTracking(label, event)
+	if event.Status == StatusFailed && label.Status != "cancelled" {
+		label.FailedAttempts++
+		if event.Description != "" {
+			label.DeliveryNotes = event.Description
+		}
+		if !advanced {
+			if _, err := s.repository.UpdateShippingLabel(label); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if !advanced {
+		s.handleDeliveryOutcome(label, event, false)
+		return tracking, nil
+	}
+
+	label.TrackingStatus = event.Status
+	label.LastTrackedAt = &event.OccurredAt
+	switch event.Status {
+	case StatusDelivered:
+		label.Status = "delivered"
+		label.ActualDelivery = &event.OccurredAt
+	case StatusReturned:
+		label.Status = "returned"
+	case StatusPickedUp, StatusInTransit, StatusOutForDelivery, StatusFailed:
+		if label.Status == "created" || label.Status == "printed" {
+			label.Status = "shipped"
+		}
+	}
+
+	if _, err := s.repository.UpdateShippingLabel(label); err != nil {
+		return nil, err
+	}
+
+	s.handleDeliveryOutcome(label, event, true)
+
+	if s.orderUpdater != nil {
+		if err := s.orderUpdater.ApplyShipmentStatus(ctx, label.TenantID, label.OrderID, string(event.Status), label.TrackingNumber, event.OccurredAt); err != nil {
+			fmt.Printf("Failed to update order %s from shipment %s: %v\n", label.OrderID, label.TrackingNumber, err)
+		}
+	}
+
+	if s.notificationService != nil {
+		if err := s.notifyCustomer(label, event.Status); err != nil {
+			fmt.Printf("Failed to notify customer of shipment %s: %v\n", label.TrackingNumber, err)
+		}
+	}
+
+	return tracking, nil
+}
+
+// GetOrderTimeline returns the shipments of an order with their tracking events
+func (s *Service) GetOrderTimeline(tenantID uuid.UUID, orderID string) (*TrackingTimeline, error) {
+	id, err := uuid.Parse(orderID)
+	if err != nil {
+		return nil, errors.New("invalid order ID")
+	}
+
+	labels, err := s.repository.GetShippingLabelsByOrder(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(labels) == 0 {
+		return nil, errors.New("no shipments found for order")
+	}
+
+	return s.buildTimeline(labels)
+}
+
+// TrackOrderPublic serves the storefront tracking page. The phone number must
+// match the shipment's receiver so order numbers alone cannot be enumerated.
+func (s *Service) TrackOrderPublic(tenantID uuid.UUID, orderNumber, phone string) (*TrackingTimeline, error) {
+	orderNumber = strings.TrimSpace(orderNumber)
+	if orderNumber == "" || strings.TrimSpace(phone) == "" {
+		return nil, errors.New("order number and phone are required")
+	}
+
+	labels, err := s.repository.GetShippingLabelsByOrderNumber(tenantID, orderNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []ShippingLabel
+	for _, label := range labels {
+		if phonesMatch(label.ReceiverPhone, phone) {
+			matched = append(matched, label)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, ErrShipmentNotFound
+	}
+
+	timeline, err := s.buildTimeline(matched)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the public response free of receiver details
+	for i := range timeline.Shipments {
+		timeline.Shipments[i].ReceiverName = ""
+	}
+	return timeline, nil
+}
+
+func (s *Service) buildTimeline(labels []ShippingLabel) (*TrackingTimeline, error) {
+	timeline := &TrackingTimeline{
+		OrderID:     labels[0].OrderID,
+		OrderNumber: labels[0].OrderNumber,
+		Shipments:   make([]ShipmentTimeline, 0, len(labels)),
+	}
+
+	for _, label := range labels {
+		history, err := s.repository.GetTrackingHistory(label.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		shipment := ShipmentTimeline{
+			LabelID:           label.ID,
+			Provider:          label.Provider,
+			TrackingNumber:    label.TrackingNumber,
+			Status:            label.TrackingStatus,
+			ReceiverName:      label.ReceiverName,
+			EstimatedDelivery: label.EstimatedDelivery,
+			DeliveredAt:       label.ActualDelivery,
+			Events:            make([]TimelineEvent, 0, len(history)+1),
+		}
+		for _, entry := range history {
+			shipment.Events = append(shipment.Events, TimelineEvent{
+				Status:      TrackingStatus(entry.Status),
+				Label:       trackingStatusLabel(TrackingStatus(entry.Status)),
+				RawStatus:   entry.RawStatus,
+				Location:    entry.Location,
+				Description: entry.Description,
+				OccurredAt:  entry.Timestamp,
+			})
+		}
+		// History is newest first; the label creation closes the list
+		shipment.Events = append(shipment.Events, TimelineEvent{
+			Status:     StatusPending,
+			Label:      "Shipment created",
+			OccurredAt: label.CreatedAt,
+		})
+
+		timeline.Shipments = append(timeline.Shipments, shipment)
+	}
+
+	return timeline, nil
+}
+
+// notifyCustomer sends SMS and email for the events customers care about
+func (s *Service) notifyCustomer(label *ShippingLabel, status TrackingStatus) error {
+	message := trackingNotificationMessage(label, status)
+	if message == "" {
+		return nil
+	}
+
+	variables := map[string]interface{}{
+		"order_number":    label.OrderNumber,
+		"tracking_number": label.TrackingNumber,
+		"provider":        string(label.Provider),
+		"status":          string(status),
+	}
+
+	var errs []error
+	if label.ReceiverPhone != "" {
+		if err := s.notificationService.SendSMS(label.TenantID, &notification.SendSMSRequest{
+			To:        []string{label.ReceiverPhone},
+			Message:   message,
+			Variables: variables,
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("SMS: %w", err))
+		}
+	}
+
+	if label.ReceiverEmail != "" {
+		if err := s.notificationService.SendEmail(label.TenantID, &notification.SendEmailRequest{
+			To:          []string{label.ReceiverEmail},
+			Subject:     fmt.Sprintf("Order %s: %s", label.OrderNumber, trackingStatusLabel(status)),
+			Content:     message,
+			ContentType: "text",
+			Variables:   variables,
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("email: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Helper functions
+
+// advancesTracking reports whether the event moves the shipment forward
+func advancesTracking(label *ShippingLabel, event *CarrierEvent) bool {
+	if label.Status == "cancelled" {
+		return false
+	}
+	if label.LastTrackedAt != nil && event.OccurredAt.Before(*label.LastTrackedAt) {
+		return false
+	}
+	current := label.TrackingStatus
+	if current == "" {
+		current = StatusPending
+	}
+	if current == StatusDelivered || current == StatusReturned {
+		return false
+	}
+	if event.Status == current {
+		return false
+	}
+	return trackingStatusRank[event.Status] >= trackingStatusRank[current]
+}
+
+func trackingNotificationMessage(label *ShippingLabel, status TrackingStatus) string {
+	order := label.OrderNumber
+	if order == "" {
+		order = label.TrackingNumber
+	}
+	carrier := strings.ToUpper(string(label.Provider))
+
+	switch status {
+	case StatusPickedUp:
+		return fmt.Sprintf("Your order %s has been handed to %s. Tracking: %s", order, carrier, label.TrackingNumber)
+	case StatusOutForDelivery:
+		message := fmt.Sprintf("Your order %s is out for delivery today.", order)
+		if label.CODAmount > 0 {
+			message += fmt.Sprintf(" Please keep %s %.0f ready.", label.Currency, label.CODAmount)
+		}
+		return message
+	case StatusDelivered:
+		return fmt.Sprintf("Your order %s has been delivered. Thank you for shopping with us!", order)
+	case StatusFailed:
+		return fmt.Sprintf("%s could not deliver your order %s. They will contact you to reschedule.", carrier, order)
+	default:
+		return ""
+	}
+}
+
+func trackingStatusLabel(status TrackingStatus) string {
+	switch status {
+	case StatusPending:
+		return "Awaiting pickup"
+	case StatusPickedUp:
+		return "Picked up"
+	case StatusInTransit:
+		return "In transit"
+	case StatusOutForDelivery:
+		return "Out for delivery"
+	case StatusDelivered:
+		return "Delivered"
+	case StatusFailed:
+		return "Delivery attempt failed"
+	case StatusReturned:
+		return "Returned to sender"
+	default:
+		return string(status)
+	}
+}
+
+func normalizeStatusKey(status string) string {
+	status = strings.ToLower(strings.TrimSpace(status))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(status)
+}
+
+func firstPayloadString(payload map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if value := getStringFromPayload(payload, key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// payloadTime reads the event time from the first key that parses, falling
+// back to the time the webhook was received
+func payloadTime(payload map[string]interface{}, keys ...string) time.Time {
+	layouts := []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05"}
+	for _, key := range keys {
+		value := getStringFromPayload(payload, key)
+		if value == "" {
+			continue
+		}
+		for _, layout := range layouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Now()
+}
+
+// payloadHash identifies a webhook payload by its content. encoding/json
+// writes map keys in sorted order, so a redelivered payload hashes the same.
+func payloadHash(payload map[string]interface{}) string {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// phonesMatch compares the last ten digits so "+8801711000000" matches "01711000000"
+func phonesMatch(a, b string) bool {
+	normalized := normalizePhone(a)
+	return normalized != "" && normalized == normalizePhone(b)
+}
@@ -0,0 +1,186 @@
+package shipping
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalizeCarrierStatus(t *testing.T) {
+	tests := []struct {
+		provider  ShippingProvider
+		raw       string
+		want      TrackingStatus
+		wantKnown bool
+	}{
+		{ProviderPathao, "Picked", StatusPickedUp, true},
+		{ProviderPathao, "Received at Last Mile Hub", StatusInTransit, true},
+		{ProviderPathao, "Delivery Failed", StatusFailed, true},
+		{ProviderRedX, "agent-returning", StatusFailed, true},
+		{ProviderRedX, " Out For Delivery ", StatusOutForDelivery, true},
+		{ProviderSteadfast, "partial_delivered", StatusDelivered, true},
+		{ProviderSteadfast, "cancelled", StatusReturned, true},
+		{ProviderFedEx, "DL", StatusDelivered, true},
+		{ProviderDHL, "pre-transit", StatusPending, true},
+		{ProviderPaperfly, "returned", StatusReturned, true},
+		{ProviderEcourier, "out_for_delivery", StatusOutForDelivery, true}, // Canonical names work for every carrier
+		{ProviderPathao, "sorting in progress", StatusInTransit, false},
+	}
+
+	for _, tt := range tests {
+		got, known := NormalizeCarrierStatus(tt.provider, tt.raw)
+		if got != tt.want || known != tt.wantKnown {
+			t.Errorf("NormalizeCarrierStatus(%s, %q) = %s, %v; want %s, %v", tt.provider, tt.raw, got, known, tt.want, tt.wantKnown)
+		}
+	}
+}
+
+func TestParseCarrierWebhook(t *testing.T) {
+	tests := []struct {
+		name       string
+		provider   ShippingProvider
+		payload    map[string]interface{}
+		want       CarrierEvent
+		wantErr    bool
+		wantTimeAt string
+	}{
+		{
+			name:     "pathao",
+			provider: ProviderPathao,
+			payload: map[string]interface{}{
+				"consignment_id": "DA240101ABC",
+				"order_status":   "Out_For_Delivery",
+				"updated_at":     "2024-01-01 10:30:00",
+				"location":       "Gulshan hub",
+			},
+			want:       CarrierEvent{TrackingNumber: "DA240101ABC", RawStatus: "Out_For_Delivery", Status: StatusOutForDelivery, Location: "Gulshan hub"},
+			wantTimeAt: "2024-01-01T10:30:00Z",
+		},
+		{
+			name:     "redx",
+			provider: ProviderRedX,
+			payload: map[string]interface{}{
+				"tracking_id":     "20A316TU9WXY",
+				"delivery_status": "delivery-failed",
+				"remarks":         "Customer not reachable",
+				"timestamp":       "2024-01-02T08:00:00+06:00",
+			},
+			want:       CarrierEvent{TrackingNumber: "20A316TU9WXY", RawStatus: "delivery-failed", Status: StatusFailed, Description: "Customer not reachable"},
+			wantTimeAt: "2024-01-02T02:00:00Z",
+		},
+		{
+			name:     "fedex reads the latest scan",
+			provider: ProviderFedEx,
+			payload: map[string]interface{}{
+				"trackingNumber": "794644790138",
+				"scanEvents": []interface{}{
+					map[string]interface{}{"eventType": "DL", "scanLocation": "Dhaka", "eventId": "scan-2"},
+					map[string]interface{}{"eventType": "OD", "scanLocation": "Dhaka", "eventId": "scan-1"},
+				},
+			},
+			want: CarrierEvent{TrackingNumber: "794644790138", RawStatus: "DL", Status: StatusDelivered, Location: "Dhaka", EventID: "scan-2"},
+		},
+		{
+			name:     "missing tracking number",
+			provider: ProviderPathao,
+			payload:  map[string]interface{}{"order_status": "Delivered"},
+			wantErr:  true,
+		},
+		{
+			name:     "missing status",
+			provider: ProviderRedX,
+			payload:  map[string]interface{}{"tracking_id": "20A316TU9WXY"},
+			wantErr:  true,
+		},
+		{
+			name:     "fedex without scans",
+			provider: ProviderFedEx,
+			payload:  map[string]interface{}{"trackingNumber": "794644790138"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := ParseCarrierWebhook(tt.provider, tt.payload)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseCarrierWebhook() = %+v, want an error", event)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCarrierWebhook() error = %v", err)
+			}
+
+			if event.Provider != tt.provider || event.TrackingNumber != tt.want.TrackingNumber || event.RawStatus != tt.want.RawStatus ||
+				event.Status != tt.want.Status || event.Location != tt.want.Location || event.Description != tt.want.Description {
+				t.Errorf("ParseCarrierWebhook() = %+v, want %+v", event, tt.want)
+			}
+			if tt.want.EventID != "" && event.EventID != tt.want.EventID {
+				t.Errorf("EventID = %q, want %q", event.EventID, tt.want.EventID)
+			}
+			if event.EventID == "" {
+				t.Error("EventID is empty")
+			}
+			if tt.wantTimeAt != "" && event.OccurredAt.UTC().Format(time.RFC3339) != tt.wantTimeAt {
+				t.Errorf("OccurredAt = %v, want %s", event.OccurredAt, tt.wantTimeAt)
+			}
+		})
+	}
+}
+
+// Carriers retry webhooks; a redelivered payload must get the same event ID so
+// it is recorded once, while a new status for the same parcel must not
+func TestParseCarrierWebhookEventID(t *testing.T) {
+	first := map[string]interface{}{"consignment_id": "DA1", "order_status": "Picked", "updated_at": "2024-01-01 10:00:00"}
+	redelivered := map[string]interface{}{"updated_at": "2024-01-01 10:00:00", "order_status": "Picked", "consignment_id": "DA1"}
+	next := map[string]interface{}{"consignment_id": "DA1", "order_status": "In_Transit", "updated_at": "2024-01-01 14:00:00"}
+	withID := map[string]interface{}{"consignment_id": "DA1", "order_status": "Picked", "event_id": "evt-42"}
+
+	a, _ := ParseCarrierWebhook(ProviderPathao, first)
+	b, _ := ParseCarrierWebhook(ProviderPathao, redelivered)
+	c, _ := ParseCarrierWebhook(ProviderPathao, next)
+	d, _ := ParseCarrierWebhook(ProviderPathao, withID)
+
+	if a.EventID != b.EventID {
+		t.Errorf("redelivered payload has a new event ID: %s != %s", a.EventID, b.EventID)
+	}
+	if a.EventID == c.EventID {
+		t.Error("different updates share an event ID")
+	}
+	if d.EventID != "evt-42" {
+		t.Errorf("EventID = %q, want the carrier's evt-42", d.EventID)
+	}
+}
+
+func TestAdvancesTracking(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	earlier, later := base.Add(-time.Hour), base.Add(time.Hour)
+
+	tests := []struct {
+		name   string
+		label  ShippingLabel
+		status TrackingStatus
+		at     time.Time
+		want   bool
+	}{
+		{"first scan", ShippingLabel{Status: "printed"}, StatusPickedUp, base, true},
+		{"forward", ShippingLabel{TrackingStatus: StatusInTransit, LastTrackedAt: &base}, StatusOutForDelivery, later, true},
+		{"same status again", ShippingLabel{TrackingStatus: StatusInTransit, LastTrackedAt: &base}, StatusInTransit, later, false},
+		{"late scan", ShippingLabel{TrackingStatus: StatusInTransit, LastTrackedAt: &base}, StatusOutForDelivery, earlier, false},
+		{"backwards", ShippingLabel{TrackingStatus: StatusOutForDelivery, LastTrackedAt: &base}, StatusPickedUp, later, false},
+		{"failed attempt", ShippingLabel{TrackingStatus: StatusOutForDelivery, LastTrackedAt: &base}, StatusFailed, later, true},
+		{"retry after a failed attempt", ShippingLabel{TrackingStatus: StatusFailed, LastTrackedAt: &base}, StatusOutForDelivery, later, true},
+		{"after delivery", ShippingLabel{TrackingStatus: StatusDelivered, LastTrackedAt: &base}, StatusReturned, later, false},
+		{"cancelled label", ShippingLabel{Status: "cancelled"}, StatusPickedUp, base, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &CarrierEvent{Status: tt.status, OccurredAt: tt.at}
+			if got := advancesTracking(&tt.label, event); got != tt.want {
+				t.Errorf("advancesTracking() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
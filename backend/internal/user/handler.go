@@ -64,13 +64,17 @@ func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 
 // Register handles user registration
 func (h *Handler) Register(c *gin.Context) {
-	var user User
-	if err := c.ShouldBindJSON(&user); err != nil {
+	var req RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	createdUser, err := h.service.RegisterUser(c.Request.Context(), &user)
+	createdUser, err := h.service.RegisterUser(c.Request.Context(), &req.User, &SignupDetails{
+		ReferralCode: req.ReferralCode,
+		IPAddress:    c.ClientIP(),
+		UserAgent:    c.Request.UserAgent(),
+	})
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -31,6 +31,12 @@ func (m *Module) RegisterRoutes(router *gin.RouterGroup) {
 	m.handler.RegisterRoutes(router)
 }
 
+// SetSignupListener registers a listener for new registrations
+func (m *Module) SetSignupListener(listener SignupListener) {
+	m.handler.service.SetSignupListener(listener)
+	m.service.SetSignupListener(listener)
+}
+
 // GetHandler returns the user handler
 func (m *Module) GetHandler() *Handler {
 	return m.handler
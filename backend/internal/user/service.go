@@ -19,11 +19,18 @@ type ResetTokenData struct {
 	ExpiresAt time.Time
 }
 
+// SignupListener is told when a user registers so other modules (such as
+// loyalty referrals) can react. Listener errors never fail the registration.
+type SignupListener interface {
+	UserRegistered(ctx context.Context, user *User, signup *SignupDetails) error
+}
+
 // Service handles user business logic
 type Service struct {
 	repo        Repository
 	jwtManager  *utils.JWTManager
 	resetTokens map[string]ResetTokenData
+	listener    SignupListener
 }
 
 // NewService creates a new user service
@@ -35,8 +42,13 @@ func NewService(repo Repository, jwtManager *utils.JWTManager) *Service {
 	}
 }
 
-// RegisterUser creates a new user account
-func (s *Service) RegisterUser(ctx context.Context, user *User) (*User, error) {
+// SetSignupListener registers a listener for new registrations
+func (s *Service) SetSignupListener(listener SignupListener) {
+	s.listener = listener
+}
+
+// RegisterUser creates a new user account. signup may be nil.
+func (s *Service) RegisterUser(ctx context.Context, user *User, signup *SignupDetails) (*User, error) {
 	// Validate input
 	if err := s.validateUser(user); err != nil {
 		return nil, err
@@ -78,6 +90,12 @@ func (s *Service) RegisterUser(ctx context.Context, user *User) (*User, error) {
 		log.Printf("Failed to send verification email: %v", err)
 	}
 
+	if s.listener != nil && signup != nil {
+		if err := s.listener.UserRegistered(ctx, user, signup); err != nil {
+			log.Printf("Failed to process signup of user %s: %v", user.ID, err)
+		}
+	}
+
 	return user, nil
 }
 
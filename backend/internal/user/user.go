@@ -210,6 +210,21 @@ type TokenResponse struct {
 	ExpiresIn    int    `json:"expires_in"`
 }
 
+// RegisterRequest represents a registration, optionally through a referral
+type RegisterRequest struct {
+	User
+	ReferralCode string `json:"referral_code,omitempty"`
+}
+
+// SignupDetails describes where a registration came from. The device is
+// identified from the IP address and user agent the server saw, never from
+// anything the client claims.
+type SignupDetails struct {
+	ReferralCode string
+	IPAddress    string
+	UserAgent    string
+}
+
 
 
 // UserFilter represents user listing filters
@@ -0,0 +1,133 @@
+-- Trigram matching for typo-tolerant search
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Create product_search_documents table
+-- One row per product, kept current by the triggers below
+CREATE TABLE IF NOT EXISTS product_search_documents (
+    product_id UUID PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    category_path TEXT,
+    search_text TEXT NOT NULL DEFAULT '',
+    document TSVECTOR NOT NULL DEFAULT ''::tsvector,
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Create search_synonyms table
+CREATE TABLE IF NOT EXISTS search_synonyms (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    terms JSONB NOT NULL,
+    one_way BOOLEAN NOT NULL DEFAULT FALSE,
+
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_product_search_documents_tenant_id ON product_search_documents(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_product_search_documents_document ON product_search_documents USING GIN (document);
+CREATE INDEX IF NOT EXISTS idx_product_search_documents_search_text ON product_search_documents USING GIN (search_text gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_search_synonyms_tenant_id ON search_synonyms(tenant_id);
+
+-- Normalise text before tokenising: Bangla digits become ASCII digits and
+-- zero-width joiners, which vary between Bangla keyboards, are dropped
+CREATE OR REPLACE FUNCTION search_normalize(value TEXT)
+RETURNS TEXT AS $$
+    SELECT lower(translate(COALESCE(value, ''), '০১২৩৪৫৬৭৮৯' || chr(8204) || chr(8205), '0123456789'));
+$$ LANGUAGE SQL IMMUTABLE;
+
+-- Full category path of a category, root first ("Fashion > Men > Shirts")
+CREATE OR REPLACE FUNCTION search_category_path(p_category_id UUID)
+RETURNS TEXT AS $$
+    WITH RECURSIVE path AS (
+        SELECT id, parent_id, name, 1 AS depth FROM categories WHERE id = p_category_id
+        UNION ALL
+        SELECT c.id, c.parent_id, c.name, path.depth + 1
+        FROM categories c JOIN path ON c.id = path.parent_id
+        WHERE path.depth < 16
+    )
+    SELECT string_agg(name, ' > ' ORDER BY depth DESC) FROM path;
+$$ LANGUAGE SQL STABLE;
+
+-- Rebuild the search document of one product. Every field is indexed with the
+-- english configuration (stemming) and the simple configuration, which keeps
+-- Bangla and other non-English words as they are. Weights: name and SKU A,
+-- tags B, category path C, description D.
+CREATE OR REPLACE FUNCTION refresh_product_search_document(p_product_id UUID)
+RETURNS VOID AS $$
+BEGIN
+    INSERT INTO product_search_documents (product_id, tenant_id, category_path, search_text, document, updated_at)
+    SELECT
+        p.id,
+        p.tenant_id,
+        cp.path,
+        search_normalize(concat_ws(' ', p.name, p.sku, p.barcode, t.tags)),
+        setweight(to_tsvector('english', search_normalize(p.name)), 'A') ||
+        setweight(to_tsvector('simple', search_normalize(concat_ws(' ', p.name, p.sku, p.barcode))), 'A') ||
+        setweight(to_tsvector('english', search_normalize(t.tags)), 'B') ||
+        setweight(to_tsvector('simple', search_normalize(t.tags)), 'B') ||
+        setweight(to_tsvector('english', search_normalize(cp.path)), 'C') ||
+        setweight(to_tsvector('simple', search_normalize(cp.path)), 'C') ||
+        setweight(to_tsvector('english', search_normalize(regexp_replace(p.description, '<[^>]*>', ' ', 'g'))), 'D') ||
+        setweight(to_tsvector('simple', search_normalize(regexp_replace(p.description, '<[^>]*>', ' ', 'g'))), 'D'),
+        NOW()
+    FROM products p
+    LEFT JOIN LATERAL (
+        SELECT string_agg(tag, ' ') AS tags
+        FROM jsonb_array_elements_text(CASE WHEN jsonb_typeof(p.tags) = 'array' THEN p.tags ELSE '[]'::jsonb END) AS tag
+    ) t ON TRUE
+    LEFT JOIN LATERAL (SELECT search_category_path(p.category_id) AS path) cp ON TRUE
+    WHERE p.id = p_product_id
+    ON CONFLICT (product_id) DO UPDATE SET
+        tenant_id = EXCLUDED.tenant_id,
+        category_path = EXCLUDED.category_path,
+        search_text = EXCLUDED.search_text,
+        document = EXCLUDED.document,
+        updated_at = EXCLUDED.updated_at;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION refresh_product_search_document_trigger()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM refresh_product_search_document(NEW.id);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Renaming or moving a category changes the path of every product below it
+CREATE OR REPLACE FUNCTION refresh_category_search_documents_trigger()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM refresh_product_search_document(p.id)
+    FROM products p
+    WHERE p.category_id IN (
+        WITH RECURSIVE tree AS (
+            SELECT id FROM categories WHERE id = NEW.id
+            UNION
+            SELECT c.id FROM categories c JOIN tree ON c.parent_id = tree.id
+        )
+        SELECT id FROM tree
+    );
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Create triggers
+CREATE TRIGGER refresh_products_search_document
+    AFTER INSERT OR UPDATE OF name, description, sku, barcode, tags, category_id ON products
+    FOR EACH ROW
+    EXECUTE FUNCTION refresh_product_search_document_trigger();
+
+CREATE TRIGGER refresh_categories_search_documents
+    AFTER UPDATE OF name, parent_id ON categories
+    FOR EACH ROW
+    EXECUTE FUNCTION refresh_category_search_documents_trigger();
+
+CREATE TRIGGER update_search_synonyms_updated_at
+    BEFORE UPDATE ON search_synonyms
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Index existing products
+SELECT refresh_product_search_document(id) FROM products;
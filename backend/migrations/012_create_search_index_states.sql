@@ -0,0 +1,14 @@
+-- Create search_index_states table
+-- Records when each product was last sent to an external search index so
+-- missed updates can be found and index lag reported
+CREATE TABLE IF NOT EXISTS search_index_states (
+    backend VARCHAR(50) NOT NULL,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    indexed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (backend, product_id)
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_search_index_states_tenant_id ON search_index_states(backend, tenant_id);
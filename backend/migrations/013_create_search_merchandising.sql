@@ -0,0 +1,49 @@
+-- Create search_logs table
+-- One row per search; clicks on a result update the row
+CREATE TABLE IF NOT EXISTS search_logs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    user_id VARCHAR(255),
+    session_id VARCHAR(255),
+    query TEXT NOT NULL,
+    type VARCHAR(50) DEFAULT 'global',
+    results BIGINT NOT NULL DEFAULT 0,
+    clicked BOOLEAN NOT NULL DEFAULT FALSE,
+    position INTEGER,
+
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Searches sent to a page by a redirect rule are neither zero-result nor unclicked
+ALTER TABLE search_logs ADD COLUMN IF NOT EXISTS redirected BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Create search_rules table
+CREATE TABLE IF NOT EXISTS search_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    query TEXT NOT NULL,
+    match_type VARCHAR(20) NOT NULL DEFAULT 'exact' CHECK (match_type IN ('exact', 'contains')),
+    pinned_product_ids JSONB,
+    hidden_product_ids JSONB,
+    boosts JSONB,
+    redirect_url VARCHAR(500),
+    priority INTEGER NOT NULL DEFAULT 0,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    starts_at TIMESTAMPTZ,
+    ends_at TIMESTAMPTZ,
+
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_search_logs_tenant_created ON search_logs(tenant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_search_logs_query ON search_logs(tenant_id, query);
+CREATE INDEX IF NOT EXISTS idx_search_rules_tenant_id ON search_rules(tenant_id, is_active);
+
+-- Create triggers
+CREATE TRIGGER update_search_rules_updated_at
+    BEFORE UPDATE ON search_rules
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,76 @@
+-- Create attribute_definitions table
+-- Typed product attributes; values live in products.attributes under code
+CREATE TABLE IF NOT EXISTS attribute_definitions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    code VARCHAR(63) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('text', 'number', 'enum', 'boolean', 'date', 'reference')),
+    options JSONB,
+    reference_type VARCHAR(20),
+    unit VARCHAR(50),
+    filterable BOOLEAN NOT NULL DEFAULT FALSE,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+
+    UNIQUE(tenant_id, code)
+);
+
+-- Create attribute_sets table
+-- attributes holds [{"attribute_id": ..., "required": ...}] in display order
+CREATE TABLE IF NOT EXISTS attribute_sets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    attributes JSONB NOT NULL DEFAULT '[]',
+
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Categories without a set inherit the set of their nearest ancestor
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS attribute_set_id UUID REFERENCES attribute_sets(id) ON DELETE SET NULL;
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS attributes JSONB;
+
+-- Create metafields table
+-- Namespaced values apps and integrations attach to products, variants, orders and customers
+CREATE TABLE IF NOT EXISTS metafields (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    owner_type VARCHAR(20) NOT NULL CHECK (owner_type IN ('product', 'variant', 'order', 'customer')),
+    owner_id UUID NOT NULL,
+    namespace VARCHAR(64) NOT NULL,
+    key VARCHAR(64) NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('text', 'number', 'boolean', 'date', 'url', 'json')),
+    value JSONB,
+
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_attribute_definitions_filterable ON attribute_definitions(tenant_id) WHERE filterable = TRUE;
+CREATE INDEX IF NOT EXISTS idx_attribute_sets_tenant_id ON attribute_sets(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_categories_attribute_set_id ON categories(attribute_set_id);
+CREATE INDEX IF NOT EXISTS idx_products_attributes ON products USING GIN(attributes);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_metafields_owner_key ON metafields(tenant_id, owner_type, owner_id, namespace, key);
+
+-- Create triggers
+CREATE TRIGGER update_attribute_definitions_updated_at
+    BEFORE UPDATE ON attribute_definitions
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_attribute_sets_updated_at
+    BEFORE UPDATE ON attribute_sets
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_metafields_updated_at
+    BEFORE UPDATE ON metafields
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,31 @@
+-- Product-level option definitions: [{"name": "Size", "values": [{"value": "M", "swatch": "#000000"}]}]
+ALTER TABLE products ADD COLUMN IF NOT EXISTS options JSONB;
+
+-- Variants duplicating the options of another variant of their product must
+-- be merged or deleted by hand first: they may be on orders, and only the
+-- merchant knows which one to keep. List them and stop instead of guessing.
+DO $$
+DECLARE
+    duplicates TEXT;
+BEGIN
+    SELECT string_agg(format('product %s: variants %s with options %s', product_id, variant_ids, options), E'\n')
+    INTO duplicates
+    FROM (
+        SELECT product_id, options, string_agg(id::text, ', ' ORDER BY created_at, id) AS variant_ids
+        FROM product_variants
+        WHERE jsonb_typeof(options) = 'object' AND options <> '{}'::jsonb
+        GROUP BY product_id, options
+        HAVING COUNT(*) > 1
+    ) d;
+
+    IF duplicates IS NOT NULL THEN
+        RAISE EXCEPTION 'product variants with duplicate options must be resolved before this migration'
+            USING DETAIL = duplicates,
+                  HINT = 'Give each listed variant distinct options or delete the extra ones, then run the migration again.';
+    END IF;
+END $$;
+
+-- A product has at most one variant per combination of option values
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_variants_product_options
+    ON product_variants(product_id, options)
+    WHERE jsonb_typeof(options) = 'object' AND options <> '{}'::jsonb;
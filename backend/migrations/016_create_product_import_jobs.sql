@@ -0,0 +1,35 @@
+-- Create product_import_jobs table
+-- Background product imports from CSV or XLSX with their progress and
+-- row-level errors ([{"row": 2, "column": "price", "message": "..."}])
+CREATE TABLE IF NOT EXISTS product_import_jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    file_name VARCHAR(255) NOT NULL,
+    format VARCHAR(10) NOT NULL CHECK (format IN ('csv', 'xlsx')),
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+
+    total_rows INTEGER NOT NULL DEFAULT 0,
+    processed_rows INTEGER NOT NULL DEFAULT 0,
+    failed_rows INTEGER NOT NULL DEFAULT 0,
+    products_created INTEGER NOT NULL DEFAULT 0,
+    products_updated INTEGER NOT NULL DEFAULT 0,
+    variants_created INTEGER NOT NULL DEFAULT 0,
+    variants_updated INTEGER NOT NULL DEFAULT 0,
+    errors JSONB,
+    error TEXT,
+
+    started_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_product_import_jobs_tenant ON product_import_jobs(tenant_id, created_at DESC);
+
+-- Create triggers
+CREATE TRIGGER update_product_import_jobs_updated_at
+    BEFORE UPDATE ON product_import_jobs
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,36 @@
+-- Summaries of approved reviews, kept on products by the reviews module so
+-- the catalog and search can show, filter, sort and rank by them.
+-- rating_distribution holds the review count per star: {"1": 0, ..., "5": 12}
+ALTER TABLE products ADD COLUMN IF NOT EXISTS rating_average NUMERIC(3, 2);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS review_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS rating_distribution JSONB;
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_products_rating ON products(tenant_id, rating_average DESC NULLS LAST, review_count DESC);
+
+-- Backfill from the reviews approved so far
+DO $$
+BEGIN
+    IF to_regclass('reviews') IS NOT NULL THEN
+        UPDATE products p
+        SET rating_average = s.rating_average,
+            review_count = s.review_count,
+            rating_distribution = s.rating_distribution
+        FROM (
+            SELECT tenant_id, product_id,
+                ROUND(AVG(rating)::numeric, 2) AS rating_average,
+                COUNT(*) AS review_count,
+                jsonb_build_object(
+                    '1', COUNT(*) FILTER (WHERE rating = 1),
+                    '2', COUNT(*) FILTER (WHERE rating = 2),
+                    '3', COUNT(*) FILTER (WHERE rating = 3),
+                    '4', COUNT(*) FILTER (WHERE rating = 4),
+                    '5', COUNT(*) FILTER (WHERE rating = 5)
+                ) AS rating_distribution
+            FROM reviews
+            WHERE status = 'approved' AND product_id IS NOT NULL
+            GROUP BY tenant_id, product_id
+        ) s
+        WHERE p.id = s.product_id AND p.tenant_id = s.tenant_id;
+    END IF;
+END $$;
@@ -0,0 +1,59 @@
+-- Vendor of a product, matched by smart collection rules
+ALTER TABLE products ADD COLUMN IF NOT EXISTS vendor VARCHAR(255);
+
+-- Create collections table
+-- Manual collections hold hand-picked products in a merchant-set order; smart
+-- collections hold the products matching their rules
+-- ([{"field": "tag", "value": "summer"}, {"field": "price", "max": 500}])
+CREATE TABLE IF NOT EXISTS collections (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    slug VARCHAR(255) NOT NULL,
+    description TEXT,
+    image VARCHAR(500),
+    type VARCHAR(20) NOT NULL DEFAULT 'manual' CHECK (type IN ('manual', 'smart')),
+    rules JSONB,
+    rule_match VARCHAR(10) NOT NULL DEFAULT 'all' CHECK (rule_match IN ('all', 'any')),
+    sort_by VARCHAR(20) NOT NULL DEFAULT 'manual',
+    is_published BOOLEAN NOT NULL DEFAULT TRUE,
+
+    -- SEO
+    meta_title VARCHAR(255),
+    meta_description TEXT,
+    meta_keywords TEXT,
+
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Create collection_products table
+-- Members of a collection; position orders manual collections
+CREATE TABLE IF NOT EXISTS collection_products (
+    collection_id UUID NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (collection_id, product_id)
+);
+
+-- Create indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_collections_tenant_slug ON collections(tenant_id, slug);
+CREATE INDEX IF NOT EXISTS idx_collections_tenant_type ON collections(tenant_id, type);
+CREATE INDEX IF NOT EXISTS idx_collection_products_product ON collection_products(product_id);
+CREATE INDEX IF NOT EXISTS idx_collection_products_position ON collection_products(collection_id, position);
+CREATE INDEX IF NOT EXISTS idx_products_vendor ON products(tenant_id, LOWER(vendor));
+
+-- Create triggers
+CREATE TRIGGER update_collections_updated_at
+    BEFORE UPDATE ON collections
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Menu items can link to a collection
+DO $$
+BEGIN
+    IF to_regclass('menu_items') IS NOT NULL THEN
+        ALTER TABLE menu_items ADD COLUMN IF NOT EXISTS collection_id UUID REFERENCES collections(id) ON DELETE SET NULL;
+    END IF;
+END $$;
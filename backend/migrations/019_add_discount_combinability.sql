@@ -0,0 +1,18 @@
+-- Automatic promotions and combinability of discounts.
+-- Discounts without a code (requires_code = false) apply automatically, in
+-- priority order. Stackable discounts combine with anything; others only
+-- with the classes their combines_with_* flags allow, and exclusive ones
+-- with nothing.
+ALTER TABLE IF EXISTS discounts ADD COLUMN IF NOT EXISTS combines_with_order BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE IF EXISTS discounts ADD COLUMN IF NOT EXISTS combines_with_product BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE IF EXISTS discounts ADD COLUMN IF NOT EXISTS combines_with_shipping BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE IF EXISTS discounts ADD COLUMN IF NOT EXISTS exclusive BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE IF EXISTS discounts ADD COLUMN IF NOT EXISTS priority INTEGER NOT NULL DEFAULT 0;
+
+-- Create indexes
+DO $$
+BEGIN
+    IF to_regclass('discounts') IS NOT NULL THEN
+        CREATE INDEX IF NOT EXISTS idx_discounts_automatic ON discounts(tenant_id, status, priority DESC, created_at) WHERE requires_code = FALSE;
+    END IF;
+END $$;
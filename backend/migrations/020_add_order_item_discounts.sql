@@ -0,0 +1,4 @@
+-- Promotion amounts allocated to each order line (buy X get Y, tiered and
+-- bundle pricing, and order-level discounts spread by line value). Returns
+-- and loyalty use it to refund and earn on what the customer actually paid.
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS discount_amount DECIMAL(10,2) NOT NULL DEFAULT 0;
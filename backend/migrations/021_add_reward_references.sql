@@ -0,0 +1,16 @@
+-- Idempotency references on store credit grants and code batches, so a
+-- retried referral reward (referral:<id>:referrer, referral:<id>:referee) is
+-- only granted once
+ALTER TABLE IF EXISTS store_credit_transactions ADD COLUMN IF NOT EXISTS reference VARCHAR(255);
+ALTER TABLE IF EXISTS discount_code_batches ADD COLUMN IF NOT EXISTS reference VARCHAR(255);
+
+-- Create indexes
+DO $$
+BEGIN
+    IF to_regclass('store_credit_transactions') IS NOT NULL THEN
+        CREATE UNIQUE INDEX IF NOT EXISTS idx_store_credit_transactions_reference ON store_credit_transactions(tenant_id, store_credit_id, reference) WHERE reference IS NOT NULL AND reference <> '';
+    END IF;
+    IF to_regclass('discount_code_batches') IS NOT NULL THEN
+        CREATE UNIQUE INDEX IF NOT EXISTS idx_discount_code_batches_reference ON discount_code_batches(tenant_id, reference) WHERE reference IS NOT NULL AND reference <> '';
+    END IF;
+END $$;
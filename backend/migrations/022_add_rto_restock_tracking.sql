@@ -0,0 +1,7 @@
+-- COD receivables are booked when a parcel ships so a return to origin has
+-- something to reverse; labels shipped before this have nothing booked.
+ALTER TABLE IF EXISTS shipping_labels ADD COLUMN IF NOT EXISTS cod_booked_at TIMESTAMP;
+
+-- Positions of the label's items already restocked, so a retried receipt
+-- doesn't put the same items back twice
+ALTER TABLE IF EXISTS rto_records ADD COLUMN IF NOT EXISTS restocked_items JSONB;
@@ -0,0 +1,12 @@
+-- Carrier event IDs (or payload hashes) so redelivered tracking webhooks are
+-- recognised instead of counted again
+ALTER TABLE IF EXISTS shipping_trackings ADD COLUMN IF NOT EXISTS event_id VARCHAR(100);
+
+DO $$
+BEGIN
+    IF to_regclass('shipping_trackings') IS NOT NULL THEN
+        CREATE UNIQUE INDEX IF NOT EXISTS idx_shipping_trackings_label_event
+            ON shipping_trackings (label_id, event_id)
+            WHERE event_id IS NOT NULL AND event_id <> '';
+    END IF;
+END $$;
@@ -0,0 +1,10 @@
+-- Product import files are kept on their job until the worker claims it,
+-- instead of being imported by the API process that received them
+ALTER TABLE IF EXISTS product_import_jobs ADD COLUMN IF NOT EXISTS file_data BYTEA;
+
+DO $$
+BEGIN
+    IF to_regclass('product_import_jobs') IS NOT NULL THEN
+        CREATE INDEX IF NOT EXISTS idx_product_import_jobs_status ON product_import_jobs(tenant_id, status, created_at);
+    END IF;
+END $$;
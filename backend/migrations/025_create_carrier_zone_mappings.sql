@@ -0,0 +1,26 @@
+-- Create carrier_zone_mappings table
+-- Courier city/zone/area IDs per geo code. Rows of the nil tenant are
+-- platform defaults, managed by platform administrators; tenant rows
+-- override them, so tenant_id has no foreign key.
+CREATE TABLE IF NOT EXISTS carrier_zone_mappings (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    geo_code VARCHAR(255) NOT NULL,
+    carrier_city_id VARCHAR(50),
+    carrier_zone_id VARCHAR(50),
+    carrier_area_id VARCHAR(50),
+    carrier_label VARCHAR(255),
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Create indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_carrier_zone_mapping ON carrier_zone_mappings(tenant_id, provider, geo_code);
+
+-- Create triggers
+CREATE TRIGGER update_carrier_zone_mappings_updated_at
+    BEFORE UPDATE ON carrier_zone_mappings
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,4 @@
+-- Amounts paid with loyalty points and with gift cards or store credit. They
+-- are tenders, not price reductions, so they stay out of discount_amount.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS points_amount DECIMAL(10,2) NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS gift_card_amount DECIMAL(10,2) NOT NULL DEFAULT 0;
@@ -0,0 +1,27 @@
+-- Create shipping_markup_rules table
+-- Markup a tenant adds on top of carrier quotes and table rates, per provider
+-- or for all providers when provider is empty, plus a free shipping threshold.
+CREATE TABLE IF NOT EXISTS shipping_markup_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    provider VARCHAR(50),
+    name VARCHAR(100) NOT NULL,
+    markup_percent DECIMAL(10,2) DEFAULT 0,
+    markup_flat DECIMAL(10,2) DEFAULT 0,
+    free_shipping_min DECIMAL(10,2) DEFAULT 0,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    deleted_at TIMESTAMPTZ
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_shipping_markup_rules_tenant_id ON shipping_markup_rules(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_shipping_markup_rules_provider ON shipping_markup_rules(provider);
+CREATE INDEX IF NOT EXISTS idx_shipping_markup_rules_deleted_at ON shipping_markup_rules(deleted_at);
+
+-- Create triggers
+CREATE TRIGGER update_shipping_markup_rules_updated_at
+    BEFORE UPDATE ON shipping_markup_rules
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
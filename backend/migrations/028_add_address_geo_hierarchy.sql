@@ -0,0 +1,22 @@
+-- Bangladesh address hierarchy (division > district > thana > area) on saved
+-- addresses. area_code is the most specific geo code picked, which carrier
+-- zone mappings are keyed on.
+ALTER TABLE IF EXISTS addresses ADD COLUMN IF NOT EXISTS division VARCHAR(100);
+ALTER TABLE IF EXISTS addresses ADD COLUMN IF NOT EXISTS district VARCHAR(100);
+ALTER TABLE IF EXISTS addresses ADD COLUMN IF NOT EXISTS thana VARCHAR(100);
+ALTER TABLE IF EXISTS addresses ADD COLUMN IF NOT EXISTS area VARCHAR(100);
+ALTER TABLE IF EXISTS addresses ADD COLUMN IF NOT EXISTS area_code VARCHAR(255);
+
+-- Courier city/zone/area IDs resolved for the receiver when a label is booked
+ALTER TABLE IF EXISTS shipping_labels ADD COLUMN IF NOT EXISTS carrier_city_id VARCHAR(50);
+ALTER TABLE IF EXISTS shipping_labels ADD COLUMN IF NOT EXISTS carrier_zone_id VARCHAR(50);
+ALTER TABLE IF EXISTS shipping_labels ADD COLUMN IF NOT EXISTS carrier_area_id VARCHAR(50);
+
+-- Create indexes
+DO $$
+BEGIN
+    IF to_regclass('addresses') IS NOT NULL THEN
+        CREATE INDEX IF NOT EXISTS idx_addresses_district ON addresses(district);
+        CREATE INDEX IF NOT EXISTS idx_addresses_area_code ON addresses(area_code);
+    END IF;
+END $$;
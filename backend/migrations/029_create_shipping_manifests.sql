@@ -0,0 +1,51 @@
+-- Create shipping_manifests table
+-- Handover sheet for one carrier's pickup. Labels are attached when the
+-- manifest is created and marked shipped on handover.
+CREATE TABLE IF NOT EXISTS shipping_manifests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    manifest_number VARCHAR(50) NOT NULL,
+    pickup_date TIMESTAMPTZ NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'open',
+    total_parcels INTEGER DEFAULT 0,
+    total_weight DECIMAL(10,3) DEFAULT 0,
+    total_cod DECIMAL(10,2) DEFAULT 0,
+    handed_over_at TIMESTAMPTZ,
+    handed_over_to VARCHAR(255),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    deleted_at TIMESTAMPTZ
+);
+
+-- Snapshot of the receiver and parcel on each label, used for printing
+-- labels, packing slips and manifests without loading the order
+ALTER TABLE IF EXISTS shipping_labels ADD COLUMN IF NOT EXISTS order_number VARCHAR(50);
+ALTER TABLE IF EXISTS shipping_labels ADD COLUMN IF NOT EXISTS receiver_name VARCHAR(255);
+ALTER TABLE IF EXISTS shipping_labels ADD COLUMN IF NOT EXISTS receiver_phone VARCHAR(50);
+ALTER TABLE IF EXISTS shipping_labels ADD COLUMN IF NOT EXISTS receiver_address TEXT;
+ALTER TABLE IF EXISTS shipping_labels ADD COLUMN IF NOT EXISTS receiver_city VARCHAR(100);
+ALTER TABLE IF EXISTS shipping_labels ADD COLUMN IF NOT EXISTS weight DECIMAL(10,3) DEFAULT 0;
+ALTER TABLE IF EXISTS shipping_labels ADD COLUMN IF NOT EXISTS cod_amount DECIMAL(10,2) DEFAULT 0;
+ALTER TABLE IF EXISTS shipping_labels ADD COLUMN IF NOT EXISTS items JSONB;
+ALTER TABLE IF EXISTS shipping_labels ADD COLUMN IF NOT EXISTS printed_at TIMESTAMPTZ;
+ALTER TABLE IF EXISTS shipping_labels ADD COLUMN IF NOT EXISTS manifest_id UUID REFERENCES shipping_manifests(id) ON DELETE SET NULL;
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_shipping_manifests_tenant_id ON shipping_manifests(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_shipping_manifests_provider ON shipping_manifests(provider);
+CREATE INDEX IF NOT EXISTS idx_shipping_manifests_manifest_number ON shipping_manifests(manifest_number);
+CREATE INDEX IF NOT EXISTS idx_shipping_manifests_deleted_at ON shipping_manifests(deleted_at);
+
+DO $$
+BEGIN
+    IF to_regclass('shipping_labels') IS NOT NULL THEN
+        CREATE INDEX IF NOT EXISTS idx_shipping_labels_manifest_id ON shipping_labels(manifest_id);
+    END IF;
+END $$;
+
+-- Create triggers
+CREATE TRIGGER update_shipping_manifests_updated_at
+    BEFORE UPDATE ON shipping_manifests
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
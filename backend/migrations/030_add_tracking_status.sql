@@ -0,0 +1,10 @@
+-- Latest normalised carrier event on each label, so order pages and the
+-- tracking poller don't have to replay the timeline
+ALTER TABLE IF EXISTS shipping_labels ADD COLUMN IF NOT EXISTS receiver_email VARCHAR(255);
+ALTER TABLE IF EXISTS shipping_labels ADD COLUMN IF NOT EXISTS tracking_status VARCHAR(50) DEFAULT 'pending';
+ALTER TABLE IF EXISTS shipping_labels ADD COLUMN IF NOT EXISTS last_tracked_at TIMESTAMPTZ;
+
+-- The carrier that sent each timeline event and its own status code, kept
+-- next to the normalised status
+ALTER TABLE IF EXISTS shipping_trackings ADD COLUMN IF NOT EXISTS provider VARCHAR(50);
+ALTER TABLE IF EXISTS shipping_trackings ADD COLUMN IF NOT EXISTS raw_status VARCHAR(100);
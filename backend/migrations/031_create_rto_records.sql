@@ -0,0 +1,86 @@
+-- Create rto_records table
+-- Tracks a return to origin from the first trigger until the parcel is
+-- received back. Each receipt step is stamped so a retry skips finished
+-- steps; restocked_items holds the label item positions already put back.
+CREATE TABLE IF NOT EXISTS rto_records (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    label_id UUID NOT NULL,
+    order_id UUID NOT NULL,
+    order_number VARCHAR(50),
+    provider VARCHAR(50) NOT NULL,
+    tracking_number VARCHAR(100) NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'initiated',
+    reason VARCHAR(50) NOT NULL,
+    notes TEXT,
+    failed_attempts INTEGER DEFAULT 0,
+    customer_phone VARCHAR(20),
+    area_key VARCHAR(150),
+    cod_amount DECIMAL(10,2) DEFAULT 0,
+    return_fee DECIMAL(10,2) DEFAULT 0,
+    currency VARCHAR(3) DEFAULT 'BDT',
+    condition VARCHAR(50),
+    received_at TIMESTAMPTZ,
+    restocked_at TIMESTAMPTZ,
+    cod_reversed_at TIMESTAMPTZ,
+    fee_charged_at TIMESTAMPTZ,
+    processing_error TEXT,
+    restocked_items JSONB,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    deleted_at TIMESTAMPTZ
+);
+
+-- Create customer_risk_profiles table
+-- Delivery history per customer phone, so repeat refusers are flagged
+-- before another COD order ships
+CREATE TABLE IF NOT EXISTS customer_risk_profiles (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    phone VARCHAR(20) NOT NULL,
+    delivered INTEGER DEFAULT 0,
+    rto_count INTEGER DEFAULT 0,
+    refused_count INTEGER DEFAULT 0,
+    failed_attempts INTEGER DEFAULT 0,
+    rto_value DECIMAL(10,2) DEFAULT 0,
+    risk_level VARCHAR(20) DEFAULT 'low',
+    is_flagged BOOLEAN DEFAULT FALSE,
+    last_rto_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Delivery attempts and the receiver's area, used to trigger RTOs and
+-- report them by area
+ALTER TABLE IF EXISTS shipping_labels ADD COLUMN IF NOT EXISTS receiver_area_code VARCHAR(150);
+ALTER TABLE IF EXISTS shipping_labels ADD COLUMN IF NOT EXISTS failed_attempts INTEGER DEFAULT 0;
+
+-- Create indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_rto_records_label_id ON rto_records(label_id);
+CREATE INDEX IF NOT EXISTS idx_rto_records_tenant_id ON rto_records(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_rto_records_order_id ON rto_records(order_id);
+CREATE INDEX IF NOT EXISTS idx_rto_records_provider ON rto_records(provider);
+CREATE INDEX IF NOT EXISTS idx_rto_records_status ON rto_records(status);
+CREATE INDEX IF NOT EXISTS idx_rto_records_customer_phone ON rto_records(customer_phone);
+CREATE INDEX IF NOT EXISTS idx_rto_records_deleted_at ON rto_records(deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_risk_tenant_phone ON customer_risk_profiles(tenant_id, phone);
+CREATE INDEX IF NOT EXISTS idx_customer_risk_profiles_risk_level ON customer_risk_profiles(risk_level);
+CREATE INDEX IF NOT EXISTS idx_customer_risk_profiles_is_flagged ON customer_risk_profiles(is_flagged);
+
+DO $$
+BEGIN
+    IF to_regclass('shipping_labels') IS NOT NULL THEN
+        CREATE INDEX IF NOT EXISTS idx_shipping_labels_receiver_area_code ON shipping_labels(receiver_area_code);
+    END IF;
+END $$;
+
+-- Create triggers
+CREATE TRIGGER update_rto_records_updated_at
+    BEFORE UPDATE ON rto_records
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_customer_risk_profiles_updated_at
+    BEFORE UPDATE ON customer_risk_profiles
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
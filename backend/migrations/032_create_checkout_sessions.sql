@@ -0,0 +1,57 @@
+-- Create checkout_sessions table
+-- Everything needed to turn a cart into an order. Lines and totals are
+-- priced on the server and refreshed on every update and again at
+-- completion; addresses, codes and applied discounts are stored as JSON.
+CREATE TABLE IF NOT EXISTS checkout_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    cart_id UUID NOT NULL,
+    customer_id UUID,
+    status VARCHAR(50) DEFAULT 'open',
+    email VARCHAR(255),
+    phone VARCHAR(50),
+    shipping_address JSONB,
+    billing_address JSONB,
+    shipping_method_id UUID,
+    payment_method VARCHAR(50),
+    discount_codes JSONB,
+    gift_card_codes JSONB,
+    loyalty_points INTEGER DEFAULT 0,
+    notes TEXT,
+    lines JSONB,
+    discounts JSONB,
+    gift_cards JSONB,
+    rejected_promotions JSONB,
+    subtotal DECIMAL(10,2) DEFAULT 0,
+    discount_amount DECIMAL(10,2) DEFAULT 0,
+    shipping_amount DECIMAL(10,2) DEFAULT 0,
+    tax_amount DECIMAL(10,2) DEFAULT 0,
+    points_redeemed INTEGER DEFAULT 0,
+    points_amount DECIMAL(10,2) DEFAULT 0,
+    gift_card_amount DECIMAL(10,2) DEFAULT 0,
+    total DECIMAL(10,2) DEFAULT 0,
+    currency VARCHAR(3) DEFAULT 'BDT',
+    priced_at TIMESTAMPTZ,
+    order_id UUID REFERENCES orders(id) ON DELETE SET NULL,
+    order_number VARCHAR(50),
+    points_transaction_id UUID,
+    error_code VARCHAR(50),
+    error_message TEXT,
+    expires_at TIMESTAMPTZ NOT NULL,
+    completed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_checkout_sessions_tenant_id ON checkout_sessions(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_checkout_sessions_cart_id ON checkout_sessions(tenant_id, cart_id, status);
+CREATE INDEX IF NOT EXISTS idx_checkout_sessions_customer_id ON checkout_sessions(customer_id);
+CREATE INDEX IF NOT EXISTS idx_checkout_sessions_status ON checkout_sessions(status);
+CREATE INDEX IF NOT EXISTS idx_checkout_sessions_order_id ON checkout_sessions(order_id);
+
+-- Create triggers
+CREATE TRIGGER update_checkout_sessions_updated_at
+    BEFORE UPDATE ON checkout_sessions
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
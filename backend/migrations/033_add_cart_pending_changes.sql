@@ -0,0 +1,4 @@
+-- Catalog changes found when a cart is re-validated (price changes, items
+-- gone or out of stock) that the customer must acknowledge before checkout
+ALTER TABLE IF EXISTS carts ADD COLUMN IF NOT EXISTS pending_changes JSONB;
+ALTER TABLE IF EXISTS carts ADD COLUMN IF NOT EXISTS validated_at TIMESTAMPTZ;
@@ -0,0 +1,7 @@
+-- Line-aware discount rules: what a buy-X-get-Y discount gives away, the
+-- quantity or spend tiers of tiered pricing, and the items of a bundle
+ALTER TABLE IF EXISTS discounts ADD COLUMN IF NOT EXISTS reward_product_ids JSONB;
+ALTER TABLE IF EXISTS discounts ADD COLUMN IF NOT EXISTS reward_category_ids JSONB;
+ALTER TABLE IF EXISTS discounts ADD COLUMN IF NOT EXISTS reward_collection_ids JSONB;
+ALTER TABLE IF EXISTS discounts ADD COLUMN IF NOT EXISTS tiers JSONB;
+ALTER TABLE IF EXISTS discounts ADD COLUMN IF NOT EXISTS bundle_items JSONB;
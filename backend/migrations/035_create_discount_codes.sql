@@ -0,0 +1,53 @@
+-- Create discount_code_batches table
+-- One bulk code generation run. reference is an idempotency key, so a
+-- retried grant (e.g. a referral reward) doesn't generate a second batch.
+CREATE TABLE IF NOT EXISTS discount_code_batches (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    discount_id UUID NOT NULL,
+    prefix VARCHAR(50),
+    pattern VARCHAR(50),
+    quantity INTEGER DEFAULT 0,
+    usage_limit INTEGER DEFAULT 0,
+    collisions INTEGER DEFAULT 0,
+    reference VARCHAR(255),
+    created_by UUID,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Create discount_codes table
+-- Pool codes apply their parent discount and are usually single-use. Codes
+-- are unique across tenants, like discount codes themselves.
+CREATE TABLE IF NOT EXISTS discount_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    discount_id UUID NOT NULL,
+    batch_id UUID NOT NULL REFERENCES discount_code_batches(id) ON DELETE CASCADE,
+    code VARCHAR(50) NOT NULL UNIQUE,
+    status VARCHAR(20) DEFAULT 'available',
+    usage_limit INTEGER DEFAULT 1,
+    usage_count INTEGER DEFAULT 0,
+    redeemed_at TIMESTAMPTZ,
+    order_id UUID,
+    order_number VARCHAR(50),
+    customer_id UUID,
+    customer_email VARCHAR(255),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_discount_code_batches_tenant_id ON discount_code_batches(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_discount_code_batches_discount_id ON discount_code_batches(discount_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_discount_code_batches_reference ON discount_code_batches(tenant_id, reference) WHERE reference IS NOT NULL AND reference <> '';
+CREATE INDEX IF NOT EXISTS idx_discount_codes_tenant_id ON discount_codes(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_discount_codes_discount_id ON discount_codes(discount_id);
+CREATE INDEX IF NOT EXISTS idx_discount_codes_batch_id ON discount_codes(batch_id);
+CREATE INDEX IF NOT EXISTS idx_discount_codes_status ON discount_codes(status);
+CREATE INDEX IF NOT EXISTS idx_discount_codes_order_id ON discount_codes(order_id);
+
+-- Create triggers
+CREATE TRIGGER update_discount_codes_updated_at
+    BEFORE UPDATE ON discount_codes
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,54 @@
+-- Create balance_holds table
+-- Amounts reserved on a gift card or store credit while checkout completes.
+-- A hold is captured when the order is placed, or released when the session
+-- fails or the hold expires; held_amount on the account is the sum of its
+-- open holds.
+CREATE TABLE IF NOT EXISTS balance_holds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    account_type VARCHAR(20) NOT NULL,
+    account_id UUID NOT NULL,
+    amount DECIMAL(10,2) NOT NULL,
+    status VARCHAR(20) DEFAULT 'held',
+    reference VARCHAR(255),
+    order_id UUID,
+    order_number VARCHAR(50),
+    customer_id UUID,
+    customer_email VARCHAR(255),
+    expires_at TIMESTAMPTZ,
+    captured_at TIMESTAMPTZ,
+    released_at TIMESTAMPTZ,
+    release_reason VARCHAR(255),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Reserved balances and the hold each ledger entry settles
+ALTER TABLE IF EXISTS gift_cards ADD COLUMN IF NOT EXISTS held_amount DECIMAL(10,2) DEFAULT 0;
+ALTER TABLE IF EXISTS store_credits ADD COLUMN IF NOT EXISTS held_amount DECIMAL(10,2) DEFAULT 0;
+ALTER TABLE IF EXISTS gift_card_transactions ADD COLUMN IF NOT EXISTS hold_id UUID;
+ALTER TABLE IF EXISTS store_credit_transactions ADD COLUMN IF NOT EXISTS hold_id UUID;
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_balance_holds_tenant_id ON balance_holds(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_balance_holds_account ON balance_holds(account_type, account_id);
+CREATE INDEX IF NOT EXISTS idx_balance_holds_status ON balance_holds(status);
+CREATE INDEX IF NOT EXISTS idx_balance_holds_reference ON balance_holds(reference);
+CREATE INDEX IF NOT EXISTS idx_balance_holds_order_id ON balance_holds(order_id);
+CREATE INDEX IF NOT EXISTS idx_balance_holds_expires_at ON balance_holds(expires_at);
+
+DO $$
+BEGIN
+    IF to_regclass('gift_card_transactions') IS NOT NULL THEN
+        CREATE INDEX IF NOT EXISTS idx_gift_card_transactions_hold_id ON gift_card_transactions(hold_id);
+    END IF;
+    IF to_regclass('store_credit_transactions') IS NOT NULL THEN
+        CREATE INDEX IF NOT EXISTS idx_store_credit_transactions_hold_id ON store_credit_transactions(hold_id);
+    END IF;
+END $$;
+
+-- Create triggers
+CREATE TRIGGER update_balance_holds_updated_at
+    BEFORE UPDATE ON balance_holds
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,57 @@
+-- Create gift_card_purchases table
+-- A gift card bought as a product. The card is issued once the order is
+-- paid and emailed to the recipient on send_at, or straight away when it is
+-- empty; failed deliveries are retried by the worker.
+CREATE TABLE IF NOT EXISTS gift_card_purchases (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    order_number VARCHAR(50),
+    product_id UUID NOT NULL,
+    variant_id UUID,
+    amount DECIMAL(10,2) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    validity_days INTEGER DEFAULT 0,
+    purchaser_id UUID,
+    purchaser_email VARCHAR(255),
+    sender_name VARCHAR(100),
+    recipient_name VARCHAR(100) NOT NULL,
+    recipient_email VARCHAR(255) NOT NULL,
+    message TEXT,
+    send_at TIMESTAMPTZ,
+    status VARCHAR(20) DEFAULT 'pending',
+    gift_card_id UUID,
+    issued_at TIMESTAMPTZ,
+    delivered_at TIMESTAMPTZ,
+    notification_id VARCHAR(255),
+    delivery_attempts INTEGER DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Gift card products, with the amounts a customer can choose from and how
+-- long issued cards stay valid (0 means they never expire)
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_type_check;
+ALTER TABLE products ADD CONSTRAINT products_type_check CHECK (type IN ('physical', 'digital', 'service', 'gift_card'));
+ALTER TABLE products ADD COLUMN IF NOT EXISTS gift_card_denominations JSONB;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS gift_card_validity_days INTEGER DEFAULT 0;
+
+-- Recipient details chosen for a gift card in the cart
+ALTER TABLE IF EXISTS cart_items ADD COLUMN IF NOT EXISTS gift_card JSONB;
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_gift_card_purchases_tenant_id ON gift_card_purchases(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_gift_card_purchases_order_id ON gift_card_purchases(order_id);
+CREATE INDEX IF NOT EXISTS idx_gift_card_purchases_product_id ON gift_card_purchases(product_id);
+CREATE INDEX IF NOT EXISTS idx_gift_card_purchases_purchaser_id ON gift_card_purchases(purchaser_id);
+CREATE INDEX IF NOT EXISTS idx_gift_card_purchases_recipient_email ON gift_card_purchases(recipient_email);
+CREATE INDEX IF NOT EXISTS idx_gift_card_purchases_send_at ON gift_card_purchases(send_at);
+CREATE INDEX IF NOT EXISTS idx_gift_card_purchases_status ON gift_card_purchases(status);
+CREATE INDEX IF NOT EXISTS idx_gift_card_purchases_gift_card_id ON gift_card_purchases(gift_card_id);
+
+-- Create triggers
+CREATE TRIGGER update_gift_card_purchases_updated_at
+    BEFORE UPDATE ON gift_card_purchases
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
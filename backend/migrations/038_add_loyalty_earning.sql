@@ -0,0 +1,25 @@
+-- Automatic loyalty earning. Points earned on an order stay pending until
+-- the return window closes (available_at); reference keeps an automatic
+-- award such as a birthday bonus from being granted twice, and
+-- reversed_points records what returns and refunds took back.
+ALTER TABLE IF EXISTS loyalty_accounts ADD COLUMN IF NOT EXISTS pending_points INTEGER DEFAULT 0;
+ALTER TABLE IF EXISTS loyalty_accounts ADD COLUMN IF NOT EXISTS birthday TIMESTAMPTZ;
+
+ALTER TABLE IF EXISTS loyalty_transactions ADD COLUMN IF NOT EXISTS status VARCHAR(20) DEFAULT 'posted';
+ALTER TABLE IF EXISTS loyalty_transactions ADD COLUMN IF NOT EXISTS source VARCHAR(50);
+ALTER TABLE IF EXISTS loyalty_transactions ADD COLUMN IF NOT EXISTS reference VARCHAR(255);
+ALTER TABLE IF EXISTS loyalty_transactions ADD COLUMN IF NOT EXISTS available_at TIMESTAMPTZ;
+ALTER TABLE IF EXISTS loyalty_transactions ADD COLUMN IF NOT EXISTS base_amount DECIMAL(10,2) DEFAULT 0;
+ALTER TABLE IF EXISTS loyalty_transactions ADD COLUMN IF NOT EXISTS reversed_points INTEGER DEFAULT 0;
+
+-- Create indexes
+DO $$
+BEGIN
+    IF to_regclass('loyalty_transactions') IS NOT NULL THEN
+        CREATE INDEX IF NOT EXISTS idx_loyalty_transactions_order_id ON loyalty_transactions(order_id);
+        CREATE INDEX IF NOT EXISTS idx_loyalty_transactions_status ON loyalty_transactions(status);
+        CREATE INDEX IF NOT EXISTS idx_loyalty_transactions_source ON loyalty_transactions(source);
+        CREATE INDEX IF NOT EXISTS idx_loyalty_transactions_reference ON loyalty_transactions(reference);
+        CREATE INDEX IF NOT EXISTS idx_loyalty_transactions_available_at ON loyalty_transactions(available_at) WHERE status = 'pending';
+    END IF;
+END $$;
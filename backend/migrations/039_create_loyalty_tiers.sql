@@ -0,0 +1,40 @@
+-- Create loyalty_tiers table
+-- Tiers of a loyalty program, best tier highest rank. Accounts qualify on
+-- points or spend within the program's rolling window and keep their tier
+-- through a grace period after falling below its threshold.
+CREATE TABLE IF NOT EXISTS loyalty_tiers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    program_id UUID NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    code VARCHAR(50) NOT NULL,
+    rank INTEGER NOT NULL,
+    threshold DECIMAL(10,2) DEFAULT 0,
+    earning_multiplier DECIMAL(5,2) DEFAULT 1,
+    free_shipping BOOLEAN DEFAULT FALSE,
+    benefits TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    deleted_at TIMESTAMPTZ
+);
+
+-- Tier qualification state on each account
+ALTER TABLE IF EXISTS loyalty_accounts ADD COLUMN IF NOT EXISTS tier_qualifying_value DECIMAL(12,2) DEFAULT 0;
+ALTER TABLE IF EXISTS loyalty_accounts ADD COLUMN IF NOT EXISTS tier_grace_until TIMESTAMPTZ;
+ALTER TABLE IF EXISTS loyalty_accounts ADD COLUMN IF NOT EXISTS tier_changed_at TIMESTAMPTZ;
+ALTER TABLE IF EXISTS loyalty_accounts ADD COLUMN IF NOT EXISTS tier_evaluated_at TIMESTAMPTZ;
+
+-- Tier codes a discount is limited to; open to everyone when empty
+ALTER TABLE IF EXISTS discounts ADD COLUMN IF NOT EXISTS eligible_loyalty_tiers JSONB;
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_loyalty_tiers_tenant_id ON loyalty_tiers(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_loyalty_tiers_program_id ON loyalty_tiers(program_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_loyalty_tiers_program_code ON loyalty_tiers(program_id, code) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_loyalty_tiers_deleted_at ON loyalty_tiers(deleted_at);
+
+-- Create triggers
+CREATE TRIGGER update_loyalty_tiers_updated_at
+    BEFORE UPDATE ON loyalty_tiers
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
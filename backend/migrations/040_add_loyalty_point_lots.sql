@@ -0,0 +1,15 @@
+-- Earned points are lots spent first in, first out. remaining_points is what
+-- a lot still holds after spending, expiry and reversals; a debit records
+-- the lots it drew from in lots, so a cancelled redemption can restore them.
+ALTER TABLE IF EXISTS loyalty_transactions ADD COLUMN IF NOT EXISTS remaining_points INTEGER DEFAULT 0;
+ALTER TABLE IF EXISTS loyalty_transactions ADD COLUMN IF NOT EXISTS reminder_sent_at TIMESTAMPTZ;
+ALTER TABLE IF EXISTS loyalty_transactions ADD COLUMN IF NOT EXISTS cash_value DECIMAL(10,2) DEFAULT 0;
+ALTER TABLE IF EXISTS loyalty_transactions ADD COLUMN IF NOT EXISTS lots JSONB;
+
+-- Create indexes
+DO $$
+BEGIN
+    IF to_regclass('loyalty_transactions') IS NOT NULL THEN
+        CREATE INDEX IF NOT EXISTS idx_loyalty_transactions_remaining_points ON loyalty_transactions(remaining_points);
+    END IF;
+END $$;
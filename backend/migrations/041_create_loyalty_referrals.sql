@@ -0,0 +1,63 @@
+-- Create loyalty_referral_codes table
+-- One shareable code per customer and program, with click and signup counts
+CREATE TABLE IF NOT EXISTS loyalty_referral_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    program_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    code VARCHAR(50) NOT NULL,
+    clicks INTEGER DEFAULT 0,
+    signups INTEGER DEFAULT 0,
+    last_clicked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Create loyalty_referrals table
+-- A referred customer, attributed on signup or first order. Both sides are
+-- rewarded once the first order's return window closes (eligible_at);
+-- fraud checks can reject the referral before that.
+CREATE TABLE IF NOT EXISTS loyalty_referrals (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    program_id UUID NOT NULL,
+    code_id UUID NOT NULL REFERENCES loyalty_referral_codes(id) ON DELETE CASCADE,
+    referrer_id UUID NOT NULL,
+    referee_id UUID NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    attributed_on VARCHAR(20),
+    ip_address VARCHAR(45),
+    device_fingerprint VARCHAR(255),
+    fraud_flags JSONB,
+    reason TEXT,
+    order_id UUID,
+    order_number VARCHAR(50),
+    delivered_at TIMESTAMPTZ,
+    eligible_at TIMESTAMPTZ,
+    referrer_reward JSONB,
+    referee_reward JSONB,
+    rewarded_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Create indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_referral_codes_code ON loyalty_referral_codes(tenant_id, code);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_referral_codes_user ON loyalty_referral_codes(program_id, user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_referrals_referee ON loyalty_referrals(tenant_id, referee_id);
+CREATE INDEX IF NOT EXISTS idx_loyalty_referrals_code_id ON loyalty_referrals(code_id);
+CREATE INDEX IF NOT EXISTS idx_loyalty_referrals_referrer_id ON loyalty_referrals(referrer_id);
+CREATE INDEX IF NOT EXISTS idx_loyalty_referrals_status ON loyalty_referrals(status);
+CREATE INDEX IF NOT EXISTS idx_loyalty_referrals_order_id ON loyalty_referrals(order_id);
+CREATE INDEX IF NOT EXISTS idx_loyalty_referrals_eligible_at ON loyalty_referrals(eligible_at);
+
+-- Create triggers
+CREATE TRIGGER update_loyalty_referral_codes_updated_at
+    BEFORE UPDATE ON loyalty_referral_codes
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_loyalty_referrals_updated_at
+    BEFORE UPDATE ON loyalty_referrals
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();